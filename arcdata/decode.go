@@ -0,0 +1,175 @@
+package arcdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"arc-to-zen/types"
+)
+
+// Decode reads Arc sidebar data from r as a stream. Only sidebar.containers
+// is materialized, and of each container only its spaces and items, one
+// entry at a time as raw JSON; everything else in the file is skipped token
+// by token. The ID strings Arc lists between the objects are dropped. Unlike
+// json.Unmarshal, neither the whole file nor a generic tree of it is ever
+// held in memory. Data after the top-level object is an error, like
+// json.Unmarshal, so damaged files still reach Recover.
+func Decode(r io.Reader) (*types.ArcData, error) {
+	dec := json.NewDecoder(r)
+	data := &types.ArcData{}
+	_, err := decodeObject(dec, func(key string) error {
+		if key != "sidebar" {
+			return skipValue(dec)
+		}
+		sidebar := &types.ArcSidebar{}
+		null, err := decodeObject(dec, func(key string) error {
+			if key != "containers" {
+				return skipValue(dec)
+			}
+			return decodeArray(dec, func() error {
+				container, err := decodeContainer(dec)
+				if err != nil {
+					return err
+				}
+				sidebar.Containers = append(sidebar.Containers, container)
+				return nil
+			})
+		})
+		if !null {
+			data.Sidebar = sidebar
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return data, nil
+}
+
+// decodeContainer reads one entry of sidebar.containers; null decodes to nil
+func decodeContainer(dec *json.Decoder) (*types.ArcContainer, error) {
+	container := &types.ArcContainer{}
+	null, err := decodeObject(dec, func(key string) error {
+		var entries *[]json.RawMessage
+		switch key {
+		case "spaces":
+			entries = &container.Spaces
+		case "items":
+			entries = &container.Items
+		default:
+			return skipValue(dec)
+		}
+		return decodeArray(dec, func() error {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if len(raw) > 0 && raw[0] == '"' {
+				return nil // An ID listed before its object
+			}
+			*entries = append(*entries, raw)
+			return nil
+		})
+	})
+	if null {
+		return nil, err
+	}
+	return container, err
+}
+
+// decodeObject reads an object, calling field for each key with the decoder
+// positioned at its value, which field must consume. null is read as no
+// object, reported by the first result.
+func decodeObject(dec *json.Decoder, field func(key string) error) (null bool, err error) {
+	token, err := dec.Token()
+	if err != nil {
+		return false, unexpectedEOF(err)
+	}
+	if token == nil {
+		return true, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return false, fmt.Errorf("expected an object, got %v", token)
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return false, fmt.Errorf("expected an object key, got %v", token)
+		}
+		if err := field(key); err != nil {
+			return false, err
+		}
+	}
+	return false, expectDelim(dec, '}')
+}
+
+// decodeArray reads an array, calling element with the decoder positioned at
+// each element, which element must consume. null is read as an empty array.
+func decodeArray(dec *json.Decoder, element func() error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected an array, got %v", token)
+	}
+	for dec.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %v, got %v", want, token)
+	}
+	return nil
+}
+
+// skipValue consumes the next value without keeping it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			default:
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// unexpectedEOF reports running out of input inside a value as such
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
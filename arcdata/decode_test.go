@@ -0,0 +1,146 @@
+package arcdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	const sidebar = `{"version": 3, "firebaseSyncState": {"a": [1, {"b": null}]},
+		"sidebar": {"containers": [
+			{"global": {}},
+			null,
+			{"spaces": ["s1", {"id": "s1", "title": "Work"}], "items": ["i1", {"id": "i1", "title": "A"}, 42, {"title": "no id"}],
+			 "topAppsContainerIDs": ["x", {"y": 1}]},
+			{"spaces": null, "items": []}
+		], "sidebarSyncState": {"z": [[]]}}}`
+
+	data, err := Decode(strings.NewReader(sidebar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := data.Sidebar.Containers
+	if len(containers) != 4 || containers[1] != nil {
+		t.Fatalf("expected 4 containers with null kept as nil, got %+v", containers)
+	}
+	if len(containers[0].Spaces) != 0 || len(containers[0].Items) != 0 {
+		t.Errorf("expected the global container empty, got %+v", containers[0])
+	}
+	if got := containers[2].Spaces; len(got) != 1 || string(got[0]) != `{"id": "s1", "title": "Work"}` {
+		t.Errorf("expected the space object without its ID string, got %s", got)
+	}
+	if got := containers[2].Items; len(got) != 3 || string(got[1]) != "42" {
+		t.Errorf("expected the item objects and the number, got %s", got)
+	}
+
+	// Same result as unmarshaling the whole file, less the ID strings
+	var want struct {
+		Sidebar struct {
+			Containers []*struct {
+				Spaces []json.RawMessage `json:"spaces"`
+				Items  []json.RawMessage `json:"items"`
+			} `json:"containers"`
+		} `json:"sidebar"`
+	}
+	if err := json.Unmarshal([]byte(sidebar), &want); err != nil {
+		t.Fatal(err)
+	}
+	var wantItems []json.RawMessage
+	for _, raw := range want.Sidebar.Containers[2].Items {
+		if raw[0] != '"' {
+			wantItems = append(wantItems, raw)
+		}
+	}
+	for i, raw := range containers[2].Items {
+		if !bytes.Equal(raw, wantItems[i]) {
+			t.Errorf("item %d: got %s, want %s", i, raw, wantItems[i])
+		}
+	}
+
+	if data, err := Decode(strings.NewReader(`{"version": 3}`)); err != nil || data.Sidebar != nil {
+		t.Errorf("expected no sidebar, got %+v, %v", data, err)
+	}
+	for _, damaged := range []string{sidebar + " {}", sidebar[:len(sidebar)/2], `{"sidebar": []}`, ``} {
+		if _, err := Decode(strings.NewReader(damaged)); err == nil {
+			t.Errorf("expected %q not to decode", damaged)
+		}
+	}
+}
+
+// largeSidebar builds a sidebar of the size heavy Arc users have: many
+// pinned items with long titles, plus sync state the importer never reads
+func largeSidebar(items int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"sidebar": {"containers": [{"global": {}}, {"spaces": [`)
+	for i := 0; i < 20; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `"s%d", {"id": "s%d", "title": "Space %d", "containerIDs": ["pinned", "p%d", "unpinned", "u%d"]}`, i, i, i, i, i)
+	}
+	b.WriteString(`], "items": [`)
+	title := strings.Repeat("A long tab title ", 8)
+	for i := 0; i < items; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `"i%d", {"id": "i%d", "parentID": "p%d", "childrenIds": [], "title": %q, "createdAt": 700000000.5, "data": {"tab": {"savedURL": "https://example.com/%d/page", "savedTitle": %q}}}`,
+			i, i, i%20, title, i, title)
+	}
+	b.WriteString(`]}], "sidebarSyncState": {"items": [`)
+	for i := 0; i < items; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `"i%d", {"value": {"title": %q, "lastChangeDate": 700000000.5}}`, i, title)
+	}
+	b.WriteString(`]}}, "firebaseSyncState": {"syncData": {"items": []}}}`)
+	return b.Bytes()
+}
+
+// legacyArcData is how the sidebar was read before Decode: the whole file
+// into generic values, each item re-marshaled for the importer to parse
+type legacyArcData struct {
+	Sidebar struct {
+		Containers []struct {
+			Spaces []interface{} `json:"spaces"`
+			Items  []interface{} `json:"items"`
+		} `json:"containers"`
+	} `json:"sidebar"`
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := largeSidebar(20000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeLegacy(b *testing.B) {
+	data := largeSidebar(20000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var arcData legacyArcData
+		if err := json.Unmarshal(data, &arcData); err != nil {
+			b.Fatal(err)
+		}
+		for _, container := range arcData.Sidebar.Containers {
+			for _, item := range container.Items {
+				if _, ok := item.(map[string]interface{}); !ok {
+					continue
+				}
+				if _, err := json.Marshal(item); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
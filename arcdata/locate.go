@@ -0,0 +1,182 @@
+// Package arcdata finds Arc's sidebar data (StorableSidebar.json). Beta and
+// dev builds keep their data in their own Application Support folder, and
+// sandboxed installs keep it under ~/Library/Containers, so the known folders
+// are probed and the Arc containers scanned rather than trusting one path.
+// Arc for Windows is a packaged app whose data lives in its package folder
+// under %LOCALAPPDATA%\Packages.
+package arcdata
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is Arc's sidebar data file
+const FileName = "StorableSidebar.json"
+
+// channelDirs are the Application Support folders of Arc's release channels
+var channelDirs = []string{"Arc", "Arc Beta", "Arc Dev", "Arc Canary", "Arc Nightly"}
+
+// containerGlob matches the sandbox containers of Arc's builds
+const containerGlob = "company.thebrowser.*"
+
+// packageGlob matches the package folders of Arc for Windows
+// (TheBrowserCompany.Arc_<publisher id>)
+const packageGlob = "TheBrowserCompany.Arc*"
+
+// maxScanDepth bounds the search below a container, whose Data directory
+// mirrors the home directory
+const maxScanDepth = 6
+
+// Candidate is a StorableSidebar.json found on disk
+type Candidate struct {
+	Path     string
+	Channel  string // Application Support folder or container it was found in
+	Modified time.Time
+}
+
+// Candidates returns the sidebar files under homeDir, most recently
+// modified first
+func Candidates(homeDir string) []Candidate {
+	return candidates(homeDir, runtime.GOOS, os.Getenv("LOCALAPPDATA"))
+}
+
+// candidates is Candidates for the platform goos. localAppData is
+// %LOCALAPPDATA% on Windows (AppData\Local in homeDir if empty).
+func candidates(homeDir, goos, localAppData string) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	add := func(path, channel string) {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return
+		}
+		key := path
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		candidates = append(candidates, Candidate{Path: path, Channel: channel, Modified: info.ModTime()})
+	}
+
+	// scan looks for the file below root, as deep as maxScanDepth
+	scan := func(root, channel string) {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Unreadable parts of the sandbox are skipped
+			}
+			if entry.IsDir() {
+				if depth := strings.Count(strings.TrimPrefix(path, root), string(filepath.Separator)); depth >= maxScanDepth {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.Name() == FileName {
+				add(path, channel)
+			}
+			return nil
+		})
+	}
+
+	if goos == "windows" {
+		local := windowsLocalAppData(homeDir, localAppData)
+		for _, dir := range channelDirs {
+			add(filepath.Join(local, dir, FileName), dir)
+		}
+		packages, _ := filepath.Glob(filepath.Join(local, "Packages", packageGlob))
+		for _, pkg := range packages {
+			scan(filepath.Join(pkg, "LocalCache"), filepath.Base(pkg))
+		}
+	} else {
+		support := filepath.Join(homeDir, "Library", "Application Support")
+		for _, dir := range channelDirs {
+			add(filepath.Join(support, dir, FileName), dir)
+		}
+		containers, _ := filepath.Glob(filepath.Join(homeDir, "Library", "Containers", containerGlob))
+		for _, container := range containers {
+			scan(filepath.Join(container, "Data"), filepath.Base(container))
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Modified.After(candidates[j].Modified)
+	})
+	return candidates
+}
+
+// Locate returns the sidebar file to import: the only candidate, or the most
+// recently modified when there are several (all are returned so the caller
+// can say which were passed over)
+func Locate(homeDir string) (*Candidate, []Candidate, error) {
+	return locate(homeDir, runtime.GOOS, os.Getenv("LOCALAPPDATA"))
+}
+
+func locate(homeDir, goos, localAppData string) (*Candidate, []Candidate, error) {
+	found := candidates(homeDir, goos, localAppData)
+	if len(found) == 0 {
+		var searched []string
+		if goos == "windows" {
+			local := windowsLocalAppData(homeDir, localAppData)
+			for _, dir := range channelDirs {
+				searched = append(searched, filepath.Join(local, dir))
+			}
+			searched = append(searched, filepath.Join(local, "Packages", packageGlob))
+		} else {
+			for _, dir := range channelDirs {
+				searched = append(searched, filepath.Join(homeDir, "Library", "Application Support", dir))
+			}
+			searched = append(searched, filepath.Join(homeDir, "Library", "Containers", containerGlob))
+		}
+		return nil, nil, fmt.Errorf("Arc browser data (%s) not found in: %s", FileName, strings.Join(searched, ", "))
+	}
+	return &found[0], found, nil
+}
+
+func windowsLocalAppData(homeDir, localAppData string) string {
+	if localAppData == "" {
+		return filepath.Join(homeDir, "AppData", "Local")
+	}
+	return localAppData
+}
+
+// ResolvePath turns a sidebar path given on the command line (-arc-data)
+// into an absolute one. A leading ~ is expanded, and a directory is taken to
+// be Arc's data folder holding FileName.
+func ResolvePath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve Arc data path %q: %w", path, err)
+	}
+	info, err := os.Stat(abs)
+	if err == nil && info.IsDir() {
+		abs = filepath.Join(abs, FileName)
+		info, err = os.Stat(abs)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("Arc data not found: %s", abs)
+		}
+		return "", fmt.Errorf("could not read Arc data %s: %w", abs, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("Arc data path is a directory: %s", abs)
+	}
+	return abs, nil
+}
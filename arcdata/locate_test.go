@@ -0,0 +1,94 @@
+package arcdata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSidebar(t *testing.T, path string, modified time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	home := t.TempDir()
+	if _, _, err := Locate(home); err == nil || !strings.Contains(err.Error(), "Arc Beta") {
+		t.Errorf("expected an error listing the searched folders, got %v", err)
+	}
+
+	now := time.Now()
+	release := filepath.Join(home, "Library", "Application Support", "Arc", FileName)
+	writeSidebar(t, release, now.Add(-time.Hour))
+	found, candidates, err := Locate(home)
+	if err != nil || found.Path != release || len(candidates) != 1 {
+		t.Fatalf("expected only the release data, got %v, %v, %v", found, candidates, err)
+	}
+
+	beta := filepath.Join(home, "Library", "Application Support", "Arc Beta", FileName)
+	writeSidebar(t, beta, now.Add(-2*time.Hour))
+	sandboxed := filepath.Join(home, "Library", "Containers", "company.thebrowser.Browser", "Data", "Library", "Application Support", "Arc", FileName)
+	writeSidebar(t, sandboxed, now)
+	// Too deep to be Arc's own data
+	writeSidebar(t, filepath.Join(home, "Library", "Containers", "company.thebrowser.Browser", "Data", "a", "b", "c", "d", "e", "f", FileName), now)
+
+	found, candidates, err = Locate(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %+v", candidates)
+	}
+	if found.Path != sandboxed || found.Channel != "company.thebrowser.Browser" {
+		t.Errorf("expected the most recently changed data in the container, got %+v", found)
+	}
+	if candidates[1].Path != release || candidates[2].Path != beta || candidates[2].Channel != "Arc Beta" {
+		t.Errorf("expected candidates newest first, got %+v", candidates)
+	}
+}
+
+func TestLocate_Windows(t *testing.T) {
+	home := t.TempDir()
+	if _, _, err := locate(home, "windows", ""); err == nil || !strings.Contains(err.Error(), filepath.Join(home, "AppData", "Local", "Packages")) {
+		t.Errorf("expected an error listing the Windows folders, got %v", err)
+	}
+
+	// The macOS locations aren't searched on Windows
+	writeSidebar(t, filepath.Join(home, "Library", "Application Support", "Arc", FileName), time.Now())
+	local := filepath.Join(home, "Local")
+	packaged := filepath.Join(local, "Packages", "TheBrowserCompany.Arc_ttt1ap7aakyb4", "LocalCache", "Local", "Arc", FileName)
+	writeSidebar(t, packaged, time.Now().Add(-time.Hour))
+	found, candidates, err := locate(home, "windows", local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Path != packaged || found.Channel != "TheBrowserCompany.Arc_ttt1ap7aakyb4" || len(candidates) != 1 {
+		t.Errorf("expected only the packaged data, got %+v", candidates)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	sidebar := filepath.Join(dir, FileName)
+	if _, err := ResolvePath(sidebar); err == nil {
+		t.Error("expected an error for missing data")
+	}
+	writeSidebar(t, sidebar, time.Now())
+
+	for _, path := range []string{sidebar, dir, "  " + dir + "  "} {
+		got, err := ResolvePath(path)
+		if err != nil || got != sidebar {
+			t.Errorf("ResolvePath(%q) = %q, %v; want %q", path, got, err, sidebar)
+		}
+	}
+}
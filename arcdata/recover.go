@@ -0,0 +1,101 @@
+package arcdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// recoveryCandidates bounds how many cut points near the end of truncated
+// data are tried; damage is expected at the end, where Arc was writing
+const recoveryCandidates = 64
+
+// Recovery describes how damaged sidebar data was repaired
+type Recovery struct {
+	Strategy string // "trailing data" or "truncated"
+	Dropped  int    // Bytes of the original left out
+}
+
+func (r *Recovery) String() string {
+	if r.Strategy == "trailing data" {
+		return fmt.Sprintf("ignored %d bytes of trailing data after the JSON", r.Dropped)
+	}
+	return fmt.Sprintf("file was cut off; dropped the last %d bytes and closed the open objects", r.Dropped)
+}
+
+// Recover salvages JSON that is damaged at the end: a complete value followed
+// by garbage (read with a streaming decoder, which stops after the first
+// value), or a value cut off midway (cut after the last closing brace or
+// bracket that ends an array element and leaves valid JSON once the containers
+// still open are closed). Cutting only after whole array elements keeps
+// items, spaces and containers complete rather than missing their later keys.
+// Valid JSON, and data damaged anywhere else, is returned as an error.
+func Recover(data []byte) ([]byte, *Recovery, error) {
+	if json.Valid(data) {
+		return nil, nil, errors.New("data is valid JSON; nothing to recover")
+	}
+
+	// Trailing data: the first value decodes on its own
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var first json.RawMessage
+	if err := decoder.Decode(&first); err == nil {
+		end := int(decoder.InputOffset())
+		return data[:end], &Recovery{Strategy: "trailing data", Dropped: len(data) - end}, nil
+	}
+
+	// Truncated: remember the last closers and what was still open after each
+	type cut struct {
+		end  int
+		open []byte
+	}
+	var cuts []cut
+	var stack []byte
+	inString, escaped := false, false
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) == 0 {
+				return nil, nil, errors.New("unbalanced closing bracket; data is damaged beyond the end")
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 || stack[len(stack)-1] != '[' {
+				continue // Top-level values decode above; object members are not cut points
+			}
+			cuts = append(cuts, cut{end: i + 1, open: append([]byte(nil), stack...)})
+			if len(cuts) > recoveryCandidates {
+				cuts = cuts[1:]
+			}
+		}
+	}
+
+	for i := len(cuts) - 1; i >= 0; i-- {
+		repaired := append([]byte(nil), data[:cuts[i].end]...)
+		for j := len(cuts[i].open) - 1; j >= 0; j-- {
+			if cuts[i].open[j] == '{' {
+				repaired = append(repaired, '}')
+			} else {
+				repaired = append(repaired, ']')
+			}
+		}
+		if json.Valid(repaired) {
+			return repaired, &Recovery{Strategy: "truncated", Dropped: len(data) - cuts[i].end}, nil
+		}
+	}
+	return nil, nil, errors.New("no valid JSON could be salvaged")
+}
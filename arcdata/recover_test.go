@@ -0,0 +1,46 @@
+package arcdata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	const sidebar = `{"sidebar": {"containers": [{"global": {}}, {"spaces": ["s1", {"id": "s1", "title": "Work"}], "items": ["i1", {"id": "i1", "title": "A \"quoted\" } name"}, "i2", {"id": "i2"`
+
+	tests := []struct {
+		name     string
+		data     string
+		strategy string
+		dropped  int
+		want     string
+	}{
+		{"trailing garbage", `{"a": [1, 2]}` + "\x00\x00garbage", "trailing data", 9, `{"a": [1, 2]}`},
+		{"second value", `{"a": 1} {"b": 2}`, "trailing data", 9, `{"a": 1}`},
+		{"truncated", sidebar, "truncated", len(`, "i2", {"id": "i2"`),
+			`{"sidebar": {"containers": [{"global": {}}, {"spaces": ["s1", {"id": "s1", "title": "Work"}], "items": ["i1", {"id": "i1", "title": "A \"quoted\" } name"}]}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, recovery, err := Recover([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recovery.Strategy != tt.strategy || string(repaired) != tt.want {
+				t.Errorf("got %s %q, want %s %q", recovery.Strategy, repaired, tt.strategy, tt.want)
+			}
+			if recovery.Dropped != tt.dropped {
+				t.Errorf("expected %d bytes dropped, got %d", tt.dropped, recovery.Dropped)
+			}
+			if !json.Valid(repaired) {
+				t.Error("repaired data is not valid JSON")
+			}
+		})
+	}
+
+	for _, data := range []string{`{"valid": true}`, `"unterminated`, `[1, 2`} {
+		if _, _, err := Recover([]byte(data)); err == nil {
+			t.Errorf("expected %q not to be recovered", data)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package arcdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// snapshotAttempts bounds the copies tried while Arc keeps rewriting the file
+	snapshotAttempts = 5
+	// snapshotRetryDelay gives Arc time to finish a write before the next copy
+	snapshotRetryDelay = 200 * time.Millisecond
+)
+
+// Snapshot is a copy of the sidebar file taken without Arc changing it
+// midway, kept in a temp file so it can be decoded as a stream
+type Snapshot struct {
+	Path     string // The copy; removed by Close
+	Attempts int    // Copies taken; more than one means Arc wrote the file meanwhile
+	Stable   bool   // False if every attempt saw the file change
+	Hash     string // SHA-256 of the copy, hex encoded
+}
+
+// TakeSnapshot copies the sidebar file at path to a temp file, comparing
+// size and modification time before and after. If Arc rewrote the file
+// during the copy it retries, up to snapshotAttempts times; the last copy is
+// kept with Stable false if none was clean. The caller closes the snapshot.
+func TakeSnapshot(path string) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	for snapshot.Attempts < snapshotAttempts {
+		if snapshot.Attempts > 0 {
+			time.Sleep(snapshotRetryDelay)
+		}
+		snapshot.Attempts++
+
+		before, err := os.Stat(path)
+		if err != nil {
+			snapshot.Close()
+			return nil, err
+		}
+		copied, size, hash, err := copyToTemp(path)
+		if err != nil {
+			snapshot.Close()
+			return nil, err
+		}
+		after, err := os.Stat(path)
+		if err != nil {
+			os.Remove(copied)
+			snapshot.Close()
+			return nil, err
+		}
+
+		snapshot.Close() // Drop the previous attempt's copy
+		snapshot.Path = copied
+		snapshot.Hash = hash
+		if before.Size() == after.Size() && before.ModTime().Equal(after.ModTime()) && size == after.Size() {
+			snapshot.Stable = true
+			return snapshot, nil
+		}
+	}
+	return snapshot, nil
+}
+
+// Open opens the copy for reading
+func (s *Snapshot) Open() (*os.File, error) {
+	return os.Open(s.Path)
+}
+
+// ReadAll returns the whole copy
+func (s *Snapshot) ReadAll() ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+// Close removes the copy
+func (s *Snapshot) Close() error {
+	if s.Path == "" {
+		return nil
+	}
+	err := os.Remove(s.Path)
+	s.Path = ""
+	return err
+}
+
+// copyToTemp copies path to a temp file and returns its name, size and
+// SHA-256, hashed as it is copied
+func copyToTemp(path string) (string, int64, string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "arc-sidebar-*.json")
+	if err != nil {
+		return "", 0, "", fmt.Errorf("could not create snapshot of %s: %w", path, err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, "", fmt.Errorf("could not copy %s: %w", path, err)
+	}
+	return tmp.Name(), size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Running reports whether the Arc whose sidebar file is at path appears to
+// be open: Chromium keeps a SingletonLock link in User Data while running and
+// removes it on exit. A crash can leave it behind, so this is only a hint.
+func Running(path string) bool {
+	_, err := os.Lstat(filepath.Join(filepath.Dir(path), "User Data", "SingletonLock"))
+	return err == nil
+}
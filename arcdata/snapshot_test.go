@@ -0,0 +1,59 @@
+package arcdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"sidebar": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := TakeSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := snapshot.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.Stable || snapshot.Attempts != 1 || string(data) != `{"sidebar": {}}` || snapshot.Path == path {
+		t.Errorf("expected one clean copy, got %+v: %s", snapshot, data)
+	}
+	if sum := sha256.Sum256(data); snapshot.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the hash of the copy, got %s", snapshot.Hash)
+	}
+	copied := snapshot.Path
+	if err := snapshot.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(copied); !os.IsNotExist(err) {
+		t.Errorf("expected Close to remove the copy, got %v", err)
+	}
+
+	if _, err := TakeSnapshot(filepath.Join(t.TempDir(), FileName)); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error for a missing file, got %v", err)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	if Running(path) {
+		t.Error("expected Arc not running without a SingletonLock")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "User Data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Chromium's lock is a dangling symlink to "host-pid"
+	if err := os.Symlink("host-123", filepath.Join(dir, "User Data", "SingletonLock")); err != nil {
+		t.Fatal(err)
+	}
+	if !Running(path) {
+		t.Error("expected Arc running with a SingletonLock")
+	}
+}
@@ -0,0 +1,123 @@
+// Package arcshare recognizes Arc share links (arc.net/folder/..., shared
+// spaces and easels), which only Arc can open, and resolves shared folders to
+// the links they contain by reading their public share page.
+package arcshare
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sharePaths are the arc.net paths Arc shares folders, spaces and easels
+// under
+var sharePaths = []string{"/folder/", "/space/", "/share/", "/e/"}
+
+// maxPageBytes bounds how much of a share page is read
+const maxPageBytes = 4 << 20
+
+const requestTimeout = 15 * time.Second
+
+// IsShareLink reports whether rawURL is an Arc share link
+func IsShareLink(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+	if host := strings.ToLower(u.Hostname()); host != "arc.net" && host != "www.arc.net" {
+		return false
+	}
+	for _, prefix := range sharePaths {
+		if strings.HasPrefix(u.Path, prefix) && len(u.Path) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Link is a page linked from a share
+type Link struct {
+	Title string
+	URL   string
+}
+
+// Resolver fetches share pages
+type Resolver struct {
+	client *http.Client
+}
+
+// NewResolver returns a Resolver with a bounded HTTP client
+func NewResolver() *Resolver {
+	return &Resolver{client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Resolve fetches the share page at shareURL and returns the pages it links
+// to, in page order and without duplicates. Links back to Arc itself are
+// left out; a share with none left is an error.
+func (r *Resolver) Resolve(ctx context.Context, shareURL string) ([]Link, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, shareURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("share page returned %s", resp.Status)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share page: %w", err)
+	}
+	links := ParseLinks(string(page))
+	if len(links) == 0 {
+		return nil, fmt.Errorf("share page lists no links (it may be private or not a folder)")
+	}
+	return links, nil
+}
+
+var (
+	anchorPattern = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+	tagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// ParseLinks returns the absolute http(s) links of a share page that don't
+// lead back to Arc, titled by their anchor text (or their URL without one)
+func ParseLinks(page string) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	for _, match := range anchorPattern.FindAllStringSubmatch(page, -1) {
+		href := strings.TrimSpace(html.UnescapeString(match[1]))
+		u, err := url.Parse(href)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || isArcHost(u.Hostname()) || seen[href] {
+			continue
+		}
+		seen[href] = true
+		title := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(match[2], " "))), " ")
+		if title == "" {
+			title = href
+		}
+		links = append(links, Link{Title: title, URL: href})
+	}
+	return links
+}
+
+// isArcHost reports whether host belongs to Arc or The Browser Company
+func isArcHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range []string{"arc.net", "thebrowser.company"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
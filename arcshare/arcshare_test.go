@@ -0,0 +1,75 @@
+package arcshare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsShareLink(t *testing.T) {
+	tests := map[string]bool{
+		"https://arc.net/folder/6F1F8D8A-2B8C-4C5E-9E0B-1A2B3C4D5E6F": true,
+		"https://www.arc.net/space/abc":                               true,
+		"https://arc.net/e/123":                                       true,
+		"https://arc.net/folder/":                                     false,
+		"https://arc.net/download":                                    false,
+		"https://example.test/folder/abc":                             false,
+		"arc://folder/abc":                                            false,
+	}
+	for url, want := range tests {
+		if got := IsShareLink(url); got != want {
+			t.Errorf("IsShareLink(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	links := ParseLinks(`<html><body>
+		<a href="https://arc.net/">Get Arc</a>
+		<a class="item" href="https://docs.example.test/a?x=1&amp;y=2"><span>Docs</span> <b>&amp; specs</b></a>
+		<a href='https://blog.example.test/'></a>
+		<a href="/relative">Relative</a>
+		<a href="https://docs.example.test/a?x=1&amp;y=2">Again</a>
+		<a href="https://resources.thebrowser.company/x">Help</a>
+	</body></html>`)
+	want := []Link{
+		{Title: "Docs & specs", URL: "https://docs.example.test/a?x=1&y=2"},
+		{Title: "https://blog.example.test/", URL: "https://blog.example.test/"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d: expected %+v, got %+v", i, want[i], links[i])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/folder/ok":
+			fmt.Fprint(w, `<a href="https://example.test/one">One</a>`)
+		case "/folder/empty":
+			fmt.Fprint(w, `<p>This folder is private</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resolver := NewResolver()
+	links, err := resolver.Resolve(context.Background(), server.URL+"/folder/ok")
+	if err != nil || len(links) != 1 || links[0].URL != "https://example.test/one" {
+		t.Errorf("expected the one link, got %+v, %v", links, err)
+	}
+	if _, err := resolver.Resolve(context.Background(), server.URL+"/folder/empty"); err == nil {
+		t.Error("expected a share page without links to be an error")
+	}
+	if _, err := resolver.Resolve(context.Background(), server.URL+"/folder/missing"); err == nil {
+		t.Error("expected a 404 to be an error")
+	}
+}
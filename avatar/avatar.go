@@ -0,0 +1,55 @@
+// Package avatar draws letter avatars: the first letter of a name on a colored
+// circle, as an SVG data URL that Zen can show as a workspace icon.
+package avatar
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// DefaultBackground is used when no color is given
+const DefaultBackground = "#7c7c7d"
+
+// FirstLetter returns the first letter or digit of name, uppercased, or "" if
+// it has none
+func FirstLetter(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return strings.ToUpper(string(r))
+		}
+	}
+	return ""
+}
+
+// Letter returns an SVG data URL with the first letter of name in white on a
+// background circle ("#rrggbb"), or "" if name has no letter
+func Letter(name, background string) string {
+	letter := FirstLetter(name)
+	if letter == "" {
+		return ""
+	}
+	if !isHexColor(background) {
+		background = DefaultBackground
+	}
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32" viewBox="0 0 32 32">`+
+		`<circle cx="16" cy="16" r="16" fill="%s"/>`+
+		`<text x="16" y="16" dy=".35em" text-anchor="middle" font-family="-apple-system, system-ui, sans-serif" font-size="17" font-weight="600" fill="#fff">%s</text>`+
+		`</svg>`, background, html.EscapeString(letter))
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+}
+
+// isHexColor reports whether s is a #rgb or #rrggbb color
+func isHexColor(s string) bool {
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !unicode.Is(unicode.ASCII_Hex_Digit, r) {
+			return false
+		}
+	}
+	return true
+}
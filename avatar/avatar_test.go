@@ -0,0 +1,52 @@
+package avatar
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func decodeSVG(t *testing.T, dataURL string) string {
+	t.Helper()
+	encoded, ok := strings.CutPrefix(dataURL, "data:image/svg+xml;base64,")
+	if !ok {
+		t.Fatalf("not an SVG data URL: %q", dataURL)
+	}
+	svg, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	return string(svg)
+}
+
+func TestFirstLetter(t *testing.T) {
+	tests := map[string]string{
+		"work":       "W",
+		"  émigré":   "É",
+		"🚀 Launch":   "L",
+		"2024 plans": "2",
+		"✨ ✨":        "",
+		"":           "",
+	}
+	for name, want := range tests {
+		if got := FirstLetter(name); got != want {
+			t.Errorf("FirstLetter(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLetter(t *testing.T) {
+	svg := decodeSVG(t, Letter("research", "#37adff"))
+	if !strings.Contains(svg, `fill="#37adff"`) || !strings.Contains(svg, ">R</text>") {
+		t.Errorf("unexpected avatar: %s", svg)
+	}
+
+	svg = decodeSVG(t, Letter("Home", `red"/><script>`))
+	if !strings.Contains(svg, `fill="`+DefaultBackground+`"`) || strings.Contains(svg, "script") {
+		t.Errorf("expected an invalid color to fall back to the default: %s", svg)
+	}
+
+	if got := Letter("✨", "#fff"); got != "" {
+		t.Errorf("expected no avatar for a name without letters, got %q", got)
+	}
+}
@@ -8,28 +8,31 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"arc-to-zen/paths"
 )
 
 const (
-	backupDirName    = ".arc-to-zen/backups"
-	sessionFileName  = "zen-sessions.jsonlz4"
-	backupTimeFormat = "2006-01-02_15-04-05"
+	backupDirName      = "backups"
+	sessionFileName    = "zen-sessions.jsonlz4"
+	containersFileName = "containers.json"
+	backupTimeFormat   = "2006-01-02_15-04-05"
 )
 
 // BackupInfo represents metadata about a backup
 type BackupInfo struct {
-	Path      string
-	Timestamp time.Time
-	Name      string
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
 }
 
 // getBackupDir returns the path to the backup directory
 func getBackupDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	dataDir, err := paths.DataDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get data directory: %w", err)
 	}
-	return filepath.Join(homeDir, backupDirName), nil
+	return filepath.Join(dataDir, backupDirName), nil
 }
 
 // ensureBackupDir creates the backup directory if it doesn't exist
@@ -72,6 +75,29 @@ func CreateBackup(profilePath string) error {
 	return nil
 }
 
+// CreateContainersBackup creates a timestamped backup of containers.json,
+// next to the session backups. A profile without one has nothing to back up.
+func CreateContainersBackup(profilePath string) error {
+	containersPath := filepath.Join(profilePath, containersFileName)
+	if _, err := os.Stat(containersPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	backupDir, err := ensureBackupDir()
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Format(backupTimeFormat)
+	backupFilename := fmt.Sprintf("containers_%s.json", timestamp)
+	if err := copyFile(containersPath, filepath.Join(backupDir, backupFilename)); err != nil {
+		return fmt.Errorf("failed to back up containers.json: %w", err)
+	}
+
+	fmt.Printf("✓ Backup created: %s\n", backupFilename)
+	return nil
+}
+
 // ListBackups returns a list of all backups sorted chronologically (newest first)
 func ListBackups() ([]BackupInfo, error) {
 	backupDir, err := getBackupDir()
@@ -124,7 +150,8 @@ func RestoreBackup(profilePath string) error {
 	}
 
 	if len(backups) == 0 {
-		return fmt.Errorf("no backups found in ~/.arc-to-zen/backups/")
+		backupDir, _ := getBackupDir()
+		return fmt.Errorf("no backups found in %s", backupDir)
 	}
 
 	// Display backups
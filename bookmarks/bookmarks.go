@@ -0,0 +1,65 @@
+// Package bookmarks writes bookmarks in the Netscape bookmark file format,
+// the bookmarks.html every browser can import
+package bookmarks
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+)
+
+// Bookmark is a link, or a folder of them when Folder is set
+type Bookmark struct {
+	Title    string
+	URL      string    // Empty for folders
+	Added    time.Time // Zero if unknown
+	Folder   bool
+	Children []Bookmark // Folders only, in order
+}
+
+// HTML returns bookmarks as a Netscape bookmark file
+func HTML(bookmarks []Bookmark) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	b.WriteString("<!-- This is an automatically generated file.\n")
+	b.WriteString("     It will be read and overwritten.\n")
+	b.WriteString("     DO NOT EDIT! -->\n")
+	b.WriteString("<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	b.WriteString("<TITLE>Bookmarks</TITLE>\n")
+	b.WriteString("<H1>Bookmarks</H1>\n")
+	writeList(&b, bookmarks, 0)
+	return b.String()
+}
+
+// writeList writes a <DL> of bookmarks, indented by depth
+func writeList(b *strings.Builder, bookmarks []Bookmark, depth int) {
+	indent := strings.Repeat("    ", depth)
+	b.WriteString(indent + "<DL><p>\n")
+	for _, bookmark := range bookmarks {
+		b.WriteString(indent + "    <DT>")
+		if bookmark.Folder {
+			fmt.Fprintf(b, "<H3%s>%s</H3>\n", addDate(bookmark.Added), html.EscapeString(bookmark.Title))
+			writeList(b, bookmark.Children, depth+1)
+			continue
+		}
+		fmt.Fprintf(b, "<A HREF=\"%s\"%s>%s</A>\n", html.EscapeString(bookmark.URL), addDate(bookmark.Added), html.EscapeString(bookmark.Title))
+	}
+	b.WriteString(indent + "</DL><p>\n")
+}
+
+func addDate(added time.Time) string {
+	if added.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" ADD_DATE=\"%d\"", added.Unix())
+}
+
+// Export writes bookmarks to path as a Netscape bookmark file
+func Export(path string, bookmarks []Bookmark) error {
+	if err := os.WriteFile(path, []byte(HTML(bookmarks)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
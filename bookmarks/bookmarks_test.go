@@ -0,0 +1,42 @@
+package bookmarks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTML(t *testing.T) {
+	added := time.Unix(1700000000, 0)
+	got := HTML([]Bookmark{
+		{Title: "Work & Play", Folder: true, Added: added, Children: []Bookmark{
+			{Title: "Search <fast>", URL: "https://example.test/?q=a&b=\"c\""},
+			{Title: "Empty", Folder: true},
+		}},
+		{Title: "Top", URL: "https://example.test/", Added: added},
+	})
+	for _, want := range []string{
+		"<!DOCTYPE NETSCAPE-Bookmark-file-1>\n",
+		"<DL><p>\n    <DT><H3 ADD_DATE=\"1700000000\">Work &amp; Play</H3>\n    <DL><p>\n",
+		"        <DT><A HREF=\"https://example.test/?q=a&amp;b=&#34;c&#34;\">Search &lt;fast&gt;</A>\n",
+		"        <DT><H3>Empty</H3>\n        <DL><p>\n        </DL><p>\n    </DL><p>\n",
+		"    <DT><A HREF=\"https://example.test/\" ADD_DATE=\"1700000000\">Top</A>\n</DL><p>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.html")
+	if err := Export(path, []Bookmark{{Title: "Top", URL: "https://example.test/"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "<A HREF=\"https://example.test/\">Top</A>") {
+		t.Errorf("expected the bookmark written, got %q, %v", data, err)
+	}
+}
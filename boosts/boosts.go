@@ -0,0 +1,273 @@
+// Package boosts reads Arc Boosts (per-site CSS and JavaScript) and exports
+// them in forms Zen can use: a userContent.css, Stylus user styles and
+// userscripts. Boosts can't be imported as they are because Zen has no
+// equivalent, so they are exported for the user to install.
+//
+// Arc keeps each Boost as an unpacked extension: a directory with a
+// manifest.json whose content_scripts list the sites and the CSS and JS files.
+// Older builds and exports keep them as JSON objects with the code inline;
+// those are found by shape.
+package boosts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AllSites is the site of a Boost that runs everywhere
+const AllSites = "*"
+
+// Boost is one Arc Boost
+type Boost struct {
+	Name    string
+	Sites   []string // Domains the Boost runs on (subdomains included), or AllSites
+	CSS     string
+	JS      string
+	Enabled bool
+	Source  string // File the Boost was read from
+}
+
+// DefaultDirs returns where Arc keeps Boosts, under its data directory
+// (~/Library/Application Support/Arc)
+func DefaultDirs(arcDir string) []string {
+	dirs := []string{filepath.Join(arcDir, "boosts"), filepath.Join(arcDir, "Boosts")}
+	profiles, _ := filepath.Glob(filepath.Join(arcDir, "User Data", "*", "Boosts"))
+	return append(dirs, profiles...)
+}
+
+// Load reads the Boosts in path: a directory of Boost extensions, a single
+// manifest.json, or a JSON file of Boost objects. Boosts are sorted by name.
+func Load(path string) ([]Boost, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Boosts: %w", err)
+	}
+
+	var boosts []Boost
+	if !info.IsDir() {
+		boosts, err = loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(file), ".json") {
+				return nil
+			}
+			found, err := loadFile(file)
+			if err != nil {
+				return err
+			}
+			boosts = append(boosts, found...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Boosts in %s: %w", path, err)
+		}
+	}
+
+	sort.SliceStable(boosts, func(i, j int) bool {
+		return strings.ToLower(boosts[i].Name) < strings.ToLower(boosts[j].Name)
+	})
+	return boosts, nil
+}
+
+// loadFile reads one JSON file, as a manifest if it looks like one
+func loadFile(path string) ([]Boost, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		if filepath.Base(path) == "manifest.json" {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return nil, nil // Not a Boost file
+	}
+
+	if manifest, ok := root.(map[string]interface{}); ok {
+		if _, ok := manifest["content_scripts"]; ok {
+			boost, err := fromManifest(path, manifest)
+			if err != nil {
+				return nil, err
+			}
+			return []Boost{boost}, nil
+		}
+	}
+
+	var boosts []Boost
+	walkBoosts(root, func(boost Boost) {
+		boost.Source = path
+		boosts = append(boosts, boost)
+	})
+	return boosts, nil
+}
+
+// fromManifest reads a Boost extension's manifest and the files it lists
+func fromManifest(path string, manifest map[string]interface{}) (Boost, error) {
+	dir := filepath.Dir(path)
+	boost := Boost{Name: stringField(manifest, "name"), Enabled: true, Source: path}
+	if boost.Name == "" {
+		boost.Name = filepath.Base(dir)
+	}
+
+	scripts, _ := manifest["content_scripts"].([]interface{})
+	for _, script := range scripts {
+		entry, ok := script.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, match := range stringList(entry["matches"]) {
+			boost.Sites = appendSite(boost.Sites, siteFromMatch(match))
+		}
+		for _, file := range stringList(entry["css"]) {
+			code, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+			if err != nil {
+				return Boost{}, fmt.Errorf("boost %q: %w", boost.Name, err)
+			}
+			boost.CSS = joinCode(boost.CSS, string(code))
+		}
+		for _, file := range stringList(entry["js"]) {
+			code, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+			if err != nil {
+				return Boost{}, fmt.Errorf("boost %q: %w", boost.Name, err)
+			}
+			boost.JS = joinCode(boost.JS, string(code))
+		}
+	}
+	return boost, nil
+}
+
+// walkBoosts calls add for every object in v that has a site and some code
+func walkBoosts(v interface{}, add func(Boost)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if boost, ok := asBoost(v); ok {
+			add(boost)
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkBoosts(v[key], add)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkBoosts(item, add)
+		}
+	}
+}
+
+// asBoost reads an inline Boost object: a domain (or URL or match patterns)
+// and CSS or JS, under the key names Arc has used
+func asBoost(m map[string]interface{}) (Boost, bool) {
+	var boost Boost
+	for _, key := range []string{"domain", "host", "hostname", "site", "url", "matches"} {
+		for _, value := range stringList(m[key]) {
+			boost.Sites = appendSite(boost.Sites, siteFromMatch(value))
+		}
+	}
+	for key, value := range m {
+		code, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch lower := strings.ToLower(key); {
+		case strings.Contains(lower, "css") || strings.Contains(lower, "style"):
+			boost.CSS = joinCode(boost.CSS, code)
+		case lower == "js" || strings.Contains(lower, "script") || strings.Contains(lower, "javascript"):
+			boost.JS = joinCode(boost.JS, code)
+		}
+	}
+	if len(boost.Sites) == 0 || (boost.CSS == "" && boost.JS == "") {
+		return Boost{}, false
+	}
+
+	boost.Name = stringField(m, "name")
+	if boost.Name == "" {
+		boost.Name = stringField(m, "title")
+	}
+	if boost.Name == "" {
+		boost.Name = boost.Sites[0]
+	}
+	boost.Enabled = true
+	for _, key := range []string{"enabled", "isEnabled"} {
+		if enabled, ok := m[key].(bool); ok {
+			boost.Enabled = enabled
+		}
+	}
+	return boost, true
+}
+
+// siteFromMatch turns a match pattern ("*://*.example.com/*"), URL or domain
+// into a domain, or AllSites
+func siteFromMatch(match string) string {
+	match = strings.TrimSpace(match)
+	if match == "<all_urls>" {
+		return AllSites
+	}
+	if _, rest, ok := strings.Cut(match, "://"); ok {
+		match = rest
+	}
+	host, _, _ := strings.Cut(match, "/")
+	host, _, _ = strings.Cut(host, ":")
+	host = strings.TrimPrefix(strings.ToLower(host), "*.")
+	host = strings.TrimPrefix(host, "www.")
+	if host == "" || host == "*" {
+		return AllSites
+	}
+	return host
+}
+
+func appendSite(sites []string, site string) []string {
+	for _, existing := range sites {
+		if existing == site {
+			return sites
+		}
+	}
+	return append(sites, site)
+}
+
+func joinCode(existing, code string) string {
+	code = strings.TrimSpace(code)
+	if existing == "" || code == "" {
+		return existing + code
+	}
+	return existing + "\n\n" + code
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// stringList reads a string or a list of strings
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
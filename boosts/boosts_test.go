@@ -0,0 +1,79 @@
+package boosts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_ManifestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "abc", "manifest.json"), `{
+		"name": "Quiet News",
+		"manifest_version": 3,
+		"content_scripts": [{"matches": ["*://*.news.test/*", "https://www.news.test/*"], "css": ["styles.css"], "js": ["content.js"]}]
+	}`)
+	writeFile(t, filepath.Join(dir, "abc", "styles.css"), ".ads { display: none; }\n")
+	writeFile(t, filepath.Join(dir, "abc", "content.js"), "console.log('boosted');\n")
+	writeFile(t, filepath.Join(dir, "inline.json"), `{"boosts": [
+		{"name": "Everywhere", "matches": ["<all_urls>"], "customCSS": "body { font-size: 18px; }", "isEnabled": false}
+	]}`)
+
+	boosts, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(boosts) != 2 {
+		t.Fatalf("expected 2 Boosts, got %+v", boosts)
+	}
+
+	everywhere, news := boosts[0], boosts[1]
+	if everywhere.Name != "Everywhere" || everywhere.Enabled || everywhere.Sites[0] != AllSites {
+		t.Errorf("unexpected inline Boost %+v", everywhere)
+	}
+	if news.Name != "Quiet News" || len(news.Sites) != 1 || news.Sites[0] != "news.test" {
+		t.Errorf("unexpected manifest Boost %+v", news)
+	}
+	if news.CSS != ".ads { display: none; }" || news.JS != "console.log('boosted');" {
+		t.Errorf("expected the listed files' code, got CSS %q JS %q", news.CSS, news.JS)
+	}
+}
+
+func TestExport(t *testing.T) {
+	boosts := []Boost{
+		{Name: "Quiet News", Sites: []string{"news.test"}, CSS: ".ads { display: none; }", JS: "run();", Enabled: true},
+		{Name: "Off", Sites: []string{"off.test"}, CSS: "body { color: red; }"},
+	}
+	dir := t.TempDir()
+	written, err := Export(dir, boosts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(written) != 4 {
+		t.Errorf("expected userContent.css, 2 styles and 1 script, got %v", written)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "userContent.css"))
+	if !strings.Contains(string(content), "@-moz-document domain(\"news.test\") {\n  .ads { display: none; }\n}") {
+		t.Errorf("expected scoped CSS in userContent.css, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "color: red") {
+		t.Error("disabled Boosts should not be in userContent.css")
+	}
+
+	script, _ := os.ReadFile(filepath.Join(dir, "Quiet-News.user.js"))
+	if !strings.Contains(string(script), "// @match       *://*.news.test/*") {
+		t.Errorf("expected match rules in the userscript, got:\n%s", script)
+	}
+}
@@ -0,0 +1,151 @@
+package boosts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// userContentFile is the file Zen reads global page CSS from, in the
+// profile's chrome directory (needs toolkit.legacyUserProfileCustomizations.stylesheets)
+const userContentFile = "userContent.css"
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// UserContent returns the CSS of all enabled Boosts as userContent.css, each
+// scoped to its sites with @-moz-document
+func UserContent(boosts []Boost) string {
+	var b strings.Builder
+	b.WriteString("/* Arc Boosts exported by arc-to-zen */\n")
+	for _, boost := range boosts {
+		if !boost.Enabled || boost.CSS == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n/* %s */\n", commentSafe(boost.Name))
+		writeScopedCSS(&b, boost)
+	}
+	return b.String()
+}
+
+// UserStyle returns a Boost's CSS as a Stylus user style (.user.css), or ""
+// if it has none
+func UserStyle(boost Boost) string {
+	if boost.CSS == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("/* ==UserStyle==\n")
+	fmt.Fprintf(&b, "@name        Arc Boost: %s\n", commentSafe(boost.Name))
+	b.WriteString("@namespace   arc-to-zen\n")
+	b.WriteString("@version     1.0.0\n")
+	b.WriteString("==/UserStyle== */\n\n")
+	writeScopedCSS(&b, boost)
+	return b.String()
+}
+
+// UserScript returns a Boost's JavaScript as a userscript (.user.js) for
+// Violentmonkey or Tampermonkey, or "" if it has none
+func UserScript(boost Boost) string {
+	if boost.JS == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("// ==UserScript==\n")
+	fmt.Fprintf(&b, "// @name        Arc Boost: %s\n", strings.ReplaceAll(boost.Name, "\n", " "))
+	b.WriteString("// @namespace   arc-to-zen\n")
+	b.WriteString("// @version     1.0.0\n")
+	for _, site := range boost.Sites {
+		if site == AllSites {
+			b.WriteString("// @match       *://*/*\n")
+			continue
+		}
+		fmt.Fprintf(&b, "// @match       *://%s/*\n// @match       *://*.%s/*\n", site, site)
+	}
+	b.WriteString("// @grant       none\n")
+	b.WriteString("// ==/UserScript==\n\n")
+	b.WriteString(boost.JS)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// Export writes userContent.css and a user style and userscript per Boost
+// into dir, and returns the files written
+func Export(dir string, boosts []Boost) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var written []string
+	write := func(name, content string) error {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+		return nil
+	}
+
+	if err := write(userContentFile, UserContent(boosts)); err != nil {
+		return written, err
+	}
+	used := make(map[string]int)
+	for _, boost := range boosts {
+		base := fileName(boost.Name)
+		used[base]++
+		if used[base] > 1 {
+			base = fmt.Sprintf("%s-%d", base, used[base])
+		}
+		if style := UserStyle(boost); style != "" {
+			if err := write(base+".user.css", style); err != nil {
+				return written, err
+			}
+		}
+		if script := UserScript(boost); script != "" {
+			if err := write(base+".user.js", script); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// writeScopedCSS writes the Boost's CSS wrapped in @-moz-document for its sites
+func writeScopedCSS(b *strings.Builder, boost Boost) {
+	var rules []string
+	for _, site := range boost.Sites {
+		if site == AllSites {
+			rules = nil
+			break
+		}
+		rules = append(rules, fmt.Sprintf("domain(%q)", site))
+	}
+	if len(rules) == 0 {
+		b.WriteString(boost.CSS)
+		b.WriteString("\n")
+		return
+	}
+	fmt.Fprintf(b, "@-moz-document %s {\n", strings.Join(rules, ", "))
+	for _, line := range strings.Split(boost.CSS, "\n") {
+		if line != "" {
+			b.WriteString("  ")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+}
+
+// commentSafe keeps a name from ending a CSS comment
+func commentSafe(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "*/", "* /"), "\n", " ")
+}
+
+func fileName(name string) string {
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		return "boost"
+	}
+	return name
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"arc-to-zen/bookmarks"
+)
+
+// defaultBookmarksFile is where export-bookmarks writes without -out
+const defaultBookmarksFile = "arc-bookmarks.html"
+
+// runExportBookmarks handles the "export-bookmarks" subcommand and returns
+// the exit code
+func runExportBookmarks(args []string) int {
+	fs := flag.NewFlagSet("export-bookmarks", flag.ContinueOnError)
+	fs.Usage = printExportBookmarksUsage
+	arcProfile := fs.String("arc-profile", "", "Export only spaces of this Arc profile")
+	out := fs.String("out", defaultBookmarksFile, "File to write the bookmarks to")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printExportBookmarksUsage()
+		return 1
+	}
+
+	arcDataPath, imp, err := arcDataImporter(fs.Arg(0), *arcProfile, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	export, err := imp.Bookmarks(arcDataPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := bookmarks.Export(*out, export.Bookmarks); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✓ Exported the Arc bookmarks to %s: %d tabs, %d folders\n", *out, export.Tabs, export.Folders)
+	if export.NoURL > 0 {
+		fmt.Printf("  • %d tabs without a URL were left out\n", export.NoURL)
+	}
+	fmt.Println("  Import it in any browser, e.g. in Zen or Firefox: Bookmarks → Manage Bookmarks →")
+	fmt.Println("  Import and Backup → Import Bookmarks from HTML")
+	return 0
+}
+
+func printExportBookmarksUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen export-bookmarks [-arc-profile name] [-out file] [StorableSidebar.json]")
+	fmt.Println("")
+	fmt.Println("Writes Arc's spaces, folders, tabs and favorites as a Netscape bookmarks")
+	fmt.Println("HTML file (default: ./" + defaultBookmarksFile + "), a folder per space, that any")
+	fmt.Println("browser can import. Nothing is read from or written to a Zen profile, so")
+	fmt.Println("it works even when importing into the session does not.")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"arc-to-zen/arcdata"
+	"arc-to-zen/boosts"
+)
+
+// runBoosts handles the "boosts" subcommand and returns the exit code
+func runBoosts(args []string) int {
+	if len(args) == 0 {
+		printBoostsUsage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("boosts "+args[0], flag.ContinueOnError)
+	fs.Usage = printBoostsUsage
+	out := fs.String("out", "arc-boosts", "Directory to export the Boosts into")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printBoostsUsage()
+		return 1
+	}
+
+	found, path, err := loadBoosts(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if path == "" {
+		fmt.Println("No Arc Boosts found. Pass the Boosts directory or file if Arc keeps them elsewhere.")
+		return 0
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Printf("%d Arc Boosts in %s:\n", len(found), path)
+		for _, boost := range found {
+			var kinds []string
+			if boost.CSS != "" {
+				kinds = append(kinds, "CSS")
+			}
+			if boost.JS != "" {
+				kinds = append(kinds, "JS")
+			}
+			state := ""
+			if !boost.Enabled {
+				state = " (disabled)"
+			}
+			fmt.Printf("  • %s%s: %s [%s]\n", boost.Name, state, strings.Join(boost.Sites, ", "), strings.Join(kinds, ", "))
+		}
+		return 0
+	case "export":
+		written, err := boosts.Export(*out, found)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Exported %d Arc Boosts to %s (%d files)\n", len(found), *out, len(written))
+		fmt.Println("  • userContent.css: copy into <zen profile>/chrome/ and set")
+		fmt.Println("    toolkit.legacyUserProfileCustomizations.stylesheets to true in about:config")
+		fmt.Println("  • *.user.css: install in Stylus instead, one style per Boost")
+		fmt.Println("  • *.user.js: install in Violentmonkey or Tampermonkey (Zen can't run page scripts itself)")
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown boosts command: %s\n\n", args[0])
+		printBoostsUsage()
+		return 1
+	}
+}
+
+// loadBoosts reads the Boosts at path, or at Arc's default locations if path
+// is empty. It returns the path read ("" if nothing was found).
+func loadBoosts(path string) ([]boosts.Boost, string, error) {
+	if path != "" {
+		found, err := boosts.Load(path)
+		return found, path, err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	// Boosts live next to the sidebar data of whichever Arc build is installed
+	arcDirs := []string{filepath.Join(homeDir, "Library", "Application Support", "Arc")}
+	for _, candidate := range arcdata.Candidates(homeDir) {
+		arcDirs = append(arcDirs, filepath.Dir(candidate.Path))
+	}
+	for _, arcDir := range arcDirs {
+		for _, dir := range boosts.DefaultDirs(arcDir) {
+			if _, err := os.Stat(dir); err != nil {
+				continue
+			}
+			found, err := boosts.Load(dir)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(found) > 0 {
+				return found, dir, nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+func printBoostsUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen boosts list [boosts-dir|file]")
+	fmt.Println("  arc-to-zen boosts export [-out dir] [boosts-dir|file]")
+	fmt.Println("")
+	fmt.Println("Arc Boosts (per-site CSS and JavaScript) have no Zen equivalent. `export`")
+	fmt.Println("writes their CSS as userContent.css and Stylus user styles, and their")
+	fmt.Println("JavaScript as userscripts (default dir: ./arc-boosts).")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/importer"
+	"arc-to-zen/lock"
+	"arc-to-zen/manifest"
+	"arc-to-zen/places"
+	"arc-to-zen/upgrade"
+)
+
+// capabilitiesReport is what this build of arc-to-zen supports, for
+// frontends adapting to the installed binary
+type capabilitiesReport struct {
+	Version  string              `json:"version"`
+	Platform string              `json:"platform"` // GOOS/GOARCH
+	Sources  []sourceInfo        `json:"sources"`
+	Targets  []targetInfo        `json:"targets"`
+	Schema   importer.Schema     `json:"schema"`
+	Upgrades []upgrade.Kind      `json:"upgrades"` // Artifacts of older versions upgrade-session repairs
+	Features map[string]bool     `json:"features"` // Optional and platform-dependent features, and whether this build has them
+	Choices  map[string][]string `json:"choices"`  // Values accepted by the options that take one of a set, by flag name
+	Flags    []flagInfo          `json:"flags"`
+	Commands []string            `json:"commands"`
+}
+
+type sourceInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Files       []string `json:"files"`
+	Platforms   []string `json:"platforms"` // Where it is found without a path (-arc-data)
+}
+
+type targetInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Files       []string `json:"files"`
+}
+
+type flagInfo struct {
+	Name    string `json:"name"`
+	Arg     string `json:"arg,omitempty"` // Placeholder of its value; empty for booleans
+	Default string `json:"default,omitempty"`
+	Usage   string `json:"usage"`
+	Group   string `json:"group"` // help topic
+}
+
+// runCapabilities handles the "capabilities" subcommand and returns the exit
+// code
+func runCapabilities(args []string) int {
+	fs := flag.NewFlagSet("capabilities", flag.ContinueOnError)
+	fs.Usage = printCapabilitiesUsage
+	asJSON := fs.Bool("json", false, "Print the capabilities as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 0 {
+		printCapabilitiesUsage()
+		return 1
+	}
+
+	report := capabilities()
+	if *asJSON {
+		return printJSON(report)
+	}
+	fmt.Printf("arc-to-zen %s (%s)\n", report.Version, report.Platform)
+	fmt.Println("")
+	fmt.Println("Sources:")
+	for _, source := range report.Sources {
+		fmt.Printf("  %-14s %s (%s; found on %s)\n", source.Name, source.Description, strings.Join(source.Files, ", "), strings.Join(source.Platforms, ", "))
+	}
+	fmt.Println("Targets:")
+	for _, target := range report.Targets {
+		fmt.Printf("  %-14s %s (%s)\n", target.Name, target.Description, strings.Join(target.Files, ", "))
+	}
+	fmt.Println("Session:")
+	fmt.Printf("  %s (%s), containers.json version %d, %s version %d\n", report.Schema.SessionFile, report.Schema.SessionFormat,
+		report.Schema.ContainersVersion, manifest.FileName, report.Schema.ManifestVersion)
+	fmt.Printf("  Pinned tab icons for Zen %s and newer\n", report.Schema.PinnedIconsSince)
+	fmt.Println("Features:")
+	for _, name := range sortedKeys(report.Features) {
+		mark := "✓"
+		if !report.Features[name] {
+			mark = "✗"
+		}
+		fmt.Printf("  %s %s\n", mark, name)
+	}
+	fmt.Println("Choices:")
+	for _, name := range sortedKeys(report.Choices) {
+		fmt.Printf("  -%-14s %s\n", name, strings.Join(report.Choices[name], ", "))
+	}
+	fmt.Printf("Commands: %s\n", strings.Join(report.Commands, ", "))
+	return 0
+}
+
+func capabilities() capabilitiesReport {
+	report := capabilitiesReport{
+		Version:  manifest.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Sources: []sourceInfo{
+			{Name: "arc", Description: "Arc spaces, folders, pinned tabs and favorites", Files: []string{"StorableSidebar.json"}, Platforms: []string{"darwin", "windows"}},
+			{Name: "arc-boosts", Description: "Arc Boosts, exported as styles and userscripts", Files: []string{"Boosts"}, Platforms: []string{"darwin", "windows"}},
+		},
+		Targets: []targetInfo{
+			{Name: "zen", Description: "Zen workspaces, folders, pinned tabs, essentials and containers",
+				Files: []string{importer.SessionSchema().SessionFile, "containers.json", manifest.FileName}},
+			{Name: "zen-bookmarks", Description: "Zen bookmarks, beside the session (-also-bookmarks)", Files: []string{places.FileName}},
+			{Name: "zen-favicons", Description: "Zen's favicon database, instead of the session (-favicon-storage sqlite)", Files: []string{places.FaviconsFileName}},
+			{Name: "bookmarks", Description: "Netscape bookmarks HTML any browser imports (export-bookmarks)", Files: []string{defaultBookmarksFile}},
+		},
+		Schema:   importer.SessionSchema(),
+		Upgrades: upgrade.Kinds,
+		Features: map[string]bool{
+			"interactive":        true,
+			"json":               true,
+			"disk-space-check":   importer.DiskSpaceCheck,
+			"lock-process-check": lock.ProcessCheck,
+			"favicon-cache-lock": favicon.CrossProcessLock,
+		},
+		Choices: map[string][]string{
+			"mode":             {importer.ModeWorkspaces, importer.ModeFolders},
+			"merge":            {importer.MergeReplace, importer.MergeAppend, importer.MergeDedupe, importer.MergeSkip},
+			"sort":             {importer.SortNone, importer.SortAlpha, importer.SortDomain, importer.SortRecent},
+			"only":             {importer.OnlyFolders, importer.OnlyTabs},
+			"empty-urls":       {importer.EmptyURLSkip, importer.EmptyURLKeep, importer.EmptyURLNote},
+			"share-links":      {importer.ShareLinkSkip, importer.ShareLinkKeep, importer.ShareLinkResolve},
+			"favicon-provider": favicon.Providers,
+			"empty-folders":    {importer.EmptyFolderSkip, importer.EmptyFolderKeep},
+			"theme":            {importer.ThemeGradient, importer.ThemeSolid, importer.ThemeNone},
+			"favicon-storage":  {importer.FaviconStorageSession, importer.FaviconStorageSQLite},
+			"over-budget":      {importer.OverBudgetWarn, importer.OverBudgetDownscale, importer.OverBudgetSkipFavicons, importer.OverBudgetFail},
+			"exclude":          {importer.CleanupDuplicates, importer.CleanupLocalhost, importer.CleanupEmptyFolders, importer.CleanupLargeSpaces},
+		},
+	}
+	for _, group := range groupedFlags() {
+		for _, doc := range group.Flags {
+			f := flag.Lookup(doc.Name)
+			if f == nil {
+				continue
+			}
+			report.Flags = append(report.Flags, flagInfo{Name: f.Name, Arg: doc.Arg, Default: flagDefault(f), Usage: f.Usage, Group: group.Topic})
+		}
+	}
+	for _, cmd := range commands {
+		report.Commands = append(report.Commands, cmd.Name)
+	}
+	return report
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printCapabilitiesUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen capabilities [-json]")
+	fmt.Println("")
+	fmt.Println("Lists what this build supports: where it reads Arc data from, the Zen")
+	fmt.Println("files it writes and their formats, optional and platform-dependent")
+	fmt.Println("features, the values each option accepts, and every flag and command.")
+	fmt.Println("Frontends can run it with -json to adapt to the installed binary.")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"arc-to-zen/importer"
+)
+
+// cleanupExamples is how many findings of each category are listed without -all
+const cleanupExamples = 10
+
+// runCleanup handles the "cleanup" subcommand and returns the exit code
+func runCleanup(args []string) int {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	fs.Usage = printCleanupUsage
+	arcProfile := fs.String("arc-profile", "", "Check only spaces of this Arc profile")
+	all := fs.Bool("all", false, fmt.Sprintf("List every finding, not just %d per category", cleanupExamples))
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printCleanupUsage()
+		return 1
+	}
+
+	arcDataPath, imp, err := arcDataImporter(fs.Arg(0), *arcProfile, *asJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	report, err := imp.Cleanup(arcDataPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(report)
+	}
+
+	fmt.Println("")
+	if len(report.Findings) == 0 {
+		fmt.Println("✓ Nothing that looks like junk")
+		return 0
+	}
+	descriptions := map[string]string{
+		importer.CleanupDuplicates:   "tabs whose URL an earlier tab already has",
+		importer.CleanupLocalhost:    "tabs on localhost, likely dead dev servers",
+		importer.CleanupEmptyFolders: "folders without a single tab",
+		importer.CleanupLargeSpaces:  fmt.Sprintf("spaces with %d or more items", importer.LargeSpaceItems),
+	}
+	var found []string
+	for _, category := range importer.CleanupCategories {
+		findings := report.Category(category)
+		if len(findings) == 0 {
+			continue
+		}
+		found = append(found, category)
+		fmt.Printf("%s: %d %s\n", category, len(findings), descriptions[category])
+		for i, finding := range findings {
+			if i == cleanupExamples && !*all {
+				fmt.Printf("  … and %d more (use -all to list them)\n", len(findings)-i)
+				break
+			}
+			if category == importer.CleanupLargeSpaces {
+				fmt.Printf("  • %q: %s\n", finding.Title, finding.Detail)
+				continue
+			}
+			fmt.Printf("  • %q in %q: %s\n", finding.Title, finding.Space, finding.Detail)
+		}
+		fmt.Println("")
+	}
+	fmt.Println("Prune them in Arc, or leave them out of the import with:")
+	fmt.Printf("  arc-to-zen -exclude %s\n", strings.Join(found, ","))
+	return 0
+}
+
+func printCleanupUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen cleanup [-arc-profile name] [-all] [-json] [StorableSidebar.json]")
+	fmt.Println("")
+	fmt.Println("Reports Arc data that is likely junk, with counts: duplicate URLs, localhost")
+	fmt.Println("links, empty folders and spaces of 500+ items. Pass the categories to the")
+	fmt.Println("import's -exclude flag to leave them out.")
+}
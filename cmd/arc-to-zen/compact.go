@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"arc-to-zen/backup"
+	"arc-to-zen/compact"
+	"arc-to-zen/lock"
+	"arc-to-zen/places"
+)
+
+// runCompact handles the "compact" subcommand and returns the exit code
+func runCompact(args []string) int {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.Usage = printCompactUsage
+	dryRun := fs.Bool("dry-run", false, "Show how much smaller the session would get without writing it")
+	moveFavicons := fs.Bool("move-favicons", false, "Move the favicons into the profile's favicons.sqlite instead of dropping them, so tabs keep their icons")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printCompactUsage()
+		return 1
+	}
+
+	zenProfilePath, err := resolveProfilePath(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\nPlease provide a profile path or use --list to see available profiles.\n")
+		return 1
+	}
+
+	if !*dryRun {
+		profileLock, err := lock.Acquire(zenProfilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer profileLock.Release()
+
+		if err := backup.CreateBackup(zenProfilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: backup failed: %v\n", err)
+			return 1
+		}
+	}
+
+	result, err := compact.Profile(zenProfilePath, compact.Options{DryRun: *dryRun, MoveFavicons: *moveFavicons})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if result.Images == 0 {
+		fmt.Println("No embedded favicons found; the session is already compact.")
+		return 0
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "[DRY-RUN] Would remove"
+	}
+	fmt.Printf("✓ %s %d embedded favicons from %d tabs\n", verb, result.Images, result.Tabs)
+	if *moveFavicons {
+		fmt.Printf("  Favicons moved to %s: %d\n", places.FaviconsFileName, result.Moved)
+	}
+	fmt.Printf("  Session size: %s → %s\n", formatSize(result.SizeBefore), formatSize(result.SizeAfter))
+	if !*dryRun {
+		fmt.Println("Restart Zen Browser; favicons come back as the tabs load.")
+	}
+	return 0
+}
+
+// formatSize formats a byte count as "1.2 MB"
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func printCompactUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen compact [-dry-run] [-move-favicons] [profile-path]")
+	fmt.Println("")
+	fmt.Println("Shrinks zen-sessions.jsonlz4 by removing the favicon images that older")
+	fmt.Println("versions embedded in every imported tab. The session is backed up first.")
+	fmt.Println("With -move-favicons the images go into Zen's favicons.sqlite, which the")
+	fmt.Println("tabs then load them from. Close Zen before running it.")
+}
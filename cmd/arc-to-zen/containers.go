@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"arc-to-zen/backup"
+	"arc-to-zen/containers"
+	"arc-to-zen/lock"
+)
+
+// runContainers handles the "containers" subcommand and returns the exit code
+func runContainers(args []string) int {
+	if len(args) == 0 {
+		printContainersUsage()
+		return 1
+	}
+
+	action, args := args[0], args[1:]
+
+	var required int
+	switch action {
+	case "list":
+		required = 0
+	case "rename", "recolor":
+		required = 2
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown containers command: %s\n\n", action)
+		printContainersUsage()
+		return 1
+	}
+
+	if len(args) < required || len(args) > required+1 {
+		printContainersUsage()
+		return 1
+	}
+
+	zenProfilePath, err := resolveProfilePath(args[required:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\nPlease provide a profile path or use --list to see available profiles.\n")
+		return 1
+	}
+
+	// Hold the lock from reading to writing, so an import can't change the
+	// file in between
+	if action != "list" {
+		profileLock, err := lock.Acquire(zenProfilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer profileLock.Release()
+	}
+
+	data, err := containers.Load(zenProfilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch action {
+	case "list":
+		fmt.Print(containers.List(data))
+		if err := containers.Validate(data); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		return 0
+	case "rename":
+		err = containers.Rename(data, args[0], args[1])
+	case "recolor":
+		err = containers.Recolor(data, args[0], args[1])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if err := backup.CreateContainersBackup(zenProfilePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: backup failed: %v\n", err)
+		return 1
+	}
+	if err := containers.Save(zenProfilePath, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("✓ Updated containers.json")
+	if err := containers.Validate(data); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Println("Restart Zen Browser to see the changes.")
+	return 0
+}
+
+func printContainersUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen containers list [zen-profile-path]")
+	fmt.Println("  arc-to-zen containers rename <id|name> <new-name> [zen-profile-path]")
+	fmt.Println("  arc-to-zen containers recolor <id|name> <color> [zen-profile-path]")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"arc-to-zen/arcdata"
+	"arc-to-zen/favicon"
+	"arc-to-zen/importer"
+)
+
+// runCount handles the "count" subcommand and returns the exit code
+func runCount(args []string) int {
+	fs := flag.NewFlagSet("count", flag.ContinueOnError)
+	fs.Usage = printCountUsage
+	arcProfile := fs.String("arc-profile", "", "Count only spaces of this Arc profile")
+	asJSON := fs.Bool("json", false, "Print the count as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printCountUsage()
+		return 1
+	}
+
+	arcDataPath, imp, err := arcDataImporter(fs.Arg(0), *arcProfile, *asJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	count, err := imp.Count(arcDataPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(count)
+	}
+
+	fmt.Println("")
+	fmt.Printf("  Spaces:        %d\n", count.Spaces)
+	fmt.Printf("  Folders:       %d\n", count.Folders)
+	fmt.Printf("  Tabs:          %d", count.Tabs)
+	if count.NoURL > 0 {
+		fmt.Printf(" (%d without a URL, skipped by default)", count.NoURL)
+	}
+	fmt.Println()
+	fmt.Printf("  Unique hosts:  %d\n", count.Hosts)
+	fmt.Printf("  Favicons:      %d cached, %d to fetch\n", count.Favicons.Cached, count.Favicons.Missing)
+	if count.Estimate < time.Second {
+		fmt.Println("  Import time:   under a second")
+	} else {
+		fmt.Printf("  Import time:   ~%s\n", count.Estimate.Round(time.Second))
+	}
+	if count.Unparsed > 0 {
+		fmt.Printf("  Not understood: %d items (see -unparsed-items)\n", count.Unparsed)
+	}
+	if len(count.PerSpace) > 1 {
+		fmt.Println("")
+		fmt.Println("Per space:")
+		for _, space := range count.PerSpace {
+			fmt.Printf("  • %q (%s): %d folders, %d tabs\n", space.Name, space.Profile, space.Folders, space.Tabs)
+		}
+	}
+	return 0
+}
+
+// arcDataImporter returns the Arc data path (located if arcDataPath is
+// empty) and an importer for reading it without a Zen profile: cache-only,
+// like a dry run, with the domain rules deciding what would be fetched.
+// quiet drops its progress output.
+func arcDataImporter(arcDataPath, arcProfile string, quiet bool) (string, *importer.Importer, error) {
+	if arcDataPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil, fmt.Errorf("could not determine home directory: %w", err)
+		}
+		arcData, _, err := arcdata.Locate(homeDir)
+		if err != nil {
+			return "", nil, err
+		}
+		arcDataPath = arcData.Path
+	}
+
+	opts := importer.ImportOptions{DryRun: true, ArcProfile: arcProfile}
+	if rulesPath, err := favicon.DefaultDomainRulesPath(); err == nil {
+		rules, err := favicon.LoadDomainRules(rulesPath)
+		if err != nil {
+			return "", nil, err
+		}
+		opts.FaviconDomains = rules
+	}
+	var logger importer.Logger
+	if quiet {
+		logger = quietLogger{}
+	}
+	return arcDataPath, importer.NewWithOptions("", logger, opts), nil
+}
+
+// quietLogger drops the importer's progress output so -json prints only JSON
+type quietLogger struct{}
+
+func (quietLogger) Info(format string, args ...interface{})  {}
+func (quietLogger) Error(format string, args ...interface{}) {}
+
+func printCountUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen count [-arc-profile name] [-json] [StorableSidebar.json]")
+	fmt.Println("")
+	fmt.Println("Counts the spaces, folders, tabs and unique hosts an import would bring")
+	fmt.Println("over and estimates how long it would take, without reading or writing a")
+	fmt.Println("Zen profile. The estimate is mostly the favicons not cached yet.")
+}
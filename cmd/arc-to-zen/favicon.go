@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"arc-to-zen/favicon"
+)
+
+// defaultFaviconProviders is the default of -favicon-provider: the site
+// first, then the public icon services
+var defaultFaviconProviders = strings.Join(favicon.Providers, ",")
+
+// runFavicon handles the "favicon" subcommand and returns the exit code
+func runFavicon(args []string) int {
+	if len(args) == 0 || args[0] != "fetch" {
+		printFaviconUsage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("favicon fetch", flag.ContinueOnError)
+	fs.Usage = printFaviconUsage
+	urlsPath := fs.String("urls", "", "File with one URL per line")
+	workers := fs.Int("workers", 10, "Number of concurrent fetches")
+	allowPrivateHosts := fs.Bool("allow-private-hosts", false, "Also fetch from localhost and private network addresses")
+	faviconProvider := fs.String("favicon-provider", defaultFaviconProviders, "Where to look for favicons, in order (comma-separated)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+	if *urlsPath == "" || fs.NArg() > 0 {
+		printFaviconUsage()
+		return 1
+	}
+
+	urls, err := readURLList(*urlsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(urls) == 0 {
+		fmt.Println("No URLs found in", *urlsPath)
+		return 0
+	}
+
+	providers, err := favicon.ParseProviders(*faviconProvider)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	f := favicon.NewWithOptions(favicon.Options{Providers: providers})
+	defer f.Close()
+	f.SetBlockPrivateHosts(!*allowPrivateHosts)
+	if rulesPath, err := favicon.DefaultDomainRulesPath(); err == nil {
+		rules, err := favicon.LoadDomainRules(rulesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		f.SetDomainRules(rules)
+	}
+
+	fmt.Printf("Fetching favicons for %d URLs...\n", len(urls))
+	spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	spinIdx := 0
+	result := f.PreCacheFaviconsWithProgress(urls, *workers, func(p favicon.Progress) {
+		fmt.Printf("\r%s %-60s", spinner[spinIdx%len(spinner)], p)
+		spinIdx++
+	})
+	fmt.Printf("\r%-62s\r", "")
+
+	fmt.Printf("✓ Favicon cache warmed: %d cached, %d fetched, %d failed\n", result.Cached, result.Fetched, result.Failed)
+	if result.Denied > 0 {
+		fmt.Printf("  Skipped %d URLs on denied domains\n", result.Denied)
+	}
+	if result.Blocked > 0 {
+		fmt.Printf("  Skipped %d URLs on private/local hosts (use -allow-private-hosts to fetch them)\n", result.Blocked)
+	}
+	return 0
+}
+
+// readURLList reads one URL per line, skipping blank lines, # comments and duplicates
+func readURLList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	defer file.Close()
+
+	var urls []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	return urls, nil
+}
+
+func printFaviconUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen favicon fetch -urls <file> [-workers N] [-allow-private-hosts] [-favicon-provider list]")
+	fmt.Println("")
+	fmt.Println("Pre-warms the favicon cache from a list of URLs (one per line) so a later")
+	fmt.Println("import doesn't need the network for them.")
+}
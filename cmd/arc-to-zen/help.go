@@ -0,0 +1,513 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The usage, `help <topic>` and the man page are all generated from the
+// metadata below. Flag descriptions and defaults come from the flags
+// themselves, so they can't drift from what the flags do; a flag missing
+// from flagGroups still shows up, under "Other options".
+
+// flagGroup is a section of the options of an import in the help
+type flagGroup struct {
+	Topic string // Name for `help <topic>`
+	Title string
+	Flags []flagDoc
+}
+
+// flagDoc names a flag of flagGroups and the placeholder of its value
+// (empty for booleans)
+type flagDoc struct {
+	Name string
+	Arg  string
+}
+
+// commandDoc is a subcommand: how it's dispatched, what it's listed as in
+// the usage, and its own detailed usage
+type commandDoc struct {
+	Name  string
+	Lines []usageLine
+	Run   func(args []string) int // nil for those main runs after defining its flags
+	Usage func()
+}
+
+type usageLine struct {
+	Synopsis string // After "arc-to-zen "
+	Summary  string
+}
+
+type exampleDoc struct {
+	Comment  string
+	Commands []string
+}
+
+type exitCodeDoc struct {
+	Code    int
+	Meaning string
+}
+
+const helpSynopsis = "arc-to-zen [options] [zen-profile-path]"
+
+const helpDescription = "Imports Arc browser spaces, folders, and tabs into Zen browser: each Arc space becomes a Zen workspace with its pinned tabs and folders, Arc profiles become containers, and favicons are fetched and cached along the way."
+
+const helpProfilePath = "If no profile path is provided, the tool will auto-discover your default Zen profile. Use -list to see all available profiles."
+
+var flagGroups = []flagGroup{
+	{Topic: "import", Title: "Import options", Flags: []flagDoc{
+		{"dry-run", ""},
+		{"verbose", ""},
+		{"yes", ""},
+		{"strict", ""},
+		{"simulate-restore", ""},
+		{"timeout", "duration"},
+		{"session-budget", "mb"},
+		{"over-budget", "policy"},
+		{"favicon-storage", "where"},
+		{"fail-fast", ""},
+		{"json", ""},
+		{"zen-version", "ver"},
+		{"principal", "scheme=kind"},
+		{"rewrites", "file"},
+		{"strip-tracking", ""},
+		{"also-bookmarks", ""},
+		{"unparsed-items", "file"},
+	}},
+	{Topic: "selection", Title: "Choosing what to import", Flags: []flagDoc{
+		{"arc-data", "path"},
+		{"interactive", ""},
+		{"selection", "file"},
+		{"only", "folders|tabs"},
+		{"exclude", "categories"},
+		{"empty-urls", "policy"},
+		{"share-links", "policy"},
+		{"empty-folders", "policy"},
+		{"quick", "n"},
+		{"favorites-as-essentials", ""},
+	}},
+	{Topic: "layout", Title: "Workspaces and folders", Flags: []flagDoc{
+		{"mode", "workspaces|folders"},
+		{"merge", "strategy"},
+		{"combine", "a+b=name"},
+		{"split-space", "name:n"},
+		{"merge-small-folders", "n"},
+		{"sort", "order"},
+		{"tag-prefix", "prefix"},
+		{"collapse-pinned", "n"},
+		{"space-setting", "space=value"},
+	}},
+	{Topic: "icons", Title: "Icons and themes", Flags: []flagDoc{
+		{"space-icon-from-favicons", ""},
+		{"letter-avatars", ""},
+		{"emoji-from-name", ""},
+		{"theme", "policy"},
+		{"mappings", "file"},
+	}},
+	{Topic: "arc-profiles", Title: "Arc profiles and containers", Flags: []flagDoc{
+		{"arc-profile", "name"},
+		{"profile-container", "profile=container"},
+		{"domain-container", "domain=container"},
+		{"update-containers", ""},
+		{"repair-containers", ""},
+		{"choose-containers", ""},
+	}},
+	{Topic: "favicons", Title: "Favicon cache", Flags: []flagDoc{
+		{"favicon-stats", ""},
+		{"favicon-retry-failed", ""},
+		{"favicon-clear-cache", ""},
+		{"allow-private-hosts", ""},
+		{"favicon-audit-log", "file"},
+		{"favicon-deny", "domains"},
+		{"favicon-force", "domains"},
+		{"favicon-provider", "list"},
+	}},
+	{Topic: "profile", Title: "Profile tools", Flags: []flagDoc{
+		{"list", ""},
+		{"list-backups", ""},
+		{"backup", ""},
+		{"restore", ""},
+		{"reset", ""},
+		{"decompress", "file"},
+	}},
+}
+
+var commands = []commandDoc{
+	{Name: "containers", Run: runContainers, Usage: printContainersUsage, Lines: []usageLine{
+		{"containers list [profile]", "List containers in containers.json"},
+		{"containers rename <id|name> <new-name>", "Rename a container"},
+		{"containers recolor <id|name> <color>", "Change a container's color"},
+	}},
+	{Name: "favicon", Run: runFavicon, Usage: printFaviconUsage, Lines: []usageLine{
+		{"favicon fetch -urls <file>", "Pre-warm the favicon cache from a URL list"},
+	}},
+	{Name: "mappings", Run: runMappings, Usage: printMappingsUsage, Lines: []usageLine{
+		{"mappings dump [-defaults] [file]", "Print the icon/color mapping tables as JSON"},
+		{"mappings validate [file]", "Check a mappings file against Zen/Firefox's icons and colors"},
+	}},
+	{Name: "boosts", Run: runBoosts, Usage: printBoostsUsage, Lines: []usageLine{
+		{"boosts list [path]", "List Arc Boosts and the sites they run on"},
+		{"boosts export [-out dir] [path]", "Export Boosts as userContent.css, user styles and userscripts"},
+	}},
+	{Name: "compact", Run: runCompact, Usage: printCompactUsage, Lines: []usageLine{
+		{"compact [-dry-run] [-move-favicons] [profile]", "Remove favicon images embedded in the session's tabs, or move them to favicons.sqlite"},
+	}},
+	{Name: "upgrade-session", Run: runUpgradeSession, Usage: printUpgradeSessionUsage, Lines: []usageLine{
+		{"upgrade-session [-dry-run] [profile]", "Rewrite folders and containers left by older versions"},
+	}},
+	{Name: "origins", Run: runOrigins, Usage: printOriginsUsage, Lines: []usageLine{
+		{"origins [-json] [profile]", "Show which Arc spaces/profiles the workspaces and containers came from"},
+	}},
+	{Name: "count", Run: runCount, Usage: printCountUsage, Lines: []usageLine{
+		{"count [-arc-profile name] [-json] [file]", "Count Arc's spaces, folders, tabs and hosts and estimate the import time"},
+	}},
+	{Name: "cleanup", Run: runCleanup, Usage: printCleanupUsage, Lines: []usageLine{
+		{"cleanup [-all] [-json] [file]", "Report duplicate, localhost, empty-folder and oversized-space junk in Arc"},
+	}},
+	{Name: "export-bookmarks", Run: runExportBookmarks, Usage: printExportBookmarksUsage, Lines: []usageLine{
+		{"export-bookmarks [-out file] [file]", "Write Arc's spaces, folders and tabs as bookmarks HTML for any browser"},
+	}},
+	{Name: "untag", Run: runUntag, Usage: printUntagUsage, Lines: []usageLine{
+		{"untag [-prefix p] [-dry-run] [profile]", "Remove the -tag-prefix from imported tab labels"},
+	}},
+	{Name: "capabilities", Usage: printCapabilitiesUsage, Lines: []usageLine{
+		{"capabilities [-json]", "List the sources, targets, session formats, features and options of this build"},
+	}},
+}
+
+var examples = []exampleDoc{
+	{"Auto-discover and import", []string{"arc-to-zen"}},
+	{"List available profiles", []string{"arc-to-zen -list"}},
+	{"Import with explicit profile path", []string{`arc-to-zen "~/Library/Application Support/zen/Profiles/xxx.default"`}},
+	{"Dry-run to see what would change, as a tree or as JSON", []string{"arc-to-zen -dry-run", "arc-to-zen -dry-run -json > plan.json"}},
+	{"Pick what to import, and import the same choice again later", []string{"arc-to-zen -interactive -selection picked.json", "arc-to-zen -selection picked.json -merge dedupe"}},
+	{"Reset profile to default state", []string{"arc-to-zen -reset", "arc-to-zen -reset -dry-run"}},
+	{"Decompress a .jsonlz4 file", []string{"arc-to-zen -decompress default", "arc-to-zen -decompress default > output.json", "arc-to-zen -decompress /path/to/zen-sessions.jsonlz4"}},
+	{"Create a backup of zen-sessions.jsonlz4", []string{"arc-to-zen -backup"}},
+	{"Restore a backup (interactive menu)", []string{"arc-to-zen -restore"}},
+	{"View favicon cache stats", []string{"arc-to-zen -favicon-stats"}},
+	{"Retry failed favicon fetches", []string{"arc-to-zen -favicon-retry-failed"}},
+	{"Clear favicon cache for fresh fetch", []string{"arc-to-zen -favicon-clear-cache"}},
+	{"Rename and recolor an imported container", []string{"arc-to-zen containers list", `arc-to-zen containers rename 6 "Work"`, "arc-to-zen containers recolor Work orange"}},
+}
+
+var exitCodes = []exitCodeDoc{
+	{0, "Success, including dry runs"},
+	{1, "Failure: an error, a canceled confirmation, or an import that skipped some Arc spaces"},
+	{2, "Invalid command line (unknown flag or bad flag value)"},
+}
+
+// findCommand returns the subcommand called name, or nil
+func findCommand(name string) *commandDoc {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// usageColumn is where descriptions start in the usage, and usageWidth where
+// they wrap
+const (
+	usageColumn = 24
+	usageWidth  = 100
+)
+
+// groupedFlags returns flagGroups plus an "Other options" group for the
+// registered flags none of them lists
+func groupedFlags() []flagGroup {
+	listed := make(map[string]bool)
+	for _, group := range flagGroups {
+		for _, doc := range group.Flags {
+			listed[doc.Name] = true
+		}
+	}
+	groups := append([]flagGroup(nil), flagGroups...)
+	other := flagGroup{Topic: "other", Title: "Other options"}
+	flag.VisitAll(func(f *flag.Flag) {
+		if !listed[f.Name] {
+			name, _ := flag.UnquoteUsage(f)
+			other.Flags = append(other.Flags, flagDoc{Name: f.Name, Arg: name})
+		}
+	})
+	if len(other.Flags) > 0 {
+		groups = append(groups, other)
+	}
+	return groups
+}
+
+// flagDefault is a flag's default value, or "" if it's the zero value
+func flagDefault(f *flag.Flag) string {
+	switch f.DefValue {
+	case "", "0", "false", "0s", "[]", "map[]":
+		return ""
+	}
+	return f.DefValue
+}
+
+// flagDescription is a flag's usage string with its default, if it has one
+func flagDescription(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	if def := flagDefault(f); def != "" {
+		return fmt.Sprintf("%s (default: %s)", usage, def)
+	}
+	return usage
+}
+
+func flagHead(doc flagDoc) string {
+	if doc.Arg == "" {
+		return "-" + doc.Name
+	}
+	return fmt.Sprintf("-%s <%s>", doc.Name, doc.Arg)
+}
+
+// wrapText splits text into lines of at most width runes, at spaces
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// writeEntry writes head and its description in the usage's two columns
+func writeEntry(w io.Writer, head, description string) {
+	indent := strings.Repeat(" ", usageColumn)
+	lines := wrapText(description, usageWidth-usageColumn)
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	first := "  " + head
+	if len(first) < usageColumn-1 {
+		fmt.Fprintf(w, "%-*s%s\n", usageColumn, first, lines[0])
+		lines = lines[1:]
+	} else {
+		fmt.Fprintln(w, first)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, strings.TrimRight(indent+line, " "))
+	}
+}
+
+func writeParagraph(w io.Writer, text string) {
+	for _, line := range wrapText(text, usageWidth-2) {
+		fmt.Fprintln(w, "  "+line)
+	}
+}
+
+func writeFlagGroup(w io.Writer, group flagGroup) {
+	fmt.Fprintf(w, "%s:\n", group.Title)
+	for _, doc := range group.Flags {
+		if f := flag.Lookup(doc.Name); f != nil {
+			writeEntry(w, flagHead(doc), flagDescription(f))
+		}
+	}
+}
+
+func writeCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		for _, line := range cmd.Lines {
+			fmt.Fprintf(w, "  %-40s %s\n", line.Synopsis, line.Summary)
+		}
+	}
+}
+
+func writeExamples(w io.Writer) {
+	fmt.Fprintln(w, "Examples:")
+	for i, example := range examples {
+		if i > 0 {
+			fmt.Fprintln(w, "")
+		}
+		fmt.Fprintf(w, "  # %s\n", example.Comment)
+		for _, command := range example.Commands {
+			fmt.Fprintf(w, "  %s\n", command)
+		}
+	}
+}
+
+func writeExitCodes(w io.Writer) {
+	fmt.Fprintln(w, "Exit Codes:")
+	for _, code := range exitCodes {
+		writeEntry(w, fmt.Sprint(code.Code), code.Meaning)
+	}
+}
+
+func printUsage() {
+	w := os.Stdout
+	fmt.Fprintln(w, "Arc to Zen Browser Import Tool")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintf(w, "  %s\n", helpSynopsis)
+	fmt.Fprintf(w, "  arc-to-zen <command> [arguments]\n")
+	fmt.Fprintf(w, "  arc-to-zen help [topic]\n")
+	for _, group := range groupedFlags() {
+		fmt.Fprintln(w, "")
+		writeFlagGroup(w, group)
+	}
+	fmt.Fprintln(w, "")
+	writeCommands(w)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Profile Path:")
+	writeParagraph(w, helpProfilePath)
+	fmt.Fprintln(w, "")
+	writeExitCodes(w)
+	fmt.Fprintln(w, "")
+	writeExamples(w)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Run 'arc-to-zen help topics' for the help topics, and 'arc-to-zen help man' for a man page.")
+}
+
+// runHelp prints the help for one topic: an option group, a command, a flag
+// (-name), the examples, the exit codes, or the man page
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 0
+	}
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "Error: help takes at most one topic")
+		return 1
+	}
+	w := os.Stdout
+	topic := args[0]
+	switch topic {
+	case "topics":
+		printHelpTopics()
+		return 0
+	case "examples":
+		writeExamples(w)
+		return 0
+	case "exit-codes":
+		writeExitCodes(w)
+		return 0
+	case "man":
+		writeManPage(w)
+		return 0
+	}
+	if cmd := findCommand(topic); cmd != nil {
+		cmd.Usage()
+		return 0
+	}
+	for _, group := range groupedFlags() {
+		if group.Topic == topic {
+			writeFlagGroup(w, group)
+			return 0
+		}
+	}
+	name := strings.TrimLeft(topic, "-")
+	for _, group := range groupedFlags() {
+		for _, doc := range group.Flags {
+			if doc.Name == name {
+				writeEntry(w, flagHead(doc), flagDescription(flag.Lookup(doc.Name)))
+				fmt.Fprintf(w, "\nSee also: arc-to-zen help %s\n", group.Topic)
+				return 0
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown help topic %q\n\n", topic)
+	printHelpTopics()
+	return 1
+}
+
+func printHelpTopics() {
+	fmt.Println("Help topics (arc-to-zen help <topic>):")
+	for _, group := range groupedFlags() {
+		writeEntry(os.Stdout, group.Topic, group.Title)
+	}
+	for _, cmd := range commands {
+		writeEntry(os.Stdout, cmd.Name, "The "+cmd.Name+" command")
+	}
+	writeEntry(os.Stdout, "-<flag>", "One option, e.g. -merge")
+	writeEntry(os.Stdout, "examples", "Examples")
+	writeEntry(os.Stdout, "exit-codes", "What the exit status means")
+	writeEntry(os.Stdout, "man", "The whole help as a man page (roff)")
+}
+
+// roffEscape makes text safe in a roff line: backslashes and dashes are
+// escaped, and a leading dot or quote can't start a request
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// writeManPage writes the help as a section 1 man page
+func writeManPage(w io.Writer) {
+	fmt.Fprintln(w, `.TH ARC-TO-ZEN 1 "" "arc-to-zen" "User Commands"`)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, `arc\-to\-zen \- import Arc browser spaces, folders and tabs into Zen browser`)
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s\n.br\n", roffEscape(helpSynopsis))
+	fmt.Fprintln(w, `.B arc\-to\-zen\fR \fIcommand\fR [\fIarguments\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B arc\-to\-zen help\fR [\fItopic\fR]`)
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, roffEscape(helpDescription))
+	fmt.Fprintln(w, ".PP")
+	fmt.Fprintln(w, roffEscape(helpProfilePath))
+	fmt.Fprintln(w, ".SH OPTIONS")
+	for _, group := range groupedFlags() {
+		fmt.Fprintf(w, ".SS %s\n", roffEscape(group.Title))
+		for _, doc := range group.Flags {
+			f := flag.Lookup(doc.Name)
+			if f == nil {
+				continue
+			}
+			fmt.Fprintln(w, ".TP")
+			if doc.Arg == "" {
+				fmt.Fprintf(w, `\fB\-%s\fR`+"\n", roffEscape(doc.Name))
+			} else {
+				fmt.Fprintf(w, `\fB\-%s\fR \fI%s\fR`+"\n", roffEscape(doc.Name), roffEscape(doc.Arg))
+			}
+			fmt.Fprintln(w, roffEscape(flagDescription(f)))
+		}
+	}
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, cmd := range commands {
+		for _, line := range cmd.Lines {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, `\fBarc\-to\-zen %s\fR`+"\n", roffEscape(line.Synopsis))
+			fmt.Fprintln(w, roffEscape(line.Summary))
+		}
+	}
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	for _, code := range exitCodes {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %d\n", code.Code)
+		fmt.Fprintln(w, roffEscape(code.Meaning))
+	}
+	fmt.Fprintln(w, ".SH EXAMPLES")
+	for _, example := range examples {
+		fmt.Fprintln(w, ".PP")
+		fmt.Fprintln(w, roffEscape(example.Comment)+":")
+		fmt.Fprintln(w, ".PP")
+		fmt.Fprintln(w, ".RS")
+		fmt.Fprintln(w, ".nf")
+		for _, command := range example.Commands {
+			fmt.Fprintln(w, roffEscape(command))
+		}
+		fmt.Fprintln(w, ".fi")
+		fmt.Fprintln(w, ".RE")
+	}
+	fmt.Fprintln(w, ".SH SEE ALSO")
+	fmt.Fprintln(w, `\fBarc\-to\-zen help topics\fR for the help of each option group and command.`)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"arc-to-zen/importer"
+)
+
+// selectionRow is one line of the -interactive checklist
+type selectionRow struct {
+	node     *importer.SelectionNode
+	depth    int
+	selected bool
+	expanded bool  // Children are listed (spaces start expanded, folders collapsed)
+	parent   int   // Row of the enclosing space or folder, -1 for spaces
+	subtree  []int // Rows of the node and everything in it
+}
+
+// promptSelection shows the Arc tree as a numbered checklist on stdout and
+// reads toggles from reader until Enter imports or q cancels. Items in saved
+// start unchecked; with savePath set, the answer is written there for -selection.
+func promptSelection(reader *bufio.Reader, saved map[string]bool, savePath string) importer.ItemSelector {
+	return func(tree []*importer.SelectionNode) (map[string]bool, bool) {
+		var rows []*selectionRow
+		var add func(node *importer.SelectionNode, depth, parent int, selected bool) []int
+		add = func(node *importer.SelectionNode, depth, parent int, selected bool) []int {
+			selected = selected && !saved[node.ArcID]
+			row := &selectionRow{node: node, depth: depth, selected: selected, expanded: node.Kind == importer.SelectSpace, parent: parent}
+			index := len(rows)
+			rows = append(rows, row)
+			row.subtree = []int{index}
+			for _, child := range node.Children {
+				row.subtree = append(row.subtree, add(child, depth+1, index, selected)...)
+			}
+			return row.subtree
+		}
+		for _, space := range tree {
+			add(space, 0, -1, true)
+		}
+
+		for {
+			visible := visibleRows(rows)
+			printSelection(rows, visible)
+			fmt.Println("Toggle by number or range (\"3 5-7\"; a space or folder toggles everything in it), \"all\" or")
+			fmt.Println("\"none\"; \"e 4\" expands or collapses folder 4, \"e all\" expands every folder.")
+			fmt.Print("Enter imports the checked items, q cancels: ")
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				fmt.Println("")
+				fmt.Fprintln(os.Stderr, "No answer on stdin; -interactive needs a terminal.")
+				return nil, false
+			}
+			input = strings.TrimSpace(input)
+			switch input {
+			case "":
+				deselected := make(map[string]bool)
+				for _, row := range rows {
+					if !row.selected {
+						deselected[row.node.ArcID] = true
+					}
+				}
+				if savePath != "" {
+					saveSelection(savePath, saved, deselected, rows)
+				}
+				return deselected, true
+			case "q", "Q":
+				return nil, false
+			case "all", "none":
+				for _, row := range rows {
+					row.selected = input == "all"
+				}
+				continue
+			case "e all":
+				for _, row := range rows {
+					row.expanded = true
+				}
+				continue
+			}
+			expand := strings.HasPrefix(input, "e ")
+			if expand {
+				input = strings.TrimSpace(input[2:])
+			}
+			numbers, err := parseSelectionNumbers(input, len(visible))
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				continue
+			}
+			for _, n := range numbers {
+				row := rows[visible[n-1]]
+				if expand {
+					row.expanded = !row.expanded
+					continue
+				}
+				toggleRow(rows, visible[n-1])
+			}
+		}
+	}
+}
+
+// toggleRow checks or unchecks a row and everything in it. Checking an item
+// also checks the spaces and folders it is in, which would otherwise leave it
+// out with them.
+func toggleRow(rows []*selectionRow, i int) {
+	selected := !rows[i].selected
+	for _, j := range rows[i].subtree {
+		rows[j].selected = selected
+	}
+	if selected {
+		for parent := rows[i].parent; parent >= 0; parent = rows[parent].parent {
+			rows[parent].selected = true
+		}
+	}
+}
+
+// selectionBox is the checkbox of a row: [~] for a space or folder with only
+// some of the items in it checked
+func selectionBox(rows []*selectionRow, row *selectionRow) string {
+	checked := 0
+	for _, j := range row.subtree {
+		if rows[j].selected {
+			checked++
+		}
+	}
+	switch checked {
+	case 0:
+		return "[ ]"
+	case len(row.subtree):
+		return "[x]"
+	}
+	return "[~]"
+}
+
+// visibleRows returns the rows not inside a collapsed folder, numbered from 1
+// in the checklist
+func visibleRows(rows []*selectionRow) []int {
+	var visible []int
+	for i, row := range rows {
+		shown := true
+		for parent := row.parent; parent >= 0; parent = rows[parent].parent {
+			if !rows[parent].expanded {
+				shown = false
+				break
+			}
+		}
+		if shown {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
+
+// saveSelection writes the answer to path, keeping saved items that weren't
+// offered this time (e.g. spaces of another -arc-profile) so they stay left out
+func saveSelection(path string, saved, deselected map[string]bool, rows []*selectionRow) {
+	offered := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		offered[row.node.ArcID] = true
+	}
+	merged := make(map[string]bool, len(deselected))
+	for id := range saved {
+		if !offered[id] {
+			merged[id] = true
+		}
+	}
+	for id := range deselected {
+		merged[id] = true
+	}
+	if err := importer.SaveSelection(path, merged); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	fmt.Printf("Selection saved to %s; pass -selection %s to import the same items again.\n", path, path)
+}
+
+// printSelection prints the visible rows of the checklist (see selectionBox);
+// a collapsed folder shows how many items it holds
+func printSelection(rows []*selectionRow, visible []int) {
+	fmt.Println("")
+	fmt.Println("Choose what to import:")
+	width := len(strconv.Itoa(len(visible)))
+	for n, i := range visible {
+		row := rows[i]
+		box := selectionBox(rows, row)
+		label := row.node.Title
+		switch row.node.Kind {
+		case importer.SelectSpace:
+			label += " (space)"
+		case importer.SelectFolder:
+			label += "/"
+			if !row.expanded && len(row.subtree) > 1 {
+				label += fmt.Sprintf(" (%d items)", len(row.subtree)-1)
+			}
+		default:
+			if row.node.URL != "" {
+				label += "  " + row.node.URL
+			}
+		}
+		fmt.Printf("  %s %*d  %s%s\n", box, width, n+1, strings.Repeat("  ", row.depth), label)
+	}
+	fmt.Println(strings.Repeat("-", 60))
+}
+
+// parseSelectionNumbers parses "3 5-7,9" into row numbers between 1 and max
+func parseSelectionNumbers(input string, max int) ([]int, error) {
+	var numbers []int
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		from, to := field, field
+		if dash := strings.Index(field, "-"); dash > 0 {
+			from, to = field[:dash], field[dash+1:]
+		}
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last > max || first > last {
+			return nil, fmt.Errorf("invalid selection %q: use numbers between 1 and %d", field, max)
+		}
+		for n := first; n <= last; n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers, nil
+}
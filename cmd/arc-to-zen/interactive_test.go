@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"arc-to-zen/importer"
+)
+
+func TestPromptSelection_RecheckInUncheckedFolder(t *testing.T) {
+	tree := []*importer.SelectionNode{{ArcID: "s", Kind: importer.SelectSpace, Title: "Work", Children: []*importer.SelectionNode{
+		{ArcID: "f", Kind: importer.SelectFolder, Title: "Docs", Children: []*importer.SelectionNode{
+			{ArcID: "t1", Kind: importer.SelectTab, Title: "One"},
+			{ArcID: "t2", Kind: importer.SelectTab, Title: "Two"},
+		}},
+		{ArcID: "t3", Kind: importer.SelectTab, Title: "Three"},
+	}}}
+
+	// Uncheck the folder, expand it, check its first tab again
+	reader := bufio.NewReader(strings.NewReader("2\ne 2\n3\n\n"))
+	deselected, ok := promptSelection(reader, nil, "")(tree)
+	if !ok {
+		t.Fatal("expected the selection to be confirmed")
+	}
+	if want := map[string]bool{"t2": true}; !reflect.DeepEqual(deselected, want) {
+		t.Errorf("expected only the other tab left out, got %v", deselected)
+	}
+}
+
+func TestSelectionBox(t *testing.T) {
+	// Space, folder, two tabs in it
+	rows := []*selectionRow{
+		{parent: -1, subtree: []int{0, 1, 2, 3}},
+		{parent: 0, subtree: []int{1, 2, 3}},
+		{parent: 1, subtree: []int{2}},
+		{parent: 1, subtree: []int{3}},
+	}
+	toggleRow(rows, 1) // Everything starts unchecked; check the folder
+	if got := selectionBox(rows, rows[1]); got != "[x]" || !rows[0].selected {
+		t.Errorf("expected the folder and its space checked, got %s", got)
+	}
+	toggleRow(rows, 1)
+	toggleRow(rows, 2)
+	if box := selectionBox(rows, rows[1]); box != "[~]" || !rows[1].selected || selectionBox(rows, rows[0]) != "[~]" {
+		t.Errorf("expected a partly checked folder, got %s", box)
+	}
+	toggleRow(rows, 2)
+	if box := selectionBox(rows, rows[2]); box != "[ ]" {
+		t.Errorf("expected an unchecked tab, got %s", box)
+	}
+}
@@ -1,33 +1,123 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"arc-to-zen/arcdata"
 	"arc-to-zen/backup"
 	"arc-to-zen/favicon"
 	"arc-to-zen/importer"
+	"arc-to-zen/lock"
+	"arc-to-zen/mappings"
 	"arc-to-zen/mozlz4"
 	"arc-to-zen/profiles"
+	"arc-to-zen/rewrite"
+	"arc-to-zen/types"
 )
 
 func main() {
+	// Handle subcommands before flag parsing
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil && cmd.Run != nil {
+			os.Exit(cmd.Run(os.Args[2:]))
+		}
+	}
+
 	// Define flags
 	dryRun := flag.Bool("dry-run", false, "Show what would be imported without making changes")
 	verbose := flag.Bool("verbose", false, "Show detailed output")
+	selectionPath := flag.String("selection", "", "File remembering what -interactive left out; without -interactive, import the same items again")
+	interactive := flag.Bool("interactive", false, "Choose the spaces, folders and tabs to import from a checklist of the Arc tree")
+	only := flag.String("only", "", "Import only root-level \"folders\" (with their contents) or only loose \"tabs\"")
+	shareLinks := flag.String("share-links", importer.ShareLinkSkip, "What to do with Arc share links (arc.net/folder/...): \"skip\" them, \"keep\" them, or \"resolve\" them into a folder of the links they share")
+	emptyURLs := flag.String("empty-urls", importer.EmptyURLSkip, "What to do with tabs that have no URL: \"skip\" them, \"keep\" them as about:blank pins, or turn them into empty \"note\" folders")
+	emptyFolders := flag.String("empty-folders", importer.EmptyFolderSkip, "What to do with Arc folders without a single tab: \"skip\" them or \"keep\" them as empty Zen folders")
+	sortOrder := flag.String("sort", importer.SortNone, "Order of the items in each space and folder: none (Arc order), alpha, domain or recent")
+	quick := flag.Int("quick", 0, "Try it out: import only the first n pinned tabs of each space, as essentials without containers")
+	mode := flag.String("mode", importer.ModeWorkspaces, "Import each Arc space as a workspace (\"workspaces\") or as a top-level folder of the first Zen workspace (\"folders\")")
+	merge := flag.String("merge", importer.MergeReplace, "Existing workspaces of the same name: \"replace\" their pins, keep them and add the imported ones after them (\"merge\"), add only tabs not pinned there yet (\"dedupe\"), or leave them alone and \"skip\" the space")
+	favoritesAsEssentials := flag.Bool("favorites-as-essentials", false, "Import Arc's favorites bar as Zen essentials shared by the workspaces of each profile's container")
+	combineSpaces := combineFlag{}
+	flag.Var(combineSpaces, "combine", "Import Arc spaces as folders of one workspace: \"Work+Clients=Work\" (repeatable)")
+	splitSpaces := splitSpaceFlag{}
+	flag.Var(splitSpaces, "split-space", "Split an Arc space into workspaces of at most this many items: \"Work:200\" makes Work, Work 2, ... (repeatable)")
+	tagPrefix := flag.String("tag-prefix", "", "Prefix the labels of imported tabs, e.g. \"[arc] \", to tell them from native Zen pins (remove later with untag)")
+	mergeSmallFolders := flag.Int("merge-small-folders", 0, "Inline folders with fewer than this many items into their parent, prefixing the moved titles with the folder name (0 = never)")
+	spaceIconFromFavicons := flag.Bool("space-icon-from-favicons", false, "Give spaces without a mapped Arc icon the favicon most of their tabs share (else their emoji or first letter) instead of the globe")
+	letterAvatars := flag.Bool("letter-avatars", false, "Draw spaces without a mapped Arc icon as the first letter of their name on their color, instead of the globe")
+	emojiFromName := flag.Bool("emoji-from-name", false, "Move a leading emoji in a space's title (\"🚀 Launch\") to its workspace icon")
+	collapsePinned := flag.Int("collapse-pinned", 0, "Start workspaces with more than this many pins with their pinned tabs collapsed (0 = never)")
+	theme := flag.String("theme", importer.ThemeGradient, "Workspace themes from Arc space colors: \"gradient\", \"solid\" (main color only) or \"none\" (Zen's default)")
+	arcDataFlag := flag.String("arc-data", "", "Path of Arc's StorableSidebar.json (or the folder holding it) instead of looking for it")
+	arcProfile := flag.String("arc-profile", "", "Import only spaces belonging to this Arc profile (e.g. \"Profile 1\" or its name)")
+	strict := flag.Bool("strict", false, "Fail instead of falling back to defaults for unmapped icons, unknown items, missing containers, and favicon failures")
+	simulateRestore := flag.Bool("simulate-restore", false, "Check the imported folders and tabs against Zen's session restore rules and warn about anything it would drop or reorder")
+	timeout := flag.Duration("timeout", 0, "Stop fetching favicons after this long (e.g. 10m) and finish the import with what was fetched")
+	sessionBudget := flag.Int("session-budget", importer.DefaultSessionBudget>>20, "Warn when the compressed session would be larger than this many MB (0 = no limit)")
+	faviconStorage := flag.String("favicon-storage", importer.FaviconStorageSession, "Where fetched favicons go: embedded in the \"session\", or Zen's \"sqlite\" favicon database (keeps the session small)")
+	overBudget := flag.String("over-budget", importer.OverBudgetWarn, "Above -session-budget: \"warn\", \"downscale\" imported favicons, \"skip-favicons\" or \"fail\"")
+	failFast := flag.Bool("fail-fast", false, "Abort the import if any Arc space can't be imported, instead of skipping it")
 	reset := flag.Bool("reset", false, "Reset the profile to default state (removes session files)")
 	listProfiles := flag.Bool("list", false, "List available Zen profiles")
+	listBackups := flag.Bool("list-backups", false, "List the session backups, newest first")
+	asJSON := flag.Bool("json", false, "Print the result (import report and plan, profiles, backups, favicon stats) as JSON on stdout; everything else goes to stderr")
 	decompress := flag.String("decompress", "", "Decompress a Mozilla LZ4 (.jsonlz4) file and print JSON to stdout")
 	backupSession := flag.Bool("backup", false, "Create a backup of the zen-sessions.jsonlz4 file")
 	restoreSession := flag.Bool("restore", false, "Restore a backup of the zen-sessions.jsonlz4 file")
 	faviconStats := flag.Bool("favicon-stats", false, "Show favicon cache statistics")
 	faviconRetryFailed := flag.Bool("favicon-retry-failed", false, "Clear failed favicon cache entries so they will be retried on next import")
 	faviconClearCache := flag.Bool("favicon-clear-cache", false, "Clear entire favicon cache for a fresh re-fetch on next import")
+	var exclude listFlag
+	flag.Var(&exclude, "exclude", "Leave out likely junk: \"duplicates\", \"localhost\", \"empty-folders\", \"large-spaces\" (comma-separated, repeatable; see 'arc-to-zen cleanup')")
+	var faviconDeny, faviconForce listFlag
+	flag.Var(&faviconDeny, "favicon-deny", "Never fetch favicons from these domains or their subdomains (comma-separated, repeatable)")
+	flag.Var(&faviconForce, "favicon-force", "Always fetch favicons fresh from these domains, even if cached or private (comma-separated, repeatable)")
+	faviconAuditLog := flag.String("favicon-audit-log", "", "Append a JSON line per outbound favicon request (URL, status, bytes, duration) to this file")
+	unparsedItems := flag.String("unparsed-items", "", "Write the Arc items that could not be understood, as raw JSON, to this file (for bug reports)")
+	allowPrivateHosts := flag.Bool("allow-private-hosts", false, "Fetch favicons from localhost and private network addresses (e.g. intranet sites)")
+	faviconProvider := flag.String("favicon-provider", defaultFaviconProviders, "Where to look for favicons, in order: \"direct\" (the site), \"google\", \"duckduckgo\" (comma-separated; \"direct\" alone keeps sites private)")
+	zenVersion := flag.String("zen-version", "", "Zen release the profile will be opened with (e.g. 1.14.5b); default: read from the profile")
+	principals := keyValueFlag{}
+	flag.Var(principals, "principal", "Restore tabs of a URL scheme with this triggeringPrincipal: \"file=null\", \"moz-extension=content\" or a base64 principal (repeatable)")
+	spaceSettings := keyValueFlag{}
+	flag.Var(spaceSettings, "space-setting", "Set a workspace's pinned section by space name: \"Work=collapsed\" or \"Work=expanded\" (repeatable)")
+	profileContainers := keyValueFlag{}
+	flag.Var(profileContainers, "profile-container", "Assign an Arc profile to a container: \"Profile 1=Work\", \"Profile 1=new\" or \"Profile 1=none\" (repeatable)")
+	updateContainers := flag.Bool("update-containers", false, "Give existing containers reused for Arc profiles the icon and color of the profile's spaces")
+	domainContainers := keyValueFlag{}
+	flag.Var(domainContainers, "domain-container", "Open the tabs of a domain and its subdomains in a container, whatever their space: \"*.workdomain.com=Work\" or \"example.com=none\" (repeatable)")
+	repairContainers := flag.Bool("repair-containers", false, "Repair containers in containers.json that share a name or ID before importing, without asking")
+	alsoBookmarks := flag.Bool("also-bookmarks", false, "Also add the imported folders and tabs to Zen's bookmarks (places.sqlite), under Other Bookmarks → Arc")
+	stripTracking := flag.Bool("strip-tracking", false, "Remove tracking parameters (utm_*, fbclid, gclid, ...) from imported tab URLs")
+	rewritesPath := flag.String("rewrites", "", "URL rewrite rules applied to every imported tab (default: rewrites.json in the config dir)")
+	mappingsPath := flag.String("mappings", "", "Icon/color mappings file extending the built-in tables (default: mappings.json in the config dir)")
+	chooseContainers := flag.Bool("choose-containers", false, "Interactively choose the container for each Arc profile")
+	yes := flag.Bool("yes", false, "Write without asking for confirmation first")
 	flag.Usage = printUsage
+	// help and capabilities describe the flags defined above (and the
+	// commands, so they aren't dispatched through them)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help":
+			os.Exit(runHelp(os.Args[2:]))
+		case "capabilities":
+			os.Exit(runCapabilities(os.Args[2:]))
+		}
+	}
 	flag.Parse()
+	if *asJSON {
+		redirectStdout()
+	}
 
 	// Handle favicon cache commands
 	if *faviconStats || *faviconRetryFailed || *faviconClearCache {
@@ -39,6 +129,9 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			if *asJSON {
+				os.Exit(printJSON(faviconStatsReport{Total: total, Successful: successful, Failed: failed}))
+			}
 			fmt.Println("Favicon Cache Statistics:")
 			fmt.Printf("  Total entries:  %d\n", total)
 			fmt.Printf("  Successful:     %d\n", successful)
@@ -55,6 +148,9 @@ func main() {
 			}
 			if failed == 0 {
 				fmt.Println("No failed favicon entries to clear.")
+				if *asJSON {
+					os.Exit(printJSON(cacheClearedReport{}))
+				}
 				os.Exit(0)
 			}
 
@@ -63,6 +159,9 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			if *asJSON {
+				os.Exit(printJSON(cacheClearedReport{Cleared: removed}))
+			}
 			fmt.Printf("✓ Cleared %d failed favicon entries.\n", removed)
 			fmt.Println("Run 'arc-to-zen' again to retry fetching these favicons.")
 			os.Exit(0)
@@ -77,6 +176,9 @@ func main() {
 			}
 			if total == 0 {
 				fmt.Println("Favicon cache is already empty.")
+				if *asJSON {
+					os.Exit(printJSON(cacheClearedReport{}))
+				}
 				os.Exit(0)
 			}
 
@@ -85,6 +187,9 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
+			if *asJSON {
+				os.Exit(printJSON(cacheClearedReport{Cleared: removed}))
+			}
 			fmt.Printf("✓ Cleared %d favicon cache entries.\n", removed)
 			fmt.Println("Run 'arc-to-zen' again to fetch all favicons fresh.")
 			os.Exit(0)
@@ -119,17 +224,46 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		if *asJSON {
+			os.Exit(printJSON(profileList))
+		}
 		fmt.Print(profiles.ListProfiles(profileList))
 		os.Exit(0)
 	}
 
+	// Handle list backups command
+	if *listBackups {
+		backups, err := backup.ListBackups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *asJSON {
+			os.Exit(printJSON(backups))
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups yet.")
+			os.Exit(0)
+		}
+		fmt.Println("Session backups (newest first):")
+		for _, b := range backups {
+			fmt.Printf("  %s (%s)\n", b.Name, b.Timestamp.Format("Mon Jan 2, 2006 at 3:04 PM"))
+		}
+		os.Exit(0)
+	}
+
 	// Handle backup and restore commands (need profile path)
 	if *backupSession || *restoreSession {
 		args := flag.Args()
 		var zenProfilePath string
 
 		if len(args) > 0 {
-			zenProfilePath = args[0]
+			resolved, err := profiles.ResolvePath(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			zenProfilePath = resolved
 		} else {
 			defaultProfile, err := profiles.GetDefaultProfile()
 			if err != nil {
@@ -151,7 +285,14 @@ func main() {
 		}
 
 		if *restoreSession {
-			if err := backup.RestoreBackup(zenProfilePath); err != nil {
+			profileLock, err := lock.Acquire(zenProfilePath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			err = backup.RestoreBackup(zenProfilePath)
+			profileLock.Release()
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: restore failed: %v\n", err)
 				os.Exit(1)
 			}
@@ -165,7 +306,12 @@ func main() {
 	
 	if len(args) > 0 {
 		// Profile path provided as argument
-		zenProfilePath = args[0]
+		resolved, err := profiles.ResolvePath(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		zenProfilePath = resolved
 	} else {
 		// Try auto-discovery
 		defaultProfile, err := profiles.GetDefaultProfile()
@@ -189,36 +335,214 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Default Arc data path
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: could not determine home directory: %v\n", err)
-		os.Exit(1)
+	// Arc data given with -arc-data, or that of the release, beta or dev
+	// build, or a sandboxed or Windows install
+	var arcDataPath string
+	var arcCandidates []arcdata.Candidate
+	if *arcDataFlag != "" {
+		var err error
+		arcDataPath, err = arcdata.ResolvePath(*arcDataFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not determine home directory: %v\n", err)
+			os.Exit(1)
+		}
+		var arcData *arcdata.Candidate
+		arcData, arcCandidates, err = arcdata.Locate(homeDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Make sure Arc is installed and has been used, or pass its StorableSidebar.json with -arc-data.\n")
+			os.Exit(1)
+		}
+		arcDataPath = arcData.Path
 	}
-
-	arcDataPath := filepath.Join(homeDir, "Library", "Application Support", "Arc", "StorableSidebar.json")
-
-	// Check if Arc data exists
-	if _, err := os.Stat(arcDataPath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: Arc browser data not found at: %s\n", arcDataPath)
-		fmt.Fprintf(os.Stderr, "Make sure Arc is installed and has been used.\n")
-		os.Exit(1)
+	if len(arcCandidates) > 1 {
+		fmt.Printf("Found Arc data for %d installs; using the most recently changed:\n", len(arcCandidates))
+		for _, candidate := range arcCandidates {
+			marker := " "
+			if candidate.Path == arcDataPath {
+				marker = "→"
+			}
+			fmt.Printf("  %s %s (%s, changed %s)\n", marker, candidate.Path, candidate.Channel, candidate.Modified.Format("2006-01-02 15:04"))
+		}
+		fmt.Println("")
 	}
 
 	// Create importer with options
 	opts := importer.ImportOptions{
-		DryRun:  *dryRun,
-		Verbose: *verbose,
+		DryRun:                *dryRun,
+		Verbose:               *verbose,
+		Strict:                *strict,
+		FailFast:              *failFast,
+		SimulateRestore:       *simulateRestore,
+		Only:                  *only,
+		EmptyURLs:             *emptyURLs,
+		ShareLinks:            *shareLinks,
+		EmptyFolders:          *emptyFolders,
+		MergeSmallFolders:     *mergeSmallFolders,
+		Sort:                  *sortOrder,
+		TagPrefix:             *tagPrefix,
+		TriggeringPrincipals:  principals,
+		StripTracking:         *stripTracking,
+		AlsoBookmarks:         *alsoBookmarks,
+		SpaceIconFromFavicons: *spaceIconFromFavicons,
+		LetterAvatars:         *letterAvatars,
+		EmojiFromName:         *emojiFromName,
+		Theme:                 *theme,
+		CollapsePinnedOver:    *collapsePinned,
+		SpaceSettings:         spaceSettings,
+		ZenVersion:            *zenVersion,
+		ArcProfile:            *arcProfile,
+		Exclude:               exclude,
+		ProfileContainers:     profileContainers,
+		DomainContainers:      domainContainers,
+		UpdateContainers:      *updateContainers,
+		RepairContainers:      *repairContainers,
+		CombineSpaces:         combineSpaces,
+		FavoritesAsEssentials: *favoritesAsEssentials,
+		Mode:                  *mode,
+		MergeStrategy:         *merge,
+		Quick:                 *quick,
+		SplitSpaces:           splitSpaces,
+		AllowPrivateHosts:     *allowPrivateHosts,
+		SessionBudget:         int64(*sessionBudget) << 20,
+		OverBudget:            *overBudget,
+		FaviconStorage:        *faviconStorage,
+	}
+
+	// Mapping tables, extended by the user's file
+	if *mappingsPath == "" {
+		if path, err := mappings.DefaultTablesPath(); err == nil {
+			*mappingsPath = path
+		}
+	}
+	if *mappingsPath != "" {
+		tables, err := mappings.LoadTables(*mappingsPath)
+		if err == nil {
+			err = tables.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid mappings file %s:\n%v\n", *mappingsPath, err)
+			fmt.Fprintf(os.Stderr, "\nRun 'arc-to-zen mappings validate' for details.\n")
+			os.Exit(1)
+		}
+		mappings.Use(tables)
+	}
+
+	// URL rewrite rules
+	if *rewritesPath == "" {
+		if path, err := rewrite.DefaultPath(); err == nil {
+			*rewritesPath = path
+		}
+	}
+	if *rewritesPath != "" {
+		rules, err := rewrite.Load(*rewritesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.URLRewrites = rules
+	}
+
+	// Domain rules from the config file, extended by flags
+	if rulesPath, err := favicon.DefaultDomainRulesPath(); err == nil {
+		rules, err := favicon.LoadDomainRules(rulesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.FaviconDomains = rules
+	}
+	opts.FaviconDomains.Deny = append(opts.FaviconDomains.Deny, faviconDeny...)
+	opts.FaviconDomains.Force = append(opts.FaviconDomains.Force, faviconForce...)
+	providers, err := favicon.ParseProviders(*faviconProvider)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.FaviconProviders = providers
+	stdin := bufio.NewReader(os.Stdin)
+	var savedSelection map[string]bool
+	if *selectionPath != "" {
+		var err error
+		savedSelection, err = importer.LoadSelection(*selectionPath)
+		if err != nil && !(*interactive && errors.Is(err, os.ErrNotExist)) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !*interactive {
+			opts.Select = importer.SavedSelector(savedSelection)
+		}
+	}
+	if *interactive {
+		opts.Select = promptSelection(stdin, savedSelection, *selectionPath)
+	}
+	if *chooseContainers {
+		opts.AssignContainer = promptContainerAssignment(stdin)
+	}
+	if !*yes {
+		opts.Confirm = promptConfirmWrite(stdin)
+	}
+	if !*repairContainers && !*yes {
+		opts.ConfirmRepair = promptConfirmRepair(stdin)
+	}
+	if *faviconAuditLog != "" {
+		auditFile, err := os.OpenFile(*faviconAuditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not open favicon audit log: %v\n", err)
+			os.Exit(1)
+		}
+		defer auditFile.Close()
+		opts.FaviconAudit = auditFile
+	}
+	if *unparsedItems != "" {
+		unparsedFile, err := os.Create(*unparsedItems)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not create unparsed items file: %v\n", err)
+			os.Exit(1)
+		}
+		defer unparsedFile.Close()
+		opts.UnparsedItems = unparsedFile
 	}
 	imp := importer.NewWithOptions(zenProfilePath, nil, opts)
 
 	// Perform import
-	result, err := imp.Import(arcDataPath)
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	result, err := imp.ImportContext(ctx, arcDataPath)
+	if *asJSON {
+		os.Exit(printImportReport(zenProfilePath, arcDataPath, *dryRun, result, err))
+	}
+	if errors.Is(err, importer.ErrNotConfirmed) {
+		fmt.Fprintf(os.Stderr, "\nImport canceled; nothing was written.\n")
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nError: import failed: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *faviconAuditLog != "" {
+		fmt.Printf("Favicon requests logged to %s\n", *faviconAuditLog)
+	}
+	if *unparsedItems != "" {
+		fmt.Printf("%d Arc items that could not be understood written to %s\n", len(result.UnparsedItems), *unparsedItems)
+	}
+
+	if len(result.SpaceErrors) > 0 {
+		fmt.Fprintf(os.Stderr, "\nImport finished, but %d of the Arc spaces were skipped (see above; use -fail-fast to abort instead)\n", len(result.SpaceErrors))
+		os.Exit(1)
+	}
+
 	if result.Success {
 		if *dryRun {
 			fmt.Println("\n✓ Dry-run completed successfully (no changes made)")
@@ -232,6 +556,192 @@ func main() {
 	}
 }
 
+// keyValueFlag collects repeated "key=value" flag values
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+	if !ok || key == "" || val == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[key] = val
+	return nil
+}
+
+// splitSpaceFlag collects "name:size" pairs from repeated -split-space flags
+type splitSpaceFlag map[string]int
+
+func (f splitSpaceFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, size := range f {
+		pairs = append(pairs, fmt.Sprintf("%s:%d", name, size))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (f splitSpaceFlag) Set(value string) error {
+	colon := strings.LastIndex(value, ":")
+	if colon <= 0 {
+		return fmt.Errorf("expected name:size, got %q", value)
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(value[colon+1:]))
+	if err != nil {
+		return fmt.Errorf("expected name:size, got %q", value)
+	}
+	f[strings.TrimSpace(value[:colon])] = size
+	return nil
+}
+
+// combineFlag collects "Space+Space=Workspace" from repeated -combine flags
+type combineFlag map[string][]string
+
+func (f combineFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for target, sources := range f {
+		pairs = append(pairs, strings.Join(sources, "+")+"="+target)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (f combineFlag) Set(value string) error {
+	sources, target, ok := strings.Cut(value, "=")
+	target = strings.TrimSpace(target)
+	if !ok || target == "" {
+		return fmt.Errorf("expected Space+Space=Workspace, got %q", value)
+	}
+	for _, source := range strings.Split(sources, "+") {
+		if source = strings.TrimSpace(source); source != "" {
+			f[target] = append(f[target], source)
+		}
+	}
+	if len(f[target]) == 0 {
+		return fmt.Errorf("expected Space+Space=Workspace, got %q", value)
+	}
+	return nil
+}
+
+// listFlag collects comma-separated values from repeated flags
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*f = append(*f, item)
+		}
+	}
+	return nil
+}
+
+// promptConfirmWrite shows what the import is about to write and waits for
+// Enter. Anything else, or no terminal to answer from, cancels.
+func promptConfirmWrite(reader *bufio.Reader) importer.Confirmer {
+	return func(summary *importer.WriteSummary) bool {
+		fmt.Println("")
+		fmt.Println(summary)
+		fmt.Print("Press Enter to write, or type n to cancel (-yes skips this): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("")
+			fmt.Fprintln(os.Stderr, "No answer on stdin; pass -yes to import without confirming.")
+			return false
+		}
+		return strings.TrimSpace(input) == ""
+	}
+}
+
+// promptConfirmRepair asks on stdin whether to repair duplicate containers
+func promptConfirmRepair(reader *bufio.Reader) importer.RepairConfirmer {
+	return func(problems []string) bool {
+		fmt.Println("")
+		fmt.Println("Zen can't tell these containers apart, so tabs may open in the wrong one:")
+		for _, problem := range problems {
+			fmt.Printf("  • %s\n", problem)
+		}
+		fmt.Println("Repairing merges same-name containers, moving their tabs, and gives the copies of a shared ID a new one.")
+		fmt.Print("Repair them and continue? [y/N] (-repair-containers skips this): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("")
+			return false
+		}
+		answer := strings.ToLower(strings.TrimSpace(input))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// promptContainerAssignment asks on stdin which container each Arc profile should use
+func promptContainerAssignment(reader *bufio.Reader) importer.ContainerAssigner {
+	return func(profile *importer.ProfileInfo, existing []types.ContainerIdentity) string {
+		fmt.Printf("\nArc profile \"%s\" (%s)\n", profile.DisplayName, profile.Name)
+		fmt.Println(strings.Repeat("-", 60))
+		for i, container := range existing {
+			name := container.Name
+			if name == "" {
+				name = container.L10nID
+			}
+			fmt.Printf("[%d] %s (ID: %d)\n", i+1, name, container.GetUserContextID())
+		}
+		fmt.Printf("[n] New container \"%s\"\n", profile.DisplayName)
+		fmt.Printf("[0] No container\n")
+		fmt.Println(strings.Repeat("-", 60))
+
+		for {
+			fmt.Print("Select container (Enter for default): ")
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return ""
+			}
+
+			input = strings.TrimSpace(input)
+			switch input {
+			case "":
+				return ""
+			case "n", "N":
+				return importer.ContainerNew
+			case "0":
+				return importer.ContainerNone
+			}
+
+			var selection int
+			if _, err := fmt.Sscanf(input, "%d", &selection); err == nil && selection >= 1 && selection <= len(existing) {
+				return strconv.Itoa(existing[selection-1].GetUserContextID())
+			}
+			fmt.Printf("Invalid selection: must be n, 0, or between 1 and %d\n", len(existing))
+		}
+	}
+}
+
+// resolveProfilePath returns the profile path from args, or auto-discovers the default profile
+func resolveProfilePath(args []string) (string, error) {
+	if len(args) > 0 {
+		return profiles.ResolvePath(args[0])
+	}
+
+	defaultProfile, err := profiles.GetDefaultProfile()
+	if err != nil {
+		return "", fmt.Errorf("no profile path provided and auto-discovery failed: %w", err)
+	}
+	fmt.Printf("Using auto-discovered profile: %s\n", defaultProfile.Name)
+	fmt.Printf("Profile path: %s\n\n", defaultProfile.Path)
+	return defaultProfile.Path, nil
+}
+
 func decompressFile(path string) error {
 	// Read compressed file
 	compressedData, err := os.ReadFile(path)
@@ -257,70 +767,6 @@ func decompressFile(path string) error {
 		return fmt.Errorf("failed to format JSON: %w", err)
 	}
 
-	fmt.Println(string(prettyJSON))
+	fmt.Fprintln(jsonStdout, string(prettyJSON))
 	return nil
 }
-
-func printUsage() {
-	fmt.Println("Arc to Zen Browser Import Tool")
-	fmt.Println("")
-	fmt.Println("Usage:")
-	fmt.Println("  arc-to-zen [options] [zen-profile-path]")
-	fmt.Println("")
-	fmt.Println("Options:")
-	fmt.Println("  -dry-run              Show what would be imported/reset without making changes")
-	fmt.Println("  -verbose              Show detailed output during import")
-	fmt.Println("  -reset                Reset the profile to default state (removes session files)")
-	fmt.Println("  -list                 List all available Zen profiles")
-	fmt.Println("  -decompress <file>    Decompress a Mozilla LZ4 file and print JSON to stdout")
-	fmt.Println("  -backup               Create a timestamped backup of zen-sessions.jsonlz4")
-	fmt.Println("  -restore              Restore a backup of zen-sessions.jsonlz4")
-	fmt.Println("")
-	fmt.Println("Favicon Cache:")
-	fmt.Println("  -favicon-stats        Show favicon cache statistics")
-	fmt.Println("  -favicon-retry-failed Clear failed entries so they retry on next import")
-	fmt.Println("  -favicon-clear-cache  Clear entire cache for fresh fetch on next import")
-	fmt.Println("")
-	fmt.Println("Profile Path:")
-	fmt.Println("  If no profile path is provided, the tool will auto-discover your default")
-	fmt.Println("  Zen profile. Use -list to see all available profiles.")
-	fmt.Println("")
-	fmt.Println("Examples:")
-	fmt.Println("  # Auto-discover and import")
-	fmt.Println("  arc-to-zen")
-	fmt.Println("")
-	fmt.Println("  # List available profiles")
-	fmt.Println("  arc-to-zen -list")
-	fmt.Println("")
-	fmt.Println("  # Import with explicit profile path")
-	fmt.Println("  arc-to-zen \"~/Library/Application Support/zen/Profiles/xxx.default\"")
-	fmt.Println("")
-	fmt.Println("  # Reset profile to default state")
-	fmt.Println("  arc-to-zen -reset")
-	fmt.Println("")
-	fmt.Println("  # Dry-run to see what would happen")
-	fmt.Println("  arc-to-zen -dry-run")
-	fmt.Println("  arc-to-zen -reset -dry-run")
-	fmt.Println("")
-	fmt.Println("  # Decompress a .jsonlz4 file")
-	fmt.Println("  arc-to-zen -decompress default")
-	fmt.Println("  arc-to-zen -decompress default > output.json")
-	fmt.Println("  arc-to-zen -decompress /path/to/zen-sessions.jsonlz4")
-	fmt.Println("")
-	fmt.Println("  # Create a backup of zen-sessions.jsonlz4")
-	fmt.Println("  arc-to-zen -backup")
-	fmt.Println("")
-	fmt.Println("  # Restore a backup (interactive menu)")
-	fmt.Println("  arc-to-zen -restore")
-	fmt.Println("")
-	fmt.Println("  # View favicon cache stats")
-	fmt.Println("  arc-to-zen -favicon-stats")
-	fmt.Println("")
-	fmt.Println("  # Retry failed favicon fetches")
-	fmt.Println("  arc-to-zen -favicon-retry-failed")
-	fmt.Println("")
-	fmt.Println("  # Clear favicon cache for fresh fetch")
-	fmt.Println("  arc-to-zen -favicon-clear-cache")
-	fmt.Println("")
-	fmt.Println("This tool imports Arc browser spaces, folders, and tabs into Zen browser.")
-}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"arc-to-zen/mappings"
+)
+
+// runMappings handles the "mappings" subcommand and returns the exit code
+func runMappings(args []string) int {
+	if len(args) == 0 {
+		printMappingsUsage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("mappings "+args[0], flag.ContinueOnError)
+	fs.Usage = printMappingsUsage
+	defaults := fs.Bool("defaults", false, "Dump the built-in tables without the user file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 || (*defaults && args[0] != "dump") {
+		printMappingsUsage()
+		return 1
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		var err error
+		if path, err = mappings.DefaultTablesPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	switch args[0] {
+	case "dump":
+		tables := mappings.DefaultTables()
+		if !*defaults {
+			var err error
+			if tables, err = mappings.LoadTables(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		data, err := tables.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		os.Stdout.Write(data)
+		return 0
+	case "validate":
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Printf("No mappings file at %s; the built-in tables are used.\n", path)
+		}
+		tables, err := mappings.LoadTables(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := tables.Validate(); err != nil {
+			problems := strings.Split(err.Error(), "\n")
+			fmt.Fprintf(os.Stderr, "%d invalid mappings in %s:\n", len(problems), path)
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "  • %s\n", problem)
+			}
+			return 1
+		}
+		fmt.Printf("✓ Mappings are valid (%d workspace icons, %d container icons, %d colors)\n",
+			len(tables.WorkspaceIcons), len(tables.ContainerIcons), len(tables.Colors))
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown mappings command: %s\n\n", args[0])
+		printMappingsUsage()
+		return 1
+	}
+}
+
+func printMappingsUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen mappings dump [-defaults] [mappings-file]")
+	fmt.Println("  arc-to-zen mappings validate [mappings-file]")
+	fmt.Println("")
+	fmt.Println("The icon and color tables are built in and can be extended by a JSON file in")
+	fmt.Println("the same format as `dump` prints (default: mappings.json in the config dir).")
+	fmt.Println("Entries in the file add to or replace the built-in ones; \"\" removes one.")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"arc-to-zen/manifest"
+)
+
+// runOrigins handles the "origins" subcommand and returns the exit code
+func runOrigins(args []string) int {
+	fs := flag.NewFlagSet("origins", flag.ContinueOnError)
+	fs.Usage = printOriginsUsage
+	asJSON := fs.Bool("json", false, "Print the audit as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printOriginsUsage()
+		return 1
+	}
+
+	zenProfilePath, err := resolveProfilePath(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\nPlease provide a profile path or use --list to see available profiles.\n")
+		return 1
+	}
+
+	audit, m, err := manifest.AuditProfile(zenProfilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(audit)
+	}
+
+	if len(m.Imports) == 0 {
+		fmt.Printf("No imports recorded in %s; everything in this profile was made in Zen\n", manifest.FileName)
+		fmt.Println("(or imported by a version of arc-to-zen that didn't keep a record).")
+		return 0
+	}
+	fmt.Printf("%d imports recorded, the last on %s\n\n", len(m.Imports), formatImportTime(m.Imports[len(m.Imports)-1].Time))
+
+	fmt.Println("Workspaces:")
+	for _, workspace := range audit.Workspaces {
+		if len(workspace.Imports) == 0 {
+			fmt.Printf("  • %q: made in Zen\n", workspace.Name)
+			continue
+		}
+		for i, record := range workspace.Imports {
+			verb := "re-imported"
+			if i == 0 && !record.Merged {
+				verb = "created"
+			}
+			prefix := fmt.Sprintf("  • %q:", workspace.Name)
+			if i > 0 {
+				prefix = strings.Repeat(" ", len([]rune(prefix)))
+			}
+			fmt.Printf("%s %s from Arc space %s, %s\n", prefix, verb, describeArcSpace(record.Space), describeSource(record.Source))
+		}
+	}
+
+	if len(audit.Containers) > 0 {
+		fmt.Println("")
+		fmt.Println("Containers:")
+		for _, container := range audit.Containers {
+			label := fmt.Sprintf("%d %q", container.UserContextID, container.Name)
+			if len(container.Imports) == 0 {
+				fmt.Printf("  • %s: not used by any import\n", label)
+				continue
+			}
+			first := container.Imports[0]
+			verb := "reused"
+			if first.Created {
+				verb = "created"
+			}
+			fmt.Printf("  • %s: %s for Arc profile %q, %s", label, verb, first.ArcProfile, describeSource(first.Source))
+			if later := len(container.Imports) - 1; later > 0 {
+				fmt.Printf("; used by %d later imports", later)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(audit.Removed) > 0 {
+		fmt.Println("")
+		fmt.Println("Imported workspaces no longer in the session:")
+		for _, record := range audit.Removed {
+			fmt.Printf("  • %q from Arc space %s, %s\n", record.Name, describeArcSpace(record.Space), describeSource(record.Source))
+		}
+	}
+	return 0
+}
+
+// describeArcSpace formats an Arc space and its profile, as `"Work" (profile "Profile 1")`
+func describeArcSpace(space manifest.Space) string {
+	name := space.ArcName
+	if name == "" {
+		name = space.Name
+	}
+	if space.ArcProfile == "" {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("%q (profile %q)", name, space.ArcProfile)
+}
+
+// describeSource formats when and by what an entry was imported
+func describeSource(source manifest.Source) string {
+	id := source.ImportID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("%s (import %s, arc-to-zen %s)", formatImportTime(source.Time), id, source.ToolVersion)
+}
+
+func formatImportTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func printOriginsUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen origins [-json] [profile-path]")
+	fmt.Println("")
+	fmt.Println("Shows which Zen workspaces and containers came from which Arc spaces and")
+	fmt.Println("profiles, and when, from the import record kept in the profile")
+	fmt.Println("(arc-to-zen.json). Workspaces without a record were made in Zen.")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"arc-to-zen/importer"
+	"arc-to-zen/places"
+)
+
+// jsonStdout is where -json documents go. It is os.Stdout until
+// redirectStdout moves everything else to stderr.
+var jsonStdout io.Writer = os.Stdout
+
+// redirectStdout makes os.Stdout stderr for the rest of the run, so the
+// progress, prompts and logger output printed throughout the tool stay off
+// the JSON document written to the real stdout (-json)
+func redirectStdout() {
+	jsonStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// printJSON writes v to stdout as indented JSON and returns the exit code
+func printJSON(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(jsonStdout, string(data))
+	return 0
+}
+
+// faviconStatsReport is -favicon-stats with -json
+type faviconStatsReport struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+}
+
+// cacheClearedReport is -favicon-retry-failed and -favicon-clear-cache with -json
+type cacheClearedReport struct {
+	Cleared int `json:"cleared"`
+}
+
+// importReport is the import (or dry run) with -json
+type importReport struct {
+	Profile       string             `json:"profile"`
+	ArcData       string             `json:"arcData"`
+	DryRun        bool               `json:"dryRun"`
+	Success       bool               `json:"success"`
+	Canceled      bool               `json:"canceled,omitempty"` // Not confirmed; nothing was written
+	Error         string             `json:"error,omitempty"`
+	ImportID      string             `json:"importId,omitempty"`
+	SpacesCreated int                `json:"spacesCreated"`
+	ItemsImported int                `json:"itemsImported"`
+	Containers    int                `json:"containers"`
+	FaviconTabs   int                `json:"faviconTabs"`
+	FaviconImages int                `json:"faviconImages"`
+	FaviconsInDB  int                `json:"faviconsStored,omitempty"` // -favicon-storage sqlite
+	Warnings      []importer.Warning `json:"warnings"`
+	SpaceErrors   []string           `json:"spaceErrors,omitempty"`
+	Repaired      []string           `json:"repairedContainers,omitempty"` // Duplicate containers repaired first
+	Bookmarks     *places.Result     `json:"bookmarks,omitempty"`          // -also-bookmarks
+	Plan          *importer.Plan     `json:"plan,omitempty"`
+}
+
+// printImportReport prints what ImportContext returned as an importReport
+// and returns the exit code the text output would have had
+func printImportReport(profilePath, arcDataPath string, dryRun bool, result *importer.ImportResult, err error) int {
+	report := importReport{Profile: profilePath, ArcData: arcDataPath, DryRun: dryRun, Warnings: []importer.Warning{}}
+	if err != nil {
+		report.Error = err.Error()
+		report.Canceled = errors.Is(err, importer.ErrNotConfirmed)
+	}
+	if result != nil {
+		report.Success = result.Success && len(result.SpaceErrors) == 0
+		report.ImportID = result.ImportID
+		report.SpacesCreated = result.SpacesCreated
+		report.ItemsImported = result.ItemsImported
+		report.Containers = result.ContainersCount
+		report.FaviconTabs = result.FaviconTabs
+		report.FaviconImages = result.FaviconImages
+		report.FaviconsInDB = result.FaviconsStored
+		if result.Warnings != nil {
+			report.Warnings = result.Warnings
+		}
+		for _, spaceErr := range result.SpaceErrors {
+			report.SpaceErrors = append(report.SpaceErrors, spaceErr.Error())
+		}
+		report.Repaired = result.RepairedContainers
+		report.Bookmarks = result.Bookmarks
+		report.Plan = result.Plan
+	}
+	if code := printJSON(report); code != 0 {
+		return code
+	}
+	if !report.Success {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"arc-to-zen/backup"
+	"arc-to-zen/lock"
+	"arc-to-zen/tag"
+)
+
+// runUntag handles the "untag" subcommand and returns the exit code
+func runUntag(args []string) int {
+	fs := flag.NewFlagSet("untag", flag.ContinueOnError)
+	fs.Usage = printUntagUsage
+	prefix := fs.String("prefix", tag.DefaultPrefix, "The label prefix the import added with -tag-prefix")
+	dryRun := fs.Bool("dry-run", false, "Count the tagged tabs without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 || *prefix == "" {
+		printUntagUsage()
+		return 1
+	}
+
+	zenProfilePath, err := resolveProfilePath(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\nPlease provide a profile path or use --list to see available profiles.\n")
+		return 1
+	}
+
+	if !*dryRun {
+		profileLock, err := lock.Acquire(zenProfilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer profileLock.Release()
+
+		if err := backup.CreateBackup(zenProfilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: backup failed: %v\n", err)
+			return 1
+		}
+	}
+
+	tabs, err := tag.Profile(zenProfilePath, *prefix, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if tabs == 0 {
+		fmt.Printf("No tab labels start with %q; nothing to untag.\n", *prefix)
+		return 0
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "[DRY-RUN] Would remove"
+	}
+	fmt.Printf("✓ %s %q from %d tab labels\n", verb, *prefix, tabs)
+	if !*dryRun {
+		fmt.Println("Restart Zen Browser to load the untagged session.")
+	}
+	return 0
+}
+
+func printUntagUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen untag [-prefix \"[arc] \"] [-dry-run] [profile-path]")
+	fmt.Println("")
+	fmt.Println("Removes the prefix an import added to tab labels with -tag-prefix, once the")
+	fmt.Println("migrated pins no longer need telling apart from native ones. The session is")
+	fmt.Println("backed up first. Close Zen before running it.")
+}
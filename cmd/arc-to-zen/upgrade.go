@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"arc-to-zen/backup"
+	"arc-to-zen/lock"
+	"arc-to-zen/upgrade"
+)
+
+// runUpgradeSession handles the "upgrade-session" subcommand and returns the exit code
+func runUpgradeSession(args []string) int {
+	fs := flag.NewFlagSet("upgrade-session", flag.ContinueOnError)
+	fs.Usage = printUpgradeSessionUsage
+	dryRun := fs.Bool("dry-run", false, "List what would be upgraded without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printUpgradeSessionUsage()
+		return 1
+	}
+
+	zenProfilePath, err := resolveProfilePath(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\nPlease provide a profile path or use --list to see available profiles.\n")
+		return 1
+	}
+
+	if !*dryRun {
+		profileLock, err := lock.Acquire(zenProfilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer profileLock.Release()
+
+		if err := backup.CreateBackup(zenProfilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: backup failed: %v\n", err)
+			return 1
+		}
+		if err := backup.CreateContainersBackup(zenProfilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: backup failed: %v\n", err)
+			return 1
+		}
+	}
+
+	result, err := upgrade.Profile(zenProfilePath, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(result.Findings) == 0 {
+		fmt.Println("No artifacts of older versions found; the session is up to date.")
+		return 0
+	}
+
+	verb := "Upgraded"
+	if *dryRun {
+		verb = "[DRY-RUN] Would upgrade"
+	}
+	fmt.Printf("✓ %s %d artifacts of older arc-to-zen versions:\n", verb, len(result.Findings))
+	for _, finding := range result.Findings {
+		fmt.Printf("  • %s\n", finding)
+	}
+	if !*dryRun {
+		fmt.Println("Restart Zen Browser to load the upgraded session.")
+	}
+	return 0
+}
+
+func printUpgradeSessionUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  arc-to-zen upgrade-session [-dry-run] [profile-path]")
+	fmt.Println("")
+	fmt.Println("Rewrites what older versions of arc-to-zen left in a session to the current")
+	fmt.Println("structure: folders without an anchor tab or groups entry, stale emptyTabIds,")
+	fmt.Println("prevSiblingInfo pointing at tabs, and duplicate containers (tabs move to the")
+	fmt.Println("one kept). The session is backed up first. Close Zen before running it.")
+}
@@ -0,0 +1,198 @@
+// Package compact shrinks a Zen session by removing the favicon images that
+// are embedded in its tabs as base64 data URLs. Older versions of arc-to-zen
+// inlined an image in every imported tab, up to three times each, which makes
+// zen-sessions.jsonlz4 large and slow for Zen to read and rewrite. Zen shows
+// the favicons again from its own icon store once the pages load, or, with
+// Options.MoveFavicons, straight away from favicons.sqlite.
+package compact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/places"
+	"arc-to-zen/principal"
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+)
+
+// Options changes what Profile does
+type Options struct {
+	DryRun bool // Report what would change without writing anything
+	// MoveFavicons writes the images into the profile's favicons.sqlite and
+	// has the tabs load them from there, as -favicon-storage sqlite does for
+	// imports, instead of dropping them
+	MoveFavicons bool
+}
+
+// Result describes what compacting a session removed
+type Result struct {
+	Tabs       int   // Tabs that had an embedded image
+	Images     int   // Data URLs removed (a tab can carry up to three)
+	Moved      int   // Pages whose icon was written to favicons.sqlite (or would be) with MoveFavicons
+	SizeBefore int64 // Compressed session size before
+	SizeAfter  int64 // Compressed session size after (or that it would have, in dry-run)
+}
+
+// Strip replaces the data URL images of session's tabs with null and returns
+// how many tabs had one and how many images were removed. Icons given by URL
+// are small and kept.
+func Strip(session *types.ZenSession) (tabs, images int) {
+	for i := range session.Tabs {
+		if removed := replaceImages(&session.Tabs[i], nil); removed > 0 {
+			tabs++
+			images += removed
+		}
+	}
+	return tabs, images
+}
+
+// Move points the tabs with data URL images at the icon favicons.sqlite
+// has for their page (places.PageIconURL) and returns the icons to write
+// there, one per page. Images that can't be converted for the database
+// (favicon.ForDatabase), and those of tabs without a page, are dropped as
+// Strip does.
+func Move(session *types.ZenSession) (icons []places.Favicon, tabs, images int) {
+	// Firefox only loads a tab icon that isn't a data URL with a principal
+	loadingPrincipal, _ := json.Marshal(principal.SystemBase64)
+
+	type converted struct {
+		data        []byte
+		contentType string
+		width       int
+		err         error
+	}
+	conversions := make(map[string]*converted) // Tabs of one host share an image
+	stored := make(map[string]bool)
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		dataURL := embeddedImage(tab)
+		if dataURL == "" {
+			continue
+		}
+		tabs++
+		page := pageURL(tab)
+		c := conversions[dataURL]
+		if c == nil {
+			c = &converted{}
+			c.data, c.contentType, c.width, c.err = favicon.ForDatabase(dataURL)
+			conversions[dataURL] = c
+		}
+		if page == "" || c.err != nil {
+			images += replaceImages(tab, nil)
+			continue
+		}
+
+		images += replaceImages(tab, places.PageIconURL(page))
+		if tab.Extra == nil {
+			tab.Extra = make(map[string]json.RawMessage)
+		}
+		tab.Extra["iconLoadingPrincipal"] = loadingPrincipal
+		if !stored[page] {
+			stored[page] = true
+			icons = append(icons, places.Favicon{PageURL: page, Data: c.data, ContentType: c.contentType, Width: c.width})
+		}
+	}
+	return icons, tabs, images
+}
+
+// replaceImages sets the data URL images of tab to image and returns how
+// many there were. A pinned icon replaced with nil is no longer static.
+func replaceImages(tab *types.ZenTab, image interface{}) int {
+	replaced := 0
+	if isDataURL(tab.Image) {
+		tab.Image = image
+		replaced++
+	}
+	if isDataURL(tab.ZenPinnedIcon) {
+		tab.ZenPinnedIcon = image
+		if image == nil {
+			tab.ZenHasStaticIcon = false
+		}
+		replaced++
+	}
+	if state, ok := tab.ZenPinnedInitialState.(map[string]interface{}); ok && isDataURL(state["image"]) {
+		state["image"] = image
+		replaced++
+	}
+	return replaced
+}
+
+// embeddedImage returns the first data URL image of tab, or ""
+func embeddedImage(tab *types.ZenTab) string {
+	candidates := []interface{}{tab.Image, tab.ZenPinnedIcon}
+	if state, ok := tab.ZenPinnedInitialState.(map[string]interface{}); ok {
+		candidates = append(candidates, state["image"])
+	}
+	for _, v := range candidates {
+		if isDataURL(v) {
+			return v.(string)
+		}
+	}
+	return ""
+}
+
+// pageURL returns the URL of the page tab shows: its current history entry
+func pageURL(tab *types.ZenTab) string {
+	if len(tab.Entries) == 0 {
+		return ""
+	}
+	i := tab.Index - 1
+	if i < 0 || i >= len(tab.Entries) {
+		i = len(tab.Entries) - 1
+	}
+	return tab.Entries[i].URL
+}
+
+// Profile compacts the session of the Zen profile at profilePath. The caller
+// backs the session up first. With MoveFavicons the icons are written to
+// favicons.sqlite before the session that refers to them. In dry-run
+// nothing is written.
+func Profile(profilePath string, opts Options) (*Result, error) {
+	session, size, err := sessionfile.Read(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{SizeBefore: size, SizeAfter: size}
+	var icons []places.Favicon
+	if opts.MoveFavicons {
+		if _, err := os.Stat(places.FaviconsPath(profilePath)); err != nil {
+			return nil, fmt.Errorf("%s not found (start Zen once, or compact without moving the favicons): %w", places.FaviconsFileName, err)
+		}
+		icons, result.Tabs, result.Images = Move(session)
+		result.Moved = len(icons)
+	} else {
+		result.Tabs, result.Images = Strip(session)
+	}
+	if result.Images == 0 {
+		return result, nil
+	}
+
+	compacted, err := sessionfile.Encode(session)
+	if err != nil {
+		return nil, err
+	}
+	result.SizeAfter = int64(len(compacted))
+	if opts.DryRun {
+		return result, nil
+	}
+	if len(icons) > 0 {
+		if _, err := places.WriteFavicons(context.Background(), places.FaviconsPath(profilePath), icons); err != nil {
+			return nil, fmt.Errorf("favicons not written: %w", err)
+		}
+	}
+	if err := sessionfile.WriteAtomic(sessionfile.Path(profilePath), compacted); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func isDataURL(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(s, "data:")
+}
@@ -0,0 +1,157 @@
+package compact
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/places"
+	"arc-to-zen/sessionfile"
+)
+
+func writeSession(t *testing.T, dir, raw string) []byte {
+	t.Helper()
+	compressed, err := mozlz4.Compress([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sessionfile.Path(dir), compressed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return compressed
+}
+
+func TestProfile(t *testing.T) {
+	icon := "data:image/png;base64," + strings.Repeat("QUJD", 2000)
+	raw := `{"spaces": [], "tabs": [
+		{"entries": [{"url": "https://a.example/"}], "image": "` + icon + `", "zenPinnedIcon": "` + icon + `",
+		 "zenHasStaticIcon": true, "_zenPinnedInitialState": {"entry": {"url": "https://a.example/"}, "image": "` + icon + `"},
+		 "futureField": {"kept": 1}},
+		{"entries": [{"url": "https://b.example/"}], "image": "https://b.example/favicon.ico"}
+	], "folders": [], "groups": [], "splitViewData": []}`
+
+	dir := t.TempDir()
+	original := writeSession(t, dir, raw)
+
+	// Dry run reports without writing
+	result, err := Profile(dir, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if result.Tabs != 1 || result.Images != 3 || result.SizeAfter >= result.SizeBefore {
+		t.Errorf("unexpected dry-run result: %+v", result)
+	}
+	if current, _ := os.ReadFile(sessionfile.Path(dir)); !bytes.Equal(current, original) {
+		t.Error("dry run changed the session file")
+	}
+
+	if _, err := Profile(dir, Options{}); err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	compressed, _ := os.ReadFile(sessionfile.Path(dir))
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session struct {
+		Tabs []map[string]interface{} `json:"tabs"`
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	first, second := session.Tabs[0], session.Tabs[1]
+	state, _ := first["_zenPinnedInitialState"].(map[string]interface{})
+	if first["image"] != nil || first["zenPinnedIcon"] != nil || first["zenHasStaticIcon"] != false || state["image"] != nil {
+		t.Errorf("expected the embedded images removed, got %v", first)
+	}
+	if first["futureField"] == nil || state["entry"] == nil {
+		t.Errorf("expected other fields kept, got %v", first)
+	}
+	if second["image"] != "https://b.example/favicon.ico" {
+		t.Errorf("expected the URL icon kept, got %v", second["image"])
+	}
+
+	// A second run finds nothing to do
+	if result, err := Profile(dir, Options{}); err != nil || result.Images != 0 {
+		t.Errorf("expected an already compact session, got %+v, %v", result, err)
+	}
+}
+
+const faviconsSchema = `
+CREATE TABLE moz_icons (id INTEGER PRIMARY KEY, icon_url TEXT NOT NULL, fixed_icon_url_hash INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0, root INTEGER NOT NULL DEFAULT 0, color INTEGER, expire_ms INTEGER NOT NULL DEFAULT 0,
+	flags INTEGER NOT NULL DEFAULT 0, data BLOB);
+CREATE TABLE moz_pages_w_icons (id INTEGER PRIMARY KEY, page_url TEXT NOT NULL, page_url_hash INTEGER NOT NULL);
+CREATE TABLE moz_icons_to_pages (page_id INTEGER NOT NULL, icon_id INTEGER NOT NULL, expire_ms INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (page_id, icon_id)) WITHOUT ROWID;
+`
+
+func TestProfile_MoveFavicons(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+	icon := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	raw := `{"spaces": [], "tabs": [
+		{"entries": [{"url": "https://a.example/"}], "index": 1, "image": "` + icon + `", "zenPinnedIcon": "` + icon + `",
+		 "_zenPinnedInitialState": {"entry": {"url": "https://a.example/"}, "image": "` + icon + `"}},
+		{"entries": [{"url": "https://b.example/old"}, {"url": "https://b.example/"}], "index": 2, "image": "` + icon + `"},
+		{"entries": [{"url": "https://c.example/"}], "index": 1, "image": "data:image/avif;base64,AAAAHGZ0eXBhdmlm"}
+	], "folders": [], "groups": [], "splitViewData": []}`
+
+	dir := t.TempDir()
+	writeSession(t, dir, raw)
+
+	// Zen creates favicons.sqlite on first start; without it nothing is written
+	if _, err := Profile(dir, Options{MoveFavicons: true}); err == nil || !strings.Contains(err.Error(), places.FaviconsFileName) {
+		t.Fatalf("expected a missing favicons.sqlite error, got %v", err)
+	}
+	db, err := sql.Open("sqlite", places.FaviconsPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(faviconsSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Profile(dir, Options{MoveFavicons: true})
+	if err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	if result.Tabs != 3 || result.Images != 5 || result.Moved != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	session, _, err := sessionfile.Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, third := session.Tabs[0], session.Tabs[1], session.Tabs[2]
+	want := places.PageIconURL("https://a.example/")
+	state, _ := first.ZenPinnedInitialState.(map[string]interface{})
+	if first.Image != want || first.ZenPinnedIcon != want || state["image"] != want || len(first.Extra["iconLoadingPrincipal"]) == 0 {
+		t.Errorf("expected the tab to refer to %s, got %v, %v, %v", want, first.Image, first.ZenPinnedIcon, state["image"])
+	}
+	if second.Image != places.PageIconURL("https://b.example/") {
+		t.Errorf("expected the icon of the current page, got %v", second.Image)
+	}
+	if third.Image != nil {
+		t.Errorf("expected the unconvertible image dropped, got %v", third.Image)
+	}
+
+	// The two pages share one stored icon
+	var icons, links int
+	db.QueryRow("SELECT COUNT(*) FROM moz_icons").Scan(&icons)
+	db.QueryRow("SELECT COUNT(*) FROM moz_icons_to_pages").Scan(&links)
+	if icons != 1 || links != 2 {
+		t.Errorf("expected 1 icon linked to 2 pages, got %d and %d", icons, links)
+	}
+}
@@ -0,0 +1,250 @@
+package containers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"arc-to-zen/mappings"
+	"arc-to-zen/types"
+)
+
+const containersFileName = "containers.json"
+
+var (
+	// ErrNotFound is returned when no container matches an ID or name
+	ErrNotFound = errors.New("container not found")
+	// ErrAmbiguousName is returned when several containers share the requested name
+	ErrAmbiguousName = errors.New("container name is ambiguous")
+)
+
+// Load reads containers.json from a Zen profile
+func Load(profilePath string) (*types.ContainersData, error) {
+	containersPath := filepath.Join(profilePath, containersFileName)
+
+	data, err := os.ReadFile(containersPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("containers.json not found at: %s", containersPath)
+		}
+		return nil, fmt.Errorf("failed to read containers: %w", err)
+	}
+
+	var containersData types.ContainersData
+	if err := json.Unmarshal(data, &containersData); err != nil {
+		return nil, fmt.Errorf("failed to parse containers: %w", err)
+	}
+
+	return &containersData, nil
+}
+
+// Save writes containers.json back to a Zen profile
+func Save(profilePath string, data *types.ContainersData) error {
+	jsonData, err := Encode(data)
+	if err != nil {
+		return err
+	}
+
+	containersPath := filepath.Join(profilePath, containersFileName)
+	if err := os.WriteFile(containersPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write containers: %w", err)
+	}
+
+	return nil
+}
+
+// Encode returns the containers.json contents Save writes, for callers that
+// write it together with other files
+func Encode(data *types.ContainersData) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal containers: %w", err)
+	}
+	return jsonData, nil
+}
+
+// Validate checks that public containers have unique userContextIds and
+// use colors and icons Firefox knows how to render
+func Validate(data *types.ContainersData) error {
+	var problems []string
+	seen := make(map[int]string)
+
+	for _, container := range data.Identities {
+		if !container.Public {
+			continue
+		}
+		name := displayName(container)
+
+		if !container.HasValidUserContextID() {
+			problems = append(problems, fmt.Sprintf("%q has no valid userContextId", name))
+		} else {
+			id := container.GetUserContextID()
+			if other, exists := seen[id]; exists {
+				problems = append(problems, fmt.Sprintf("%q and %q share userContextId %d", other, name, id))
+			} else {
+				seen[id] = name
+			}
+		}
+
+		if !mappings.IsValidContainerColor(container.Color) {
+			problems = append(problems, fmt.Sprintf("%q has invalid color %q", name, container.Color))
+		}
+		if !mappings.IsValidContainerIcon(container.Icon) {
+			problems = append(problems, fmt.Sprintf("%q has invalid icon %q", name, container.Icon))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid containers: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Duplicates describes the public containers that share a name, and the
+// containers that share a userContextId, including public ones that take the
+// ID of one of Firefox's internal containers. Zen can't tell these apart, so
+// tabs end up in the wrong container or lose theirs on restore.
+func Duplicates(data *types.ContainersData) []string {
+	var problems []string
+	var names []string
+	byName := make(map[string][]string)
+	var ids []int
+	byID := make(map[int][]string)
+
+	for _, container := range data.Identities {
+		name := displayName(container)
+		id := "no userContextId"
+		if container.HasValidUserContextID() {
+			id = strconv.Itoa(container.GetUserContextID())
+			label := strconv.Quote(name)
+			if !container.Public {
+				label = "internal " + label
+			}
+			if byID[container.GetUserContextID()] == nil {
+				ids = append(ids, container.GetUserContextID())
+			}
+			byID[container.GetUserContextID()] = append(byID[container.GetUserContextID()], label)
+		}
+		if container.Public && name != "" {
+			if byName[name] == nil {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], id)
+		}
+	}
+
+	for _, name := range names {
+		if len(byName[name]) > 1 {
+			problems = append(problems, fmt.Sprintf("%q is the name of %d containers (%s)", name, len(byName[name]), strings.Join(byName[name], ", ")))
+		}
+	}
+	for _, id := range ids {
+		if len(byID[id]) > 1 {
+			problems = append(problems, fmt.Sprintf("userContextId %d is shared by %s", id, strings.Join(byID[id], " and ")))
+		}
+	}
+	return problems
+}
+
+// Find looks up a public container by userContextId or by name
+func Find(data *types.ContainersData, idOrName string) (*types.ContainerIdentity, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		for i := range data.Identities {
+			if data.Identities[i].Public && data.Identities[i].GetUserContextID() == id {
+				return &data.Identities[i], nil
+			}
+		}
+	}
+
+	var match *types.ContainerIdentity
+	for i := range data.Identities {
+		if data.Identities[i].Public && displayName(data.Identities[i]) == idOrName {
+			if match != nil {
+				return nil, fmt.Errorf("%w: %q, use its ID instead", ErrAmbiguousName, idOrName)
+			}
+			match = &data.Identities[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, idOrName)
+	}
+	return match, nil
+}
+
+// Rename changes a container's name
+// Built-in containers lose their l10nId so the new name is actually displayed
+func Rename(data *types.ContainersData, idOrName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("container name cannot be empty")
+	}
+
+	container, err := Find(data, idOrName)
+	if err != nil {
+		return err
+	}
+
+	container.Name = newName
+	container.L10nID = ""
+	container.AccessKey = ""
+	return nil
+}
+
+// Recolor changes a container's color
+func Recolor(data *types.ContainersData, idOrName, color string) error {
+	if !mappings.IsValidContainerColor(color) {
+		return fmt.Errorf("invalid color %q (valid: %s)", color, strings.Join(mappings.ContainerColors(), ", "))
+	}
+
+	container, err := Find(data, idOrName)
+	if err != nil {
+		return err
+	}
+
+	container.Color = color
+	return nil
+}
+
+// List returns a formatted list of public containers for display
+func List(data *types.ContainersData) string {
+	var sb strings.Builder
+	sb.WriteString("Zen containers:\n\n")
+
+	count := 0
+	for _, container := range data.Identities {
+		if !container.Public {
+			continue
+		}
+		count++
+
+		idLabel := "-"
+		if container.HasValidUserContextID() {
+			idLabel = strconv.Itoa(container.GetUserContextID())
+		}
+		builtIn := ""
+		if container.L10nID != "" {
+			builtIn = " (built-in)"
+		}
+		sb.WriteString(fmt.Sprintf("  [%s] %s%s\n", idLabel, displayName(container), builtIn))
+		sb.WriteString(fmt.Sprintf("     Color: %s, Icon: %s\n", container.Color, container.Icon))
+		sb.WriteString("\n")
+	}
+
+	if count == 0 {
+		sb.WriteString("  (none)\n")
+	}
+
+	return sb.String()
+}
+
+// displayName returns the container name, falling back to the l10nId of built-in containers
+func displayName(container types.ContainerIdentity) string {
+	if container.Name != "" {
+		return container.Name
+	}
+	return container.L10nID
+}
@@ -0,0 +1,120 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func testData() *types.ContainersData {
+	return &types.ContainersData{
+		Version: 5,
+		Identities: []types.ContainerIdentity{
+			{UserContextID: intPtr(1), L10nID: "userContextPersonal.label", Icon: "fingerprint", Color: "blue", Public: true},
+			{UserContextID: intPtr(6), Name: "Shopping", Icon: "cart", Color: "pink", Public: true},
+			{UserContextID: intPtr(7), Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := testData()
+	if err := Validate(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data.Identities[1].UserContextID = intPtr(7)
+	data.Identities[2].Color = "gray"
+	err := Validate(data)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(err.Error(), "share userContextId 7") {
+		t.Errorf("expected duplicate ID error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `invalid color "gray"`) {
+		t.Errorf("expected invalid color error, got %v", err)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	if problems := Duplicates(testData()); len(problems) != 0 {
+		t.Fatalf("unexpected duplicates: %v", problems)
+	}
+
+	data := testData()
+	data.Identities = append(data.Identities,
+		types.ContainerIdentity{UserContextID: intPtr(9), Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+		types.ContainerIdentity{UserContextID: intPtr(6), Name: "Banking", Icon: "dollar", Color: "green", Public: true},
+		types.ContainerIdentity{UserContextID: intPtr(4294967295), Name: "userContextIdInternal.thumbnail", Public: false},
+		types.ContainerIdentity{UserContextID: intPtr(4294967295), Name: "Travel", Icon: "vacation", Color: "blue", Public: true},
+	)
+	want := []string{
+		`"Work" is the name of 2 containers (7, 9)`,
+		`userContextId 6 is shared by "Shopping" and "Banking"`,
+		`userContextId 4294967295 is shared by internal "userContextIdInternal.thumbnail" and "Travel"`,
+	}
+	if got := Duplicates(data); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRename(t *testing.T) {
+	data := testData()
+
+	if err := Rename(data, "6", "Personal Shopping"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Identities[1].Name != "Personal Shopping" {
+		t.Errorf("expected rename by ID, got %q", data.Identities[1].Name)
+	}
+
+	// Renaming a built-in container drops its l10nId
+	if err := Rename(data, "userContextPersonal.label", "Me"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Identities[0].Name != "Me" || data.Identities[0].L10nID != "" {
+		t.Errorf("expected built-in container to be renamed, got %+v", data.Identities[0])
+	}
+
+	if err := Rename(data, "Missing", "X"); err == nil {
+		t.Error("expected error for unknown container")
+	}
+	if err := Rename(data, "Work", "  "); err == nil {
+		t.Error("expected error for empty name")
+	}
+}
+
+func TestRecolor(t *testing.T) {
+	data := testData()
+
+	if err := Recolor(data, "Work", "purple"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Identities[2].Color != "purple" {
+		t.Errorf("expected purple, got %q", data.Identities[2].Color)
+	}
+
+	if err := Recolor(data, "Work", "gray"); err == nil {
+		t.Error("expected error for invalid color")
+	}
+}
+
+func TestFindAmbiguousName(t *testing.T) {
+	data := testData()
+	data.Identities = append(data.Identities, types.ContainerIdentity{
+		UserContextID: intPtr(8), Name: "Work", Icon: "circle", Color: "red", Public: true,
+	})
+
+	if _, err := Find(data, "Work"); err == nil {
+		t.Error("expected ambiguity error")
+	}
+	if c, err := Find(data, "8"); err != nil || c.Color != "red" {
+		t.Errorf("expected lookup by ID to succeed, got %v, %v", c, err)
+	}
+}
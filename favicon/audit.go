@@ -0,0 +1,110 @@
+package favicon
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuditEntry describes one outbound favicon request, including each redirect hop
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	URL        string    `json:"url"`
+	Status     int       `json:"status,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	Blocked    bool      `json:"blocked,omitempty"` // Refused by the private host guard; nothing was sent
+}
+
+// auditLog writes entries as JSON lines
+type auditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (a *auditLog) write(entry AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_ = a.enc.Encode(entry)
+}
+
+// SetAuditLog records every outbound favicon request to w as JSON lines. Pass
+// nil to stop.
+func (f *Fetcher) SetAuditLog(w io.Writer) {
+	if w == nil {
+		f.audit.Store(nil)
+		return
+	}
+	f.audit.Store(&auditLog{enc: json.NewEncoder(w)})
+}
+
+// auditTransport counts each round trip for NetworkStats and, if an audit log
+// is set, logs it once its body has been read and closed
+type auditTransport struct {
+	next    http.RoundTripper
+	fetcher *Fetcher
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log := t.fetcher.audit.Load()
+	counters := &t.fetcher.net
+
+	start := t.fetcher.clock.Now()
+	entry := AuditEntry{Time: start, URL: req.URL.String()}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		elapsed := t.fetcher.clock.Now().Sub(start)
+		entry.Blocked = errors.Is(err, ErrPrivateHost)
+		if !entry.Blocked {
+			counters.requests.Add(1)
+			counters.errors.Add(1)
+			counters.latency.Add(int64(elapsed))
+		}
+		if log != nil {
+			entry.DurationMs = elapsed.Milliseconds()
+			entry.Error = err.Error()
+			log.write(entry)
+		}
+		return nil, err
+	}
+
+	counters.requests.Add(1)
+	entry.Status = resp.StatusCode
+	resp.Body = &auditBody{ReadCloser: resp.Body, log: log, counters: counters, entry: entry, start: start, clock: t.fetcher.clock}
+	return resp, nil
+}
+
+// auditBody counts bytes read and records the request when closed
+type auditBody struct {
+	io.ReadCloser
+	log      *auditLog // nil if no audit log is set
+	counters *netCounters
+	entry    AuditEntry
+	start    time.Time
+	clock    Clock
+	once     sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.entry.Bytes += int64(n)
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		elapsed := b.clock.Now().Sub(b.start)
+		b.counters.bytes.Add(b.entry.Bytes)
+		b.counters.latency.Add(int64(elapsed))
+		if b.log != nil {
+			b.entry.DurationMs = elapsed.Milliseconds()
+			b.log.write(b.entry)
+		}
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	icon := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.NotFound(w, r) // No home page to find icon links in
+			return
+		}
+		if r.URL.Query().Get("moved") == "" {
+			http.Redirect(w, r, "/favicon.ico?moved=1", http.StatusFound)
+			return
+		}
+		w.Write(icon)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	f := NewWithCache(t.TempDir())
+	f.SetAuditLog(&buf)
+	if f.FetchAsDataURL(ts.URL+"/page") == "" {
+		t.Fatal("expected favicon")
+	}
+
+	var entries []AuditEntry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry AuditEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// The home page, the redirect and the final request are all logged
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	if entries[0].Status != http.StatusNotFound || entries[1].Status != http.StatusFound || entries[2].Status != http.StatusOK {
+		t.Errorf("unexpected statuses: %d, %d, %d", entries[0].Status, entries[1].Status, entries[2].Status)
+	}
+	if entries[2].Bytes != int64(len(icon)) || !strings.HasSuffix(entries[2].URL, "/favicon.ico?moved=1") {
+		t.Errorf("unexpected final entry: %+v", entries[2])
+	}
+
+	// Blocked requests are logged without anything being sent
+	buf.Reset()
+	blocked := NewWithCache(t.TempDir())
+	blocked.SetBlockPrivateHosts(true)
+	blocked.SetAuditLog(&buf)
+	blocked.FetchAsDataURL(ts.URL + "/page")
+	var entry AuditEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil || !entry.Blocked {
+		t.Errorf("expected a blocked entry, got %q (%v)", buf.String(), err)
+	}
+}
@@ -0,0 +1,432 @@
+package favicon
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // database/sql driver "sqlite"
+)
+
+// CacheFileName is the favicon cache database in the cache directory
+const CacheFileName = "favicons.db"
+
+// cacheSchema keeps one row per host and each distinct image once, so hosts
+// serving identical icons share a copy. A host whose fetch failed has no
+// image and a failure count; it isn't retried until ClearFailedCache. The
+// icon URL the host's home page led to is kept apart from the image, so
+// fetching it again (for a forced domain, or after the image was cleared)
+// skips the page.
+const cacheSchema = `
+CREATE TABLE IF NOT EXISTS images (
+	hash     TEXT PRIMARY KEY, -- sha256 of data_url
+	data_url TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS hosts (
+	host         TEXT PRIMARY KEY, -- Lowercased, port included
+	image        TEXT REFERENCES images(hash),
+	content_type TEXT NOT NULL DEFAULT '',
+	size         INTEGER NOT NULL DEFAULT 0, -- Decoded image bytes
+	fetched_at   INTEGER NOT NULL,           -- Unix seconds of the last fetch
+	failures     INTEGER NOT NULL DEFAULT 0  -- Failed fetches since the last success
+);
+CREATE TABLE IF NOT EXISTS discovered (
+	host       TEXT PRIMARY KEY, -- As in hosts
+	icon_url   TEXT NOT NULL,    -- The icon fetched, /favicon.ico if the page had none that worked
+	fetched_at INTEGER NOT NULL
+);`
+
+// cacheDB opens the cache database the first time it's needed, creating it
+// and moving the entries of the older per-host .txt files into it. In
+// cache-only mode nothing on disk changes: see openCacheReadOnly.
+func (f *Fetcher) cacheDB() (*sql.DB, error) {
+	f.dbOnce.Do(func() {
+		if f.cacheDir == "" {
+			f.dbErr = errors.New("no cache directory configured")
+			return
+		}
+		if f.cacheOnly.Load() {
+			f.db, f.dbErr = f.openCacheReadOnly()
+			return
+		}
+		if err := os.MkdirAll(f.cacheDir, 0o755); err != nil {
+			f.dbErr = fmt.Errorf("failed to create cache directory: %w", err)
+			return
+		}
+		path := filepath.Join(f.cacheDir, CacheFileName)
+		db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+		if err == nil {
+			_, err = db.Exec(cacheSchema)
+			if err != nil {
+				db.Close()
+			}
+		}
+		if err != nil {
+			f.dbErr = fmt.Errorf("failed to open favicon cache %s: %w", path, err)
+			return
+		}
+		f.db = db
+		_ = f.migrateTextCache(true)
+	})
+	return f.db, f.dbErr
+}
+
+// openCacheReadOnly opens the cache database read-only. Without one yet, the
+// older .txt files are read into a database in memory and left in place, so
+// a dry run sees the same cache the real run will.
+func (f *Fetcher) openCacheReadOnly() (*sql.DB, error) {
+	path := filepath.Join(f.cacheDir, CacheFileName)
+	if _, err := os.Stat(path); err == nil {
+		db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?mode=ro&_pragma=busy_timeout(5000)")
+		if err == nil {
+			err = db.QueryRow("SELECT COUNT(*) FROM hosts").Scan(new(int))
+			if err != nil {
+				db.Close()
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open favicon cache %s: %w", path, err)
+		}
+		return db, nil
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // Every connection would get its own empty database
+	if _, err := db.Exec(cacheSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	f.db = db
+	_ = f.migrateTextCache(false)
+	return db, nil
+}
+
+// Close closes the cache database. The Fetcher must not be used afterwards.
+func (f *Fetcher) Close() error {
+	f.dbOnce.Do(func() { f.dbErr = errors.New("fetcher closed") })
+	if f.db == nil {
+		return nil
+	}
+	return f.db.Close()
+}
+
+// cacheHost returns the cache key of a page URL's host, or "" if it can't be cached
+func cacheHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// readFromCache tries to read a cached data URL for the page host
+// Returns the cached value, or empty string if not cached
+// Returns failedMarker if the URL previously failed (so we can skip it)
+func (f *Fetcher) readFromCache(pageURL string) string {
+	host := cacheHost(pageURL)
+	if host == "" {
+		return ""
+	}
+	db, err := f.cacheDB()
+	if err != nil {
+		return ""
+	}
+	var dataURL sql.NullString
+	var failures int
+	err = db.QueryRow(`SELECT images.data_url, hosts.failures FROM hosts
+		LEFT JOIN images ON images.hash = hosts.image WHERE hosts.host = ?`, host).Scan(&dataURL, &failures)
+	switch {
+	case err != nil:
+		return ""
+	case dataURL.Valid:
+		return dataURL.String
+	case failures > 0:
+		return failedMarker
+	}
+	return ""
+}
+
+// cacheFailure records a failed fetch so we don't retry unreachable URLs
+func (f *Fetcher) cacheFailure(pageURL string) {
+	host := cacheHost(pageURL)
+	if host == "" {
+		return
+	}
+	db, err := f.cacheDB()
+	if err != nil {
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+	var previous sql.NullString
+	_ = tx.QueryRow("SELECT image FROM hosts WHERE host = ?", host).Scan(&previous)
+	_, err = tx.Exec(`INSERT INTO hosts (host, fetched_at, failures) VALUES (?, ?, 1)
+		ON CONFLICT (host) DO UPDATE SET image = NULL, content_type = '', size = 0,
+			fetched_at = excluded.fetched_at, failures = hosts.failures + 1`, host, f.clock.Now().Unix())
+	if err != nil {
+		return
+	}
+	if previous.Valid {
+		// Drop the image this host had, unless another host still uses it
+		_, err = tx.Exec("DELETE FROM images WHERE hash = ? AND NOT EXISTS (SELECT 1 FROM hosts WHERE image = ?)", previous.String, previous.String)
+		if err != nil {
+			return
+		}
+	}
+	_ = tx.Commit()
+}
+
+// writeToCache stores the data URL once under its hash and points the
+// host's row at it, so hosts serving identical icons share one copy
+func (f *Fetcher) writeToCache(pageURL, dataURL string) {
+	host := cacheHost(pageURL)
+	if dataURL == "" || host == "" {
+		return
+	}
+	db, err := f.cacheDB()
+	if err != nil {
+		return
+	}
+	if shared, err := storeEntry(db, host, dataURL, f.clock.Now()); err == nil && shared {
+		f.savedBytes.Add(int64(len(dataURL) - sha256.Size*2))
+	}
+}
+
+// storeEntry writes the host's row and its image in one transaction. shared
+// reports whether the image was already stored for another host.
+func storeEntry(db *sql.DB, host, dataURL string, fetched time.Time) (shared bool, err error) {
+	sum := sha256.Sum256([]byte(dataURL))
+	hash := hex.EncodeToString(sum[:])
+	contentType, size := dataURLInfo(dataURL)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT OR IGNORE INTO images (hash, data_url) VALUES (?, ?)", hash, dataURL)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	_, err = tx.Exec(`INSERT INTO hosts (host, image, content_type, size, fetched_at, failures) VALUES (?, ?, ?, ?, ?, 0)
+		ON CONFLICT (host) DO UPDATE SET image = excluded.image, content_type = excluded.content_type,
+			size = excluded.size, fetched_at = excluded.fetched_at, failures = 0`,
+		host, hash, contentType, size, fetched.Unix())
+	if err != nil {
+		return false, err
+	}
+	return n == 0, tx.Commit()
+}
+
+// readDiscovered returns the icon URL cached for the page's host, or "" if
+// its home page hasn't been looked at yet
+func (f *Fetcher) readDiscovered(pageURL string) string {
+	host := cacheHost(pageURL)
+	if host == "" {
+		return ""
+	}
+	db, err := f.cacheDB()
+	if err != nil {
+		return ""
+	}
+	var iconURL string
+	if err := db.QueryRow("SELECT icon_url FROM discovered WHERE host = ?", host).Scan(&iconURL); err != nil {
+		return "" // Including a read-only cache made before the table existed
+	}
+	return iconURL
+}
+
+// writeDiscovered records the icon URL fetched for the page's host
+func (f *Fetcher) writeDiscovered(pageURL, iconURL string) {
+	host := cacheHost(pageURL)
+	if host == "" {
+		return
+	}
+	db, err := f.cacheDB()
+	if err != nil {
+		return
+	}
+	_, _ = db.Exec(`INSERT INTO discovered (host, icon_url, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT (host) DO UPDATE SET icon_url = excluded.icon_url, fetched_at = excluded.fetched_at`,
+		host, iconURL, f.clock.Now().Unix())
+}
+
+// dataURLInfo returns the content type and decoded size of a base64 data URL
+func dataURLInfo(dataURL string) (contentType string, size int) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok {
+		return "", 0
+	}
+	contentType, _, _ = strings.Cut(header, ";")
+	return contentType, base64.StdEncoding.DecodedLen(len(payload)) - strings.Count(payload, "=")
+}
+
+// ClearCache removes all cached favicons
+// Returns the number of hosts removed
+func (f *Fetcher) ClearCache() (int, error) {
+	db, err := f.cacheDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("DELETE FROM hosts")
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear favicon cache: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM images"); err != nil {
+		return 0, fmt.Errorf("failed to clear favicon cache: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM discovered"); err != nil {
+		return 0, fmt.Errorf("failed to clear favicon cache: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+	return int(removed), nil
+}
+
+// ClearFailedCache removes only the failed favicon entries from cache
+// Returns the number of failed entries removed
+func (f *Fetcher) ClearFailedCache() (int, error) {
+	db, err := f.cacheDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("DELETE FROM hosts WHERE image IS NULL")
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear failed favicons: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+	return int(removed), nil
+}
+
+// GetCacheStats returns statistics about the current cache
+func (f *Fetcher) GetCacheStats() (total, successful, failed int, err error) {
+	db, err := f.cacheDB()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	err = db.QueryRow(`SELECT COUNT(*), COUNT(image), COUNT(*) - COUNT(image) FROM hosts`).Scan(&total, &successful, &failed)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to read favicon cache: %w", err)
+	}
+	return total, successful, failed, nil
+}
+
+// Older versions kept a .txt file per host in the cache directory, holding
+// the data URL, failedMarker or imageRefPrefix plus the hash of a file in
+// imagesDirName
+const (
+	imageRefPrefix = "sha256:"
+	imagesDirName  = "images"
+)
+
+// migrateTextCache moves the entries of the per-host .txt files into the
+// database and, if remove is set, removes the files. Entries already in the
+// database win, and files whose host can't be worked out from the name are
+// dropped (their favicons are fetched again). Files that couldn't be read
+// are kept for the next run, and so is imagesDirName, which they may need.
+func (f *Fetcher) migrateTextCache(remove bool) error {
+	entries, err := os.ReadDir(f.cacheDir)
+	if err != nil {
+		return err
+	}
+	kept := false
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+			continue
+		}
+		path := filepath.Join(f.cacheDir, entry.Name())
+		if host := textCacheHost(entry.Name()); host != "" {
+			if err := f.migrateTextEntry(host, path); err != nil {
+				kept = true // Left for the next run
+				continue
+			}
+		}
+		if remove {
+			_ = os.Remove(path)
+		}
+	}
+	if !remove || kept {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(f.cacheDir, imagesDirName))
+}
+
+func (f *Fetcher) migrateTextEntry(host, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	if hash, ok := strings.CutPrefix(content, imageRefPrefix); ok {
+		image, err := os.ReadFile(filepath.Join(f.cacheDir, imagesDirName, hash+".txt"))
+		if err != nil {
+			return nil // Image was removed; nothing to keep
+		}
+		content = string(image)
+	}
+
+	var exists bool
+	if err := f.db.QueryRow("SELECT EXISTS (SELECT 1 FROM hosts WHERE host = ?)", host).Scan(&exists); err != nil || exists {
+		return err
+	}
+	if content == failedMarker {
+		_, err = f.db.Exec("INSERT OR IGNORE INTO hosts (host, fetched_at, failures) VALUES (?, ?, 1)", host, info.ModTime().Unix())
+		return err
+	}
+	if !strings.HasPrefix(content, "data:") {
+		return nil
+	}
+	_, err = storeEntry(f.db, host, content, info.ModTime())
+	return err
+}
+
+// textCacheHost works out the host of a .txt cache file name: cacheFileName's
+// "<prefix>-<hash>.txt" when the prefix is the whole host (the hash must
+// match), else the older "<host>.txt". A ":" before the port was written as
+// "_"; "" means the host can't be told.
+func textCacheHost(name string) string {
+	name = strings.TrimSuffix(name, ".txt")
+	if i := strings.LastIndex(name, "-"); i > 0 && isHex16(name[i+1:]) {
+		for _, host := range hostCandidates(name[:i]) {
+			if sum := sha256.Sum256([]byte(host)); hex.EncodeToString(sum[:8]) == name[i+1:] {
+				return host
+			}
+		}
+		return "" // Truncated
+	}
+	candidates := hostCandidates(name)
+	return candidates[len(candidates)-1]
+}
+
+func isHex16(s string) bool {
+	_, err := hex.DecodeString(s)
+	return len(s) == 16 && err == nil && strings.ToLower(s) == s
+}
+
+// hostCandidates lists the hosts name may be sanitized from, the one with a
+// port last
+func hostCandidates(name string) []string {
+	name = strings.ToLower(name)
+	if i := strings.LastIndex(name, "_"); i > 0 && strings.Trim(name[i+1:], "0123456789") == "" && i < len(name)-1 {
+		return []string{name, name[:i] + ":" + name[i+1:]}
+	}
+	return []string{name}
+}
@@ -0,0 +1,38 @@
+package favicon
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// locksDirName holds the per-host lock files under the cache directory
+const locksDirName = "locks"
+
+// hostLocks holds a mutex per host lock file, shared by every Fetcher in the
+// process (watch mode, batch imports and servers may run several at once)
+var hostLocks sync.Map // Absolute lock file path -> *sync.Mutex
+
+// lockHost keeps other fetchers sharing the cache, in this process or another,
+// from fetching and writing the favicon of pageURL's host until unlock is
+// called. Callers re-read the cache once they hold it: whoever got there first
+// has usually cached the favicon already.
+func (f *Fetcher) lockHost(pageURL string) (unlock func()) {
+	host := cacheHost(pageURL)
+	if host == "" || f.cacheDir == "" {
+		return func() {}
+	}
+	path := filepath.Join(f.cacheDir, locksDirName, strings.TrimSuffix(cacheFileName(host), ".txt")+".lock")
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	v, _ := hostLocks.LoadOrStore(path, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	release := lockFile(path)
+	return func() {
+		release()
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !linux
+
+package favicon
+
+// CrossProcessLock reports whether runs in separate processes take turns
+// writing the favicon cache
+const CrossProcessLock = false
+
+// lockFile can't lock across processes on this platform; fetchers in one
+// process still share the in-process lock, and writes stay atomic
+func lockFile(path string) (release func()) {
+	return func() {}
+}
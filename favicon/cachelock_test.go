@@ -0,0 +1,54 @@
+package favicon
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentFetchers_ShareCache(t *testing.T) {
+	dir := t.TempDir()
+	var requests atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		time.Sleep(20 * time.Millisecond) // Long enough for the others to pile up
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("\x89PNG shared")),
+			Request:    req,
+		}, nil
+	})
+	fetchers := []*Fetcher{
+		NewWithOptions(Options{CacheDir: dir, Transport: transport}),
+		NewWithOptions(Options{CacheDir: dir, Transport: transport}),
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = fetchers[i%2].FetchAsDataURL("https://shared.example/page")
+		}(i)
+	}
+	wg.Wait()
+	precache := fetchers[1].PreCacheFavicons([]string{"https://shared.example/a", "https://shared.example/b"}, 2)
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected one home page and one /favicon.ico request for the shared host, got %d", n)
+	}
+	for i, got := range results {
+		if got == "" || got != results[0] {
+			t.Errorf("fetch %d: expected the shared favicon, got %q", i, got)
+		}
+	}
+	if precache.Cached != 2 || precache.Fetched != 0 {
+		t.Errorf("expected pre-cache to find both cached, got %+v", precache)
+	}
+}
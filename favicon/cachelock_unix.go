@@ -0,0 +1,32 @@
+//go:build darwin || linux
+
+package favicon
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// CrossProcessLock reports whether runs in separate processes take turns
+// writing the favicon cache
+const CrossProcessLock = true
+
+// lockFile takes an exclusive flock on path, so fetchers in other processes
+// wait too. The lock is advisory and best effort: if it can't be taken, the
+// in-process lock and atomic writes still keep the cache consistent.
+func lockFile(path string) (release func()) {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return func() {}
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return func() {}
+	}
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}
+}
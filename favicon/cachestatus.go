@@ -0,0 +1,61 @@
+package favicon
+
+import "time"
+
+// typicalFetchTime is a rough duration of one favicon request, used only to
+// estimate how long fetching the missing ones will take
+const typicalFetchTime = 400 * time.Millisecond
+
+// CacheStatus is what pre-caching a list of URLs would do, worked out from the
+// cache alone
+type CacheStatus struct {
+	Total   int // Non-empty URLs looked up
+	Cached  int // Favicon in the cache
+	Failed  int // Cached as failed, so not retried (see ClearFailedCache)
+	Missing int // Would be fetched, including force-listed domains
+	Denied  int // On denied domains, never fetched
+}
+
+// Estimate returns roughly how long fetching the missing favicons takes with
+// workers concurrent requests
+func (s *CacheStatus) Estimate(workers int) time.Duration {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	rounds := (s.Missing + workers - 1) / workers
+	return time.Duration(rounds) * typicalFetchTime
+}
+
+// CacheStatus looks urls up in the cache without any network access
+func (f *Fetcher) CacheStatus(urls []string) *CacheStatus {
+	status := &CacheStatus{}
+	for _, pageURL := range urls {
+		if pageURL == "" {
+			continue
+		}
+		status.Total++
+		action := f.domainActionFor(pageURL)
+		if action == domainDeny {
+			status.Denied++
+			continue
+		}
+		switch cached := f.readFromCache(pageURL); {
+		case action == domainForce || cached == "":
+			status.Missing++
+		case cached == failedMarker:
+			status.Failed++
+		default:
+			status.Cached++
+		}
+	}
+	return status
+}
+
+// SetCacheOnly makes FetchAsDataURL answer from the cache only, without any
+// network access (for dry runs). Force-listed domains then use their cached
+// favicon too. Set before the cache is first used, it also keeps the cache
+// on disk unchanged: the database is opened read-only and older .txt files
+// are read but not migrated.
+func (f *Fetcher) SetCacheOnly(cacheOnly bool) {
+	f.cacheOnly.Store(cacheOnly)
+}
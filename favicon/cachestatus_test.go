@@ -0,0 +1,48 @@
+package favicon
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheStatus_NoNetwork(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, context.Canceled
+	})
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: transport})
+	f.SetDomainRules(DomainRules{Deny: []string{"ads.test"}})
+	f.writeToCache("https://cached.test/", "data:image/png;base64,iVBORw0KGgo=")
+	f.cacheFailure("https://broken.test/")
+
+	status := f.CacheStatus([]string{
+		"https://cached.test/a", "https://broken.test/", "https://new.test/", "https://other.test/", "https://ads.test/", "",
+	})
+	want := CacheStatus{Total: 5, Cached: 1, Failed: 1, Missing: 2, Denied: 1}
+	if *status != want {
+		t.Errorf("got %+v, want %+v", *status, want)
+	}
+	if got := status.Estimate(10); got != typicalFetchTime {
+		t.Errorf("expected one round of requests, got %v", got)
+	}
+
+	f.SetCacheOnly(true)
+	if got := f.FetchAsDataURLContext(context.Background(), "https://new.test/"); got != "" {
+		t.Errorf("expected nothing for an uncached favicon in cache-only mode, got %q", got)
+	}
+	if got := f.FetchAsDataURLContext(context.Background(), "https://cached.test/b"); got == "" {
+		t.Error("expected the cached favicon in cache-only mode")
+	}
+}
+
+func TestCacheStatus_Estimate(t *testing.T) {
+	status := &CacheStatus{Missing: 812}
+	if got := status.Estimate(10); got != 82*typicalFetchTime {
+		t.Errorf("expected 82 rounds, got %v", got)
+	}
+	if got := (&CacheStatus{}).Estimate(10); got != 0*time.Second {
+		t.Errorf("expected no time with nothing to fetch, got %v", got)
+	}
+}
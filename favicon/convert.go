@@ -0,0 +1,74 @@
+package favicon
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	// Decoders for formats that are re-encoded as PNG
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// sniffImageType identifies an image from its leading bytes. Returns "" if the
+// data isn't a recognized image format.
+func sniffImageType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte{0x00, 0x00, 0x01, 0x00}), bytes.HasPrefix(data, []byte{0x00, 0x00, 0x02, 0x00}):
+		return "image/x-icon" // ICO and CUR
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "image/bmp"
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "image/webp"
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) &&
+		(bytes.Equal(data[8:12], []byte("avif")) || bytes.Equal(data[8:12], []byte("avis"))):
+		return "image/avif"
+	case isSVG(data):
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// isSVG reports whether data looks like an SVG document
+func isSVG(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	head = bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+	if bytes.HasPrefix(head, []byte("<svg")) {
+		return true
+	}
+	// XML prolog, comments or doctype before the root element
+	return (bytes.HasPrefix(head, []byte("<?xml")) || bytes.HasPrefix(head, []byte("<!"))) &&
+		bytes.Contains(head, []byte("<svg"))
+}
+
+// needsConversion lists formats Zen doesn't reliably render from data URLs
+var needsConversion = map[string]bool{
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// toCompatibleFormat re-encodes formats Zen may not render as PNG. Data in other
+// formats, or that fails to decode, is returned unchanged.
+func toCompatibleFormat(data []byte, contentType string) ([]byte, string) {
+	if !needsConversion[contentType] {
+		return data, contentType
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, contentType
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return data, contentType
+	}
+	return buf.Bytes(), "image/png"
+}
@@ -0,0 +1,112 @@
+package favicon
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+// 1x1 lossless WebP
+const tinyWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestSniffImageType(t *testing.T) {
+	webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n...."), "image/png"},
+		{"gif", []byte("GIF89a...."), "image/gif"},
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0}, "image/jpeg"},
+		{"ico", []byte{0x00, 0x00, 0x01, 0x00, 0x01}, "image/x-icon"},
+		{"webp", webp, "image/webp"},
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00"), "image/avif"},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "image/svg+xml"},
+		{"svg with prolog", []byte("<?xml version=\"1.0\"?>\n<svg></svg>"), "image/svg+xml"},
+		{"html", []byte("<!DOCTYPE html><html><body>Not found</body></html>"), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffImageType(tt.data); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestToCompatibleFormat(t *testing.T) {
+	webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var bmpData bytes.Buffer
+	if err := bmp.Encode(&bmpData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, input := range map[string][]byte{"image/webp": webp, "image/bmp": bmpData.Bytes()} {
+		data, contentType := toCompatibleFormat(input, name)
+		if contentType != "image/png" || sniffImageType(data) != "image/png" {
+			t.Errorf("%s: expected PNG output, got %s", name, contentType)
+		}
+	}
+
+	// Supported formats pass through untouched
+	ico := []byte{0x00, 0x00, 0x01, 0x00}
+	if data, contentType := toCompatibleFormat(ico, "image/x-icon"); contentType != "image/x-icon" || !bytes.Equal(data, ico) {
+		t.Errorf("expected ICO unchanged, got %s", contentType)
+	}
+}
+
+func TestFetchFavicon_IgnoresWrongContentType(t *testing.T) {
+	webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon") // Lies about the format
+		w.Write(webp)
+	}))
+	defer ts.Close()
+
+	f := NewWithCache(t.TempDir())
+	dataURL := f.FetchAsDataURL(ts.URL + "/page")
+	if !strings.HasPrefix(dataURL, "data:image/png;base64,") {
+		t.Errorf("expected WebP to be converted to PNG, got %q", dataURL)
+	}
+}
+
+func TestFetchFavicon_RejectsNonImages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write([]byte("<!DOCTYPE html><html><body>Page not found</body></html>"))
+	}))
+	defer ts.Close()
+
+	f := NewWithCache(t.TempDir())
+	if _, _, err := f.fetchFavicon(context.Background(), ts.URL+"/favicon.ico"); !errors.Is(err, errNotImage) {
+		t.Fatalf("expected errNotImage, got %v", err)
+	}
+
+	if got := f.FetchAsDataURL(ts.URL + "/page"); got != "" {
+		t.Errorf("expected no favicon, got %q", got)
+	}
+	if got := f.readFromCache(ts.URL + "/page"); got != failedMarker {
+		t.Errorf("expected failure to be cached, got %q", got)
+	}
+
+	result := NewWithCache(t.TempDir()).PreCacheFavicons([]string{ts.URL + "/a"}, 1)
+	if result.Failed != 1 {
+		t.Errorf("expected precache to count a failure, got %+v", result)
+	}
+}
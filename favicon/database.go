@@ -0,0 +1,64 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+)
+
+// maxStoredIconSize is the widest favicon ForDatabase keeps as it is; Zen
+// draws favicons at 16px, 32px on HiDPI screens
+const maxStoredIconSize = 64
+
+// ForDatabase converts a favicon data URL to what Firefox keeps in
+// favicons.sqlite: SVG as it is, anything else as PNG no larger than 64px.
+// ICO files give the image closest to 32px. width is the PNG's (0 for SVG).
+// Formats that can't be decoded, such as AVIF, return an error.
+func ForDatabase(dataURL string) (data []byte, contentType string, width int, err error) {
+	header, encoded, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:image/") || !strings.HasSuffix(header, ";base64") {
+		return nil, "", 0, errors.New("not a base64 image data URL")
+	}
+	data, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var src image.Image
+	switch sniffImageType(data) {
+	case "image/svg+xml":
+		return data, "image/svg+xml", 0, nil
+	case "image/png":
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", 0, err
+		}
+		if cfg.Width <= maxStoredIconSize && cfg.Height <= maxStoredIconSize {
+			return data, "image/png", cfg.Width, nil
+		}
+		src, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", 0, err
+		}
+	case "image/x-icon":
+		src, err = decodeICO(data)
+	default:
+		src, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	bounds := src.Bounds()
+	if bounds.Dx() > maxStoredIconSize || bounds.Dy() > maxStoredIconSize {
+		src = scale(src, maxStoredIconSize)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		return nil, "", 0, err
+	}
+	return buf.Bytes(), "image/png", src.Bounds().Dx(), nil
+}
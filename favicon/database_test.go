@@ -0,0 +1,101 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image/png"
+	"testing"
+)
+
+// icoFile builds an ICO file from its images, each given with its width
+func icoFile(widths []int, images [][]byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, uint16(len(images))})
+	offset := icoHeaderSize + icoEntrySize*len(images)
+	for i, img := range images {
+		buf.Write([]byte{byte(widths[i]), byte(widths[i]), 0, 0})
+		binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(img)), uint32(offset)})
+		offset += len(img)
+	}
+	for _, img := range images {
+		buf.Write(img)
+	}
+	return buf.Bytes()
+}
+
+// dib builds a size×size 24-bit ICO bitmap of one color whose mask makes the
+// left half transparent
+func dib(size int, r, g, b byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{40, uint32(size), uint32(size * 2)})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 24})
+	binary.Write(&buf, binary.LittleEndian, []uint32{0, 0, 0, 0, 0, 0})
+	stride := (size*24 + 31) / 32 * 4
+	for y := 0; y < size; y++ {
+		row := make([]byte, stride)
+		for x := 0; x < size; x++ {
+			copy(row[x*3:], []byte{b, g, r})
+		}
+		buf.Write(row)
+	}
+	maskStride := (size + 31) / 32 * 4
+	for y := 0; y < size; y++ {
+		row := make([]byte, maskStride)
+		for x := 0; x < size/2; x++ {
+			row[x/8] |= 0x80 >> (x % 8)
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+func dataURL(contentType string, data []byte) string {
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+func TestForDatabase_ICO(t *testing.T) {
+	small, _ := base64.StdEncoding.DecodeString(pngDataURL(t, 16)[len("data:image/png;base64,"):])
+	ico := icoFile([]int{16, 32, 48}, [][]byte{small, dib(32, 200, 10, 20), dib(48, 0, 0, 0)})
+
+	data, contentType, width, err := ForDatabase(dataURL("image/x-icon", ico))
+	if err != nil || contentType != "image/png" || width != 32 {
+		t.Fatalf("expected the 32px image as PNG, got %s %d (%v)", contentType, width, err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(3, 5).RGBA(); a != 0 {
+		t.Errorf("expected the masked half transparent, got alpha %d", a)
+	}
+	if r, g, b, a := img.At(20, 5).RGBA(); r>>8 != 200 || g>>8 != 10 || b>>8 != 20 || a>>8 != 0xFF {
+		t.Errorf("unexpected pixel %d,%d,%d,%d", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestForDatabase(t *testing.T) {
+	small := pngDataURL(t, 32)
+	if data, contentType, width, err := ForDatabase(small); err != nil || contentType != "image/png" || width != 32 ||
+		dataURL("image/png", data) != small {
+		t.Errorf("expected a small PNG kept as it is, got %s %d (%v)", contentType, width, err)
+	}
+	if _, contentType, width, err := ForDatabase(pngDataURL(t, 200)); err != nil || contentType != "image/png" || width != maxStoredIconSize {
+		t.Errorf("expected a large PNG scaled to %dpx, got %s %d (%v)", maxStoredIconSize, contentType, width, err)
+	}
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+	if data, contentType, width, err := ForDatabase(dataURL("image/svg+xml", svg)); err != nil || contentType != "image/svg+xml" ||
+		width != 0 || !bytes.Equal(data, svg) {
+		t.Errorf("expected the SVG kept, got %s %d (%v)", contentType, width, err)
+	}
+	for _, bad := range []string{
+		"https://a.example/favicon.ico",
+		dataURL("image/avif", []byte("\x00\x00\x00\x1cftypavif")),
+		dataURL("image/x-icon", []byte{0, 0, 1, 0, 1, 0}),
+	} {
+		if _, _, _, err := ForDatabase(bad); err == nil {
+			t.Errorf("%.40s: expected an error", bad)
+		}
+	}
+}
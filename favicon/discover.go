@@ -0,0 +1,348 @@
+package favicon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Maximum page size read when looking for icon links (512KB); the links
+	// are in <head>, so a truncated page still has them
+	maxPageSize = 512 * 1024
+	// Maximum web app manifest size (256KB)
+	maxManifestSize = 256 * 1024
+	// Icons tried from the page before falling back to /favicon.ico
+	maxIconCandidates = 3
+	// The size icons are ranked against: Zen shows favicons at 16px, so 32px
+	// stays sharp on high-density screens without bloating the session
+	idealIconSize = 32
+	// apple-touch-icon links without sizes are 180px by convention
+	appleTouchIconSize = 180
+)
+
+var (
+	linkTagRe   = regexp.MustCompile(`(?is)<(link|base)\b[^>]*>`)
+	attributeRe = regexp.MustCompile(`(?s)([a-zA-Z][a-zA-Z0-9_:-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	headEndRe   = regexp.MustCompile(`(?i)</head\s*>`)
+)
+
+// iconCandidate is an icon a page links to
+type iconCandidate struct {
+	URL   string
+	Sizes []int // Largest dimension of each size listed; empty if not given
+	Any   bool  // sizes="any", or an SVG
+}
+
+// distance is how far the candidate is from idealIconSize; lower is better.
+// Icons smaller than ideal are penalized more than larger ones, since they
+// blur when scaled up, and icons of unknown size rank after a close match.
+func (c iconCandidate) distance() int {
+	if c.Any {
+		return idealIconSize / 2
+	}
+	if len(c.Sizes) == 0 {
+		return 2 * idealIconSize
+	}
+	best := -1
+	for _, size := range c.Sizes {
+		if d := sizeDistance(size); best < 0 || d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// sizeDistance is how far an icon size pixels wide is from idealIconSize,
+// smaller sizes counting eight times as much
+func sizeDistance(size int) int {
+	if d := size - idealIconSize; d >= 0 {
+		return d
+	}
+	return (idealIconSize - size) * 8
+}
+
+// discoverIcons fetches the site's home page and returns the icons it links
+// to, best first. With no <link> icons it reads the web app manifest's. A
+// page that isn't HTML or doesn't answer 200 yields no candidates; only
+// errors reaching the site are returned, so the caller can skip
+// /favicon.ico for hosts that are down or blocked.
+func (f *Fetcher) discoverIcons(ctx context.Context, pageURL string) ([]iconCandidate, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	home := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+	body, base, err := f.fetchDocument(ctx, home.String(), "text/html,application/xhtml+xml", maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, nil
+	}
+
+	candidates, manifestURL := parseIconLinks(string(body), base)
+	if len(candidates) == 0 && manifestURL != "" {
+		candidates = f.manifestIcons(ctx, manifestURL)
+	}
+	return rankIcons(candidates), nil
+}
+
+// fetchDocument GETs rawURL and returns up to limit bytes of its body and
+// the URL it was served from after redirects. The body is nil, without an
+// error, if the response isn't 200 or not of the accepted type.
+func (f *Fetcher) fetchDocument(ctx context.Context, rawURL, accept string, limit int64) ([]byte, *url.URL, error) {
+	if f.domainActionFor(rawURL) == domainForce {
+		ctx = withForce(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !acceptsType(accept, resp.Header.Get("Content-Type")) {
+		return nil, nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, nil, nil
+	}
+	return data, resp.Request.URL, nil
+}
+
+// acceptsType reports whether contentType is one of the comma-separated
+// types in accept. A missing Content-Type is accepted.
+func acceptsType(accept, contentType string) bool {
+	contentType = mediaType(contentType)
+	if contentType == "" {
+		return true
+	}
+	for _, t := range strings.Split(accept, ",") {
+		if strings.TrimSpace(t) == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType is contentType lowercased, without parameters
+func mediaType(contentType string) string {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// parseIconLinks returns the icons an HTML page links to in document order,
+// and its manifest URL, resolved against pageURL and any <base href>. Only
+// the <head> is read when the page has one.
+func parseIconLinks(page string, pageURL *url.URL) ([]iconCandidate, string) {
+	if loc := headEndRe.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+	base := pageURL
+	var candidates []iconCandidate
+	var manifestURL string
+	for _, tag := range linkTagRe.FindAllStringSubmatch(page, -1) {
+		attrs := parseAttributes(tag[0])
+		href := attrs["href"]
+		if href == "" {
+			continue
+		}
+		if strings.EqualFold(tag[1], "base") {
+			if resolved, err := pageURL.Parse(href); err == nil {
+				base = resolved
+			}
+			continue
+		}
+		resolved, err := base.Parse(href)
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			continue // data: icons and the like can't be fetched
+		}
+
+		var icon, appleTouch bool
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			switch rel {
+			case "icon":
+				icon = true
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				appleTouch = true
+			case "manifest":
+				if manifestURL == "" {
+					manifestURL = resolved.String()
+				}
+			}
+		}
+		if !icon && !appleTouch {
+			continue // Including mask-icon, a monochrome SVG Safari tints
+		}
+		candidate := newIconCandidate(resolved.String(), attrs["sizes"], attrs["type"])
+		if appleTouch && len(candidate.Sizes) == 0 && !candidate.Any {
+			candidate.Sizes = []int{appleTouchIconSize}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, manifestURL
+}
+
+// parseAttributes returns the attributes of an HTML tag, names lowercased
+// and values unescaped. The first occurrence of a name wins.
+func parseAttributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attributeRe.FindAllStringSubmatch(tag, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := attrs[name]; ok {
+			continue
+		}
+		value := strings.Trim(m[2], `"'`)
+		attrs[name] = strings.TrimSpace(html.UnescapeString(value))
+	}
+	return attrs
+}
+
+func newIconCandidate(iconURL, sizes, mimeType string) iconCandidate {
+	candidate := iconCandidate{URL: iconURL}
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			candidate.Any = true
+			continue
+		}
+		w, h, ok := strings.Cut(size, "x")
+		if !ok {
+			continue
+		}
+		width, err1 := strconv.Atoi(w)
+		height, err2 := strconv.Atoi(h)
+		if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+			continue
+		}
+		candidate.Sizes = append(candidate.Sizes, max(width, height))
+	}
+	path, _, _ := strings.Cut(iconURL, "?")
+	if mediaType(mimeType) == "image/svg+xml" || strings.HasSuffix(strings.ToLower(path), ".svg") {
+		candidate.Any = true
+	}
+	return candidate
+}
+
+// manifestIcons returns the icons listed in a web app manifest, leaving out
+// the monochrome ones. A manifest that can't be fetched or parsed has none.
+func (f *Fetcher) manifestIcons(ctx context.Context, manifestURL string) []iconCandidate {
+	body, base, err := f.fetchDocument(ctx, manifestURL, "application/manifest+json,application/json", maxManifestSize)
+	if err != nil || body == nil {
+		return nil
+	}
+	var manifest struct {
+		Icons []struct {
+			Src     string `json:"src"`
+			Sizes   string `json:"sizes"`
+			Type    string `json:"type"`
+			Purpose string `json:"purpose"`
+		} `json:"icons"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil
+	}
+	var candidates []iconCandidate
+	for _, icon := range manifest.Icons {
+		if purpose := strings.Fields(strings.ToLower(icon.Purpose)); len(purpose) > 0 && !contains(purpose, "any") && !contains(purpose, "maskable") {
+			continue
+		}
+		resolved, err := base.Parse(icon.Src)
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			continue
+		}
+		candidates = append(candidates, newIconCandidate(resolved.String(), icon.Sizes, icon.Type))
+	}
+	return candidates
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rankIcons sorts candidates best first, keeping document order between
+// equally good ones, and drops repeated URLs
+func rankIcons(candidates []iconCandidate) []iconCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance() < candidates[j].distance()
+	})
+	seen := make(map[string]bool)
+	ranked := candidates[:0]
+	for _, c := range candidates {
+		if !seen[c.URL] {
+			seen[c.URL] = true
+			ranked = append(ranked, c)
+		}
+	}
+	return ranked
+}
+
+// fetchBestIcon tries the icons the site's home page links to, best first,
+// and falls back to faviconURL. A host that can't be reached isn't asked
+// again for /favicon.ico. The icon that worked is cached for the host and
+// tried first next time without fetching the page; if it fails, the page is
+// looked at again.
+func (f *Fetcher) fetchBestIcon(ctx context.Context, pageURL, faviconURL string) ([]byte, string, error) {
+	var failed string
+	var failedErr error
+	if cached := f.readDiscovered(pageURL); cached != "" && f.domainActionFor(cached) != domainDeny {
+		data, contentType, err := f.fetchFavicon(ctx, cached)
+		if err == nil {
+			return data, contentType, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", err
+		}
+		failed, failedErr = cached, err
+	}
+
+	candidates, err := f.discoverIcons(ctx, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	tried := 0
+	for _, candidate := range candidates {
+		if tried == maxIconCandidates {
+			break
+		}
+		if candidate.URL == faviconURL || candidate.URL == failed || f.domainActionFor(candidate.URL) == domainDeny {
+			continue
+		}
+		tried++
+		data, contentType, err := f.fetchFavicon(ctx, candidate.URL)
+		if err == nil {
+			f.writeDiscovered(pageURL, candidate.URL)
+			return data, contentType, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", err
+		}
+	}
+	if faviconURL == failed {
+		return nil, "", failedErr
+	}
+	data, contentType, err := f.fetchFavicon(ctx, faviconURL)
+	if err != nil {
+		return nil, "", err
+	}
+	f.writeDiscovered(pageURL, faviconURL)
+	return data, contentType, nil
+}
@@ -0,0 +1,213 @@
+package favicon
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseIconLinks(t *testing.T) {
+	page := `<!doctype html>
+<html><head>
+<base href="/static/">
+<link rel="mask-icon" href="mask.svg" color="#000">
+<link rel="stylesheet" href="site.css">
+<LINK REL="Shortcut Icon" HREF="favicon-16.png" sizes="16x16">
+<link href='favicon-32.png' rel=icon sizes="32x32 64x64">
+<link rel="apple-touch-icon" href="https://cdn.example/touch.png">
+<link rel="icon" href="data:image/png;base64,AAAA">
+<link rel="manifest" href="/site.webmanifest">
+</head><body><link rel="icon" href="body.png"></body></html>`
+	pageURL, _ := url.Parse("https://example.com/")
+
+	candidates, manifestURL := parseIconLinks(page, pageURL)
+	var got []string
+	for _, c := range rankIcons(candidates) {
+		got = append(got, c.URL)
+	}
+	want := []string{
+		"https://example.com/static/favicon-32.png",
+		"https://example.com/static/favicon-16.png",
+		"https://cdn.example/touch.png", // 180px, far larger than Zen shows
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if manifestURL != "https://example.com/site.webmanifest" {
+		t.Errorf("unexpected manifest URL %q", manifestURL)
+	}
+}
+
+func TestIconCandidateDistance(t *testing.T) {
+	tests := []struct {
+		sizes, mimeType, url string
+		want                 int
+	}{
+		{"32x32", "", "https://a.example/i.png", 0},
+		{"16x16 48x48", "", "https://a.example/i.png", 16},
+		{"16x16", "", "https://a.example/i.png", 128},
+		{"", "", "https://a.example/i.png", 64},
+		{"any", "", "https://a.example/i.png", 16},
+		{"", "image/svg+xml", "https://a.example/i", 16},
+		{"", "", "https://a.example/i.svg?v=2", 16},
+		{"bogus 0x0", "", "https://a.example/i.png", 64},
+	}
+	for _, tt := range tests {
+		if got := newIconCandidate(tt.url, tt.sizes, tt.mimeType).distance(); got != tt.want {
+			t.Errorf("%q %q %q: expected %d, got %d", tt.sizes, tt.mimeType, tt.url, tt.want, got)
+		}
+	}
+}
+
+func TestFetchAsDataURL_PageIcons(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, `<head><link rel="icon" href="/missing.png" sizes="32x32"><link rel="icon" href="/icon.png" sizes="64x64"></head>`)
+		case "/icon.png":
+			w.Write([]byte("\x89PNG page icon"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	f := NewWithCache(t.TempDir())
+	got := f.FetchAsDataURL(ts.URL + "/some/page")
+	if got != f.encodeAsDataURL([]byte("\x89PNG page icon"), "image/png") {
+		t.Errorf("expected the page's icon, got %q", got)
+	}
+	// The best candidate failed, so the next was tried; /favicon.ico wasn't needed
+	if want := []string{"/", "/missing.png", "/icon.png"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected requests %v, got %v", want, requested)
+	}
+}
+
+func TestFetchAsDataURL_ManifestIcons(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<link rel="manifest" href="/app/manifest.json">`)
+		case "/app/manifest.json":
+			w.Header().Set("Content-Type", "application/manifest+json")
+			io.WriteString(w, `{"icons": [
+				{"src": "mono.png", "sizes": "32x32", "purpose": "monochrome"},
+				{"src": "icon-512.png", "sizes": "512x512"},
+				{"src": "icon-48.png", "sizes": "48x48", "purpose": "any maskable"}
+			]}`)
+		case "/app/icon-48.png":
+			w.Write([]byte("\x89PNG manifest icon"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	f := NewWithCache(t.TempDir())
+	if got := f.FetchAsDataURL(ts.URL); got != f.encodeAsDataURL([]byte("\x89PNG manifest icon"), "image/png") {
+		t.Errorf("expected the manifest's icon, got %q", got)
+	}
+}
+
+func TestFetchAsDataURL_FallsBackToFaviconICO(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<link rel="icon" href="/broken.png">`)
+		case "/broken.png":
+			io.WriteString(w, "<html>not an image</html>")
+		case "/favicon.ico":
+			w.Write([]byte("\x89PNG fallback"))
+		}
+	}))
+	defer ts.Close()
+
+	f := NewWithCache(t.TempDir())
+	if got := f.FetchAsDataURL(ts.URL + "/page"); got != f.encodeAsDataURL([]byte("\x89PNG fallback"), "image/png") {
+		t.Errorf("expected /favicon.ico, got %q", got)
+	}
+}
+
+func TestFetchAsDataURL_UnreachableHostTriedOnce(t *testing.T) {
+	requests := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, errors.New("connection refused")
+	})
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: transport})
+
+	if got := f.FetchAsDataURL("https://down.example/page"); got != "" || requests != 1 {
+		t.Errorf("expected one failed request, got %q with %d requests", got, requests)
+	}
+	if cached := f.readFromCache("https://down.example/page"); cached != "" {
+		t.Errorf("network errors must not be cached as failures, got %q", cached)
+	}
+	if stats := f.NetworkStats(); stats.Errors != 1 {
+		t.Errorf("expected one network error, got %+v", stats)
+	}
+}
+
+func TestFetchAsDataURL_CachedIconSkipsPage(t *testing.T) {
+	var requested []string
+	icon := "/icon.png"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<link rel="icon" href="`+icon+`">`)
+		case icon:
+			w.Write([]byte("\x89PNG page icon"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	f := NewWithCache(dir)
+	want := f.encodeAsDataURL([]byte("\x89PNG page icon"), "image/png")
+	if got := f.FetchAsDataURL(ts.URL + "/page"); got != want {
+		t.Fatalf("expected the page's icon, got %q", got)
+	}
+	// Drop the image but not the icon URL, as if a forced domain were refetched
+	if _, err := f.db.Exec("DELETE FROM hosts"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	requested = nil
+	f = NewWithCache(dir)
+	defer f.Close()
+	if got := f.FetchAsDataURL(ts.URL + "/other"); got != want {
+		t.Errorf("expected the cached icon, got %q", got)
+	}
+	if want := []string{"/icon.png"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected only the icon fetched, got %v", requested)
+	}
+
+	// The site moved its icon: the page is looked at again
+	if _, err := f.db.Exec("DELETE FROM hosts"); err != nil {
+		t.Fatal(err)
+	}
+	requested = nil
+	icon = "/new-icon.png"
+	if got := f.FetchAsDataURL(ts.URL + "/other"); got != want {
+		t.Errorf("expected the moved icon, got %q", got)
+	}
+	if want := []string{"/icon.png", "/", "/new-icon.png"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected the page fetched again, got %v", requested)
+	}
+	if cached := f.readDiscovered(ts.URL); cached != ts.URL+"/new-icon.png" {
+		t.Errorf("expected the new icon cached, got %q", cached)
+	}
+}
@@ -0,0 +1,154 @@
+package favicon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"arc-to-zen/paths"
+)
+
+// domainRulesFile is the optional per-user list of deny/force domains in the config dir
+const domainRulesFile = "favicon-domains.txt"
+
+// DomainRules lists domains whose favicons are never fetched (Deny) or always
+// fetched fresh (Force). A domain also matches its subdomains.
+type DomainRules struct {
+	Deny  []string
+	Force []string
+}
+
+type domainAction int
+
+const (
+	domainDefault domainAction = iota
+	domainDeny                 // Never contacted, no favicon
+	domainForce                // Cache and private host guard bypassed
+)
+
+// DefaultDomainRulesPath returns where LoadDomainRules looks by default
+func DefaultDomainRulesPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, domainRulesFile), nil
+}
+
+// LoadDomainRules reads a rules file with one "deny <domain>" or "force <domain>"
+// per line; blank lines and lines starting with # are ignored. A missing file
+// yields empty rules.
+func LoadDomainRules(path string) (DomainRules, error) {
+	var rules DomainRules
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, fmt.Errorf("failed to read favicon domain rules: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return rules, fmt.Errorf("%s:%d: expected \"deny <domain>\" or \"force <domain>\"", path, lineNum)
+		}
+		switch strings.ToLower(fields[0]) {
+		case "deny":
+			rules.Deny = append(rules.Deny, fields[1])
+		case "force":
+			rules.Force = append(rules.Force, fields[1])
+		default:
+			return rules, fmt.Errorf("%s:%d: unknown rule %q (use deny or force)", path, lineNum, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return rules, fmt.Errorf("failed to read favicon domain rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SetDomainRules replaces the fetcher's deny/force domains
+func (f *Fetcher) SetDomainRules(rules DomainRules) {
+	normalized := DomainRules{}
+	for _, d := range rules.Deny {
+		if d = normalizeDomain(d); d != "" {
+			normalized.Deny = append(normalized.Deny, d)
+		}
+	}
+	for _, d := range rules.Force {
+		if d = normalizeDomain(d); d != "" {
+			normalized.Force = append(normalized.Force, d)
+		}
+	}
+	f.domainRules.Store(&normalized)
+}
+
+// IsDenied reports whether pageURL's host is on the deny list
+func (f *Fetcher) IsDenied(pageURL string) bool {
+	return f.domainActionFor(pageURL) == domainDeny
+}
+
+// domainActionFor returns the rule for pageURL's host. The most specific
+// matching domain wins; deny wins a tie.
+func (f *Fetcher) domainActionFor(pageURL string) domainAction {
+	rules := f.domainRules.Load()
+	if rules == nil {
+		return domainDefault
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return domainDefault
+	}
+	host := normalizeDomain(u.Hostname())
+
+	action, best := domainDefault, -1
+	for _, d := range rules.Force {
+		if matchesDomain(host, d) && len(d) > best {
+			action, best = domainForce, len(d)
+		}
+	}
+	for _, d := range rules.Deny {
+		if matchesDomain(host, d) && len(d) >= best {
+			action, best = domainDeny, len(d)
+		}
+	}
+	return action
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains
+func matchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// normalizeDomain lowercases a domain and strips a scheme, wildcard or trailing dot
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if u, err := url.Parse(domain); err == nil && u.Host != "" {
+		domain = u.Hostname()
+	}
+	domain = strings.TrimPrefix(domain, "*.")
+	return strings.Trim(domain, ".")
+}
+
+// forceKey marks a request context for a force-listed domain
+type forceKey struct{}
+
+func withForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+func isForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceKey{}).(bool)
+	return forced
+}
@@ -0,0 +1,94 @@
+package favicon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDomainActionFor(t *testing.T) {
+	f := NewWithCache(t.TempDir())
+	f.SetDomainRules(DomainRules{
+		Deny:  []string{"Bank.com", "*.health.example", "corp.example"},
+		Force: []string{"https://wiki.corp.example/", "bank.com"},
+	})
+
+	tests := map[string]domainAction{
+		"https://bank.com/login":           domainDeny, // Deny wins a tie
+		"https://www.bank.com/":            domainDeny,
+		"https://notbank.com/":             domainDefault,
+		"https://portal.health.example/":   domainDeny,
+		"https://mail.corp.example/":       domainDeny,
+		"https://wiki.corp.example/page":   domainForce, // More specific than the deny
+		"https://docs.wiki.corp.example/x": domainForce,
+		"https://example.com/":             domainDefault,
+	}
+	for pageURL, want := range tests {
+		if got := f.domainActionFor(pageURL); got != want {
+			t.Errorf("%s: expected %v, got %v", pageURL, want, got)
+		}
+	}
+}
+
+func TestLoadDomainRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favicon-domains.txt")
+	content := "# privacy\ndeny mybank.com\n\nforce wiki.corp\nDENY health.example\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadDomainRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rules.Deny, ",") != "mybank.com,health.example" || strings.Join(rules.Force, ",") != "wiki.corp" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	if _, err := LoadDomainRules(filepath.Join(t.TempDir(), "missing.txt")); err != nil {
+		t.Errorf("missing file should be empty rules, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("block mybank.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDomainRules(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("expected error with line number, got %v", err)
+	}
+}
+
+func TestDomainRules_AppliedToFetches(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte{0x89, 0x50, 0x4E, 0x47})
+	}))
+	defer ts.Close()
+	pageURL := ts.URL + "/page"
+
+	// Denied: never contacted in either path
+	f := NewWithCache(t.TempDir())
+	f.SetDomainRules(DomainRules{Deny: []string{"127.0.0.1"}})
+	if result := f.PreCacheFavicons([]string{pageURL}, 1); result.Denied != 1 {
+		t.Errorf("expected precache to deny, got %+v", result)
+	}
+	if got := f.FetchAsDataURL(pageURL); got != "" || requests != 0 {
+		t.Errorf("expected no fetch for denied domain, got %q with %d requests", got, requests)
+	}
+
+	// Forced: fetched (home page, then /favicon.ico) despite the private host guard and a cached failure
+	f = NewWithCache(t.TempDir())
+	f.SetBlockPrivateHosts(true)
+	f.SetDomainRules(DomainRules{Force: []string{"127.0.0.1"}})
+	f.cacheFailure(pageURL)
+	if got := f.FetchAsDataURL(pageURL); got == "" || requests != 2 {
+		t.Errorf("expected forced fetch, got %q with %d requests", got, requests)
+	}
+	// Refetched straight from the /favicon.ico the first fetch ended on
+	if result := f.PreCacheFavicons([]string{pageURL}, 1); result.Fetched != 1 || requests != 3 {
+		t.Errorf("expected precache to refetch forced domain, got %+v with %d requests", result, requests)
+	}
+}
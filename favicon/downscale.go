@@ -0,0 +1,54 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+
+	// Decoders for the formats favicons are fetched in
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// Downscale re-encodes a base64 data URL image wider or taller than size
+// pixels as a size×size PNG. It returns dataURL unchanged if it is already
+// small enough, can't be decoded (ICO, SVG, AVIF), or would not get smaller.
+func Downscale(dataURL string, size int) string {
+	header, encoded, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:image/") || !strings.HasSuffix(header, ";base64") {
+		return dataURL
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return dataURL
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return dataURL
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() <= size && bounds.Dy() <= size {
+		return dataURL
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scale(src, size)); err != nil {
+		return dataURL
+	}
+	scaled := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(scaled) >= len(dataURL) {
+		return dataURL
+	}
+	return scaled
+}
+
+// scale resizes src to size×size
+func scale(src image.Image, size int) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
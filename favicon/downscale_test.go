@@ -0,0 +1,52 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func pngDataURL(t *testing.T, size int) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	seed := uint32(1)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			seed = seed*1664525 + 1013904223 // Noise, so the PNG doesn't compress away
+			img.Set(x, y, color.NRGBA{uint8(seed >> 24), uint8(seed >> 16), uint8(seed >> 8), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDownscale(t *testing.T) {
+	large := pngDataURL(t, 128)
+	scaled := Downscale(large, 32)
+	if scaled == large || len(scaled) >= len(large) || !strings.HasPrefix(scaled, "data:image/png;base64,") {
+		t.Fatalf("expected a smaller PNG, got %d bytes from %d", len(scaled), len(large))
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(scaled, "data:image/png;base64,"))
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width != 32 || cfg.Height != 32 {
+		t.Errorf("expected 32x32, got %dx%d (%v)", cfg.Width, cfg.Height, err)
+	}
+
+	for _, unchanged := range []string{
+		pngDataURL(t, 16), // Already small
+		"data:image/svg+xml;base64,PHN2Zy8+",
+		"data:image/x-icon;base64,AAABAA==",
+		"https://a.example/favicon.ico",
+	} {
+		if got := Downscale(unchanged, 32); got != unchanged {
+			t.Errorf("expected %.40s unchanged", unchanged)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package favicon
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,7 +15,11 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"arc-to-zen/paths"
 )
 
 const (
@@ -24,61 +33,155 @@ const (
 	failedMarker = "FAILED"
 )
 
+// errNotImage is returned when a favicon response isn't a recognized image
+var errNotImage = errors.New("response is not an image")
+
+// Clock supplies the current time, so tests can be deterministic
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Options configures a Fetcher. Zero values select the defaults.
+type Options struct {
+	// CacheDir holds the cache database, CacheFileName (default: favicons/
+	// under paths.CacheDir)
+	CacheDir string
+	// Transport performs HTTP requests. The default dials directly and enforces
+	// SetBlockPrivateHosts at connect time; with a custom transport the check
+	// runs on the resolved host before each request instead.
+	Transport http.RoundTripper
+	// Clock timestamps audit log and cache entries (default: the system clock)
+	Clock Clock
+	// Providers are asked for a favicon in order until one has it:
+	// ProviderDirect, ProviderGoogle, ProviderDuckDuckGo (default: ProviderDirect
+	// only, so no third party learns which sites are imported). Unknown names
+	// are ignored.
+	Providers []string
+}
+
 // Fetcher handles fetching and encoding favicons
 type Fetcher struct {
-	client   *http.Client
-	cacheDir string
+	client     *http.Client
+	clock      Clock
+	providers  []string // See Options.Providers
+	cacheDir   string
+	db         *sql.DB // Cache database, opened by cacheDB
+	dbOnce     sync.Once
+	dbErr      error
+	savedBytes atomic.Int64 // Cache bytes not written because the image was already stored
+	net        netCounters  // See NetworkStats
+
+	blockPrivate atomic.Bool // See SetBlockPrivateHosts
+	cacheOnly    atomic.Bool // See SetCacheOnly
+	blockedHosts sync.Map    // Hosts refused by the private host guard
+
+	domainRules atomic.Pointer[DomainRules] // See SetDomainRules
+	audit       atomic.Pointer[auditLog]    // See SetAuditLog
 }
 
-// New creates a new Fetcher with default settings and cache directory (~/.arc-to-zen/favicons)
+// New creates a new Fetcher with default settings and cache directory (see paths.CacheDir)
 func New() *Fetcher {
-	cache := defaultCacheDir()
-	return &Fetcher{
-		client: &http.Client{
-			Timeout: httpTimeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				// Allow up to 5 redirects
-				if len(via) >= 5 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
-		},
-		cacheDir: cache,
-	}
+	return NewWithOptions(Options{})
 }
 
 // NewWithCache creates a new Fetcher with a custom cache directory
 func NewWithCache(cacheDir string) *Fetcher {
-	if cacheDir == "" {
-		cacheDir = defaultCacheDir()
+	return NewWithOptions(Options{CacheDir: cacheDir})
+}
+
+// NewWithOptions creates a new Fetcher with a custom cache directory, HTTP transport or clock
+func NewWithOptions(opts Options) *Fetcher {
+	f := &Fetcher{clock: opts.Clock, cacheDir: opts.CacheDir}
+	if f.cacheDir == "" {
+		f.cacheDir = defaultCacheDir()
+	}
+	_ = os.MkdirAll(f.cacheDir, 0o755)
+	if f.clock == nil {
+		f.clock = systemClock{}
+	}
+	for _, provider := range opts.Providers {
+		if isProvider(provider) {
+			f.providers = append(f.providers, provider)
+		}
+	}
+	if len(f.providers) == 0 {
+		f.providers = []string{ProviderDirect}
 	}
-	_ = os.MkdirAll(cacheDir, 0o755)
-	return &Fetcher{
-		client: &http.Client{Timeout: httpTimeout},
-		cacheDir: cacheDir,
+
+	transport := opts.Transport
+	if transport == nil {
+		transport = f.guardedTransport()
+	} else {
+		transport = &hostCheckTransport{next: transport, fetcher: f}
 	}
+	f.client = &http.Client{
+		Timeout:   httpTimeout,
+		Transport: &auditTransport{next: transport, fetcher: f},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Allow up to 5 redirects
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+	return f
 }
 
 // FetchAsDataURL fetches a favicon from the given URL and returns it as a data URL
 // Returns empty string if fetch fails
 func (f *Fetcher) FetchAsDataURL(pageURL string) string {
+	return f.FetchAsDataURLContext(context.Background(), pageURL)
+}
+
+// FetchAsDataURLContext is FetchAsDataURL with a context bounding the request
+func (f *Fetcher) FetchAsDataURLContext(ctx context.Context, pageURL string) string {
 	faviconURL, err := f.buildFaviconURL(pageURL)
 	if err != nil {
 		return ""
 	}
 
-	// Try cache first
-	if cached := f.readFromCache(pageURL); cached != "" {
+	action := f.domainActionFor(pageURL)
+	if action == domainDeny {
+		return ""
+	}
+
+	// Try cache first (force-listed domains are always fetched fresh)
+	cacheOnly := f.cacheOnly.Load()
+	if cached := f.readFromCache(pageURL); cached != "" && (action != domainForce || cacheOnly) {
 		// Return empty for failed markers (tab imports without favicon)
 		if cached == failedMarker {
 			return ""
 		}
 		return cached
 	}
+	if cacheOnly {
+		return ""
+	}
+
+	unlock := f.lockHost(pageURL)
+	defer unlock()
+	if cached := f.readFromCache(pageURL); cached != "" && action != domainForce {
+		// Another fetcher sharing the cache got it while we waited
+		if cached == failedMarker {
+			return ""
+		}
+		return cached
+	}
 
-	data, contentType, err := f.fetchFavicon(faviconURL)
+	data, contentType, err := f.fetchFromProviders(ctx, pageURL, faviconURL)
 	if err != nil {
+		// Garbage won't become an image on retry; network errors might
+		if errors.Is(err, errNotImage) {
+			f.cacheFailure(pageURL)
+		}
+		if errors.Is(err, ErrPrivateHost) {
+			f.markBlocked(pageURL)
+		}
 		return ""
 	}
 
@@ -110,8 +213,15 @@ func (f *Fetcher) buildFaviconURL(pageURL string) (string, error) {
 }
 
 // fetchFavicon downloads the favicon from the given URL
-func (f *Fetcher) fetchFavicon(faviconURL string) ([]byte, string, error) {
-	resp, err := f.client.Get(faviconURL)
+func (f *Fetcher) fetchFavicon(ctx context.Context, faviconURL string) ([]byte, string, error) {
+	if f.domainActionFor(faviconURL) == domainForce {
+		ctx = withForce(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, faviconURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch favicon: %w", err)
+	}
+	resp, err := f.client.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch favicon: %w", err)
 	}
@@ -141,6 +251,14 @@ func (f *Fetcher) fetchFavicon(faviconURL string) ([]byte, string, error) {
 		return nil, "", fmt.Errorf("favicon too large")
 	}
 
+	// Trust the bytes over the Content-Type header; servers often answer
+	// /favicon.ico with an HTML error page and a 200 status
+	sniffed := sniffImageType(data)
+	if sniffed == "" {
+		return nil, "", fmt.Errorf("%w (served as %q)", errNotImage, contentType)
+	}
+	data, contentType = toCompatibleFormat(data, sniffed)
+
 	return data, contentType, nil
 }
 
@@ -160,75 +278,54 @@ func (f *Fetcher) encodeAsDataURL(data []byte, contentType string) string {
 	return fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
 }
 
-// readFromCache tries to read a cached data URL for the page host
-// Returns the cached value, or empty string if not cached
-// Returns failedMarker if the URL previously failed (so we can skip it)
-func (f *Fetcher) readFromCache(pageURL string) string {
-	if f.cacheDir == "" {
-		return ""
-	}
-	u, err := url.Parse(pageURL)
-	if err != nil || u.Host == "" {
-		return ""
-	}
-	_ = os.MkdirAll(f.cacheDir, 0o755)
-	path := filepath.Join(f.cacheDir, sanitizeFilename(u.Host)+".txt")
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return ""
-	}
-	return string(b)
+// DedupSavedBytes returns how many bytes of cache writes were avoided because an
+// identical image was already stored for another host, since the Fetcher was
+// created. Callers reusing a Fetcher subtract an earlier value to get a run's.
+func (f *Fetcher) DedupSavedBytes() int64 {
+	return f.savedBytes.Load()
 }
 
-// cacheFailure writes a failure marker so we don't retry unreachable URLs
-func (f *Fetcher) cacheFailure(pageURL string) {
-	if f.cacheDir == "" {
-		return
-	}
-	u, err := url.Parse(pageURL)
-	if err != nil || u.Host == "" {
-		return
-	}
-	_ = os.MkdirAll(f.cacheDir, 0o755)
-	path := filepath.Join(f.cacheDir, sanitizeFilename(u.Host)+".txt")
-	_ = os.WriteFile(path, []byte(failedMarker), 0o644)
-}
-
-// writeToCache writes the data URL to cache
-func (f *Fetcher) writeToCache(pageURL, dataURL string) {
-	if f.cacheDir == "" || dataURL == "" {
-		return
-	}
-	u, err := url.Parse(pageURL)
-	if err != nil || u.Host == "" {
-		return
-	}
-	_ = os.MkdirAll(f.cacheDir, 0o755)
-	path := filepath.Join(f.cacheDir, sanitizeFilename(u.Host)+".txt")
-	_ = os.WriteFile(path, []byte(dataURL), 0o644)
-}
-
-// defaultCacheDir returns ~/.arc-to-zen/favicons and ensures it exists
+// defaultCacheDir returns the favicons directory under the cache dir and ensures it exists
 func defaultCacheDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil || home == "" {
+	cacheDir, err := paths.CacheDir()
+	if err != nil {
 		return ""
 	}
-	dir := filepath.Join(home, ".arc-to-zen", "favicons")
+	dir := filepath.Join(cacheDir, "favicons")
 	_ = os.MkdirAll(dir, 0o755)
 	return dir
 }
 
-// sanitizeFilename makes a safe filename from a host (includes port if present)
+// Length limit for the readable part of cache filenames
+const maxCacheNamePrefix = 64
+
+// cacheFileName returns the file name older versions cached a host (port
+// included) in, still used for its lock file: a readable sanitized prefix
+// plus a hash of the full host, so hosts that sanitize or truncate to the
+// same prefix never share a file
+func cacheFileName(host string) string {
+	host = strings.ToLower(host)
+	sum := sha256.Sum256([]byte(host))
+	prefix := truncateRunes(sanitizeFilename(host), maxCacheNamePrefix)
+	return fmt.Sprintf("%s-%s.txt", prefix, hex.EncodeToString(sum[:8]))
+}
+
+// sanitizeFilename makes a safe filename from a host
 func sanitizeFilename(name string) string {
 	// Replace path separators and spaces/colons with underscore
 	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", " ", "_")
-	name = replacer.Replace(name)
-	// Limit length to a reasonable size
-	if len(name) > 200 {
-		name = name[:200]
+	return replacer.Replace(name)
+}
+
+// truncateRunes shortens s to at most maxBytes without splitting a UTF-8 sequence
+func truncateRunes(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
 	}
-	return name
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
 }
 
 // normalizeContentType normalizes the content type for common favicon formats
@@ -251,6 +348,10 @@ func normalizeContentType(contentType string) string {
 		return "image/svg+xml"
 	case "image/webp":
 		return "image/webp"
+	case "image/avif":
+		return "image/avif"
+	case "image/bmp", "image/x-ms-bmp":
+		return "image/bmp"
 	default:
 		// Default to x-icon if unknown
 		return "image/x-icon"
@@ -263,11 +364,14 @@ type PreCacheResult struct {
 	Cached     int // URLs already in cache
 	Fetched    int // URLs successfully fetched
 	Failed     int // URLs that failed to fetch
+	Blocked    int // URLs on private hosts that were not contacted
+	Denied     int // URLs on denied domains that were not contacted
+	Canceled   int // URLs not fetched because the context ended (not cached as failures)
 }
 
-// ProgressCallback is called during pre-caching to report progress
-// processed is the number of URLs processed so far, total is the total count
-type ProgressCallback func(processed, total int)
+// ProgressCallback is called during pre-caching to report progress, at most
+// every progressInterval and once for the last URL
+type ProgressCallback func(Progress)
 
 // PreCacheFavicons fetches favicons for multiple URLs in parallel
 // Returns statistics about the operation
@@ -275,110 +379,16 @@ func (f *Fetcher) PreCacheFavicons(urls []string, workers int) *PreCacheResult {
 	return f.PreCacheFaviconsWithProgress(urls, workers, nil)
 }
 
-// ClearCache removes all cached favicons
-// Returns the number of files removed
-func (f *Fetcher) ClearCache() (int, error) {
-	if f.cacheDir == "" {
-		return 0, fmt.Errorf("no cache directory configured")
-	}
-
-	entries, err := os.ReadDir(f.cacheDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to read cache directory: %w", err)
-	}
-
-	removed := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if strings.HasSuffix(entry.Name(), ".txt") {
-			path := filepath.Join(f.cacheDir, entry.Name())
-			if err := os.Remove(path); err == nil {
-				removed++
-			}
-		}
-	}
-	return removed, nil
-}
-
-// ClearFailedCache removes only the failed favicon markers from cache
-// Returns the number of failed entries removed
-func (f *Fetcher) ClearFailedCache() (int, error) {
-	if f.cacheDir == "" {
-		return 0, fmt.Errorf("no cache directory configured")
-	}
-
-	entries, err := os.ReadDir(f.cacheDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to read cache directory: %w", err)
-	}
-
-	removed := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if strings.HasSuffix(entry.Name(), ".txt") {
-			path := filepath.Join(f.cacheDir, entry.Name())
-			content, err := os.ReadFile(path)
-			if err != nil {
-				continue
-			}
-			if string(content) == failedMarker {
-				if err := os.Remove(path); err == nil {
-					removed++
-				}
-			}
-		}
-	}
-	return removed, nil
-}
-
-// GetCacheStats returns statistics about the current cache
-func (f *Fetcher) GetCacheStats() (total, successful, failed int, err error) {
-	if f.cacheDir == "" {
-		return 0, 0, 0, fmt.Errorf("no cache directory configured")
-	}
-
-	entries, err := os.ReadDir(f.cacheDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, 0, 0, nil
-		}
-		return 0, 0, 0, fmt.Errorf("failed to read cache directory: %w", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if strings.HasSuffix(entry.Name(), ".txt") {
-			total++
-			path := filepath.Join(f.cacheDir, entry.Name())
-			content, err := os.ReadFile(path)
-			if err != nil {
-				continue
-			}
-			if string(content) == failedMarker {
-				failed++
-			} else {
-				successful++
-			}
-		}
-	}
-	return total, successful, failed, nil
-}
-
 // PreCacheFaviconsWithProgress fetches favicons for multiple URLs in parallel
 // with an optional progress callback
 func (f *Fetcher) PreCacheFaviconsWithProgress(urls []string, workers int, progress ProgressCallback) *PreCacheResult {
+	return f.PreCacheFaviconsContext(context.Background(), urls, workers, progress)
+}
+
+// PreCacheFaviconsContext is PreCacheFaviconsWithProgress with a context bounding
+// the requests. URLs that fail because ctx ended count as Canceled and are not
+// cached as failures.
+func (f *Fetcher) PreCacheFaviconsContext(ctx context.Context, urls []string, workers int, progress ProgressCallback) *PreCacheResult {
 	if workers <= 0 {
 		workers = defaultWorkers
 	}
@@ -395,7 +405,7 @@ func (f *Fetcher) PreCacheFaviconsWithProgress(urls []string, workers int, progr
 	urlChan := make(chan string, len(urls))
 	var wg sync.WaitGroup
 	var mu sync.Mutex
-	processed := 0
+	tracker := newProgressTracker(progress, PhaseFavicons, result.Total)
 
 	// Start workers
 	for i := 0; i < workers; i++ {
@@ -405,22 +415,37 @@ func (f *Fetcher) PreCacheFaviconsWithProgress(urls []string, workers int, progr
 			for pageURL := range urlChan {
 				if pageURL == "" {
 					mu.Lock()
-					processed++
-					if progress != nil {
-						progress(processed, result.Total)
-					}
+					tracker.done(pageURL)
+					mu.Unlock()
+					continue
+				}
+
+				action := f.domainActionFor(pageURL)
+				if action == domainDeny {
+					mu.Lock()
+					result.Denied++
+					tracker.done(pageURL)
 					mu.Unlock()
 					continue
 				}
 
 				// Check if already cached (includes failed markers)
-				if cached := f.readFromCache(pageURL); cached != "" {
+				if cached := f.readFromCache(pageURL); cached != "" && action != domainForce {
 					mu.Lock()
 					result.Cached++
-					processed++
-					if progress != nil {
-						progress(processed, result.Total)
-					}
+					tracker.done(pageURL)
+					mu.Unlock()
+					continue
+				}
+
+				// Wait for any other fetcher on this host (another worker, or
+				// another Fetcher sharing the cache), then check again
+				unlock := f.lockHost(pageURL)
+				if cached := f.readFromCache(pageURL); cached != "" && action != domainForce {
+					unlock()
+					mu.Lock()
+					result.Cached++
+					tracker.done(pageURL)
 					mu.Unlock()
 					continue
 				}
@@ -429,41 +454,52 @@ func (f *Fetcher) PreCacheFaviconsWithProgress(urls []string, workers int, progr
 				faviconURL, err := f.buildFaviconURL(pageURL)
 				if err != nil {
 					f.cacheFailure(pageURL) // Cache the failure
+					unlock()
 					mu.Lock()
 					result.Failed++
-					processed++
-					if progress != nil {
-						progress(processed, result.Total)
-					}
+					tracker.done(pageURL)
 					mu.Unlock()
 					continue
 				}
 
-				data, contentType, err := f.fetchFavicon(faviconURL)
+				data, contentType, err := f.fetchFromProviders(ctx, pageURL, faviconURL)
+				if errors.Is(err, ErrPrivateHost) {
+					// Not cached, so a later run with private hosts allowed can fetch it
+					f.markBlocked(pageURL)
+					unlock()
+					mu.Lock()
+					result.Blocked++
+					tracker.done(pageURL)
+					mu.Unlock()
+					continue
+				}
 				if err != nil {
-					f.cacheFailure(pageURL) // Cache the failure
+					canceled := ctx.Err() != nil
+					if !canceled {
+						f.cacheFailure(pageURL) // Cache the failure
+					}
+					unlock()
 					mu.Lock()
-					result.Failed++
-					processed++
-					if progress != nil {
-						progress(processed, result.Total)
+					if canceled {
+						result.Canceled++
+					} else {
+						result.Failed++
 					}
+					tracker.done(pageURL)
 					mu.Unlock()
 					continue
 				}
 
 				dataURL := f.encodeAsDataURL(data, contentType)
+				f.writeToCache(pageURL, dataURL)
+				unlock()
 				mu.Lock()
 				if dataURL != "" {
-					f.writeToCache(pageURL, dataURL)
 					result.Fetched++
 				} else {
 					result.Failed++
 				}
-				processed++
-				if progress != nil {
-					progress(processed, result.Total)
-				}
+				tracker.done(pageURL)
 				mu.Unlock()
 			}
 		}()
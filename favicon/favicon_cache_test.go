@@ -1,12 +1,15 @@
 package favicon
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestCacheReadWrite(t *testing.T) {
@@ -28,10 +31,117 @@ func TestCacheReadWrite(t *testing.T) {
 		t.Fatalf("cache mismatch: want %q got %q", dataURL, got)
 	}
 
-	// Verify filename is based on host
-	u := filepath.Join(tmp, "example.com.txt")
-	if _, err := os.Stat(u); err != nil {
-		t.Fatalf("expected cache file at %s: %v", u, err)
+	// One row per host, with what the image is
+	var contentType string
+	var size, fetchedAt, failures int
+	err := f.db.QueryRow("SELECT content_type, size, fetched_at, failures FROM hosts WHERE host = ?", "example.com").
+		Scan(&contentType, &size, &fetchedAt, &failures)
+	if err != nil {
+		t.Fatalf("expected a cache row for example.com: %v", err)
+	}
+	if contentType != "image/png" || size != 8 || fetchedAt == 0 || failures != 0 {
+		t.Errorf("unexpected row: %s, %d bytes, fetched %d, %d failures", contentType, size, fetchedAt, failures)
+	}
+
+	// Failures are counted, and a success resets them
+	f.cacheFailure(pageURL)
+	f.cacheFailure("https://EXAMPLE.com/other")
+	if got := f.readFromCache(pageURL); got != failedMarker {
+		t.Fatalf("expected the failure marker, got %q", got)
+	}
+	f.db.QueryRow("SELECT failures FROM hosts WHERE host = ?", "example.com").Scan(&failures)
+	if failures != 2 {
+		t.Errorf("expected 2 failures, got %d", failures)
+	}
+	if total, successful, failed, err := f.GetCacheStats(); total != 1 || successful != 0 || failed != 1 || err != nil {
+		t.Errorf("unexpected stats %d/%d/%d (%v)", total, successful, failed, err)
+	}
+	f.writeToCache(pageURL, dataURL)
+	f.db.QueryRow("SELECT failures FROM hosts WHERE host = ?", "example.com").Scan(&failures)
+	if failures != 0 || f.readFromCache(pageURL) != dataURL {
+		t.Errorf("expected the favicon back without failures, got %d", failures)
+	}
+}
+
+func TestCacheFileName_NoCollisionsAndValidUTF8(t *testing.T) {
+	// These hosts sanitize to the same string
+	a := cacheFileName("example.com:8080")
+	b := cacheFileName("example.com_8080")
+	if a == b {
+		t.Errorf("expected distinct cache files, both got %s", a)
+	}
+
+	// Long multi-byte hosts are truncated on a rune boundary
+	long := strings.Repeat("ü", 100) + ".example"
+	name := cacheFileName(long)
+	if !utf8.ValidString(name) {
+		t.Errorf("cache file name is not valid UTF-8: %q", name)
+	}
+	if len(name) > maxCacheNamePrefix+len("-0123456789abcdef.txt") {
+		t.Errorf("cache file name too long: %d bytes", len(name))
+	}
+}
+
+func TestCacheDB_MigratesTextFiles(t *testing.T) {
+	tmp := t.TempDir()
+	dataURL := "data:image/png;base64,iVBORw0KGgo="
+	shared := "data:image/x-icon;base64,AAABAA=="
+	sum := sha256.Sum256([]byte(shared))
+	hash := hex.EncodeToString(sum[:])
+	long := strings.Repeat("a", 80) + ".example"
+	files := map[string]string{
+		"example.com.txt":                         dataURL, // Oldest naming
+		cacheFileName("one.example:8080"):         imageRefPrefix + hash,
+		cacheFileName("two.example"):              imageRefPrefix + hash,
+		cacheFileName("down.example"):             failedMarker,
+		cacheFileName("gone.example"):             imageRefPrefix + "0000", // Image already removed
+		cacheFileName(long):                       dataURL,                 // Host truncated out of the name
+		filepath.Join(imagesDirName, hash+".txt"): shared,
+	}
+	if err := os.MkdirAll(filepath.Join(tmp, imagesDirName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewWithCache(tmp)
+	want := map[string]string{
+		"https://example.com/page":  dataURL,
+		"https://one.example:8080/": shared,
+		"https://two.example/":      shared,
+		"https://down.example/":     failedMarker,
+		"https://gone.example/":     "",
+		"https://" + long + "/":     "",
+	}
+	for pageURL, cached := range want {
+		if got := f.readFromCache(pageURL); got != cached {
+			t.Errorf("%s: expected %q, got %q", pageURL, cached, got)
+		}
+	}
+	var images int
+	f.db.QueryRow("SELECT COUNT(*) FROM images").Scan(&images)
+	if images != 2 {
+		t.Errorf("expected the shared image stored once, got %d images", images)
+	}
+	entries, _ := os.ReadDir(tmp)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".txt") {
+			t.Errorf("expected %s to be removed", entry.Name())
+		}
+	}
+}
+
+func TestTextCacheHost(t *testing.T) {
+	for _, host := range []string{"example.com", "example.com:8080", "xn--mnchen-3ya.de", "my-site.example"} {
+		if got := textCacheHost(cacheFileName(host)); got != host {
+			t.Errorf("%s: got %q", host, got)
+		}
+	}
+	if got := textCacheHost("Example.com_8443.txt"); got != "example.com:8443" {
+		t.Errorf("expected the legacy name's host and port, got %q", got)
 	}
 }
 
@@ -71,3 +181,114 @@ func TestFetchAsDataURL_UsesCacheOnSubsequentCalls(t *testing.T) {
 		t.Fatalf("expected cached data URL, got empty string")
 	}
 }
+
+func TestWriteToCache_StoresIdenticalImagesOnce(t *testing.T) {
+	tmp := t.TempDir()
+	f := NewWithCache(tmp)
+	dataURL := "data:image/png;base64," + strings.Repeat("A", 1000)
+
+	f.writeToCache("https://one.example.com/", dataURL)
+	f.writeToCache("https://two.example.com/", dataURL)
+	f.writeToCache("https://other.example.com/", "data:image/png;base64,Bv==")
+
+	for _, page := range []string{"https://one.example.com/x", "https://two.example.com/y"} {
+		if got := f.readFromCache(page); got != dataURL {
+			t.Errorf("%s: expected shared image, got %q", page, got)
+		}
+	}
+
+	var images int
+	if err := f.db.QueryRow("SELECT COUNT(*) FROM images").Scan(&images); err != nil {
+		t.Fatal(err)
+	}
+	if images != 2 {
+		t.Errorf("expected 2 stored images, got %d", images)
+	}
+	if saved := f.DedupSavedBytes(); saved <= 0 || saved >= int64(len(dataURL)) {
+		t.Errorf("expected savings just under %d bytes, got %d", len(dataURL), saved)
+	}
+
+	// A host that now fails drops its image only once no one else uses it
+	f.cacheFailure("https://one.example.com/")
+	f.cacheFailure("https://other.example.com/")
+	f.db.QueryRow("SELECT COUNT(*) FROM images").Scan(&images)
+	if images != 1 || f.readFromCache("https://two.example.com/") != dataURL {
+		t.Errorf("expected only the shared image left, got %d images", images)
+	}
+
+	// Clearing removes images too, so stale references can't resolve
+	if removed, err := f.ClearCache(); err != nil || removed != 3 {
+		t.Fatalf("expected 3 hosts cleared, got %d (%v)", removed, err)
+	}
+	f.db.QueryRow("SELECT COUNT(*) FROM images").Scan(&images)
+	if images != 0 {
+		t.Errorf("expected no images left, got %d", images)
+	}
+}
+
+func TestCacheDB_CacheOnlyLeavesDiskAlone(t *testing.T) {
+	tmp := t.TempDir()
+	dataURL := "data:image/png;base64,iVBORw0KGgo="
+	name := cacheFileName("example.com")
+	if err := os.WriteFile(filepath.Join(tmp, name), []byte(dataURL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewWithCache(tmp)
+	f.SetCacheOnly(true)
+	if got := f.readFromCache("https://example.com/"); got != dataURL {
+		t.Errorf("expected the .txt entry to be read, got %q", got)
+	}
+	f.Close()
+	entries, _ := os.ReadDir(tmp)
+	if len(entries) != 1 || entries[0].Name() != name {
+		t.Errorf("expected only the .txt file, got %v", entries)
+	}
+
+	// The real run migrates; a later dry run reads the database read-only
+	f = NewWithCache(tmp)
+	f.readFromCache("https://example.com/")
+	f.Close()
+	f = NewWithCache(tmp)
+	f.SetCacheOnly(true)
+	defer f.Close()
+	if got := f.readFromCache("https://example.com/"); got != dataURL {
+		t.Errorf("expected the migrated entry, got %q", got)
+	}
+	f.writeToCache("https://other.example/", dataURL)
+	if total, _, _, _ := f.GetCacheStats(); total != 1 {
+		t.Errorf("expected the cache-only fetcher not to write, got %d entries", total)
+	}
+}
+
+func TestCacheDB_KeepsFilesThatFailedToMigrate(t *testing.T) {
+	tmp := t.TempDir()
+	shared := "data:image/x-icon;base64,AAABAA=="
+	sum := sha256.Sum256([]byte(shared))
+	hash := hex.EncodeToString(sum[:])
+	if err := os.MkdirAll(filepath.Join(tmp, imagesDirName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(tmp, imagesDirName, hash+".txt"), []byte(shared), 0o644)
+	os.WriteFile(filepath.Join(tmp, cacheFileName("one.example")), []byte(imageRefPrefix+hash), 0o644)
+	// Unreadable: a dangling link
+	broken := filepath.Join(tmp, cacheFileName("two.example"))
+	if err := os.Symlink(filepath.Join(tmp, "missing"), broken); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	f := NewWithCache(tmp)
+	defer f.Close()
+	if got := f.readFromCache("https://one.example/"); got != shared {
+		t.Errorf("expected the migrated entry, got %q", got)
+	}
+	if _, err := os.Lstat(broken); err != nil {
+		t.Errorf("expected the unmigrated file to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, imagesDirName, hash+".txt")); err != nil {
+		t.Errorf("expected images/ to be kept while a file is left: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, cacheFileName("one.example"))); !os.IsNotExist(err) {
+		t.Errorf("expected the migrated file to be removed")
+	}
+}
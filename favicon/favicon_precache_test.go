@@ -21,7 +21,7 @@ func TestPreCacheFavicons(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestCount++
 		w.Header().Set("Content-Type", "image/x-icon")
-		w.Write([]byte("fake-favicon-data"))
+		w.Write([]byte("\x89PNG fake-favicon-data"))
 	}))
 	defer server.Close()
 
@@ -52,15 +52,13 @@ func TestPreCacheFavicons(t *testing.T) {
 		t.Errorf("Expected failed=0, got %d", result.Failed)
 	}
 
-	// Verify cache files were created
-	entries, err := os.ReadDir(tempDir)
+	// Should have 1 host cache entry (all URLs use same host)
+	total, successful, _, err := fetcher.GetCacheStats()
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Should have 1 cache file (all URLs use same host)
-	if len(entries) != 1 {
-		t.Errorf("Expected 1 cache file, got %d", len(entries))
+	if total != 1 || successful != 1 {
+		t.Errorf("Expected 1 cache entry, got %d", total)
 	}
 
 	// Pre-cache again - should use cache
@@ -112,7 +110,7 @@ func TestPreCacheFavicons_WithFailures(t *testing.T) {
 			return
 		}
 		w.Header().Set("Content-Type", "image/x-icon")
-		w.Write([]byte("fake-favicon-data"))
+		w.Write([]byte("\x89PNG fake-favicon-data"))
 	}))
 	defer server.Close()
 
@@ -174,7 +172,7 @@ func TestPreCacheFavicons_DefaultWorkers(t *testing.T) {
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/x-icon")
-		w.Write([]byte("fake-favicon-data"))
+		w.Write([]byte("\x89PNG fake-favicon-data"))
 	}))
 	defer server.Close()
 
@@ -203,7 +201,7 @@ func BenchmarkPreCacheFavicons(b *testing.B) {
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/x-icon")
-		w.Write([]byte("fake-favicon-data"))
+		w.Write([]byte("\x89PNG fake-favicon-data"))
 	}))
 	defer server.Close()
 
@@ -1,11 +1,17 @@
 package favicon
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestBuildFaviconURL(t *testing.T) {
@@ -167,7 +173,7 @@ func TestFetchFavicon(t *testing.T) {
 	f := New()
 
 	t.Run("successful fetch", func(t *testing.T) {
-		data, contentType, err := f.fetchFavicon(ts.URL + "/favicon.ico")
+		data, contentType, err := f.fetchFavicon(context.Background(), ts.URL+"/favicon.ico")
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -180,14 +186,14 @@ func TestFetchFavicon(t *testing.T) {
 	})
 
 	t.Run("404 not found", func(t *testing.T) {
-		_, _, err := f.fetchFavicon(ts.URL + "/notfound")
+		_, _, err := f.fetchFavicon(context.Background(), ts.URL+"/notfound")
 		if err == nil {
 			t.Error("expected error for 404, got none")
 		}
 	})
 
 	t.Run("invalid URL", func(t *testing.T) {
-		_, _, err := f.fetchFavicon("http://this-domain-does-not-exist-12345.com/favicon.ico")
+		_, _, err := f.fetchFavicon(context.Background(), "http://this-domain-does-not-exist-12345.com/favicon.ico")
 		if err == nil {
 			t.Error("expected error for invalid URL, got none")
 		}
@@ -261,3 +267,71 @@ func TestFetchAsDataURL(t *testing.T) {
 		}
 	})
 }
+
+// roundTripFunc lets a function stand in for the HTTP layer
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return fn(req) }
+
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+func TestNewWithOptions_CustomTransportAndClock(t *testing.T) {
+	var requested []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("\x89PNG offline")),
+			Request:    req,
+		}, nil
+	})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var audit bytes.Buffer
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: transport, Clock: fixedClock{now}})
+	f.SetAuditLog(&audit)
+
+	got := f.FetchAsDataURLContext(context.Background(), "https://offline.test/page")
+	if !strings.HasPrefix(got, "data:image/png;base64,") {
+		t.Fatalf("expected PNG data URL, got %q", got)
+	}
+	if len(requested) != 2 || requested[0] != "https://offline.test/" || requested[1] != "https://offline.test/favicon.ico" {
+		t.Errorf("unexpected requests: %v", requested)
+	}
+
+	var entry AuditEntry
+	if err := json.NewDecoder(&audit).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Time.Equal(now) || entry.DurationMs != 0 {
+		t.Errorf("expected audit entry timed by the clock, got %+v", entry)
+	}
+
+	// The private host guard still applies in front of a custom transport
+	f.SetBlockPrivateHosts(true)
+	if _, _, err := f.fetchFavicon(context.Background(), "http://127.0.0.1/favicon.ico"); !errors.Is(err, ErrPrivateHost) {
+		t.Errorf("expected ErrPrivateHost, got %v", err)
+	}
+	if len(requested) != 2 {
+		t.Errorf("blocked request reached the transport: %v", requested)
+	}
+}
+
+func TestPreCacheFaviconsContext_CanceledNotCached(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, req.Context().Err()
+	})
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: transport})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := f.PreCacheFaviconsContext(ctx, []string{"https://offline.test/"}, 1, nil); result.Canceled != 1 {
+		t.Fatalf("expected 1 canceled, got %+v", result)
+	}
+	if f.readFromCache("https://offline.test/") != "" {
+		t.Error("canceled fetch should not be cached as a failure")
+	}
+}
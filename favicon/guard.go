@@ -0,0 +1,153 @@
+package favicon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateHost is returned when a favicon would be fetched from a private or
+// local address while private hosts are blocked
+var ErrPrivateHost = errors.New("private or local address blocked")
+
+// Ranges beyond net.IP's own checks that shouldn't be reached from an import
+var extraPrivateRanges = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT, common for VPNs
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+}
+
+// SetBlockPrivateHosts refuses favicon requests to loopback, private, link-local
+// and similar addresses when block is true. The check runs on the resolved
+// address at connect time, so DNS names and redirects can't bypass it.
+// Force-listed domains (see SetDomainRules) are exempt.
+func (f *Fetcher) SetBlockPrivateHosts(block bool) {
+	f.blockPrivate.Store(block)
+}
+
+// IsBlocked reports whether the favicon for pageURL was not fetched because its
+// host is private
+func (f *Fetcher) IsBlocked(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	_, blocked := f.blockedHosts.Load(strings.ToLower(u.Host))
+	return blocked
+}
+
+func (f *Fetcher) markBlocked(pageURL string) {
+	if u, err := url.Parse(pageURL); err == nil {
+		f.blockedHosts.Store(strings.ToLower(u.Host), true)
+	}
+}
+
+// guardedTransport is the default transport with connections checked by
+// checkDialAddress. Behind an HTTP proxy the proxy connects on our behalf, so
+// the target is resolved and checked before the request is handed to it.
+func (f *Fetcher) guardedTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	guarded := &net.Dialer{Timeout: httpTimeout, KeepAlive: 30 * time.Second, Control: f.checkDialAddress}
+	plain := &net.Dialer{Timeout: httpTimeout, KeepAlive: 30 * time.Second}
+
+	var proxies sync.Map // host:port of proxies in use; connecting to them is allowed
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxy, err := http.ProxyFromEnvironment(req)
+		if err != nil || proxy == nil || !f.blockPrivate.Load() || isForced(req.Context()) {
+			return proxy, err
+		}
+		if err := checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		proxies.Store(canonicalAddr(proxy), true)
+		return proxy, nil
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := proxies.Load(addr); ok || isForced(ctx) {
+			return plain.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// hostCheckTransport enforces SetBlockPrivateHosts in front of a caller-supplied
+// transport, whose dialing we don't control
+type hostCheckTransport struct {
+	next    http.RoundTripper
+	fetcher *Fetcher
+}
+
+func (t *hostCheckTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.fetcher.blockPrivate.Load() && !isForced(req.Context()) {
+		if err := checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// checkHost resolves host and fails if any of its addresses is private
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range addrs {
+		if isPrivateAddr(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateHost, host, ip)
+		}
+	}
+	return nil
+}
+
+// canonicalAddr returns the host:port a proxy URL is dialed at
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[u.Scheme]
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// checkDialAddress is the dialer's Control hook enforcing SetBlockPrivateHosts
+func (f *Fetcher) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if !f.blockPrivate.Load() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: unparseable address %s", ErrPrivateHost, host)
+	}
+	if isPrivateAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateHost, ip)
+	}
+	return nil
+}
+
+// isPrivateAddr reports whether ip is not a public internet address
+func isPrivateAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range extraPrivateRanges {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package favicon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPrivateAddr(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1":         true,
+		"10.1.2.3":          true,
+		"172.16.0.1":        true,
+		"192.168.1.1":       true,
+		"169.254.169.254":   true, // Cloud metadata
+		"100.100.1.1":       true, // CGNAT / VPN
+		"0.0.0.0":           true,
+		"::1":               true,
+		"fd00::1":           true,
+		"fe80::1":           true,
+		"::ffff:10.0.0.1":   true,
+		"93.184.216.34":     false,
+		"2606:4700::6810:1": false,
+	}
+	for addr, want := range tests {
+		if got := isPrivateAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("%s: expected %v, got %v", addr, want, got)
+		}
+	}
+}
+
+func TestBlockPrivateHosts(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte{0x89, 0x50, 0x4E, 0x47})
+	}))
+	defer ts.Close()
+	pageURL := ts.URL + "/page"
+
+	f := NewWithCache(t.TempDir())
+	f.SetBlockPrivateHosts(true)
+
+	result := f.PreCacheFavicons([]string{pageURL}, 1)
+	if result.Blocked != 1 || requests != 0 {
+		t.Fatalf("expected request to be blocked, got %+v with %d requests", result, requests)
+	}
+	if !f.IsBlocked(pageURL) {
+		t.Error("expected host to be reported as blocked")
+	}
+	if cached := f.readFromCache(pageURL); cached != "" {
+		t.Errorf("blocked hosts must not be cached as failures, got %q", cached)
+	}
+
+	// Allowing private hosts fetches it on the next attempt
+	f.SetBlockPrivateHosts(false)
+	if got := f.FetchAsDataURL(pageURL); got == "" || requests != 2 {
+		t.Errorf("expected favicon after allowing private hosts, got %q with %d requests", got, requests)
+	}
+}
@@ -0,0 +1,146 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// An ICO file is a directory of images, each a PNG or a BMP without its file
+// header whose height counts the 1-bit transparency (AND) mask below the
+// pixels too. Go's image packages decode neither.
+
+const (
+	icoHeaderSize = 6
+	icoEntrySize  = 16
+	maxICOSize    = 256
+)
+
+var errTruncatedICO = errors.New("truncated ICO file")
+
+// decodeICO decodes the image of an ICO file closest to idealIconSize
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < icoHeaderSize || binary.LittleEndian.Uint16(data[0:]) != 0 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errors.New("not an ICO file")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < icoHeaderSize+count*icoEntrySize {
+		return nil, errTruncatedICO
+	}
+
+	var best []byte
+	bestDistance := -1
+	for i := 0; i < count; i++ {
+		entry := data[icoHeaderSize+i*icoEntrySize:]
+		width := int(entry[0])
+		if width == 0 {
+			width = maxICOSize
+		}
+		if d := sizeDistance(width); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = entry, d
+		}
+	}
+	length := int64(binary.LittleEndian.Uint32(best[8:]))
+	offset := int64(binary.LittleEndian.Uint32(best[12:]))
+	if length == 0 || offset+length > int64(len(data)) {
+		return nil, errTruncatedICO
+	}
+	payload := data[offset : offset+length]
+	if bytes.HasPrefix(payload, []byte("\x89PNG")) {
+		return png.Decode(bytes.NewReader(payload))
+	}
+	return decodeDIB(payload)
+}
+
+// decodeDIB decodes an uncompressed 1, 4, 8, 24 or 32-bit ICO bitmap
+func decodeDIB(dib []byte) (image.Image, error) {
+	if len(dib) < 40 {
+		return nil, errTruncatedICO
+	}
+	headerSize := int(binary.LittleEndian.Uint32(dib[0:]))
+	width := int(int32(binary.LittleEndian.Uint32(dib[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(dib[8:]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(dib[14:]))
+	if headerSize < 40 || headerSize > len(dib) || width <= 0 || height <= 0 || width > maxICOSize || height > maxICOSize {
+		return nil, errors.New("invalid ICO bitmap header")
+	}
+	if compression := binary.LittleEndian.Uint32(dib[16:]); compression != 0 {
+		return nil, fmt.Errorf("unsupported ICO bitmap compression %d", compression)
+	}
+
+	pos := headerSize
+	var palette []color.NRGBA
+	switch bpp {
+	case 1, 4, 8:
+		colors := int(binary.LittleEndian.Uint32(dib[32:]))
+		if colors == 0 || colors > 1<<bpp {
+			colors = 1 << bpp
+		}
+		if len(dib) < pos+colors*4 {
+			return nil, errTruncatedICO
+		}
+		for i := 0; i < colors; i++ {
+			p := dib[pos+i*4:]
+			palette = append(palette, color.NRGBA{p[2], p[1], p[0], 0xFF})
+		}
+		pos += colors * 4
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported ICO bitmap depth %d", bpp)
+	}
+
+	// Rows are padded to 4 bytes and stored bottom-up
+	stride := (width*bpp + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if len(dib) < pos+stride*height {
+		return nil, errTruncatedICO
+	}
+	pixels := dib[pos:]
+	var mask []byte
+	if len(dib) >= pos+stride*height+maskStride*height {
+		mask = dib[pos+stride*height:]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				c = color.NRGBA{row[x*4+2], row[x*4+1], row[x*4], row[x*4+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{row[x*3+2], row[x*3+1], row[x*3], 0xFF}
+			default:
+				bit := x * bpp
+				index := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if index >= len(palette) {
+					return nil, errors.New("invalid ICO palette index")
+				}
+				c = palette[index]
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit images carry their own alpha; older ones rely on the mask
+	if bpp == 32 && hasAlpha {
+		return img, nil
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			alpha := uint8(0xFF)
+			if mask != nil && mask[(height-1-y)*maskStride+x/8]>>(7-x%8)&1 == 1 {
+				alpha = 0
+			}
+			img.Pix[img.PixOffset(x, y)+3] = alpha
+		}
+	}
+	return img, nil
+}
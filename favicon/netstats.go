@@ -0,0 +1,51 @@
+package favicon
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// NetworkStats counts the HTTP requests a Fetcher has made. Subtract an
+// earlier snapshot (Sub) to get the cost of one run.
+type NetworkStats struct {
+	Requests int           // Requests sent, each redirect hop counted (blocked ones weren't sent)
+	Errors   int           // Requests that got no response
+	Bytes    int64         // Response body bytes downloaded
+	Latency  time.Duration // Total time from sending each request to closing its body
+}
+
+// AvgLatency returns the mean time per request, or 0 if none were sent
+func (s NetworkStats) AvgLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Requests)
+}
+
+// Sub returns the traffic since the earlier snapshot
+func (s NetworkStats) Sub(earlier NetworkStats) NetworkStats {
+	return NetworkStats{
+		Requests: s.Requests - earlier.Requests,
+		Errors:   s.Errors - earlier.Errors,
+		Bytes:    s.Bytes - earlier.Bytes,
+		Latency:  s.Latency - earlier.Latency,
+	}
+}
+
+// netCounters are the live counters behind NetworkStats
+type netCounters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	bytes    atomic.Int64
+	latency  atomic.Int64 // Nanoseconds
+}
+
+// NetworkStats returns the requests made by f so far
+func (f *Fetcher) NetworkStats() NetworkStats {
+	return NetworkStats{
+		Requests: int(f.net.requests.Load()),
+		Errors:   int(f.net.errors.Load()),
+		Bytes:    f.net.bytes.Load(),
+		Latency:  time.Duration(f.net.latency.Load()),
+	}
+}
@@ -0,0 +1,64 @@
+package favicon
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// steppingClock advances by step on every reading
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestNetworkStats(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Host == "down.example":
+			return nil, errors.New("connection refused")
+		case req.URL.Path == "/":
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		case req.URL.Query().Get("moved") == "":
+			return &http.Response{
+				StatusCode: http.StatusFound,
+				Header:     http.Header{"Location": []string{"/favicon.ico?moved=1"}},
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("\x89PNG stats")),
+			Request:    req,
+		}, nil
+	})
+	clock := &steppingClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), step: 10 * time.Millisecond}
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: transport, Clock: clock})
+
+	before := f.NetworkStats()
+	f.FetchAsDataURL("https://up.example/")
+	f.FetchAsDataURL("https://down.example/")
+	f.FetchAsDataURL("https://up.example/again") // Cached, no request
+	stats := f.NetworkStats().Sub(before)
+
+	if stats.Requests != 4 || stats.Errors != 1 || stats.Bytes != int64(len("\x89PNG stats")) {
+		t.Errorf("expected 4 requests (a home page, a redirect, a fetch, an error) and the icon's bytes, got %+v", stats)
+	}
+	if stats.Latency != 40*time.Millisecond || stats.AvgLatency() != 10*time.Millisecond {
+		t.Errorf("expected 10ms per request, got %v total, %v average", stats.Latency, stats.AvgLatency())
+	}
+}
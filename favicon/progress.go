@@ -0,0 +1,98 @@
+package favicon
+
+import (
+	"fmt"
+	"time"
+)
+
+// PhaseFavicons is the phase reported while pre-caching favicons
+const PhaseFavicons = "Fetching favicons"
+
+const (
+	// progressInterval is how often the callback is called at most (the last
+	// URL is always reported). Workers wait while it runs, so a slow terminal
+	// would otherwise slow the fetch down.
+	progressInterval = 100 * time.Millisecond
+	// rateWindow is how far back the rate looks, so it follows slowdowns such
+	// as a run of timeouts instead of averaging them away
+	rateWindow = 10 * time.Second
+)
+
+// Progress is a snapshot of a running pre-cache
+type Progress struct {
+	Phase     string
+	Processed int
+	Total     int
+	Current   string  // URL just finished
+	Rate      float64 // URLs per second over the last rateWindow
+	Elapsed   time.Duration
+	ETA       time.Duration // Estimated time left (0 until a rate is known)
+}
+
+// String formats p as "Fetching favicons 340/812 (12/s, ~40s left)"
+func (p Progress) String() string {
+	s := fmt.Sprintf("%s %d/%d", p.Phase, p.Processed, p.Total)
+	if p.Rate <= 0 {
+		return s
+	}
+	rate := fmt.Sprintf("%.0f/s", p.Rate)
+	if p.Rate < 10 {
+		rate = fmt.Sprintf("%.1f/s", p.Rate)
+	}
+	if p.ETA <= 0 || p.Processed >= p.Total {
+		return fmt.Sprintf("%s (%s)", s, rate)
+	}
+	return fmt.Sprintf("%s (%s, ~%s left)", s, rate, p.ETA.Round(time.Second))
+}
+
+// progressTracker turns finished URLs into throttled Progress reports. It is
+// not safe for concurrent use; PreCacheFaviconsContext calls it under its lock.
+type progressTracker struct {
+	callback   ProgressCallback
+	phase      string
+	total      int
+	processed  int
+	start      time.Time
+	lastReport time.Time
+	recent     []time.Time // Completion times within rateWindow
+	now        func() time.Time
+}
+
+func newProgressTracker(callback ProgressCallback, phase string, total int) *progressTracker {
+	t := &progressTracker{callback: callback, phase: phase, total: total, now: time.Now}
+	t.start = t.now()
+	return t
+}
+
+// done records that url finished and reports progress if it is time to
+func (t *progressTracker) done(url string) {
+	t.processed++
+	if t.callback == nil {
+		return
+	}
+	now := t.now()
+	t.recent = append(t.recent, now)
+	for len(t.recent) > 0 && now.Sub(t.recent[0]) > rateWindow {
+		t.recent = t.recent[1:]
+	}
+	if t.processed < t.total && now.Sub(t.lastReport) < progressInterval {
+		return
+	}
+	t.lastReport = now
+	t.callback(t.snapshot(url, now))
+}
+
+func (t *progressTracker) snapshot(url string, now time.Time) Progress {
+	p := Progress{Phase: t.phase, Processed: t.processed, Total: t.total, Current: url, Elapsed: now.Sub(t.start)}
+
+	// Rate over the recent window, or since the start while it is still filling
+	span, count := now.Sub(t.start), t.processed
+	if len(t.recent) > 1 && now.Sub(t.start) > rateWindow {
+		span, count = now.Sub(t.recent[0]), len(t.recent)-1
+	}
+	if span > 0 && count > 0 {
+		p.Rate = float64(count) / span.Seconds()
+		p.ETA = time.Duration(float64(t.total-t.processed) / p.Rate * float64(time.Second))
+	}
+	return p
+}
@@ -0,0 +1,52 @@
+package favicon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTracker_RateAndETA(t *testing.T) {
+	var reports []Progress
+	tracker := newProgressTracker(func(p Progress) { reports = append(reports, p) }, PhaseFavicons, 812)
+	clock := tracker.start
+	tracker.now = func() time.Time { return clock }
+
+	// 12 URLs a second for 30s, reported at most every progressInterval
+	for i := 0; i < 340; i++ {
+		clock = clock.Add(time.Second / 12)
+		tracker.done("https://example.test/")
+	}
+	last := reports[len(reports)-1]
+	if len(reports) >= 340 {
+		t.Errorf("expected throttled reports, got %d for 340 URLs", len(reports))
+	}
+	if last.Rate < 11.5 || last.Rate > 12.5 {
+		t.Errorf("expected about 12/s, got %.2f", last.Rate)
+	}
+	if got := tracker.snapshot("", clock).String(); got != "Fetching favicons 340/812 (12/s, ~39s left)" {
+		t.Errorf("unexpected progress line %q", got)
+	}
+
+	// A slowdown shows in the rate within the window, not after the whole run
+	for i := 0; i < 20; i++ {
+		clock = clock.Add(time.Second)
+		tracker.done("https://slow.test/")
+	}
+	if rate := reports[len(reports)-1].Rate; rate > 2 {
+		t.Errorf("expected the rate to follow the slowdown, got %.2f/s", rate)
+	}
+}
+
+func TestProgressTracker_AlwaysReportsLast(t *testing.T) {
+	var reports []Progress
+	tracker := newProgressTracker(func(p Progress) { reports = append(reports, p) }, PhaseFavicons, 3)
+	for i := 0; i < 3; i++ {
+		tracker.done("https://example.test/")
+	}
+	if len(reports) == 0 || reports[len(reports)-1].Processed != 3 {
+		t.Errorf("expected the final count to be reported, got %+v", reports)
+	}
+	if got := (Progress{Phase: PhaseFavicons, Processed: 1, Total: 3}).String(); got != "Fetching favicons 1/3" {
+		t.Errorf("expected no rate before one is known, got %q", got)
+	}
+}
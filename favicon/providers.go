@@ -0,0 +1,132 @@
+package favicon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Where favicons are looked for (see Options.Providers)
+const (
+	// ProviderDirect asks the site itself: the icons its home page links to,
+	// then /favicon.ico
+	ProviderDirect = "direct"
+	// ProviderGoogle asks Google's s2/favicons service
+	ProviderGoogle = "google"
+	// ProviderDuckDuckGo asks DuckDuckGo's icon service
+	ProviderDuckDuckGo = "duckduckgo"
+)
+
+// Providers lists every provider, in the CLI's default order
+var Providers = []string{ProviderDirect, ProviderGoogle, ProviderDuckDuckGo}
+
+// Suffixes of names that only resolve inside a private network
+var intranetSuffixes = []string{".local", ".localhost", ".internal", ".intranet", ".lan", ".home.arpa", ".corp"}
+
+// ParseProviders parses a comma-separated list of providers, e.g.
+// "direct,duckduckgo"
+func ParseProviders(list string) ([]string, error) {
+	var providers []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !isProvider(name) {
+			return nil, fmt.Errorf("unknown favicon provider %q (expected %s)", name, strings.Join(Providers, ", "))
+		}
+		seen[name] = true
+		providers = append(providers, name)
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("no favicon provider given")
+	}
+	return providers, nil
+}
+
+func isProvider(name string) bool {
+	for _, provider := range Providers {
+		if name == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// providerURL returns the URL provider serves host's favicon at
+func providerURL(provider, host string) string {
+	switch provider {
+	case ProviderGoogle:
+		return "https://www.google.com/s2/favicons?sz=32&domain=" + url.QueryEscape(host)
+	case ProviderDuckDuckGo:
+		return "https://icons.duckduckgo.com/ip3/" + url.PathEscape(host) + ".ico"
+	}
+	return ""
+}
+
+// fetchFromProviders tries f.providers in order and returns the first
+// favicon found. The error is the first provider's, so a site that is down
+// or blocked is reported (and cached) as before.
+func (f *Fetcher) fetchFromProviders(ctx context.Context, pageURL, faviconURL string) ([]byte, string, error) {
+	var firstErr error
+	for _, provider := range f.providers {
+		var data []byte
+		var contentType string
+		var err error
+		if provider == ProviderDirect {
+			data, contentType, err = f.fetchBestIcon(ctx, pageURL, faviconURL)
+		} else {
+			host := pageHost(pageURL)
+			if host == "" || f.isIntranetHost(ctx, host) {
+				continue
+			}
+			data, contentType, err = f.fetchFavicon(ctx, providerURL(provider, host))
+		}
+		if err == nil {
+			return data, contentType, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		// Blocked sites aren't handed to third parties either
+		if ctx.Err() != nil || errors.Is(err, ErrPrivateHost) {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no favicon provider to ask")
+	}
+	return nil, "", firstErr
+}
+
+func pageHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.ToLower(u.Hostname()), ".")
+}
+
+// isIntranetHost reports whether host is, or resolves to, a private address,
+// or has a name only a private network resolves. Such hosts are never sent to
+// third-party providers, which couldn't know them and shouldn't learn them.
+// Names that don't resolve at all (sites that are gone) are still asked
+// about.
+func (f *Fetcher) isIntranetHost(ctx context.Context, host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return isPrivateAddr(ip)
+	}
+	if !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range intranetSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return errors.Is(checkHost(ctx, host), ErrPrivateHost)
+}
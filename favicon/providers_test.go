@@ -0,0 +1,82 @@
+package favicon
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProviders(t *testing.T) {
+	got, err := ParseProviders(" DuckDuckGo, direct,duckduckgo ")
+	if err != nil || !reflect.DeepEqual(got, []string{ProviderDuckDuckGo, ProviderDirect}) {
+		t.Errorf("unexpected providers %v (%v)", got, err)
+	}
+	for _, bad := range []string{"", " , ", "direct,bing"} {
+		if _, err := ParseProviders(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+// providerTransport fails every request to the sites themselves and serves
+// an icon from the providers
+func providerTransport(requested *[]string) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requested = append(*requested, req.URL.String())
+		switch req.URL.Host {
+		case "www.google.com", "icons.duckduckgo.com":
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"image/png"}},
+				Body:       io.NopCloser(strings.NewReader("\x89PNG " + req.URL.Host)),
+				Request:    req,
+			}, nil
+		}
+		return nil, errors.New("connection refused")
+	})
+}
+
+func TestFetchAsDataURL_Providers(t *testing.T) {
+	var requested []string
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: providerTransport(&requested),
+		Providers: []string{ProviderDirect, ProviderDuckDuckGo, ProviderGoogle}})
+
+	got := f.FetchAsDataURL("https://203.0.113.5/gone")
+	if got != f.encodeAsDataURL([]byte("\x89PNG icons.duckduckgo.com"), "image/png") {
+		t.Errorf("expected DuckDuckGo's icon, got %q", got)
+	}
+	want := []string{"https://203.0.113.5/", "https://icons.duckduckgo.com/ip3/203.0.113.5.ico"}
+	if !reflect.DeepEqual(requested, want) {
+		t.Errorf("expected requests %v, got %v", want, requested)
+	}
+
+	// Intranet names are never sent to a provider
+	requested = nil
+	for _, pageURL := range []string{"https://wiki.corp/", "http://intranet/", "http://192.168.1.20/"} {
+		if got := f.FetchAsDataURL(pageURL); got != "" {
+			t.Errorf("%s: expected no favicon, got %q", pageURL, got)
+		}
+	}
+	if len(requested) != 3 {
+		t.Errorf("expected only the sites to be asked, got %v", requested)
+	}
+}
+
+func TestFetchAsDataURL_ProvidersOptIn(t *testing.T) {
+	var requested []string
+	f := NewWithOptions(Options{CacheDir: t.TempDir(), Transport: providerTransport(&requested)})
+	if got := f.FetchAsDataURL("https://203.0.113.5/gone"); got != "" || len(requested) != 1 {
+		t.Errorf("expected only the site to be asked by default, got %q after %v", got, requested)
+	}
+
+	// Without direct, the site itself is never contacted
+	requested = nil
+	f = NewWithOptions(Options{CacheDir: t.TempDir(), Transport: providerTransport(&requested), Providers: []string{ProviderGoogle}})
+	if got := f.FetchAsDataURL("https://203.0.113.5/"); got == "" || len(requested) != 1 ||
+		requested[0] != "https://www.google.com/s2/favicons?sz=32&domain=203.0.113.5" {
+		t.Errorf("expected Google's icon only, got %q after %v", got, requested)
+	}
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"fmt"
+	"time"
+
+	"arc-to-zen/types"
+)
+
+// Zen has no auto-archive: tabs stay open until closed. The closest behavior
+// is its tab unloader, which frees idle tabs' memory but keeps them in the
+// sidebar, with a single timeout for every workspace.
+const (
+	zenTabUnloaderEnabledPref = "zen.tab-unloader.enabled"
+	zenTabUnloaderTimeoutPref = "zen.tab-unloader.timeout-minutes"
+)
+
+// ArchiveSetting is an imported space's Arc auto-archive setting
+type ArchiveSetting struct {
+	SpaceID      string `json:"spaceId"` // Zen workspace UUID
+	Space        string `json:"space"`
+	Never        bool   `json:"never"`
+	AfterMinutes int    `json:"afterMinutes,omitempty"` // Idle time before Arc archives a tab
+	Raw          string `json:"raw"`                    // As stored by Arc
+}
+
+// PrefSuggestion is a Zen pref that approximates Arc behavior
+type PrefSuggestion struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Reason string      `json:"reason"`
+}
+
+func (p *Plan) addArchive(spaceID, space string, setting *types.ArcAutoArchive) {
+	p.Archive = append(p.Archive, ArchiveSetting{
+		SpaceID:      spaceID,
+		Space:        space,
+		Never:        setting.Never,
+		AfterMinutes: int(setting.After / time.Minute),
+		Raw:          setting.Raw,
+	})
+}
+
+// String describes the setting for the summary
+func (s ArchiveSetting) String() string {
+	if s.Never {
+		return "never archives tabs"
+	}
+	return "archives tabs idle for " + formatMinutes(s.AfterMinutes)
+}
+
+// SuggestedPrefs returns Zen prefs that come closest to the spaces' Arc
+// auto-archive settings, or nil if none of them archives tabs. The unloader
+// timeout is global, so the shortest Arc interval is used.
+func (p *Plan) SuggestedPrefs() []PrefSuggestion {
+	shortest := 0
+	for _, setting := range p.Archive {
+		if !setting.Never && setting.AfterMinutes > 0 && (shortest == 0 || setting.AfterMinutes < shortest) {
+			shortest = setting.AfterMinutes
+		}
+	}
+	if shortest == 0 {
+		return nil
+	}
+	return []PrefSuggestion{
+		{Name: zenTabUnloaderEnabledPref, Value: true, Reason: "Zen unloads idle tabs instead of archiving them"},
+		{Name: zenTabUnloaderTimeoutPref, Value: shortest, Reason: "shortest Arc archive interval (" + formatMinutes(shortest) + ")"},
+	}
+}
+
+// logArchiveReport prints the Arc auto-archive settings and the Zen prefs
+// suggested for them
+func (imp *Importer) logArchiveReport(plan *Plan) {
+	if len(plan.Archive) == 0 {
+		return
+	}
+	imp.logger.Info("Arc auto-archive (Zen keeps tabs open until you close them):")
+	for _, setting := range plan.Archive {
+		imp.logger.Info("  • %q %s", setting.Space, setting)
+	}
+	if prefs := plan.SuggestedPrefs(); len(prefs) > 0 {
+		imp.logger.Info("Suggested Zen prefs (about:config):")
+		for _, pref := range prefs {
+			imp.logger.Info("  %s = %v  (%s)", pref.Name, pref.Value, pref.Reason)
+		}
+	}
+	imp.logger.Info("")
+}
+
+// formatMinutes writes minutes as "12h", "7d" or "90m"
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes >= 24*60 && minutes%(24*60) == 0:
+		return fmt.Sprintf("%dd", minutes/(24*60))
+	case minutes >= 60 && minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
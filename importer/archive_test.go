@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func TestDoImport_ArchiveReport(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned"], "autoArchiveThreshold": 43200},
+				{"id": "s2", "title": "Research", "containerIDs": ["pinned"], "customInfo": {"archiveInterval": {"sevenDays": {}}}},
+				{"id": "s3", "title": "Keep", "containerIDs": ["pinned"], "isAutoArchiveEnabled": false},
+				{"id": "s4", "title": "Plain", "containerIDs": ["pinned"]}
+			],
+			"items": []
+		}]}
+	}`
+
+	imp := newTestImporter(t, ImportOptions{})
+	result, err := imp.doImport(context.Background(), parseTestArcData(t, raw), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	archive := result.Plan.Archive
+	if len(archive) != 3 {
+		t.Fatalf("expected 3 archive settings, got %+v", archive)
+	}
+	if archive[0].Space != "Work" || archive[0].AfterMinutes != 720 || archive[2].Space != "Keep" || !archive[2].Never {
+		t.Errorf("unexpected archive settings %+v", archive)
+	}
+	if got := archive[1].String(); got != "archives tabs idle for 7d" {
+		t.Errorf("unexpected description %q", got)
+	}
+
+	prefs := result.Plan.SuggestedPrefs()
+	if len(prefs) != 2 || prefs[1].Name != zenTabUnloaderTimeoutPref || prefs[1].Value != 720 {
+		t.Errorf("expected the unloader timeout from the shortest interval, got %+v", prefs)
+	}
+}
+
+func TestSuggestedPrefs_NeverArchives(t *testing.T) {
+	plan := &Plan{Archive: []ArchiveSetting{{Space: "Keep", Never: true}}}
+	if prefs := plan.SuggestedPrefs(); prefs != nil {
+		t.Errorf("expected no suggestions when no space archives, got %+v", prefs)
+	}
+}
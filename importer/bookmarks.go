@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"arc-to-zen/bookmarks"
+	"arc-to-zen/places"
+	"arc-to-zen/types"
+)
+
+// placesFolderTitle is the Other Bookmarks folder ImportOptions.AlsoBookmarks
+// writes into
+const placesFolderTitle = "Arc"
+
+// arcEpoch is where Arc time (seconds since 2001, as in CreatedAt) starts
+var arcEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// BookmarkExport is Arc data as bookmarks: a folder per space (and per
+// profile's favorites) with its folders and tabs, for browsers other than
+// Zen or when writing the session fails
+type BookmarkExport struct {
+	Bookmarks []bookmarks.Bookmark `json:"-"`
+	Folders   int                  `json:"folders"` // Including a folder per space and favorites bar
+	Tabs      int                  `json:"tabs"`
+	NoURL     int                  `json:"noUrl"` // Tabs left out, as a bookmark needs a URL
+}
+
+// Bookmarks reads the Arc data at arcDataPath and converts it to bookmarks,
+// keeping the folder hierarchy. ImportOptions.ArcProfile limits it to one Arc
+// profile, as it does the import.
+func (imp *Importer) Bookmarks(arcDataPath string) (*BookmarkExport, error) {
+	defer imp.begin()()
+	arcData, err := imp.readArcData(arcDataPath)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := imp.selectArcSpaces(arcData)
+	if err != nil {
+		return nil, err
+	}
+	return arcBookmarks(selection), nil
+}
+
+func arcBookmarks(selection *arcSelection) *BookmarkExport {
+	export := &BookmarkExport{}
+	seen := make(map[string]bool)
+	// Walked like insertItemWithChildren: Arc containers are transparent
+	var walk func(item *types.ArcItem) []bookmarks.Bookmark
+	walk = func(item *types.ArcItem) []bookmarks.Bookmark {
+		if seen[item.ID] {
+			return nil
+		}
+		seen[item.ID] = true
+		var children []bookmarks.Bookmark
+		for _, childID := range item.ChildrenIds {
+			if child := selection.itemsMap[childID]; child != nil {
+				children = append(children, walk(child)...)
+			}
+		}
+		isArcContainer := item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil
+		switch {
+		case isArcContainer:
+			return children
+		case isArcFolder(item):
+			export.Folders++
+			return []bookmarks.Bookmark{{Title: arcItemTitle(item), Added: arcTime(item.CreatedAt), Folder: true, Children: children}}
+		}
+		pageURL := ""
+		if item.Data != nil && item.Data.Tab != nil {
+			pageURL = strings.TrimSpace(item.Data.Tab.SavedURL)
+		}
+		if pageURL == "" {
+			export.NoURL++
+			return nil
+		}
+		export.Tabs++
+		return []bookmarks.Bookmark{{Title: arcItemTitle(item), URL: pageURL, Added: arcTime(item.CreatedAt)}}
+	}
+
+	for _, space := range selection.spaces {
+		folder := bookmarks.Bookmark{Title: selection.names[space.ID], Folder: true}
+		for _, root := range getRootItemsForSpace(space, selection.itemsMap) {
+			folder.Children = append(folder.Children, walk(root)...)
+		}
+		export.Folders++
+		export.Bookmarks = append(export.Bookmarks, folder)
+	}
+	for _, favorite := range selection.favorites {
+		profile := selection.profiles[favorite.profile]
+		if profile == nil {
+			continue // None of its spaces are exported
+		}
+		title := "Favorites"
+		if len(selection.profiles) > 1 {
+			title += " (" + profile.DisplayName + ")"
+		}
+		folder := bookmarks.Bookmark{Title: title, Folder: true, Children: walk(favorite.container)}
+		if len(folder.Children) > 0 {
+			export.Folders++
+			export.Bookmarks = append(export.Bookmarks, folder)
+		}
+	}
+	return export
+}
+
+// arcTime converts Arc time to a time, zero if unknown
+func arcTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return arcEpoch.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+// planBookmarks nests what plan imports as bookmarks: a folder per workspace
+// with its folders and tabs
+func planBookmarks(plan *Plan) []bookmarks.Bookmark {
+	var convert func(nodes []*TreeNode) []bookmarks.Bookmark
+	convert = func(nodes []*TreeNode) []bookmarks.Bookmark {
+		var converted []bookmarks.Bookmark
+		for _, node := range nodes {
+			if node.Type == NodeTab {
+				converted = append(converted, bookmarks.Bookmark{Title: node.Name, URL: node.URL})
+				continue
+			}
+			converted = append(converted, bookmarks.Bookmark{Title: node.Name, Folder: true, Children: convert(node.Children)})
+		}
+		return converted
+	}
+	return convert(plan.Tree())
+}
+
+// writePlacesBookmarks adds what plan imported to the profile's bookmarks
+// (ImportOptions.AlsoBookmarks). The session is written by then, so failing
+// is a warning; places.sqlite is left as it was. The import time limit is for
+// favicon requests, so it doesn't cut this short.
+func (imp *Importer) writePlacesBookmarks(ctx context.Context, plan *Plan) *places.Result {
+	path := places.Path(imp.zenProfilePath)
+	result, err := places.Write(context.WithoutCancel(ctx), path, placesFolderTitle, planBookmarks(plan))
+	if err != nil {
+		imp.warnings.Add(WarningWrite, path, "bookmarks not written: %v", err)
+		return nil
+	}
+	imp.logger.Info("✓ Added %s and %s to Other Bookmarks → %s", plural(result.Bookmarks, "bookmark"), plural(result.Folders, "folder"), placesFolderTitle)
+	return result
+}
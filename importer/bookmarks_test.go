@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"arc-to-zen/bookmarks"
+	"arc-to-zen/places"
+	"arc-to-zen/types"
+)
+
+// placesSchema is the part of Firefox's places.sqlite places.Write touches
+const placesSchema = `
+CREATE TABLE moz_origins (id INTEGER PRIMARY KEY, prefix TEXT NOT NULL, host TEXT NOT NULL, frecency INTEGER NOT NULL, UNIQUE (prefix, host));
+CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0,
+	hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER,
+	guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL, origin_id INTEGER REFERENCES moz_origins(id),
+	recalc_frecency INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR,
+	keyword_id INTEGER, folder_type TEXT, dateAdded INTEGER, lastModified INTEGER, guid TEXT, syncStatus INTEGER NOT NULL DEFAULT 0,
+	syncChangeCounter INTEGER NOT NULL DEFAULT 1);
+CREATE TABLE moz_bookmarks_deleted (guid TEXT PRIMARY KEY, dateRemoved INTEGER NOT NULL DEFAULT 0);
+INSERT INTO moz_bookmarks (id, type, parent, position, title, guid) VALUES
+	(1, 2, 0, 0, '', 'root________'), (2, 2, 1, 0, 'menu', 'menu________'), (3, 2, 1, 1, 'toolbar', 'toolbar_____'),
+	(4, 2, 1, 2, 'tags', 'tags________'), (5, 2, 1, 3, 'unfiled', 'unfiled_____');
+`
+
+func TestArcBookmarks(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Home", "containerIDs": ["pinned", "f1", "t3"]}],
+			"items": [
+				{"id": "f1", "title": "Reading", "childrenIds": ["t1", "t2"], "data": {}, "createdAt": 700000000},
+				{"id": "t1", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://example.test/one"}}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "No URL"}}},
+				{"id": "t3", "title": "Renamed", "childrenIds": [], "data": {"tab": {"savedTitle": "Three", "savedURL": "https://example.test/three"}}},
+				{"id": "top", "childrenIds": ["e1"], "data": {"itemContainer": {"containerType": {"topApps": {"_0": {"default": {}}}}}}},
+				{"id": "e1", "parentID": "top", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "https://example.test/mail"}}}
+			]
+		}]}
+	}`)
+	selection, err := imp.selectArcSpaces(arcData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	export := arcBookmarks(selection)
+	if export.Folders != 3 || export.Tabs != 3 || export.NoURL != 1 {
+		t.Errorf("expected 3 folders, 3 tabs and 1 without a URL, got %+v", export)
+	}
+	if len(export.Bookmarks) != 2 || export.Bookmarks[0].Title != "Home" || export.Bookmarks[1].Title != "Favorites" {
+		t.Fatalf("expected the Home space and the favorites, got %+v", export.Bookmarks)
+	}
+	home := export.Bookmarks[0].Children
+	if len(home) != 2 || !home[0].Folder || home[0].Title != "Reading" || home[1].Title != "Renamed" {
+		t.Fatalf("expected the Reading folder and the renamed tab, got %+v", home)
+	}
+	if want := (bookmarks.Bookmark{Title: "One", URL: "https://example.test/one"}); len(home[0].Children) != 1 || home[0].Children[0].Title != want.Title || home[0].Children[0].URL != want.URL {
+		t.Errorf("expected only %+v in the folder, got %+v", want, home[0].Children)
+	}
+	if added := home[0].Added.UTC(); added.Year() != 2023 {
+		t.Errorf("expected the folder added in 2023 (Arc time 700000000), got %s", added)
+	}
+	if favorites := export.Bookmarks[1].Children; len(favorites) != 1 || favorites[0].URL != "https://example.test/mail" {
+		t.Errorf("expected the Mail favorite, got %+v", favorites)
+	}
+}
+
+func TestPlanBookmarks(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{DryRun: true})
+	result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := planBookmarks(result.Plan)
+	if len(got) != 3 || !got[0].Folder || got[0].Title != "Personal" || got[2].Title != "Samsung" {
+		t.Fatalf("expected a folder per workspace, got %+v", got)
+	}
+	reading := got[0].Children
+	if len(reading) != 1 || !reading[0].Folder || reading[0].Title != "Reading" || len(reading[0].Children) != 2 ||
+		reading[0].Children[1].Title != "Two" || reading[0].Children[1].URL != testSite+"/two" {
+		t.Errorf("expected the Reading folder with its two tabs, got %+v", reading)
+	}
+
+	// Without a places.sqlite the session stays written and it's a warning
+	imp.zenProfilePath = t.TempDir()
+	if written := imp.writePlacesBookmarks(context.Background(), result.Plan); written != nil || imp.warnings.Len() != 1 {
+		t.Errorf("expected a warning and nothing written, got %+v, %v", written, imp.warnings.List())
+	}
+}
+
+func TestImportContext_AlsoBookmarksAfterTimeout(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{AlsoBookmarks: true, ZenVersion: "1.14.5b", FaviconFetcher: iconFetcher{}})
+	db, err := sql.Open("sqlite", places.Path(imp.zenProfilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(placesSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	// -timeout ran out while fetching favicons; the bookmarks are still written
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	result, err := imp.ImportContext(ctx, arcDataPath)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Bookmarks == nil || result.Bookmarks.Bookmarks == 0 {
+		t.Fatalf("expected bookmarks written, got %+v (warnings %v)", result.Bookmarks, result.Warnings)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM moz_bookmarks WHERE type = 1").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != result.Bookmarks.Bookmarks {
+		t.Errorf("expected %d bookmarks in %s, got %d", result.Bookmarks.Bookmarks, places.FileName, count)
+	}
+}
@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+)
+
+const (
+	// OverBudgetWarn writes the session anyway and warns
+	OverBudgetWarn = "warn"
+	// OverBudgetDownscale shrinks the imported favicons, then warns if still over
+	OverBudgetDownscale = "downscale"
+	// OverBudgetSkipFavicons imports the tabs without favicons, then warns if still over
+	OverBudgetSkipFavicons = "skip-favicons"
+	// OverBudgetFail refuses to write the session
+	OverBudgetFail = "fail"
+)
+
+// DefaultSessionBudget is the compressed session size the CLI warns above
+const DefaultSessionBudget = 20 << 20
+
+// budgetIconSize is what OverBudgetDownscale scales favicons to: Zen draws
+// them at 16px, 32px on HiDPI screens
+const budgetIconSize = 32
+
+// checkSessionBudget measures the compressed session about to be written and,
+// above SessionBudget, applies the OverBudget mitigation to the imported tabs.
+// Only OverBudgetFail returns an error (a warning in dry-run).
+func (imp *Importer) checkSessionBudget(session *types.ZenSession, plan *Plan) error {
+	budget := imp.options.SessionBudget
+	if budget <= 0 {
+		return nil
+	}
+	size, err := compressedSessionSize(session)
+	if err != nil {
+		return err
+	}
+	if size <= budget {
+		imp.logger.Info("✓ Projected session size: %s (budget %s)", formatBytes(uint64(size)), formatBytes(uint64(budget)))
+		return nil
+	}
+	imp.logger.Info("⚠ Projected session size %s is over the %s budget", formatBytes(uint64(size)), formatBytes(uint64(budget)))
+
+	var mitigate func(icon string) string
+	switch imp.options.OverBudget {
+	case OverBudgetDownscale:
+		mitigate = func(icon string) string { return favicon.Downscale(icon, budgetIconSize) }
+	case OverBudgetSkipFavicons:
+		mitigate = func(string) string { return "" }
+	}
+	if mitigate != nil {
+		changed := mapImportedIcons(session, plan, mitigate)
+		if size, err = compressedSessionSize(session); err != nil {
+			return err
+		}
+		imp.logger.Info("  -over-budget %s changed the favicons of %d tabs: session now %s",
+			imp.options.OverBudget, changed, formatBytes(uint64(size)))
+		if size <= budget {
+			return nil
+		}
+	}
+
+	message := fmt.Sprintf("session would be %s, over the %s budget; %s",
+		formatBytes(uint64(size)), formatBytes(uint64(budget)), budgetHints(session, plan, imp.options.OverBudget))
+	if imp.options.OverBudget == OverBudgetFail {
+		if !imp.options.DryRun {
+			return fmt.Errorf("%s (nothing was written)", message)
+		}
+		message = "import would fail: " + message
+	}
+	imp.warnings.Add(WarningWrite, "", "%s", message)
+	return nil
+}
+
+// compressedSessionSize returns the size of the session file session encodes to
+func compressedSessionSize(session *types.ZenSession) (int64, error) {
+	data, err := sessionfile.Encode(session)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// mapImportedIcons replaces the favicon of every tab in plan with mitigate's
+// result (none if it returns "") and returns how many tabs changed
+func mapImportedIcons(session *types.ZenSession, plan *Plan, mitigate func(icon string) string) int {
+	imported := make(map[string]*PlannedTab, len(plan.Tabs))
+	for i := range plan.Tabs {
+		imported[plan.Tabs[i].ID] = &plan.Tabs[i]
+	}
+	results := make(map[string]interface{}) // Tabs of one host share an icon
+	apply := func(v interface{}) interface{} {
+		icon, ok := v.(string)
+		if !ok || icon == "" {
+			return v
+		}
+		if result, ok := results[icon]; ok {
+			return result
+		}
+		var result interface{}
+		if mitigated := mitigate(icon); mitigated != "" {
+			result = mitigated
+		}
+		results[icon] = result
+		return result
+	}
+
+	changed := 0
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		planned := imported[tab.ZenSyncID]
+		if planned == nil || planned.Icon == "" {
+			continue
+		}
+		image := apply(tab.Image)
+		if image == tab.Image {
+			continue
+		}
+		tab.Image = image
+		if tab.ZenPinnedIcon != nil {
+			tab.ZenPinnedIcon = apply(tab.ZenPinnedIcon)
+			tab.ZenHasStaticIcon = tab.ZenPinnedIcon != nil
+		}
+		if state, ok := tab.ZenPinnedInitialState.(map[string]interface{}); ok {
+			state["image"] = apply(state["image"])
+		}
+		planned.Icon, _ = image.(string)
+		changed++
+	}
+	return changed
+}
+
+// budgetHints suggests ways to get under the budget: the mitigations not yet
+// tried, compacting embedded favicons of existing tabs, and the imported
+// workspaces taking the most room (candidates for importing separately)
+func budgetHints(session *types.ZenSession, plan *Plan, tried string) string {
+	var hints []string
+	switch tried {
+	case OverBudgetSkipFavicons:
+	case OverBudgetDownscale:
+		hints = append(hints, "use -over-budget skip-favicons")
+	default:
+		hints = append(hints, "use -over-budget downscale or skip-favicons")
+	}
+
+	imported := make(map[string]bool, len(plan.Tabs))
+	for _, tab := range plan.Tabs {
+		imported[tab.ID] = true
+	}
+	existingIcons := 0
+	spaceBytes := make(map[string]int)
+	total := 0
+	for _, tab := range session.Tabs {
+		data, _ := json.Marshal(tab)
+		total += len(data)
+		if imported[tab.ZenSyncID] {
+			spaceBytes[tab.ZenWorkspace] += len(data)
+		} else if icon, ok := tab.Image.(string); ok && strings.HasPrefix(icon, "data:") {
+			existingIcons++
+		}
+	}
+	if existingIcons > 0 {
+		hints = append(hints, fmt.Sprintf("run `arc-to-zen compact` to drop the favicons embedded in %d existing tabs", existingIcons))
+	}
+
+	type spaceShare struct {
+		name  string
+		bytes int
+	}
+	var shares []spaceShare
+	for _, space := range plan.Spaces {
+		if spaceBytes[space.ID] > 0 {
+			shares = append(shares, spaceShare{space.Name, spaceBytes[space.ID]})
+		}
+	}
+	sort.SliceStable(shares, func(i, j int) bool { return shares[i].bytes > shares[j].bytes })
+	if len(shares) > 1 && total > 0 {
+		var largest []string
+		for _, share := range shares[:min(3, len(shares))] {
+			largest = append(largest, fmt.Sprintf("%s %d%%", share.name, share.bytes*100/total))
+		}
+		hints = append(hints, "or import fewer workspaces (largest: "+strings.Join(largest, ", ")+")")
+	}
+	if len(hints) == 0 {
+		return "import fewer workspaces"
+	}
+	return strings.Join(hints, ", ")
+}
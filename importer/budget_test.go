@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// noisyIcon returns a PNG data URL of random-looking pixels, which barely compresses
+func noisyIcon(t *testing.T, size int) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	seed := uint32(1)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			seed = seed*1664525 + 1013904223
+			img.Set(x, y, color.NRGBA{uint8(seed >> 24), uint8(seed >> 16), uint8(seed >> 8), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// budgetImport imports the multi-profile test data with a large favicon on every tab
+func budgetImport(t *testing.T, opts ImportOptions, icon string) (*Importer, *types.ZenSession, *ImportResult) {
+	t.Helper()
+	opts.ZenVersion = "1.14.5b" // Writes zenPinnedIcon too
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, opts)
+	imp.faviconFetcher = iconFetcher{icon}
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	session := emptySession()
+	result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	return imp, session, result
+}
+
+func TestCheckSessionBudget_UnderBudget(t *testing.T) {
+	imp, session, result := budgetImport(t, ImportOptions{SessionBudget: 1 << 20}, noisyIcon(t, 64))
+	if err := imp.checkSessionBudget(session, result.Plan); err != nil {
+		t.Fatal(err)
+	}
+	if imp.warnings.Len() != 0 {
+		t.Errorf("expected no warnings under budget, got %v", imp.warnings.List())
+	}
+}
+
+func TestCheckSessionBudget_Policies(t *testing.T) {
+	icon := noisyIcon(t, 64)
+	for _, policy := range []string{OverBudgetWarn, OverBudgetDownscale, OverBudgetSkipFavicons} {
+		imp, session, result := budgetImport(t, ImportOptions{SessionBudget: 1, OverBudget: policy}, icon)
+		if err := imp.checkSessionBudget(session, result.Plan); err != nil {
+			t.Fatalf("%s: %v", policy, err)
+		}
+		warnings := imp.warnings.ByCategory()[WarningWrite]
+		if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "over the") {
+			t.Errorf("%s: expected an over-budget warning, got %v", policy, warnings)
+		}
+		for _, tab := range session.Tabs {
+			if tab.ZenIsEmpty {
+				continue
+			}
+			image, _ := tab.Image.(string)
+			switch policy {
+			case OverBudgetWarn:
+				if image != icon {
+					t.Errorf("warn: expected the icon unchanged")
+				}
+			case OverBudgetDownscale:
+				if image == "" || len(image) >= len(icon) || tab.ZenPinnedIcon != image {
+					t.Errorf("downscale: expected a smaller icon in both fields, got %d bytes", len(image))
+				}
+			case OverBudgetSkipFavicons:
+				if tab.Image != nil || tab.ZenPinnedIcon != nil || tab.ZenHasStaticIcon {
+					t.Errorf("skip-favicons: expected no icon, got %v", tab.Image)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckSessionBudget_Fail(t *testing.T) {
+	imp, session, result := budgetImport(t, ImportOptions{SessionBudget: 1, OverBudget: OverBudgetFail}, noisyIcon(t, 64))
+	if err := imp.checkSessionBudget(session, result.Plan); err == nil || !strings.Contains(err.Error(), "nothing was written") {
+		t.Errorf("expected the import to fail, got %v", err)
+	}
+
+	imp, session, result = budgetImport(t, ImportOptions{SessionBudget: 1, OverBudget: OverBudgetFail, DryRun: true}, noisyIcon(t, 64))
+	if err := imp.checkSessionBudget(session, result.Plan); err != nil {
+		t.Errorf("expected only a warning in dry-run, got %v", err)
+	}
+	if warnings := imp.warnings.ByCategory()[WarningWrite]; len(warnings) != 1 || !strings.Contains(warnings[0].Message, "import would fail") {
+		t.Errorf("expected a would-fail warning, got %v", warnings)
+	}
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"arc-to-zen/paths"
+	"github.com/google/uuid"
+)
+
+const checkpointDirName = "checkpoints"
+
+// Import phases recorded in a checkpoint
+const (
+	phasePlanned  = "planned"  // Workspace and item IDs chosen
+	phaseFavicons = "favicons" // Favicon pre-cache finished
+)
+
+// phaseOrder ranks phases so later ones imply earlier ones
+var phaseOrder = map[string]int{phasePlanned: 1, phaseFavicons: 2}
+
+// checkpoint records an import in progress so a rerun after a crash or Ctrl-C
+// resumes with the same workspace, item and folder IDs instead of starting over.
+// It is removed once the profile has been written. A nil checkpoint (dry-run,
+// tests) hands out fresh IDs and saves nothing.
+type checkpoint struct {
+	Profile     string            `json:"profile"`
+	ArcDataHash string            `json:"arcDataHash"` // StorableSidebar.json the IDs were planned for
+	Started     time.Time         `json:"started"`
+	Phase       string            `json:"phase"`
+	Now         int64             `json:"now"`        // Timestamp used in folder IDs
+	SpaceUUIDs  map[string]string `json:"spaceUuids"` // Arc space ID → new Zen workspace UUID
+	ItemUUIDs   map[string]string `json:"itemUuids"`  // Arc item ID → Zen sync ID
+
+	path    string
+	resumed bool
+}
+
+// openCheckpoint returns the checkpoint left by an interrupted import of the
+// same Arc data into profilePath, or a new one. hash is the SHA-256 of the
+// Arc data snapshot being imported (arcdata.Snapshot.Hash).
+func openCheckpoint(profilePath, hash string) (*checkpoint, error) {
+	path, err := checkpointPath(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing checkpoint
+	if raw, err := os.ReadFile(path); err == nil && json.Unmarshal(raw, &existing) == nil && existing.ArcDataHash == hash {
+		existing.path = path
+		existing.resumed = true
+		if existing.SpaceUUIDs == nil {
+			existing.SpaceUUIDs = make(map[string]string)
+		}
+		if existing.ItemUUIDs == nil {
+			existing.ItemUUIDs = make(map[string]string)
+		}
+		return &existing, nil
+	}
+
+	now := time.Now()
+	return &checkpoint{
+		Profile:     profilePath,
+		ArcDataHash: hash,
+		Started:     now,
+		Now:         now.UnixMilli(),
+		SpaceUUIDs:  make(map[string]string),
+		ItemUUIDs:   make(map[string]string),
+		path:        path,
+	}, nil
+}
+
+// checkpointPath returns where the checkpoint for profilePath is kept
+func checkpointPath(profilePath string) (string, error) {
+	dataDir, err := paths.DataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get data directory: %w", err)
+	}
+	abs, err := filepath.Abs(profilePath)
+	if err != nil {
+		abs = profilePath
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(abs)))
+	name := fmt.Sprintf("%s-%s.json", filepath.Base(abs), hex.EncodeToString(sum[:8]))
+	return filepath.Join(dataDir, checkpointDirName, name), nil
+}
+
+// spaceUUID returns the workspace UUID planned for an Arc space
+func (c *checkpoint) spaceUUID(arcID string) string {
+	if c == nil {
+		return newZenUUID()
+	}
+	if id, ok := c.SpaceUUIDs[arcID]; ok {
+		return id
+	}
+	id := newZenUUID()
+	c.SpaceUUIDs[arcID] = id
+	return id
+}
+
+// itemUUID returns the sync ID planned for an Arc item
+func (c *checkpoint) itemUUID(arcID string) string {
+	if c == nil {
+		return newZenUUID()
+	}
+	if id, ok := c.ItemUUIDs[arcID]; ok {
+		return id
+	}
+	id := newZenUUID()
+	c.ItemUUIDs[arcID] = id
+	return id
+}
+
+// timestamp returns the time used for folder IDs and tab access times
+func (c *checkpoint) timestamp() int64 {
+	if c == nil {
+		return time.Now().UnixMilli()
+	}
+	return c.Now
+}
+
+// reached reports whether a resumed import already finished phase
+func (c *checkpoint) reached(phase string) bool {
+	if c == nil || !c.resumed {
+		return false
+	}
+	return phaseOrder[c.Phase] >= phaseOrder[phase]
+}
+
+// advance records that phase has finished, along with the IDs planned so far
+func (c *checkpoint) advance(phase string) error {
+	if c == nil {
+		return nil
+	}
+	if phaseOrder[phase] > phaseOrder[c.Phase] {
+		c.Phase = phase
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode import checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write import checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write import checkpoint: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the checkpoint once the import has been written
+func (c *checkpoint) remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove import checkpoint: %w", err)
+	}
+	return nil
+}
+
+func newZenUUID() string {
+	return fmt.Sprintf("{%s}", uuid.New().String())
+}
@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arc-to-zen/arcdata"
+	"arc-to-zen/types"
+)
+
+// snapshotHash is the hash readArcData passes to openCheckpoint
+func snapshotHash(t *testing.T, path string) string {
+	t.Helper()
+	snapshot, err := arcdata.TakeSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	return snapshot.Hash
+}
+
+func TestCheckpoint_ResumesSameArcData(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	profile := t.TempDir()
+	arcPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcPath, []byte(`{"v": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := openCheckpoint(profile, snapshotHash(t, arcPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.resumed || cp.reached(phasePlanned) {
+		t.Fatal("fresh checkpoint should not be resumed")
+	}
+	space, item := cp.spaceUUID("s1"), cp.itemUUID("t1")
+	if err := cp.advance(phasePlanned); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := openCheckpoint(profile, snapshotHash(t, arcPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.reached(phasePlanned) || resumed.reached(phaseFavicons) {
+		t.Errorf("expected resume after planning, got phase %q", resumed.Phase)
+	}
+	if resumed.spaceUUID("s1") != space || resumed.itemUUID("t1") != item || resumed.Now != cp.Now {
+		t.Error("resumed checkpoint should reuse planned IDs")
+	}
+
+	// Changed Arc data starts over
+	if err := os.WriteFile(arcPath, []byte(`{"v": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := openCheckpoint(profile, snapshotHash(t, arcPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.resumed || fresh.itemUUID("t1") == item {
+		t.Error("checkpoint for other Arc data should not be resumed")
+	}
+
+	if err := resumed.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(resumed.path); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint to be removed, got %v", err)
+	}
+}
+
+func TestDoImport_UsesCheckpointIDs(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	imp.checkpoint = &checkpoint{
+		Phase:      phaseFavicons,
+		Now:        1700000000000,
+		SpaceUUIDs: map[string]string{"s3": "{space-3}"},
+		ItemUUIDs:  map[string]string{"t4": "{tab-4}"},
+		path:       filepath.Join(t.TempDir(), "checkpoint.json"),
+		resumed:    true,
+	}
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	found := false
+	for _, tab := range session.Tabs {
+		if tab.ZenSyncID == "{tab-4}" {
+			found = tab.ZenWorkspace == "{space-3}" && tab.LastAccessed == 1700000000000
+		}
+	}
+	if !found {
+		t.Error("expected the checkpoint's IDs to be reused")
+	}
+	if len(imp.checkpoint.ItemUUIDs) != 5 {
+		t.Errorf("expected newly planned IDs to be recorded, got %v", imp.checkpoint.ItemUUIDs)
+	}
+}
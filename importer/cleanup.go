@@ -0,0 +1,253 @@
+package importer
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// Cleanup categories: Arc data that is likely junk, reported by Cleanup and
+// left out of an import by ImportOptions.Exclude
+const (
+	// CleanupDuplicates is a tab whose URL an earlier tab already has
+	CleanupDuplicates = "duplicates"
+	// CleanupLocalhost is a tab on localhost or a loopback address, usually a
+	// dev server that is long gone
+	CleanupLocalhost = "localhost"
+	// CleanupEmptyFolders is a folder with no tab in it, at any depth
+	CleanupEmptyFolders = "empty-folders"
+	// CleanupLargeSpaces is a space with LargeSpaceItems or more folders and
+	// tabs, often years of pins nobody prunes
+	CleanupLargeSpaces = "large-spaces"
+)
+
+// CleanupCategories lists the cleanup categories in report order
+var CleanupCategories = []string{CleanupDuplicates, CleanupLocalhost, CleanupEmptyFolders, CleanupLargeSpaces}
+
+// LargeSpaceItems is where a space counts as CleanupLargeSpaces
+const LargeSpaceItems = 500
+
+// CleanupFinding is one likely junk item or space
+type CleanupFinding struct {
+	Category string `json:"category"`
+	ArcID    string `json:"arcId"` // Arc item, or space for CleanupLargeSpaces
+	Title    string `json:"title"`
+	Space    string `json:"space"`  // Workspace name the import would use
+	Detail   string `json:"detail"` // URL, or what makes it junk
+}
+
+// CleanupReport lists the likely junk in Arc data, in sidebar order
+type CleanupReport struct {
+	Findings []CleanupFinding `json:"findings"`
+}
+
+// Counts returns the number of findings per category
+func (r *CleanupReport) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, finding := range r.Findings {
+		counts[finding.Category]++
+	}
+	return counts
+}
+
+// Category returns the findings of one category
+func (r *CleanupReport) Category(category string) []CleanupFinding {
+	var findings []CleanupFinding
+	for _, finding := range r.Findings {
+		if finding.Category == category {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// Cleanup reads the Arc data at arcDataPath and reports what is likely junk.
+// ImportOptions.ArcProfile limits it to one Arc profile, as it does the import.
+func (imp *Importer) Cleanup(arcDataPath string) (*CleanupReport, error) {
+	defer imp.begin()()
+	arcData, err := imp.readArcData(arcDataPath)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := imp.selectArcSpaces(arcData)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeCleanup(selection.spaces, selection.names, selection.itemsMap), nil
+}
+
+// validateExclude checks ImportOptions.Exclude against the cleanup categories
+func validateExclude(exclude []string) error {
+	for _, category := range exclude {
+		known := false
+		for _, c := range CleanupCategories {
+			known = known || c == category
+		}
+		if !known {
+			return fmt.Errorf("invalid -exclude category %q (use %s)", category, strings.Join(CleanupCategories, ", "))
+		}
+	}
+	return nil
+}
+
+// excludes reports whether ImportOptions.Exclude has category
+func (imp *Importer) excludes(category string) bool {
+	for _, c := range imp.options.Exclude {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// applyExclude drops the spaces and marks the items of the excluded cleanup
+// categories. Large spaces go first, so duplicates are judged among the
+// spaces still imported. The marked items are skipped by insertItemWithChildren.
+func (imp *Importer) applyExclude(spaces []*types.ArcSpace, names map[string]string, itemsMap map[string]*types.ArcItem) ([]*types.ArcSpace, error) {
+	imp.excluded = nil
+	if len(imp.options.Exclude) == 0 {
+		return spaces, nil
+	}
+	if imp.excludes(CleanupLargeSpaces) {
+		large := make(map[string]bool)
+		for _, finding := range analyzeCleanup(spaces, names, itemsMap).Category(CleanupLargeSpaces) {
+			imp.logger.Info("Excluding space \"%s\" (%s, -exclude %s)", finding.Title, finding.Detail, CleanupLargeSpaces)
+			large[finding.ArcID] = true
+		}
+		kept := spaces[:0:0]
+		for _, space := range spaces {
+			if !large[space.ID] {
+				kept = append(kept, space)
+			}
+		}
+		if len(kept) == 0 {
+			return nil, fmt.Errorf("every Arc space has %d or more items; nothing left to import with -exclude %s", LargeSpaceItems, CleanupLargeSpaces)
+		}
+		spaces = kept
+	}
+
+	imp.excluded = make(map[string]string)
+	excluded := make(map[string]int)
+	for _, finding := range analyzeCleanup(spaces, names, itemsMap).Findings {
+		if finding.Category == CleanupLargeSpaces || !imp.excludes(finding.Category) {
+			continue
+		}
+		if _, marked := imp.excluded[finding.ArcID]; !marked {
+			imp.excluded[finding.ArcID] = "-exclude " + finding.Category
+			excluded[finding.Category]++
+		}
+	}
+	for _, category := range CleanupCategories {
+		if excluded[category] > 0 {
+			imp.logger.Info("Excluding %d items (-exclude %s)", excluded[category], category)
+		}
+	}
+	return spaces, nil
+}
+
+// analyzeCleanup walks the spaces' items in sidebar order, like
+// insertItemWithChildren: Arc containers are transparent and folders are
+// what isArcFolder says. Empty folders are those isEmptyArcFolder reports.
+func analyzeCleanup(spaces []*types.ArcSpace, names map[string]string, itemsMap map[string]*types.ArcItem) *CleanupReport {
+	report := &CleanupReport{}
+	firstTab := make(map[string]string) // URL → workspace of the first tab with it
+	for _, space := range spaces {
+		name := names[space.ID]
+		if name == "" {
+			name = getTitleOrDefault(space.Title, space.ID)
+		}
+		items := 0
+		seen := make(map[string]bool)
+
+		// walk returns the number of tabs (and other non-folder items) under
+		// item, itself included
+		var walk func(item *types.ArcItem) int
+		walk = func(item *types.ArcItem) int {
+			if seen[item.ID] {
+				return 0
+			}
+			seen[item.ID] = true
+			isArcContainer := item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil
+			if !isArcContainer {
+				items++
+			}
+			title := item.Title
+			if !isArcContainer && !isArcFolder(item) {
+				if item.Data == nil || item.Data.Tab == nil {
+					return 1
+				}
+				title = getTitleOrDefault(title, item.Data.Tab.SavedTitle)
+				pageURL := strings.TrimSpace(item.Data.Tab.SavedURL)
+				if pageURL != "" {
+					if first, ok := firstTab[pageURL]; ok {
+						report.Findings = append(report.Findings, CleanupFinding{Category: CleanupDuplicates, ArcID: item.ID,
+							Title: title, Space: name, Detail: fmt.Sprintf("%s (first in \"%s\")", pageURL, first)})
+					} else {
+						firstTab[pageURL] = name
+					}
+					if isLocalhostURL(pageURL) {
+						report.Findings = append(report.Findings, CleanupFinding{Category: CleanupLocalhost, ArcID: item.ID,
+							Title: title, Space: name, Detail: pageURL})
+					}
+				}
+				return 1
+			}
+
+			tabs := 0
+			if !isArcContainer && item.Data != nil && item.Data.Tab != nil {
+				tabs++ // A tab Arc gave children; imported as a folder, but not empty
+			}
+			at := len(report.Findings) // Empty subfolders are listed after their folder
+			for _, childID := range item.ChildrenIds {
+				if child := itemsMap[childID]; child != nil {
+					tabs += walk(child)
+				}
+			}
+			if !isArcContainer && tabs == 0 {
+				finding := CleanupFinding{Category: CleanupEmptyFolders, ArcID: item.ID,
+					Title: getTitleOrDefault(title, "Untitled"), Space: name, Detail: "no tabs"}
+				report.Findings = append(report.Findings[:at], append([]CleanupFinding{finding}, report.Findings[at:]...)...)
+			}
+			return tabs
+		}
+		for _, root := range getRootItemsForSpace(space, itemsMap) {
+			walk(root)
+		}
+		if items >= LargeSpaceItems {
+			report.Findings = append(report.Findings, CleanupFinding{Category: CleanupLargeSpaces, ArcID: space.ID,
+				Title: name, Space: name, Detail: fmt.Sprintf("%d items", items)})
+		}
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return categoryOrder(report.Findings[i].Category) < categoryOrder(report.Findings[j].Category)
+	})
+	return report
+}
+
+func categoryOrder(category string) int {
+	for i, c := range CleanupCategories {
+		if c == category {
+			return i
+		}
+	}
+	return len(CleanupCategories)
+}
+
+// isLocalhostURL reports whether pageURL points at this machine: localhost,
+// *.localhost, a loopback address or 0.0.0.0
+func isLocalhostURL(pageURL string) bool {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// cleanupArcData has one of each kind of junk in "Work" and a "Big" space of
+// LargeSpaceItems tabs, one a duplicate of a "Work" tab
+func cleanupArcData(t *testing.T) *types.ArcData {
+	t.Helper()
+	items := []string{
+		`{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Docs", "savedURL": "https://docs.test/"}}}`,
+		`{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Docs again", "savedURL": "https://docs.test/"}}}`,
+		`{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Dev server", "savedURL": "http://localhost:3000/"}}}`,
+		`{"id": "f1", "title": "Old", "childrenIds": ["f2"], "data": {}}`,
+		`{"id": "f2", "title": "Older", "parentID": "f1", "childrenIds": [], "data": {"list": {}}}`,
+		`{"id": "f3", "title": "Kept", "childrenIds": ["t4"], "data": {}}`,
+		`{"id": "t4", "parentID": "f3", "childrenIds": [], "data": {"tab": {"savedTitle": "Loopback", "savedURL": "http://127.0.0.1:8080/"}}}`,
+	}
+	big := []string{`"pinned"`}
+	for i := 0; i < LargeSpaceItems; i++ {
+		big = append(big, fmt.Sprintf(`"b%d"`, i))
+		url := fmt.Sprintf("https://big.test/%d", i)
+		if i == 0 {
+			url = "https://docs.test/"
+		}
+		items = append(items, fmt.Sprintf(`{"id": "b%d", "childrenIds": [], "data": {"tab": {"savedTitle": "B", "savedURL": "%s"}}}`, i, url))
+	}
+	return parseTestArcData(t, fmt.Sprintf(`{"sidebar": {"containers": [{"global": {}}, {
+		"spaces": [
+			{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2", "t3", "f1", "f3"]},
+			{"id": "s2", "title": "Big", "containerIDs": [%s]}
+		],
+		"items": [%s]}]}}`, strings.Join(big, ", "), strings.Join(items, ", ")))
+}
+
+func TestCleanup_Report(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	selection, err := imp.selectArcSpaces(cleanupArcData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := analyzeCleanup(selection.spaces, selection.names, selection.itemsMap)
+
+	want := map[string][]string{
+		CleanupDuplicates:   {"t2", "b0"},
+		CleanupLocalhost:    {"t3", "t4"},
+		CleanupEmptyFolders: {"f1", "f2"},
+		CleanupLargeSpaces:  {"s2"},
+	}
+	for _, category := range CleanupCategories {
+		var got []string
+		for _, finding := range report.Category(category) {
+			got = append(got, finding.ArcID)
+		}
+		if strings.Join(got, ",") != strings.Join(want[category], ",") {
+			t.Errorf("%s: got %v, want %v", category, got, want[category])
+		}
+	}
+	if got := report.Category(CleanupDuplicates)[1].Detail; got != `https://docs.test/ (first in "Work")` {
+		t.Errorf("expected the duplicate to name the first tab's space, got %q", got)
+	}
+	if counts := report.Counts(); counts[CleanupLargeSpaces] != 1 || len(report.Findings) != 7 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestCleanup_Exclude(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{Exclude: []string{CleanupDuplicates, CleanupEmptyFolders, CleanupLargeSpaces}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := imp.doImport(context.Background(), cleanupArcData(t), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The big space is gone, so its copy of the docs tab is no longer a duplicate
+	if len(result.Plan.Spaces) != 1 || result.Plan.Spaces[0].Name != "Work" {
+		t.Fatalf("expected only Work imported, got %+v", result.Plan.Spaces)
+	}
+	var tabs []string
+	for _, tab := range result.Plan.Tabs {
+		tabs = append(tabs, tab.Title)
+	}
+	if got := strings.Join(tabs, ","); got != "Docs,Dev server,Loopback" {
+		t.Errorf("expected the duplicate left out, got %s", got)
+	}
+	if len(result.Plan.Folders) != 1 || result.Plan.Folders[0].Name != "Kept" {
+		t.Errorf("expected the empty folder left out, got %+v", result.Plan.Folders)
+	}
+	reasons := make(map[string]string)
+	for _, skipped := range result.Plan.Skipped {
+		reasons[skipped.ID] = skipped.Reason
+	}
+	if reasons["t2"] != "-exclude duplicates" || reasons["f1"] != "-exclude empty-folders" || len(reasons) != 2 {
+		t.Errorf("unexpected skipped items %v", reasons)
+	}
+
+	imp = newTestImporter(t, ImportOptions{Exclude: []string{"junk"}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected an unknown category to be rejected")
+	}
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// validateCombineSpaces checks ImportOptions.CombineSpaces: every workspace
+// needs a name and its spaces, and a space can only go into one
+func validateCombineSpaces(combine map[string][]string) error {
+	into := make(map[string]string)
+	for target, sources := range combine {
+		if strings.TrimSpace(target) == "" || len(sources) == 0 {
+			return fmt.Errorf("invalid -combine %q (expected \"Space+Space=Workspace\")", strings.Join(sources, "+")+"="+target)
+		}
+		for _, source := range sources {
+			if other, ok := into[source]; ok && other != target {
+				return fmt.Errorf("invalid -combine: space %q can't go into both %q and %q", source, other, target)
+			}
+			into[source] = target
+		}
+	}
+	return nil
+}
+
+// combineTargets maps the titles of the spaces to combine to their
+// workspace: every space into one with -mode folders, else per
+// ImportOptions.CombineSpaces
+func (imp *Importer) combineTargets(spaces []*types.ArcSpace, zenSession *types.ZenSession) map[string]string {
+	into := make(map[string]string)
+	if imp.options.Mode == ModeFolders {
+		workspace := folderModeWorkspace(zenSession)
+		for _, space := range spaces {
+			into[space.Title] = workspace
+		}
+		imp.logger.Info("Importing %d Arc spaces as folders of workspace \"%s\" (-mode folders)", len(spaces), workspace)
+		return into
+	}
+	for target, sources := range imp.options.CombineSpaces {
+		for _, source := range sources {
+			into[source] = target
+		}
+	}
+	return into
+}
+
+// combineSpaces replaces the spaces into maps (by title, see combineTargets)
+// with one space per workspace, where each becomes a folder named after it.
+// The combined space takes the place, icon, color and profile of the first
+// of its spaces in sidebar order. It returns the spaces and the folder items
+// it made up, which are added to itemsMap and must be imported like Arc's.
+func (imp *Importer) combineSpaces(spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem, into map[string]string) ([]*types.ArcSpace, []*types.ArcItem) {
+	if len(into) == 0 {
+		return spaces, nil
+	}
+
+	combined := make(map[string]*types.ArcSpace) // Workspace → combined space
+	found := make(map[string]bool)
+	var result []*types.ArcSpace
+	var folders []*types.ArcItem
+	for _, space := range spaces {
+		target, ok := into[space.Title]
+		if !ok {
+			result = append(result, space)
+			continue
+		}
+		found[space.Title] = true
+
+		folder := &types.ArcItem{
+			ID:    "combine/" + space.ID,
+			Title: getTitleOrDefault(space.Title, space.ID),
+			Data:  &types.ArcItemData{List: json.RawMessage(`{}`)},
+		}
+		for _, item := range topLevelItems(space, itemsMap) {
+			folder.ChildrenIds = append(folder.ChildrenIds, item.ID)
+		}
+		itemsMap[folder.ID] = folder
+		folders = append(folders, folder)
+
+		whole := combined[target]
+		if whole == nil {
+			copied := *space
+			whole = &copied
+			whole.ID = "combine/" + target
+			whole.Title = target
+			whole.ContainerIDs = nil
+			combined[target] = whole
+			result = append(result, whole)
+		} else if getProfileName(space) != getProfileName(whole) {
+			imp.warnings.Add(WarningMapping, space.Title, "-combine: space is in Arc profile %q but workspace %q uses %q's container",
+				getProfileName(space), target, getProfileName(whole))
+		}
+		whole.ContainerIDs = append(whole.ContainerIDs, folder.ID)
+	}
+
+	targets := make([]string, 0, len(imp.options.CombineSpaces))
+	for target := range imp.options.CombineSpaces {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		var missing []string
+		for _, source := range imp.options.CombineSpaces[target] {
+			if !found[source] {
+				missing = append(missing, source)
+			}
+		}
+		if len(missing) > 0 {
+			imp.warnings.Add(WarningMapping, target, "-combine: no Arc space named %s", strings.Join(missing, ", "))
+		}
+		if whole := combined[target]; whole != nil {
+			imp.logger.Info("Combining %d Arc spaces into workspace \"%s\" (-combine)", len(whole.ContainerIDs), target)
+		}
+	}
+	return result, folders
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_CombineSpaces(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Home", "containerIDs": ["t1"]},
+				{"id": "s2", "title": "Work", "containerIDs": ["pinned", "c1"]},
+				{"id": "s3", "title": "Clients", "containerIDs": ["t4"]}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}},
+				{"id": "c1", "childrenIds": ["t2", "f1"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s2"}}}}},
+				{"id": "t2", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f1", "parentID": "c1", "title": "Docs", "childrenIds": ["t3"], "data": {}},
+				{"id": "t3", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "t4", "childrenIds": [], "data": {"tab": {"savedTitle": "Acme", "savedURL": "%[1]s/acme"}}}
+			]
+		}]}
+	}`, testSite)
+
+	imp := newTestImporter(t, ImportOptions{CombineSpaces: map[string][]string{"Office": {"Work", "Clients", "Gone"}}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	spaces := make(map[string]string)
+	var names []string
+	for _, space := range session.Spaces {
+		spaces[space.UUID] = space.Name
+		names = append(names, space.Name)
+	}
+	if got := strings.Join(names, ","); got != "Home,Office" {
+		t.Fatalf("expected Home and the combined workspace, got %s", got)
+	}
+	folders := make(map[string]string)
+	for _, folder := range session.Folders {
+		folders[folder.ID] = folder.Name
+		if folder.ParentID != "" {
+			folders[folder.ID] = folders[folder.ParentID] + "/" + folder.Name
+		}
+	}
+	var tabs []string
+	for _, tab := range session.Tabs {
+		if !tab.ZenIsEmpty {
+			tabs = append(tabs, tab.ZenStaticLabel+"@"+spaces[tab.ZenWorkspace]+":"+folders[tab.GroupID])
+		}
+	}
+	if got := strings.Join(tabs, ","); got != "News@Home:,Mail@Office:Work,Spec@Office:Work/Docs,Acme@Office:Clients" {
+		t.Errorf("unexpected tabs %s", got)
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the combined spaces to restore, got %v", report.Issues)
+	}
+	if warnings := imp.warnings.List(); len(warnings) == 0 || warnings[0].Message != "-combine: no Arc space named Gone" {
+		t.Errorf("expected a warning about the unknown space, got %v", warnings)
+	}
+
+	imp = newTestImporter(t, ImportOptions{CombineSpaces: map[string][]string{"A": {"Work"}, "B": {"Work"}}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected a space combined into two workspaces to be rejected")
+	}
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotConfirmed is returned when ImportOptions.Confirm declines the write
+var ErrNotConfirmed = errors.New("import not confirmed; nothing was written")
+
+// Confirmer is asked, once everything is planned and before anything is
+// written, whether to go ahead
+type Confirmer func(summary *WriteSummary) bool
+
+// WriteSummary is what an import is about to write into which profile, for
+// a last check that it is the right one
+type WriteSummary struct {
+	ProfilePath       string
+	ProfileName       string   // Profile directory name ("abcd1234.Default (release)")
+	Workspaces        int      // Workspaces in the profile before the import
+	NewSpaces         []string // Workspaces created
+	MergedSpaces      []string // Existing workspaces whose pins are replaced
+	ExtendedSpaces    []string // Existing workspaces only added to (-mode folders, -merge merge|dedupe)
+	SkippedSpaces     []string // Existing workspaces left alone (-merge skip)
+	MergeStrategy     string   // ImportOptions.MergeStrategy
+	Folders           int
+	Tabs              int
+	ContainersCreated []string
+}
+
+// newWriteSummary summarizes plan for the profile at profilePath, which had
+// workspaces workspaces before the import
+func newWriteSummary(profilePath string, workspaces int, plan *Plan, mergeStrategy string) *WriteSummary {
+	summary := &WriteSummary{
+		ProfilePath:   profilePath,
+		ProfileName:   filepath.Base(profilePath),
+		Workspaces:    workspaces,
+		Folders:       len(plan.Folders),
+		Tabs:          len(plan.Tabs),
+		MergeStrategy: mergeStrategy,
+	}
+	for _, space := range plan.Spaces {
+		if space.KeptPins {
+			summary.ExtendedSpaces = append(summary.ExtendedSpaces, space.Name)
+		} else if space.Merged {
+			summary.MergedSpaces = append(summary.MergedSpaces, space.Name)
+		} else {
+			summary.NewSpaces = append(summary.NewSpaces, space.Name)
+		}
+	}
+	for _, item := range plan.Skipped {
+		if item.Reason == existingSpaceReason {
+			summary.SkippedSpaces = append(summary.SkippedSpaces, item.Title)
+		}
+	}
+	for _, container := range plan.Containers {
+		if container.Created {
+			summary.ContainersCreated = append(summary.ContainersCreated, container.Name)
+		}
+	}
+	return summary
+}
+
+// String formats the summary as one paragraph
+func (s *WriteSummary) String() string {
+	var changes []string
+	if len(s.NewSpaces) > 0 {
+		changes = append(changes, fmt.Sprintf("create %s (%s)", plural(len(s.NewSpaces), "workspace"), quoteList(s.NewSpaces)))
+	}
+	if len(s.MergedSpaces) > 0 {
+		changes = append(changes, fmt.Sprintf("replace the pinned tabs of %s (%s)", plural(len(s.MergedSpaces), "existing workspace"), quoteList(s.MergedSpaces)))
+	}
+	if len(s.ExtendedSpaces) > 0 {
+		spaces := fmt.Sprintf("%s (%s)", plural(len(s.ExtendedSpaces), "existing workspace"), quoteList(s.ExtendedSpaces))
+		switch s.MergeStrategy {
+		case MergeAppend:
+			changes = append(changes, fmt.Sprintf("keep the pinned tabs of %s and add the imported ones after them", spaces))
+		case MergeDedupe:
+			changes = append(changes, fmt.Sprintf("keep the pinned tabs of %s and add the tabs not pinned there yet", spaces))
+		default:
+			changes = append(changes, fmt.Sprintf("keep the pinned tabs of %s but replace earlier imported folders of the same name", spaces))
+		}
+	}
+	if len(s.SkippedSpaces) > 0 {
+		changes = append(changes, fmt.Sprintf("leave %s (%s) alone", plural(len(s.SkippedSpaces), "existing workspace"), quoteList(s.SkippedSpaces)))
+	}
+	changes = append(changes, fmt.Sprintf("add %s and %s", plural(s.Folders, "folder"), plural(s.Tabs, "pinned tab")))
+	if len(s.ContainersCreated) > 0 {
+		changes = append(changes, fmt.Sprintf("create %s (%s)", plural(len(s.ContainersCreated), "container"), quoteList(s.ContainersCreated)))
+	}
+	if len(changes) > 1 {
+		changes[len(changes)-1] = "and " + changes[len(changes)-1]
+	}
+	separator := ", "
+	if len(changes) == 2 {
+		separator = " "
+	}
+	return fmt.Sprintf("About to write to Zen profile %q (%s), which has %s now. This will %s.",
+		s.ProfileName, s.ProfilePath, plural(s.Workspaces, "workspace"), strings.Join(changes, separator))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
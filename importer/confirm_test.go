@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const confirmArcData = `{"sidebar": {"containers": [{"global": {}}, {
+	"spaces": [{"id": "s1", "title": "Reading", "containerIDs": ["pinned", "t1"]}],
+	"items": [{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "https://site.test/news"}}}]
+}]}}`
+
+func TestImportContext_ConfirmBeforeWriting(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked *WriteSummary
+	imp := newTestImporter(t, ImportOptions{Confirm: func(summary *WriteSummary) bool {
+		asked = summary
+		return false
+	}})
+	sessionPath := filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")
+
+	if _, err := imp.ImportContext(context.Background(), arcDataPath); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("expected ErrNotConfirmed, got %v", err)
+	}
+	if _, err := os.Stat(sessionPath); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written when declined, got %v", err)
+	}
+	if asked == nil || asked.ProfilePath != imp.zenProfilePath || asked.Workspaces != 0 || len(asked.NewSpaces) != 1 || asked.Tabs != 1 {
+		t.Fatalf("unexpected summary %+v", asked)
+	}
+	if text := asked.String(); !strings.Contains(text, `create 1 workspace ("Reading")`) || !strings.Contains(text, "has 0 workspaces now") {
+		t.Errorf("unexpected summary text %q", text)
+	}
+
+	imp.options.Confirm = func(*WriteSummary) bool { return true }
+	if _, err := imp.ImportContext(context.Background(), arcDataPath); err != nil {
+		t.Fatalf("confirmed import failed: %v", err)
+	}
+	if _, err := os.Stat(sessionPath); err != nil {
+		t.Errorf("expected the session written once confirmed, got %v", err)
+	}
+}
+
+func TestWriteSummary_String(t *testing.T) {
+	summary := &WriteSummary{
+		ProfilePath:       "/profiles/ab12.Default",
+		ProfileName:       "ab12.Default",
+		Workspaces:        4,
+		NewSpaces:         []string{"Work"},
+		MergedSpaces:      []string{"Home", "Media"},
+		Folders:           2,
+		Tabs:              17,
+		ContainersCreated: []string{"Work"},
+	}
+	want := `About to write to Zen profile "ab12.Default" (/profiles/ab12.Default), which has 4 workspaces now. ` +
+		`This will create 1 workspace ("Work"), replace the pinned tabs of 2 existing workspaces ("Home", "Media"), ` +
+		`add 2 folders and 17 pinned tabs, and create 1 container ("Work").`
+	if got := summary.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestWriteSummary_StringKeptPins(t *testing.T) {
+	summary := &WriteSummary{
+		ProfilePath:    "/profiles/ab12.Default",
+		ProfileName:    "ab12.Default",
+		Workspaces:     2,
+		ExtendedSpaces: []string{"Work"},
+		SkippedSpaces:  []string{"Home"},
+		MergeStrategy:  MergeDedupe,
+		Tabs:           3,
+	}
+	want := `About to write to Zen profile "ab12.Default" (/profiles/ab12.Default), which has 2 workspaces now. ` +
+		`This will keep the pinned tabs of 1 existing workspace ("Work") and add the tabs not pinned there yet, ` +
+		`leave 1 existing workspace ("Home") alone, and add 0 folders and 3 pinned tabs.`
+	if got := summary.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/types"
+	"arc-to-zen/upgrade"
+)
+
+// ErrDuplicateContainers is returned when containers.json has containers
+// sharing a name or userContextId and repairing them wasn't allowed
+var ErrDuplicateContainers = errors.New("containers.json has duplicate containers; nothing was written")
+
+// RepairConfirmer is asked, before anything is planned, whether to repair
+// the duplicate containers problems describes
+type RepairConfirmer func(problems []string) bool
+
+// repairContainers checks containers.json for containers Zen can't tell
+// apart and, if allowed, repairs them the way upgrade-session does: copies
+// sharing a userContextId are renumbered or removed, then copies sharing a
+// name are merged into the first, moving the session's tabs with them.
+// Returns the repairs made.
+func (imp *Importer) repairContainers(session *types.ZenSession, containersData *types.ContainersData) ([]string, error) {
+	problems := containers.Duplicates(containersData)
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	imp.logger.Info("containers.json has duplicate containers:")
+	for _, problem := range problems {
+		imp.logger.Info("  • %s", problem)
+	}
+
+	switch {
+	case imp.options.RepairContainers:
+	case imp.options.DryRun:
+		imp.warnings.Add(WarningMapping, "containers.json", "duplicate containers; the import will ask before repairing them (or pass -repair-containers)")
+	case imp.options.ConfirmRepair == nil || !imp.options.ConfirmRepair(problems):
+		return nil, fmt.Errorf("%w (%s); pass -repair-containers or run upgrade-session first", ErrDuplicateContainers, strings.Join(problems, "; "))
+	}
+
+	findings := append(upgrade.ContainerIDs(containersData, session), upgrade.Containers(containersData, session)...)
+	repaired := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		if imp.options.DryRun {
+			imp.logger.Info("[DRY-RUN] Would repair %s", finding)
+		} else {
+			imp.logger.Info("Repaired %s", finding)
+		}
+		repaired = append(repaired, finding.String())
+	}
+	return repaired, nil
+}
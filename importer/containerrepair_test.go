@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arc-to-zen/containers"
+)
+
+const duplicateContainers = `{"version": 5, "lastUserContextId": 5, "identities": [
+	{"name": "Work", "icon": "briefcase", "color": "orange", "public": true, "userContextId": 4},
+	{"name": "Work", "icon": "briefcase", "color": "orange", "public": true, "userContextId": 5},
+	{"name": "Banking", "icon": "dollar", "color": "green", "public": true, "userContextId": 4},
+	{"name": "userContextIdInternal.thumbnail", "icon": "", "color": "", "public": false, "userContextId": 4294967295}
+]}`
+
+func TestImportContext_DuplicateContainers(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked []string
+	imp := newTestImporter(t, ImportOptions{ConfirmRepair: func(problems []string) bool {
+		asked = problems
+		return false
+	}})
+	containersPath := filepath.Join(imp.zenProfilePath, "containers.json")
+	if err := os.WriteFile(containersPath, []byte(duplicateContainers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sessionPath := filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")
+
+	if _, err := imp.ImportContext(context.Background(), arcDataPath); !errors.Is(err, ErrDuplicateContainers) {
+		t.Fatalf("expected ErrDuplicateContainers, got %v", err)
+	}
+	if len(asked) != 2 {
+		t.Errorf("expected to be asked about 2 problems, got %v", asked)
+	}
+	if _, err := os.Stat(sessionPath); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written when declined, got %v", err)
+	}
+
+	// Dry-run repairs in memory only, without asking
+	asked = nil
+	imp.options.DryRun = true
+	result, err := imp.ImportContext(context.Background(), arcDataPath)
+	if err != nil || len(result.RepairedContainers) == 0 || asked != nil {
+		t.Fatalf("expected a dry-run repair without asking, got %v, %v (asked %v)", result, err, asked)
+	}
+	if current, _ := os.ReadFile(containersPath); string(current) != duplicateContainers {
+		t.Error("dry run changed containers.json")
+	}
+
+	imp.options.DryRun = false
+	imp.options.ConfirmRepair = nil
+	imp.options.RepairContainers = true
+	result, err = imp.ImportContext(context.Background(), arcDataPath)
+	if err != nil {
+		t.Fatalf("repairing import failed: %v", err)
+	}
+	if len(result.RepairedContainers) != 2 {
+		t.Errorf("expected Banking renumbered and the Work copy merged, got %v", result.RepairedContainers)
+	}
+	data, err := containers.Load(imp.zenProfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := containers.Duplicates(data); len(problems) != 0 {
+		t.Errorf("expected no duplicates left, got %v", problems)
+	}
+	if err := containers.Validate(data); err != nil {
+		t.Errorf("expected valid containers, got %v", err)
+	}
+}
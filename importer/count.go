@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/types"
+)
+
+// ArcCount is a quick tally of what importing Arc data would bring over, for
+// users deciding whether to prune Arc first. Nothing is planned, fetched or
+// written, and no Zen profile is read.
+type ArcCount struct {
+	Spaces   int                  `json:"spaces"`
+	Folders  int                  `json:"folders"`
+	Tabs     int                  `json:"tabs"`
+	NoURL    int                  `json:"noUrl"`      // Tabs without a URL, skipped unless -empty-urls says otherwise
+	Hosts    int                  `json:"hosts"`      // Unique hosts among the tabs' URLs
+	Favicons *favicon.CacheStatus `json:"favicons"`   // What pre-caching the favicons would do
+	Estimate time.Duration        `json:"estimateNs"` // Rough import duration, nearly all of it fetching favicons
+	PerSpace []SpaceCount         `json:"perSpace"`
+	Unparsed int                  `json:"unparsed"` // Arc items that could not be understood
+}
+
+// SpaceCount is the tally of one Arc space
+type SpaceCount struct {
+	Name    string `json:"name"` // Workspace name the import would use
+	Profile string `json:"profile"`
+	Folders int    `json:"folders"`
+	Tabs    int    `json:"tabs"`
+}
+
+// Count reads the Arc data at arcDataPath and tallies it. ImportOptions.ArcProfile
+// limits the count to one Arc profile, as it does the import.
+func (imp *Importer) Count(arcDataPath string) (*ArcCount, error) {
+	defer imp.begin()()
+	arcData, err := imp.readArcData(arcDataPath)
+	if err != nil {
+		return nil, err
+	}
+	return imp.countArcData(arcData)
+}
+
+// arcSelection is the parsed Arc data Count, Cleanup and Bookmarks work on:
+// the spaces an import would take, after ImportOptions.ArcProfile
+type arcSelection struct {
+	spaces    []*types.ArcSpace
+	itemsMap  map[string]*types.ArcItem
+	names     map[string]string // Workspace name by Arc space ID, made unique
+	unparsed  []UnparsedItem
+	profiles  map[string]*ProfileInfo
+	favorites []arcFavorites // Of every Arc profile, selected or not
+}
+
+// selectArcSpaces parses the main sidebar container and selects its spaces
+// as doImport does
+func (imp *Importer) selectArcSpaces(arcData *types.ArcData) (*arcSelection, error) {
+	if arcData.Sidebar == nil || len(arcData.Sidebar.Containers) < 2 || arcData.Sidebar.Containers[1] == nil {
+		return nil, fmt.Errorf("no main container found in Arc data")
+	}
+	mainContainer := arcData.Sidebar.Containers[1]
+
+	spaces, err := parseArcSpaces(mainContainer.Spaces, imp.warnings)
+	if err != nil {
+		return nil, err
+	}
+	items, unparsed, err := parseArcItems(mainContainer.Items, imp.warnings)
+	if err != nil {
+		return nil, err
+	}
+	if len(spaces) == 0 {
+		spaces = []*types.ArcSpace{createDefaultSpace(items)}
+	}
+	if imp.options.ArcProfile != "" {
+		selected := filterSpacesByProfile(spaces, imp.options.ArcProfile, arcData.ProfileNames)
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("no Arc spaces use profile %q (available: %s)",
+				imp.options.ArcProfile, strings.Join(describeProfiles(spaces, arcData.ProfileNames), ", "))
+		}
+		spaces = selected
+	}
+
+	itemsMap := make(map[string]*types.ArcItem)
+	for _, item := range items {
+		itemsMap[item.ID] = item
+	}
+	return &arcSelection{
+		spaces:    spaces,
+		itemsMap:  itemsMap,
+		names:     uniqueSpaceNames(spaces, imp.warnings),
+		unparsed:  unparsed,
+		profiles:  collectUniqueProfiles(spaces, arcData.ProfileNames),
+		favorites: findArcFavorites(items),
+	}, nil
+}
+
+func (imp *Importer) countArcData(arcData *types.ArcData) (*ArcCount, error) {
+	selection, err := imp.selectArcSpaces(arcData)
+	if err != nil {
+		return nil, err
+	}
+	itemsMap := selection.itemsMap
+
+	count := &ArcCount{Spaces: len(selection.spaces), Unparsed: len(selection.unparsed)}
+	hosts := make(map[string]bool)
+	var rootItems []*types.ArcItem
+	for _, space := range selection.spaces {
+		spaceCount := SpaceCount{Name: selection.names[space.ID]}
+		if profile := selection.profiles[getProfileName(space)]; profile != nil {
+			spaceCount.Profile = profile.DisplayName
+		}
+		roots := getRootItemsForSpace(space, itemsMap)
+		rootItems = append(rootItems, roots...)
+
+		// Walked like insertItemWithChildren: Arc containers are transparent and
+		// folders are what isArcFolder says. Seen items guard against cycles.
+		seen := make(map[string]bool)
+		var walk func(item *types.ArcItem)
+		walk = func(item *types.ArcItem) {
+			if seen[item.ID] {
+				return
+			}
+			seen[item.ID] = true
+			isArcContainer := item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil
+			if !isArcContainer && isArcFolder(item) {
+				spaceCount.Folders++
+			} else if !isArcContainer {
+				spaceCount.Tabs++
+				pageURL := ""
+				if item.Data != nil && item.Data.Tab != nil {
+					pageURL = strings.TrimSpace(item.Data.Tab.SavedURL)
+				}
+				if pageURL == "" {
+					count.NoURL++
+				} else if parsed, err := url.Parse(pageURL); err == nil && parsed.Hostname() != "" {
+					hosts[strings.ToLower(parsed.Hostname())] = true
+				}
+			}
+			for _, childID := range item.ChildrenIds {
+				if child := itemsMap[childID]; child != nil {
+					walk(child)
+				}
+			}
+		}
+		for _, root := range roots {
+			walk(root)
+		}
+
+		count.Folders += spaceCount.Folders
+		count.Tabs += spaceCount.Tabs
+		count.PerSpace = append(count.PerSpace, spaceCount)
+	}
+	count.Hosts = len(hosts)
+
+	// Without a cache to ask, every favicon is assumed to be fetched
+	urls := collectAllURLs(rootItems, itemsMap)
+	if inspector, ok := imp.faviconFetcher.(faviconCacheInspector); ok {
+		count.Favicons = inspector.CacheStatus(urls)
+	} else {
+		count.Favicons = &favicon.CacheStatus{Total: len(urls), Missing: len(urls)}
+	}
+	count.Estimate = count.Favicons.Estimate(faviconWorkers)
+	return count, nil
+}
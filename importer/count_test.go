@@ -0,0 +1,32 @@
+package importer
+
+import "testing"
+
+func TestCountArcData(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	count, err := imp.countArcData(multiProfileArcData(t, "https://Example.test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Spaces != 3 || count.Folders != 1 || count.Tabs != 4 || count.Hosts != 1 {
+		t.Errorf("expected 3 spaces, 1 folder, 4 tabs on 1 host, got %+v", count)
+	}
+	if count.Favicons.Total != 4 || count.Favicons.Missing != 4 || count.Estimate <= 0 {
+		t.Errorf("expected 4 favicons to fetch and an estimate, got %+v, %s", count.Favicons, count.Estimate)
+	}
+	want := []SpaceCount{
+		{Name: "Personal", Profile: "Home", Folders: 1, Tabs: 2},
+		{Name: "Home Media", Profile: "Home", Tabs: 1},
+		{Name: "Samsung", Profile: "Samsung", Tabs: 1},
+	}
+	for i, space := range count.PerSpace {
+		if space != want[i] {
+			t.Errorf("space %d: got %+v, want %+v", i, space, want[i])
+		}
+	}
+
+	imp = newTestImporter(t, ImportOptions{ArcProfile: "Samsung"})
+	if count, err := imp.countArcData(multiProfileArcData(t, "https://example.test")); err != nil || count.Spaces != 1 || count.Tabs != 1 {
+		t.Errorf("expected only the Samsung space counted, got %+v, %v", count, err)
+	}
+}
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"net/url"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// alreadyPinnedReason is the Plan.Skipped reason of the tabs -merge dedupe
+// leaves out
+const alreadyPinnedReason = "already pinned"
+
+// normalizeURL is the form URLs are compared in by -merge dedupe: scheme and
+// host lowercased, default ports, fragments and trailing slashes dropped
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+func tabURL(tab *types.ZenTab) string {
+	if n := len(tab.Entries); n > 0 {
+		return tab.Entries[n-1].URL
+	}
+	return ""
+}
+
+// dedupeBuild makes build only add to workspace what isn't there yet
+// (-merge dedupe): tabs whose URL is already pinned anywhere in the
+// workspace are left out, folders whose path of names exists there already
+// get the new tabs instead of a copy, and folders left without a tab are
+// dropped. It returns how many tabs were left out.
+func dedupeBuild(build *spaceBuild, session *types.ZenSession, workspace string) int {
+	pinned := make(map[string]bool)
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		if tab.Pinned && !tab.ZenIsEmpty && !tab.ZenEssential && tab.ZenWorkspace == workspace {
+			pinned[normalizeURL(tabURL(tab))] = true
+		}
+	}
+	existing := make(map[string]string) // Path of names → folder ID
+	sessionFolderMap := sessionFolders(session)
+	for _, folder := range session.Folders {
+		if folder.WorkspaceID == workspace {
+			if path := folderPath(sessionFolderMap, folder.ID); existing[path] == "" {
+				existing[path] = folder.ID
+			}
+		}
+	}
+
+	// Folders are built parents first
+	alias := make(map[string]string) // Built folder → existing folder with its path
+	paths := make(map[string]string)
+	placeholders := make(map[string]bool)
+	for _, folder := range build.session.Folders {
+		path := folder.Name
+		if parent := paths[folder.ParentID]; parent != "" {
+			path = parent + "/" + folder.Name
+		}
+		paths[folder.ID] = path
+		if id := existing[path]; id != "" {
+			alias[folder.ID] = id
+		}
+		for _, id := range folder.EmptyTabIDs {
+			placeholders[id] = true
+		}
+	}
+	resolve := func(id string) string {
+		if existingID, ok := alias[id]; ok {
+			return existingID
+		}
+		return id
+	}
+
+	// Leave out the tabs already pinned, counting the tabs left in each folder
+	skipped := make(map[string]bool)
+	before := make(map[string]int)
+	after := make(map[string]int)
+	for i := range build.session.Tabs {
+		tab := &build.session.Tabs[i]
+		if placeholders[tab.ZenSyncID] {
+			continue
+		}
+		before[tab.GroupID]++
+		if pinned[normalizeURL(tabURL(tab))] {
+			skipped[tab.ZenSyncID] = true
+			continue
+		}
+		after[tab.GroupID]++
+	}
+	for i := len(build.session.Folders) - 1; i >= 0; i-- {
+		folder := build.session.Folders[i]
+		if folder.ParentID != "" {
+			before[folder.ParentID] += before[folder.ID]
+			after[folder.ParentID] += after[folder.ID]
+		}
+	}
+
+	// Drop the folders that exist already, and those all of whose tabs were
+	// left out (with everything in them)
+	dropped := make(map[string]bool)
+	emptied := make(map[string]bool)
+	for _, folder := range build.session.Folders {
+		if emptied[folder.ParentID] || (before[folder.ID] > 0 && after[folder.ID] == 0) {
+			emptied[folder.ID] = true
+		}
+		if _, ok := alias[folder.ID]; ok || emptied[folder.ID] {
+			dropped[folder.ID] = true
+		}
+	}
+	if len(skipped) == 0 && len(dropped) == 0 {
+		return 0
+	}
+	// prevSibling follows a dropped folder's own previous sibling until one
+	// that stays (or one that already existed)
+	built := sessionFolders(build.session)
+	var prevSibling func(info interface{}) interface{}
+	prevSibling = func(info interface{}) interface{} {
+		ref, ok := info.(map[string]interface{})
+		if !ok {
+			return info
+		}
+		id, _ := ref["id"].(string)
+		if existingID, ok := alias[id]; ok {
+			return map[string]interface{}{"type": ref["type"], "id": existingID}
+		}
+		if dropped[id] {
+			return prevSibling(built[id].PrevSiblingInfo)
+		}
+		return info
+	}
+
+	folders := build.session.Folders[:0]
+	for _, folder := range build.session.Folders {
+		if dropped[folder.ID] {
+			continue
+		}
+		folder.ParentID = resolve(folder.ParentID)
+		folder.PrevSiblingInfo = prevSibling(folder.PrevSiblingInfo)
+		folders = append(folders, folder)
+	}
+	build.session.Folders = folders
+	tabs := build.session.Tabs[:0]
+	for _, tab := range build.session.Tabs {
+		if skipped[tab.ZenSyncID] || (placeholders[tab.ZenSyncID] && dropped[tab.GroupID]) {
+			continue
+		}
+		tab.GroupID = resolve(tab.GroupID)
+		tabs = append(tabs, tab)
+	}
+	build.session.Tabs = tabs
+	groups := build.session.Groups[:0]
+	for _, group := range build.session.Groups {
+		if !dropped[group.ID] {
+			groups = append(groups, group)
+		}
+	}
+	build.session.Groups = groups
+
+	// The plan and item count only list what is added
+	plannedFolders := build.plan.Folders[:0]
+	for _, folder := range build.plan.Folders {
+		if !dropped[folder.ID] {
+			folder.ParentID = resolve(folder.ParentID)
+			plannedFolders = append(plannedFolders, folder)
+		}
+	}
+	build.items -= len(build.plan.Folders) - len(plannedFolders)
+	build.plan.Folders = plannedFolders
+	plannedTabs := build.plan.Tabs[:0]
+	for _, tab := range build.plan.Tabs {
+		if skipped[tab.ID] {
+			build.plan.addSkipped(SkippedItem{ID: tab.ArcID, Title: tab.Title, SpaceID: tab.SpaceID, FolderID: resolve(tab.FolderID), Reason: alreadyPinnedReason})
+			continue
+		}
+		tab.FolderID = resolve(tab.FolderID)
+		plannedTabs = append(plannedTabs, tab)
+	}
+	build.items -= len(skipped)
+	build.plan.Tabs = plannedTabs
+	return len(skipped)
+}
@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://Example.COM/path/", "https://example.com/path"},
+		{"HTTPS://example.com:443/a#section", "https://example.com/a"},
+		{"http://example.com:8080/", "http://example.com:8080"},
+		{"https://example.com/search?q=Go", "https://example.com/search?q=Go"},
+		{"about:blank", "about:blank"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.in); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDoImport_MergeDedupe(t *testing.T) {
+	first := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "f1"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f1", "title": "Docs", "childrenIds": ["t2"], "data": {}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}}
+			]
+		}]}
+	}`, testSite))
+	session := emptySession()
+	if _, err := newTestImporter(t, ImportOptions{}).doImport(context.Background(), first, session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+	workspace := session.Spaces[0].UUID
+	docs := session.Folders[0].ID
+	// A pin the user added in Zen since
+	session.Tabs = append(session.Tabs, types.ZenTab{Pinned: true, ZenWorkspace: workspace, ZenSyncID: "own", ZenStaticLabel: "Own",
+		Entries: []types.ZenTabEntry{{URL: testSite + "/own"}}})
+
+	// Arc has a new tab in Docs and a new folder since; Mail is the same page
+	second := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "f1", "f2"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail/#inbox"}}},
+				{"id": "f1", "title": "Docs", "childrenIds": ["t2", "t3"], "data": {}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "t3", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Notes", "savedURL": "%[1]s/notes"}}},
+				{"id": "f2", "title": "Extra", "childrenIds": ["t4"], "data": {}},
+				{"id": "t4", "parentID": "f2", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "%[1]s/wiki"}}}
+			]
+		}]}
+	}`, testSite))
+	result, err := newTestImporter(t, ImportOptions{MergeStrategy: MergeDedupe}).doImport(context.Background(), second, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pins []string
+	for _, tab := range session.Tabs {
+		if tab.Pinned && !tab.ZenIsEmpty {
+			pins = append(pins, folderPath(sessionFolders(session), tab.GroupID)+"/"+tab.ZenStaticLabel)
+		}
+	}
+	sort.Strings(pins)
+	if got := strings.Join(pins, " "); got != "/Mail /Own Docs/Notes Docs/Spec Extra/Wiki" {
+		t.Errorf("unexpected pins %s", got)
+	}
+	if len(session.Folders) != 2 || session.Folders[0].ID != docs {
+		t.Errorf("expected Docs kept and Extra added, got %+v", session.Folders)
+	}
+	if result.ItemsImported != 3 || result.Plan.countSkipped(alreadyPinnedReason) != 2 {
+		t.Errorf("expected Notes, Extra and Wiki added and 2 tabs left out, got %d items, skipped %+v", result.ItemsImported, result.Plan.Skipped)
+	}
+	for _, tab := range result.Plan.Tabs {
+		if tab.Title == "Notes" && tab.FolderID != docs {
+			t.Errorf("expected Notes planned in the existing Docs, got %q", tab.FolderID)
+		}
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the merged workspace to restore, got %v", report.Issues)
+	}
+
+	// A third run adds nothing
+	result, err = newTestImporter(t, ImportOptions{MergeStrategy: MergeDedupe}).doImport(context.Background(), second, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ItemsImported != 0 || len(session.Folders) != 2 {
+		t.Errorf("expected nothing added again, got %d items, %d folders", result.ItemsImported, len(session.Folders))
+	}
+}
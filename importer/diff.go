@@ -0,0 +1,317 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// Changes recorded in a SessionDiff
+const (
+	DiffAdded    = "added"
+	DiffModified = "modified"
+	DiffRemoved  = "removed"
+)
+
+// SessionDiff is what an import changes in the Zen session, found by
+// comparing the session before and after it. Only workspaces, pinned tabs,
+// essentials, folders and containers are compared; open tabs are never
+// touched by an import.
+type SessionDiff struct {
+	Spaces            []SpaceDiff `json:"spaces"`               // Workspaces added, modified or removed, in sidebar order
+	Essentials        []TabDiff   `json:"essentials,omitempty"` // Essentials added, modified or removed
+	ContainersCreated []string    `json:"containersCreated,omitempty"`
+	TabsAdded         int         `json:"tabsAdded"` // Pinned tabs and essentials
+	TabsModified      int         `json:"tabsModified"`
+	TabsRemoved       int         `json:"tabsRemoved"`
+	FoldersAdded      int         `json:"foldersAdded"`
+	FoldersRemoved    int         `json:"foldersRemoved"`
+}
+
+// SpaceDiff is a workspace an import adds, modifies or removes
+type SpaceDiff struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Change         string    `json:"change"` // DiffAdded, DiffModified or DiffRemoved
+	Tabs           []TabDiff `json:"tabs,omitempty"`
+	FoldersAdded   int       `json:"foldersAdded"`
+	FoldersRemoved int       `json:"foldersRemoved"`
+}
+
+// TabDiff is a pinned tab an import adds, modifies (same zenSyncId, another
+// title, URL or folder) or removes
+type TabDiff struct {
+	ID     string `json:"id"` // zenSyncId
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Folder string `json:"folder,omitempty"` // Folder names from the workspace root: "Work/Specs"
+	Change string `json:"change"`
+}
+
+// sessionSnapshot is what diffSession compares from the session before the
+// import; the import changes the session in place
+type sessionSnapshot struct {
+	spaces     []types.ZenSpace
+	tabs       []diffedTab
+	folders    map[string]types.ZenFolder
+	containers map[int]bool
+}
+
+// diffedTab is a pinned tab or essential reduced to what a TabDiff shows
+type diffedTab struct {
+	key       string
+	workspace string // Empty for essentials
+	diff      TabDiff
+}
+
+// snapshotSession records what diffSession needs of session before the import
+func snapshotSession(session *types.ZenSession, containers *types.ContainersData) *sessionSnapshot {
+	snapshot := &sessionSnapshot{
+		spaces:     append([]types.ZenSpace(nil), session.Spaces...),
+		folders:    sessionFolders(session),
+		containers: make(map[int]bool),
+	}
+	snapshot.tabs = pinnedTabs(session, snapshot.folders)
+	if containers != nil {
+		for i := range containers.Identities {
+			snapshot.containers[containers.Identities[i].GetUserContextID()] = true
+		}
+	}
+	return snapshot
+}
+
+func sessionFolders(session *types.ZenSession) map[string]types.ZenFolder {
+	folders := make(map[string]types.ZenFolder, len(session.Folders))
+	for _, folder := range session.Folders {
+		folders[folder.ID] = folder
+	}
+	return folders
+}
+
+// pinnedTabs returns session's pinned tabs and essentials, keyed by
+// zenSyncId (or, without one, by workspace, URL and title)
+func pinnedTabs(session *types.ZenSession, folders map[string]types.ZenFolder) []diffedTab {
+	var tabs []diffedTab
+	for _, tab := range session.Tabs {
+		if (!tab.Pinned && !tab.ZenEssential) || tab.ZenIsEmpty {
+			continue // Open tabs, and the placeholders that keep folders alive
+		}
+		diff := TabDiff{ID: tab.ZenSyncID, Title: tab.ZenStaticLabel, Folder: folderPath(folders, tab.GroupID)}
+		if n := len(tab.Entries); n > 0 {
+			diff.URL = tab.Entries[n-1].URL
+			if diff.Title == "" {
+				diff.Title = tab.Entries[n-1].Title
+			}
+		}
+		key := tab.ZenSyncID
+		if key == "" {
+			key = tab.ZenWorkspace + "\x00" + diff.URL + "\x00" + diff.Title
+		}
+		workspace := tab.ZenWorkspace
+		if tab.ZenEssential {
+			workspace = ""
+		}
+		tabs = append(tabs, diffedTab{key: key, workspace: workspace, diff: diff})
+	}
+	return tabs
+}
+
+// folderPath returns the names of folder id and its parents, root first
+func folderPath(folders map[string]types.ZenFolder, id string) string {
+	var names []string
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		seen[id] = true
+		folder, ok := folders[id]
+		if !ok {
+			break
+		}
+		names = append([]string{folder.Name}, names...)
+		id = folder.ParentID
+	}
+	return strings.Join(names, "/")
+}
+
+// diffSession compares session and containers, as the import left them, with
+// the snapshot taken before it
+func diffSession(before *sessionSnapshot, session *types.ZenSession, containers *types.ContainersData) *SessionDiff {
+	diff := &SessionDiff{Spaces: []SpaceDiff{}}
+	afterFolders := sessionFolders(session)
+
+	// Tabs per workspace ("" for essentials), in session order
+	changes := make(map[string][]TabDiff)
+	beforeTabs := make(map[string]diffedTab, len(before.tabs))
+	for _, tab := range before.tabs {
+		beforeTabs[tab.key] = tab
+	}
+	afterKeys := make(map[string]bool)
+	for _, tab := range pinnedTabs(session, afterFolders) {
+		afterKeys[tab.key] = true
+		old, existed := beforeTabs[tab.key]
+		switch {
+		case !existed:
+			tab.diff.Change = DiffAdded
+			diff.TabsAdded++
+		case old.diff.Title != tab.diff.Title || old.diff.URL != tab.diff.URL || old.diff.Folder != tab.diff.Folder || old.workspace != tab.workspace:
+			tab.diff.Change = DiffModified
+			diff.TabsModified++
+		default:
+			continue
+		}
+		changes[tab.workspace] = append(changes[tab.workspace], tab.diff)
+	}
+	for _, tab := range before.tabs {
+		if !afterKeys[tab.key] {
+			tab.diff.Change = DiffRemoved
+			diff.TabsRemoved++
+			changes[tab.workspace] = append(changes[tab.workspace], tab.diff)
+		}
+	}
+	diff.Essentials = changes[""]
+
+	foldersAdded := make(map[string]int)
+	foldersRemoved := make(map[string]int)
+	for id, folder := range afterFolders {
+		if _, existed := before.folders[id]; !existed {
+			foldersAdded[folder.WorkspaceID]++
+			diff.FoldersAdded++
+		}
+	}
+	for id, folder := range before.folders {
+		if _, kept := afterFolders[id]; !kept {
+			foldersRemoved[folder.WorkspaceID]++
+			diff.FoldersRemoved++
+		}
+	}
+
+	beforeSpaces := make(map[string]types.ZenSpace, len(before.spaces))
+	for _, space := range before.spaces {
+		beforeSpaces[space.UUID] = space
+	}
+	afterSpaces := make(map[string]bool, len(session.Spaces))
+	for _, space := range session.Spaces {
+		afterSpaces[space.UUID] = true
+		entry := SpaceDiff{ID: space.UUID, Name: space.Name, Tabs: changes[space.UUID],
+			FoldersAdded: foldersAdded[space.UUID], FoldersRemoved: foldersRemoved[space.UUID]}
+		old, existed := beforeSpaces[space.UUID]
+		switch {
+		case !existed:
+			entry.Change = DiffAdded
+		case len(entry.Tabs) > 0 || entry.FoldersAdded > 0 || entry.FoldersRemoved > 0 ||
+			old.Name != space.Name || old.Icon != space.Icon || old.ContainerTabID != space.ContainerTabID:
+			entry.Change = DiffModified
+		default:
+			continue
+		}
+		diff.Spaces = append(diff.Spaces, entry)
+	}
+	for _, space := range before.spaces {
+		if !afterSpaces[space.UUID] {
+			diff.Spaces = append(diff.Spaces, SpaceDiff{ID: space.UUID, Name: space.Name, Change: DiffRemoved,
+				Tabs: changes[space.UUID], FoldersRemoved: foldersRemoved[space.UUID]})
+		}
+	}
+
+	if containers != nil {
+		for i := range containers.Identities {
+			identity := &containers.Identities[i]
+			if !before.containers[identity.GetUserContextID()] {
+				name := identity.Name
+				if name == "" {
+					name = identity.L10nID
+				}
+				diff.ContainersCreated = append(diff.ContainersCreated, name)
+			}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the import changes nothing
+func (d *SessionDiff) Empty() bool {
+	return len(d.Spaces) == 0 && len(d.Essentials) == 0 && len(d.ContainersCreated) == 0
+}
+
+// Summary counts the changes in one line
+func (d *SessionDiff) Summary() string {
+	counts := map[string]int{}
+	for _, space := range d.Spaces {
+		counts[space.Change]++
+	}
+	parts := []string{
+		fmt.Sprintf("workspaces: %d added, %d modified, %d removed", counts[DiffAdded], counts[DiffModified], counts[DiffRemoved]),
+		fmt.Sprintf("pinned tabs: %d added, %d modified, %d removed", d.TabsAdded, d.TabsModified, d.TabsRemoved),
+		fmt.Sprintf("folders: %d added, %d removed", d.FoldersAdded, d.FoldersRemoved),
+		fmt.Sprintf("containers: %d created", len(d.ContainersCreated)),
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Lines renders the diff as a tree: each workspace with its changed tabs,
+// then essentials and containers. Lines start with + (added), ~ (modified)
+// or - (removed).
+func (d *SessionDiff) Lines() []string {
+	var lines []string
+	for _, space := range d.Spaces {
+		line := fmt.Sprintf("%s %s", diffMark(space.Change), space.Name)
+		var details []string
+		if space.Change == DiffAdded {
+			details = append(details, "new workspace")
+		}
+		if space.FoldersAdded > 0 {
+			details = append(details, plural(space.FoldersAdded, "folder")+" added")
+		}
+		if space.FoldersRemoved > 0 {
+			details = append(details, plural(space.FoldersRemoved, "folder")+" removed")
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		lines = append(lines, line)
+		lines = append(lines, tabLines(space.Tabs)...)
+	}
+	if len(d.Essentials) > 0 {
+		lines = append(lines, "~ Essentials")
+		lines = append(lines, tabLines(d.Essentials)...)
+	}
+	for _, name := range d.ContainersCreated {
+		lines = append(lines, fmt.Sprintf("+ Container %q", name))
+	}
+	return lines
+}
+
+func tabLines(tabs []TabDiff) []string {
+	lines := make([]string, 0, len(tabs))
+	for _, tab := range tabs {
+		title := tab.Title
+		if tab.Folder != "" {
+			title = tab.Folder + "/" + title
+		}
+		lines = append(lines, fmt.Sprintf("    %s %s  %s", diffMark(tab.Change), title, tab.URL))
+	}
+	return lines
+}
+
+func diffMark(change string) string {
+	switch change {
+	case DiffAdded:
+		return "+"
+	case DiffRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// logDiff logs what a dry run would change in the session
+func (imp *Importer) logDiff(diff *SessionDiff) {
+	imp.logger.Info("")
+	if diff.Empty() {
+		imp.logger.Info("[DRY-RUN] The session would not change")
+		return
+	}
+	imp.logger.Info("[DRY-RUN] Changes to the session (%s):", diff.Summary())
+	for _, line := range diff.Lines() {
+		imp.logger.Info("  %s", line)
+	}
+}
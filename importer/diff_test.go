@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func TestDiffSession(t *testing.T) {
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "f1"]},
+				{"id": "s2", "title": "Home", "containerIDs": ["t3"]}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f1", "title": "Docs", "childrenIds": ["t2"], "data": {}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}}
+			]
+		}]}
+	}`, testSite))
+
+	session := emptySession()
+	session.Spaces = append(session.Spaces, types.ZenSpace{UUID: "z1", Name: "Work"}, types.ZenSpace{UUID: "z2", Name: "Untouched"})
+	session.Tabs = append(session.Tabs,
+		types.ZenTab{Pinned: true, ZenWorkspace: "z1", ZenSyncID: "old", ZenStaticLabel: "Old", Entries: []types.ZenTabEntry{{URL: testSite + "/old"}}},
+		types.ZenTab{Pinned: true, ZenWorkspace: "z2", ZenSyncID: "keep", ZenStaticLabel: "Keep", Entries: []types.ZenTabEntry{{URL: testSite + "/keep"}}},
+		types.ZenTab{ZenWorkspace: "z1", Entries: []types.ZenTabEntry{{URL: testSite + "/open"}}},
+	)
+	containers := &types.ContainersData{Version: 5}
+
+	before := snapshotSession(session, containers)
+	if _, err := newTestImporter(t, ImportOptions{}).doImport(context.Background(), arcData, session, containers); err != nil {
+		t.Fatal(err)
+	}
+	diff := diffSession(before, session, containers)
+
+	var spaces []string
+	for _, space := range diff.Spaces {
+		var tabs []string
+		for _, tab := range space.Tabs {
+			tabs = append(tabs, diffMark(tab.Change)+tab.Title)
+		}
+		spaces = append(spaces, fmt.Sprintf("%s%s(%s)", diffMark(space.Change), space.Name, strings.Join(tabs, " ")))
+	}
+	// The open tab and the untouched workspace are left out
+	if got := strings.Join(spaces, " "); got != "~Work(+Mail +Spec -Old) +Home(+News)" {
+		t.Errorf("unexpected diff %s", got)
+	}
+	if diff.TabsAdded != 3 || diff.TabsRemoved != 1 || diff.TabsModified != 0 || diff.FoldersAdded != 1 {
+		t.Errorf("unexpected counts %s", diff.Summary())
+	}
+	if tab := diff.Spaces[0].Tabs[1]; tab.Folder != "Docs" || tab.URL != testSite+"/spec" {
+		t.Errorf("expected Spec in Docs, got %+v", tab)
+	}
+	if len(diff.ContainersCreated) != len(containers.Identities) {
+		t.Errorf("expected the new containers %v, got %v", containers.Identities, diff.ContainersCreated)
+	}
+
+	lines := diff.Lines()
+	if len(lines) < 2 || lines[0] != "~ Work (1 folder added)" || lines[1] != "    + Mail  "+testSite+"/mail" {
+		t.Errorf("unexpected rendering %q", lines)
+	}
+
+	// Nothing changes when the session is compared with itself
+	if again := diffSession(snapshotSession(session, containers), session, containers); !again.Empty() {
+		t.Errorf("expected an empty diff, got %+v", again)
+	}
+}
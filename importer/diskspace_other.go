@@ -0,0 +1,12 @@
+//go:build !darwin && !linux
+
+package importer
+
+// DiskSpaceCheck reports whether the free space of the profile's disk is
+// checked before writing
+const DiskSpaceCheck = false
+
+// freeDiskSpace is not implemented on this platform; the check is skipped
+func freeDiskSpace(path string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,19 @@
+//go:build darwin || linux
+
+package importer
+
+import "syscall"
+
+// DiskSpaceCheck reports whether the free space of the profile's disk is
+// checked before writing
+const DiskSpaceCheck = true
+
+// freeDiskSpace returns the bytes available to unprivileged users on the
+// filesystem containing path
+func freeDiskSpace(path string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/mappings"
+	"arc-to-zen/types"
+)
+
+// domainContainer is a -domain-container rule with its container resolved
+type domainContainer struct {
+	domain      string
+	containerID int // 0 for ContainerNone
+}
+
+// ruleDomain is the domain a -domain-container rule matches, lowercased and
+// without a scheme, "*." prefix or trailing dot
+func ruleDomain(pattern string) string {
+	domain := strings.ToLower(strings.TrimSpace(pattern))
+	if u, err := url.Parse(domain); err == nil && u.Host != "" {
+		domain = u.Hostname()
+	}
+	domain = strings.TrimPrefix(domain, "*.")
+	return strings.Trim(domain, ".")
+}
+
+func (imp *Importer) validateDomainContainers() error {
+	imp.domainRules = nil
+	for pattern, container := range imp.options.DomainContainers {
+		if ruleDomain(pattern) == "" || strings.TrimSpace(container) == "" {
+			return fmt.Errorf("invalid -domain-container rule %q=%q (expected domain=container)", pattern, container)
+		}
+	}
+	return nil
+}
+
+// assignDomainContainers resolves the container of every -domain-container
+// rule, by name or userContextId, creating the containers that don't exist
+// yet. Rules are kept most specific first.
+func (imp *Importer) assignDomainContainers(containersData *types.ContainersData, session *types.ZenSession) error {
+	imp.domainRules = nil
+	if len(imp.options.DomainContainers) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(imp.options.DomainContainers))
+	for pattern := range imp.options.DomainContainers {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns) // New containers get stable IDs
+
+	for _, pattern := range patterns {
+		assignment := strings.TrimSpace(imp.options.DomainContainers[pattern])
+		rule := domainContainer{domain: ruleDomain(pattern)}
+		if assignment != ContainerNone {
+			existing, err := containers.Find(containersData, assignment)
+			if errors.Is(err, containers.ErrAmbiguousName) {
+				return fmt.Errorf("domain %q: %w", pattern, err)
+			}
+			if existing != nil && existing.HasValidUserContextID() {
+				rule.containerID = existing.GetUserContextID()
+				if !imp.plan.hasContainer(rule.containerID) {
+					imp.plan.addContainer(PlannedContainer{ID: rule.containerID, Name: displayContainerName(existing, assignment)})
+				}
+			} else {
+				id, err := calculateNextContainerID(containersData, session)
+				if err != nil {
+					return fmt.Errorf("domain %q: %w", pattern, err)
+				}
+				rule.containerID = id
+				containersData.Identities = append(containersData.Identities, types.ContainerIdentity{
+					UserContextID: &rule.containerID,
+					Name:          assignment,
+					Icon:          mappings.MapArcIconToContainerIcon(""),
+					Color:         containerColors[len(containersData.Identities)%len(containerColors)],
+					Public:        true,
+				})
+				recordContainerID(containersData, rule.containerID)
+				imp.plan.addContainer(PlannedContainer{ID: rule.containerID, Name: assignment, Created: true})
+				if !imp.options.DryRun {
+					imp.logger.Info("Created container \"%s\" for %s (ID: %d)", assignment, rule.domain, rule.containerID)
+				} else {
+					imp.logger.Info("[DRY-RUN] Would create container \"%s\" for %s (ID: %d)", assignment, rule.domain, rule.containerID)
+				}
+			}
+		}
+		imp.domainRules = append(imp.domainRules, rule)
+	}
+	sort.SliceStable(imp.domainRules, func(i, j int) bool {
+		return len(imp.domainRules[i].domain) > len(imp.domainRules[j].domain)
+	})
+	return nil
+}
+
+func displayContainerName(container *types.ContainerIdentity, fallback string) string {
+	if container.Name != "" {
+		return container.Name
+	}
+	return fallback
+}
+
+// tabContainer returns the container of a tab opening rawURL: that of the
+// most specific -domain-container rule matching its host, else containerID,
+// the container of its space. overridden reports whether a rule applied.
+func (imp *Importer) tabContainer(rawURL string, containerID int) (id int, overridden bool) {
+	if len(imp.domainRules) == 0 {
+		return containerID, false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return containerID, false
+	}
+	host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return containerID, false
+	}
+	for _, rule := range imp.domainRules {
+		if host == rule.domain || strings.HasSuffix(host, "."+rule.domain) {
+			return rule.containerID, true
+		}
+	}
+	return containerID, false
+}
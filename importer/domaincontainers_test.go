@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_DomainContainers(t *testing.T) {
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "t1", "t2", "t3", "t4", "t5"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 1"}}}}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "https://mail.workdomain.com/inbox"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Home", "savedURL": "https://WorkDomain.com/"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Docs", "savedURL": "https://docs.workdomain.com/spec"}}},
+				{"id": "t4", "childrenIds": [], "data": {"tab": {"savedTitle": "Shop", "savedURL": "https://shop.example/"}}},
+				{"id": "t5", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "https://news.test/"}}}
+			]
+		}]}
+	}`)
+	workID := 9
+	containersData := &types.ContainersData{
+		Version: 5,
+		Identities: []types.ContainerIdentity{
+			{UserContextID: &workID, Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+		},
+	}
+	imp := newTestImporter(t, ImportOptions{DomainContainers: map[string]string{
+		"*.workdomain.com":     "Work",
+		"docs.workdomain.com":  "Docs",
+		"https://shop.example": ContainerNone,
+	}})
+	session := emptySession()
+
+	result, err := imp.doImport(context.Background(), arcData, session, containersData)
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	docs, err := findContainerID(containersData, "Docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := findContainerID(containersData, "Profile 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"Mail": workID, "Home": workID, "Docs": docs, "Shop": 0, "News": profileID}
+	for _, tab := range session.Tabs {
+		if tab.ZenIsEmpty {
+			continue
+		}
+		want, ok := expected[tab.ZenStaticLabel]
+		if !ok {
+			t.Fatalf("unexpected tab %q", tab.ZenStaticLabel)
+		}
+		if tab.UserContextID != want {
+			t.Errorf("%s: expected container %d, got %d", tab.ZenStaticLabel, want, tab.UserContextID)
+		}
+		// Only tabs left in the space's container are marked as its default
+		if overridden := want != profileID; overridden != (tab.ZenDefaultUserContextID == nil) {
+			t.Errorf("%s: unexpected zenDefaultUserContextId %v", tab.ZenStaticLabel, tab.ZenDefaultUserContextID)
+		}
+	}
+	for _, tab := range result.Plan.Tabs {
+		if tab.ContainerID != expected[tab.Title] {
+			t.Errorf("plan %s: expected container %d, got %d", tab.Title, expected[tab.Title], tab.ContainerID)
+		}
+	}
+
+	created := map[string]bool{}
+	for _, container := range result.Plan.Containers {
+		if container.Created {
+			created[container.Name] = true
+		}
+	}
+	if !created["Docs"] || created["Work"] || len(created) != 2 {
+		t.Errorf("expected the profile's and the Docs containers to be created, got %+v", result.Plan.Containers)
+	}
+}
+
+func TestValidateDomainContainers(t *testing.T) {
+	for _, rules := range []map[string]string{{"*.": "Work"}, {"example.com": " "}} {
+		imp := newTestImporter(t, ImportOptions{DomainContainers: rules})
+		if err := imp.validateOptions(); err == nil {
+			t.Errorf("%v: expected an error", rules)
+		}
+	}
+}
+
+// findContainerID returns the userContextId of the container named name
+func findContainerID(data *types.ContainersData, name string) (int, error) {
+	container, err := containers.Find(data, name)
+	if err != nil {
+		return 0, err
+	}
+	return container.GetUserContextID(), nil
+}
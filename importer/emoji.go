@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"arc-to-zen/types"
+)
+
+// Code points that combine with the emoji before them
+const (
+	zeroWidthJoiner   = '\u200d'
+	emojiPresentation = '\ufe0f'
+	textPresentation  = '\ufe0e'
+	combiningKeycap   = '\u20e3'
+)
+
+// splitLeadingEmoji splits an emoji (with its modifiers, ZWJ sequence or flag
+// pair) off the start of name. It returns "" and name unchanged when name
+// doesn't start with one or has nothing after it.
+func splitLeadingEmoji(name string) (emoji, rest string) {
+	s := strings.TrimLeftFunc(name, unicode.IsSpace)
+	end := emojiLength(s)
+	if end == 0 {
+		return "", name
+	}
+	rest = strings.TrimLeftFunc(s[end:], func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '|' || r == ':' || r == '·'
+	})
+	if rest == "" {
+		return "", name
+	}
+	return s[:end], rest
+}
+
+// emojiLength returns the byte length of the emoji at the start of s, or 0
+func emojiLength(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	switch {
+	case isRegionalIndicator(r):
+		if next, n := utf8.DecodeRuneInString(s[size:]); isRegionalIndicator(next) {
+			return size + n // Flag
+		}
+		return 0
+	case r == '#' || r == '*' || (r >= '0' && r <= '9') || r == '©' || r == '®':
+		// Text characters that are only emoji with a presentation selector
+		next, n := utf8.DecodeRuneInString(s[size:])
+		if next != emojiPresentation {
+			return 0
+		}
+		size += n
+		if next, n := utf8.DecodeRuneInString(s[size:]); next == combiningKeycap {
+			size += n
+		}
+		return size
+	case !isEmojiBase(r):
+		return 0
+	}
+
+	for size < len(s) {
+		next, n := utf8.DecodeRuneInString(s[size:])
+		switch {
+		case next == emojiPresentation || next == textPresentation || next == combiningKeycap,
+			next >= 0x1f3fb && next <= 0x1f3ff, // Skin tones
+			next >= 0xe0020 && next <= 0xe007f: // Tag sequences (subdivision flags)
+			size += n
+		case next == zeroWidthJoiner:
+			joined, m := utf8.DecodeRuneInString(s[size+n:])
+			if !isEmojiBase(joined) {
+				return size
+			}
+			size += n + m
+		default:
+			return size
+		}
+	}
+	return size
+}
+
+// isEmojiBase reports whether r is shown as an emoji on its own
+func isEmojiBase(r rune) bool {
+	switch {
+	case r >= 0x1f000 && r <= 0x1faff: // Symbols, pictographs, emoticons, transport
+		return !isRegionalIndicator(r)
+	case r >= 0x2600 && r <= 0x27bf: // Miscellaneous symbols, dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23ff: // ⌚ ⏰ ⏳ ...
+		return true
+	case r >= 0x2b00 && r <= 0x2bff: // ⬛ ⭐ ⭕ ...
+		return true
+	}
+	return r == 0x3030 || r == 0x303d || r == 0x3297 || r == 0x3299
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// stripNameEmojis returns spaces with a leading emoji removed from their
+// titles, and the removed emoji by space ID. The spaces are copies, so the
+// Arc data itself is left as read.
+func stripNameEmojis(spaces []*types.ArcSpace) ([]*types.ArcSpace, map[string]string) {
+	stripped := make([]*types.ArcSpace, len(spaces))
+	emojis := make(map[string]string)
+	for i, space := range spaces {
+		stripped[i] = space
+		emoji, rest := splitLeadingEmoji(space.Title)
+		if emoji == "" {
+			continue
+		}
+		copied := *space
+		copied.Title = rest
+		stripped[i] = &copied
+		emojis[space.ID] = emoji
+	}
+	return stripped, emojis
+}
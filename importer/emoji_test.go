@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func TestSplitLeadingEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		rest  string
+	}{
+		{"🚀 Launch", "🚀", "Launch"},
+		{"🚀Launch", "🚀", "Launch"},
+		{"  ☕️ - Coffee", "☕️", "Coffee"},
+		{"👩🏽‍💻 Dev", "👩🏽‍💻", "Dev"},
+		{"🇩🇪 Berlin", "🇩🇪", "Berlin"},
+		{"1️⃣ First", "1️⃣", "First"},
+		{"1 First", "", "1 First"},
+		{"Work 🚀", "", "Work 🚀"},
+		{"🚀", "", "🚀"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		emoji, rest := splitLeadingEmoji(tt.name)
+		if emoji != tt.emoji || rest != tt.rest {
+			t.Errorf("splitLeadingEmoji(%q) = %q, %q, want %q, %q", tt.name, emoji, rest, tt.emoji, tt.rest)
+		}
+	}
+}
+
+func TestDoImport_EmojiFromName(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "🚀 Launch", "containerIDs": ["pinned"], "customInfo": {"iconType": {"emoji_v2": "🚀"}}},
+				{"id": "s2", "title": "Launch", "containerIDs": ["pinned"]}
+			],
+			"items": []
+		}]}
+	}`
+
+	imp := newTestImporter(t, ImportOptions{EmojiFromName: true})
+	session := emptySession()
+	arcData := parseTestArcData(t, raw)
+	if _, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	icons := make(map[string]string)
+	for _, space := range session.Spaces {
+		icons[space.Name] = space.Icon
+	}
+	if icons["Launch"] != "🚀" {
+		t.Errorf("expected the title emoji as icon of \"Launch\", got %v", icons)
+	}
+	if _, ok := icons["Launch (2)"]; !ok {
+		t.Errorf("expected the stripped name to be made unique, got %v", icons)
+	}
+	if imp.warnings.Len() != 1 {
+		t.Errorf("expected only the duplicate name warning, got %d", imp.warnings.Len())
+	}
+}
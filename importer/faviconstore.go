@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/places"
+	"arc-to-zen/principal"
+	"arc-to-zen/types"
+)
+
+const (
+	// FaviconStorageSession embeds favicons in the session as data URLs
+	FaviconStorageSession = "session"
+	// FaviconStorageSQLite writes favicons into the profile's favicons.sqlite,
+	// where Zen keeps the icons of the pages it visits, and has the session
+	// refer to them
+	FaviconStorageSQLite = "sqlite"
+)
+
+// storeFaviconsInDatabase moves the fetched favicons of the imported tabs out
+// of session (FaviconStorageSQLite): each tab's image becomes a page-icon:
+// URL Zen resolves from favicons.sqlite, and the icons to write there are
+// returned. Custom Arc icons, which aren't the site's, and favicons that
+// can't be converted stay in the session.
+func (imp *Importer) storeFaviconsInDatabase(session *types.ZenSession, plan *Plan) ([]places.Favicon, error) {
+	path := places.FaviconsPath(imp.zenProfilePath)
+	if _, err := os.Stat(path); err != nil {
+		if !imp.options.DryRun {
+			return nil, fmt.Errorf("%s not found (start Zen once, or use -favicon-storage %s): %w", places.FaviconsFileName, FaviconStorageSession, err)
+		}
+		imp.warnings.Add(WarningWrite, path, "import would fail: %s not found", places.FaviconsFileName)
+	}
+
+	imported := make(map[string]*PlannedTab, len(plan.Tabs))
+	for i := range plan.Tabs {
+		imported[plan.Tabs[i].ID] = &plan.Tabs[i]
+	}
+	// Firefox only loads a tab icon that isn't a data URL with a principal
+	loadingPrincipal, err := json.Marshal(principal.SystemBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	type converted struct {
+		data        []byte
+		contentType string
+		width       int
+		err         error
+	}
+	conversions := make(map[string]*converted) // Tabs of one host share an icon
+	stored := make(map[string]bool)
+	var icons []places.Favicon
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		planned := imported[tab.ZenSyncID]
+		if planned == nil || planned.Icon == "" || planned.customIcon || planned.URL == "" || tab.Image != planned.Icon {
+			continue
+		}
+		c := conversions[planned.Icon]
+		if c == nil {
+			c = &converted{}
+			c.data, c.contentType, c.width, c.err = favicon.ForDatabase(planned.Icon)
+			if c.err != nil {
+				imp.debug("  Favicon of %s kept in the session: %v", planned.URL, c.err)
+			}
+			conversions[planned.Icon] = c
+		}
+		if c.err != nil {
+			continue
+		}
+
+		image := places.PageIconURL(planned.URL)
+		tab.Image = image
+		if tab.ZenPinnedIcon != nil {
+			tab.ZenPinnedIcon = image
+		}
+		if state, ok := tab.ZenPinnedInitialState.(map[string]interface{}); ok {
+			state["image"] = image
+		}
+		if tab.Extra == nil {
+			tab.Extra = make(map[string]json.RawMessage)
+		}
+		tab.Extra["iconLoadingPrincipal"] = loadingPrincipal
+		if !stored[planned.URL] {
+			stored[planned.URL] = true
+			icons = append(icons, places.Favicon{PageURL: planned.URL, Data: c.data, ContentType: c.contentType, Width: c.width})
+		}
+	}
+	return icons, nil
+}
+
+// writeFavicons writes what storeFaviconsInDatabase returned, before the
+// session that refers to it. The import time limit is for favicon requests,
+// so it doesn't cut this short.
+func (imp *Importer) writeFavicons(ctx context.Context, icons []places.Favicon) (int, error) {
+	written, err := places.WriteFavicons(context.WithoutCancel(ctx), places.FaviconsPath(imp.zenProfilePath), icons)
+	if err != nil {
+		return 0, fmt.Errorf("favicons not written: %w", err)
+	}
+	imp.logger.Info("✓ Wrote %s to %s", plural(written, "favicon"), places.FaviconsFileName)
+	return written, nil
+}
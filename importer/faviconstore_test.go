@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"arc-to-zen/places"
+)
+
+const faviconsSchema = `
+CREATE TABLE moz_icons (id INTEGER PRIMARY KEY, icon_url TEXT NOT NULL, fixed_icon_url_hash INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0, root INTEGER NOT NULL DEFAULT 0, color INTEGER, expire_ms INTEGER NOT NULL DEFAULT 0,
+	flags INTEGER NOT NULL DEFAULT 0, data BLOB);
+CREATE TABLE moz_pages_w_icons (id INTEGER PRIMARY KEY, page_url TEXT NOT NULL, page_url_hash INTEGER NOT NULL);
+CREATE TABLE moz_icons_to_pages (page_id INTEGER NOT NULL, icon_id INTEGER NOT NULL, expire_ms INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (page_id, icon_id)) WITHOUT ROWID;
+`
+
+func TestImportContext_FaviconStorageSQLite(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	icon := noisyIcon(t, 128)
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{FaviconStorage: FaviconStorageSQLite, ZenVersion: "1.14.5b"})
+	imp.faviconFetcher = iconFetcher{icon}
+
+	// Zen creates favicons.sqlite on first start; without it nothing is written
+	if _, err := imp.ImportContext(context.Background(), arcDataPath); err == nil || !strings.Contains(err.Error(), places.FaviconsFileName) {
+		t.Fatalf("expected a missing favicons.sqlite error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")); !os.IsNotExist(err) {
+		t.Errorf("expected no session written, got %v", err)
+	}
+
+	db, err := sql.Open("sqlite", places.FaviconsPath(imp.zenProfilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(faviconsSchema); err != nil {
+		t.Fatal(err)
+	}
+	result, err := imp.ImportContext(context.Background(), arcDataPath)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.FaviconsStored != 1 {
+		t.Errorf("expected 1 favicon stored, got %d", result.FaviconsStored)
+	}
+
+	session, err := imp.readZenSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := places.PageIconURL("https://site.test/news")
+	for _, tab := range session.Tabs {
+		if tab.ZenIsEmpty {
+			continue
+		}
+		state, _ := tab.ZenPinnedInitialState.(map[string]interface{})
+		if tab.Image != want || tab.ZenPinnedIcon != want || state["image"] != want {
+			t.Errorf("expected the tab to refer to %s, got %v, %v, %v", want, tab.Image, tab.ZenPinnedIcon, state["image"])
+		}
+		if string(tab.Extra["iconLoadingPrincipal"]) == "" {
+			t.Error("expected an icon loading principal")
+		}
+	}
+
+	var width int
+	var pageURL string
+	if err := db.QueryRow(`SELECT i.width, p.page_url FROM moz_icons i JOIN moz_icons_to_pages l ON l.icon_id = i.id
+		JOIN moz_pages_w_icons p ON p.id = l.page_id`).Scan(&width, &pageURL); err != nil {
+		t.Fatal(err)
+	}
+	if width != 64 || pageURL != "https://site.test/news" {
+		t.Errorf("expected a 64px icon for the tab, got %dpx for %s", width, pageURL)
+	}
+}
+
+func TestStoreFaviconsInDatabase_KeepsCustomIcons(t *testing.T) {
+	icon := noisyIcon(t, 16)
+	imp, session, result := budgetImport(t, ImportOptions{FaviconStorage: FaviconStorageSQLite, DryRun: true}, icon)
+	custom := &result.Plan.Tabs[0]
+	custom.customIcon = true
+	icons, err := imp.storeFaviconsInDatabase(session, result.Plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := make(map[string]bool)
+	for _, tab := range result.Plan.Tabs[1:] {
+		if tab.URL != custom.URL {
+			pages[tab.URL] = true
+		}
+	}
+	if len(icons) != len(pages) || len(icons) == 0 {
+		t.Errorf("expected %d icons to store, got %d", len(pages), len(icons))
+	}
+	for _, tab := range session.Tabs {
+		if tab.ZenSyncID == custom.ID && tab.Image != icon {
+			t.Errorf("expected the custom icon kept in the session, got %v", tab.Image)
+		}
+	}
+	if warnings := imp.warnings.ByCategory()[WarningWrite]; len(warnings) != 1 {
+		t.Errorf("expected a dry-run warning about the missing database, got %v", warnings)
+	}
+
+	// Icons that can't be converted stay in the session
+	imp, session, result = budgetImport(t, ImportOptions{FaviconStorage: FaviconStorageSQLite, DryRun: true}, "data:image/avif;base64,AAAAHGZ0eXBhdmlm")
+	if icons, err := imp.storeFaviconsInDatabase(session, result.Plan); err != nil || len(icons) != 0 {
+		t.Errorf("expected no icons to store, got %d (%v)", len(icons), err)
+	}
+}
+
+// dedupFetcher counts as saved a fixed number of cache bytes per favicon
+type dedupFetcher struct {
+	iconFetcher
+	saved *atomic.Int64
+}
+
+func (f dedupFetcher) FetchAsDataURLContext(ctx context.Context, pageURL string) string {
+	f.saved.Add(10)
+	return f.dataURL
+}
+
+func (f dedupFetcher) DedupSavedBytes() int64 { return f.saved.Load() }
+
+func TestImportContext_FaviconCacheSavedPerRun(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := dedupFetcher{iconFetcher{noisyIcon(t, 16)}, &atomic.Int64{}}
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{ZenVersion: "1.14.5b", FaviconFetcher: fetcher})
+
+	// The fetcher is reused; each import reports only what it saved
+	var saved []int64
+	for run := 0; run < 2; run++ {
+		result, err := imp.ImportContext(context.Background(), arcDataPath)
+		if err != nil {
+			t.Fatalf("run %d: import failed: %v", run, err)
+		}
+		saved = append(saved, result.FaviconCacheSaved)
+	}
+	if saved[0] == 0 || saved[1] != saved[0] || fetcher.saved.Load() != saved[0]+saved[1] {
+		t.Errorf("expected equal savings per run adding up to %d, got %v", fetcher.saved.Load(), saved)
+	}
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"context"
+
+	"arc-to-zen/types"
+)
+
+// alreadyEssentialReason is the Plan.Skipped reason of Arc favorites that
+// are already essentials of their container, e.g. from an earlier import
+const alreadyEssentialReason = "already an essential"
+
+// arcFavorites is the favorites bar of one Arc profile: the children of its
+// topApps container item, which no space lists
+type arcFavorites struct {
+	profile   string // Profile directory name, as getProfileName returns it
+	container *types.ArcItem
+}
+
+// findArcFavorites returns the favorites containers in items, in Arc order
+func findArcFavorites(items []*types.ArcItem) []arcFavorites {
+	var favorites []arcFavorites
+	for _, item := range items {
+		if item.Data == nil || item.Data.ItemContainer == nil {
+			continue
+		}
+		if profile, ok := topAppsProfile(item.Data.ItemContainer.ContainerType); ok {
+			favorites = append(favorites, arcFavorites{profile: profile, container: item})
+		}
+	}
+	return favorites
+}
+
+// topAppsProfile reads the profile of a topApps container type:
+// {"topApps": {"_0": {"default": {}}}} or
+// {"topApps": {"_0": {"custom": {"_0": {"directoryBasename": "Profile 1"}}}}}
+func topAppsProfile(containerType interface{}) (string, bool) {
+	typed, ok := containerType.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	topApps, ok := typed["topApps"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	profile, _ := topApps["_0"].(map[string]interface{})
+	custom, _ := profile["custom"].(map[string]interface{})
+	data, _ := custom["_0"].(map[string]interface{})
+	if name, _ := data["directoryBasename"].(string); name != "" {
+		return name, true
+	}
+	return "default", true
+}
+
+// importFavorites adds the Arc favorites of the imported profiles to
+// zenSession as essential tabs, shared by every workspace of the profile's
+// container (-favorites-as-essentials). Favorites whose URL is already an
+// essential of that container are skipped, so importing again adds nothing.
+// It returns the number of essentials created.
+func (imp *Importer) importFavorites(
+	ctx context.Context,
+	favorites []arcFavorites,
+	profiles map[string]*ProfileInfo,
+	itemsMap map[string]*types.ArcItem,
+	arcToZenUUIDMap map[string]string,
+	zenSession *types.ZenSession,
+	now int64,
+) int {
+	existing := essentialURLs(zenSession)
+	created := 0
+	for _, favorite := range favorites {
+		profile := profiles[favorite.profile]
+		if profile == nil {
+			imp.debug("Skipping the favorites of Arc profile \"%s\" (none of its spaces are imported)", favorite.profile)
+			continue
+		}
+		imp.logger.Info("Importing the favorites of Arc profile \"%s\" as essentials", profile.DisplayName)
+		for _, childID := range favorite.container.ChildrenIds {
+			if child := itemsMap[childID]; child != nil {
+				if isArcFolder(child) {
+					imp.warnings.Add(WarningMapping, arcItemTitle(child), "favorites folder %s not imported (Zen essentials can't hold folders)", child.ID)
+					continue
+				}
+				created += imp.addEssential(ctx, child, profile.ContainerID, existing, itemsMap, arcToZenUUIDMap, zenSession, now)
+			}
+		}
+	}
+	return created
+}
+
+// essentialURLs maps each container to the URLs of its essentials in zenSession
+func essentialURLs(zenSession *types.ZenSession) map[int]map[string]bool {
+	existing := make(map[int]map[string]bool)
+	for _, tab := range zenSession.Tabs {
+		if !tab.ZenEssential {
+			continue
+		}
+		if existing[tab.UserContextID] == nil {
+			existing[tab.UserContextID] = make(map[string]bool)
+		}
+		for _, entry := range tab.Entries {
+			existing[tab.UserContextID][entry.URL] = true
+		}
+	}
+	return existing
+}
+
+// addEssential creates the Arc tab item as an essential of containerID,
+// unless existing (see essentialURLs) says it already is one. It returns the
+// number of tabs created.
+func (imp *Importer) addEssential(
+	ctx context.Context,
+	item *types.ArcItem,
+	containerID int,
+	existing map[int]map[string]bool,
+	itemsMap map[string]*types.ArcItem,
+	arcToZenUUIDMap map[string]string,
+	zenSession *types.ZenSession,
+	now int64,
+) int {
+	if item.Data != nil && item.Data.Tab != nil && existing[containerID][item.Data.Tab.SavedURL] {
+		title := arcItemTitle(item)
+		imp.debug("Skipping \"%s\" (%s)", title, alreadyEssentialReason)
+		imp.plan.addSkipped(SkippedItem{ID: item.ID, Title: title, Reason: alreadyEssentialReason})
+		return 0
+	}
+
+	first, firstPlanned := len(zenSession.Tabs), len(imp.plan.Tabs)
+	created := imp.insertItemWithChildren(
+		ctx, item, "", "", map[string]string{}, nil, containerID,
+		itemsMap, arcToZenUUIDMap, zenSession, now, 1,
+		map[string]string{},
+	)
+	for i := first; i < len(zenSession.Tabs); i++ {
+		zenSession.Tabs[i].ZenEssential = true
+	}
+	for i := firstPlanned; i < len(imp.plan.Tabs); i++ {
+		imp.plan.Tabs[i].Essential = true
+	}
+	return created
+}
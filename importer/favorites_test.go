@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_FavoritesAsEssentials(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Home", "containerIDs": ["t1"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}},
+				{"id": "top", "childrenIds": ["e1", "e2", "ef"], "data": {"itemContainer": {"containerType": {"topApps": {"_0": {"default": {}}}}}}},
+				{"id": "e1", "parentID": "top", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "e2", "parentID": "top", "childrenIds": [], "data": {"tab": {"savedTitle": "Calendar", "savedURL": "%[1]s/calendar"}}},
+				{"id": "ef", "parentID": "top", "title": "Nested", "childrenIds": ["e3"], "data": {}},
+				{"id": "e3", "parentID": "ef", "childrenIds": [], "data": {"tab": {"savedTitle": "Chat", "savedURL": "%[1]s/chat"}}},
+				{"id": "top2", "childrenIds": ["e4"], "data": {"itemContainer": {"containerType": {"topApps": {"_0": {"custom": {"_0": {"directoryBasename": "Profile 9"}}}}}}}},
+				{"id": "e4", "parentID": "top2", "childrenIds": [], "data": {"tab": {"savedTitle": "Other", "savedURL": "%[1]s/other"}}}
+			]
+		}]}
+	}`, testSite)
+
+	essentials := func(session *types.ZenSession) []string {
+		var titles []string
+		for _, tab := range session.Tabs {
+			if tab.ZenEssential {
+				if tab.ZenWorkspace != "" || !tab.Pinned {
+					t.Errorf("expected %q pinned and in no workspace", tab.ZenStaticLabel)
+				}
+				titles = append(titles, tab.ZenStaticLabel)
+			}
+		}
+		return titles
+	}
+
+	// Off by default: the favorites bar is not part of any space
+	session := emptySession()
+	if _, err := newTestImporter(t, ImportOptions{}).doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if got := essentials(session); len(got) != 0 || len(session.Tabs) != 1 {
+		t.Fatalf("expected no essentials, got %v", got)
+	}
+
+	imp := newTestImporter(t, ImportOptions{FavoritesAsEssentials: true})
+	session = emptySession()
+	result, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Profile 9 has no imported space, and essentials can't hold folders
+	if got := fmt.Sprint(essentials(session)); got != "[Mail Calendar]" {
+		t.Errorf("unexpected essentials %s", got)
+	}
+	planned := 0
+	for _, tab := range result.Plan.Tabs {
+		if tab.Essential {
+			planned++
+		}
+	}
+	if planned != 2 {
+		t.Errorf("expected 2 essentials in the plan, got %d", planned)
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the essentials to restore, got %v", report.Issues)
+	}
+
+	// Importing again doesn't duplicate them
+	result, err = newTestImporter(t, ImportOptions{FavoritesAsEssentials: true}).doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := essentials(session); len(got) != 2 || result.Plan.countSkipped(alreadyEssentialReason) != 2 {
+		t.Errorf("expected the essentials kept once, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+
+	"arc-to-zen/types"
+)
+
+// defaultFolderModeWorkspace names the workspace -mode folders creates when
+// the session has none yet
+const defaultFolderModeWorkspace = "Arc"
+
+// validateMode checks ImportOptions.Mode and the options it rules out
+func (imp *Importer) validateMode() error {
+	switch imp.options.Mode {
+	case "", ModeWorkspaces:
+		return nil
+	case ModeFolders:
+		if len(imp.options.CombineSpaces) > 0 || len(imp.options.SplitSpaces) > 0 {
+			return fmt.Errorf("-mode folders imports every space into one workspace; it can't be used with -combine or -split-space")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid -mode value %q (expected %q or %q)", imp.options.Mode, ModeWorkspaces, ModeFolders)
+	}
+}
+
+// folderModeWorkspace is the workspace -mode folders imports into: the first
+// in Zen's sidebar, the one a profile without workspaces in use shows
+func folderModeWorkspace(zenSession *types.ZenSession) string {
+	if len(zenSession.Spaces) == 0 {
+		return defaultFolderModeWorkspace
+	}
+	spaces := append([]types.ZenSpace(nil), zenSession.Spaces...)
+	sort.SliceStable(spaces, func(i, j int) bool { return spaces[i].Position < spaces[j].Position })
+	return spaces[0].Name
+}
+
+// removeRootFolders removes the top-level folders of workspace named in
+// names, with everything in them, so importing with -mode folders again
+// replaces the folders of the earlier import but keeps the workspace's
+// other pins
+func removeRootFolders(zenSession *types.ZenSession, workspace string, names map[string]bool) {
+	removed := make(map[string]bool)
+	for _, folder := range zenSession.Folders {
+		if folder.WorkspaceID == workspace && folder.ParentID == "" && names[folder.Name] {
+			removed[folder.ID] = true
+		}
+	}
+	// Nested folders may come before their parent
+	for grew := len(removed) > 0; grew; {
+		grew = false
+		for _, folder := range zenSession.Folders {
+			if !removed[folder.ID] && removed[folder.ParentID] {
+				removed[folder.ID] = true
+				grew = true
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	folders := zenSession.Folders[:0]
+	for _, folder := range zenSession.Folders {
+		if !removed[folder.ID] {
+			folders = append(folders, folder)
+		}
+	}
+	zenSession.Folders = folders
+	tabs := zenSession.Tabs[:0]
+	for _, tab := range zenSession.Tabs {
+		if !removed[tab.GroupID] {
+			tabs = append(tabs, tab)
+		}
+	}
+	zenSession.Tabs = tabs
+}
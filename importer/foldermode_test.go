@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_ModeFolders(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Home", "containerIDs": ["t1"]},
+				{"id": "s2", "title": "Work", "containerIDs": ["f1"]}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}},
+				{"id": "f1", "title": "Docs", "childrenIds": ["t2"], "data": {}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}}
+			]
+		}]}
+	}`, testSite)
+
+	session := emptySession()
+	session.Spaces = []types.ZenSpace{
+		{UUID: "{other}", Name: "Other", Icon: "O", Position: 2000},
+		{UUID: "{main}", Name: "Main", Icon: "M", Position: 1000},
+	}
+	session.Tabs = []types.ZenTab{{Pinned: true, ZenWorkspace: "{main}", ZenStaticLabel: "Native",
+		Entries: []types.ZenTabEntry{{URL: "https://native.test/"}}}}
+
+	describe := func() string {
+		folders := make(map[string]string)
+		for _, folder := range session.Folders {
+			if folder.WorkspaceID != "{main}" {
+				t.Errorf("folder %q outside the first workspace", folder.Name)
+			}
+			folders[folder.ID] = folder.Name
+			if folder.ParentID != "" {
+				folders[folder.ID] = folders[folder.ParentID] + "/" + folder.Name
+			}
+		}
+		var tabs []string
+		for _, tab := range session.Tabs {
+			if !tab.ZenIsEmpty {
+				tabs = append(tabs, tab.ZenStaticLabel+"@"+folders[tab.GroupID])
+			}
+		}
+		return strings.Join(tabs, ",")
+	}
+
+	// Importing twice replaces the folders of the first import, not the native pin
+	for run := 0; run < 2; run++ {
+		imp := newTestImporter(t, ImportOptions{Mode: ModeFolders})
+		if err := imp.validateOptions(); err != nil {
+			t.Fatal(err)
+		}
+		result, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := describe(); got != "Native@,News@Home,Spec@Work/Docs" {
+			t.Fatalf("run %d: unexpected tabs %s", run+1, got)
+		}
+		if len(session.Spaces) != 2 || session.Spaces[1].Icon != "M" {
+			t.Errorf("run %d: expected the workspaces untouched, got %+v", run+1, session.Spaces)
+		}
+		if space := result.Plan.Spaces[0]; !space.Merged || !space.KeptPins || space.Name != "Main" {
+			t.Errorf("run %d: unexpected planned space %+v", run+1, space)
+		}
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the folders to restore, got %v", report.Issues)
+	}
+
+	imp := newTestImporter(t, ImportOptions{Mode: ModeFolders, SplitSpaces: map[string]int{"Work": 10}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected -mode folders with -split-space to be rejected")
+	}
+}
@@ -0,0 +1,316 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/restoresim"
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/golden files")
+
+// sessionSample describes a sample in testdata/sessions/samples.json
+type sessionSample struct {
+	Source string `json:"source"`        // "captured" from a Zen release, or "synthetic"
+	Zen    string `json:"zen,omitempty"` // Release a captured sample came from (compatibility.ini LastVersion)
+	Models string `json:"models"`        // What the sample covers
+}
+
+// loadSessionSamples reads samples.json and checks that every sample is
+// listed, and every captured one labeled with the Zen release it came from
+func loadSessionSamples(t *testing.T, samples []string) map[string]sessionSample {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "sessions", "samples.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var described map[string]sessionSample
+	if err := json.Unmarshal(data, &described); err != nil {
+		t.Fatalf("invalid samples.json: %v", err)
+	}
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".jsonlz4")
+		info, ok := described[name]
+		switch {
+		case !ok:
+			t.Errorf("%s is not described in samples.json", name)
+		case info.Source == "captured":
+			if _, err := parseZenVersion(info.Zen); err != nil {
+				t.Errorf("captured sample %s needs the Zen release it came from: %v", name, err)
+			}
+		case info.Source != "synthetic":
+			t.Errorf("sample %s: source must be \"captured\" or \"synthetic\", got %q", name, info.Source)
+		}
+	}
+	return described
+}
+
+// TestGoldenSessions reads every sample in testdata/sessions, checks it round-trips
+// without losing fields, merges the multi-profile Arc data into it and compares
+// the result with testdata/golden/<sample>.txt. Run with -update after an
+// intended change.
+func TestGoldenSessions(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.jsonlz4"))
+	if err != nil || len(samples) == 0 {
+		t.Fatalf("no session samples found: %v", err)
+	}
+	described := loadSessionSamples(t, samples)
+
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".jsonlz4")
+		label := name
+		if info := described[name]; info.Source == "captured" {
+			label += "@zen-" + info.Zen
+		}
+		t.Run(label, func(t *testing.T) {
+			raw, err := os.ReadFile(sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := decodeGeneric(t, raw)
+
+			imp := newTestImporter(t, ImportOptions{})
+			if err := os.WriteFile(filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4"), raw, 0644); err != nil {
+				t.Fatal(err)
+			}
+			session, err := imp.readZenSession()
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+
+			// Round-trip without changes
+			encoded, err := sessionfile.Encode(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundTripped := decodeGeneric(t, encoded)
+			if lost := lostFields(original, roundTripped, ""); len(lost) > 0 {
+				t.Errorf("round-trip lost fields: %s", strings.Join(lost, ", "))
+			}
+			var added []string
+			for _, field := range lostFields(roundTripped, original, "") {
+				switch field {
+				case ".folders", ".groups", ".splitViewData": // Filled in by readZenSession
+				default:
+					added = append(added, field)
+				}
+			}
+			if len(added) > 0 {
+				t.Errorf("round-trip added fields: %s", strings.Join(added, ", "))
+			}
+
+			// Merge an import into it
+			result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, &types.ContainersData{Version: 5})
+			if err != nil {
+				t.Fatalf("merge failed: %v", err)
+			}
+			encoded, err = sessionfile.Encode(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged := decodeGeneric(t, encoded)
+			forgetMergedSpaceFields(original, result.Plan)
+			if lost := lostFields(original, merged, ""); len(lost) > 0 {
+				t.Errorf("merge lost fields of untouched entries: %s", strings.Join(lost, ", "))
+			}
+			for _, key := range []string{"spaces", "tabs", "folders", "groups", "splitViewData"} {
+				if _, ok := merged.(map[string]interface{})[key].([]interface{}); !ok {
+					t.Errorf("%s is not an array after merge", key)
+				}
+			}
+
+			var workspaces []string
+			for _, space := range result.Plan.Spaces {
+				workspaces = append(workspaces, space.ID)
+			}
+			if issues := restoresim.Simulate(session).ForWorkspaces(workspaces); len(issues) > 0 {
+				t.Errorf("merged session would not restore cleanly: %v", issues)
+			}
+
+			golden := filepath.Join("testdata", "golden", name+".txt")
+			got := describeSession(session)
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("merged session differs from %s:\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+// forgetMergedSpaceFields drops the fields a merge rewrites on purpose (icon and
+// container of workspaces imported into) from a decoded session
+func forgetMergedSpaceFields(session interface{}, plan *Plan) {
+	merged := make(map[string]bool)
+	for _, space := range plan.Spaces {
+		merged[space.ID] = space.Merged
+	}
+	spaces, _ := session.(map[string]interface{})["spaces"].([]interface{})
+	for _, space := range spaces {
+		if m, ok := space.(map[string]interface{}); ok && merged[fmt.Sprint(m["uuid"])] {
+			delete(m, "icon")
+			delete(m, "containerTabId")
+		}
+	}
+}
+
+func decodeGeneric(t *testing.T, compressed []byte) interface{} {
+	t.Helper()
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// entryKeys identify array entries, so entries can be matched after a merge
+// reorders or removes some of them
+var entryKeys = []string{"uuid", "zenSyncId", "id"}
+
+// lostFields lists the paths present in want whose value is missing or different
+// in got. Array entries with an ID that no longer exist in got are skipped (the
+// merge replaced them); any that remain must be intact.
+func lostFields(want, got interface{}, path string) []string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{path}
+		}
+		var lost []string
+		for key, value := range w {
+			child, exists := g[key]
+			if !exists {
+				lost = append(lost, path+"."+key)
+				continue
+			}
+			lost = append(lost, lostFields(value, child, path+"."+key)...)
+		}
+		sort.Strings(lost)
+		return lost
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return []string{path}
+		}
+		byID := make(map[string]interface{})
+		for _, entry := range g {
+			if id := entryID(entry); id != "" {
+				byID[id] = entry
+			}
+		}
+		var lost []string
+		for i, entry := range w {
+			if id := entryID(entry); id != "" {
+				if match, exists := byID[id]; exists {
+					lost = append(lost, lostFields(entry, match, fmt.Sprintf("%s[%s]", path, id))...)
+				}
+				continue
+			}
+			if i >= len(g) {
+				lost = append(lost, fmt.Sprintf("%s[%d]", path, i))
+				continue
+			}
+			lost = append(lost, lostFields(entry, g[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return lost
+	default:
+		if fmt.Sprint(want) != fmt.Sprint(got) {
+			return []string{path}
+		}
+		return nil
+	}
+}
+
+func entryID(entry interface{}) string {
+	m, ok := entry.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, key := range entryKeys {
+		if id, ok := m[key].(string); ok && id != "" {
+			return key + "=" + id
+		}
+	}
+	return ""
+}
+
+// describeSession renders the pinned structure without generated IDs
+func describeSession(session *types.ZenSession) string {
+	folderNames := make(map[string]string)
+	for _, folder := range session.Folders {
+		folderNames[folder.ID] = folder.Name
+	}
+
+	var b strings.Builder
+	for _, space := range session.Spaces {
+		fmt.Fprintf(&b, "space %q container=%d position=%d\n", space.Name, space.ContainerTabID, space.Position)
+		for _, folder := range session.Folders {
+			if folder.WorkspaceID != space.UUID {
+				continue
+			}
+			fmt.Fprintf(&b, "  folder %q", folder.Name)
+			if folder.ParentID != "" {
+				fmt.Fprintf(&b, " in %q", folderNames[folder.ParentID])
+			}
+			if prevKind, prevID := siblingOf(folder.PrevSiblingInfo); prevKind != "" {
+				fmt.Fprintf(&b, " after %s %q", prevKind, folderNames[prevID])
+			}
+			fmt.Fprintf(&b, " placeholders=%d\n", len(folder.EmptyTabIDs))
+		}
+		for _, tab := range session.Tabs {
+			if tab.ZenWorkspace != space.UUID {
+				continue
+			}
+			label := tab.ZenStaticLabel
+			if tab.ZenIsEmpty {
+				label = "(placeholder)"
+			}
+			fmt.Fprintf(&b, "  tab %q", label)
+			if tab.GroupID != "" {
+				fmt.Fprintf(&b, " in %q", folderNames[tab.GroupID])
+			}
+			if tab.ZenEssential {
+				b.WriteString(" essential")
+			}
+			fmt.Fprintf(&b, " container=%d\n", tab.UserContextID)
+		}
+	}
+	fmt.Fprintf(&b, "groups=%d splitViews=%d\n", len(session.Groups), len(session.SplitViewData))
+	return b.String()
+}
+
+func siblingOf(info interface{}) (kind, id string) {
+	m, ok := info.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	kind, _ = m["type"].(string)
+	id, _ = m["id"].(string)
+	return kind, id
+}
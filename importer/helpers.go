@@ -1,39 +1,60 @@
 package importer
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
+	"arc-to-zen/containers"
 	"arc-to-zen/mappings"
 	"arc-to-zen/types"
 )
 
-// parseArcSpaces converts interface{} slice to ArcSpace slice
-func parseArcSpaces(rawSpaces []interface{}) ([]*types.ArcSpace, error) {
-	var spaces []*types.ArcSpace
+// rawEntryID reads the "id" of a raw spaces or items entry. ok is false for
+// anything but an object; hasID is false for an object without an id.
+func rawEntryID(raw json.RawMessage) (id string, ok, hasID bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return "", false, false
+	}
+	var entry struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(trimmed, &entry); err != nil || entry.ID == nil {
+		return "", true, false
+	}
+	if err := json.Unmarshal(entry.ID, &id); err != nil {
+		id = string(entry.ID) // A non-string ID; the struct won't parse, but name it
+	}
+	return id, true, true
+}
 
-	for _, raw := range rawSpaces {
-		// Skip if not an object
-		objMap, ok := raw.(map[string]interface{})
-		if !ok {
-			continue
-		}
+// isRawString reports whether raw is a JSON string, like the IDs Arc lists
+// between the objects
+func isRawString(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '"'
+}
 
-		// Check if it has an ID (required for valid space)
-		if _, hasID := objMap["id"]; !hasID {
-			continue
-		}
+// parseArcSpaces parses the space objects of a container's raw entries
+func parseArcSpaces(rawSpaces []json.RawMessage, warnings *Warnings) ([]*types.ArcSpace, error) {
+	var spaces []*types.ArcSpace
 
-		// Marshal back to JSON and unmarshal to struct
-		jsonData, err := json.Marshal(objMap)
-		if err != nil {
+	for _, raw := range rawSpaces {
+		// Skip anything but objects with an ID (required for valid space)
+		id, ok, hasID := rawEntryID(raw)
+		if !ok || !hasID {
 			continue
 		}
 
 		var space types.ArcSpace
-		if err := json.Unmarshal(jsonData, &space); err != nil {
+		if err := json.Unmarshal(raw, &space); err != nil {
+			warnings.Add(WarningParse, id, "could not parse space: %v", err)
 			continue
 		}
 
@@ -43,55 +64,113 @@ func parseArcSpaces(rawSpaces []interface{}) ([]*types.ArcSpace, error) {
 	return spaces, nil
 }
 
-// parseArcItems converts interface{} slice to ArcItem slice
-func parseArcItems(rawItems []interface{}) ([]*types.ArcItem, error) {
+// parseArcItems parses the item objects of a container's raw entries. It
+// also returns the entries it had to drop. Arc interleaves item IDs with
+// the items, so plain strings are not reported.
+func parseArcItems(rawItems []json.RawMessage, warnings *Warnings) ([]*types.ArcItem, []UnparsedItem, error) {
 	var items []*types.ArcItem
+	var unparsed []UnparsedItem
 
 	for _, raw := range rawItems {
-		// Skip if not an object
-		objMap, ok := raw.(map[string]interface{})
+		id, ok, hasID := rawEntryID(raw)
 		if !ok {
+			if !isRawString(raw) {
+				unparsed = append(unparsed, UnparsedItem{Reason: "not an object", Raw: raw})
+			}
 			continue
 		}
 
 		// Check if it has an ID (required for valid item)
-		if _, hasID := objMap["id"]; !hasID {
-			continue
-		}
-
-		// Marshal back to JSON and unmarshal to struct
-		jsonData, err := json.Marshal(objMap)
-		if err != nil {
+		if !hasID {
+			warnings.Add(WarningParse, "", "skipped an item without an id")
+			unparsed = append(unparsed, UnparsedItem{Reason: "no id", Raw: raw})
 			continue
 		}
 
 		var item types.ArcItem
-		if err := json.Unmarshal(jsonData, &item); err != nil {
+		if err := json.Unmarshal(raw, &item); err != nil {
+			warnings.Add(WarningParse, id, "could not parse item: %v", err)
+			unparsed = append(unparsed, UnparsedItem{ID: id, Reason: err.Error(), Raw: raw})
 			continue
 		}
 
 		items = append(items, &item)
 	}
 
-	return items, nil
+	return items, unparsed, nil
 }
 
+// Firefox hands out internal userContextIds from the top of the 32-bit range
+// down (userContextIdInternal.thumbnail is 4294967295, webextStorageLocal
+// 4294967294), so new containers stay below this
+const firstReservedContainerID = 1<<32 - 256
+
+// ErrNoContainerID is returned when every userContextId below the reserved
+// range is taken
+var ErrNoContainerID = errors.New("no free userContextId left for a new container")
+
 // calculateNextContainerID finds the next available container ID
-func calculateNextContainerID(containersData *types.ContainersData) int {
+// Firefox's internal containers (public: false) sit at the top of the ID
+// range, so they don't count and their IDs are never handed out. IDs
+// normally only grow, like Firefox's own; once they would reach the
+// reserved range, the lowest ID left by a deleted container is reused, as
+// long as no tab or workspace of the session still uses it.
+func calculateNextContainerID(containersData *types.ContainersData, session *types.ZenSession) (int, error) {
+	used := make(map[int]bool)
+	internal := make(map[int]bool)
+	for _, container := range containersData.Identities {
+		if container.HasValidUserContextID() {
+			used[container.GetUserContextID()] = true
+			if !container.Public {
+				internal[container.GetUserContextID()] = true
+			}
+		}
+	}
+
 	// Start with lastUserContextId if available
 	maxID := 0
-	if containersData.LastUserContextID != nil {
-		maxID = *containersData.LastUserContextID
+	if last := containersData.LastUserContextID; last != nil && !internal[*last] && *last < firstReservedContainerID {
+		maxID = *last
 	}
 	
 	// Also check all existing containers to be safe
 	for _, container := range containersData.Identities {
 		id := container.GetUserContextID()
-		if id != maxUint32 && id > maxID {
+		if container.Public && !internal[id] && id > maxID && id < firstReservedContainerID {
 			maxID = id
 		}
 	}
-	return maxID + 1
+	next := maxID + 1
+	for used[next] {
+		next++
+	}
+	if next < firstReservedContainerID {
+		return next, nil
+	}
+
+	// Out of fresh IDs: look for a gap no one refers to any more
+	if session != nil {
+		for _, tab := range session.Tabs {
+			used[tab.UserContextID] = true
+		}
+		for _, space := range session.Spaces {
+			used[space.ContainerTabID] = true
+		}
+	}
+	for id := 1; id < firstReservedContainerID; id++ {
+		if !used[id] {
+			return id, nil
+		}
+	}
+	return 0, ErrNoContainerID
+}
+
+// recordContainerID updates lastUserContextId for a new container. It never
+// goes down, so a reused gap doesn't make Firefox hand out taken IDs.
+func recordContainerID(containersData *types.ContainersData, id int) {
+	if last := containersData.LastUserContextID; last == nil || *last < id {
+		containersData.LastUserContextID = &id
+	}
 }
 
 // findSpaceByName finds a space by its name
@@ -104,6 +183,40 @@ func findSpaceByName(spaces []types.ZenSpace, name string) *types.ZenSpace {
 	return nil
 }
 
+// uniqueSpaceNames returns the Zen workspace name for each Arc space ID.
+// Repeated titles get a " (2)", " (3)", ... suffix in Arc order, which keeps
+// the names stable across re-imports.
+func uniqueSpaceNames(spaces []*types.ArcSpace, warnings *Warnings) map[string]string {
+	names := make(map[string]string)
+	used := make(map[string]bool)
+	for _, space := range spaces {
+		used[space.Title] = true
+	}
+
+	taken := make(map[string]bool)
+	for _, space := range spaces {
+		name := space.Title
+		if name == "" {
+			name = fmt.Sprintf("Workspace %s", space.ID)
+		}
+
+		if taken[name] {
+			base := name
+			for n := 2; ; n++ {
+				name = fmt.Sprintf("%s (%d)", base, n)
+				if !taken[name] && !used[name] {
+					break
+				}
+			}
+			warnings.Add(WarningMapping, base, "duplicate space title, importing space %s as %q", space.ID, name)
+		}
+
+		taken[name] = true
+		names[space.ID] = name
+	}
+	return names
+}
+
 // findContainerByName finds a container by its name (only returns containers with valid userContextId)
 func findContainerByName(containers []types.ContainerIdentity, name string) *types.ContainerIdentity {
 	for i := range containers {
@@ -114,34 +227,48 @@ func findContainerByName(containers []types.ContainerIdentity, name string) *typ
 	return nil
 }
 
-// updateContainer updates an existing container's properties
-func updateContainer(containers []types.ContainerIdentity, containerID int, name, icon, color string) {
+// updateContainer gives an existing container the icon of an Arc icon and a
+// container color, leaving its name alone. An unmapped icon or an empty color
+// keeps what the container has. It reports whether anything changed.
+func updateContainer(containers []types.ContainerIdentity, containerID int, icon, color string) bool {
 	for i := range containers {
-		if containers[i].GetUserContextID() == containerID {
-			containers[i].Name = name
-			containers[i].Icon = mappings.MapArcIconToContainerIcon(icon)
-			containers[i].Color = mappings.MapArcColorToZen(color)
-			break
+		if containers[i].GetUserContextID() != containerID {
+			continue
+		}
+		changed := false
+		if mappings.IsMappedArcContainerIcon(icon) {
+			if mapped := mappings.MapArcIconToContainerIcon(icon); containers[i].Icon != mapped {
+				containers[i].Icon = mapped
+				changed = true
+			}
+		}
+		if color != "" && containers[i].Color != color {
+			containers[i].Color = color
+			changed = true
 		}
+		return changed
 	}
+	return false
 }
 
-// filterTabs removes tabs for a specific workspace that are pinned
-func filterTabs(tabs []types.ZenTab, workspaceUUID string) []types.ZenTab {
-	var filtered []types.ZenTab
+// filterTabs removes the pinned tabs of the given workspaces in one pass,
+// reusing tabs' backing array
+func filterTabs(tabs []types.ZenTab, workspaces map[string]bool) []types.ZenTab {
+	filtered := tabs[:0]
 	for _, tab := range tabs {
-		if tab.ZenWorkspace != workspaceUUID || !tab.Pinned {
+		if !tab.Pinned || !workspaces[tab.ZenWorkspace] {
 			filtered = append(filtered, tab)
 		}
 	}
 	return filtered
 }
 
-// filterFolders removes folders for a specific workspace
-func filterFolders(folders []types.ZenFolder, workspaceID string) []types.ZenFolder {
-	var filtered []types.ZenFolder
+// filterFolders removes the folders of the given workspaces in one pass,
+// reusing folders' backing array
+func filterFolders(folders []types.ZenFolder, workspaces map[string]bool) []types.ZenFolder {
+	filtered := folders[:0]
 	for _, folder := range folders {
-		if folder.WorkspaceID != workspaceID {
+		if !workspaces[folder.WorkspaceID] {
 			filtered = append(filtered, folder)
 		}
 	}
@@ -206,17 +333,18 @@ func getProfileName(space *types.ArcSpace) string {
 // ProfileInfo holds information about a unique profile
 type ProfileInfo struct {
 	Name        string // Profile directory name (e.g., "Profile 1")
-	DisplayName string // First space that uses this profile (for container name)
+	DisplayName string // Arc profile name (for container name), falling back to the directory name
 	ContainerID int    // Zen container ID
 	Icon        string // Icon from first space
-	Color       string // Color from first space
+	Color       string // Container color from the first space's theme
+	ArcColor    bool   // Color comes from Arc rather than the rotation
 }
 
 // Firefox container colors
 var containerColors = []string{"blue", "turquoise", "green", "yellow", "orange", "red", "pink", "purple"}
 
-// collectUniqueProfiles finds all unique profiles and their first associated space
-func collectUniqueProfiles(spaces []*types.ArcSpace) map[string]*ProfileInfo {
+// collectUniqueProfiles finds all unique profiles, named from Arc's profile metadata
+func collectUniqueProfiles(spaces []*types.ArcSpace, profileNames map[string]string) map[string]*ProfileInfo {
 	profiles := make(map[string]*ProfileInfo)
 	colorIndex := 0
 	
@@ -234,20 +362,26 @@ func collectUniqueProfiles(spaces []*types.ArcSpace) map[string]*ProfileInfo {
 				icon = space.Icon
 			}
 			
-			displayName := space.Title
+			dir := profileDirectory(profileName)
+			displayName := profileNames[dir]
 			if displayName == "" {
-				displayName = profileName
+				displayName = dir
 			}
 			
-			// Arc doesn't have simple color names, so we rotate through colors
-			color := containerColors[colorIndex%len(containerColors)]
-			colorIndex++
+			// Spaces without a theme color or color name rotate through the colors
+			color := spaceContainerColor(space)
+			arcColor := color != ""
+			if color == "" {
+				color = containerColors[colorIndex%len(containerColors)]
+				colorIndex++
+			}
 			
 			profiles[profileName] = &ProfileInfo{
 				Name:        profileName,
 				DisplayName: displayName,
 				Icon:        icon,
 				Color:       color,
+				ArcColor:    arcColor,
 			}
 		}
 	}
@@ -255,6 +389,169 @@ func collectUniqueProfiles(spaces []*types.ArcSpace) map[string]*ProfileInfo {
 	return profiles
 }
 
+// profileDirectory returns the Arc "User Data" directory name for a profile
+func profileDirectory(profileName string) string {
+	if profileName == "default" {
+		return "Default"
+	}
+	return profileName
+}
+
+// filterSpacesByProfile returns the spaces whose Arc profile matches profile by
+// directory name or display name (case-insensitive)
+func filterSpacesByProfile(spaces []*types.ArcSpace, profile string, profileNames map[string]string) []*types.ArcSpace {
+	var selected []*types.ArcSpace
+	for _, space := range spaces {
+		dir := profileDirectory(getProfileName(space))
+		if strings.EqualFold(dir, profile) || strings.EqualFold(profileNames[dir], profile) {
+			selected = append(selected, space)
+		}
+	}
+	return selected
+}
+
+// describeProfiles lists the distinct profiles used by spaces, for error messages
+func describeProfiles(spaces []*types.ArcSpace, profileNames map[string]string) []string {
+	var descriptions []string
+	seen := make(map[string]bool)
+	for _, space := range spaces {
+		dir := profileDirectory(getProfileName(space))
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if name := profileNames[dir]; name != "" && name != dir {
+			descriptions = append(descriptions, fmt.Sprintf("%q (%s)", dir, name))
+		} else {
+			descriptions = append(descriptions, fmt.Sprintf("%q", dir))
+		}
+	}
+	return descriptions
+}
+
+// assignContainers resolves the Zen container for every unique profile, creating containers as needed
+func (imp *Importer) assignContainers(profiles map[string]*ProfileInfo, containersData *types.ContainersData, session *types.ZenSession) error {
+	// Sort so prompts and newly assigned IDs are stable between runs
+	profileNames := make([]string, 0, len(profiles))
+	for profileName := range profiles {
+		profileNames = append(profileNames, profileName)
+	}
+	sort.Strings(profileNames)
+
+	for _, profileName := range profileNames {
+		profile := profiles[profileName]
+
+		assignment, ok := imp.options.ProfileContainers[profileName]
+		if !ok && imp.options.AssignContainer != nil {
+			assignment = imp.options.AssignContainer(profile, usableContainers(containersData.Identities))
+		}
+
+		if assignment == ContainerNone {
+			profile.ContainerID = 0
+			if !imp.options.DryRun {
+				imp.logger.Info("Importing profile \"%s\" without a container", profileName)
+			} else {
+				imp.logger.Info("[DRY-RUN] Would import profile \"%s\" without a container", profileName)
+			}
+			continue
+		}
+
+		containerName := profile.DisplayName
+		if assignment != "" && assignment != ContainerNew {
+			containerName = assignment
+		}
+
+		// Check if container already exists for this profile
+		var existingContainer *types.ContainerIdentity
+		switch assignment {
+		case "":
+			existingContainer = findContainerByName(containersData.Identities, containerName)
+		case ContainerNew:
+		default:
+			found, err := containers.Find(containersData, assignment)
+			if errors.Is(err, containers.ErrAmbiguousName) {
+				return fmt.Errorf("profile %q: %w", profileName, err)
+			}
+			existingContainer = found
+			if found == nil {
+				imp.warnings.Add(WarningMapping, profileName, "container %q not found (creating a new one)", assignment)
+			}
+		}
+		if existingContainer != nil && existingContainer.HasValidUserContextID() {
+			profile.ContainerID = existingContainer.GetUserContextID()
+			if existingContainer.Name != "" {
+				containerName = existingContainer.Name
+			}
+			updated := false
+			if imp.options.UpdateContainers {
+				color := ""
+				if profile.ArcColor {
+					color = profile.Color
+				}
+				updated = updateContainer(containersData.Identities, profile.ContainerID, profile.Icon, color)
+			}
+			imp.plan.addContainer(PlannedContainer{ID: profile.ContainerID, Name: containerName, Profile: profile.DisplayName, Updated: updated})
+			if !imp.options.DryRun {
+				imp.logger.Info("Reusing existing container \"%s\" for profile \"%s\" (ID: %d)",
+					containerName, profileName, profile.ContainerID)
+			} else {
+				imp.logger.Info("[DRY-RUN] Would reuse container \"%s\" for profile \"%s\" (ID: %d)",
+					containerName, profileName, profile.ContainerID)
+			}
+			if updated && !imp.options.DryRun {
+				imp.logger.Info("Updated the icon and color of container \"%s\" from Arc", containerName)
+			} else if updated {
+				imp.logger.Info("[DRY-RUN] Would update the icon and color of container \"%s\" from Arc", containerName)
+			}
+			continue
+		}
+
+		if profile.Icon != "" && !mappings.IsMappedArcContainerIcon(profile.Icon) {
+			imp.warnings.Add(WarningMapping, profileName, "unmapped container icon %q (using default)", profile.Icon)
+		}
+
+		// Create new container for this profile
+		id, err := calculateNextContainerID(containersData, session)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", profileName, err)
+		}
+		profile.ContainerID = id
+
+		containersData.Identities = append(containersData.Identities, types.ContainerIdentity{
+			UserContextID: &profile.ContainerID,
+			Name:          containerName,
+			Icon:          mappings.MapArcIconToContainerIcon(profile.Icon),
+			Color:         profile.Color,
+			Public:        true,
+		})
+
+		// Update lastUserContextId
+		recordContainerID(containersData, profile.ContainerID)
+		imp.plan.addContainer(PlannedContainer{ID: profile.ContainerID, Name: containerName, Profile: profile.DisplayName, Created: true})
+
+		if !imp.options.DryRun {
+			imp.logger.Info("Created container \"%s\" for profile \"%s\" (ID: %d)",
+				containerName, profileName, profile.ContainerID)
+		} else {
+			imp.logger.Info("[DRY-RUN] Would create container \"%s\" for profile \"%s\" (ID: %d)",
+				containerName, profileName, profile.ContainerID)
+		}
+	}
+
+	return nil
+}
+
+// usableContainers returns the public containers that tabs can be assigned to
+func usableContainers(identities []types.ContainerIdentity) []types.ContainerIdentity {
+	var usable []types.ContainerIdentity
+	for _, container := range identities {
+		if container.Public && container.HasValidUserContextID() {
+			usable = append(usable, container)
+		}
+	}
+	return usable
+}
+
 // createDefaultSpace creates a synthetic default space containing all root-level items
 // This is used when Arc has no explicit spaces (user only uses default profile)
 func createDefaultSpace(items []*types.ArcItem) *types.ArcSpace {
@@ -296,6 +593,45 @@ func filterArcContainers(items []*types.ArcItem) []*types.ArcItem {
 	return filtered
 }
 
+// isArcFolder reports whether item is a folder: it has children, or is an
+// Arc list without any. Arc containers are not folders.
+func isArcFolder(item *types.ArcItem) bool {
+	if item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil {
+		return false
+	}
+	return len(item.ChildrenIds) > 0 || (item.Data != nil && item.Data.Tab == nil && item.Data.List != nil)
+}
+
+// isEmptyArcFolder reports whether item is a folder with nothing but folders
+// (and Arc containers) under it, at any depth
+func isEmptyArcFolder(item *types.ArcItem, itemsMap map[string]*types.ArcItem) bool {
+	if !isArcFolder(item) {
+		return false
+	}
+	seen := make(map[string]bool)
+	var empty func(item *types.ArcItem) bool
+	empty = func(item *types.ArcItem) bool {
+		if seen[item.ID] {
+			return true
+		}
+		seen[item.ID] = true
+		if item.Data != nil && item.Data.Tab != nil {
+			return false
+		}
+		isArcContainer := item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil
+		if !isArcContainer && !isArcFolder(item) {
+			return false // Not a tab Arc knows, but something to import
+		}
+		for _, childID := range item.ChildrenIds {
+			if child := itemsMap[childID]; child != nil && !empty(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return empty(item)
+}
+
 // getRootItemsForSpace gets the root-level items for a space
 func getRootItemsForSpace(space *types.ArcSpace, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
 	var rootItems []*types.ArcItem
@@ -346,14 +682,15 @@ func getTitleOrDefault(title, defaultTitle string) string {
 
 // insertItemWithChildren recursively inserts an item and its children
 func (imp *Importer) insertItemWithChildren(
+	ctx context.Context,
 	arcItem *types.ArcItem,
 	parentFolderID string,
 	spaceID string,
 	spaceUUIDMap map[string]string,
 	space *types.ArcSpace,
+	containerID int, // userContextId resolved from the space's Arc profile
 	itemsMap map[string]*types.ArcItem,
 	arcToZenUUIDMap map[string]string,
-	containersData *types.ContainersData,
 	zenSession *types.ZenSession,
 	now int64,
 	level int,
@@ -367,60 +704,111 @@ func (imp *Importer) insertItemWithChildren(
 	if isArcContainer {
 		imp.logger.Info("%sSkipping Arc container \"%s\"", indent, getTitleOrDefault(arcItem.Title, arcItem.ID))
 		// Arc containers at root level - process in normal order
-		for _, childID := range arcItem.ChildrenIds {
-			if child := itemsMap[childID]; child != nil {
+		for _, child := range imp.sortedChildren(arcItem, itemsMap) {
 			itemsCreated += imp.insertItemWithChildren(
-					child, parentFolderID, spaceID, spaceUUIDMap, space,
-					itemsMap, arcToZenUUIDMap, containersData, zenSession, now, level,
-					lastFolderByParent,
-				)
-			}
+				ctx, child, parentFolderID, spaceID, spaceUUIDMap, space, containerID,
+				itemsMap, arcToZenUUIDMap, zenSession, now, level,
+				lastFolderByParent,
+			)
 		}
 		return itemsCreated
 	}
 
 	// Get space info
 	workspaceUUID := spaceUUIDMap[spaceID]
-	spaceName := space.Title
-	if spaceName == "" {
-		spaceName = fmt.Sprintf("Workspace %s", spaceID)
-	}
-
-	// Find container ID
-	containerID := 0
-	for _, container := range containersData.Identities {
-		if container.Name == spaceName && container.HasValidUserContextID() {
-			containerID = container.GetUserContextID()
-			break
-		}
-	}
 
 	zenUUID := arcToZenUUIDMap[arcItem.ID]
 	isFolder := len(arcItem.ChildrenIds) > 0
 
-	// Get title and URL
-	title := arcItem.Title
-	if title == "" && arcItem.Data != nil && arcItem.Data.Tab != nil {
-		title = arcItem.Data.Tab.SavedTitle
-	}
-	if title == "" {
-		title = "Untitled"
-	}
+	// Get title and URL; items moved up out of a merged small folder carry its name
+	title := imp.titlePrefix[arcItem.ID] + arcItemTitle(arcItem)
 
 	url := ""
 	if arcItem.Data != nil && arcItem.Data.Tab != nil {
 		url = arcItem.Data.Tab.SavedURL
 	}
 
+	// Likely junk the user asked to leave out
+	if reason, excluded := imp.excluded[arcItem.ID]; excluded {
+		imp.debug("%sSkipping \"%s\" (%s)", indent, title, reason)
+		imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: reason})
+		return 0
+	}
+
+	// Folders without a single tab, per -empty-folders
+	if isEmptyArcFolder(arcItem, itemsMap) {
+		if imp.options.EmptyFolders != EmptyFolderKeep {
+			imp.logger.Info("%sSkipping empty folder \"%s\"", indent, title)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: emptyFolderReason})
+			return 0
+		}
+		isFolder = true // Even with no children at all
+	}
+
+	// Apply -only filter to root-level items (folder contents are always kept)
+	if parentFolderID == "" && imp.titlePrefix[arcItem.ID] == "" {
+		if (imp.options.Only == OnlyFolders && !isFolder) || (imp.options.Only == OnlyTabs && isFolder) {
+			imp.debug("%sSkipping \"%s\" (-only %s)", indent, title, imp.options.Only)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, Reason: "-only " + imp.options.Only})
+			return 0
+		}
+	}
+
+	// Folders merged by -merge-small-folders: their items go to the parent
+	if imp.inlined[arcItem.ID] {
+		imp.debug("%sMerging small folder \"%s\" into its parent", indent, title)
+		for _, child := range imp.sortedChildren(arcItem, itemsMap) {
+			itemsCreated += imp.insertItemWithChildren(
+				ctx, child, parentFolderID, spaceID, spaceUUIDMap, space, containerID,
+				itemsMap, arcToZenUUIDMap, zenSession, now, level,
+				lastFolderByParent,
+			)
+		}
+		return itemsCreated
+	}
+
+	// Tabs without a URL would restore as blank pins; apply the -empty-urls policy
+	isNote := false
+	if !isFolder && strings.TrimSpace(url) == "" {
+		problem := "no URL"
+		if arcItem.Data == nil || arcItem.Data.Tab == nil {
+			problem = "unknown Arc item type"
+		}
+		switch imp.options.EmptyURLs {
+		case EmptyURLKeep:
+			url = "about:blank"
+			imp.warnings.Add(WarningMapping, title, "%s for %s (kept as an about:blank placeholder)", problem, arcItem.ID)
+		case EmptyURLNote:
+			isFolder = true
+			isNote = true
+			imp.warnings.Add(WarningMapping, title, "%s for %s (imported as an empty folder)", problem, arcItem.ID)
+		default:
+			imp.logger.Info("%sSkipping \"%s\" (%s)", indent, title, problem)
+			imp.warnings.Add(WarningMapping, title, "%s for %s (skipped; use -empty-urls keep or note to import it)", problem, arcItem.ID)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: problem})
+			return 0
+		}
+	}
+
 	if isFolder {
+		transformed := imp.transformFolder(PlannedFolder{Name: title, SpaceID: workspaceUUID, ParentID: parentFolderID, ArcID: arcItem.ID})
+		if transformed == nil {
+			imp.debug("%sSkipping \"%s\" (%s)", indent, title, transformReason)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: transformReason})
+			return 0
+		}
+		title = transformed.Name
+
 		if !imp.options.DryRun {
 			imp.logger.Info("%sCreating \"%s\" (FOLDER)", indent, title)
+		} else if isNote {
+			imp.logger.Info("%s[DRY-RUN] Would create empty folder for tab without URL: \"%s\"", indent, title)
 		} else {
 			imp.logger.Info("%s[DRY-RUN] Would create folder: \"%s\"", indent, title)
 		}
 
 		// Create folder
-		folderID := fmt.Sprintf("%d-%d", now, len(zenSession.Folders))
+		folderID := fmt.Sprintf("%d-%d", now, imp.folderSeq.Add(1)-1)
 
 		// Create an anchor tab for this folder - required for Firefox to create the tab-group.
 		// Without at least one tab with groupId=folderID, no tab-group element is created,
@@ -430,7 +818,7 @@ func (imp *Importer) insertItemWithChildren(
 			Entries: []types.ZenTabEntry{{
 				URL:                       "about:blank",
 				Title:                     "",
-				TriggeringPrincipalBase64: "eyIzIjp7fX0=",
+				TriggeringPrincipalBase64: imp.principals.ForURL("about:blank", containerID),
 			}},
 			LastAccessed:            now,
 			Pinned:                  true,
@@ -456,6 +844,7 @@ func (imp *Importer) insertItemWithChildren(
 			GroupID:                 folderID, // Critical: links tab to folder's tab-group
 		}
 		zenSession.Tabs = append(zenSession.Tabs, anchorTab)
+		imp.plan.addFolder(PlannedFolder{ID: folderID, Name: title, SpaceID: workspaceUUID, ParentID: parentFolderID, ArcID: arcItem.ID})
 
 		// Determine prevSiblingInfo for nested folders
 		// For nested folders, reference the previous sibling FOLDER (not tabs) to ensure
@@ -505,44 +894,90 @@ func (imp *Importer) insertItemWithChildren(
 
 		// Process children in FORWARD order - with folder-based prevSiblingInfo chaining,
 		// each folder references its predecessor, maintaining natural order
-		for _, childID := range arcItem.ChildrenIds {
-			if child := itemsMap[childID]; child != nil {
-				itemsCreated += imp.insertItemWithChildren(
-					child, folderID, spaceID, spaceUUIDMap, space,
-					itemsMap, arcToZenUUIDMap, containersData, zenSession, now, level+1,
-					lastFolderByParent,
-				)
-			}
+		for _, child := range imp.sortedChildren(arcItem, itemsMap) {
+			itemsCreated += imp.insertItemWithChildren(
+				ctx, child, folderID, spaceID, spaceUUIDMap, space, containerID,
+				itemsMap, arcToZenUUIDMap, zenSession, now, level+1,
+				lastFolderByParent,
+			)
 		}
 	} else {
+		if rewritten, rules := imp.rewriteURL(url); len(rules) > 0 {
+			imp.debug("%sRewriting %s → %s", indent, url, rewritten)
+			imp.plan.Rewrites = append(imp.plan.Rewrites, RewrittenURL{ArcID: arcItem.ID, Title: title, From: url, To: rewritten, Rules: rules})
+			url = rewritten
+		}
+		if reason := imp.skipShareLink(arcItem, title, url); reason != "" {
+			imp.logger.Info("%sSkipping \"%s\" (%s)", indent, title, reason)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: reason})
+			return 0
+		}
+		transformed := imp.transformTab(PlannedTab{ID: zenUUID, Title: title, URL: url, SpaceID: workspaceUUID,
+			FolderID: parentFolderID, ContainerID: containerID, ArcID: arcItem.ID})
+		if transformed == nil {
+			imp.debug("%sSkipping \"%s\" (%s)", indent, title, transformReason)
+			imp.plan.addSkipped(SkippedItem{ID: arcItem.ID, Title: title, SpaceID: workspaceUUID, FolderID: parentFolderID, Reason: transformReason})
+			return 0
+		}
+		title, url = transformed.Title, transformed.URL
+
 		if !imp.options.DryRun {
 			imp.logger.Info("%sCreating \"%s\" (TAB)", indent, title)
 		} else {
 			imp.logger.Info("%s[DRY-RUN] Would create tab: \"%s\" → %s", indent, title, url)
 		}
 
-		// Fetch favicon
-		var faviconDataURL string
-		if url != "" {
-			faviconDataURL = imp.faviconFetcher.FetchAsDataURL(url)
-			if faviconDataURL != "" && imp.options.Verbose {
-				imp.logger.Info("%s  ✓ Fetched favicon", indent)
+		// Use the tab's custom Arc icon, else fetch its favicon
+		faviconDataURL := imp.customTabIcon(arcItem, title)
+		customIcon := faviconDataURL != ""
+		if customIcon {
+			imp.debug("%s  ✓ Using custom Arc icon", indent)
+		}
+		if faviconDataURL == "" && url != "" {
+			faviconDataURL = imp.faviconFetcher.FetchAsDataURLContext(ctx, url)
+			if faviconDataURL != "" {
+				imp.debug("%s  ✓ Fetched favicon", indent)
+			}
+			switch {
+			case faviconDataURL != "" || imp.faviconFetcher.IsDenied(url):
+				// Fetched, or skipped on purpose
+			case imp.options.DryRun:
+				// Not cached; the real run fetches it (see reportFaviconCache)
+			case ctx.Err() != nil:
+				imp.warnings.Add(WarningFavicon, title, "favicon not fetched for %s (import time limit reached)", url)
+			case imp.faviconFetcher.IsBlocked(url):
+				imp.warnings.Add(WarningFavicon, title, "favicon not fetched from private host for %s (use -allow-private-hosts)", url)
+			case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
+				imp.warnings.Add(WarningFavicon, title, "favicon could not be fetched for %s", url)
 			}
 		}
 
-		// Create tab
+		// -domain-container rules override the space's container; such tabs
+		// aren't marked as being in the workspace's default container
+		tabContainerID, overridden := imp.tabContainer(url, containerID)
+		var defaultContainerID interface{} = tabContainerID
+		if overridden && tabContainerID != containerID {
+			defaultContainerID = nil
+		}
+
+		// Create tab, with a principal that lets Zen load the URL on restore
+		triggeringPrincipal := imp.principals.ForURL(url, tabContainerID)
 		tabEntry := types.ZenTabEntry{
 			URL:                      url,
 			Title:                    title,
-			TriggeringPrincipalBase64: "eyIzIjp7fX0=",
+			TriggeringPrincipalBase64: triggeringPrincipal,
 		}
 
-		// Prepare image field (nil if no favicon)
-		var imageField interface{}
+		// Prepare image field (nil if no favicon). Zen builds that read the
+		// static icon fields show zenPinnedIcon before the page has loaded.
+		var imageField, pinnedIcon interface{}
+		hasStaticIcon := false
 		if faviconDataURL != "" {
 			imageField = faviconDataURL
-		} else {
-			imageField = nil
+			if imp.staticPinnedIcons() {
+				pinnedIcon = faviconDataURL
+				hasStaticIcon = true
+			}
 		}
 
 		tab := types.ZenTab{
@@ -553,23 +988,23 @@ func (imp *Importer) insertItemWithChildren(
 			ZenWorkspace:            workspaceUUID,
 			ZenSyncID:               zenUUID,
 			ZenEssential:            false,
-			ZenDefaultUserContextID: containerID,
-			ZenPinnedIcon:           nil,
+			ZenDefaultUserContextID: defaultContainerID,
+			ZenPinnedIcon:           pinnedIcon,
 			ZenIsEmpty:              false,
-			ZenHasStaticIcon:        false,
+			ZenHasStaticIcon:        hasStaticIcon,
 			ZenGlanceID:             nil,
 			ZenIsGlance:             false,
-			ZenStaticLabel:          title,
+			ZenStaticLabel:          imp.options.TagPrefix + title,
 			ZenPinnedInitialState: map[string]interface{}{
 				"entry": map[string]interface{}{
 					"url":                        url,
 					"title":                      title,
-					"triggeringPrincipal_base64": "eyIzIjp7fX0=",
+					"triggeringPrincipal_base64": triggeringPrincipal,
 				},
 				"image": imageField,
 			},
 			SearchMode:     nil,
-			UserContextID:  containerID,
+			UserContextID:  tabContainerID,
 			Attributes:     map[string]interface{}{},
 			Index:          len(zenSession.Tabs),
 			UserTypedValue: "",
@@ -583,6 +1018,17 @@ func (imp *Importer) insertItemWithChildren(
 		}
 
 		zenSession.Tabs = append(zenSession.Tabs, tab)
+		imp.plan.addTab(PlannedTab{
+			ID:          zenUUID,
+			Title:       title,
+			URL:         url,
+			Icon:        faviconDataURL,
+			SpaceID:     workspaceUUID,
+			FolderID:    parentFolderID,
+			ContainerID: tabContainerID,
+			ArcID:       arcItem.ID,
+			customIcon:  customIcon,
+		})
 		itemsCreated++
 	}
 
@@ -612,8 +1058,8 @@ func collectAllURLs(items []*types.ArcItem, itemsMap map[string]*types.ArcItem)
 			return
 		}
 
-		// Extract URL from tab data
-		if item.Data != nil && item.Data.Tab != nil && item.Data.Tab.SavedURL != "" {
+		// Extract URL from tab data (tabs with a custom Arc icon need no favicon)
+		if item.Data != nil && item.Data.Tab != nil && item.Data.Tab.SavedURL != "" && !hasCustomTabIcon(item) {
 			url := item.Data.Tab.SavedURL
 			if !seen[url] {
 				urls = append(urls, url)
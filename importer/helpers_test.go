@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// largeSession has workspaces w0..wN-1, each with tabsPer pinned tabs, as
+// many unpinned ones, and foldersPer folders
+func largeSession(workspaces, tabsPer, foldersPer int) *types.ZenSession {
+	session := emptySession()
+	for w := 0; w < workspaces; w++ {
+		uuid := fmt.Sprintf("w%d", w)
+		session.Spaces = append(session.Spaces, types.ZenSpace{UUID: uuid, Name: fmt.Sprintf("Space %d", w)})
+		for i := 0; i < tabsPer; i++ {
+			session.Tabs = append(session.Tabs,
+				types.ZenTab{ZenWorkspace: uuid, ZenSyncID: fmt.Sprintf("%s-p%d", uuid, i), Pinned: true},
+				types.ZenTab{ZenWorkspace: uuid, ZenSyncID: fmt.Sprintf("%s-u%d", uuid, i)})
+		}
+		for i := 0; i < foldersPer; i++ {
+			session.Folders = append(session.Folders, types.ZenFolder{ID: fmt.Sprintf("%s-f%d", uuid, i), WorkspaceID: uuid})
+		}
+	}
+	return session
+}
+
+func TestFilterTabsAndFolders(t *testing.T) {
+	session := largeSession(3, 2, 2)
+	replaced := map[string]bool{"w0": true, "w2": true}
+
+	tabs := filterTabs(session.Tabs, replaced)
+	if len(tabs) != 8 {
+		t.Fatalf("expected 8 tabs left, got %d", len(tabs))
+	}
+	for _, tab := range tabs {
+		if tab.Pinned && replaced[tab.ZenWorkspace] {
+			t.Errorf("pinned tab %s of a replaced workspace kept", tab.ZenSyncID)
+		}
+	}
+	if tabs[0].ZenSyncID != "w0-u0" || tabs[2].ZenSyncID != "w1-p0" {
+		t.Errorf("expected the remaining tabs in their order, got %s, %s", tabs[0].ZenSyncID, tabs[2].ZenSyncID)
+	}
+
+	folders := filterFolders(session.Folders, replaced)
+	if len(folders) != 2 || folders[0].ID != "w1-f0" || folders[1].ID != "w1-f1" {
+		t.Errorf("expected only w1's folders, got %+v", folders)
+	}
+}
+
+// largeMergeArcData has one Arc space per workspace of largeSession(n, ...),
+// by name, with a tab each, so every space merges into an existing workspace
+func largeMergeArcData(b *testing.B, n int) *types.ArcData {
+	var spaces, items []string
+	for w := 0; w < n; w++ {
+		spaces = append(spaces, fmt.Sprintf(`{"id": "s%d", "title": "Space %d", "containerIDs": ["pinned", "t%d"]}`, w, w, w))
+		items = append(items, fmt.Sprintf(`{"id": "t%d", "childrenIds": [], "data": {"tab": {"savedTitle": "Tab", "savedURL": "%s/%d"}}}`, w, testSite, w))
+	}
+	var arcData types.ArcData
+	raw := fmt.Sprintf(`{"sidebar": {"containers": [{"global": {}}, {"spaces": [%s], "items": [%s]}]}}`, strings.Join(spaces, ","), strings.Join(items, ","))
+	if err := json.Unmarshal([]byte(raw), &arcData); err != nil {
+		b.Fatal(err)
+	}
+	return &arcData
+}
+
+func BenchmarkFilterPins(b *testing.B) {
+	// 100 workspaces of 250 pinned and 250 unpinned tabs: 50,000 tabs
+	session := largeSession(100, 250, 25)
+	replaced := make(map[string]bool)
+	for w := 0; w < 100; w += 2 {
+		replaced[fmt.Sprintf("w%d", w)] = true
+	}
+	tabs := make([]types.ZenTab, len(session.Tabs))
+	folders := make([]types.ZenFolder, len(session.Folders))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(tabs, session.Tabs)
+		copy(folders, session.Folders)
+		filterTabs(tabs, replaced)
+		filterFolders(folders, replaced)
+	}
+}
+
+func BenchmarkDoImport_MergeLargeSession(b *testing.B) {
+	const workspaces = 100
+	arcData := largeMergeArcData(b, workspaces)
+	base := largeSession(workspaces, 250, 25)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		session := *base
+		session.Spaces = append([]types.ZenSpace(nil), base.Spaces...)
+		session.Tabs = append([]types.ZenTab(nil), base.Tabs...)
+		session.Folders = append([]types.ZenFolder(nil), base.Folders...)
+		imp := NewWithOptions(b.TempDir(), &testLogger{}, ImportOptions{FaviconFetcher: iconFetcher{}})
+		b.StartTimer()
+
+		if _, err := imp.doImport(context.Background(), arcData, &session, &types.ContainersData{Version: 5}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCalculateNextContainerID_SkipsInternalContainers(t *testing.T) {
+	id := func(v int) *int { return &v }
+	data := &types.ContainersData{
+		LastUserContextID: id(4294967294),
+		Identities: []types.ContainerIdentity{
+			{UserContextID: id(3), Name: "Work", Public: true},
+			{UserContextID: id(4294967295), Name: "userContextIdInternal.thumbnail"},
+			{UserContextID: id(4294967294), Name: "userContextIdInternal.webextStorageLocal"},
+			{UserContextID: id(5), Name: "hidden", Public: false},
+		},
+	}
+	if got, err := calculateNextContainerID(data, nil); got != 4 || err != nil {
+		t.Errorf("expected 4, got %d (%v)", got, err)
+	}
+	data.Identities[0].UserContextID = id(4)
+	if got, err := calculateNextContainerID(data, nil); got != 6 || err != nil {
+		t.Errorf("expected the internal container's 5 to be skipped, got %d (%v)", got, err)
+	}
+}
+
+func TestCalculateNextContainerID_ReservedRange(t *testing.T) {
+	id := func(v int) *int { return &v }
+	data := &types.ContainersData{
+		LastUserContextID: id(firstReservedContainerID - 1),
+		Identities: []types.ContainerIdentity{
+			{UserContextID: id(1), Name: "Personal", Public: true},
+			{UserContextID: id(firstReservedContainerID - 1), Name: "Last", Public: true},
+			{UserContextID: id(firstReservedContainerID + 5), Name: "Stray", Public: true},
+		},
+	}
+	// 2 and 3 were deleted, but the session still has tabs in 2
+	session := &types.ZenSession{Tabs: []types.ZenTab{{UserContextID: 2}}}
+	if got, err := calculateNextContainerID(data, session); got != 3 || err != nil {
+		t.Errorf("expected the unused gap 3, got %d (%v)", got, err)
+	}
+	recordContainerID(data, 3)
+	if *data.LastUserContextID != firstReservedContainerID-1 {
+		t.Errorf("lastUserContextId went down to %d", *data.LastUserContextID)
+	}
+
+	// A public container inside the reserved range doesn't push IDs past it
+	data = &types.ContainersData{Identities: []types.ContainerIdentity{
+		{UserContextID: id(4), Name: "Work", Public: true},
+		{UserContextID: id(firstReservedContainerID + 5), Name: "Stray", Public: true},
+	}}
+	if got, err := calculateNextContainerID(data, nil); got != 5 || err != nil {
+		t.Errorf("expected 5, got %d (%v)", got, err)
+	}
+}
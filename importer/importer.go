@@ -1,51 +1,303 @@
 package importer
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
+	"arc-to-zen/arcdata"
+	"arc-to-zen/avatar"
 	"arc-to-zen/favicon"
+	"arc-to-zen/lock"
+	"arc-to-zen/manifest"
 	"arc-to-zen/mappings"
-	"arc-to-zen/mozlz4"
+	"arc-to-zen/places"
+	"arc-to-zen/principal"
+	"arc-to-zen/restoresim"
+	"arc-to-zen/rewrite"
+	"arc-to-zen/sessionfile"
 	"arc-to-zen/types"
 )
 
-const maxUint32 = 4294967295
-
 // ImportOptions configures the import behavior
 type ImportOptions struct {
-	DryRun  bool // If true, only show what would be imported
-	Verbose bool // If true, show detailed output
+	DryRun   bool // If true, only show what would be imported
+	Verbose  bool // If true, show detailed output
+	Strict   bool // If true, fail instead of silently falling back to defaults (implies FailFast)
+	FailFast bool // If true, abort on the first space that can't be imported instead of skipping it
+
+	// SimulateRestore replays the imported workspaces through a model of Zen's
+	// session restore and reports anything it would drop or reorder as warnings
+	SimulateRestore bool
+	Only            string // OnlyFolders or OnlyTabs to import just one kind of root item ("" imports everything)
+	// EmptyURLs decides what happens to tabs without a URL: EmptyURLSkip
+	// (the default), EmptyURLKeep or EmptyURLNote
+	EmptyURLs string
+	// EmptyFolders decides what happens to Arc folders without a single tab:
+	// EmptyFolderSkip (the default) or EmptyFolderKeep
+	EmptyFolders string
+	// MergeSmallFolders inlines folders with fewer than this many items into
+	// their parent, prefixing the moved items' titles with the folder's name
+	// (0 keeps every folder)
+	MergeSmallFolders int
+	// Sort reorders the items of each space and folder: SortNone (Arc's
+	// order, the default), SortAlpha, SortDomain or SortRecent
+	Sort string
+	// TagPrefix is put in front of the labels of imported tabs so they can be
+	// told apart from native Zen pins; the untag command removes it
+	TagPrefix string
+
+	// SpaceIconFromFavicons gives spaces without a mapped Arc icon the favicon
+	// most of their tabs share, falling back to their Arc emoji or the first
+	// letter of their name, instead of the globe
+	SpaceIconFromFavicons bool
+	// LetterAvatars draws spaces without a mapped Arc icon as the first letter
+	// of their name on their color, instead of the globe
+	LetterAvatars bool
+	// EmojiFromName moves a leading emoji in a space's title ("🚀 Launch") to
+	// its workspace icon, so the name doesn't repeat it
+	EmojiFromName bool
+	// Theme decides how Arc space colors become workspace themes: ThemeGradient
+	// (the default), ThemeSolid or ThemeNone
+	Theme string
+	// CollapsePinnedOver starts the pinned section of workspaces with more than
+	// this many pins collapsed (0 leaves them all expanded)
+	CollapsePinnedOver int
+	// SpaceSettings sets workspace settings by space name, overriding
+	// CollapsePinnedOver: SpaceCollapsed or SpaceExpanded
+	SpaceSettings map[string]string
+
+	// TriggeringPrincipals overrides the principal tabs are restored with, by
+	// URL scheme: "system", "content", "null" or a base64 principal (see
+	// principal.DefaultKinds for the defaults)
+	TriggeringPrincipals map[string]string
+
+	// URLRewrites are applied in order to the URL of every imported tab (see
+	// package rewrite); the changes are listed in Plan.Rewrites
+	URLRewrites []rewrite.Rule
+
+	// StripTracking removes tracking parameters (utm_*, fbclid, gclid and the
+	// others in rewrite's tracking.txt) from imported tab URLs, after the
+	// URLRewrites; cleaned URLs are listed in Plan.Rewrites too
+	StripTracking bool
+
+	// ShareLinks decides what happens to Arc share links (arc.net/folder/...),
+	// which only open in Arc: ShareLinkSkip (the default), ShareLinkKeep or
+	// ShareLinkResolve
+	ShareLinks string
+	// ShareResolver resolves share links for ShareLinkResolve (default: an
+	// arcshare.Resolver reading arc.net's share pages)
+	ShareResolver ShareResolver
+
+	// AlsoBookmarks also writes the imported workspaces, folders and tabs
+	// into the profile's places.sqlite as bookmarks, in a folder under Other
+	// Bookmarks that the next import replaces
+	AlsoBookmarks bool
+
+	// ZenVersion is the Zen release the profile will be opened with (e.g.
+	// "1.14.5b"); it decides which pinned icon fields are written. Empty reads
+	// it from the profile's compatibility.ini.
+	ZenVersion string
+
+	// ArcProfile limits the import to spaces of one Arc profile, matched by
+	// directory name ("Profile 1") or display name ("Work")
+	ArcProfile string
+
+	// ProfileContainers maps Arc profile names (e.g. "Profile 1") to a container
+	// name or userContextId, ContainerNew or ContainerNone
+	ProfileContainers map[string]string
+	// UpdateContainers gives the existing containers reused for Arc profiles
+	// the icon and color of the profile's first space (by default they are
+	// left as they are)
+	UpdateContainers bool
+	// DomainContainers maps domains (e.g. "*.workdomain.com") to a container
+	// name or userContextId, or ContainerNone. Tabs on a domain or its
+	// subdomains open in that container whatever their space's container;
+	// the most specific domain wins. Missing containers are created.
+	DomainContainers map[string]string
+	// RepairContainers repairs containers in containers.json that share a
+	// name or userContextId before importing, without asking ConfirmRepair
+	RepairContainers bool
+	// ConfirmRepair is asked whether to repair them when RepairContainers
+	// isn't set; declining, or leaving it nil, fails the import with
+	// ErrDuplicateContainers. Not called in dry-run, which repairs in memory.
+	ConfirmRepair RepairConfirmer
+	// Exclude leaves out the likely junk of these cleanup categories
+	// (CleanupDuplicates, CleanupLocalhost, CleanupEmptyFolders, CleanupLargeSpaces)
+	Exclude []string
+	// AssignContainer is asked about profiles missing from ProfileContainers
+	AssignContainer ContainerAssigner
+	// Quick imports only the first Quick pinned tabs of each space, as
+	// essentials without a container (0 does the full import)
+	Quick int
+	// Mode is ModeWorkspaces (the default: a workspace per Arc space) or
+	// ModeFolders (every space as a folder of Zen's first workspace)
+	Mode string
+	// MergeStrategy is what happens to an existing workspace with the name of
+	// an imported space: MergeReplace (the default: its pins are replaced),
+	// MergeAppend (they are kept and the imported items added after them),
+	// MergeDedupe (kept, and only tabs with URLs not pinned there yet are
+	// added) or MergeSkip (the space isn't imported)
+	MergeStrategy string
+	// FavoritesAsEssentials imports the Arc favorites bar of each imported
+	// profile as Zen essentials of its container instead of leaving it out
+	FavoritesAsEssentials bool
+	// CombineSpaces imports the Arc spaces with these titles into one
+	// workspace of the key's name, each space as a folder
+	CombineSpaces map[string][]string
+	// SplitSpaces divides the Arc spaces with these titles into workspaces of
+	// at most this many items each ("Work", "Work 2", ...), keeping folders whole
+	SplitSpaces map[string]int
+	// Select is shown the Arc spaces, folders and tabs left after the other
+	// filters and picks what to import (nil imports everything)
+	Select ItemSelector
+
+	// AllowPrivateHosts lets favicons be fetched from localhost and private
+	// networks; by default imported intranet URLs are never contacted
+	AllowPrivateHosts bool
+	// FaviconDomains lists domains whose favicons are never fetched or always refetched
+	FaviconDomains favicon.DomainRules
+	// FaviconAudit receives a JSON line for every outbound favicon request (nil disables)
+	FaviconAudit io.Writer
+	// FaviconProviders are asked for each favicon in order (favicon.Options.Providers;
+	// empty asks only the site itself)
+	FaviconProviders []string
+	// UnparsedItems receives a JSON array of the Arc items that could not be
+	// understood, with their raw JSON, for bug reports (nil disables)
+	UnparsedItems io.Writer
+	// FaviconFetcher replaces the default fetcher, e.g. one built with
+	// favicon.NewWithOptions around an instrumented transport. The favicon
+	// options above are not applied to it.
+	FaviconFetcher FaviconFetcher
+
+	// SessionBudget is the largest compressed session, in bytes, the import
+	// should write (0 doesn't check; the CLI uses DefaultSessionBudget). Zen
+	// rewrites the whole file every few seconds, so a bloated one slows it down.
+	SessionBudget int64
+	// OverBudget decides what happens above SessionBudget: OverBudgetWarn (the
+	// default), OverBudgetDownscale, OverBudgetSkipFavicons or OverBudgetFail
+	OverBudget string
+	// FaviconStorage is where fetched favicons go: FaviconStorageSession (the
+	// default) or FaviconStorageSQLite, which keeps them out of the session
+	FaviconStorage string
+
+	// Confirm is asked before the profile is written; declining fails the
+	// import with ErrNotConfirmed. Nil writes without asking. Not called in dry-run.
+	Confirm Confirmer
+	// TransformHook and FolderTransformHook let library callers rewrite or
+	// leave out each tab and folder as it is planned (nil imports them as
+	// they are)
+	TransformHook       TabTransform
+	FolderTransformHook FolderTransform
 }
 
-// Importer handles Arc to Zen browser data import
-type Importer struct {
-	zenProfilePath  string
-	logger          Logger
-	options         ImportOptions
-	faviconFetcher  *favicon.Fetcher
-}
+// faviconWorkers is how many favicons are fetched at once while pre-caching
+const faviconWorkers = 10
 
-// Logger interface for custom logging
-type Logger interface {
-	Info(format string, args ...interface{})
-	Error(format string, args ...interface{})
+// FaviconFetcher looks up favicons for tab URLs; *favicon.Fetcher implements it
+type FaviconFetcher interface {
+	FetchAsDataURLContext(ctx context.Context, pageURL string) string
+	PreCacheFaviconsContext(ctx context.Context, urls []string, workers int, progress favicon.ProgressCallback) *favicon.PreCacheResult
+	IsBlocked(pageURL string) bool
+	IsDenied(pageURL string) bool
+	DedupSavedBytes() int64
 }
 
-// defaultLogger implements Logger with standard output
-type defaultLogger struct{}
+const (
+	// ContainerNew always creates a new container for the profile
+	ContainerNew = "new"
+	// ContainerNone imports the profile's tabs without a container
+	ContainerNone = "none"
+)
 
-func (l *defaultLogger) Info(format string, args ...interface{}) {
-	fmt.Printf("[ARC-IMPORT] "+format+"\n", args...)
-}
+const (
+	// OnlyFolders imports folders (with their contents) but no loose root-level tabs
+	OnlyFolders = "folders"
+	// OnlyTabs imports loose root-level tabs but no folders
+	OnlyTabs = "tabs"
+)
+
+const (
+	// EmptyURLSkip leaves tabs without a URL out and lists them in Plan.Skipped
+	EmptyURLSkip = "skip"
+	// EmptyURLKeep imports them as about:blank pins that keep their title
+	EmptyURLKeep = "keep"
+	// EmptyURLNote imports them as empty folders named after the tab, as a note
+	EmptyURLNote = "note"
+)
+
+const (
+	// EmptyFolderSkip leaves folders without a tab out and lists them in Plan.Skipped
+	EmptyFolderSkip = "skip"
+	// EmptyFolderKeep imports them as empty Zen folders
+	EmptyFolderKeep = "keep"
+)
+
+const (
+	// ModeWorkspaces imports each Arc space as a Zen workspace
+	ModeWorkspaces = "workspaces"
+	// ModeFolders imports each Arc space as a top-level folder of the first Zen
+	// workspace, keeping its other pins, for users who don't use workspaces
+	ModeFolders = "folders"
+)
+
+// emptyFolderReason is the Plan.Skipped reason of folders left out by EmptyFolderSkip
+const emptyFolderReason = "empty folder"
 
-func (l *defaultLogger) Error(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "[ERROR] "+format+"\n", args...)
+const (
+	// ThemeGradient builds a two or three stop gradient from the Arc colors
+	ThemeGradient = "gradient"
+	// ThemeSolid uses only the space's main Arc color
+	ThemeSolid = "solid"
+	// ThemeNone leaves workspaces on Zen's default theme
+	ThemeNone = "none"
+)
+
+const (
+	// SpaceCollapsed starts the workspace with its pinned tabs collapsed
+	SpaceCollapsed = "collapsed"
+	// SpaceExpanded starts the workspace with its pinned tabs shown
+	SpaceExpanded = "expanded"
+)
+
+// ContainerAssigner chooses the container for an Arc profile. It returns an existing
+// container's name or userContextId, ContainerNew, ContainerNone, or "" for the default
+// (reuse a container named after the profile, or create one)
+type ContainerAssigner func(profile *ProfileInfo, existing []types.ContainerIdentity) string
+
+// Importer handles Arc to Zen browser data import. One Importer can be used
+// for any number of runs (Import, Bookmarks, Cleanup, Count) and shared
+// between goroutines: runs on the same Importer take turns, each starting
+// from a clean state, while its favicon fetcher and cache are kept. Use one
+// Importer per profile to run imports in parallel.
+type Importer struct {
+	run            *sync.Mutex // Held for the whole of a run (see begin); shared by the space workers' copies
+	zenProfilePath string
+	logger         Logger
+	options        ImportOptions
+	faviconFetcher FaviconFetcher
+	warnings       *Warnings         // Warnings raised during the current import
+	plan           *Plan             // What the current import creates
+	folderSeq      *atomic.Int64     // Next folder ID suffix, shared by the space workers
+	checkpoint     *checkpoint       // Resume state of the current import (nil in dry-run)
+	arcDataHash    string            // SHA-256 of the Arc data snapshot readArcData parsed
+	principals     *principal.Policy // Tab triggeringPrincipal by URL scheme (nil uses the defaults)
+	rewriter       *rewrite.Rewriter // URLRewrites, compiled (nil rewrites nothing)
+	shareErrors    map[string]error  // Arc item ID → why its share link could not be resolved
+	domainRules    []domainContainer // DomainContainers resolved, most specific first
+	zenVersion     zenVersion        // Target Zen release (nil if unknown)
+	importID       string            // ID of the current import, recorded in the profile's manifest
+	excluded       map[string]string // Arc item ID → why -exclude leaves it out
+	inlined        map[string]bool   // Arc folders -merge-small-folders inlines into their parent
+	titlePrefix    map[string]string // Arc item ID → names of the inlined folders it moves out of
 }
 
 // New creates a new Importer
@@ -58,24 +310,76 @@ func NewWithOptions(zenProfilePath string, logger Logger, options ImportOptions)
 	if logger == nil {
 		logger = &defaultLogger{}
 	}
+	fetcher := options.FaviconFetcher
+	if fetcher == nil {
+		f := favicon.NewWithOptions(favicon.Options{Providers: options.FaviconProviders})
+		f.SetBlockPrivateHosts(!options.AllowPrivateHosts)
+		f.SetDomainRules(options.FaviconDomains)
+		f.SetCacheOnly(options.DryRun) // A dry run never touches the network
+		if options.FaviconAudit != nil {
+			f.SetAuditLog(options.FaviconAudit)
+		}
+		fetcher = f
+	}
 	return &Importer{
-		zenProfilePath:  zenProfilePath,
-		logger:          logger,
-		options:         options,
-		faviconFetcher:  favicon.New(),
+		zenProfilePath: zenProfilePath,
+		logger:         logger,
+		options:        options,
+		faviconFetcher: fetcher,
+		warnings:       newWarnings(logger),
+		run:            &sync.Mutex{},
 	}
 }
 
 // ImportResult contains statistics about the import
 type ImportResult struct {
-	Success         bool
-	SpacesCreated   int
-	ItemsImported   int
-	ContainersCount int
+	Success             bool
+	SpacesCreated       int
+	ItemsImported       int
+	ContainersCount     int
+	Warnings            []Warning            // Everything that could not be imported exactly (fatal in strict mode)
+	SpaceErrors         []*SpaceError        // Spaces skipped because their data could not be imported
+	Plan                *Plan                // Workspaces, folders and tabs created (or that would be, in dry-run)
+	FaviconTabs         int                  // Tabs with a favicon
+	FaviconImages       int                  // Distinct favicon images among them
+	FaviconCacheSaved   int64                // Favicon cache bytes this import didn't write because an identical image was stored already
+	FaviconsStored      int                  // Favicons written to favicons.sqlite (or that would be) with FaviconStorageSQLite
+	FaviconSaved        int64                // Bytes of those not written because pages with identical icons share one
+	FaviconCache        *favicon.CacheStatus // Dry-run only: favicons cached vs. to be fetched by the real run
+	Network             *NetworkReport       // Favicon traffic of this import (nil if the fetcher doesn't count it)
+	ImportID            string               // Recorded in the profile's arc-to-zen.json (empty in dry-run)
+	UnparsedItems       []UnparsedItem       // Arc items dropped because they could not be understood
+	EmptyFoldersSkipped int                  // Arc folders without a tab left out (see ImportOptions.EmptyFolders)
+	RepairedContainers  []string             // Duplicate containers repaired before importing (see ImportOptions.RepairContainers)
+	Bookmarks           *places.Result       // Written to places.sqlite (ImportOptions.AlsoBookmarks); nil otherwise
+}
+
+// begin starts a run: it waits for any other run on imp to finish, then
+// drops what the previous one left behind. Call the returned func when done.
+func (imp *Importer) begin() (end func()) {
+	imp.run.Lock()
+	imp.warnings = newWarnings(imp.logger)
+	imp.plan = nil
+	imp.folderSeq = nil
+	imp.checkpoint = nil
+	imp.arcDataHash = ""
+	imp.shareErrors = nil
+	imp.domainRules = nil
+	imp.importID = ""
+	imp.excluded = nil
+	imp.inlined = nil
+	imp.titlePrefix = nil
+	return imp.run.Unlock
 }
 
 // Import performs the Arc to Zen import
 func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
+	return imp.ImportContext(context.Background(), arcDataPath)
+}
+
+// ImportContext is Import with a context bounding the favicon requests
+func (imp *Importer) ImportContext(ctx context.Context, arcDataPath string) (*ImportResult, error) {
+	defer imp.begin()()
 	imp.logger.Info(strings.Repeat("=", 80))
 	if imp.options.DryRun {
 		imp.logger.Info("DRY-RUN MODE - NO CHANGES WILL BE MADE")
@@ -85,10 +389,41 @@ func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
 	imp.logger.Info(strings.Repeat("=", 80))
 	imp.logger.Info("Zen Profile: %s", imp.zenProfilePath)
 
+	if err := imp.validateOptions(); err != nil {
+		return nil, err
+	}
+
 	// Validate Zen profile
 	if err := imp.validateZenProfile(); err != nil {
 		return nil, err
 	}
+	if imp.zenVersion == nil {
+		if detected := detectZenVersion(imp.zenProfilePath); detected != "" {
+			if version, err := parseZenVersion(detected); err == nil {
+				imp.zenVersion = version
+			}
+		}
+	}
+	if imp.zenVersion != nil {
+		imp.logger.Info("Target Zen version: %s", imp.zenVersion)
+	}
+
+	// Serialize runs against this profile; two writers would corrupt the session
+	if !imp.options.DryRun {
+		profileLock, err := lock.Acquire(imp.zenProfilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer profileLock.Release()
+	}
+
+	// Fail before the slow phases if the results couldn't be written anyway
+	if err := imp.preflightPermissions(); err != nil {
+		if !imp.options.DryRun {
+			return nil, err
+		}
+		imp.warnings.Add(WarningWrite, imp.zenProfilePath, "import would fail: %v", err)
+	}
 
 	// Read Arc data
 	arcData, err := imp.readArcData(arcDataPath)
@@ -96,6 +431,19 @@ func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
 		return nil, err
 	}
 
+	// Pick up where an interrupted import of the same Arc data left off
+	imp.checkpoint = nil
+	if !imp.options.DryRun {
+		cp, err := openCheckpoint(imp.zenProfilePath, imp.arcDataHash)
+		if err != nil {
+			return nil, err
+		}
+		if cp.resumed {
+			imp.logger.Info("Resuming interrupted import from %s (reusing planned IDs)", cp.Started.Format("2006-01-02 15:04:05"))
+		}
+		imp.checkpoint = cp
+	}
+
 	// Read Zen session
 	zenSession, err := imp.readZenSession()
 	if err != nil {
@@ -107,23 +455,88 @@ func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	repaired, err := imp.repairContainers(zenSession, containersData)
+	if err != nil {
+		return nil, err
+	}
 
 	// Perform import
-	result, err := imp.doImport(arcData, zenSession, containersData)
+	workspacesBefore := len(zenSession.Spaces)
+	before := snapshotSession(zenSession, containersData)
+	result, err := imp.doImport(ctx, arcData, zenSession, containersData)
 	if err != nil {
 		return nil, err
 	}
+	result.Plan.Diff = diffSession(before, zenSession, containersData)
+	result.RepairedContainers = repaired
+
+	if imp.options.SimulateRestore {
+		imp.simulateRestore(zenSession, result.Plan)
+	}
+
+	// Favicons kept in favicons.sqlite instead count against no budget
+	var storedFavicons []places.Favicon
+	if imp.options.FaviconStorage == FaviconStorageSQLite {
+		storedFavicons, err = imp.storeFaviconsInDatabase(zenSession, result.Plan)
+		if err != nil {
+			return nil, err
+		}
+		result.FaviconsStored = len(storedFavicons)
+		result.FaviconSaved = places.SharedBytes(storedFavicons)
+	}
+
+	// Keep the session small enough for Zen to rewrite quickly
+	if err := imp.checkSessionBudget(zenSession, result.Plan); err != nil {
+		return nil, err
+	}
+
+	// Strict mode refuses to write anything that isn't a faithful migration
+	if imp.options.Strict && imp.warnings.Len() > 0 {
+		warnings := imp.warnings.List()
+		imp.logger.Error("Strict mode: %d items could not be imported faithfully:", len(warnings))
+		for _, warning := range warnings {
+			imp.logger.Error("  • %s", warning)
+		}
+		return nil, fmt.Errorf("strict mode: %d warnings, nothing was written", len(warnings))
+	}
 
 	// Write back (skip in dry-run mode)
 	if !imp.options.DryRun {
-		if err := imp.writeContainers(containersData); err != nil {
+		if imp.options.Confirm != nil && !imp.options.Confirm(newWriteSummary(imp.zenProfilePath, workspacesBefore, result.Plan, imp.options.MergeStrategy)) {
+			// Declined, not interrupted: the next run plans afresh
+			if err := imp.checkpoint.remove(); err != nil {
+				imp.warnings.Add(WarningWrite, "", "%v", err)
+			}
+			return nil, ErrNotConfirmed
+		}
+		if err := imp.preflightDiskSpace(zenSession, containersData); err != nil {
 			return nil, err
 		}
+		if len(storedFavicons) > 0 {
+			if result.FaviconsStored, err = imp.writeFavicons(ctx, storedFavicons); err != nil {
+				return nil, err
+			}
+		}
 
-		if err := imp.writeZenSession(zenSession); err != nil {
+		imp.importID = manifest.NewImportID()
+		if err := imp.writeProfile(zenSession, containersData, imp.manifestRecord(result.Plan, arcDataPath)); err != nil {
 			return nil, err
 		}
+		result.ImportID = imp.importID
+		if imp.options.AlsoBookmarks {
+			result.Bookmarks = imp.writePlacesBookmarks(ctx, result.Plan)
+		}
+		if err := imp.checkpoint.remove(); err != nil {
+			imp.warnings.Add(WarningWrite, "", "%v", err)
+		}
 	} else {
+		imp.logDiff(result.Plan.Diff)
+		if len(storedFavicons) > 0 {
+			imp.logger.Info("[DRY-RUN] Would write %s to %s", plural(len(storedFavicons), "favicon"), places.FaviconsFileName)
+		}
+		if imp.options.AlsoBookmarks {
+			imp.logger.Info("[DRY-RUN] Would also add the imported folders and tabs to %s, under Other Bookmarks → %s", places.FileName, placesFolderTitle)
+		}
 		imp.logger.Info("")
 		imp.logger.Info("[DRY-RUN] Skipping file writes")
 	}
@@ -141,7 +554,50 @@ func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
 	imp.logger.Info("  • Spaces to import: %d", result.SpacesCreated)
 	imp.logger.Info("  • Items to import: %d", result.ItemsImported)
 	imp.logger.Info("  • Containers to create/update: %d", result.ContainersCount)
+	if result.FaviconTabs > 0 {
+		cached := ""
+		if result.FaviconCacheSaved > 0 {
+			cached = fmt.Sprintf(" (%s not cached twice)", formatBytes(uint64(result.FaviconCacheSaved)))
+		}
+		imp.logger.Info("  • Favicons: %d tabs, %d unique images%s", result.FaviconTabs, result.FaviconImages, cached)
+	}
+	if result.FaviconsStored > 0 {
+		shared := ""
+		if result.FaviconSaved > 0 {
+			shared = fmt.Sprintf("; identical icons stored once, %s saved", formatBytes(uint64(result.FaviconSaved)))
+		}
+		imp.logger.Info("  • Favicons in %s: %d (loaded from there, not the session%s)", places.FaviconsFileName, result.FaviconsStored, shared)
+	}
+	if result.Network != nil && (result.Network.Requests > 0 || result.Network.CacheLookups() > 0) {
+		imp.logger.Info("  • Network: %s", result.Network)
+	}
+	imp.logger.Info("  • Warnings: %d", imp.warnings.Len())
+	if len(result.SpaceErrors) > 0 {
+		imp.logger.Info("  • Spaces skipped: %d", len(result.SpaceErrors))
+	}
+	if len(result.Plan.Skipped) > 0 {
+		imp.logger.Info("  • Items skipped: %d", len(result.Plan.Skipped))
+	}
+	if result.EmptyFoldersSkipped > 0 {
+		imp.logger.Info("  • Empty folders skipped: %d (use -empty-folders keep to import them)", result.EmptyFoldersSkipped)
+	}
+	if len(result.RepairedContainers) > 0 {
+		imp.logger.Info("  • Duplicate containers repaired: %d", len(result.RepairedContainers))
+	}
 	imp.logger.Info("")
+	if len(result.Plan.Skipped) > 0 {
+		imp.logger.Info("Skipped items:")
+		for _, item := range result.Plan.Skipped {
+			imp.logger.Info("  • %q (%s)", item.Title, item.Reason)
+		}
+		imp.logger.Info("")
+	}
+	imp.logArchiveReport(result.Plan)
+	for _, spaceErr := range result.SpaceErrors {
+		imp.logger.Error("Not imported: %v", spaceErr)
+	}
+	result.Warnings = imp.warnings.List()
+	imp.logWarnings()
 	if imp.options.DryRun {
 		imp.logger.Info("This was a dry-run. No changes were made.")
 		imp.logger.Info("Run without -dry-run to perform the actual import.")
@@ -156,6 +612,227 @@ func (imp *Importer) Import(arcDataPath string) (*ImportResult, error) {
 	return result, nil
 }
 
+// faviconCacheInspector is implemented by fetchers that can tell what is
+// cached without the network, as *favicon.Fetcher does
+type faviconCacheInspector interface {
+	CacheStatus(urls []string) *favicon.CacheStatus
+}
+
+// reportFaviconCache logs, for a dry run, how many favicons are cached and how
+// many the real run would fetch. It returns nil if the fetcher can't tell.
+func (imp *Importer) reportFaviconCache(urls []string) *favicon.CacheStatus {
+	inspector, ok := imp.faviconFetcher.(faviconCacheInspector)
+	if !ok {
+		return nil
+	}
+	status := inspector.CacheStatus(urls)
+	imp.logger.Info("[DRY-RUN] Favicon cache: %d of %d cached, %d to fetch (~%s with %d workers)",
+		status.Cached, status.Total, status.Missing, status.Estimate(faviconWorkers).Round(time.Second), faviconWorkers)
+	if status.Failed > 0 {
+		imp.logger.Info("  %d failed before and won't be retried (use -favicon-retry-failed to retry them)", status.Failed)
+	}
+	if status.Denied > 0 {
+		imp.logger.Info("  %d on denied domains", status.Denied)
+	}
+	return status
+}
+
+// networkStatsReporter is implemented by fetchers that count their HTTP
+// traffic, as *favicon.Fetcher does
+type networkStatsReporter interface {
+	NetworkStats() favicon.NetworkStats
+}
+
+// NetworkReport is what fetching favicons cost during one import, for users on
+// metered connections and for gauging the fetcher
+type NetworkReport struct {
+	favicon.NetworkStats
+	CacheHits   int // Favicons the pre-cache found cached (failures included)
+	CacheMisses int // Favicons the pre-cache had to request
+}
+
+// CacheLookups returns how many favicons the pre-cache looked up
+func (r *NetworkReport) CacheLookups() int {
+	return r.CacheHits + r.CacheMisses
+}
+
+// HitRatio returns the share of pre-cache lookups answered by the cache
+func (r *NetworkReport) HitRatio() float64 {
+	if r.CacheLookups() == 0 {
+		return 0
+	}
+	return float64(r.CacheHits) / float64(r.CacheLookups())
+}
+
+// String formats r as "12 requests, 48.2 KB, 180ms average, 62% cache hits (31/50)"
+func (r *NetworkReport) String() string {
+	s := fmt.Sprintf("%d requests, %s, %s average", r.Requests, formatBytes(uint64(r.Bytes)), r.AvgLatency().Round(time.Millisecond))
+	if r.Errors > 0 {
+		s += fmt.Sprintf(", %d failed", r.Errors)
+	}
+	if r.CacheLookups() > 0 {
+		s += fmt.Sprintf(", %.0f%% cache hits (%d/%d)", r.HitRatio()*100, r.CacheHits, r.CacheLookups())
+	}
+	return s
+}
+
+// simulateRestore checks the imported workspaces against Zen's restore rules
+func (imp *Importer) simulateRestore(session *types.ZenSession, plan *Plan) {
+	imp.logger.Info("Simulating Zen session restore...")
+	report := restoresim.Simulate(session)
+
+	var workspaces []string
+	for _, space := range plan.Spaces {
+		workspaces = append(workspaces, space.ID)
+	}
+	issues := report.ForWorkspaces(workspaces)
+	for _, issue := range issues {
+		imp.warnings.Add(WarningRestore, issue.Name, "%s: %s", issue.Kind, issue.Message)
+	}
+	if len(issues) == 0 {
+		imp.logger.Info("✓ Restore simulation: imported folders and tabs restore as planned")
+	} else {
+		imp.logger.Info("⚠ Restore simulation: %d problems in the imported workspaces", len(issues))
+	}
+}
+
+// maxWarningsPerCategory limits the summary output unless running verbose
+const maxWarningsPerCategory = 10
+
+// logWarnings prints collected warnings grouped by category. A LevelLogger
+// has had each of them already.
+func (imp *Importer) logWarnings() {
+	if _, ok := imp.logger.(LevelLogger); ok || imp.warnings.Len() == 0 {
+		return
+	}
+
+	grouped := imp.warnings.ByCategory()
+	imp.logger.Info("Warnings:")
+	for _, category := range warningCategories {
+		warnings := grouped[category]
+		if len(warnings) == 0 {
+			continue
+		}
+		imp.logger.Info("  %s (%d):", category, len(warnings))
+		for i, warning := range warnings {
+			if i == maxWarningsPerCategory && !imp.options.Verbose {
+				imp.logger.Info("    ... and %d more (use -verbose to see all)", len(warnings)-i)
+				break
+			}
+			if warning.Item == "" {
+				imp.logger.Info("    • %s", warning.Message)
+			} else {
+				imp.logger.Info("    • %s: %s", warning.Item, warning.Message)
+			}
+		}
+	}
+	if !imp.options.Strict {
+		imp.logger.Info("  (use -strict to fail instead of falling back to defaults)")
+	}
+	imp.logger.Info("")
+}
+
+// validateOptions rejects option values the importer doesn't understand
+func (imp *Importer) validateOptions() error {
+	switch imp.options.Only {
+	case "", OnlyFolders, OnlyTabs:
+	default:
+		return fmt.Errorf("invalid -only value %q (expected %q or %q)", imp.options.Only, OnlyFolders, OnlyTabs)
+	}
+	switch imp.options.EmptyURLs {
+	case "", EmptyURLSkip, EmptyURLKeep, EmptyURLNote:
+	default:
+		return fmt.Errorf("invalid -empty-urls value %q (expected %q, %q or %q)", imp.options.EmptyURLs, EmptyURLSkip, EmptyURLKeep, EmptyURLNote)
+	}
+	switch imp.options.FaviconStorage {
+	case "", FaviconStorageSession, FaviconStorageSQLite:
+	default:
+		return fmt.Errorf("invalid -favicon-storage value %q (expected %q or %q)", imp.options.FaviconStorage, FaviconStorageSession, FaviconStorageSQLite)
+	}
+	switch imp.options.EmptyFolders {
+	case "", EmptyFolderSkip, EmptyFolderKeep:
+	default:
+		return fmt.Errorf("invalid -empty-folders value %q (expected %q or %q)", imp.options.EmptyFolders, EmptyFolderSkip, EmptyFolderKeep)
+	}
+	switch imp.options.Theme {
+	case "", ThemeGradient, ThemeSolid, ThemeNone:
+	default:
+		return fmt.Errorf("invalid -theme value %q (expected %q, %q or %q)", imp.options.Theme, ThemeGradient, ThemeSolid, ThemeNone)
+	}
+	switch imp.options.OverBudget {
+	case "", OverBudgetWarn, OverBudgetDownscale, OverBudgetSkipFavicons, OverBudgetFail:
+	default:
+		return fmt.Errorf("invalid -over-budget value %q (expected %q, %q, %q or %q)", imp.options.OverBudget, OverBudgetWarn, OverBudgetDownscale, OverBudgetSkipFavicons, OverBudgetFail)
+	}
+	if err := validateShareLinks(imp.options.ShareLinks); err != nil {
+		return err
+	}
+	if err := validateExclude(imp.options.Exclude); err != nil {
+		return err
+	}
+	if imp.options.SessionBudget < 0 {
+		return fmt.Errorf("invalid -session-budget value (expected 0 or more)")
+	}
+	if err := imp.validateMergeStrategy(); err != nil {
+		return err
+	}
+	if err := imp.validateQuick(); err != nil {
+		return err
+	}
+	if err := imp.validateMode(); err != nil {
+		return err
+	}
+	if err := validateCombineSpaces(imp.options.CombineSpaces); err != nil {
+		return err
+	}
+	if err := validateSplitSpaces(imp.options.SplitSpaces); err != nil {
+		return err
+	}
+	if err := validateSort(imp.options.Sort); err != nil {
+		return err
+	}
+	if imp.options.MergeSmallFolders < 0 {
+		return fmt.Errorf("invalid -merge-small-folders value %d (expected 0 or more)", imp.options.MergeSmallFolders)
+	}
+	if imp.options.CollapsePinnedOver < 0 {
+		return fmt.Errorf("invalid -collapse-pinned value %d (expected 0 or more)", imp.options.CollapsePinnedOver)
+	}
+	for name, setting := range imp.options.SpaceSettings {
+		switch setting {
+		case SpaceCollapsed, SpaceExpanded:
+		default:
+			return fmt.Errorf("invalid -space-setting value %q for %q (expected %q or %q)", setting, name, SpaceCollapsed, SpaceExpanded)
+		}
+	}
+	principals, err := principal.NewPolicy(imp.options.TriggeringPrincipals)
+	if err != nil {
+		return fmt.Errorf("invalid -principal value: %w", err)
+	}
+	imp.principals = principals
+	if err := imp.validateDomainContainers(); err != nil {
+		return err
+	}
+	if len(imp.options.FaviconProviders) > 0 {
+		if _, err := favicon.ParseProviders(strings.Join(imp.options.FaviconProviders, ",")); err != nil {
+			return fmt.Errorf("invalid -favicon-provider value: %w", err)
+		}
+	}
+	rewriter, err := rewrite.New(imp.options.URLRewrites)
+	if err != nil {
+		return fmt.Errorf("invalid URL rewrite rules: %w", err)
+	}
+	imp.rewriter = rewriter
+	imp.zenVersion = nil
+	if imp.options.ZenVersion != "" {
+		version, err := parseZenVersion(imp.options.ZenVersion)
+		if err != nil {
+			return fmt.Errorf("invalid -zen-version value: %w", err)
+		}
+		imp.zenVersion = version
+	}
+	return nil
+}
+
 func (imp *Importer) validateZenProfile() error {
 	imp.logger.Info("Validating Zen profile path...")
 	if _, err := os.Stat(imp.zenProfilePath); os.IsNotExist(err) {
@@ -176,17 +853,89 @@ func (imp *Importer) validateZenProfile() error {
 func (imp *Importer) readArcData(arcDataPath string) (*types.ArcData, error) {
 	imp.logger.Info("Reading Arc data from: %s", arcDataPath)
 
-	data, err := os.ReadFile(arcDataPath)
+	// Arc rewrites the file while open, so read a copy it didn't change midway
+	running := arcdata.Running(arcDataPath)
+	if running {
+		imp.logger.Info("⚠ Arc appears to be running; reading a snapshot of its data. Quit Arc first to import its latest changes.")
+	}
+	snapshot, err := arcdata.TakeSnapshot(arcDataPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Arc data: %w", err)
 	}
+	defer snapshot.Close()
+	imp.arcDataHash = snapshot.Hash
+	if !snapshot.Stable {
+		imp.warnings.Add(WarningParse, arcDataPath, "Arc kept changing the file during %d reads; the import may be incomplete", snapshot.Attempts)
+	} else if snapshot.Attempts > 1 {
+		imp.logger.Info("  Arc changed its data while it was read; took %d copies", snapshot.Attempts)
+	}
 
-	var arcData types.ArcData
-	if err := json.Unmarshal(data, &arcData); err != nil {
-		return nil, fmt.Errorf("failed to parse Arc data: %w", err)
+	// Sidebars of heavy users run past 100MB; decode from the copy as a
+	// stream and keep only the containers' spaces and items
+	arcData, err := imp.decodeSnapshot(snapshot)
+	if err != nil {
+		// Damage at the end of the file still leaves most spaces importable
+		var repaired []byte
+		var recovery *arcdata.Recovery
+		data, recoverErr := snapshot.ReadAll()
+		if recoverErr == nil {
+			repaired, recovery, recoverErr = arcdata.Recover(data)
+		}
+		if recoverErr == nil {
+			arcData, recoverErr = arcdata.Decode(bytes.NewReader(repaired))
+		}
+		if recoverErr != nil {
+			if running || !snapshot.Stable {
+				return nil, fmt.Errorf("failed to parse Arc data (Arc may have been writing it; quit Arc and try again): %w", err)
+			}
+			return nil, fmt.Errorf("failed to parse Arc data: %w", err)
+		}
+		imp.logger.Info("⚠ Arc data is damaged (%v); importing what could be salvaged: %s", err, recovery)
+		imp.logger.Info("  Spaces, folders or tabs near the end of the file may be missing. Check the result, or quit Arc and import again.")
+		imp.warnings.Add(WarningParse, arcDataPath, "damaged Arc data only partly imported: %s", recovery)
 	}
 
-	return &arcData, nil
+	arcData.ProfileNames = imp.readArcProfileNames(arcDataPath)
+
+	return arcData, nil
+}
+
+// decodeSnapshot decodes the sidebar data from a snapshot's copy
+func (imp *Importer) decodeSnapshot(snapshot *arcdata.Snapshot) (*types.ArcData, error) {
+	file, err := snapshot.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return arcdata.Decode(file)
+}
+
+// readArcProfileNames reads profile display names from Arc's "User Data/Local State"
+// file next to StorableSidebar.json. Missing or unreadable metadata is not fatal.
+func (imp *Importer) readArcProfileNames(arcDataPath string) map[string]string {
+	names := make(map[string]string)
+	localStatePath := filepath.Join(filepath.Dir(arcDataPath), "User Data", "Local State")
+
+	data, err := os.ReadFile(localStatePath)
+	if err != nil {
+		imp.debug("  Arc profile metadata not found at %s - using profile directory names", localStatePath)
+		return names
+	}
+
+	var localState types.ArcLocalState
+	if err := json.Unmarshal(data, &localState); err != nil {
+		imp.warnings.Add(WarningParse, localStatePath, "could not parse Arc profile metadata (using profile directory names): %v", err)
+		return names
+	}
+
+	if localState.Profile != nil {
+		for dir, info := range localState.Profile.InfoCache {
+			if info.Name != "" {
+				names[dir] = info.Name
+			}
+		}
+	}
+	return names
 }
 
 func (imp *Importer) readZenSession() (*types.ZenSession, error) {
@@ -212,19 +961,25 @@ func (imp *Importer) readZenSession() (*types.ZenSession, error) {
 		return nil, fmt.Errorf("failed to read session file: %w", err)
 	}
 
-	decompressedData, err := mozlz4.Decompress(compressedData)
+	session, err := sessionfile.Decode(compressedData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decompress session: %w", err)
+		return nil, err
 	}
 
-	var session types.ZenSession
-	if err := json.Unmarshal(decompressedData, &session); err != nil {
-		return nil, fmt.Errorf("failed to parse session: %w", err)
+	// Older sessions omit folders, groups and split views; Zen expects arrays, not null
+	if session.Folders == nil {
+		session.Folders = []types.ZenFolder{}
+	}
+	if session.Groups == nil {
+		session.Groups = []types.ZenGroup{}
+	}
+	if session.SplitViewData == nil {
+		session.SplitViewData = []interface{}{}
 	}
 
 	imp.logger.Info("✓ Session loaded: %d spaces, %d tabs", len(session.Spaces), len(session.Tabs))
 
-	return &session, nil
+	return session, nil
 }
 
 func (imp *Importer) readContainers() (*types.ContainersData, error) {
@@ -234,7 +989,7 @@ func (imp *Importer) readContainers() (*types.ContainersData, error) {
 	if _, err := os.Stat(containersPath); os.IsNotExist(err) {
 		// Create default containers data
 		return &types.ContainersData{
-			Version:    5,
+			Version:    ContainersVersion,
 			Identities: []types.ContainerIdentity{},
 		}, nil
 	}
@@ -252,10 +1007,97 @@ func (imp *Importer) readContainers() (*types.ContainersData, error) {
 	return &containersData, nil
 }
 
-func (imp *Importer) writeContainers(data *types.ContainersData) error {
-	containersPath := filepath.Join(imp.zenProfilePath, "containers.json")
-	imp.logger.Info("Writing containers.json...")
+// writeProfile writes containers.json, the session file and the import
+// manifest together. All are staged first so a failure leaves the profile as
+// it was.
+func (imp *Importer) writeProfile(session *types.ZenSession, containersData *types.ContainersData, record manifest.Import) error {
+	containersJSON, err := imp.encodeContainers(containersData)
+	if err != nil {
+		return err
+	}
+	sessionData, err := sessionfile.Encode(session)
+	if err != nil {
+		return err
+	}
+	files := []sessionfile.File{
+		{Path: filepath.Join(imp.zenProfilePath, "containers.json"), Data: containersJSON},
+		{Path: sessionfile.Path(imp.zenProfilePath), Data: sessionData},
+	}
+
+	// A manifest that can't be read is left alone rather than replaced
+	recorded := false
+	if m, err := manifest.Load(imp.zenProfilePath); err != nil {
+		imp.warnings.Add(WarningWrite, manifest.Path(imp.zenProfilePath), "import not recorded: %v", err)
+	} else {
+		m.Add(record)
+		manifestData, err := m.Encode()
+		if err != nil {
+			return err
+		}
+		files = append(files, sessionfile.File{Path: manifest.Path(imp.zenProfilePath), Data: manifestData})
+		recorded = true
+	}
+
+	// Create backup first
+	sessionPath := filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")
+	if err := imp.backupSession(); err != nil {
+		imp.warnings.Add(WarningWrite, sessionPath, "failed to create backup: %v", err)
+	}
+
+	imp.logger.Info("Writing containers.json and Zen session file...")
+	if err := sessionfile.WriteTogether(files); err != nil {
+		return err
+	}
+
+	imp.logger.Info("✓ Updated containers.json")
+	imp.logger.Info("✓ Session file written successfully")
+	if recorded {
+		imp.logger.Info("✓ Recorded import %s in %s", record.ID, manifest.FileName)
+	}
+	return nil
+}
+
+// manifestRecord lists what the import created, by the IDs Zen keeps
+func (imp *Importer) manifestRecord(plan *Plan, arcDataPath string) manifest.Import {
+	record := manifest.Import{
+		ID:          imp.importID,
+		ToolVersion: manifest.Version(),
+		Time:        time.Now().UTC(),
+		ArcData:     arcDataPath,
+		Spaces:      []manifest.Space{},
+		Folders:     []manifest.Object{},
+		Tabs:        []manifest.Object{},
+	}
+	for _, space := range plan.Spaces {
+		record.Spaces = append(record.Spaces, manifest.Space{
+			UUID:        space.ID,
+			Name:        space.Name,
+			ArcID:       space.ArcID,
+			ArcName:     space.ArcName,
+			ArcProfile:  space.Profile,
+			ContainerID: space.ContainerID,
+			Merged:      space.Merged,
+		})
+	}
+	for _, container := range plan.Containers {
+		record.Containers = append(record.Containers, manifest.Container{
+			UserContextID: container.ID,
+			Name:          container.Name,
+			ArcProfile:    container.Profile,
+			Created:       container.Created,
+		})
+	}
+	for _, folder := range plan.Folders {
+		record.Folders = append(record.Folders, manifest.Object{ID: folder.ID, ArcID: folder.ArcID, SpaceUUID: folder.SpaceID})
+	}
+	for _, tab := range plan.Tabs {
+		record.Tabs = append(record.Tabs, manifest.Object{ID: tab.ID, ArcID: tab.ArcID, SpaceUUID: tab.SpaceID})
+	}
+	return record
+}
 
+// encodeContainers drops invalid containers and returns containers.json contents
+func (imp *Importer) encodeContainers(data *types.ContainersData) ([]byte, error) {
 	// Clean up invalid containers (those with null/missing userContextId)
 	// Keep: internal containers (public=false), containers with valid userContextId
 	var validIdentities []types.ContainerIdentity
@@ -271,7 +1113,7 @@ func (imp *Importer) writeContainers(data *types.ContainersData) error {
 			validIdentities = append(validIdentities, container)
 		} else {
 			invalidCount++
-			imp.logger.Info("  Removing invalid container: %q (null/missing userContextId)", container.Name)
+			imp.warnings.Add(WarningWrite, container.Name, "removed invalid container (null/missing userContextId)")
 		}
 	}
 	if invalidCount > 0 {
@@ -281,45 +1123,9 @@ func (imp *Importer) writeContainers(data *types.ContainersData) error {
 
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal containers: %w", err)
+		return nil, fmt.Errorf("failed to marshal containers: %w", err)
 	}
-
-	if err := os.WriteFile(containersPath, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write containers: %w", err)
-	}
-
-	imp.logger.Info("✓ Updated containers.json")
-	return nil
-}
-
-func (imp *Importer) writeZenSession(session *types.ZenSession) error {
-	sessionPath := filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")
-	imp.logger.Info("Writing Zen session file...")
-
-	// Create backup first
-	if err := imp.backupSession(); err != nil {
-		imp.logger.Error("Warning: failed to create backup: %v", err)
-	}
-
-	// Marshal to JSON (no indentation for compression)
-	jsonData, err := json.Marshal(session)
-	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
-	}
-
-	// Compress
-	compressedData, err := mozlz4.Compress(jsonData)
-	if err != nil {
-		return fmt.Errorf("failed to compress session: %w", err)
-	}
-
-	// Write
-	if err := os.WriteFile(sessionPath, compressedData, 0644); err != nil {
-		return fmt.Errorf("failed to write session: %w", err)
-	}
-
-	imp.logger.Info("✓ Session file written successfully")
-	return nil
+	return jsonData, nil
 }
 
 func (imp *Importer) backupSession() error {
@@ -361,6 +1167,7 @@ func (imp *Importer) backupSession() error {
 }
 
 func (imp *Importer) doImport(
+	ctx context.Context,
 	arcData *types.ArcData,
 	zenSession *types.ZenSession,
 	containersData *types.ContainersData,
@@ -371,19 +1178,29 @@ func (imp *Importer) doImport(
 	}
 
 	mainContainer := arcData.Sidebar.Containers[1]
+	imp.plan = &Plan{}
+	var network *NetworkReport
+	netStats, countsNetwork := imp.faviconFetcher.(networkStatsReporter)
+	if countsNetwork {
+		network = &NetworkReport{NetworkStats: netStats.NetworkStats()} // Baseline; the fetcher may be reused
+	}
+	cacheSavedBefore := imp.faviconFetcher.DedupSavedBytes() // Likewise
 
 	// Parse spaces
-	spaces, err := parseArcSpaces(mainContainer.Spaces)
+	spaces, err := parseArcSpaces(mainContainer.Spaces, imp.warnings)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse items
-	items, err := parseArcItems(mainContainer.Items)
+	items, unparsed, err := parseArcItems(mainContainer.Items, imp.warnings)
 	if err != nil {
 		return nil, err
 	}
 	imp.logger.Info("Found %d Arc items", len(items))
+	if err := imp.reportUnparsed(unparsed); err != nil {
+		return nil, err
+	}
 
 	// If no spaces found, create a synthetic default space with all root items
 	if len(spaces) == 0 {
@@ -392,51 +1209,52 @@ func (imp *Importer) doImport(
 	}
 	imp.logger.Info("Found %d Arc spaces", len(spaces))
 
-	// Collect unique profiles (Arc profiles map to Zen containers)
-	// Multiple Arc spaces can share the same profile/container
-	profiles := collectUniqueProfiles(spaces)
-	imp.logger.Info("Found %d unique profiles", len(profiles))
+	// Limit to a single Arc profile if requested
+	if imp.options.ArcProfile != "" {
+		selected := filterSpacesByProfile(spaces, imp.options.ArcProfile, arcData.ProfileNames)
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("no Arc spaces use profile %q (available: %s)",
+				imp.options.ArcProfile, strings.Join(describeProfiles(spaces, arcData.ProfileNames), ", "))
+		}
+		imp.logger.Info("Importing %d of %d spaces for Arc profile \"%s\"", len(selected), len(spaces), imp.options.ArcProfile)
+		spaces = selected
+	}
 
-	// Calculate next container ID
-	nextContainerID := calculateNextContainerID(containersData)
+	// Build item lookup map
+	itemsMap := make(map[string]*types.ArcItem)
+	for _, item := range items {
+		itemsMap[item.ID] = item
+	}
 
-	// Create containers for each unique profile
-	for profileName, profile := range profiles {
-		// Check if container already exists for this profile
-		existingContainer := findContainerByName(containersData.Identities, profile.DisplayName)
-		if existingContainer != nil {
-			profile.ContainerID = existingContainer.GetUserContextID()
-			if !imp.options.DryRun {
-				imp.logger.Info("Reusing existing container \"%s\" for profile \"%s\" (ID: %d)", 
-					profile.DisplayName, profileName, profile.ContainerID)
-			} else {
-				imp.logger.Info("[DRY-RUN] Would reuse container \"%s\" for profile \"%s\" (ID: %d)", 
-					profile.DisplayName, profileName, profile.ContainerID)
-			}
-		} else {
-			// Create new container for this profile
-			profile.ContainerID = nextContainerID
-			nextContainerID++
-
-			containersData.Identities = append(containersData.Identities, types.ContainerIdentity{
-				UserContextID: &profile.ContainerID,
-				Name:          profile.DisplayName,
-				Icon:          mappings.MapArcIconToContainerIcon(profile.Icon),
-				Color:         mappings.MapArcColorToZen(profile.Color),
-				Public:        true,
-			})
+	// Leave out the likely junk of the -exclude categories
+	spaces, err = imp.applyExclude(spaces, nil, itemsMap)
+	if err != nil {
+		return nil, err
+	}
+	// Let the user pick what to import (-interactive)
+	spaces, err = imp.applySelection(spaces, itemsMap)
+	if err != nil {
+		return nil, err
+	}
+	if imp.options.Quick > 0 {
+		return imp.quickImport(ctx, spaces, itemsMap, zenSession, cacheSavedBefore)
+	}
+	spaces, combined := imp.combineSpaces(spaces, itemsMap, imp.combineTargets(spaces, zenSession))
+	items = append(items, combined...)
+	spaces = imp.splitSpaces(spaces, itemsMap)
+	imp.planSmallFolders(spaces, itemsMap)
 
-			// Update lastUserContextId
-			containersData.LastUserContextID = &profile.ContainerID
+	// Collect unique profiles (Arc profiles map to Zen containers)
+	// Multiple Arc spaces can share the same profile/container
+	profiles := collectUniqueProfiles(spaces, arcData.ProfileNames)
+	imp.logger.Info("Found %d unique profiles", len(profiles))
 
-			if !imp.options.DryRun {
-				imp.logger.Info("Created container \"%s\" for profile \"%s\" (ID: %d)", 
-					profile.DisplayName, profileName, profile.ContainerID)
-			} else {
-				imp.logger.Info("[DRY-RUN] Would create container \"%s\" for profile \"%s\" (ID: %d)", 
-					profile.DisplayName, profileName, profile.ContainerID)
-			}
-		}
+	// Assign a container to each unique profile
+	if err := imp.assignContainers(profiles, containersData, zenSession); err != nil {
+		return nil, err
+	}
+	if err := imp.assignDomainContainers(containersData, zenSession); err != nil {
+		return nil, err
 	}
 
 	// Map space IDs to UUIDs
@@ -451,13 +1269,31 @@ func (imp *Importer) doImport(
 	}
 	nextSpacePosition := maxSpacePosition + 1000
 
-	spacesCreated := 0
+	// Move leading title emoji to the icon before names are made unique
+	var nameEmojis map[string]string
+	if imp.options.EmojiFromName {
+		spaces, nameEmojis = stripNameEmojis(spaces)
+	}
+
+	// Give Arc spaces with duplicate titles distinct workspace names so they don't merge
+	spaceNames := uniqueSpaceNames(spaces, imp.warnings)
 
-	// Process each space
+	// Resolve each space's workspace up front. The session itself is only changed
+	// once a space's items have been built, so a broken space leaves it untouched.
+	var jobs []spaceJob
+	var targets []spaceTarget
+	var spaceErrors []*SpaceError
 	for _, space := range spaces {
-		spaceName := space.Title
-		if spaceName == "" {
-			spaceName = fmt.Sprintf("Workspace %s", space.ID)
+		spaceName := spaceNames[space.ID]
+
+		if err := validateSpaceItems(space, itemsMap); err != nil {
+			spaceErr := &SpaceError{Space: spaceName, Err: err}
+			if imp.failFast() {
+				return nil, spaceErr
+			}
+			imp.logger.Error("Skipping space \"%s\": %v", spaceName, err)
+			spaceErrors = append(spaceErrors, spaceErr)
+			continue
 		}
 
 		// Extract icon
@@ -468,154 +1304,301 @@ func (imp *Importer) doImport(
 		if arcIcon == "" {
 			arcIcon = space.Icon
 		}
+		nameEmoji := nameEmojis[space.ID]
+		deriveIcon := imp.options.SpaceIconFromFavicons && !mappings.IsMappedArcIcon(arcIcon) && nameEmoji == ""
+		letterIcon := avatar.FirstLetter(spaceName)
+		if imp.options.LetterAvatars {
+			letterIcon = avatar.Letter(spaceName, spaceColorHex(space))
+		}
+		if arcIcon != "" && !mappings.IsMappedArcIcon(arcIcon) && nameEmoji == "" {
+			switch {
+			case deriveIcon:
+				imp.warnings.Add(WarningMapping, spaceName, "unmapped space icon %q (using one derived from its tabs)", arcIcon)
+			case imp.options.LetterAvatars && letterIcon != "":
+				imp.warnings.Add(WarningMapping, spaceName, "unmapped space icon %q (using a letter avatar)", arcIcon)
+			default:
+				imp.warnings.Add(WarningMapping, spaceName, "unmapped space icon %q (using default)", arcIcon)
+			}
+		}
 
 		// Get the container ID from the space's profile
 		profileName := getProfileName(space)
 		profile := profiles[profileName]
-		containerID := profile.ContainerID
-
-		// Check if space exists
-		var spaceUUID string
-		existingSpace := findSpaceByName(zenSession.Spaces, spaceName)
 
-		if existingSpace != nil {
-			// Merge into existing
-			spaceUUID = existingSpace.UUID
-
-			if !imp.options.DryRun {
-				imp.logger.Info("Merging into existing space \"%s\" (profile: %s, container: %d)", spaceName, profileName, containerID)
-			} else {
-				imp.logger.Info("[DRY-RUN] Would merge into existing space: \"%s\" (profile: %s)", spaceName, profileName)
-			}
-
-			// Delete old pins for this workspace
-			zenSession.Tabs = filterTabs(zenSession.Tabs, spaceUUID)
-			zenSession.Folders = filterFolders(zenSession.Folders, spaceUUID)
-
-			// Update space icon and container
-			for i := range zenSession.Spaces {
-				if zenSession.Spaces[i].UUID == spaceUUID {
-					zenSession.Spaces[i].Icon = mappings.MapArcIconToSvg(arcIcon)
-					zenSession.Spaces[i].ContainerTabID = containerID
-					break
-				}
+		// Merge into an existing space with the same name, or create a new one
+		target := spaceTarget{
+			name:        spaceName,
+			icon:        mappings.MapArcIconToSvg(arcIcon),
+			profileName: profileName,
+			profile:     profile,
+			deriveIcon:  deriveIcon,
+			letterIcon:  letterIcon,
+			theme:       zenTheme(space, imp.options.Theme),
+		}
+		if imp.options.LetterAvatars && letterIcon != "" && !mappings.IsMappedArcIcon(arcIcon) {
+			target.icon = letterIcon
+		}
+		if nameEmoji != "" {
+			target.icon = nameEmoji
+		}
+		if deriveIcon && space.CustomInfo != nil && space.CustomInfo.IconType != nil {
+			target.emoji = space.CustomInfo.IconType.Emoji
+		}
+		if existingSpace := findSpaceByName(zenSession.Spaces, spaceName); existingSpace != nil {
+			if imp.options.MergeStrategy == MergeSkip {
+				imp.logger.Info("Skipping space \"%s\": a workspace of that name exists (-merge skip)", spaceName)
+				imp.plan.addSkipped(SkippedItem{ID: space.ID, Title: spaceName, SpaceID: existingSpace.UUID, Reason: existingSpaceReason})
+				continue
 			}
+			target.uuid = existingSpace.UUID
+			target.merge = true
+			target.keep = imp.options.Mode == ModeFolders
 		} else {
-			// Create new space
-			spaceUUID = fmt.Sprintf("{%s}", uuid.New().String())
-
-			// Add new space using the profile's container
-			zenSession.Spaces = append(zenSession.Spaces, types.ZenSpace{
-				UUID:           spaceUUID,
-				Name:           spaceName,
-				Icon:           mappings.MapArcIconToSvg(arcIcon),
-				ContainerTabID: containerID,
-				Position:       nextSpacePosition,
-				Theme: types.ZenTheme{
-					Type:           "gradient",
-					GradientColors: []interface{}{},
-					Opacity:        0.5,
-					Rotation:       nil,
-					Texture:        nil,
-				},
-				HasCollapsedPinnedTabs: false,
-			})
-
-			if !imp.options.DryRun {
-				imp.logger.Info("Created space \"%s\" (profile: %s, container: %d)", spaceName, profileName, containerID)
-			} else {
-				imp.logger.Info("[DRY-RUN] Would create space: \"%s\" (profile: %s)", spaceName, profileName)
-			}
-			nextSpacePosition += 1000
+			target.uuid = imp.checkpoint.spaceUUID(space.ID)
 		}
 
-		spaceUUIDMap[space.ID] = spaceUUID
-		spacesCreated++
+		spaceUUIDMap[space.ID] = target.uuid
+		jobs = append(jobs, spaceJob{space: space, name: spaceName, containerID: profile.ContainerID})
+		targets = append(targets, target)
 	}
 
-	// Build item lookup map
-	itemsMap := make(map[string]*types.ArcItem)
-	for _, item := range items {
-		itemsMap[item.ID] = item
+	// Arc share links become folders of the links they share
+	items = imp.resolveShareLinks(ctx, jobs, items, itemsMap)
+
+	// Filter out Arc internal containers
+	itemsToProcess := filterArcContainers(items)
+
+	// Pre-generate UUIDs for all items (reused when resuming)
+	arcToZenUUIDMap := make(map[string]string)
+	for _, item := range itemsToProcess {
+		arcToZenUUIDMap[item.ID] = imp.checkpoint.itemUUID(item.ID)
+	}
+	if err := imp.checkpoint.advance(phasePlanned); err != nil {
+		imp.warnings.Add(WarningWrite, "", "%v", err)
 	}
 
 	// Build item-to-space mapping (for future use)
 	// itemToSpaceMap := buildItemToSpaceMap(spaces, itemsMap)
 
-	// Pre-cache favicons for all URLs
-	allURLs := collectAllURLs(items, itemsMap)
-	if len(allURLs) > 0 {
+	// Pre-cache favicons for all URLs in the spaces being imported
+	var spaceRootItems []*types.ArcItem
+	for _, job := range jobs {
+		spaceRootItems = append(spaceRootItems, getRootItemsForSpace(job.space, itemsMap)...)
+	}
+	var favorites []arcFavorites
+	if imp.options.FavoritesAsEssentials {
+		favorites = findArcFavorites(items)
+		for _, favorite := range favorites {
+			if profiles[favorite.profile] != nil {
+				spaceRootItems = append(spaceRootItems, favorite.container)
+			}
+		}
+	}
+	allURLs := imp.rewriteURLs(collectAllURLs(spaceRootItems, itemsMap))
+	var faviconCache *favicon.CacheStatus
+	if imp.checkpoint.reached(phaseFavicons) {
+		imp.logger.Info("Favicons were pre-cached by the interrupted import, skipping")
+	} else if len(allURLs) > 0 && imp.options.DryRun {
+		faviconCache = imp.reportFaviconCache(allURLs)
+	} else if len(allURLs) > 0 {
 		imp.logger.Info("Pre-caching favicons for %d URLs...", len(allURLs))
-		
+
 		// Spinner characters for animation
 		spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 		spinIdx := 0
-		
+
 		// Progress callback with spinner
-		progress := func(processed, total int) {
-			fmt.Printf("\r%s Fetching favicons... %d/%d", spinner[spinIdx%len(spinner)], processed, total)
+		progress := func(p favicon.Progress) {
+			fmt.Printf("\r%s %-60s", spinner[spinIdx%len(spinner)], p)
 			spinIdx++
 		}
-		
-		result := imp.faviconFetcher.PreCacheFaviconsWithProgress(allURLs, 10, progress)
-		fmt.Print("\r") // Clear the spinner line
-		imp.logger.Info("✓ Favicon pre-cache complete: %d cached, %d fetched, %d failed", 
+
+		result := imp.faviconFetcher.PreCacheFaviconsContext(ctx, allURLs, faviconWorkers, progress)
+		fmt.Printf("\r%-62s\r", "") // Clear the spinner line
+		imp.logger.Info("✓ Favicon pre-cache complete: %d cached, %d fetched, %d failed",
 			result.Cached, result.Fetched, result.Failed)
+		if network != nil {
+			network.CacheHits = result.Cached
+			network.CacheMisses = result.Fetched + result.Failed
+		}
+		if result.Denied > 0 {
+			imp.logger.Info("  Skipped %d favicons on denied domains", result.Denied)
+		}
+		if result.Blocked > 0 {
+			imp.logger.Info("  Skipped %d favicons on private/local hosts (use -allow-private-hosts to fetch them)", result.Blocked)
+		}
+		if result.Canceled > 0 {
+			imp.logger.Info("  Time limit reached: continuing without %d favicons", result.Canceled)
+		}
 	} else {
 		imp.logger.Info("No URLs to fetch favicons for")
 	}
-
-	// Filter out Arc internal containers
-	itemsToProcess := filterArcContainers(items)
-
-	// Pre-generate UUIDs for all items
-	arcToZenUUIDMap := make(map[string]string)
-	for _, item := range itemsToProcess {
-		arcToZenUUIDMap[item.ID] = fmt.Sprintf("{%s}", uuid.New().String())
+	if ctx.Err() == nil {
+		if err := imp.checkpoint.advance(phaseFavicons); err != nil {
+			imp.warnings.Add(WarningWrite, "", "%v", err)
+		}
 	}
 
 	// Process items
-	now := time.Now().UnixMilli()
+	now := imp.checkpoint.timestamp()
 	pinsCreated := 0
-
-	// Track the last folder created for each parent (for sibling references in nested folders)
-	lastFolderByParent := make(map[string]string)
+	spacesCreated := 0
+	imp.folderSeq = &atomic.Int64{}
+	imp.folderSeq.Store(int64(len(zenSession.Folders)))
 
 	imp.logger.Info("Creating items...")
-	for _, space := range spaces {
-		spaceTitle := space.Title
-		if spaceTitle == "" {
-			spaceTitle = fmt.Sprintf("Workspace %s", space.ID)
-		}
-		imp.logger.Info("Processing space: \"%s\"", spaceTitle)
-
-		// Get root items for this space
-		rootItems := getRootItemsForSpace(space, itemsMap)
-		imp.logger.Info("Found %d root items", len(rootItems))
-
-		for _, rootItem := range rootItems {
-			pinsCreated += imp.insertItemWithChildren(
-				rootItem,
-				"",
-				space.ID,
-				spaceUUIDMap,
-				space,
-				itemsMap,
-				arcToZenUUIDMap,
-				containersData,
-				zenSession,
-				now,
-				0,
-				lastFolderByParent,
-			)
+	builds := imp.buildSpaces(ctx, jobs, spaceUUIDMap, itemsMap, arcToZenUUIDMap, now)
+
+	// Delete the old pins of every workspace merged into in one pass, before
+	// the new ones are added; spaces that failed to build keep theirs
+	replaced := make(map[string]bool)
+	for i, build := range builds {
+		if !targets[i].merge || build.err != nil {
+			continue
 		}
+		switch {
+		case imp.options.MergeStrategy == MergeDedupe:
+			// Nothing is removed; what is pinned there already isn't added again
+			if skipped := dedupeBuild(build, zenSession, targets[i].uuid); skipped > 0 {
+				build.log.Info("Leaving out %d tabs already pinned in \"%s\" (-merge dedupe)", skipped, targets[i].name)
+			}
+		case imp.options.MergeStrategy == MergeAppend:
+			// Nothing is removed; the imported items go after the pins there
+		case targets[i].keep:
+			// Only the top-level folders imported again replace their earlier copies
+			names := make(map[string]bool)
+			for _, folder := range build.plan.Folders {
+				if folder.ParentID == "" {
+					names[folder.Name] = true
+				}
+			}
+			removeRootFolders(zenSession, targets[i].uuid, names)
+		default:
+			replaced[targets[i].uuid] = true
+		}
+	}
+	if len(replaced) > 0 {
+		zenSession.Tabs = filterTabs(zenSession.Tabs, replaced)
+		zenSession.Folders = filterFolders(zenSession.Folders, replaced)
+	}
+
+	for i, build := range builds {
+		target := targets[i]
+		if build.err != nil {
+			build.log.replay(imp.logger, imp.options.Verbose)
+			spaceErr := &SpaceError{Space: target.name, Err: build.err}
+			if imp.failFast() {
+				return nil, spaceErr
+			}
+			imp.logger.Error("Skipping space \"%s\": %v", target.name, build.err)
+			spaceErrors = append(spaceErrors, spaceErr)
+			continue
+		}
+
+		if target.deriveIcon {
+			target.icon = derivedSpaceIcon(build.plan.Tabs, target.emoji, target.letterIcon, target.icon)
+		}
+
+		containerID := target.profile.ContainerID
+		collapsed, collapseSet := imp.collapsePinned(target.name, len(build.plan.Tabs))
+		if target.merge {
+			if !imp.options.DryRun {
+				imp.logger.Info("Merging into existing space \"%s\" (profile: %s, container: %d)", target.name, target.profileName, containerID)
+			} else {
+				imp.logger.Info("[DRY-RUN] Would merge into existing space: \"%s\" (profile: %s)", target.name, target.profileName)
+			}
+
+			// Update space icon and container, unless it's the user's own workspace
+			for i := range zenSession.Spaces {
+				if target.keep {
+					break
+				}
+				if zenSession.Spaces[i].UUID == target.uuid {
+					zenSession.Spaces[i].Icon = target.icon
+					zenSession.Spaces[i].ContainerTabID = containerID
+					if collapseSet {
+						zenSession.Spaces[i].HasCollapsedPinnedTabs = collapsed
+					}
+					if existing := &zenSession.Spaces[i].Theme; len(existing.GradientColors) == 0 && len(target.theme.GradientColors) > 0 {
+						// Keep a gradient set in Zen, and fields this version doesn't model
+						existing.GradientColors = target.theme.GradientColors
+						existing.Opacity = target.theme.Opacity
+						existing.Rotation = target.theme.Rotation
+					}
+					break
+				}
+			}
+		} else {
+			// Add new space using the profile's container
+			zenSession.Spaces = append(zenSession.Spaces, types.ZenSpace{
+				UUID:                   target.uuid,
+				Name:                   target.name,
+				Icon:                   target.icon,
+				ContainerTabID:         containerID,
+				Position:               nextSpacePosition,
+				Theme:                  target.theme,
+				HasCollapsedPinnedTabs: collapsed,
+			})
+
+			if !imp.options.DryRun {
+				imp.logger.Info("Created space \"%s\" (profile: %s, container: %d)", target.name, target.profileName, containerID)
+			} else {
+				imp.logger.Info("[DRY-RUN] Would create space: \"%s\" (profile: %s)", target.name, target.profileName)
+			}
+			nextSpacePosition += 1000
+		}
+
+		build.log.replay(imp.logger, imp.options.Verbose)
+		build.mergeInto(zenSession)
+		imp.plan.addSpace(PlannedSpace{
+			ID:          target.uuid,
+			Name:        target.name,
+			Icon:        target.icon,
+			Profile:     target.profile.DisplayName,
+			ContainerID: containerID,
+			Merged:      target.merge,
+			KeptPins:    target.keep || (target.merge && imp.keepsPins()),
+			ArcID:       jobs[i].space.ID,
+			ArcName:     jobs[i].space.Title,
+		})
+		if setting := jobs[i].space.AutoArchive; setting != nil {
+			imp.plan.addArchive(target.uuid, target.name, setting)
+		}
+		imp.plan.merge(build.plan)
+		imp.warnings.addAll(build.warnings)
+		pinsCreated += build.items
+		spacesCreated++
+	}
+
+	if spacesCreated == 0 && len(spaceErrors) > 0 {
+		return nil, fmt.Errorf("none of the %d spaces could be imported: %w", len(spaceErrors), spaceErrors[0])
+	}
+
+	// Arc's favorites bar becomes the essentials of each profile's container
+	if len(favorites) > 0 {
+		pinsCreated += imp.importFavorites(ctx, favorites, profiles, itemsMap, arcToZenUUIDMap, zenSession, now)
+	}
+	imp.logRewrites()
+
+	faviconTabs, faviconImages := imp.plan.faviconCounts()
+	if network != nil {
+		network.NetworkStats = netStats.NetworkStats().Sub(network.NetworkStats)
 	}
 
 	return &ImportResult{
-		Success:         true,
-		SpacesCreated:   spacesCreated,
-		ItemsImported:   pinsCreated,
-		ContainersCount: len(containersData.Identities),
+		Success:             true,
+		SpacesCreated:       spacesCreated,
+		ItemsImported:       pinsCreated,
+		ContainersCount:     len(containersData.Identities),
+		SpaceErrors:         spaceErrors,
+		Plan:                imp.plan,
+		FaviconTabs:         faviconTabs,
+		FaviconImages:       faviconImages,
+		FaviconCacheSaved:   imp.faviconFetcher.DedupSavedBytes() - cacheSavedBefore,
+		FaviconCache:        faviconCache,
+		Network:             network,
+		UnparsedItems:       unparsed,
+		EmptyFoldersSkipped: imp.plan.countSkipped(emptyFolderReason),
 	}, nil
 }
 
@@ -0,0 +1,748 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/principal"
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+// testLogger discards import narration
+type testLogger struct{}
+
+func (l *testLogger) Info(format string, args ...interface{})  {}
+func (l *testLogger) Error(format string, args ...interface{}) {}
+
+// testSite is the origin of test tab URLs; notFoundTransport answers for it
+const testSite = "https://site.test"
+
+// notFoundTransport answers every request with a 404 without touching the network
+type notFoundTransport struct{}
+
+func (notFoundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+// roundTripFunc lets a function stand in for the HTTP layer
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return fn(req) }
+
+// fixedClock always reports the same time
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+// newTestImporter returns an importer whose favicon fetcher never leaves the machine
+func newTestImporter(t *testing.T, opts ImportOptions) *Importer {
+	t.Helper()
+	opts.FaviconFetcher = favicon.NewWithOptions(favicon.Options{
+		CacheDir:  t.TempDir(),
+		Transport: notFoundTransport{},
+		Clock:     fixedClock{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	return NewWithOptions(t.TempDir(), &testLogger{}, opts)
+}
+
+func parseTestArcData(t *testing.T, raw string) *types.ArcData {
+	t.Helper()
+	var arcData types.ArcData
+	if err := json.Unmarshal([]byte(raw), &arcData); err != nil {
+		t.Fatalf("invalid test Arc data: %v", err)
+	}
+	return &arcData
+}
+
+func emptySession() *types.ZenSession {
+	return &types.ZenSession{
+		Spaces:        []types.ZenSpace{},
+		Tabs:          []types.ZenTab{},
+		Folders:       []types.ZenFolder{},
+		Groups:        []types.ZenGroup{},
+		SplitViewData: []interface{}{},
+	}
+}
+
+// multiProfileArcData has two spaces sharing "Profile 1" and one on "Profile 2"
+func multiProfileArcData(t *testing.T, site string) *types.ArcData {
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "f1"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 1"}}}},
+				{"id": "s2", "title": "Home Media", "containerIDs": ["pinned", "t3"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 1"}}}},
+				{"id": "s3", "title": "Samsung", "containerIDs": ["pinned", "t4"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 2"}}}}
+			],
+			"items": [
+				{"id": "f1", "title": "Reading", "childrenIds": ["t1", "t2"], "data": {}},
+				{"id": "t1", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "%[1]s/one"}}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Two", "savedURL": "%[1]s/two"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Three", "savedURL": "%[1]s/three"}}},
+				{"id": "t4", "childrenIds": [], "data": {"tab": {"savedTitle": "Four", "savedURL": "%[1]s/four"}}}
+			]
+		}]}
+	}`, site))
+	arcData.ProfileNames = map[string]string{"Profile 1": "Home", "Profile 2": "Samsung"}
+	return arcData
+}
+
+// containerIDsByWorkspace returns the set of userContextIds used by tabs in each workspace name
+func containerIDsByWorkspace(session *types.ZenSession) map[string]map[int]bool {
+	names := make(map[string]string)
+	for _, space := range session.Spaces {
+		names[space.UUID] = space.Name
+	}
+	result := make(map[string]map[int]bool)
+	for _, tab := range session.Tabs {
+		name := names[tab.ZenWorkspace]
+		if result[name] == nil {
+			result[name] = make(map[int]bool)
+		}
+		result[name][tab.UserContextID] = true
+	}
+	return result
+}
+
+func TestDoImport_MultiSpaceProfilesShareContainer(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	session := emptySession()
+	containersData := &types.ContainersData{Version: 5}
+
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, containersData); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	ids := make(map[string]int)
+	for _, c := range containersData.Identities {
+		ids[c.Name] = c.GetUserContextID()
+	}
+	if len(ids) != 2 || ids["Home"] == 0 || ids["Samsung"] == 0 {
+		t.Fatalf("expected containers Home and Samsung, got %v", ids)
+	}
+
+	expected := map[string]int{"Personal": ids["Home"], "Home Media": ids["Home"], "Samsung": ids["Samsung"]}
+	for workspace, used := range containerIDsByWorkspace(session) {
+		if len(used) != 1 || !used[expected[workspace]] {
+			t.Errorf("workspace %q: expected all tabs in container %d, got %v", workspace, expected[workspace], used)
+		}
+	}
+	for _, space := range session.Spaces {
+		if space.ContainerTabID != expected[space.Name] {
+			t.Errorf("workspace %q: expected containerTabId %d, got %d", space.Name, expected[space.Name], space.ContainerTabID)
+		}
+	}
+}
+
+func TestDoImport_ReusesExistingContainerForProfile(t *testing.T) {
+	existingID := 9
+	containersData := &types.ContainersData{
+		Version: 5,
+		Identities: []types.ContainerIdentity{
+			{UserContextID: &existingID, Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+		},
+	}
+	imp := newTestImporter(t, ImportOptions{
+		ProfileContainers: map[string]string{"Profile 2": "Work", "Profile 1": ContainerNone},
+	})
+	session := emptySession()
+
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, containersData); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	if len(containersData.Identities) != 1 {
+		t.Errorf("expected no new containers, got %d", len(containersData.Identities))
+	}
+
+	expected := map[string]int{"Personal": 0, "Home Media": 0, "Samsung": existingID}
+	for workspace, used := range containerIDsByWorkspace(session) {
+		if len(used) != 1 || !used[expected[workspace]] {
+			t.Errorf("workspace %q: expected all tabs in container %d, got %v", workspace, expected[workspace], used)
+		}
+	}
+}
+
+func TestDoImport_UpdateContainers(t *testing.T) {
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Office", "containerIDs": ["pinned", "t1"], "color": "green",
+				 "customInfo": {"iconType": {"icon": "office"}},
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 1"}}}},
+				{"id": "s2", "title": "Plain", "containerIDs": ["pinned", "t2"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 2"}}}}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/1"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Two", "savedURL": "https://site.test/2"}}}
+			]
+		}]}
+	}`)
+	workID, homeID := 7, 8
+	newContainers := func() *types.ContainersData {
+		return &types.ContainersData{Version: 5, Identities: []types.ContainerIdentity{
+			{UserContextID: &workID, Name: "Work", Icon: "fingerprint", Color: "orange", Public: true},
+			{UserContextID: &homeID, Name: "Home", Icon: "tree", Color: "pink", Public: true},
+		}}
+	}
+	assignments := map[string]string{"Profile 1": "Work", "Profile 2": "Home"}
+
+	// Left alone by default
+	containersData := newContainers()
+	imp := newTestImporter(t, ImportOptions{ProfileContainers: assignments})
+	if _, err := imp.doImport(context.Background(), arcData, emptySession(), containersData); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if !reflect.DeepEqual(containersData, newContainers()) {
+		t.Errorf("expected containers untouched, got %+v", containersData.Identities)
+	}
+
+	containersData = newContainers()
+	imp = newTestImporter(t, ImportOptions{ProfileContainers: assignments, UpdateContainers: true})
+	result, err := imp.doImport(context.Background(), arcData, emptySession(), containersData)
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	work, home := containersData.Identities[0], containersData.Identities[1]
+	if work.Name != "Work" || work.Icon != "briefcase" || work.Color != "green" {
+		t.Errorf("expected Work to get the Arc icon and color, got %+v", work)
+	}
+	// No Arc icon or color to take: the rotated color isn't forced on it
+	if home.Icon != "tree" || home.Color != "pink" {
+		t.Errorf("expected Home unchanged, got %+v", home)
+	}
+	updated := map[string]bool{}
+	for _, container := range result.Plan.Containers {
+		updated[container.Name] = container.Updated
+	}
+	if !updated["Work"] || updated["Home"] {
+		t.Errorf("unexpected plan containers %+v", result.Plan.Containers)
+	}
+}
+
+func TestCollectUniqueProfiles_FallsBackToDirectoryName(t *testing.T) {
+	arcData := multiProfileArcData(t, "https://example.com")
+	spaces, err := parseArcSpaces(arcData.Sidebar.Containers[1].Spaces, &Warnings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := collectUniqueProfiles(spaces, map[string]string{"Profile 2": "Samsung"})
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %d", len(profiles))
+	}
+	if got := profiles["Profile 1"].DisplayName; got != "Profile 1" {
+		t.Errorf("expected directory name fallback, got %q", got)
+	}
+	if got := profiles["Profile 2"].DisplayName; got != "Samsung" {
+		t.Errorf("expected profile display name, got %q", got)
+	}
+}
+
+func TestDoImport_OnlyFilter(t *testing.T) {
+	site := testSite
+
+	tests := []struct {
+		only        string
+		wantFolders int
+		wantTabs    int // excluding folder anchor tabs
+	}{
+		{"", 1, 4},
+		{OnlyFolders, 1, 2},
+		{OnlyTabs, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.only, func(t *testing.T) {
+			imp := newTestImporter(t, ImportOptions{Only: tt.only})
+			session := emptySession()
+			if _, err := imp.doImport(context.Background(), multiProfileArcData(t, site), session, &types.ContainersData{Version: 5}); err != nil {
+				t.Fatalf("doImport failed: %v", err)
+			}
+
+			tabs := 0
+			for _, tab := range session.Tabs {
+				if !tab.ZenIsEmpty {
+					tabs++
+				}
+			}
+			if len(session.Folders) != tt.wantFolders || tabs != tt.wantTabs {
+				t.Errorf("expected %d folders and %d tabs, got %d and %d", tt.wantFolders, tt.wantTabs, len(session.Folders), tabs)
+			}
+		})
+	}
+}
+
+func TestDoImport_EmptyURLPolicy(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "t1", "t2", "t3"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "%s/one"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Blank", "savedURL": ""}}},
+				{"id": "t3", "title": "Odd", "childrenIds": [], "data": {}}
+			]
+		}]}
+	}`, testSite)
+
+	tests := []struct {
+		policy      string
+		wantTabs    []string // Titles of non-placeholder tabs
+		wantFolders int
+		wantSkipped int
+	}{
+		{"", []string{"One"}, 0, 2},
+		{EmptyURLKeep, []string{"One", "Blank", "Odd"}, 0, 0},
+		{EmptyURLNote, []string{"One"}, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			imp := newTestImporter(t, ImportOptions{EmptyURLs: tt.policy})
+			session := emptySession()
+			result, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+			if err != nil {
+				t.Fatalf("doImport failed: %v", err)
+			}
+
+			var titles []string
+			for _, tab := range session.Tabs {
+				if tab.ZenIsEmpty {
+					continue
+				}
+				titles = append(titles, tab.ZenStaticLabel)
+				if tab.Entries[0].URL == "" {
+					t.Errorf("tab %q has no URL", tab.ZenStaticLabel)
+				}
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.wantTabs, ",") {
+				t.Errorf("expected tabs %v, got %v", tt.wantTabs, titles)
+			}
+			if len(session.Folders) != tt.wantFolders {
+				t.Errorf("expected %d folders, got %d", tt.wantFolders, len(session.Folders))
+			}
+			if len(result.Plan.Skipped) != tt.wantSkipped {
+				t.Errorf("expected %d skipped items, got %+v", tt.wantSkipped, result.Plan.Skipped)
+			}
+			if mapping := imp.warnings.ByCategory()[WarningMapping]; len(mapping) != 2 {
+				t.Errorf("expected a warning per tab without URL, got %v", mapping)
+			}
+		})
+	}
+}
+
+func TestDoImport_EmptyFolderPolicy(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "f1", "f3", "f4"]}],
+			"items": [
+				{"id": "f1", "title": "Old", "childrenIds": ["f2"], "data": {}},
+				{"id": "f2", "title": "Older", "parentID": "f1", "childrenIds": [], "data": {"list": {}}},
+				{"id": "f3", "title": "Reading", "childrenIds": ["t1"], "data": {}},
+				{"id": "t1", "parentID": "f3", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "%s/one"}}},
+				{"id": "f4", "title": "Fresh", "childrenIds": [], "data": {"list": {}}}
+			]
+		}]}
+	}`, testSite)
+
+	tests := []struct {
+		policy      string
+		wantFolders []string
+		wantSkipped int
+	}{
+		{"", []string{"Reading"}, 2},
+		{EmptyFolderKeep, []string{"Old", "Older", "Reading", "Fresh"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			imp := newTestImporter(t, ImportOptions{EmptyFolders: tt.policy})
+			session := emptySession()
+			result, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+			if err != nil {
+				t.Fatalf("doImport failed: %v", err)
+			}
+			var names []string
+			for _, folder := range session.Folders {
+				names = append(names, folder.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantFolders, ",") {
+				t.Errorf("expected folders %v, got %v", tt.wantFolders, names)
+			}
+			if result.EmptyFoldersSkipped != tt.wantSkipped {
+				t.Errorf("expected %d empty folders skipped, got %d", tt.wantSkipped, result.EmptyFoldersSkipped)
+			}
+			if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+				t.Errorf("expected the folders to restore, got %v", report.Issues)
+			}
+		})
+	}
+}
+
+func TestDoImport_TriggeringPrincipalByScheme(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "t1", "t2", "t3"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Web", "savedURL": "https://site.test/"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Notes", "savedURL": "file:///tmp/notes.html"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Bookmarklet", "savedURL": "javascript:void(0)"}}}
+			]
+		}]}
+	}`
+	imp := newTestImporter(t, ImportOptions{TriggeringPrincipals: map[string]string{"file": "null"}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatalf("validateOptions failed: %v", err)
+	}
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	for _, tab := range session.Tabs {
+		entry := tab.Entries[0]
+		system := entry.TriggeringPrincipalBase64 == principal.SystemBase64
+		if system != (tab.ZenStaticLabel == "Web") {
+			t.Errorf("%q: unexpected principal %s", tab.ZenStaticLabel, entry.TriggeringPrincipalBase64)
+		}
+		initial := tab.ZenPinnedInitialState.(map[string]interface{})["entry"].(map[string]interface{})
+		if initial["triggeringPrincipal_base64"] != entry.TriggeringPrincipalBase64 {
+			t.Errorf("%q: pinned initial state has a different principal", tab.ZenStaticLabel)
+		}
+	}
+}
+
+func TestValidateOptions_Invalid(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{EmptyURLs: "drop"})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected an error for an unknown -empty-urls value")
+	}
+	imp = newTestImporter(t, ImportOptions{TriggeringPrincipals: map[string]string{"file": "admin"}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected an error for an unknown principal kind")
+	}
+	imp = newTestImporter(t, ImportOptions{SpaceSettings: map[string]string{"Work": "hidden"}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected an error for an unknown space setting")
+	}
+}
+
+func TestDoImport_ArcProfileFilter(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{ArcProfile: "samsung"})
+	session := emptySession()
+	containersData := &types.ContainersData{Version: 5}
+
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, containersData); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if len(session.Spaces) != 1 || session.Spaces[0].Name != "Samsung" {
+		t.Errorf("expected only the Samsung space, got %+v", session.Spaces)
+	}
+	if len(containersData.Identities) != 1 || containersData.Identities[0].Name != "Samsung" {
+		t.Errorf("expected only the Samsung container, got %+v", containersData.Identities)
+	}
+
+	imp = newTestImporter(t, ImportOptions{ArcProfile: "Profile 9"})
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), emptySession(), &types.ContainersData{Version: 5}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestUniqueSpaceNames(t *testing.T) {
+	spaces := []*types.ArcSpace{
+		{ID: "a", Title: "School"},
+		{ID: "b", Title: "School"},
+		{ID: "c", Title: "School (2)"},
+		{ID: "d", Title: ""},
+	}
+	warnings := &Warnings{}
+
+	names := uniqueSpaceNames(spaces, warnings)
+
+	expected := map[string]string{"a": "School", "b": "School (3)", "c": "School (2)", "d": "Workspace d"}
+	for id, want := range expected {
+		if names[id] != want {
+			t.Errorf("space %s: expected %q, got %q", id, want, names[id])
+		}
+	}
+	if warnings.Len() != 1 {
+		t.Errorf("expected 1 duplicate warning, got %d", warnings.Len())
+	}
+}
+
+func TestDoImport_ExpiredContextStillImports(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	session := emptySession()
+	result, err := imp.doImport(ctx, multiProfileArcData(t, testSite), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if result.ItemsImported != 5 {
+		t.Errorf("expected all items despite the expired context, got %d", result.ItemsImported)
+	}
+	warnings := imp.warnings.ByCategory()[WarningFavicon]
+	if len(warnings) != 4 || !strings.Contains(warnings[0].Message, "time limit") {
+		t.Errorf("expected time limit favicon warnings, got %+v", warnings)
+	}
+}
+
+func TestDoImport_RestoreSimulationClean(t *testing.T) {
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "f1", "t9"]}],
+			"items": [
+				{"id": "f1", "title": "Projects", "childrenIds": ["f2", "f3", "t1"], "data": {}},
+				{"id": "f2", "parentID": "f1", "title": "Alpha", "childrenIds": ["t2"], "data": {}},
+				{"id": "f3", "parentID": "f1", "title": "Beta", "childrenIds": ["t3"], "data": {}},
+				{"id": "t1", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/1"}}},
+				{"id": "t2", "parentID": "f2", "childrenIds": [], "data": {"tab": {"savedTitle": "Two", "savedURL": "https://site.test/2"}}},
+				{"id": "t3", "parentID": "f3", "childrenIds": [], "data": {"tab": {"savedTitle": "Three", "savedURL": "https://site.test/3"}}},
+				{"id": "t9", "childrenIds": [], "data": {"tab": {"savedTitle": "Loose", "savedURL": "https://site.test/9"}}}
+			]
+		}]}
+	}`)
+
+	imp := newTestImporter(t, ImportOptions{SimulateRestore: true})
+	session := emptySession()
+	result, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	imp.simulateRestore(session, result.Plan)
+	if warnings := imp.warnings.ByCategory()[WarningRestore]; len(warnings) != 0 {
+		t.Errorf("expected the importer's output to restore cleanly, got %v", warnings)
+	}
+}
+
+// failTransport fails the test on any request
+type failTransport struct{ t *testing.T }
+
+func (f failTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Errorf("unexpected favicon request in dry run: %s", req.URL)
+	return nil, fmt.Errorf("no network in dry run")
+}
+
+func TestDoImport_DryRunFaviconCacheOnly(t *testing.T) {
+	fetcher := favicon.NewWithOptions(favicon.Options{CacheDir: t.TempDir(), Transport: failTransport{t}})
+	fetcher.SetCacheOnly(true)
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{DryRun: true, FaviconFetcher: fetcher})
+
+	result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if result.FaviconCache == nil || result.FaviconCache.Total != 4 || result.FaviconCache.Missing != 4 {
+		t.Errorf("expected 4 uncached favicons reported, got %+v", result.FaviconCache)
+	}
+	if warnings := imp.warnings.ByCategory()[WarningFavicon]; len(warnings) != 0 {
+		t.Errorf("expected no favicon warnings in a dry run, got %+v", warnings)
+	}
+}
+
+func TestDoImport_CustomArcIconsSkipFetch(t *testing.T) {
+	const png = "data:image/png;base64,iVBORw0KGgo="
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "customInfo": {"iconType": {"emoji_v2": "🔥"}},
+				 "data": {"tab": {"savedTitle": "Hot", "savedURL": "https://site.test/hot"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Logo", "savedURL": "https://site.test/logo", "customIcon": "`+png+`"}}}
+			]
+		}]}
+	}`)
+	fetcher := favicon.NewWithOptions(favicon.Options{CacheDir: t.TempDir(), Transport: failTransport{t}})
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{FaviconFetcher: fetcher})
+
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	icons := make(map[string]interface{})
+	for _, tab := range session.Tabs {
+		icons[tab.ZenStaticLabel] = tab.Image
+	}
+	if icon, _ := icons["Hot"].(string); !strings.HasPrefix(icon, "data:image/svg+xml;base64,") {
+		t.Errorf("expected the emoji as an SVG icon, got %v", icons["Hot"])
+	}
+	if icons["Logo"] != png {
+		t.Errorf("expected the custom image icon, got %v", icons["Logo"])
+	}
+}
+
+func TestEmojiIconDataURL(t *testing.T) {
+	if emojiIconDataURL("\U0001F469\u200d\U0001F4BB") == "" {
+		t.Error("expected a ZWJ sequence to render")
+	}
+	for _, notOne := range []string{"", "fire", "🔥🔥", "🔥 hot"} {
+		if got := emojiIconDataURL(notOne); got != "" {
+			t.Errorf("%q: expected no icon, got %s", notOne, got)
+		}
+	}
+}
+
+func TestDoImport_NetworkReportPerRun(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("\x89PNG site")),
+			Request:    req,
+		}, nil
+	})
+	fetcher := favicon.NewWithOptions(favicon.Options{CacheDir: t.TempDir(), Transport: transport})
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{FaviconFetcher: fetcher})
+
+	var reports []*NetworkReport
+	for run := 0; run < 2; run++ {
+		result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), emptySession(), &types.ContainersData{Version: 5})
+		if err != nil {
+			t.Fatalf("doImport failed: %v", err)
+		}
+		reports = append(reports, result.Network)
+	}
+
+	// All tabs share one host, so the first run fetches its home page and
+	// /favicon.ico once and the second run nothing at all
+	first, second := reports[0], reports[1]
+	if first == nil || first.Requests != 2 || first.Bytes != int64(len("\x89PNG site")) || first.CacheMisses != 1 || first.CacheHits != 3 {
+		t.Errorf("unexpected first run report: %+v", first)
+	}
+	if second == nil || second.Requests != 0 || second.CacheHits != 4 || second.HitRatio() != 1 {
+		t.Errorf("unexpected second run report: %+v", second)
+	}
+	if got := second.String(); !strings.Contains(got, "100% cache hits (4/4)") {
+		t.Errorf("unexpected summary line: %s", got)
+	}
+}
+
+func TestReadArcData_SalvagesTruncatedFile(t *testing.T) {
+	// Cut off in the middle of the second tab
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	truncated := `{"sidebar": {"containers": [{"global": {}}, {
+		"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2"]}],
+		"items": [
+			{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/1"}}},
+			{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTi`
+	if err := os.WriteFile(arcDataPath, []byte(truncated), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imp := newTestImporter(t, ImportOptions{})
+	arcData, err := imp.readArcData(arcDataPath)
+	if err != nil {
+		t.Fatalf("expected the truncated file to be salvaged, got %v", err)
+	}
+	if items := arcData.Sidebar.Containers[1].Items; len(items) != 1 {
+		t.Errorf("expected the first tab salvaged, got %d items", len(items))
+	}
+	warnings := imp.warnings.ByCategory()[WarningParse]
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "cut off") {
+		t.Errorf("expected a parse warning about the damage, got %+v", warnings)
+	}
+
+	if err := os.WriteFile(arcDataPath, []byte(`{"sidebar": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imp.readArcData(arcDataPath); err == nil {
+		t.Error("expected data with nothing to salvage to fail")
+	}
+}
+
+func TestDoImport_UnparsedItems(t *testing.T) {
+	arcData := parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2"]}],
+			"items": [
+				"t1",
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/1"}}},
+				{"id": "t2", "childrenIds": "not-a-list", "data": {}},
+				{"title": "No ID"},
+				42
+			]
+		}]}
+	}`)
+
+	var out strings.Builder
+	imp := newTestImporter(t, ImportOptions{UnparsedItems: &out})
+	result, err := imp.doImport(context.Background(), arcData, emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if len(result.UnparsedItems) != 3 || result.ItemsImported != 1 {
+		t.Fatalf("expected 3 unparsed items and 1 imported, got %+v, %d", result.UnparsedItems, result.ItemsImported)
+	}
+
+	var written []UnparsedItem
+	if err := json.Unmarshal([]byte(out.String()), &written); err != nil {
+		t.Fatalf("unparsed items file is not JSON: %v", err)
+	}
+	if len(written) != 3 || written[0].ID != "t2" || !strings.Contains(string(written[0].Raw), "not-a-list") {
+		t.Errorf("expected t2 first with its raw JSON, got %+v", written)
+	}
+	if written[1].Reason != "no id" || written[2].Reason != "not an object" || string(written[2].Raw) != "42" {
+		t.Errorf("unexpected reasons %+v", written[1:])
+	}
+}
+
+func TestImportContext_ReusedImporter(t *testing.T) {
+	t.Setenv("ARC_TO_ZEN_HOME", t.TempDir())
+	arcDataPath := filepath.Join(t.TempDir(), "StorableSidebar.json")
+	if err := os.WriteFile(arcDataPath, []byte(confirmArcData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imp := newTestImporter(t, ImportOptions{DryRun: true})
+
+	// Concurrent runs take turns, so each sees only its own plan and warnings
+	results := make([]*ImportResult, 4)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = imp.ImportContext(context.Background(), arcDataPath)
+		}(i)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("run %d failed: %v", i, errs[i])
+		}
+		if len(result.Plan.Spaces) != 1 || len(result.Plan.Tabs) != 1 || len(result.Warnings) != len(results[0].Warnings) {
+			t.Errorf("run %d: expected the same single-tab plan, got %+v", i, result.Plan)
+		}
+	}
+
+	// A real run after them, then another command, on the same Importer
+	imp.options.DryRun = false
+	result, err := imp.ImportContext(context.Background(), arcDataPath)
+	if err != nil || result.ImportID == "" || len(result.Plan.Tabs) != 1 {
+		t.Fatalf("expected a real import, got %+v, %v", result, err)
+	}
+	if count, err := imp.Count(arcDataPath); err != nil || count.Tabs != 1 {
+		t.Errorf("expected Count to see 1 tab, got %+v, %v", count, err)
+	}
+}
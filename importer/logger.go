@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+)
+
+// Logger interface for custom logging. Info carries the import's progress
+// narration; warnings are summarized through it at the end, and detail is
+// only logged with Verbose.
+type Logger interface {
+	Info(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// LevelLogger is a Logger that takes warnings and detail on streams of their
+// own, so an embedding application can show them apart from progress. Warn
+// gets each warning as it is recorded, the same ones ImportResult.Warnings
+// lists, in that order, and the end-of-import warning summary is left out of
+// Info. Debug gets the detail a Logger only sees with Verbose, whether or not
+// Verbose is set.
+type LevelLogger interface {
+	Logger
+	Warn(warning Warning)
+	Debug(format string, args ...interface{})
+}
+
+// defaultLogger implements Logger with standard output
+type defaultLogger struct{}
+
+func (l *defaultLogger) Info(format string, args ...interface{}) {
+	fmt.Printf("[ARC-IMPORT] "+format+"\n", args...)
+}
+
+func (l *defaultLogger) Error(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[ERROR] "+format+"\n", args...)
+}
+
+// debug logs detail: to a LevelLogger's Debug, else to Info with Verbose
+func (imp *Importer) debug(format string, args ...interface{}) {
+	logDebug(imp.logger, imp.options.Verbose, format, args...)
+}
+
+func logDebug(logger Logger, verbose bool, format string, args ...interface{}) {
+	if levels, ok := logger.(LevelLogger); ok {
+		levels.Debug(format, args...)
+	} else if verbose {
+		logger.Info(format, args...)
+	}
+}
+
+// newWarnings collects an import's warnings, passing each to logger's Warn
+// if it is a LevelLogger
+func newWarnings(logger Logger) *Warnings {
+	warnings := &Warnings{}
+	if levels, ok := logger.(LevelLogger); ok {
+		warnings.notify = levels.Warn
+	}
+	return warnings
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// recordingLogger keeps each stream's lines
+type recordingLogger struct {
+	info, debug []string
+	warnings    []Warning
+}
+
+func (l *recordingLogger) Info(format string, args ...interface{}) {
+	l.info = append(l.info, fmt.Sprintf(format, args...))
+}
+func (l *recordingLogger) Error(format string, args ...interface{}) {}
+
+// levelRecorder is a recordingLogger that also takes warnings and detail
+type levelRecorder struct{ recordingLogger }
+
+func (l *levelRecorder) Warn(warning Warning) { l.warnings = append(l.warnings, warning) }
+func (l *levelRecorder) Debug(format string, args ...interface{}) {
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+const loggerArcData = `{"sidebar": {"containers": [{"global": {}}, {
+	"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "f1", "t2"]}],
+	"items": [
+		{"id": "f1", "title": "Docs", "childrenIds": ["t1"], "data": {}},
+		{"id": "t1", "parentID": "f1", "title": "Blank", "childrenIds": [], "data": {"tab": {"savedTitle": "Blank"}}},
+		{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Loose", "savedURL": "https://site.test/2"}}}
+	]
+}]}}`
+
+func containsLine(lines []string, substr string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLevelLogger_SeparatesStreams(t *testing.T) {
+	logger := &levelRecorder{}
+	imp := newTestImporter(t, ImportOptions{Only: OnlyFolders})
+	imp.logger = logger
+	imp.warnings = newWarnings(logger)
+
+	_, err := imp.doImport(context.Background(), parseTestArcData(t, loggerArcData), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	recorded := imp.warnings.List()
+	if len(recorded) == 0 || len(logger.warnings) != len(recorded) {
+		t.Fatalf("expected every warning passed to Warn, got %d of %d", len(logger.warnings), len(recorded))
+	}
+	for i := range recorded {
+		if logger.warnings[i] != recorded[i] {
+			t.Errorf("warning %d: got %v, want %v", i, logger.warnings[i], recorded[i])
+		}
+	}
+	if !containsLine(logger.debug, `Skipping "Loose" (-only folders)`) {
+		t.Errorf("expected skip detail on Debug without Verbose, got %q", logger.debug)
+	}
+	if containsLine(logger.info, "Skipping \"Loose\"") {
+		t.Error("expected detail kept out of Info")
+	}
+	if !containsLine(logger.info, `Processing space: "Work"`) {
+		t.Errorf("expected progress on Info, got %q", logger.info)
+	}
+
+	imp.logWarnings()
+	if containsLine(logger.info, "Warnings:") {
+		t.Error("expected no warning summary on Info for a LevelLogger")
+	}
+}
+
+func TestLogger_DetailOnlyWhenVerbose(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		logger := &recordingLogger{}
+		imp := newTestImporter(t, ImportOptions{Only: OnlyFolders, Verbose: verbose})
+		imp.logger = logger
+		imp.warnings = newWarnings(logger)
+
+		if _, err := imp.doImport(context.Background(), parseTestArcData(t, loggerArcData), emptySession(), &types.ContainersData{Version: 5}); err != nil {
+			t.Fatalf("doImport failed: %v", err)
+		}
+		if got := containsLine(logger.info, `Skipping "Loose"`); got != verbose {
+			t.Errorf("verbose=%v: expected skip detail on Info %v, got %v", verbose, verbose, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"arc-to-zen/manifest"
+	"arc-to-zen/types"
+)
+
+func TestWriteProfile_RecordsImportInManifest(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	session := emptySession()
+	containersData := &types.ContainersData{Version: 5}
+
+	for run := 1; run <= 2; run++ {
+		result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, containersData)
+		if err != nil {
+			t.Fatalf("doImport failed: %v", err)
+		}
+		imp.importID = manifest.NewImportID()
+		if err := imp.writeProfile(session, containersData, imp.manifestRecord(result.Plan, "StorableSidebar.json")); err != nil {
+			t.Fatalf("writeProfile failed: %v", err)
+		}
+
+		m, err := manifest.Load(imp.zenProfilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Imports) != run {
+			t.Fatalf("expected %d recorded imports, got %d", run, len(m.Imports))
+		}
+		record := m.Imports[run-1]
+		if record.ID != imp.importID || record.ToolVersion == "" || record.ArcData != "StorableSidebar.json" {
+			t.Errorf("unexpected import record %+v", record)
+		}
+		if len(record.Spaces) != 3 || len(record.Tabs) != 4 || len(record.Folders) != 1 {
+			t.Errorf("expected 3 spaces, 1 folder and 4 tabs, got %d, %d and %d", len(record.Spaces), len(record.Folders), len(record.Tabs))
+		}
+		for _, space := range record.Spaces {
+			if space.ArcID == "" || space.ArcProfile == "" {
+				t.Errorf("expected the Arc source of space %q, got %+v", space.Name, space)
+			}
+		}
+
+		// The first run creates both containers, the second reuses them
+		if len(record.Containers) != 2 {
+			t.Fatalf("expected 2 containers, got %+v", record.Containers)
+		}
+		for _, container := range record.Containers {
+			if container.Created != (run == 1) {
+				t.Errorf("run %d: unexpected container record %+v", run, container)
+			}
+		}
+
+		// Every pinned tab in the session is owned by the latest import
+		for _, tab := range session.Tabs {
+			if tab.ZenIsEmpty {
+				continue
+			}
+			if owner := m.Owner(tab.ZenSyncID); owner == nil || owner.ID != imp.importID {
+				t.Errorf("run %d: tab %s not attributed to import %s", run, tab.ZenSyncID, imp.importID)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package importer
+
+import "fmt"
+
+// How an import treats a Zen workspace with the name of an imported Arc
+// space (ImportOptions.MergeStrategy)
+const (
+	MergeReplace = "replace" // Replace its pins with the imported ones
+	MergeAppend  = "merge"   // Keep its pins and add the imported items after them
+	MergeDedupe  = "dedupe"  // Keep its pins and only add tabs whose URL isn't pinned there yet
+	MergeSkip    = "skip"    // Leave it alone and don't import the space
+)
+
+// existingSpaceReason is the Plan.Skipped reason of the Arc spaces
+// -merge skip leaves out
+const existingSpaceReason = "workspace exists"
+
+func (imp *Importer) validateMergeStrategy() error {
+	switch imp.options.MergeStrategy {
+	case "", MergeReplace, MergeAppend, MergeDedupe:
+		return nil
+	case MergeSkip:
+		if imp.options.Mode == ModeFolders {
+			return fmt.Errorf("-merge %s can't be combined with -mode %s (every space goes into an existing workspace)", MergeSkip, ModeFolders)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid -merge value %q (expected %s, %s, %s or %s)",
+		imp.options.MergeStrategy, MergeReplace, MergeAppend, MergeDedupe, MergeSkip)
+}
+
+// keepsPins reports whether workspaces merged into keep their own pins
+func (imp *Importer) keepsPins() bool {
+	return imp.options.MergeStrategy == MergeAppend || imp.options.MergeStrategy == MergeDedupe
+}
@@ -0,0 +1,112 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// mergeTestSession imports a "Work" space with a Mail tab, then adds a pin of
+// the user's own to the workspace
+func mergeTestSession(t *testing.T) *types.ZenSession {
+	t.Helper()
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1"]}],
+			"items": [{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%s/mail"}}}]
+		}]}
+	}`, testSite))
+	session := emptySession()
+	if _, err := newTestImporter(t, ImportOptions{}).doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+	session.Tabs = append(session.Tabs, types.ZenTab{Pinned: true, ZenWorkspace: session.Spaces[0].UUID, ZenSyncID: "own", ZenStaticLabel: "Own",
+		Entries: []types.ZenTabEntry{{URL: testSite + "/own"}}})
+	return session
+}
+
+// mergeTestArcData has Work again, with Mail and a new Wiki tab, and a new
+// Home space
+func mergeTestArcData(t *testing.T) *types.ArcData {
+	t.Helper()
+	return parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2"]},
+				{"id": "s2", "title": "Home", "containerIDs": ["pinned", "t3"]}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "%[1]s/wiki"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Recipes", "savedURL": "%[1]s/recipes"}}}
+			]
+		}]}
+	}`, testSite))
+}
+
+// workspacePins lists the labels of the pinned tabs of the workspace called
+// name, in session order
+func workspacePins(session *types.ZenSession, name string) string {
+	space := findSpaceByName(session.Spaces, name)
+	if space == nil {
+		return ""
+	}
+	var labels []string
+	for _, tab := range session.Tabs {
+		if tab.Pinned && !tab.ZenIsEmpty && tab.ZenWorkspace == space.UUID {
+			labels = append(labels, tab.ZenStaticLabel)
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+func TestDoImport_MergeStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		work     string // Pins of Work afterwards
+		skipped  int    // Spaces left out
+	}{
+		{MergeReplace, "Mail Wiki", 0},
+		{MergeAppend, "Mail Own Mail Wiki", 0},
+		{MergeDedupe, "Mail Own Wiki", 0},
+		{MergeSkip, "Mail Own", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			session := mergeTestSession(t)
+			result, err := newTestImporter(t, ImportOptions{MergeStrategy: tt.strategy}).doImport(context.Background(), mergeTestArcData(t), session, &types.ContainersData{Version: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := workspacePins(session, "Work"); got != tt.work {
+				t.Errorf("expected Work pinned %q, got %q", tt.work, got)
+			}
+			if got := workspacePins(session, "Home"); got != "Recipes" {
+				t.Errorf("expected Home imported as usual, got %q", got)
+			}
+			if got := result.Plan.countSkipped(existingSpaceReason); got != tt.skipped {
+				t.Errorf("expected %d spaces skipped, got %d", tt.skipped, got)
+			}
+			for _, space := range result.Plan.Spaces {
+				if space.Name == "Work" && space.KeptPins != (tt.strategy == MergeAppend || tt.strategy == MergeDedupe) {
+					t.Errorf("unexpected KeptPins %v for -merge %s", space.KeptPins, tt.strategy)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMergeStrategy(t *testing.T) {
+	if err := newTestImporter(t, ImportOptions{MergeStrategy: "overwrite"}).validateMergeStrategy(); err == nil {
+		t.Error("expected an invalid -merge value to be rejected")
+	}
+	if err := newTestImporter(t, ImportOptions{MergeStrategy: MergeSkip, Mode: ModeFolders}).validateMergeStrategy(); err == nil {
+		t.Error("expected -merge skip with -mode folders to be rejected")
+	}
+	if err := newTestImporter(t, ImportOptions{MergeStrategy: MergeAppend, Mode: ModeFolders}).validateMergeStrategy(); err != nil {
+		t.Errorf("expected -merge merge with -mode folders to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package importer
+
+import "sort"
+
+// Plan records the workspaces, folders and tabs an import creates, in the order
+// they are created. It is filled in dry-run mode too, so it doubles as a preview.
+type Plan struct {
+	Spaces     []PlannedSpace     `json:"spaces"`
+	Folders    []PlannedFolder    `json:"folders"`
+	Tabs       []PlannedTab       `json:"tabs"`
+	Containers []PlannedContainer `json:"containers,omitempty"` // Container of each imported Arc profile
+	Skipped    []SkippedItem      `json:"skipped,omitempty"`    // Arc items left out, in sidebar order
+	Archive    []ArchiveSetting   `json:"archive,omitempty"`    // Arc auto-archive settings of the imported spaces
+	Rewrites   []RewrittenURL     `json:"rewrites,omitempty"`   // Tab URLs changed by the rewrite rules
+	Diff       *SessionDiff       `json:"diff,omitempty"`       // Changes to the session, set by ImportContext
+
+	seq int // Shared creation order of folders and tabs
+}
+
+// PlannedSpace is a Zen workspace created or merged into
+type PlannedSpace struct {
+	ID          string `json:"id"` // Zen workspace UUID
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`    // Zen workspace icon
+	Profile     string `json:"profile"` // Arc profile display name
+	ContainerID int    `json:"containerId"`
+	Merged      bool   `json:"merged"`             // Goes into an existing workspace (replacing its pins unless KeptPins)
+	KeptPins    bool   `json:"keptPins,omitempty"` // With Merged: adds to its pins instead (-mode folders, -merge merge|dedupe)
+	ArcID       string `json:"arcId,omitempty"`    // Arc space it was imported from
+	ArcName     string `json:"arcName,omitempty"`  // Arc space title, before names were made unique
+}
+
+// PlannedContainer is the Zen container an Arc profile's spaces use
+type PlannedContainer struct {
+	ID      int    `json:"id"` // userContextId
+	Name    string `json:"name"`
+	Profile string `json:"profile"`           // Arc profile display name; empty for -domain-container ones
+	Created bool   `json:"created"`           // Created by this import rather than reused
+	Updated bool   `json:"updated,omitempty"` // Reused with its icon and color updated (-update-containers)
+}
+
+// PlannedFolder is a pinned folder
+type PlannedFolder struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	SpaceID  string `json:"spaceId"`
+	ParentID string `json:"parentId,omitempty"` // Empty at the workspace root
+	ArcID    string `json:"arcId,omitempty"`    // Arc item it was imported from
+
+	seq int
+}
+
+// PlannedTab is a pinned tab
+type PlannedTab struct {
+	ID          string `json:"id"` // Zen sync ID
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Icon        string `json:"icon,omitempty"`     // Favicon data URL
+	SpaceID     string `json:"spaceId"`            // Empty for essentials
+	FolderID    string `json:"folderId,omitempty"` // Empty at the workspace root
+	ContainerID int    `json:"containerId"`
+	ArcID       string `json:"arcId,omitempty"`     // Arc item it was imported from
+	Essential   bool   `json:"essential,omitempty"` // Arc favorite imported as a Zen essential
+
+	seq        int
+	customIcon bool // Icon is the tab's custom Arc icon, not its favicon
+}
+
+// SkippedItem is an Arc item that was not imported
+type SkippedItem struct {
+	ID       string `json:"id"` // Arc item ID
+	Title    string `json:"title"`
+	SpaceID  string `json:"spaceId"`
+	FolderID string `json:"folderId,omitempty"` // Folder it would have been created in
+	Reason   string `json:"reason"`
+}
+
+// NodeType identifies what a TreeNode represents
+type NodeType string
+
+const (
+	NodeSpace  NodeType = "space"
+	NodeFolder NodeType = "folder"
+	NodeTab    NodeType = "tab"
+)
+
+// TreeNode is one entry of Plan.Tree
+type TreeNode struct {
+	Type     NodeType    `json:"type"`
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	URL      string      `json:"url,omitempty"`
+	Icon     string      `json:"icon,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+func (p *Plan) addSpace(space PlannedSpace) {
+	p.Spaces = append(p.Spaces, space)
+}
+
+func (p *Plan) addContainer(container PlannedContainer) {
+	p.Containers = append(p.Containers, container)
+}
+
+func (p *Plan) hasContainer(id int) bool {
+	for _, container := range p.Containers {
+		if container.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plan) addFolder(folder PlannedFolder) {
+	p.seq++
+	folder.seq = p.seq
+	p.Folders = append(p.Folders, folder)
+}
+
+func (p *Plan) addTab(tab PlannedTab) {
+	p.seq++
+	tab.seq = p.seq
+	p.Tabs = append(p.Tabs, tab)
+}
+
+func (p *Plan) addSkipped(item SkippedItem) {
+	p.Skipped = append(p.Skipped, item)
+}
+
+// countSkipped returns how many items were skipped for reason
+func (p *Plan) countSkipped(reason string) int {
+	n := 0
+	for _, item := range p.Skipped {
+		if item.Reason == reason {
+			n++
+		}
+	}
+	return n
+}
+
+// merge appends other's folders and tabs, keeping their creation order after ours
+func (p *Plan) merge(other *Plan) {
+	for _, folder := range other.Folders {
+		folder.seq += p.seq
+		p.Folders = append(p.Folders, folder)
+	}
+	for _, tab := range other.Tabs {
+		tab.seq += p.seq
+		p.Tabs = append(p.Tabs, tab)
+	}
+	p.Skipped = append(p.Skipped, other.Skipped...)
+	p.Rewrites = append(p.Rewrites, other.Rewrites...)
+	p.seq += other.seq
+}
+
+// faviconCounts returns how many tabs have a favicon and how many distinct images they use
+func (p *Plan) faviconCounts() (tabs, unique int) {
+	seen := make(map[string]bool)
+	for _, tab := range p.Tabs {
+		if tab.Icon == "" {
+			continue
+		}
+		tabs++
+		seen[tab.Icon] = true
+	}
+	return tabs, len(seen)
+}
+
+// Tree nests the plan as space → folders → tabs, keeping Arc's sidebar order
+func (p *Plan) Tree() []*TreeNode {
+	type entry struct {
+		node   *TreeNode
+		parent string // Folder ID, or space ID at the root
+		space  string
+		seq    int
+	}
+
+	nodes := make(map[string]*TreeNode)
+	var roots []*TreeNode
+	for _, space := range p.Spaces {
+		node := &TreeNode{Type: NodeSpace, ID: space.ID, Name: space.Name, Icon: space.Icon}
+		nodes[space.ID] = node
+		roots = append(roots, node)
+	}
+
+	entries := make([]entry, 0, len(p.Folders)+len(p.Tabs))
+	for _, folder := range p.Folders {
+		node := &TreeNode{Type: NodeFolder, ID: folder.ID, Name: folder.Name}
+		nodes[folder.ID] = node
+		parent := folder.ParentID
+		if parent == "" {
+			parent = folder.SpaceID
+		}
+		entries = append(entries, entry{node, parent, folder.SpaceID, folder.seq})
+	}
+	for _, tab := range p.Tabs {
+		node := &TreeNode{Type: NodeTab, ID: tab.ID, Name: tab.Title, URL: tab.URL, Icon: tab.Icon}
+		parent := tab.FolderID
+		if parent == "" {
+			parent = tab.SpaceID
+		}
+		entries = append(entries, entry{node, parent, tab.SpaceID, tab.seq})
+	}
+
+	// Attach in creation order so siblings keep their original positions.
+	// Items added to a folder that existed before the import (-merge dedupe)
+	// go at the root of their space.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	for _, e := range entries {
+		parent := nodes[e.parent]
+		if parent == nil {
+			parent = nodes[e.space]
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, e.node)
+		}
+	}
+	return roots
+}
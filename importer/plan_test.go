@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// describeTree renders nodes as "Name[child child]" for compact comparisons
+func describeTree(nodes []*TreeNode) string {
+	var parts []string
+	for _, node := range nodes {
+		part := node.Name
+		if len(node.Children) > 0 {
+			part += "[" + describeTree(node.Children) + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestDoImport_PlanTree(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	result, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	tree := result.Plan.Tree()
+	want := "Personal[Reading[One Two]] Home Media[Three] Samsung[Four]"
+	if got := describeTree(tree); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if tree[0].Type != NodeSpace || tree[0].Children[0].Type != NodeFolder || tree[0].Children[0].Children[0].Type != NodeTab {
+		t.Errorf("unexpected node types in %+v", tree[0])
+	}
+	if len(result.Plan.Spaces) != 3 || result.Plan.Spaces[2].Profile != "Samsung" {
+		t.Errorf("unexpected planned spaces: %+v", result.Plan.Spaces)
+	}
+}
+
+func TestPlanTree_KeepsSiblingOrder(t *testing.T) {
+	plan := &Plan{}
+	plan.addSpace(PlannedSpace{ID: "s", Name: "Space"})
+	plan.addTab(PlannedTab{ID: "t1", Title: "first", SpaceID: "s"})
+	plan.addFolder(PlannedFolder{ID: "f", Name: "Folder", SpaceID: "s"})
+	plan.addFolder(PlannedFolder{ID: "n", Name: "Nested", SpaceID: "s", ParentID: "f"})
+	plan.addTab(PlannedTab{ID: "t2", Title: "inner", SpaceID: "s", FolderID: "n"})
+	plan.addTab(PlannedTab{ID: "t3", Title: "last", SpaceID: "s"})
+
+	want := "Space[first Folder[Nested[inner]] last]"
+	if got := describeTree(plan.Tree()); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
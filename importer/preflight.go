@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"arc-to-zen/types"
+)
+
+// Extra free space required beyond the files we write, for filesystem overhead
+const diskSpaceMargin = 1024 * 1024
+
+// preflightPermissions verifies the profile directory, session file and
+// containers.json can all be written before any work is done
+func (imp *Importer) preflightPermissions() error {
+	probe, err := os.CreateTemp(imp.zenProfilePath, ".arc-to-zen-preflight-*")
+	if err != nil {
+		return fmt.Errorf("Zen profile directory is not writable: %s: %w (check its permissions and that the volume isn't read-only)", imp.zenProfilePath, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	for _, name := range []string{"zen-sessions.jsonlz4", "containers.json"} {
+		path := filepath.Join(imp.zenProfilePath, name)
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if os.IsNotExist(err) {
+			continue // Will be created
+		}
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w (check the file's permissions and owner)", path, err)
+		}
+		f.Close()
+	}
+
+	imp.logger.Info("✓ Profile is writable")
+	return nil
+}
+
+// preflightDiskSpace verifies there is room for the new session, its backup and
+// containers.json. The uncompressed session size is used as a safe upper bound.
+func (imp *Importer) preflightDiskSpace(session *types.ZenSession, containersData *types.ContainersData) error {
+	free, ok := freeDiskSpace(imp.zenProfilePath)
+	if !ok {
+		return nil // Not supported on this platform
+	}
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	containersJSON, err := json.MarshalIndent(containersData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal containers: %w", err)
+	}
+
+	required := uint64(len(sessionJSON)+len(containersJSON)) + diskSpaceMargin
+	if info, err := os.Stat(filepath.Join(imp.zenProfilePath, "zen-sessions.jsonlz4")); err == nil {
+		required += uint64(info.Size()) // Backup copy
+	}
+
+	if free < required {
+		return fmt.Errorf("not enough disk space in %s: need about %s, only %s free (free up space or use -dry-run to preview)",
+			imp.zenProfilePath, formatBytes(required), formatBytes(free))
+	}
+	return nil
+}
+
+// formatBytes renders a byte count for messages (e.g. "12.3 MB")
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
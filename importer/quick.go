@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"arc-to-zen/types"
+)
+
+// validateQuick checks ImportOptions.Quick and the options it rules out
+func (imp *Importer) validateQuick() error {
+	if imp.options.Quick < 0 {
+		return fmt.Errorf("invalid -quick value %d (expected 0 or more)", imp.options.Quick)
+	}
+	if imp.options.Quick > 0 && (imp.options.Mode == ModeFolders || len(imp.options.CombineSpaces) > 0 || len(imp.options.SplitSpaces) > 0) {
+		return fmt.Errorf("-quick creates no workspaces or folders; it can't be used with -mode folders, -combine or -split-space")
+	}
+	return nil
+}
+
+// quickImport imports the first ImportOptions.Quick pinned tabs of each space
+// as essentials without a container (-quick): no workspaces, folders or
+// containers are created, so trying it out changes little. Folders among
+// the first items are passed over. Tabs already essentials are skipped.
+// cacheSavedBefore is the fetcher's DedupSavedBytes when the import started.
+func (imp *Importer) quickImport(ctx context.Context, spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem, zenSession *types.ZenSession, cacheSavedBefore int64) (*ImportResult, error) {
+	imp.logger.Info("Quick import: the first %d pinned tabs of %d spaces as essentials (-quick)", imp.options.Quick, len(spaces))
+	arcToZenUUIDMap := make(map[string]string)
+	var picked []*types.ArcItem
+	for _, space := range spaces {
+		taken := 0
+		for _, item := range pinnedTopLevelItems(space, itemsMap) {
+			if taken == imp.options.Quick {
+				break
+			}
+			if _, excluded := imp.excluded[item.ID]; excluded || isArcFolder(item) {
+				continue
+			}
+			picked = append(picked, item)
+			arcToZenUUIDMap[item.ID] = imp.checkpoint.itemUUID(item.ID)
+			taken++
+		}
+	}
+	if err := imp.checkpoint.advance(phasePlanned); err != nil {
+		imp.warnings.Add(WarningWrite, "", "%v", err)
+	}
+
+	now := imp.checkpoint.timestamp()
+	imp.folderSeq = &atomic.Int64{}
+	imp.folderSeq.Store(int64(len(zenSession.Folders)))
+	existing := essentialURLs(zenSession)
+	created := 0
+	for _, item := range picked {
+		created += imp.addEssential(ctx, item, 0, existing, itemsMap, arcToZenUUIDMap, zenSession, now)
+	}
+
+	faviconTabs, faviconImages := imp.plan.faviconCounts()
+	return &ImportResult{
+		Success:           true,
+		ItemsImported:     created,
+		Plan:              imp.plan,
+		FaviconTabs:       faviconTabs,
+		FaviconImages:     faviconImages,
+		FaviconCacheSaved: imp.faviconFetcher.DedupSavedBytes() - cacheSavedBefore,
+	}, nil
+}
+
+// pinnedTopLevelItems returns the top-level items of space's pinned section:
+// those after Arc's "pinned" marker in containerIDs, before "unpinned". A
+// space without the markers has only pinned items.
+func pinnedTopLevelItems(space *types.ArcSpace, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
+	pinned := &types.ArcSpace{}
+	section := "pinned"
+	for _, raw := range space.ContainerIDs {
+		id, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if id == "pinned" || id == "unpinned" {
+			section = id
+			continue
+		}
+		if section == "pinned" {
+			pinned.ContainerIDs = append(pinned.ContainerIDs, id)
+		}
+	}
+	return topLevelItems(pinned, itemsMap)
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_Quick(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned", "p1", "unpinned", "u1"]},
+				{"id": "s2", "title": "Home", "containerIDs": ["t1"]}
+			],
+			"items": [
+				{"id": "p1", "childrenIds": ["f1", "a", "b", "c"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s1"}}}}},
+				{"id": "f1", "parentID": "p1", "title": "Docs", "childrenIds": ["d"], "data": {}},
+				{"id": "d", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Doc", "savedURL": "%[1]s/doc"}}},
+				{"id": "a", "parentID": "p1", "childrenIds": [], "data": {"tab": {"savedTitle": "A", "savedURL": "%[1]s/a"}}},
+				{"id": "b", "parentID": "p1", "childrenIds": [], "data": {"tab": {"savedTitle": "B", "savedURL": "%[1]s/b"}}},
+				{"id": "c", "parentID": "p1", "childrenIds": [], "data": {"tab": {"savedTitle": "C", "savedURL": "%[1]s/c"}}},
+				{"id": "u1", "childrenIds": ["u"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s1"}}}}},
+				{"id": "u", "parentID": "u1", "childrenIds": [], "data": {"tab": {"savedTitle": "Unpinned", "savedURL": "%[1]s/u"}}},
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Home", "savedURL": "%[1]s/home"}}}
+			]
+		}]}
+	}`, testSite)
+
+	session := emptySession()
+	result, err := newTestImporter(t, ImportOptions{Quick: 2}).doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, tab := range session.Tabs {
+		if !tab.ZenEssential || tab.ZenWorkspace != "" || tab.UserContextID != 0 {
+			t.Errorf("expected %q an essential without a workspace or container", tab.ZenStaticLabel)
+		}
+		titles = append(titles, tab.ZenStaticLabel)
+	}
+	// Folders are passed over and unpinned tabs left out
+	if got := fmt.Sprint(titles); got != "[A B Home]" {
+		t.Errorf("unexpected essentials %s", got)
+	}
+	if len(session.Spaces) != 0 || len(session.Folders) != 0 || result.ItemsImported != 3 {
+		t.Errorf("expected only 3 essentials, got %d workspaces, %d folders, %d items", len(session.Spaces), len(session.Folders), result.ItemsImported)
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the essentials to restore, got %v", report.Issues)
+	}
+
+	// Running it again adds nothing
+	if _, err := newTestImporter(t, ImportOptions{Quick: 2}).doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Tabs) != 3 {
+		t.Errorf("expected the essentials kept once, got %d tabs", len(session.Tabs))
+	}
+}
+
+func TestValidateQuick(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ImportOptions
+		wantErr bool
+	}{
+		{"off", ImportOptions{}, false},
+		{"on", ImportOptions{Quick: 3}, false},
+		{"negative", ImportOptions{Quick: -1}, true},
+		{"folders mode", ImportOptions{Quick: 3, Mode: ModeFolders}, true},
+		{"combine", ImportOptions{Quick: 3, CombineSpaces: map[string][]string{"All": {"A", "B"}}}, true},
+		{"split", ImportOptions{Quick: 3, SplitSpaces: map[string]int{"Work": 10}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := &Importer{options: tt.opts}
+			if err := imp.validateQuick(); (err != nil) != tt.wantErr {
+				t.Errorf("validateQuick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
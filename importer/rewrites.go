@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"strings"
+
+	"arc-to-zen/rewrite"
+)
+
+// RewrittenURL is a tab URL changed by ImportOptions.URLRewrites or
+// StripTracking
+type RewrittenURL struct {
+	ArcID string   `json:"arcId"`
+	Title string   `json:"title"`
+	From  string   `json:"from"` // URL in Arc
+	To    string   `json:"to"`   // URL imported
+	Rules []string `json:"rules"`
+}
+
+// rewriteURL applies the rewrite rules, then strips tracking parameters if
+// asked to. It returns the labels of what changed rawURL.
+func (imp *Importer) rewriteURL(rawURL string) (string, []string) {
+	rawURL, rules := imp.rewriter.Rewrite(rawURL)
+	if imp.options.StripTracking {
+		if stripped, ok := rewrite.StripTracking(rawURL); ok {
+			rawURL = stripped
+			rules = append(rules, rewrite.TrackingRule)
+		}
+	}
+	return rawURL, rules
+}
+
+// rewriteURLs returns urls as rewriteURL changes them, without duplicates,
+// for pre-caching the favicons of the URLs actually imported
+func (imp *Importer) rewriteURLs(urls []string) []string {
+	if imp.rewriter == nil && !imp.options.StripTracking {
+		return urls
+	}
+	seen := make(map[string]bool, len(urls))
+	rewritten := urls[:0:0]
+	for _, u := range urls {
+		u, _ = imp.rewriteURL(u)
+		if !seen[u] {
+			seen[u] = true
+			rewritten = append(rewritten, u)
+		}
+	}
+	return rewritten
+}
+
+// logRewrites lists the rewritten URLs in dry-run or verbose mode, and
+// counts them otherwise
+func (imp *Importer) logRewrites() {
+	rewrites := imp.plan.Rewrites
+	if len(rewrites) == 0 {
+		return
+	}
+	if !imp.options.DryRun && !imp.options.Verbose {
+		rewritten, cleaned := countRewrites(rewrites)
+		if rewritten > 0 {
+			imp.logger.Info("Rewrote %s (-rewrites)", plural(rewritten, "URL"))
+		}
+		if cleaned > 0 {
+			imp.logger.Info("Removed tracking parameters from %s (-strip-tracking)", plural(cleaned, "URL"))
+		}
+		return
+	}
+	prefix := ""
+	if imp.options.DryRun {
+		prefix = "[DRY-RUN] Would rewrite "
+	} else {
+		prefix = "Rewrote "
+	}
+	imp.logger.Info("%s%s:", prefix, plural(len(rewrites), "URL"))
+	for _, rewrite := range rewrites {
+		imp.logger.Info("  %s: %s → %s (%s)", rewrite.Title, rewrite.From, rewrite.To, strings.Join(rewrite.Rules, ", "))
+	}
+}
+
+// countRewrites returns how many of rewrites the rewrite rules changed, and
+// how many had tracking parameters stripped (a URL can be in both)
+func countRewrites(rewrites []RewrittenURL) (rewritten, cleaned int) {
+	for _, r := range rewrites {
+		rules := len(r.Rules)
+		if rules > 0 && r.Rules[rules-1] == rewrite.TrackingRule {
+			cleaned++ // Always applied last
+			rules--
+		}
+		if rules > 0 {
+			rewritten++
+		}
+	}
+	return rewritten, cleaned
+}
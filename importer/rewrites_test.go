@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/rewrite"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_URLRewrites(t *testing.T) {
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "https://sso.test/login?next=%[1]s%%2Fwiki"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}}
+			]
+		}]}
+	}`, testSite))
+	session := emptySession()
+	imp := newTestImporter(t, ImportOptions{DryRun: true, URLRewrites: []rewrite.Rule{
+		{Name: "sso", Match: `^https://sso\.test/login\?next=(.+)$`, Replace: "$1", Unescape: true},
+	}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	for _, tab := range session.Tabs {
+		if tab.Pinned {
+			urls = append(urls, tab.Entries[0].URL)
+		}
+	}
+	if got, want := strings.Join(urls, " "), testSite+"/wiki "+testSite+"/mail"; got != want {
+		t.Errorf("expected URLs %s, got %s", want, got)
+	}
+	rewrites := result.Plan.Rewrites
+	if len(rewrites) != 1 || rewrites[0].ArcID != "t1" || rewrites[0].To != testSite+"/wiki" || rewrites[0].Rules[0] != "sso" {
+		t.Errorf("unexpected rewrites %+v", rewrites)
+	}
+
+	if err := newTestImporter(t, ImportOptions{URLRewrites: []rewrite.Rule{{Match: "("}}}).validateOptions(); err == nil {
+		t.Error("expected an invalid rewrite rule to be rejected")
+	}
+}
+
+func TestDoImport_StripTracking(t *testing.T) {
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2", "t3"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news?id=1&utm_source=mail"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "https://old.test/wiki?fbclid=x"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}}
+			]
+		}]}
+	}`, testSite))
+	session := emptySession()
+	imp := newTestImporter(t, ImportOptions{DryRun: true, StripTracking: true, URLRewrites: []rewrite.Rule{
+		{Name: "move", Match: `^https://old\.test/`, Replace: testSite + "/"},
+	}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	for _, tab := range session.Tabs {
+		if tab.Pinned {
+			urls = append(urls, tab.Entries[0].URL)
+		}
+	}
+	if got, want := strings.Join(urls, " "), testSite+"/news?id=1 "+testSite+"/wiki "+testSite+"/mail"; got != want {
+		t.Errorf("expected URLs %s, got %s", want, got)
+	}
+	rewrites := result.Plan.Rewrites
+	if len(rewrites) != 2 || strings.Join(rewrites[1].Rules, ",") != "move,"+rewrite.TrackingRule {
+		t.Fatalf("unexpected rewrites %+v", rewrites)
+	}
+	if rewritten, cleaned := countRewrites(rewrites); rewritten != 1 || cleaned != 2 {
+		t.Errorf("expected 1 URL rewritten and 2 cleaned, got %d and %d", rewritten, cleaned)
+	}
+}
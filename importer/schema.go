@@ -0,0 +1,27 @@
+package importer
+
+import "arc-to-zen/manifest"
+
+// ContainersVersion is the containers.json "version" written when a profile
+// has none yet
+const ContainersVersion = 5
+
+// Schema describes the Zen profile files an import reads and writes
+type Schema struct {
+	SessionFile       string `json:"sessionFile"`
+	SessionFormat     string `json:"sessionFormat"` // Compression of the session file
+	ContainersVersion int    `json:"containersVersion"`
+	ManifestVersion   int    `json:"manifestVersion"`  // Newest arc-to-zen.json format read
+	PinnedIconsSince  string `json:"pinnedIconsSince"` // First Zen release given zenPinnedIcon; older ones only get image
+}
+
+// SessionSchema returns the Schema of this version
+func SessionSchema() Schema {
+	return Schema{
+		SessionFile:       "zen-sessions.jsonlz4",
+		SessionFormat:     "mozlz4",
+		ContainersVersion: ContainersVersion,
+		ManifestVersion:   manifest.FormatVersion,
+		PinnedIconsSince:  pinnedIconMinVersion.String(),
+	}
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"arc-to-zen/types"
+)
+
+// Kinds of SelectionNode
+const (
+	SelectSpace  = "space"
+	SelectFolder = "folder"
+	SelectTab    = "tab"
+)
+
+// selectionReason is the Plan.Skipped reason of items an ItemSelector deselected
+const selectionReason = "deselected"
+
+// SelectionNode is an Arc space, folder or tab offered to an ItemSelector, in
+// sidebar order. Arc containers are left out (their items appear in their
+// place), as are items -exclude already leaves out.
+type SelectionNode struct {
+	ArcID    string
+	Kind     string // SelectSpace, SelectFolder or SelectTab
+	Title    string
+	URL      string // Tabs only
+	Children []*SelectionNode
+}
+
+// ItemSelector is shown the Arc tree about to be imported and returns the
+// Arc IDs of the spaces, folders and tabs to leave out. Deselecting a space or
+// folder leaves out everything in it. Returning false cancels the import with
+// ErrNotConfirmed.
+type ItemSelector func(tree []*SelectionNode) (deselected map[string]bool, ok bool)
+
+// applySelection asks ImportOptions.Select what to import. Deselected spaces
+// are dropped; deselected items, and folders with everything in them
+// deselected, are marked in imp.excluded for insertItemWithChildren to skip.
+func (imp *Importer) applySelection(spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem) ([]*types.ArcSpace, error) {
+	if imp.options.Select == nil {
+		return spaces, nil
+	}
+	tree := imp.selectionTree(spaces, itemsMap)
+	deselected, ok := imp.options.Select(tree)
+	if !ok {
+		return nil, ErrNotConfirmed
+	}
+	if len(deselected) == 0 {
+		return spaces, nil
+	}
+
+	kept := spaces[:0:0]
+	for _, space := range spaces {
+		if !deselected[space.ID] {
+			kept = append(kept, space)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("every Arc space was deselected; nothing left to import")
+	}
+
+	if imp.excluded == nil {
+		imp.excluded = make(map[string]string)
+	}
+	items := 0
+	// mark returns whether node ends up left out
+	var mark func(node *SelectionNode) bool
+	mark = func(node *SelectionNode) bool {
+		if deselected[node.ArcID] {
+			imp.excluded[node.ArcID] = selectionReason
+			items++
+			return true
+		}
+		if len(node.Children) == 0 {
+			return false
+		}
+		all := true
+		for _, child := range node.Children {
+			all = mark(child) && all
+		}
+		if all && node.Kind == SelectFolder {
+			imp.excluded[node.ArcID] = selectionReason
+		}
+		return all
+	}
+	for _, space := range tree {
+		if !deselected[space.ArcID] {
+			for _, node := range space.Children {
+				mark(node)
+			}
+		}
+	}
+	imp.logger.Info("Importing %d of %d spaces, leaving out %d deselected items", len(kept), len(spaces), items)
+	return kept, nil
+}
+
+// selectionTree builds the tree an ItemSelector is shown, walking items like
+// insertItemWithChildren
+func (imp *Importer) selectionTree(spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem) []*SelectionNode {
+	var tree []*SelectionNode
+	for _, space := range spaces {
+		node := &SelectionNode{ArcID: space.ID, Kind: SelectSpace, Title: getTitleOrDefault(space.Title, space.ID)}
+		seen := make(map[string]bool)
+		var walk func(item *types.ArcItem) []*SelectionNode
+		walk = func(item *types.ArcItem) []*SelectionNode {
+			if seen[item.ID] {
+				return nil
+			}
+			seen[item.ID] = true
+			if _, excluded := imp.excluded[item.ID]; excluded {
+				return nil
+			}
+			var children []*SelectionNode
+			for _, childID := range item.ChildrenIds {
+				if child := itemsMap[childID]; child != nil {
+					children = append(children, walk(child)...)
+				}
+			}
+			if item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil {
+				return children // Transparent
+			}
+			child := &SelectionNode{ArcID: item.ID, Kind: SelectTab, Title: arcItemTitle(item), Children: children}
+			if isArcFolder(item) {
+				child.Kind = SelectFolder
+			} else if item.Data != nil && item.Data.Tab != nil {
+				child.URL = item.Data.Tab.SavedURL
+			}
+			return []*SelectionNode{child}
+		}
+		for _, root := range getRootItemsForSpace(space, itemsMap) {
+			node.Children = append(node.Children, walk(root)...)
+		}
+		tree = append(tree, node)
+	}
+	return tree
+}
+
+// savedSelection is the -selection file: what an ItemSelector left out, so
+// later runs import the same items without asking again
+type savedSelection struct {
+	Deselected []string `json:"deselected"` // Arc IDs of spaces, folders and tabs, sorted
+}
+
+// LoadSelection reads a selection saved by SaveSelection. The error wraps
+// os.ErrNotExist if there is none yet.
+func LoadSelection(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read selection: %w", err)
+	}
+	var saved savedSelection
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("could not parse selection %s: %w", path, err)
+	}
+	deselected := make(map[string]bool, len(saved.Deselected))
+	for _, id := range saved.Deselected {
+		deselected[id] = true
+	}
+	return deselected, nil
+}
+
+// SaveSelection writes the Arc IDs an ItemSelector deselected to path
+func SaveSelection(path string, deselected map[string]bool) error {
+	saved := savedSelection{Deselected: []string{}}
+	for id, ok := range deselected {
+		if ok {
+			saved.Deselected = append(saved.Deselected, id)
+		}
+	}
+	sort.Strings(saved.Deselected)
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal selection: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not save selection: %w", err)
+	}
+	return nil
+}
+
+// SavedSelector answers like the ItemSelector that chose deselected, without
+// asking (-selection without -interactive)
+func SavedSelector(deselected map[string]bool) ItemSelector {
+	return func(tree []*SelectionNode) (map[string]bool, bool) {
+		return deselected, true
+	}
+}
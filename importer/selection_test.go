@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func selectionArcData(t *testing.T) *types.ArcData {
+	t.Helper()
+	return parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned", "c1"]},
+				{"id": "s2", "title": "Home", "containerIDs": ["t5"]}
+			],
+			"items": [
+				{"id": "c1", "childrenIds": ["t1", "f1", "f2"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s1"}}}}},
+				{"id": "t1", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f1", "parentID": "c1", "title": "Docs", "childrenIds": ["t2", "t3"], "data": {}},
+				{"id": "t2", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "t3", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Notes", "savedURL": "%[1]s/notes"}}},
+				{"id": "f2", "parentID": "c1", "title": "Old", "childrenIds": ["t4"], "data": {}},
+				{"id": "t4", "parentID": "f2", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "%[1]s/wiki"}}},
+				{"id": "t5", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}}
+			]
+		}]}
+	}`, testSite))
+}
+
+func TestDoImport_Select(t *testing.T) {
+	var shown []*SelectionNode
+	imp := newTestImporter(t, ImportOptions{Select: func(tree []*SelectionNode) (map[string]bool, bool) {
+		shown = tree
+		return map[string]bool{"s2": true, "t3": true, "t4": true}, true
+	}})
+	result, err := imp.doImport(context.Background(), selectionArcData(t), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The selector sees the tree without the Arc container
+	var describe func(nodes []*SelectionNode) string
+	describe = func(nodes []*SelectionNode) string {
+		var parts []string
+		for _, node := range nodes {
+			part := node.Title
+			if len(node.Children) > 0 {
+				part += "(" + describe(node.Children) + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, " ")
+	}
+	if got := describe(shown); got != "Work(Mail Docs(Spec Notes) Old(Wiki)) Home(News)" {
+		t.Errorf("unexpected tree %s", got)
+	}
+
+	if len(result.Plan.Spaces) != 1 || result.Plan.Spaces[0].Name != "Work" {
+		t.Fatalf("expected only Work imported, got %+v", result.Plan.Spaces)
+	}
+	var tabs []string
+	for _, tab := range result.Plan.Tabs {
+		tabs = append(tabs, tab.Title)
+	}
+	if got := strings.Join(tabs, ","); got != "Mail,Spec" {
+		t.Errorf("expected the deselected tabs left out, got %s", got)
+	}
+	// Old had every item deselected, so it goes too
+	if len(result.Plan.Folders) != 1 || result.Plan.Folders[0].Name != "Docs" {
+		t.Errorf("expected only Docs, got %+v", result.Plan.Folders)
+	}
+	for _, skipped := range result.Plan.Skipped {
+		if skipped.Reason != selectionReason {
+			t.Errorf("unexpected skip reason %q for %s", skipped.Reason, skipped.ID)
+		}
+	}
+
+	imp = newTestImporter(t, ImportOptions{Select: func([]*SelectionNode) (map[string]bool, bool) { return nil, false }})
+	if _, err := imp.doImport(context.Background(), selectionArcData(t), emptySession(), &types.ContainersData{Version: 5}); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected a canceled selection to return ErrNotConfirmed, got %v", err)
+	}
+}
+
+func TestSaveSelection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selection.json")
+	if _, err := LoadSelection(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist before saving, got %v", err)
+	}
+	if err := SaveSelection(path, map[string]bool{"t4": true, "s2": true, "t3": true}); err != nil {
+		t.Fatal(err)
+	}
+	deselected, err := LoadSelection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deselected) != 3 || !deselected["s2"] || !deselected["t3"] || !deselected["t4"] {
+		t.Errorf("unexpected selection %v", deselected)
+	}
+
+	// Importing with the saved answer leaves out the same items
+	result, err := newTestImporter(t, ImportOptions{Select: SavedSelector(deselected)}).doImport(context.Background(), selectionArcData(t), emptySession(), &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Plan.Spaces) != 1 || len(result.Plan.Tabs) != 2 || len(result.Plan.Folders) != 1 {
+		t.Errorf("expected Work with Mail and Docs/Spec, got %d spaces, %d tabs, %d folders", len(result.Plan.Spaces), len(result.Plan.Tabs), len(result.Plan.Folders))
+	}
+}
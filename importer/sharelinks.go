@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"arc-to-zen/arcshare"
+	"arc-to-zen/types"
+)
+
+const (
+	// ShareLinkSkip leaves Arc share links out with a warning
+	ShareLinkSkip = "skip"
+	// ShareLinkKeep imports them as they are, as tabs that open arc.net
+	ShareLinkKeep = "keep"
+	// ShareLinkResolve fetches each share page and imports a folder of the
+	// links it lists instead
+	ShareLinkResolve = "resolve"
+)
+
+// shareLinkReason is the Plan.Skipped reason of Arc share links left out
+const shareLinkReason = "Arc share link"
+
+// ShareResolver returns the links an Arc share link points to
+// (arcshare.Resolver unless ImportOptions.ShareResolver is set)
+type ShareResolver interface {
+	Resolve(ctx context.Context, shareURL string) ([]arcshare.Link, error)
+}
+
+func validateShareLinks(value string) error {
+	switch value {
+	case "", ShareLinkSkip, ShareLinkKeep, ShareLinkResolve:
+		return nil
+	}
+	return fmt.Errorf("invalid -share-links value %q (expected %q, %q or %q)", value, ShareLinkSkip, ShareLinkKeep, ShareLinkResolve)
+}
+
+// resolveShareLinks replaces the Arc share links in the imported spaces with
+// folders of the links they share (-share-links resolve), returning items
+// with the new tabs added. Share links that can't be resolved stay tabs, and
+// insertItemWithChildren skips them with the error in imp.shareErrors. A
+// dry run never contacts Arc.
+func (imp *Importer) resolveShareLinks(ctx context.Context, jobs []spaceJob, items []*types.ArcItem, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
+	imp.shareErrors = nil
+	if imp.options.ShareLinks != ShareLinkResolve {
+		return items
+	}
+	var shares []*types.ArcItem
+	seen := make(map[string]bool)
+	var find func(item *types.ArcItem)
+	find = func(item *types.ArcItem) {
+		if seen[item.ID] {
+			return
+		}
+		seen[item.ID] = true
+		if len(item.ChildrenIds) == 0 && item.Data != nil && item.Data.Tab != nil {
+			if url, _ := imp.rewriteURL(item.Data.Tab.SavedURL); arcshare.IsShareLink(url) {
+				shares = append(shares, item)
+			}
+		}
+		for _, childID := range item.ChildrenIds {
+			if child := itemsMap[childID]; child != nil {
+				find(child)
+			}
+		}
+	}
+	for _, job := range jobs {
+		for _, root := range getRootItemsForSpace(job.space, itemsMap) {
+			find(root)
+		}
+	}
+	if len(shares) == 0 {
+		return items
+	}
+	if imp.options.DryRun {
+		imp.logger.Info("[DRY-RUN] Would resolve %s into the links they share", plural(len(shares), "Arc share link"))
+		return items
+	}
+
+	resolver := imp.options.ShareResolver
+	if resolver == nil {
+		resolver = arcshare.NewResolver()
+	}
+	imp.logger.Info("Resolving %s...", plural(len(shares), "Arc share link"))
+	imp.shareErrors = make(map[string]error)
+	for _, share := range shares {
+		url, _ := imp.rewriteURL(share.Data.Tab.SavedURL)
+		links, err := resolver.Resolve(ctx, url)
+		if err != nil {
+			imp.shareErrors[share.ID] = err
+			continue
+		}
+		imp.debug("Resolved %s to %s", url, plural(len(links), "link"))
+		folder := *share
+		folder.Title = arcItemTitle(share)
+		folder.Data = &types.ArcItemData{List: []byte("{}")}
+		folder.ChildrenIds = nil
+		for i, link := range links {
+			tab := &types.ArcItem{
+				ID:        share.ID + "-share-" + strconv.Itoa(i+1),
+				ParentID:  share.ID,
+				Data:      &types.ArcItemData{Tab: &types.ArcTab{SavedTitle: link.Title, SavedURL: link.URL}},
+				CreatedAt: share.CreatedAt,
+			}
+			folder.ChildrenIds = append(folder.ChildrenIds, tab.ID)
+			itemsMap[tab.ID] = tab
+			items = append(items, tab)
+		}
+		itemsMap[share.ID] = &folder
+		for i := range items {
+			if items[i] == share {
+				items[i] = &folder
+			}
+		}
+	}
+	return items
+}
+
+// skipShareLink reports why the Arc share link url of a tab is left out, or
+// "" to import it. Errors resolving it are warned about here.
+func (imp *Importer) skipShareLink(item *types.ArcItem, title, url string) string {
+	if imp.options.ShareLinks == ShareLinkKeep || !arcshare.IsShareLink(url) {
+		return ""
+	}
+	switch err := imp.shareErrors[item.ID]; {
+	case err != nil:
+		imp.warnings.Add(WarningMapping, title, "could not resolve Arc share link %s: %v (skipped)", url, err)
+		return shareLinkReason + " (unresolved)"
+	case imp.options.ShareLinks == ShareLinkResolve:
+		return shareLinkReason + " (resolved when importing)" // Dry run
+	}
+	imp.warnings.Add(WarningMapping, title, "%s is an Arc share link, which only opens in Arc (skipped; use -share-links resolve to import the links it shares, or keep)", url)
+	return shareLinkReason
+}
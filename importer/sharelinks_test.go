@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/arcshare"
+	"arc-to-zen/types"
+)
+
+type fakeShareResolver map[string][]arcshare.Link
+
+func (f fakeShareResolver) Resolve(ctx context.Context, shareURL string) ([]arcshare.Link, error) {
+	if links, ok := f[shareURL]; ok {
+		return links, nil
+	}
+	return nil, errors.New("share page returned 404 Not Found")
+}
+
+func shareLinksArcData(t *testing.T) *types.ArcData {
+	return parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2", "t3"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Team links", "savedURL": "https://arc.net/folder/team"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Gone", "savedURL": "https://arc.net/folder/gone"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}}
+			]
+		}]}
+	}`, testSite))
+}
+
+func TestDoImport_ShareLinks(t *testing.T) {
+	resolver := fakeShareResolver{"https://arc.net/folder/team": {
+		{Title: "Wiki", URL: testSite + "/wiki"},
+		{Title: "Board", URL: testSite + "/board"},
+	}}
+	tests := []struct {
+		policy  string
+		dryRun  bool
+		tabs    string
+		folders int
+		skipped map[string]int
+	}{
+		{ShareLinkSkip, false, "Mail", 0, map[string]int{shareLinkReason: 2}},
+		{ShareLinkKeep, false, "Team links Gone Mail", 0, nil},
+		{ShareLinkResolve, false, "Wiki Board Mail", 1, map[string]int{shareLinkReason + " (unresolved)": 1}},
+		{ShareLinkResolve, true, "Mail", 0, map[string]int{shareLinkReason + " (resolved when importing)": 2}},
+	}
+	for _, tt := range tests {
+		session := emptySession()
+		imp := newTestImporter(t, ImportOptions{ShareLinks: tt.policy, ShareResolver: resolver, DryRun: tt.dryRun})
+		result, err := imp.doImport(context.Background(), shareLinksArcData(t), session, &types.ContainersData{Version: 5})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.policy, err)
+		}
+		var titles []string
+		for _, tab := range session.Tabs {
+			if tab.Pinned && !tab.ZenIsEmpty {
+				titles = append(titles, tab.ZenStaticLabel)
+			}
+		}
+		if got := strings.Join(titles, " "); got != tt.tabs {
+			t.Errorf("%s (dry run %v): expected tabs %q, got %q", tt.policy, tt.dryRun, tt.tabs, got)
+		}
+		if len(result.Plan.Folders) != tt.folders {
+			t.Errorf("%s (dry run %v): expected %d folders, got %+v", tt.policy, tt.dryRun, tt.folders, result.Plan.Folders)
+		} else if tt.folders > 0 && result.Plan.Folders[0].Name != "Team links" {
+			t.Errorf("expected the resolved folder named after the share, got %q", result.Plan.Folders[0].Name)
+		}
+		for reason, want := range tt.skipped {
+			if got := result.Plan.countSkipped(reason); got != want {
+				t.Errorf("%s (dry run %v): expected %d skipped as %q, got %d", tt.policy, tt.dryRun, want, reason, got)
+			}
+		}
+	}
+
+	if err := newTestImporter(t, ImportOptions{ShareLinks: "open"}).validateOptions(); err == nil {
+		t.Error("expected an unknown -share-links value to be rejected")
+	}
+}
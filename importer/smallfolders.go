@@ -0,0 +1,86 @@
+package importer
+
+import "arc-to-zen/types"
+
+// smallFolderSeparator joins an inlined folder's name and the titles of the
+// items moved up out of it: "Recipes / Pancakes"
+const smallFolderSeparator = " / "
+
+// planSmallFolders finds the folders ImportOptions.MergeSmallFolders inlines
+// into their parent and the title prefix of every item moved up out of them,
+// for insertItemWithChildren. Items the import leaves out (-exclude, empty
+// folders it skips) don't count towards a folder's size.
+func (imp *Importer) planSmallFolders(spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem) {
+	imp.inlined = nil
+	imp.titlePrefix = nil
+	if imp.options.MergeSmallFolders <= 0 {
+		return
+	}
+	imp.inlined = make(map[string]bool)
+	imp.titlePrefix = make(map[string]string)
+
+	seen := make(map[string]bool)
+	var walk func(item *types.ArcItem, prefix string)
+	walk = func(item *types.ArcItem, prefix string) {
+		if seen[item.ID] {
+			return
+		}
+		seen[item.ID] = true
+		if prefix != "" {
+			imp.titlePrefix[item.ID] = prefix
+		}
+
+		childPrefix := ""
+		isArcContainer := item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil
+		switch {
+		case isArcContainer:
+			childPrefix = prefix // Transparent, like in insertItemWithChildren
+		case isArcFolder(item):
+			if size := imp.folderSize(item, itemsMap); size > 0 && size < imp.options.MergeSmallFolders {
+				imp.inlined[item.ID] = true
+				childPrefix = prefix + arcItemTitle(item) + smallFolderSeparator
+			}
+		}
+		for _, childID := range item.ChildrenIds {
+			if child := itemsMap[childID]; child != nil {
+				walk(child, childPrefix)
+			}
+		}
+	}
+	for _, space := range spaces {
+		for _, root := range getRootItemsForSpace(space, itemsMap) {
+			walk(root, "")
+		}
+	}
+	if len(imp.inlined) > 0 {
+		imp.logger.Info("Merging %d folders with fewer than %d items into their parents (-merge-small-folders)", len(imp.inlined), imp.options.MergeSmallFolders)
+	}
+}
+
+// folderSize counts the items the import would create directly in folder
+func (imp *Importer) folderSize(folder *types.ArcItem, itemsMap map[string]*types.ArcItem) int {
+	size := 0
+	for _, childID := range folder.ChildrenIds {
+		child := itemsMap[childID]
+		if child == nil {
+			continue
+		}
+		if _, excluded := imp.excluded[child.ID]; excluded {
+			continue
+		}
+		if imp.options.EmptyFolders != EmptyFolderKeep && isEmptyArcFolder(child, itemsMap) {
+			continue
+		}
+		size++
+	}
+	return size
+}
+
+// arcItemTitle is the title insertItemWithChildren gives an item
+func arcItemTitle(item *types.ArcItem) string {
+	title := item.Title
+	if title == "" && item.Data != nil && item.Data.Tab != nil {
+		title = item.Data.Tab.SavedTitle
+	}
+	return getTitleOrDefault(title, "Untitled")
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_MergeSmallFolders(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "f1", "f2"]}],
+			"items": [
+				{"id": "f1", "title": "Recipes", "childrenIds": ["t1"], "data": {}},
+				{"id": "t1", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Pancakes", "savedURL": "%[1]s/pancakes"}}},
+				{"id": "f2", "title": "Work", "childrenIds": ["t2", "f3", "f4"], "data": {}},
+				{"id": "t2", "parentID": "f2", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f3", "parentID": "f2", "title": "Tiny", "childrenIds": ["t3"], "data": {}},
+				{"id": "t3", "parentID": "f3", "childrenIds": [], "data": {"tab": {"savedTitle": "Docs", "savedURL": "%[1]s/docs"}}},
+				{"id": "f4", "parentID": "f2", "title": "Old", "childrenIds": [], "data": {"list": {}}}
+			]
+		}]}
+	}`, testSite)
+
+	tests := []struct {
+		name        string
+		opts        ImportOptions
+		wantFolders []string
+		wantTabs    []string // Title@folder name
+	}{
+		{"below", ImportOptions{MergeSmallFolders: 2}, []string{"Work"},
+			[]string{"Recipes / Pancakes@", "Mail@Work", "Tiny / Docs@Work"}},
+		// Work holds 2 items; the empty folder it also holds is skipped, so it doesn't count
+		{"skipped items", ImportOptions{MergeSmallFolders: 3}, nil,
+			[]string{"Recipes / Pancakes@", "Work / Mail@", "Work / Tiny / Docs@"}},
+		{"kept empty folder", ImportOptions{MergeSmallFolders: 3, EmptyFolders: EmptyFolderKeep}, []string{"Work", "Old"},
+			[]string{"Recipes / Pancakes@", "Mail@Work", "Tiny / Docs@Work"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := newTestImporter(t, tt.opts)
+			session := emptySession()
+			if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+				t.Fatal(err)
+			}
+			var folders []string
+			names := make(map[string]string)
+			for _, folder := range session.Folders {
+				folders = append(folders, folder.Name)
+				names[folder.ID] = folder.Name
+			}
+			if strings.Join(folders, ",") != strings.Join(tt.wantFolders, ",") {
+				t.Errorf("expected folders %v, got %v", tt.wantFolders, folders)
+			}
+			var tabs []string
+			for _, tab := range session.Tabs {
+				if !tab.ZenIsEmpty {
+					tabs = append(tabs, tab.ZenStaticLabel+"@"+names[tab.GroupID])
+				}
+			}
+			if strings.Join(tabs, ",") != strings.Join(tt.wantTabs, ",") {
+				t.Errorf("expected tabs %v, got %v", tt.wantTabs, tabs)
+			}
+			if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+				t.Errorf("expected the flattened folders to restore, got %v", report.Issues)
+			}
+		})
+	}
+}
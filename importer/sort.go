@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// Sort orders for ImportOptions.Sort, applied to the items of each space and
+// folder. Folders come before tabs in every order but SortNone.
+const (
+	// SortNone keeps Arc's sidebar order (the default)
+	SortNone = "none"
+	// SortAlpha orders by title, ignoring case
+	SortAlpha = "alpha"
+	// SortDomain groups tabs by host ("www." ignored), then orders by title
+	SortDomain = "domain"
+	// SortRecent puts the most recently used items first; a folder counts as
+	// used when any tab in it was
+	SortRecent = "recent"
+)
+
+// SortOrders lists the valid ImportOptions.Sort values
+var SortOrders = []string{SortNone, SortAlpha, SortDomain, SortRecent}
+
+// validateSort checks ImportOptions.Sort
+func validateSort(order string) error {
+	if order == "" {
+		return nil
+	}
+	for _, o := range SortOrders {
+		if o == order {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid -sort value %q (use %s)", order, strings.Join(SortOrders, ", "))
+}
+
+// sortedChildren returns the children of item that exist in itemsMap, in the
+// order ImportOptions.Sort asks for
+func (imp *Importer) sortedChildren(item *types.ArcItem, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
+	var children []*types.ArcItem
+	for _, childID := range item.ChildrenIds {
+		if child := itemsMap[childID]; child != nil {
+			children = append(children, child)
+		}
+	}
+	return imp.sortItems(children, itemsMap)
+}
+
+// sortItems orders sibling items for ImportOptions.Sort. Arc containers keep
+// their place ahead of the rest; ties keep Arc's order.
+func (imp *Importer) sortItems(items []*types.ArcItem, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
+	order := imp.options.Sort
+	if order == "" || order == SortNone || len(items) < 2 {
+		return items
+	}
+	type sortKey struct {
+		rank   int // 0 Arc container, 1 folder, 2 tab
+		host   string
+		title  string
+		recent float64
+	}
+	keys := make(map[*types.ArcItem]sortKey, len(items))
+	for _, item := range items {
+		key := sortKey{rank: 2, title: strings.ToLower(arcItemTitle(item))}
+		switch {
+		case item.Data != nil && item.Data.ItemContainer != nil && item.Data.ItemContainer.ContainerType != nil:
+			key.rank = 0
+		case isArcFolder(item):
+			key.rank = 1
+		default:
+			key.host = sortHost(item)
+		}
+		if order == SortRecent {
+			key.recent = lastActivity(item, itemsMap, make(map[string]bool))
+		}
+		keys[item] = key
+	}
+
+	sorted := append([]*types.ArcItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := keys[sorted[i]], keys[sorted[j]]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.rank == 0 {
+			return false
+		}
+		switch order {
+		case SortDomain:
+			if a.host != b.host {
+				// Tabs without a host go last
+				if a.host == "" || b.host == "" {
+					return b.host == ""
+				}
+				return a.host < b.host
+			}
+		case SortRecent:
+			return a.recent > b.recent
+		}
+		return a.title < b.title
+	})
+	return sorted
+}
+
+// sortHost is the host SortDomain groups a tab by, without "www."
+func sortHost(item *types.ArcItem) string {
+	if item.Data == nil || item.Data.Tab == nil {
+		return ""
+	}
+	parsed, err := url.Parse(strings.TrimSpace(item.Data.Tab.SavedURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// lastActivity is when item was last used, in Arc time: a tab's last visit
+// (or creation, if Arc has no visit for it), the latest of a folder's items
+func lastActivity(item *types.ArcItem, itemsMap map[string]*types.ArcItem, seen map[string]bool) float64 {
+	if seen[item.ID] {
+		return 0
+	}
+	seen[item.ID] = true
+	latest := item.CreatedAt
+	if item.Data != nil && item.Data.Tab != nil && item.Data.Tab.LastActiveAt > latest {
+		latest = item.Data.Tab.LastActiveAt
+	}
+	for _, childID := range item.ChildrenIds {
+		if child := itemsMap[childID]; child != nil {
+			if t := lastActivity(child, itemsMap, seen); t > latest {
+				latest = t
+			}
+		}
+	}
+	return latest
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_Sort(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "c1"]}],
+			"items": [
+				{"id": "c1", "childrenIds": ["t1", "f1", "t2", "t5"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s1"}}}}},
+				{"id": "t1", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "beta", "savedURL": "https://www.zed.test/", "timeLastActiveAt": 100}}},
+				{"id": "f1", "parentID": "c1", "title": "Zeta", "childrenIds": ["t3", "t4"], "data": {}},
+				{"id": "t3", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Yak", "savedURL": "https://b.test/", "timeLastActiveAt": 500}}},
+				{"id": "t4", "parentID": "f1", "createdAt": 50, "childrenIds": [], "data": {"tab": {"savedTitle": "Ant", "savedURL": "https://a.test/"}}},
+				{"id": "t2", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "Alpha", "savedURL": "https://zed.test/x", "timeLastActiveAt": 300}}},
+				{"id": "t5", "parentID": "c1", "createdAt": 400, "childrenIds": [], "data": {"tab": {"savedTitle": "Gamma", "savedURL": "https://mid.test/"}}}
+			]
+		}]}
+	}`
+
+	tests := []struct {
+		sort string
+		want string // Tab titles in session order
+	}{
+		{SortNone, "beta,Yak,Ant,Alpha,Gamma"},
+		{SortAlpha, "Ant,Yak,Alpha,beta,Gamma"},
+		{SortDomain, "Ant,Yak,Gamma,Alpha,beta"},
+		// The folder counts as used at 500, when Yak was
+		{SortRecent, "Yak,Ant,Gamma,Alpha,beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			imp := newTestImporter(t, ImportOptions{Sort: tt.sort})
+			if err := imp.validateOptions(); err != nil {
+				t.Fatal(err)
+			}
+			session := emptySession()
+			if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+				t.Fatal(err)
+			}
+			var tabs []string
+			for _, tab := range session.Tabs {
+				if !tab.ZenIsEmpty {
+					tabs = append(tabs, tab.ZenStaticLabel)
+				}
+			}
+			if got := strings.Join(tabs, ","); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+				t.Errorf("expected the sorted items to restore, got %v", report.Issues)
+			}
+		})
+	}
+
+	imp := newTestImporter(t, ImportOptions{Sort: "size"})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected an unknown sort order to be rejected")
+	}
+}
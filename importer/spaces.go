@@ -0,0 +1,269 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"arc-to-zen/types"
+)
+
+// SpaceError records an Arc space that could not be imported. The other
+// spaces are still imported unless ImportOptions.FailFast is set.
+type SpaceError struct {
+	Space string // Workspace name
+	Err   error
+}
+
+func (e *SpaceError) Error() string {
+	return fmt.Sprintf("space %q: %v", e.Space, e.Err)
+}
+
+func (e *SpaceError) Unwrap() error {
+	return e.Err
+}
+
+// spaceBuild is what one Arc space's items produce. It is built apart from the
+// session so a failing space can be dropped without touching the others.
+type spaceBuild struct {
+	session  *types.ZenSession // Tabs, folders and groups of this space only
+	plan     *Plan
+	warnings *Warnings
+	log      *bufferedLogger
+	items    int
+	err      error
+}
+
+// spaceTarget is the Zen workspace a space is imported into
+type spaceTarget struct {
+	uuid        string
+	name        string
+	icon        string // Zen workspace icon
+	profileName string
+	profile     *ProfileInfo
+	merge       bool           // Replaces the pins of an existing workspace
+	keep        bool           // With merge: only replaces the folders it imports again (-mode folders)
+	deriveIcon  bool           // Replace icon with one derived from the built tabs
+	emoji       string         // Arc emoji icon, used by deriveIcon when no favicon is shared
+	letterIcon  string         // Initial of the name (or its avatar), deriveIcon's last resort
+	theme       types.ZenTheme // Zen theme built from the Arc space colors
+}
+
+// spaceJob is the input for building one space
+type spaceJob struct {
+	space       *types.ArcSpace
+	name        string
+	containerID int
+}
+
+// failFast reports whether one broken space should abort the whole import
+func (imp *Importer) failFast() bool {
+	return imp.options.FailFast || imp.options.Strict
+}
+
+// buildSpaces builds every space concurrently and returns the results in job order
+func (imp *Importer) buildSpaces(
+	ctx context.Context,
+	jobs []spaceJob,
+	spaceUUIDMap map[string]string,
+	itemsMap map[string]*types.ArcItem,
+	arcToZenUUIDMap map[string]string,
+	now int64,
+) []*spaceBuild {
+	builds := make([]*spaceBuild, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job spaceJob) {
+			defer wg.Done()
+			builds[i] = imp.buildSpace(ctx, job, spaceUUIDMap, itemsMap, arcToZenUUIDMap, now)
+		}(i, job)
+	}
+	wg.Wait()
+	return builds
+}
+
+// buildSpace inserts one space's items into a fresh session fragment
+func (imp *Importer) buildSpace(
+	ctx context.Context,
+	job spaceJob,
+	spaceUUIDMap map[string]string,
+	itemsMap map[string]*types.ArcItem,
+	arcToZenUUIDMap map[string]string,
+	now int64,
+) (build *spaceBuild) {
+	build = &spaceBuild{
+		session:  &types.ZenSession{},
+		plan:     &Plan{},
+		warnings: &Warnings{},
+		log:      &bufferedLogger{},
+	}
+
+	// A worker shares the importer's settings but logs and records into the build
+	worker := *imp
+	worker.logger = build.log
+	worker.plan = build.plan
+	worker.warnings = build.warnings
+
+	defer func() {
+		if r := recover(); r != nil {
+			build.err = fmt.Errorf("unexpected error while creating items: %v", r)
+		}
+	}()
+
+	worker.logger.Info("Processing space: \"%s\"", job.name)
+	rootItems := worker.sortItems(getRootItemsForSpace(job.space, itemsMap), itemsMap)
+	worker.logger.Info("Found %d root items", len(rootItems))
+
+	// Track the last folder created for each parent (for sibling references in nested folders)
+	lastFolderByParent := make(map[string]string)
+	for _, rootItem := range rootItems {
+		build.items += worker.insertItemWithChildren(
+			ctx,
+			rootItem,
+			"",
+			job.space.ID,
+			spaceUUIDMap,
+			job.space,
+			job.containerID,
+			itemsMap,
+			arcToZenUUIDMap,
+			build.session,
+			now,
+			0,
+			lastFolderByParent,
+		)
+	}
+	return build
+}
+
+// mergeInto appends the build's tabs, folders and groups to session
+func (b *spaceBuild) mergeInto(session *types.ZenSession) {
+	for _, tab := range b.session.Tabs {
+		tab.Index = len(session.Tabs)
+		session.Tabs = append(session.Tabs, tab)
+	}
+	session.Folders = append(session.Folders, b.session.Folders...)
+	session.Groups = append(session.Groups, b.session.Groups...)
+}
+
+// validateSpaceItems checks that a space's item tree can be walked, i.e. no
+// item is its own ancestor through childrenIds
+func validateSpaceItems(space *types.ArcSpace, itemsMap map[string]*types.ArcItem) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+
+	var visit func(item *types.ArcItem) error
+	visit = func(item *types.ArcItem) error {
+		switch state[item.ID] {
+		case visiting:
+			return fmt.Errorf("item %s contains itself (cyclic childrenIds)", item.ID)
+		case done:
+			return nil
+		}
+		state[item.ID] = visiting
+		for _, childID := range item.ChildrenIds {
+			if child := itemsMap[childID]; child != nil {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+		}
+		state[item.ID] = done
+		return nil
+	}
+
+	for _, item := range getRootItemsForSpace(space, itemsMap) {
+		if err := visit(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferedLogger holds a worker's output so spaces are logged one after another
+type bufferedLogger struct {
+	lines []bufferedLine
+}
+
+type bufferedLine struct {
+	isError bool
+	isDebug bool
+	format  string
+	args    []interface{}
+}
+
+func (l *bufferedLogger) Info(format string, args ...interface{}) {
+	l.lines = append(l.lines, bufferedLine{format: format, args: args})
+}
+
+func (l *bufferedLogger) Error(format string, args ...interface{}) {
+	l.lines = append(l.lines, bufferedLine{isError: true, format: format, args: args})
+}
+
+// Debug is kept until replay, which knows whether the real logger wants it
+func (l *bufferedLogger) Debug(format string, args ...interface{}) {
+	l.lines = append(l.lines, bufferedLine{isDebug: true, format: format, args: args})
+}
+
+// Warn is never called: a worker's warnings reach the logger when they are
+// merged into the importer's (Warnings.addAll)
+func (l *bufferedLogger) Warn(Warning) {}
+
+// replay writes the buffered lines to logger, detail only if logger is a
+// LevelLogger or verbose is set
+func (l *bufferedLogger) replay(logger Logger, verbose bool) {
+	for _, line := range l.lines {
+		switch {
+		case line.isError:
+			logger.Error(line.format, line.args...)
+		case line.isDebug:
+			logDebug(logger, verbose, line.format, line.args...)
+		default:
+			logger.Info(line.format, line.args...)
+		}
+	}
+}
+
+// derivedSpaceIcon returns the favicon shared by most of a space's tabs (ties
+// go to the one that reached the count first), else its Arc emoji, else
+// letterIcon (its initial, plain or as an avatar), else fallback
+func derivedSpaceIcon(tabs []PlannedTab, emoji, letterIcon, fallback string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, tab := range tabs {
+		if tab.Icon == "" {
+			continue
+		}
+		counts[tab.Icon]++
+		if counts[tab.Icon] > counts[best] {
+			best = tab.Icon
+		}
+	}
+	switch {
+	case best != "":
+		return best
+	case strings.TrimSpace(emoji) != "":
+		return strings.TrimSpace(emoji)
+	case letterIcon != "":
+		return letterIcon
+	}
+	return fallback
+}
+
+// collapsePinned reports whether the workspace name, holding pins pinned tabs,
+// should start with its pinned section collapsed, and whether the options
+// decide it at all (a merged workspace otherwise keeps its own setting)
+func (imp *Importer) collapsePinned(name string, pins int) (collapsed, set bool) {
+	if setting, ok := imp.options.SpaceSettings[name]; ok {
+		return setting == SpaceCollapsed, true
+	}
+	if imp.options.CollapsePinnedOver > 0 {
+		return pins > imp.options.CollapsePinnedOver, true
+	}
+	return false, false
+}
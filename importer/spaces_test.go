@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"arc-to-zen/avatar"
+	"arc-to-zen/types"
+)
+
+// brokenSpaceArcData has a healthy space and one whose folder contains itself
+func brokenSpaceArcData(t *testing.T) *types.ArcData {
+	return parseTestArcData(t, `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Good", "containerIDs": ["pinned", "f1"]},
+				{"id": "s2", "title": "Broken", "containerIDs": ["pinned", "f2"]}
+			],
+			"items": [
+				{"id": "f1", "title": "Folder", "childrenIds": ["t1"], "data": {}},
+				{"id": "t1", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/one"}}},
+				{"id": "f2", "title": "Loop", "childrenIds": ["f3"], "data": {}},
+				{"id": "f3", "parentID": "f2", "title": "Back", "childrenIds": ["f2"], "data": {}}
+			]
+		}]}
+	}`)
+}
+
+func TestDoImport_SkipsBrokenSpace(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	session := emptySession()
+	session.Spaces = append(session.Spaces, types.ZenSpace{UUID: "{broken}", Name: "Broken"})
+	session.Tabs = append(session.Tabs, types.ZenTab{ZenWorkspace: "{broken}", ZenSyncID: "{keep}"})
+
+	result, err := imp.doImport(context.Background(), brokenSpaceArcData(t), session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+	if len(result.SpaceErrors) != 1 || result.SpaceErrors[0].Space != "Broken" {
+		t.Fatalf("expected Broken to fail, got %+v", result.SpaceErrors)
+	}
+	if result.SpacesCreated != 1 || describeTree(result.Plan.Tree()) != "Good[Folder[One]]" {
+		t.Errorf("expected only Good to be imported, got %d spaces: %s", result.SpacesCreated, describeTree(result.Plan.Tree()))
+	}
+
+	// The broken space's existing pins are left alone
+	kept := false
+	for _, tab := range session.Tabs {
+		kept = kept || tab.ZenSyncID == "{keep}"
+	}
+	if !kept {
+		t.Error("existing pins of the failed space were removed")
+	}
+}
+
+func TestDoImport_FailFast(t *testing.T) {
+	for _, opts := range []ImportOptions{{FailFast: true}, {Strict: true}} {
+		imp := newTestImporter(t, opts)
+		_, err := imp.doImport(context.Background(), brokenSpaceArcData(t), emptySession(), &types.ContainersData{Version: 5})
+		var spaceErr *SpaceError
+		if !errors.As(err, &spaceErr) || spaceErr.Space != "Broken" {
+			t.Errorf("%+v: expected SpaceError for Broken, got %v", opts, err)
+		}
+	}
+}
+
+func TestSpaceBuild_MergeKeepsOrder(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), multiProfileArcData(t, testSite), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	var titles []string
+	for i, tab := range session.Tabs {
+		if tab.Index != i {
+			t.Errorf("tab %d has index %d", i, tab.Index)
+		}
+		if !tab.ZenIsEmpty {
+			titles = append(titles, tab.ZenStaticLabel)
+		}
+	}
+	if got := fmt.Sprint(titles); got != "[One Two Three Four]" {
+		t.Errorf("unexpected tab order %s", got)
+	}
+}
+
+func TestDerivedSpaceIcon(t *testing.T) {
+	const globe = "chrome://browser/skin/zen-icons/selectable/globe.svg"
+	tabs := []PlannedTab{{Icon: "data:a"}, {Icon: ""}, {Icon: "data:b"}, {Icon: "data:b"}, {Icon: "data:a"}}
+
+	tests := []struct {
+		tabs   []PlannedTab
+		emoji  string
+		letter string
+		want   string
+	}{
+		{tabs, "🚀", "W", "data:b"},
+		{tabs[:2], "", "W", "data:a"},
+		{nil, "🚀", "W", "🚀"},
+		{nil, " ", "W", "W"},
+		{nil, "", "", globe},
+	}
+	for _, tt := range tests {
+		if got := derivedSpaceIcon(tt.tabs, tt.emoji, tt.letter, globe); got != tt.want {
+			t.Errorf("derivedSpaceIcon(%d tabs, %q, %q) = %q, want %q", len(tt.tabs), tt.emoji, tt.letter, got, tt.want)
+		}
+	}
+}
+
+func TestDoImport_SpaceIconFromFavicons(t *testing.T) {
+	const icon = "data:image/png;base64,iVBORw0KGgo="
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Sites", "containerIDs": ["pinned", "t1"]},
+				{"id": "s2", "title": "Fun", "containerIDs": ["pinned"], "customInfo": {"iconType": {"emoji_v2": "🎉"}}},
+				{"id": "s3", "title": "Mail", "containerIDs": ["pinned", "t2"], "customInfo": {"iconType": {"icon": "mail"}}}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/one"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Two", "savedURL": "https://site.test/two"}}}
+			]
+		}]}
+	}`
+
+	imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{SpaceIconFromFavicons: true, FaviconFetcher: iconFetcher{icon}})
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	want := map[string]string{
+		"Sites": icon,
+		"Fun":   "🎉",
+		"Mail":  "chrome://browser/skin/zen-icons/selectable/mail.svg",
+	}
+	for _, space := range session.Spaces {
+		if space.Icon != want[space.Name] {
+			t.Errorf("space %q: expected icon %q, got %q", space.Name, want[space.Name], space.Icon)
+		}
+	}
+	for _, space := range imp.plan.Spaces {
+		if space.Icon != want[space.Name] {
+			t.Errorf("planned space %q: expected icon %q, got %q", space.Name, want[space.Name], space.Icon)
+		}
+	}
+}
+
+func TestDoImport_LetterAvatars(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "research", "color": "blue", "containerIDs": ["pinned"]},
+				{"id": "s2", "title": "Mail", "containerIDs": ["pinned"], "customInfo": {"iconType": {"icon": "mail"}}},
+				{"id": "s3", "title": "✨", "containerIDs": ["pinned"]}
+			],
+			"items": []
+		}]}
+	}`
+
+	imp := newTestImporter(t, ImportOptions{LetterAvatars: true})
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	want := map[string]string{
+		"research": avatar.Letter("research", "#37adff"),
+		"Mail":     "chrome://browser/skin/zen-icons/selectable/mail.svg",
+		"✨":        "chrome://browser/skin/zen-icons/selectable/globe.svg",
+	}
+	for _, space := range session.Spaces {
+		if space.Icon != want[space.Name] {
+			t.Errorf("space %q: expected icon %q, got %q", space.Name, want[space.Name], space.Icon)
+		}
+	}
+}
+
+func TestDoImport_CollapsePinned(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Big", "containerIDs": ["pinned", "t1", "t2", "t3"]},
+				{"id": "s2", "title": "Small", "containerIDs": ["pinned", "t4"]},
+				{"id": "s3", "title": "Pinned Open", "containerIDs": ["pinned", "t5", "t6", "t7"]}
+			],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/1"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/2"}}},
+				{"id": "t3", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/3"}}},
+				{"id": "t4", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/4"}}},
+				{"id": "t5", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/5"}}},
+				{"id": "t6", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/6"}}},
+				{"id": "t7", "childrenIds": [], "data": {"tab": {"savedURL": "https://a.test/7"}}}
+			]
+		}]}
+	}`
+
+	imp := newTestImporter(t, ImportOptions{
+		CollapsePinnedOver: 2,
+		SpaceSettings:      map[string]string{"Pinned Open": SpaceExpanded},
+	})
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	collapsed := make(map[string]bool)
+	for _, space := range session.Spaces {
+		collapsed[space.Name] = space.HasCollapsedPinnedTabs
+	}
+	if !collapsed["Big"] || collapsed["Small"] || collapsed["Pinned Open"] {
+		t.Errorf("expected only Big collapsed, got %v", collapsed)
+	}
+}
+
+func TestCollapsePinned_UnsetKeepsMergedSetting(t *testing.T) {
+	imp := newTestImporter(t, ImportOptions{})
+	if _, set := imp.collapsePinned("Work", 50); set {
+		t.Error("expected no decision without -collapse-pinned or -space-setting")
+	}
+}
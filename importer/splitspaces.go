@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// validateSplitSpaces checks ImportOptions.SplitSpaces
+func validateSplitSpaces(split map[string]int) error {
+	for name, size := range split {
+		if size < 1 {
+			return fmt.Errorf("invalid -split-space size %d for %q (expected 1 or more)", size, name)
+		}
+	}
+	return nil
+}
+
+// splitSpaces divides the spaces named in ImportOptions.SplitSpaces into
+// several of at most that many items each: "Work", "Work 2", ... Only the
+// space's top-level items are dealt out, so folders stay intact; a folder
+// bigger than the limit gets a space of its own. The extra spaces copy the
+// original's icon, color and profile and list their items directly, without
+// Arc's pinned/unpinned containers.
+func (imp *Importer) splitSpaces(spaces []*types.ArcSpace, itemsMap map[string]*types.ArcItem) []*types.ArcSpace {
+	if len(imp.options.SplitSpaces) == 0 {
+		return spaces
+	}
+	matched := make(map[string]bool)
+	var result []*types.ArcSpace
+	for _, space := range spaces {
+		limit, ok := imp.options.SplitSpaces[space.Title]
+		if !ok {
+			result = append(result, space)
+			continue
+		}
+		matched[space.Title] = true
+
+		var chunks [][]interface{}
+		var chunk []interface{}
+		size := 0
+		for _, item := range topLevelItems(space, itemsMap) {
+			n := imp.itemCount(item, itemsMap, make(map[string]bool))
+			if n == 0 {
+				continue
+			}
+			if size+n > limit && len(chunk) > 0 {
+				chunks = append(chunks, chunk)
+				chunk, size = nil, 0
+			}
+			chunk = append(chunk, item.ID)
+			size += n
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+		if len(chunks) < 2 {
+			result = append(result, space)
+			continue
+		}
+
+		imp.logger.Info("Splitting space \"%s\" into %d workspaces of up to %d items (-split-space)", space.Title, len(chunks), limit)
+		for i, ids := range chunks {
+			part := *space
+			part.ContainerIDs = ids
+			if i > 0 {
+				part.ID = fmt.Sprintf("%s/%d", space.ID, i+1)
+				part.Title = fmt.Sprintf("%s %d", space.Title, i+1)
+			}
+			result = append(result, &part)
+		}
+	}
+
+	var missing []string
+	for name := range imp.options.SplitSpaces {
+		if !matched[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		imp.warnings.Add(WarningMapping, "", "-split-space: no Arc space named %s", strings.Join(missing, ", "))
+	}
+	return result
+}
+
+// topLevelItems returns the items at the top of space, looking through Arc's
+// pinned/unpinned containers
+func topLevelItems(space *types.ArcSpace, itemsMap map[string]*types.ArcItem) []*types.ArcItem {
+	var items []*types.ArcItem
+	seen := make(map[string]bool)
+	var walk func(item *types.ArcItem)
+	walk = func(item *types.ArcItem) {
+		if seen[item.ID] {
+			return
+		}
+		seen[item.ID] = true
+		if item.Data == nil || item.Data.ItemContainer == nil || item.Data.ItemContainer.ContainerType == nil {
+			items = append(items, item)
+			return
+		}
+		for _, childID := range item.ChildrenIds {
+			if child := itemsMap[childID]; child != nil {
+				walk(child)
+			}
+		}
+	}
+	for _, root := range getRootItemsForSpace(space, itemsMap) {
+		walk(root)
+	}
+	return items
+}
+
+// itemCount counts item and everything in it the import would create,
+// leaving out excluded and deselected items
+func (imp *Importer) itemCount(item *types.ArcItem, itemsMap map[string]*types.ArcItem, seen map[string]bool) int {
+	if seen[item.ID] {
+		return 0
+	}
+	seen[item.ID] = true
+	if _, excluded := imp.excluded[item.ID]; excluded {
+		return 0
+	}
+	count := 1
+	for _, childID := range item.ChildrenIds {
+		if child := itemsMap[childID]; child != nil {
+			count += imp.itemCount(child, itemsMap, seen)
+		}
+	}
+	return count
+}
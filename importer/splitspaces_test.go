@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"arc-to-zen/restoresim"
+	"arc-to-zen/types"
+)
+
+func TestDoImport_SplitSpaces(t *testing.T) {
+	raw := fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "c1"]}],
+			"items": [
+				{"id": "c1", "childrenIds": ["t1", "f1", "t2", "t3"], "data": {"itemContainer": {"containerType": {"spaceItems": {"_0": "s1"}}}}},
+				{"id": "t1", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "%[1]s/mail"}}},
+				{"id": "f1", "parentID": "c1", "title": "Docs", "childrenIds": ["t4", "t5"], "data": {}},
+				{"id": "t4", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "t5", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Notes", "savedURL": "%[1]s/notes"}}},
+				{"id": "t2", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "Wiki", "savedURL": "%[1]s/wiki"}}},
+				{"id": "t3", "parentID": "c1", "childrenIds": [], "data": {"tab": {"savedTitle": "News", "savedURL": "%[1]s/news"}}}
+			]
+		}]}
+	}`, testSite)
+
+	imp := newTestImporter(t, ImportOptions{SplitSpaces: map[string]int{"Work": 3, "Play": 10}})
+	if err := imp.validateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	session := emptySession()
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Docs and its two tabs are 3 items, so Mail can't share a workspace with them
+	spaces := make(map[string]string)
+	var names []string
+	for _, space := range session.Spaces {
+		spaces[space.UUID] = space.Name
+		names = append(names, space.Name)
+	}
+	if got := strings.Join(names, ","); got != "Work,Work 2,Work 3" {
+		t.Fatalf("expected three workspaces, got %s", got)
+	}
+	var tabs []string
+	for _, tab := range session.Tabs {
+		if !tab.ZenIsEmpty {
+			tabs = append(tabs, tab.ZenStaticLabel+"@"+spaces[tab.ZenWorkspace])
+		}
+	}
+	if got := strings.Join(tabs, ","); got != "Mail@Work,Spec@Work 2,Notes@Work 2,Wiki@Work 3,News@Work 3" {
+		t.Errorf("unexpected tabs %s", got)
+	}
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 {
+		t.Errorf("expected the split spaces to restore, got %v", report.Issues)
+	}
+	if warnings := imp.warnings.List(); len(warnings) == 0 || warnings[0].Message != "-split-space: no Arc space named Play" {
+		t.Errorf("expected a warning about the unknown space, got %v", warnings)
+	}
+
+	imp = newTestImporter(t, ImportOptions{SplitSpaces: map[string]int{"Work": 0}})
+	if err := imp.validateOptions(); err == nil {
+		t.Error("expected a size of 0 to be rejected")
+	}
+}
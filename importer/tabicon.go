@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// maxCustomIconLength caps a custom icon's data URL. Fetched favicons are
+// capped at 1MB of image, which is about 1.4MB once base64-encoded.
+const maxCustomIconLength = 2 << 20
+
+// customTabIcon returns the data URL for the icon the user gave a tab in Arc,
+// or "" if it has none (or one that can't be used, with a warning)
+func (imp *Importer) customTabIcon(item *types.ArcItem, title string) string {
+	icon := item.CustomIcon
+	if icon == nil {
+		return ""
+	}
+	if icon.DataURL != "" {
+		if len(icon.DataURL) > maxCustomIconLength {
+			imp.warnings.Add(WarningFavicon, title, "custom Arc icon is too large (%d bytes); using the site favicon", len(icon.DataURL))
+			return ""
+		}
+		return icon.DataURL
+	}
+	return emojiIconDataURL(icon.Emoji)
+}
+
+// hasCustomTabIcon reports whether customTabIcon will return an icon for item
+func hasCustomTabIcon(item *types.ArcItem) bool {
+	icon := item.CustomIcon
+	if icon == nil {
+		return false
+	}
+	if icon.DataURL != "" {
+		return len(icon.DataURL) <= maxCustomIconLength
+	}
+	return emojiIconDataURL(icon.Emoji) != ""
+}
+
+// emojiIconDataURL renders a single emoji as an SVG data URL, which Zen shows
+// like any other favicon. Returns "" if emoji isn't exactly one emoji.
+func emojiIconDataURL(emoji string) string {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || emojiLength(emoji) != len(emoji) {
+		return ""
+	}
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><text x="50" y="50" font-size="80" text-anchor="middle" dominant-baseline="central">%s</text></svg>`,
+		html.EscapeString(emoji))
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+
+	"arc-to-zen/mappings"
+	"arc-to-zen/types"
+)
+
+// Zen's gradient picker is a color wheel; dots are placed by hue (angle) and
+// saturation (distance from the center). The color itself comes from "c".
+const (
+	gradientPickerCenter = 179.0
+	gradientPickerRadius = 160.0
+)
+
+// Theme settings for imported workspaces. Arc's themes are soft and diagonal,
+// so gradients are a little more opaque than Zen's default and run at 45°.
+const (
+	defaultThemeOpacity  = 0.5 // Zen's default, used without a theme
+	solidThemeOpacity    = 0.55
+	gradientThemeOpacity = 0.65
+	gradientRotation     = 45
+	analogousHueShift    = 30.0 // Hue offset of the stop generated for one-color themes
+	analogousLightness   = 0.08 // and how much lighter it is
+)
+
+// spaceContainerColor returns the container color for a space: the nearest
+// match to its Arc theme color, else its color name mapped through the
+// tables, else ""
+func spaceContainerColor(space *types.ArcSpace) string {
+	if colors := space.ThemeColors(); len(colors) > 0 {
+		return mappings.NearestContainerColor(colors[0].RGB())
+	}
+	if space.Color != "" {
+		return mappings.MapArcColorToZen(space.Color)
+	}
+	return ""
+}
+
+// spaceColorHex returns the #rrggbb color Arc shows for a space, falling back
+// to its container color's hex
+func spaceColorHex(space *types.ArcSpace) string {
+	if colors := space.ThemeColors(); len(colors) > 0 {
+		r, g, b := colors[0].RGB()
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	return mappings.ContainerColorHex(mappings.MapArcColorToZen(space.Color))
+}
+
+// zenTheme builds the Zen workspace theme for an Arc space under policy
+// (ThemeNone, ThemeSolid or ThemeGradient). Spaces without theme colors, and
+// ThemeNone, get Zen's default theme.
+func zenTheme(space *types.ArcSpace, policy string) types.ZenTheme {
+	theme := types.ZenTheme{
+		Type:           "gradient",
+		GradientColors: []interface{}{},
+		Opacity:        defaultThemeOpacity,
+		Rotation:       nil,
+		Texture:        nil,
+	}
+	colors := space.ThemeColors()
+	if len(colors) == 0 || policy == ThemeNone {
+		return theme
+	}
+
+	stops := make([][3]int, 0, len(colors)+1)
+	for _, color := range colors {
+		r, g, b := color.RGB()
+		stops = append(stops, [3]int{r, g, b})
+	}
+	if policy == ThemeSolid {
+		theme.GradientColors = gradientColors(stops[:1])
+		theme.Opacity = solidThemeOpacity
+		return theme
+	}
+
+	// Two or three stops: a one-color theme gets a neighbouring hue
+	if len(stops) == 1 {
+		hue, saturation, lightness := mappings.HSL(stops[0][0], stops[0][1], stops[0][2])
+		r, g, b := mappings.FromHSL(math.Mod(hue+analogousHueShift, 360), saturation, math.Min(lightness+analogousLightness, 0.9))
+		stops = append(stops, [3]int{r, g, b})
+	}
+	theme.GradientColors = gradientColors(stops)
+	theme.Opacity = gradientThemeOpacity
+	theme.Rotation = gradientRotation
+	return theme
+}
+
+// gradientColors converts RGB stops to Zen gradient colors, the first primary
+func gradientColors(stops [][3]int) []interface{} {
+	gradient := make([]interface{}, 0, len(stops))
+	for i, stop := range stops {
+		r, g, b := stop[0], stop[1], stop[2]
+		hue, saturation, lightness := mappings.HSL(r, g, b)
+		angle := hue * math.Pi / 180
+		distance := saturation * gradientPickerRadius
+		gradient = append(gradient, map[string]interface{}{
+			"c":         []int{r, g, b},
+			"isCustom":  false,
+			"algorithm": "floating",
+			"isPrimary": i == 0,
+			"lightness": math.Round(lightness * 100),
+			"position": map[string]int{
+				"x": int(math.Round(gradientPickerCenter + distance*math.Cos(angle))),
+				"y": int(math.Round(gradientPickerCenter + distance*math.Sin(angle))),
+			},
+			"type": "explicit-lightness",
+		})
+	}
+	return gradient
+}
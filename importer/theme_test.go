@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func TestDoImport_ColorsFromArcTheme(t *testing.T) {
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [
+				{"id": "s1", "title": "Work", "containerIDs": ["pinned"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 1"}}},
+				 "customInfo": {"windowTheme": {
+					"primaryColorPalette": {"midTone": {"red": 0.2, "green": 0.7, "blue": 0.3, "alpha": 1}},
+					"background": {"colors": [
+						{"red": 0.2, "green": 0.7, "blue": 0.3, "alpha": 1},
+						{"red": 0.95, "green": 0.8, "blue": 0.1, "alpha": 1}
+					]}
+				 }}},
+				{"id": "s2", "title": "Home", "color": "crimson", "containerIDs": ["pinned"],
+				 "profile": {"custom": {"_0": {"directoryBasename": "Profile 2"}}}}
+			],
+			"items": []
+		}]}
+	}`
+
+	imp := newTestImporter(t, ImportOptions{})
+	session := emptySession()
+	containersData := &types.ContainersData{Version: 5}
+	if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, containersData); err != nil {
+		t.Fatalf("doImport failed: %v", err)
+	}
+
+	colors := make(map[string]string)
+	for _, identity := range containersData.Identities {
+		colors[identity.Name] = identity.Color
+	}
+	if colors["Profile 1"] != "green" || colors["Profile 2"] != "red" {
+		t.Errorf("expected green from the theme and red from the color name, got %v", colors)
+	}
+
+	for _, space := range session.Spaces {
+		gradient := space.Theme.GradientColors
+		switch space.Name {
+		case "Work":
+			if len(gradient) != 2 {
+				t.Fatalf("expected two gradient colors, got %v", gradient)
+			}
+			first := gradient[0].(map[string]interface{})
+			if c := first["c"].([]int); c[0] != 51 || c[1] != 179 || c[2] != 77 || first["isPrimary"] != true {
+				t.Errorf("unexpected primary gradient color %v", first)
+			}
+		case "Home":
+			if len(gradient) != 0 {
+				t.Errorf("expected no gradient without a theme, got %v", gradient)
+			}
+		}
+	}
+}
+
+func TestZenTheme_Policies(t *testing.T) {
+	oneColor := &types.ArcSpace{CustomInfo: &types.ArcCustomInfo{WindowTheme: []byte(`{
+		"primaryColorPalette": {"midTone": {"red": 0.2, "green": 0.4, "blue": 0.9, "alpha": 1}}
+	}`)}}
+
+	tests := []struct {
+		policy   string
+		stops    int
+		opacity  float64
+		rotation interface{}
+	}{
+		{"", 2, gradientThemeOpacity, gradientRotation},
+		{ThemeGradient, 2, gradientThemeOpacity, gradientRotation},
+		{ThemeSolid, 1, solidThemeOpacity, nil},
+		{ThemeNone, 0, defaultThemeOpacity, nil},
+	}
+	for _, tt := range tests {
+		theme := zenTheme(oneColor, tt.policy)
+		if len(theme.GradientColors) != tt.stops || theme.Opacity != tt.opacity || theme.Rotation != tt.rotation {
+			t.Errorf("policy %q: got %d stops, opacity %v, rotation %v", tt.policy, len(theme.GradientColors), theme.Opacity, theme.Rotation)
+		}
+		if theme.Type != "gradient" {
+			t.Errorf("policy %q: expected type gradient, got %q", tt.policy, theme.Type)
+		}
+	}
+
+	// The generated stop is a lighter neighbouring hue, not the primary again
+	theme := zenTheme(oneColor, ThemeGradient)
+	primary := theme.GradientColors[0].(map[string]interface{})
+	second := theme.GradientColors[1].(map[string]interface{})
+	if second["isPrimary"] != false || second["lightness"].(float64) <= primary["lightness"].(float64) {
+		t.Errorf("expected a lighter, non-primary second stop, got %v after %v", second, primary)
+	}
+
+	if theme := zenTheme(&types.ArcSpace{}, ThemeGradient); len(theme.GradientColors) != 0 || theme.Rotation != nil {
+		t.Errorf("expected the default theme for a space without colors, got %+v", theme)
+	}
+}
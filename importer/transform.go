@@ -0,0 +1,36 @@
+package importer
+
+// TabTransform rewrites a tab while the import is planned, e.g. to unwrap
+// SSO redirects or tidy titles. It gets a copy of the tab as planned so far
+// (Icon and Essential aren't known yet) and returns the tab to import: its
+// Title and URL are used, changes to other fields are ignored. Returning nil
+// leaves the tab out. Spaces are built concurrently, so it may be called
+// from several goroutines at once.
+type TabTransform func(tab *PlannedTab) *PlannedTab
+
+// FolderTransform is TabTransform for folders: the returned folder's Name is
+// used, and nil leaves the folder out with everything in it. ID isn't known
+// yet.
+type FolderTransform func(folder *PlannedFolder) *PlannedFolder
+
+// transformReason is the Plan.Skipped reason of the items a transform hook
+// left out
+const transformReason = "transform hook"
+
+// transformTab returns tab as ImportOptions.TransformHook rewrites it, or
+// nil if it is left out
+func (imp *Importer) transformTab(tab PlannedTab) *PlannedTab {
+	if imp.options.TransformHook == nil {
+		return &tab
+	}
+	return imp.options.TransformHook(&tab)
+}
+
+// transformFolder returns folder as ImportOptions.FolderTransformHook
+// rewrites it, or nil if it is left out
+func (imp *Importer) transformFolder(folder PlannedFolder) *PlannedFolder {
+	if imp.options.FolderTransformHook == nil {
+		return &folder
+	}
+	return imp.options.FolderTransformHook(&folder)
+}
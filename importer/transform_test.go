@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+func TestDoImport_TransformHooks(t *testing.T) {
+	arcData := parseTestArcData(t, fmt.Sprintf(`{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Work", "containerIDs": ["pinned", "t1", "t2", "f1", "f2"]}],
+			"items": [
+				{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "Mail", "savedURL": "https://sso.test/login?next=%[1]s%%2Fmail"}}},
+				{"id": "t2", "childrenIds": [], "data": {"tab": {"savedTitle": "Tracker", "savedURL": "%[1]s/track"}}},
+				{"id": "f1", "title": "docs", "childrenIds": ["t3"], "data": {}},
+				{"id": "t3", "parentID": "f1", "childrenIds": [], "data": {"tab": {"savedTitle": "Spec", "savedURL": "%[1]s/spec"}}},
+				{"id": "f2", "title": "Old", "childrenIds": ["t4"], "data": {}},
+				{"id": "t4", "parentID": "f2", "childrenIds": [], "data": {"tab": {"savedTitle": "Legacy", "savedURL": "%[1]s/legacy"}}}
+			]
+		}]}
+	}`, testSite))
+	session := emptySession()
+	imp := newTestImporter(t, ImportOptions{
+		TransformHook: func(tab *PlannedTab) *PlannedTab {
+			if strings.HasSuffix(tab.URL, "/track") {
+				return nil
+			}
+			if u, err := url.Parse(tab.URL); err == nil && u.Host == "sso.test" {
+				tab.URL = u.Query().Get("next")
+			}
+			tab.Title = strings.ToUpper(tab.Title)
+			return tab
+		},
+		FolderTransformHook: func(folder *PlannedFolder) *PlannedFolder {
+			if folder.Name == "Old" {
+				return nil
+			}
+			folder.Name = strings.ToUpper(folder.Name[:1]) + folder.Name[1:]
+			return folder
+		},
+	})
+	result, err := imp.doImport(context.Background(), arcData, session, &types.ContainersData{Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pins []string
+	for _, tab := range session.Tabs {
+		if tab.Pinned && !tab.ZenIsEmpty {
+			pins = append(pins, tab.ZenStaticLabel+"="+tab.Entries[0].URL)
+		}
+	}
+	if got, want := strings.Join(pins, " "), fmt.Sprintf("MAIL=%[1]s/mail SPEC=%[1]s/spec", testSite); got != want {
+		t.Errorf("expected pins %s, got %s", want, got)
+	}
+	if len(session.Folders) != 1 || session.Folders[0].Name != "Docs" || session.Groups[0].Name != "Docs" {
+		t.Errorf("expected only the renamed Docs folder, got %+v", session.Folders)
+	}
+	if got := result.Plan.countSkipped(transformReason); got != 2 {
+		t.Errorf("expected the tracker and the Old folder skipped, got %+v", result.Plan.Skipped)
+	}
+	if len(result.Plan.Tabs) != 2 || result.Plan.Tabs[0].Title != "MAIL" || result.Plan.Tabs[0].URL != testSite+"/mail" {
+		t.Errorf("expected the plan to show the rewritten tabs, got %+v", result.Plan.Tabs)
+	}
+}
@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UnparsedItem is an entry of Arc's items list the importer could not
+// understand, kept verbatim so users can report structures it doesn't know
+type UnparsedItem struct {
+	ID     string          `json:"id,omitempty"`
+	Reason string          `json:"reason"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+// newUnparsedItem records raw with why it was dropped
+func newUnparsedItem(id, reason string, raw interface{}) UnparsedItem {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(raw))
+	}
+	return UnparsedItem{ID: id, Reason: reason, Raw: data}
+}
+
+// reportUnparsed writes the unparsed items to ImportOptions.UnparsedItems as
+// an indented JSON array, or says how to get them if it's not set
+func (imp *Importer) reportUnparsed(unparsed []UnparsedItem) error {
+	if imp.options.UnparsedItems == nil {
+		if len(unparsed) > 0 {
+			imp.logger.Info("  %d Arc items could not be understood; rerun with -unparsed-items <file> to save them for a bug report", len(unparsed))
+		}
+		return nil
+	}
+	if unparsed == nil {
+		unparsed = []UnparsedItem{}
+	}
+	data, err := json.MarshalIndent(unparsed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal unparsed Arc items: %w", err)
+	}
+	if _, err := imp.options.UnparsedItems.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write unparsed Arc items: %w", err)
+	}
+	return nil
+}
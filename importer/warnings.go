@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WarningCategory groups warnings by the part of the import that raised them
+type WarningCategory string
+
+const (
+	WarningParse   WarningCategory = "parse"   // Arc data that could not be understood
+	WarningMapping WarningCategory = "mapping" // Icons, containers, items that fell back to defaults
+	WarningFavicon WarningCategory = "favicon" // Favicons that could not be fetched
+	WarningWrite   WarningCategory = "write"   // Cleanup or backup problems while writing
+	WarningRestore WarningCategory = "restore" // Structure Zen would drop or reorder (SimulateRestore)
+)
+
+// warningCategories lists categories in the order they are reported
+var warningCategories = []WarningCategory{WarningParse, WarningMapping, WarningFavicon, WarningRestore, WarningWrite}
+
+// Warning describes something the import could not carry over exactly
+type Warning struct {
+	Category WarningCategory `json:"category"`
+	Item     string          `json:"item"`
+	Message  string          `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Item == "" {
+		return fmt.Sprintf("[%s] %s", w.Category, w.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", w.Category, w.Item, w.Message)
+}
+
+// Warnings collects warnings raised during an import. Safe for concurrent use.
+type Warnings struct {
+	mu     sync.Mutex
+	items  []Warning
+	notify func(Warning) // Called with each warning recorded (LevelLogger.Warn), outside the lock
+}
+
+// Add records a warning about item
+func (w *Warnings) Add(category WarningCategory, item, format string, args ...interface{}) {
+	warning := Warning{
+		Category: category,
+		Item:     item,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	w.mu.Lock()
+	w.items = append(w.items, warning)
+	w.mu.Unlock()
+	if w.notify != nil {
+		w.notify(warning)
+	}
+}
+
+// addAll records other's warnings after the ones collected so far
+func (w *Warnings) addAll(other *Warnings) {
+	items := other.List()
+	w.mu.Lock()
+	w.items = append(w.items, items...)
+	w.mu.Unlock()
+	if w.notify != nil {
+		for _, warning := range items {
+			w.notify(warning)
+		}
+	}
+}
+
+// List returns a copy of all warnings in the order they were raised
+func (w *Warnings) List() []Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.items...)
+}
+
+// Len returns the number of warnings collected
+func (w *Warnings) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.items)
+}
+
+// ByCategory groups warnings by category
+func (w *Warnings) ByCategory() map[WarningCategory][]Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	grouped := make(map[WarningCategory][]Warning)
+	for _, warning := range w.items {
+		grouped[warning.Category] = append(grouped[warning.Category], warning)
+	}
+	return grouped
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pinnedIconMinVersion is the first Zen release that restores a pinned tab's
+// icon from zenPinnedIcon/zenHasStaticIcon. Earlier builds only read image and
+// ignore the static icon fields.
+var pinnedIconMinVersion = zenVersion{1, 0}
+
+// zenVersion is a Zen release number such as 1.14.5 (suffixes like "b" dropped)
+type zenVersion []int
+
+// parseZenVersion reads versions like "1.14.5b", "1.0.2-b.5" or "1.7t"
+func parseZenVersion(s string) (zenVersion, error) {
+	s = strings.TrimSpace(s)
+	var v zenVersion
+	for _, part := range strings.Split(s, ".") {
+		digits := part
+		if i := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+			digits = part[:i]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			if len(v) > 0 {
+				break // Pre-release suffix such as "-b.5"
+			}
+			return nil, fmt.Errorf("invalid Zen version %q", s)
+		}
+		v = append(v, n)
+		if digits != part {
+			break
+		}
+	}
+	return v, nil
+}
+
+func (v zenVersion) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// atLeast reports whether v is min or newer
+func (v zenVersion) atLeast(min zenVersion) bool {
+	for i := 0; i < len(min); i++ {
+		var n int
+		if i < len(v) {
+			n = v[i]
+		}
+		if n != min[i] {
+			return n > min[i]
+		}
+	}
+	return true
+}
+
+// detectZenVersion returns the version that last ran the profile, from the
+// LastVersion line of compatibility.ini ("" if unknown)
+func detectZenVersion(profilePath string) string {
+	f, err := os.Open(filepath.Join(profilePath, "compatibility.ini"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && key == "LastVersion" {
+			version, _, _ := strings.Cut(value, "_") // "1.14.5b_20250601000000/20250601000000"
+			return version
+		}
+	}
+	return ""
+}
+
+// staticPinnedIcons reports whether pinned tabs should carry their favicon in
+// zenPinnedIcon. An unknown target is assumed to be a current Zen.
+func (imp *Importer) staticPinnedIcons() bool {
+	return imp.zenVersion == nil || imp.zenVersion.atLeast(pinnedIconMinVersion)
+}
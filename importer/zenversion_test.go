@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arc-to-zen/favicon"
+	"arc-to-zen/types"
+)
+
+// iconFetcher hands out the same favicon for every URL
+type iconFetcher struct{ dataURL string }
+
+func (f iconFetcher) FetchAsDataURLContext(ctx context.Context, pageURL string) string {
+	return f.dataURL
+}
+
+func (f iconFetcher) PreCacheFaviconsContext(ctx context.Context, urls []string, workers int, progress favicon.ProgressCallback) *favicon.PreCacheResult {
+	return &favicon.PreCacheResult{}
+}
+
+func (f iconFetcher) IsBlocked(pageURL string) bool { return false }
+func (f iconFetcher) IsDenied(pageURL string) bool  { return false }
+func (f iconFetcher) DedupSavedBytes() int64        { return 0 }
+
+func TestParseZenVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.14.5b":   "1.14.5",
+		"1.0.2-b.5": "1.0.2",
+		"1.7t":      "1.7",
+		" 138.0.1 ": "138.0.1",
+	}
+	for input, want := range tests {
+		got, err := parseZenVersion(input)
+		if err != nil || got.String() != want {
+			t.Errorf("parseZenVersion(%q) = %v, %v; want %s", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "beta", "b.1"} {
+		if _, err := parseZenVersion(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestZenVersionAtLeast(t *testing.T) {
+	if !(zenVersion{1, 0}).atLeast(zenVersion{1}) || !(zenVersion{1, 14, 5}).atLeast(zenVersion{1, 0}) {
+		t.Error("expected newer or equal versions to pass")
+	}
+	if (zenVersion{0, 9}).atLeast(zenVersion{1, 0}) || (zenVersion{1}).atLeast(zenVersion{1, 0, 1}) {
+		t.Error("expected older versions to fail")
+	}
+}
+
+func TestDetectZenVersion(t *testing.T) {
+	dir := t.TempDir()
+	if got := detectZenVersion(dir); got != "" {
+		t.Errorf("expected no version without compatibility.ini, got %q", got)
+	}
+	ini := "[Compatibility]\nLastVersion=1.14.5b_20250601000000/20250601000000\nLastOSABI=Darwin_aarch64-gcc3\n"
+	if err := os.WriteFile(filepath.Join(dir, "compatibility.ini"), []byte(ini), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := detectZenVersion(dir); got != "1.14.5b" {
+		t.Errorf("expected 1.14.5b, got %q", got)
+	}
+}
+
+func TestDoImport_PinnedIconFieldsByZenVersion(t *testing.T) {
+	const icon = "data:image/png;base64,iVBORw0KGgo="
+	raw := `{
+		"sidebar": {"containers": [{"global": {}}, {
+			"spaces": [{"id": "s1", "title": "Personal", "containerIDs": ["pinned", "t1"]}],
+			"items": [{"id": "t1", "childrenIds": [], "data": {"tab": {"savedTitle": "One", "savedURL": "https://site.test/"}}}]
+		}]}
+	}`
+
+	for _, tt := range []struct {
+		version    string
+		wantStatic bool
+	}{
+		{"", true},
+		{"1.14.5b", true},
+		{"0.9.1", false},
+	} {
+		imp := NewWithOptions(t.TempDir(), &testLogger{}, ImportOptions{ZenVersion: tt.version, FaviconFetcher: iconFetcher{icon}})
+		if err := imp.validateOptions(); err != nil {
+			t.Fatalf("validateOptions failed: %v", err)
+		}
+		session := emptySession()
+		if _, err := imp.doImport(context.Background(), parseTestArcData(t, raw), session, &types.ContainersData{Version: 5}); err != nil {
+			t.Fatalf("doImport failed: %v", err)
+		}
+
+		tab := session.Tabs[0]
+		initial := tab.ZenPinnedInitialState.(map[string]interface{})
+		if tab.Image != icon || initial["image"] != icon {
+			t.Errorf("%q: expected image and initial state image to be the favicon", tt.version)
+		}
+		if tab.ZenHasStaticIcon != tt.wantStatic || (tab.ZenPinnedIcon == icon) != tt.wantStatic {
+			t.Errorf("%q: expected static icon %v, got zenPinnedIcon=%v zenHasStaticIcon=%v", tt.version, tt.wantStatic, tab.ZenPinnedIcon, tab.ZenHasStaticIcon)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"arc-to-zen/paths"
+)
+
+const lockDirName = "locks"
+
+// staleAfter is how old a lock must be before it is broken when its owner
+// can't be checked (another host, or a platform without process lookup)
+const staleAfter = time.Hour
+
+// Owner describes the process holding a lock
+type Owner struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Profile  string    `json:"profile"`
+	Started  time.Time `json:"started"`
+}
+
+// LockedError is returned when another run holds the profile lock
+type LockedError struct {
+	Path  string
+	Owner Owner
+}
+
+func (e *LockedError) Error() string {
+	if e.Owner.PID == 0 {
+		return fmt.Sprintf("profile is in use by another arc-to-zen run; if no other run is active, delete %s", e.Path)
+	}
+	return fmt.Sprintf("profile is in use by another arc-to-zen run (pid %d on %s, started %s); if that run is gone, delete %s",
+		e.Owner.PID, e.Owner.Hostname, e.Owner.Started.Format("2006-01-02 15:04:05"), e.Path)
+}
+
+// Lock is a held lock on a Zen profile
+type Lock struct {
+	path string
+}
+
+// Acquire takes the lock for profilePath, breaking it first if its owner is gone.
+// Returns a *LockedError if another live run holds it.
+func Acquire(profilePath string) (*Lock, error) {
+	path, err := lockPath(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	owner := Owner{PID: os.Getpid(), Hostname: hostname, Profile: profilePath, Started: time.Now()}
+
+	// Second attempt only happens after a stale lock was removed
+	for attempt := 0; attempt < 2; attempt++ {
+		err := create(path, owner)
+		if err == nil {
+			return &Lock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", path, err)
+		}
+
+		existing, readErr := readOwner(path)
+		if readErr != nil {
+			// Half-written by a run that is starting right now, or corrupt; judge by age
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			existing = Owner{Started: info.ModTime()}
+		}
+		if !isStale(existing, hostname) {
+			return nil, &LockedError{Path: path, Owner: existing}
+		}
+		if err := breakLock(path, existing); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock %s: it keeps being recreated", path)
+}
+
+// Release removes the lock
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// lockPath returns the lock file for a profile. The hash keeps it unique per
+// profile while the base name keeps it recognizable.
+func lockPath(profilePath string) (string, error) {
+	dataDir, err := paths.DataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get data directory: %w", err)
+	}
+	abs, err := filepath.Abs(profilePath)
+	if err != nil {
+		abs = profilePath
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(abs)))
+	name := fmt.Sprintf("%s-%s.lock", filepath.Base(abs), hex.EncodeToString(sum[:8]))
+	return filepath.Join(dataDir, lockDirName, name), nil
+}
+
+// create writes a new lock file, failing if one already exists
+func create(path string, owner Owner) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(owner)
+}
+
+func readOwner(path string) (Owner, error) {
+	var owner Owner
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+	err = json.Unmarshal(data, &owner)
+	return owner, err
+}
+
+// isStale reports whether a lock's owner has exited
+func isStale(owner Owner, hostname string) bool {
+	if owner.PID > 0 && owner.Hostname == hostname {
+		if alive, known := processAlive(owner.PID); known {
+			return !alive
+		}
+	}
+	return time.Since(owner.Started) > staleAfter
+}
+
+// breakLock removes a stale lock. It is moved aside first and checked again so
+// a lock that another run just re-created isn't deleted by mistake.
+func breakLock(path string, stale Owner) error {
+	aside := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Someone else broke it
+		}
+		return fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+	}
+
+	current, err := readOwner(aside)
+	if err == nil && current != stale {
+		if restoreErr := os.Rename(aside, path); restoreErr != nil {
+			return fmt.Errorf("failed to restore lock %s: %w", path, restoreErr)
+		}
+		return &LockedError{Path: path, Owner: current}
+	}
+	if err := os.Remove(aside); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale lock %s: %w", aside, err)
+	}
+	return nil
+}
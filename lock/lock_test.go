@@ -0,0 +1,103 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"arc-to-zen/paths"
+)
+
+func TestAcquireSerializesRuns(t *testing.T) {
+	t.Setenv(paths.HomeEnv, t.TempDir())
+	profile := t.TempDir()
+
+	first, err := Acquire(profile)
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	var locked *LockedError
+	if _, err := Acquire(profile); !errors.As(err, &locked) {
+		t.Fatalf("expected LockedError while held, got %v", err)
+	}
+	if locked.Owner.PID != os.Getpid() {
+		t.Errorf("expected owner pid %d, got %d", os.Getpid(), locked.Owner.PID)
+	}
+
+	// Other profiles are unaffected
+	other, err := Acquire(t.TempDir())
+	if err != nil {
+		t.Fatalf("acquire of another profile failed: %v", err)
+	}
+	other.Release()
+
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Acquire(profile)
+	if err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	second.Release()
+}
+
+func TestAcquireBreaksStaleLock(t *testing.T) {
+	t.Setenv(paths.HomeEnv, t.TempDir())
+	profile := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name  string
+		owner Owner
+	}{
+		// A lock from another host can't be checked, so only age makes it stale
+		{"old lock from another host", Owner{PID: 1, Hostname: "elsewhere", Started: time.Now().Add(-2 * staleAfter)}},
+		{"dead process on this host", Owner{PID: deadPID(t), Hostname: hostname, Started: time.Now()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeLock(t, profile, tt.owner)
+			l, err := Acquire(profile)
+			if err != nil {
+				t.Fatalf("expected stale lock to be broken, got %v", err)
+			}
+			l.Release()
+		})
+	}
+
+	writeLock(t, profile, Owner{PID: 1, Hostname: "elsewhere", Started: time.Now()})
+	if _, err := Acquire(profile); err == nil {
+		t.Error("expected a fresh lock from another host to be respected")
+	}
+}
+
+func writeLock(t *testing.T, profile string, owner Owner) {
+	t.Helper()
+	path, err := lockPath(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(owner)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deadPID returns a pid that isn't running, or skips if none can be found
+func deadPID(t *testing.T) int {
+	t.Helper()
+	for pid := 999999; pid > 900000; pid-- {
+		if alive, known := processAlive(pid); known && !alive {
+			return pid
+		}
+	}
+	t.Skip("process lookup not supported")
+	return 0
+}
@@ -0,0 +1,12 @@
+//go:build !darwin && !linux
+
+package lock
+
+// ProcessCheck reports whether stale locks are recognized by their process
+// having exited, rather than only by age
+const ProcessCheck = false
+
+// processAlive can't check processes on this platform; locks expire by age instead
+func processAlive(pid int) (alive, known bool) {
+	return false, false
+}
@@ -0,0 +1,19 @@
+//go:build darwin || linux
+
+package lock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ProcessCheck reports whether stale locks are recognized by their process
+// having exited, rather than only by age
+const ProcessCheck = true
+
+// processAlive reports whether pid is running on this machine
+func processAlive(pid int) (alive, known bool) {
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but belongs to someone else
+	return err == nil || errors.Is(err, syscall.EPERM), true
+}
@@ -0,0 +1,174 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/types"
+)
+
+const sessionFileName = "zen-sessions.jsonlz4"
+
+// Source identifies the import an entry comes from
+type Source struct {
+	ImportID    string    `json:"importId"`
+	ToolVersion string    `json:"toolVersion"`
+	Time        time.Time `json:"time"`
+}
+
+func sourceOf(imp *Import) Source {
+	return Source{ImportID: imp.ID, ToolVersion: imp.ToolVersion, Time: imp.Time}
+}
+
+// SpaceRecord is one import's entry for a workspace
+type SpaceRecord struct {
+	Space
+	Source
+}
+
+// ContainerRecord is one import's entry for a container
+type ContainerRecord struct {
+	Container
+	Source
+}
+
+// WorkspaceOrigin is a workspace in the session and the imports that created
+// or merged into it, oldest first. No imports means it was made in Zen.
+type WorkspaceOrigin struct {
+	UUID        string        `json:"uuid"`
+	Name        string        `json:"name"`
+	ContainerID int           `json:"containerId"`
+	Imports     []SpaceRecord `json:"imports"`
+}
+
+// ContainerOrigin is a container in containers.json and the imports that
+// created or reused it, oldest first
+type ContainerOrigin struct {
+	UserContextID int               `json:"userContextId"`
+	Name          string            `json:"name"`
+	Imports       []ContainerRecord `json:"imports"`
+}
+
+// Audit maps a profile's current workspaces and containers back to the Arc
+// spaces and profiles they were imported from
+type Audit struct {
+	Workspaces []WorkspaceOrigin `json:"workspaces"`
+	Containers []ContainerOrigin `json:"containers"`
+	Removed    []SpaceRecord     `json:"removed"` // Imported workspaces no longer in the session
+}
+
+// SpaceHistory returns every import's entry for the workspace, oldest first
+func (m *Manifest) SpaceHistory(uuid string) []SpaceRecord {
+	var history []SpaceRecord
+	for i := range m.Imports {
+		for _, space := range m.Imports[i].Spaces {
+			if space.UUID == uuid {
+				history = append(history, SpaceRecord{Space: space, Source: sourceOf(&m.Imports[i])})
+			}
+		}
+	}
+	return history
+}
+
+// ContainerHistory returns every import's entry for the container, oldest first
+func (m *Manifest) ContainerHistory(userContextID int) []ContainerRecord {
+	var history []ContainerRecord
+	for i := range m.Imports {
+		for _, container := range m.Imports[i].Containers {
+			if container.UserContextID == userContextID {
+				history = append(history, ContainerRecord{Container: container, Source: sourceOf(&m.Imports[i])})
+			}
+		}
+	}
+	return history
+}
+
+// AuditSession matches session's workspaces and containersData's public
+// containers (nil if the profile has none) against the manifest
+func (m *Manifest) AuditSession(session *types.ZenSession, containersData *types.ContainersData) *Audit {
+	audit := &Audit{Workspaces: []WorkspaceOrigin{}, Containers: []ContainerOrigin{}, Removed: []SpaceRecord{}}
+	present := make(map[string]bool)
+	for _, space := range session.Spaces {
+		present[space.UUID] = true
+		audit.Workspaces = append(audit.Workspaces, WorkspaceOrigin{
+			UUID:        space.UUID,
+			Name:        space.Name,
+			ContainerID: space.ContainerTabID,
+			Imports:     m.SpaceHistory(space.UUID),
+		})
+	}
+	if containersData != nil {
+		for _, container := range containersData.Identities {
+			if !container.Public || !container.HasValidUserContextID() {
+				continue
+			}
+			name := container.Name
+			if name == "" {
+				name = container.L10nID
+			}
+			audit.Containers = append(audit.Containers, ContainerOrigin{
+				UserContextID: container.GetUserContextID(),
+				Name:          name,
+				Imports:       m.ContainerHistory(container.GetUserContextID()),
+			})
+		}
+	}
+
+	// Latest entry of each workspace that is gone, in import order
+	latest := make(map[string]int)
+	for i := range m.Imports {
+		for _, space := range m.Imports[i].Spaces {
+			if present[space.UUID] {
+				continue
+			}
+			record := SpaceRecord{Space: space, Source: sourceOf(&m.Imports[i])}
+			if at, ok := latest[space.UUID]; ok {
+				audit.Removed[at] = record
+				continue
+			}
+			latest[space.UUID] = len(audit.Removed)
+			audit.Removed = append(audit.Removed, record)
+		}
+	}
+	return audit
+}
+
+// AuditProfile reads the session, containers.json and manifest of the Zen
+// profile at profilePath and audits them
+func AuditProfile(profilePath string) (*Audit, *Manifest, error) {
+	m, err := Load(profilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessionPath := filepath.Join(profilePath, sessionFileName)
+	compressed, err := os.ReadFile(sessionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("zen-sessions.jsonlz4 not found at: %s", sessionPath)
+		}
+		return nil, nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decompress session: %w", err)
+	}
+	var session types.ZenSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+
+	// containers.json is optional: Zen only writes it once a container is edited
+	var containersData *types.ContainersData
+	if _, err := os.Stat(filepath.Join(profilePath, "containers.json")); err == nil {
+		if containersData, err = containers.Load(profilePath); err != nil {
+			return nil, nil, err
+		}
+	}
+	return m.AuditSession(&session, containersData), m, nil
+}
@@ -0,0 +1,55 @@
+package manifest
+
+import (
+	"testing"
+	"time"
+
+	"arc-to-zen/types"
+)
+
+func TestAuditSession(t *testing.T) {
+	first := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	m := &Manifest{Version: FormatVersion}
+	m.Add(Import{ID: "first", Time: first,
+		Spaces: []Space{
+			{UUID: "w1", Name: "Work", ArcID: "s1", ArcName: "Work", ArcProfile: "Profile 1", ContainerID: 3},
+			{UUID: "w2", Name: "Gone", ArcID: "s2", ArcProfile: "Profile 1", ContainerID: 3},
+		},
+		Containers: []Container{{UserContextID: 3, Name: "Profile 1", ArcProfile: "Profile 1", Created: true}}})
+	m.Add(Import{ID: "second", Time: first.Add(24 * time.Hour),
+		Spaces:     []Space{{UUID: "w1", Name: "Work", ArcID: "s1", ArcProfile: "Profile 1", ContainerID: 3, Merged: true}},
+		Containers: []Container{{UserContextID: 3, Name: "Profile 1", ArcProfile: "Profile 1"}}})
+
+	session := &types.ZenSession{Spaces: []types.ZenSpace{{UUID: "w1", Name: "Work", ContainerTabID: 3}, {UUID: "manual", Name: "Mine"}}}
+	one, three := 1, 3
+	containersData := &types.ContainersData{Identities: []types.ContainerIdentity{
+		{UserContextID: &one, L10nID: "user-context-personal", Public: true},
+		{UserContextID: &three, Name: "Profile 1", Public: true},
+	}}
+
+	audit := m.AuditSession(session, containersData)
+	if len(audit.Workspaces) != 2 {
+		t.Fatalf("expected 2 workspaces, got %+v", audit.Workspaces)
+	}
+	work, mine := audit.Workspaces[0], audit.Workspaces[1]
+	if len(work.Imports) != 2 || work.Imports[0].ImportID != "first" || work.Imports[0].Merged || !work.Imports[1].Merged {
+		t.Errorf("expected Work created then merged into, got %+v", work.Imports)
+	}
+	if work.Imports[0].ArcProfile != "Profile 1" || !work.Imports[0].Time.Equal(first) {
+		t.Errorf("expected the Arc profile and import time, got %+v", work.Imports[0])
+	}
+	if len(mine.Imports) != 0 {
+		t.Errorf("expected the manual workspace to have no imports, got %+v", mine.Imports)
+	}
+
+	if len(audit.Containers) != 2 || audit.Containers[0].Name != "user-context-personal" || len(audit.Containers[0].Imports) != 0 {
+		t.Errorf("expected the built-in container unattributed, got %+v", audit.Containers)
+	}
+	if imports := audit.Containers[1].Imports; len(imports) != 2 || !imports[0].Created || imports[1].Created {
+		t.Errorf("expected container 3 created then reused, got %+v", imports)
+	}
+
+	if len(audit.Removed) != 1 || audit.Removed[0].UUID != "w2" || audit.Removed[0].ImportID != "first" {
+		t.Errorf("expected the removed workspace reported, got %+v", audit.Removed)
+	}
+}
@@ -0,0 +1,152 @@
+// Package manifest records what arc-to-zen created in a Zen profile. Every
+// import appends an entry to arc-to-zen.json in the profile directory with
+// the tool version, an import ID and the IDs of the workspaces, containers,
+// folders and tabs it created. Those are the IDs Zen keeps when it rewrites
+// the session (workspace UUIDs, folder IDs, zenSyncId), so later commands can
+// tell the tool's objects from the user's without guessing by name.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileName is the manifest's name in the Zen profile directory
+const FileName = "arc-to-zen.json"
+
+// FormatVersion is bumped when the file layout changes incompatibly; it is
+// the newest format this version reads
+const FormatVersion = 1
+
+// ToolVersion is the arc-to-zen version recorded with each import, set at
+// build time with -ldflags "-X arc-to-zen/manifest.ToolVersion=..."
+var ToolVersion = "dev"
+
+// Manifest lists the imports into one profile, oldest first
+type Manifest struct {
+	Version int      `json:"version"`
+	Imports []Import `json:"imports"`
+}
+
+// Import is one import run that wrote the profile
+type Import struct {
+	ID          string      `json:"id"`
+	ToolVersion string      `json:"toolVersion"`
+	Time        time.Time   `json:"time"`
+	ArcData     string      `json:"arcData,omitempty"` // StorableSidebar.json imported
+	Spaces      []Space     `json:"spaces"`
+	Containers  []Container `json:"containers,omitempty"`
+	Folders     []Object    `json:"folders"`
+	Tabs        []Object    `json:"tabs"`
+}
+
+// Space is a workspace the import created, or merged its pins into
+type Space struct {
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	ArcID       string `json:"arcId,omitempty"`
+	ArcName     string `json:"arcName,omitempty"`
+	ArcProfile  string `json:"arcProfile,omitempty"`
+	ContainerID int    `json:"containerId"`
+	Merged      bool   `json:"merged,omitempty"` // Workspace existed; only its pins are the tool's
+}
+
+// Container is the container an Arc profile was imported into
+type Container struct {
+	UserContextID int    `json:"userContextId"`
+	Name          string `json:"name"`
+	ArcProfile    string `json:"arcProfile"`
+	Created       bool   `json:"created"` // False if an existing container was reused
+}
+
+// Object is a folder (by folder ID) or pinned tab (by zenSyncId) the import created
+type Object struct {
+	ID        string `json:"id"`
+	ArcID     string `json:"arcId,omitempty"`
+	SpaceUUID string `json:"spaceUuid"`
+}
+
+// NewImportID returns a fresh import ID
+func NewImportID() string {
+	return uuid.New().String()
+}
+
+// Version returns ToolVersion, or the module version Go recorded if the
+// binary was built without setting it (go install arc-to-zen/...@v1.2.3)
+func Version() string {
+	if ToolVersion != "dev" {
+		return ToolVersion
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ToolVersion
+}
+
+// Path returns the manifest path in a Zen profile
+func Path(profilePath string) string {
+	return filepath.Join(profilePath, FileName)
+}
+
+// Load reads the manifest of a Zen profile. A profile nothing was imported
+// into yet has an empty one.
+func Load(profilePath string) (*Manifest, error) {
+	data, err := os.ReadFile(Path(profilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{Version: FormatVersion}, nil
+		}
+		return nil, fmt.Errorf("failed to read import manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse import manifest %s: %w", Path(profilePath), err)
+	}
+	if m.Version > FormatVersion {
+		return nil, fmt.Errorf("import manifest %s is version %d; this arc-to-zen reads up to %d", Path(profilePath), m.Version, FormatVersion)
+	}
+	m.Version = FormatVersion
+	return &m, nil
+}
+
+// Add appends an import
+func (m *Manifest) Add(imp Import) {
+	m.Imports = append(m.Imports, imp)
+}
+
+// Encode returns the manifest file contents
+func (m *Manifest) Encode() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal import manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Owner returns the latest import that created the workspace, folder or tab
+// with the given ID, or nil if arc-to-zen didn't create it. A merged
+// workspace is not owned; its pins are.
+func (m *Manifest) Owner(id string) *Import {
+	for i := len(m.Imports) - 1; i >= 0; i-- {
+		imp := &m.Imports[i]
+		for _, space := range imp.Spaces {
+			if space.UUID == id && !space.Merged {
+				return imp
+			}
+		}
+		for _, objects := range [][]Object{imp.Folders, imp.Tabs} {
+			for _, object := range objects {
+				if object.ID == id {
+					return imp
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package manifest
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoad_MissingIsEmpty(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Version != FormatVersion || len(m.Imports) != 0 {
+		t.Errorf("expected an empty manifest, got %+v", m)
+	}
+}
+
+func TestLoad_RejectsNewerFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(Path(dir), []byte(`{"version": 99, "imports": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	m := &Manifest{Version: FormatVersion}
+	m.Add(Import{ID: "first", Spaces: []Space{{UUID: "w1"}}, Tabs: []Object{{ID: "t1", SpaceUUID: "w1"}}})
+	m.Add(Import{ID: "second", Spaces: []Space{{UUID: "w1", Merged: true}, {UUID: "w2"}},
+		Folders: []Object{{ID: "f1", SpaceUUID: "w1"}}, Tabs: []Object{{ID: "t2", SpaceUUID: "w1"}}})
+
+	// Round-trip through the file format
+	dir := t.TempDir()
+	data, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path(dir), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]string{"w1": "first", "w2": "second", "f1": "second", "t1": "first", "t2": "second", "manual": ""} {
+		got := ""
+		if owner := m.Owner(id); owner != nil {
+			got = owner.ID
+		}
+		if got != want {
+			t.Errorf("Owner(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
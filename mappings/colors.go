@@ -0,0 +1,102 @@
+package mappings
+
+import "math"
+
+// Colors less saturated than this, or nearly black or white, map to toolbar (gray)
+const (
+	minColorSaturation = 0.2
+	minColorLightness  = 0.12
+	maxColorLightness  = 0.92
+)
+
+// containerColorHues is the hue (degrees) each container color stands for.
+// These are the hues people call by the color's name rather than the hues
+// Firefox draws, which lean toward yellow for green and turquoise.
+var containerColorHues = map[string]float64{
+	"red":       0,
+	"orange":    30,
+	"yellow":    55,
+	"green":     120,
+	"turquoise": 172,
+	"blue":      215,
+	"purple":    272,
+	"pink":      318,
+}
+
+// NearestContainerColor returns the Firefox container color closest in hue to
+// an RGB color (0–255 components). Grays map to toolbar.
+func NearestContainerColor(r, g, b int) string {
+	hue, saturation, lightness := HSL(r, g, b)
+	if saturation < minColorSaturation || lightness < minColorLightness || lightness > maxColorLightness {
+		return "toolbar"
+	}
+
+	best, bestDistance := "toolbar", math.Inf(1)
+	for _, name := range containerColorNames {
+		containerHue, ok := containerColorHues[name]
+		if !ok {
+			continue
+		}
+		distance := math.Abs(hue - containerHue)
+		if distance > 180 {
+			distance = 360 - distance
+		}
+		if distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// HSL converts 0–255 RGB to hue (degrees), saturation and lightness (0–1)
+func HSL(r, g, b int) (hue, saturation, lightness float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	lightness = (max + min) / 2
+	delta := max - min
+	if delta == 0 {
+		return 0, 0, lightness
+	}
+	saturation = delta / (1 - math.Abs(2*lightness-1))
+	switch max {
+	case rf:
+		hue = math.Mod((gf-bf)/delta, 6)
+	case gf:
+		hue = (bf-rf)/delta + 2
+	default:
+		hue = (rf-gf)/delta + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return hue, saturation, lightness
+}
+
+// FromHSL converts hue (degrees), saturation and lightness (0–1) to 0–255 RGB
+func FromHSL(hue, saturation, lightness float64) (r, g, b int) {
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	h := math.Mod(math.Mod(hue, 360)+360, 360) / 60
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+	var rf, gf, bf float64
+	switch {
+	case h < 1:
+		rf, gf = chroma, x
+	case h < 2:
+		rf, gf = x, chroma
+	case h < 3:
+		gf, bf = chroma, x
+	case h < 4:
+		gf, bf = x, chroma
+	case h < 5:
+		rf, bf = x, chroma
+	default:
+		rf, bf = chroma, x
+	}
+	m := lightness - chroma/2
+	to255 := func(v float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, v+m)) * 255))
+	}
+	return to255(rf), to255(gf), to255(bf)
+}
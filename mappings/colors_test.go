@@ -0,0 +1,49 @@
+package mappings
+
+import "testing"
+
+func TestNearestContainerColor(t *testing.T) {
+	tests := []struct {
+		r, g, b int
+		want    string
+	}{
+		{0x37, 0xad, 0xff, "blue"},
+		{30, 60, 200, "blue"},
+		{20, 180, 150, "turquoise"},
+		{60, 170, 40, "green"},
+		{250, 210, 40, "yellow"},
+		{240, 140, 30, "orange"},
+		{220, 40, 40, "red"},
+		{230, 80, 200, "pink"},
+		{140, 70, 220, "purple"},
+		{128, 128, 128, "toolbar"},
+		{10, 10, 30, "toolbar"},
+		{250, 250, 255, "toolbar"},
+	}
+	for _, tt := range tests {
+		if got := NearestContainerColor(tt.r, tt.g, tt.b); got != tt.want {
+			t.Errorf("NearestContainerColor(%d, %d, %d) = %s, want %s", tt.r, tt.g, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNearestContainerColor_AlwaysValid(t *testing.T) {
+	for r := 0; r < 256; r += 15 {
+		for g := 0; g < 256; g += 15 {
+			for b := 0; b < 256; b += 15 {
+				if color := NearestContainerColor(r, g, b); !IsValidContainerColor(color) {
+					t.Fatalf("NearestContainerColor(%d, %d, %d) = %q is not a container color", r, g, b, color)
+				}
+			}
+		}
+	}
+}
+
+func TestFromHSL_RoundTrip(t *testing.T) {
+	for _, rgb := range [][3]int{{255, 0, 0}, {51, 179, 77}, {30, 90, 200}, {128, 128, 128}, {240, 200, 20}} {
+		h, s, l := HSL(rgb[0], rgb[1], rgb[2])
+		if r, g, b := FromHSL(h, s, l); r != rgb[0] || g != rgb[1] || b != rgb[2] {
+			t.Errorf("FromHSL(HSL(%v)) = %d,%d,%d", rgb, r, g, b)
+		}
+	}
+}
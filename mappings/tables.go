@@ -0,0 +1,200 @@
+package mappings
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"arc-to-zen/paths"
+)
+
+// userTablesFile is the optional per-user override of the mapping tables in the config dir
+const userTablesFile = "mappings.json"
+
+//go:embed mappings.json
+var defaultTablesJSON []byte
+
+// Tables are the Arc → Zen mapping tables. The defaults are embedded from
+// mappings.json; a user file in the same format adds or replaces entries.
+type Tables struct {
+	Defaults       TableDefaults     `json:"defaults"`
+	WorkspaceIcons map[string]string `json:"workspaceIcons"` // Arc icon → Zen workspace icon
+	ContainerIcons map[string]string `json:"containerIcons"` // Arc icon → Firefox container icon
+	Colors         map[string]string `json:"colors"`         // Arc color → Firefox container color
+}
+
+// TableDefaults are used for Arc values missing from the tables
+type TableDefaults struct {
+	WorkspaceIcon string `json:"workspaceIcon"`
+	ContainerIcon string `json:"containerIcon"`
+	Color         string `json:"color"`
+}
+
+// active holds the tables the Map functions use
+var active atomic.Pointer[Tables]
+
+func init() {
+	active.Store(DefaultTables())
+}
+
+func current() *Tables {
+	return active.Load()
+}
+
+// DefaultTables returns a copy of the built-in tables
+func DefaultTables() *Tables {
+	var tables Tables
+	if err := json.Unmarshal(defaultTablesJSON, &tables); err != nil {
+		panic(fmt.Sprintf("mappings: invalid embedded mappings.json: %v", err))
+	}
+	return &tables
+}
+
+// Current returns a copy of the tables in use
+func Current() *Tables {
+	return current().clone()
+}
+
+// Use makes tables the ones the Map functions use
+func Use(tables *Tables) {
+	active.Store(tables.clone())
+}
+
+// DefaultTablesPath returns where LoadTables looks by default
+func DefaultTablesPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, userTablesFile), nil
+}
+
+// LoadTables returns the built-in tables with the user file at path applied.
+// Entries in the file add to or replace the built-in ones, an empty value
+// removes one, and non-empty defaults replace the built-in defaults. A
+// missing file yields the built-in tables. The result is not validated.
+func LoadTables(path string) (*Tables, error) {
+	tables := DefaultTables()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return tables, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mappings: %w", err)
+	}
+
+	var overrides Tables
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse mappings %s: %w", path, err)
+	}
+	tables.apply(&overrides)
+	return tables, nil
+}
+
+// apply adds or replaces entries with those in overrides
+func (t *Tables) apply(overrides *Tables) {
+	if overrides.Defaults.WorkspaceIcon != "" {
+		t.Defaults.WorkspaceIcon = overrides.Defaults.WorkspaceIcon
+	}
+	if overrides.Defaults.ContainerIcon != "" {
+		t.Defaults.ContainerIcon = overrides.Defaults.ContainerIcon
+	}
+	if overrides.Defaults.Color != "" {
+		t.Defaults.Color = overrides.Defaults.Color
+	}
+	mergeTable(t.WorkspaceIcons, overrides.WorkspaceIcons)
+	mergeTable(t.ContainerIcons, overrides.ContainerIcons)
+	mergeTable(t.Colors, overrides.Colors)
+}
+
+func mergeTable(table, overrides map[string]string) {
+	for key, value := range overrides {
+		if value == "" {
+			delete(table, key)
+		} else {
+			table[key] = value
+		}
+	}
+}
+
+// Validate checks that every value is one Zen and Firefox can show: workspace
+// icons must be chrome:// or data: URLs or a short emoji, container icons and
+// colors must come from Firefox's fixed sets
+func (t *Tables) Validate() error {
+	var problems []error
+	check := func(table, key, value string, valid func(string) bool, want string) {
+		if !valid(value) {
+			problems = append(problems, fmt.Errorf("%s %q: invalid value %q (%s)", table, key, value, want))
+		}
+	}
+	containerIcons := "one of " + strings.Join(containerIconNames, ", ")
+	containerColors := "one of " + strings.Join(containerColorNames, ", ")
+
+	check("defaults", "workspaceIcon", t.Defaults.WorkspaceIcon, isWorkspaceIcon, "chrome:// or data: URL, or an emoji")
+	check("defaults", "containerIcon", t.Defaults.ContainerIcon, IsValidContainerIcon, containerIcons)
+	check("defaults", "color", t.Defaults.Color, IsValidContainerColor, containerColors)
+	for _, key := range sortedKeys(t.WorkspaceIcons) {
+		check("workspaceIcons", key, t.WorkspaceIcons[key], isWorkspaceIcon, "chrome:// or data: URL, or an emoji")
+	}
+	for _, key := range sortedKeys(t.ContainerIcons) {
+		check("containerIcons", key, t.ContainerIcons[key], IsValidContainerIcon, containerIcons)
+	}
+	for _, key := range sortedKeys(t.Colors) {
+		check("colors", key, t.Colors[key], IsValidContainerColor, containerColors)
+	}
+	return errors.Join(problems...)
+}
+
+// JSON returns the tables in the mappings.json format
+func (t *Tables) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (t *Tables) clone() *Tables {
+	return &Tables{
+		Defaults:       t.Defaults,
+		WorkspaceIcons: cloneTable(t.WorkspaceIcons),
+		ContainerIcons: cloneTable(t.ContainerIcons),
+		Colors:         cloneTable(t.Colors),
+	}
+}
+
+func cloneTable(table map[string]string) map[string]string {
+	clone := make(map[string]string, len(table))
+	for key, value := range table {
+		clone[key] = value
+	}
+	return clone
+}
+
+// isWorkspaceIcon reports whether Zen can show icon as a workspace icon
+func isWorkspaceIcon(icon string) bool {
+	if strings.HasPrefix(icon, "chrome://") || strings.HasPrefix(icon, "data:image/") {
+		return true
+	}
+	// Anything else is shown as text, so only an emoji (a few code points) fits
+	return icon != "" && utf8.RuneCountInString(icon) <= 8 && !strings.ContainsAny(icon, " \t\n/:")
+}
+
+func sortedKeys(table map[string]string) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package mappings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTables(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mappings.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultTablesAreValid(t *testing.T) {
+	tables := DefaultTables()
+	if err := tables.Validate(); err != nil {
+		t.Errorf("built-in mappings.json is invalid:\n%v", err)
+	}
+	if len(tables.WorkspaceIcons) == 0 || len(tables.ContainerIcons) == 0 || len(tables.Colors) == 0 {
+		t.Error("expected every built-in table to have entries")
+	}
+}
+
+func TestLoadTables_Overrides(t *testing.T) {
+	path := writeTables(t, `{
+		"defaults": {"color": "purple"},
+		"workspaceIcons": {"sparkles": "✨", "mail": "", "code": "chrome://browser/skin/zen-icons/selectable/terminal.svg"},
+		"colors": {"mauve": "pink"}
+	}`)
+	tables, err := LoadTables(path)
+	if err != nil {
+		t.Fatalf("LoadTables failed: %v", err)
+	}
+	if err := tables.Validate(); err != nil {
+		t.Errorf("unexpected validation errors: %v", err)
+	}
+
+	defaults := DefaultTables()
+	if tables.WorkspaceIcons["sparkles"] != "✨" || tables.Colors["mauve"] != "pink" {
+		t.Error("expected new entries to be added")
+	}
+	if _, ok := tables.WorkspaceIcons["mail"]; ok {
+		t.Error("expected an empty value to remove the entry")
+	}
+	if !strings.HasSuffix(tables.WorkspaceIcons["code"], "/terminal.svg") {
+		t.Errorf("expected code to be replaced, got %q", tables.WorkspaceIcons["code"])
+	}
+	if tables.Defaults.Color != "purple" || tables.Defaults.WorkspaceIcon != defaults.Defaults.WorkspaceIcon {
+		t.Errorf("expected only the color default to change, got %+v", tables.Defaults)
+	}
+	if len(tables.ContainerIcons) != len(defaults.ContainerIcons) {
+		t.Error("expected untouched tables to keep the built-in entries")
+	}
+}
+
+func TestLoadTables_MissingAndMalformed(t *testing.T) {
+	tables, err := LoadTables(filepath.Join(t.TempDir(), "absent.json"))
+	if err != nil || len(tables.Colors) != len(DefaultTables().Colors) {
+		t.Errorf("expected the built-in tables for a missing file, got %v", err)
+	}
+	if _, err := LoadTables(writeTables(t, `{"icons": {}}`)); err == nil {
+		t.Error("expected an error for an unknown table")
+	}
+	if _, err := LoadTables(writeTables(t, `{"colors": [`)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	tables := DefaultTables()
+	tables.Colors["cyan"] = "teal"
+	tables.ContainerIcons["paw"] = "dog"
+	tables.WorkspaceIcons["web"] = "https://example.com/icon.png"
+	err := tables.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{`colors "cyan"`, `containerIcons "paw"`, `workspaceIcons "web"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %s to be reported, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	defer Use(DefaultTables())
+
+	tables := DefaultTables()
+	tables.WorkspaceIcons["sparkles"] = "✨"
+	tables.Defaults.Color = "red"
+	Use(tables)
+	tables.WorkspaceIcons["sparkles"] = "changed after Use"
+
+	if got := MapArcIconToSvg("sparkles"); got != "✨" {
+		t.Errorf("expected the installed tables to be used, got %q", got)
+	}
+	if got := MapArcColorToZen("no-such-color"); got != "red" {
+		t.Errorf("expected the installed default color, got %q", got)
+	}
+}
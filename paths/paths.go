@@ -0,0 +1,101 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	appName = "arc-to-zen"
+	// HomeEnv overrides every location below with a single directory
+	HomeEnv = "ARC_TO_ZEN_HOME"
+	// legacyDirName is where versions before XDG support kept everything
+	legacyDirName = ".arc-to-zen"
+)
+
+// DataDir returns the directory for persistent data such as session backups
+func DataDir() (string, error) {
+	return resolve("XDG_DATA_HOME", func(home string) string {
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "Application Support")
+		case "windows":
+			return windowsDir("LOCALAPPDATA", home, "AppData", "Local")
+		default:
+			return filepath.Join(home, ".local", "share")
+		}
+	})
+}
+
+// CacheDir returns the directory for disposable data such as the favicon cache
+func CacheDir() (string, error) {
+	return resolve("XDG_CACHE_HOME", func(home string) string {
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "Application Support")
+		case "windows":
+			return windowsDir("LOCALAPPDATA", home, "AppData", "Local")
+		default:
+			return filepath.Join(home, ".cache")
+		}
+	})
+}
+
+// ConfigDir returns the directory for user configuration files
+func ConfigDir() (string, error) {
+	return resolve("XDG_CONFIG_HOME", func(home string) string {
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "Application Support")
+		case "windows":
+			return windowsDir("APPDATA", home, "AppData", "Roaming")
+		default:
+			return filepath.Join(home, ".config")
+		}
+	})
+}
+
+// LogDir returns the directory for log files
+func LogDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs"), nil
+}
+
+// resolve picks a location in order of precedence: ARC_TO_ZEN_HOME, the XDG
+// variable, an existing legacy ~/.arc-to-zen directory, then the platform default
+func resolve(xdgEnv string, platformBase func(home string) string) (string, error) {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		return dir, nil
+	}
+
+	// XDG paths must be absolute; relative values are ignored per the spec
+	if dir := os.Getenv(xdgEnv); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+
+	// Keep using the old location so existing caches and backups aren't orphaned
+	legacyDir := filepath.Join(home, legacyDirName)
+	if info, err := os.Stat(legacyDir); err == nil && info.IsDir() {
+		return legacyDir, nil
+	}
+
+	return filepath.Join(platformBase(home), appName), nil
+}
+
+// windowsDir returns the directory in env, or a path under home if it's unset
+func windowsDir(env, home string, fallback ...string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
@@ -0,0 +1,74 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHomeOverride(t *testing.T) {
+	override := t.TempDir()
+	t.Setenv(HomeEnv, override)
+	t.Setenv("XDG_CACHE_HOME", "/should/not/be/used")
+
+	for name, fn := range map[string]func() (string, error){
+		"data": DataDir, "cache": CacheDir, "config": ConfigDir,
+	} {
+		dir, err := fn()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if dir != override {
+			t.Errorf("%s: expected %s, got %s", name, override, dir)
+		}
+	}
+}
+
+func TestXDGVariables(t *testing.T) {
+	t.Setenv(HomeEnv, "")
+	t.Setenv("HOME", t.TempDir())
+	xdg := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", xdg)
+	t.Setenv("XDG_CONFIG_HOME", "relative/ignored")
+
+	dir, err := CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(xdg, appName); dir != want {
+		t.Errorf("expected %s, got %s", want, dir)
+	}
+
+	// Relative XDG paths fall through to the platform default
+	dir, err = ConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dir) != appName || !filepath.IsAbs(dir) {
+		t.Errorf("expected absolute platform default, got %s", dir)
+	}
+}
+
+func TestLegacyDirectoryIsKept(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("HOME is not used for the home directory on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv(HomeEnv, "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", home)
+
+	legacy := filepath.Join(home, legacyDirName)
+	if err := os.Mkdir(legacy, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != legacy {
+		t.Errorf("expected legacy dir %s, got %s", legacy, dir)
+	}
+}
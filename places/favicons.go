@@ -0,0 +1,217 @@
+package places
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FaviconsFileName is the favicon database in the profile directory
+const FaviconsFileName = "favicons.sqlite"
+
+// fakeIconScheme prefixes the icon URL of icons stored without one, as
+// Firefox does for the icons of imported bookmarks. Ours are named by the
+// hash of the image (sharedIconURL), so pages with identical icons share one
+// moz_icons row.
+const fakeIconScheme = "fake-favicon-uri:"
+
+// vectorWidth is moz_icons.width of an SVG icon
+const vectorWidth = 65535
+
+// faviconLifetime is how long Zen uses a stored icon before refreshing it
+// from the site, as for icons it fetches itself
+const faviconLifetime = 7 * 24 * time.Hour
+
+// FaviconsPath returns the favicon database of the profile at profilePath
+func FaviconsPath(profilePath string) string {
+	return filepath.Join(profilePath, FaviconsFileName)
+}
+
+// PageIconURL is the URL Zen loads the icon favicons.sqlite has for pageURL
+// from, usable as a tab's image in the session
+func PageIconURL(pageURL string) string {
+	return "page-icon:" + pageURL
+}
+
+// Favicon is the icon of a page
+type Favicon struct {
+	PageURL     string
+	Data        []byte // PNG or SVG
+	ContentType string
+	Width       int // Pixels; ignored for SVG
+}
+
+// sharedIconURL is the moz_icons icon URL of an icon stored without one
+func sharedIconURL(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fakeIconScheme + "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// SharedBytes returns how many bytes of icons WriteFavicons doesn't write
+// because another page has an identical icon
+func SharedBytes(icons []Favicon) int64 {
+	seen := make(map[string]bool)
+	var shared int64
+	for _, icon := range icons {
+		if icon.PageURL == "" || len(icon.Data) == 0 {
+			continue
+		}
+		if key := string(icon.Data); seen[key] {
+			shared += int64(len(icon.Data))
+		} else {
+			seen[key] = true
+		}
+	}
+	return shared
+}
+
+// WriteFavicons stores icons in the favicon database at path, each linked
+// to its page, replacing the icons stored for the page without a URL (by an
+// earlier WriteFavicons, or Firefox's bookmark import). Identical icons are
+// stored once and linked to each of their pages; icons no page links to any
+// more are removed. Icons Zen fetched itself are left alone. Returns how many
+// pages got an icon. The database must exist (Zen creates it on first start)
+// and Zen must be closed.
+func WriteFavicons(ctx context.Context, path string, icons []Favicon) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("no favicon database: %w", err)
+	}
+	db, tx, err := begin(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	defer tx.Rollback()
+
+	columns, err := tableColumns(ctx, tx, "moz_icons_to_pages")
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("not a favicon database (no moz_icons_to_pages table)")
+	}
+	expires := time.Now().Add(faviconLifetime).UnixMilli()
+	linkQuery := "INSERT OR REPLACE INTO moz_icons_to_pages (page_id, icon_id) VALUES (?, ?)"
+	if columns["expire_ms"] {
+		linkQuery = "INSERT OR REPLACE INTO moz_icons_to_pages (page_id, icon_id, expire_ms) VALUES (?, ?, ?)"
+	}
+
+	iconIDs := make(map[string]int64) // By icon URL, so each image is stored once
+	written := 0
+	for _, icon := range icons {
+		if icon.PageURL == "" || len(icon.Data) == 0 {
+			continue
+		}
+		pageID, err := iconPage(ctx, tx, icon.PageURL)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM moz_icons_to_pages WHERE page_id = ? AND icon_id IN
+			(SELECT id FROM moz_icons WHERE icon_url LIKE ?)`, pageID, fakeIconScheme+"%"); err != nil {
+			return 0, err
+		}
+
+		iconURL := sharedIconURL(icon.Data)
+		iconID, ok := iconIDs[iconURL]
+		if !ok {
+			if iconID, err = storeIcon(ctx, tx, iconURL, icon, expires); err != nil {
+				return 0, fmt.Errorf("failed to add the icon of %s: %w", icon.PageURL, err)
+			}
+			iconIDs[iconURL] = iconID
+		}
+		args := []interface{}{pageID, iconID}
+		if columns["expire_ms"] {
+			args = append(args, expires)
+		}
+		if _, err := tx.ExecContext(ctx, linkQuery, args...); err != nil {
+			return 0, err
+		}
+		written++
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM moz_icons WHERE icon_url LIKE ?
+		AND id NOT IN (SELECT icon_id FROM moz_icons_to_pages)`, fakeIconScheme+"%"); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return written, nil
+}
+
+// storeIcon returns the id of the moz_icons row named iconURL, replacing its
+// image and expiry, or adds one
+func storeIcon(ctx context.Context, tx *sql.Tx, iconURL string, icon Favicon, expires int64) (int64, error) {
+	width := icon.Width
+	if strings.HasPrefix(icon.ContentType, "image/svg") {
+		width = vectorWidth
+	}
+	hash := URLHash(fixupURL(iconURL))
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM moz_icons WHERE fixed_icon_url_hash = ? AND icon_url = ?", hash, iconURL).Scan(&id)
+	switch {
+	case err == nil:
+		_, err = tx.ExecContext(ctx, "UPDATE moz_icons SET width = ?, expire_ms = ?, data = ? WHERE id = ?", width, expires, icon.Data, id)
+		return id, err
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO moz_icons (icon_url, fixed_icon_url_hash, width, root, expire_ms, data) VALUES (?, ?, ?, 0, ?, ?)",
+		iconURL, hash, width, expires, icon.Data)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// iconPage returns the moz_pages_w_icons id of pageURL, adding it if needed
+func iconPage(ctx context.Context, tx *sql.Tx, pageURL string) (int64, error) {
+	hash := URLHash(pageURL)
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM moz_pages_w_icons WHERE page_url_hash = ? AND page_url = ?", hash, pageURL).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO moz_pages_w_icons (page_url, page_url_hash) VALUES (?, ?)", pageURL, hash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add %s: %w", pageURL, err)
+	}
+	return res.LastInsertId()
+}
+
+// tableColumns returns the columns of table, none if it doesn't exist
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// fixupURL is Firefox's fixup_url() SQL function, which moz_icons hashes
+// icon URLs through: the URL without an http, https or ftp scheme and a
+// leading "www."
+func fixupURL(rawURL string) string {
+	for _, scheme := range []string{"http://", "https://", "ftp://"} {
+		if strings.HasPrefix(rawURL, scheme) {
+			rawURL = rawURL[len(scheme):]
+			break
+		}
+	}
+	return strings.TrimPrefix(rawURL, "www.")
+}
@@ -0,0 +1,93 @@
+package places
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// faviconsSchema is Firefox's favicons.sqlite
+const faviconsSchema = `
+CREATE TABLE moz_icons (id INTEGER PRIMARY KEY, icon_url TEXT NOT NULL, fixed_icon_url_hash INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0, root INTEGER NOT NULL DEFAULT 0, color INTEGER, expire_ms INTEGER NOT NULL DEFAULT 0,
+	flags INTEGER NOT NULL DEFAULT 0, data BLOB);
+CREATE TABLE moz_pages_w_icons (id INTEGER PRIMARY KEY, page_url TEXT NOT NULL, page_url_hash INTEGER NOT NULL);
+CREATE TABLE moz_icons_to_pages (page_id INTEGER NOT NULL, icon_id INTEGER NOT NULL, expire_ms INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (page_id, icon_id)) WITHOUT ROWID;
+INSERT INTO moz_icons (id, icon_url, fixed_icon_url_hash, width, data) VALUES (1, 'https://docs.example.test/favicon.ico', 0, 16, x'00');
+INSERT INTO moz_pages_w_icons (id, page_url, page_url_hash) VALUES (1, 'https://docs.example.test/', 0);
+INSERT INTO moz_icons_to_pages (page_id, icon_id) VALUES (1, 1);
+`
+
+func TestWriteFavicons(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FaviconsFileName)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(faviconsSchema); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("UPDATE moz_pages_w_icons SET page_url_hash = ?", URLHash("https://docs.example.test/"))
+
+	icons := []Favicon{
+		{PageURL: "https://docs.example.test/", Data: []byte("\x89PNG docs"), ContentType: "image/png", Width: 32},
+		{PageURL: "https://www.example.test/app", Data: []byte("<old/>"), ContentType: "image/svg+xml"},
+		{PageURL: "https://docs.example.test/guide", Data: []byte("\x89PNG docs"), ContentType: "image/png", Width: 32},
+		{PageURL: "https://empty.example.test/"},
+	}
+	if shared := SharedBytes(icons); shared != int64(len("\x89PNG docs")) {
+		t.Errorf("expected the second docs icon counted as shared, got %d bytes", shared)
+	}
+	for run := 0; run < 2; run++ {
+		if run == 1 {
+			icons[1].Data = []byte("<svg/>")
+		}
+		written, err := WriteFavicons(context.Background(), path, icons)
+		if err != nil || written != 3 {
+			t.Fatalf("run %d: expected 3 icons written, got %d (%v)", run, written, err)
+		}
+	}
+
+	// Writing again replaced the icons, removing the one no page uses any
+	// more; the docs pages share one row, and Zen's own icon is kept
+	var iconCount, pages, links int
+	db.QueryRow("SELECT COUNT(*) FROM moz_icons").Scan(&iconCount)
+	db.QueryRow("SELECT COUNT(*) FROM moz_pages_w_icons").Scan(&pages)
+	db.QueryRow("SELECT COUNT(*) FROM moz_icons_to_pages").Scan(&links)
+	if iconCount != 3 || pages != 3 || links != 4 {
+		t.Errorf("expected 3 icons, 3 pages and 4 links, got %d, %d and %d", iconCount, pages, links)
+	}
+
+	var width int
+	var hash int64
+	var data []byte
+	err = db.QueryRow(`SELECT i.width, i.fixed_icon_url_hash, i.data FROM moz_icons i
+		JOIN moz_icons_to_pages l ON l.icon_id = i.id JOIN moz_pages_w_icons p ON p.id = l.page_id
+		WHERE p.page_url = ? AND p.page_url_hash = ?`, "https://www.example.test/app", URLHash("https://www.example.test/app")).Scan(&width, &hash, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != vectorWidth || string(data) != "<svg/>" || hash != URLHash(sharedIconURL([]byte("<svg/>"))) {
+		t.Errorf("unexpected SVG icon: width %d, hash %d, data %q", width, hash, data)
+	}
+
+	if _, err := WriteFavicons(context.Background(), filepath.Join(t.TempDir(), FaviconsFileName), icons); err == nil {
+		t.Error("expected an error for a missing database")
+	}
+}
+
+func TestFixupURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.example.test/favicon.ico": "example.test/favicon.ico",
+		"http://example.test/":                 "example.test/",
+		"ftp://www.files.test/":                "files.test/",
+		"fake-favicon-uri:https://a.test/":     "fake-favicon-uri:https://a.test/",
+	} {
+		if got := fixupURL(in); got != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package places
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// maxHashedLength is how much of a URL Firefox hashes
+const maxHashedLength = 1500
+
+const goldenRatio = 0x9E3779B9
+
+// hashString is mozilla::HashString over bytes
+func hashString(s string) uint32 {
+	var hash uint32
+	for i := 0; i < len(s); i++ {
+		hash = goldenRatio * (bits.RotateLeft32(hash, 5) ^ uint32(s[i]))
+	}
+	return hash
+}
+
+// URLHash is moz_places.url_hash, Firefox's hash() SQL function: the hash of
+// the scheme in the upper 16 bits above the hash of the URL (its first 1500
+// bytes). Places are looked up by it, so it must match what Zen computes.
+func URLHash(rawURL string) int64 {
+	hashed := rawURL
+	if len(hashed) > maxHashedLength {
+		hashed = hashed[:maxHashedLength]
+	}
+	scheme, _, found := strings.Cut(rawURL, ":")
+	if !found {
+		return int64(hashString(hashed))
+	}
+	return int64(hashString(scheme)&0xFFFF)<<32 + int64(hashString(hashed))
+}
@@ -0,0 +1,378 @@
+// Package places writes bookmarks into a Firefox/Zen places.sqlite, the
+// database behind the bookmarks manager. Everything happens in one
+// transaction, and imports go into a single folder under Other Bookmarks
+// that importing again replaces.
+package places
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"arc-to-zen/bookmarks"
+
+	_ "modernc.org/sqlite" // database/sql driver "sqlite"
+)
+
+// FileName is the database in the profile directory
+const FileName = "places.sqlite"
+
+// ImportFolderGUID is the GUID of the folder imported bookmarks go into. A
+// fixed GUID lets the next import find and replace it.
+const ImportFolderGUID = "arctozen____"
+
+// unfiledGUID is the root of Other Bookmarks
+const unfiledGUID = "unfiled_____"
+
+// Bookmark types in moz_bookmarks
+const (
+	typeBookmark = 1
+	typeFolder   = 2
+)
+
+// Sync statuses in moz_bookmarks: NEW items were never synced; NORMAL ones
+// need a tombstone in moz_bookmarks_deleted when removed
+const (
+	syncStatusNew    = 1
+	syncStatusNormal = 2
+)
+
+// Path returns the places database of the profile at profilePath
+func Path(profilePath string) string {
+	return filepath.Join(profilePath, FileName)
+}
+
+// Result is what Write changed
+type Result struct {
+	Folders   int `json:"folders"`   // Including the import folder
+	Bookmarks int `json:"bookmarks"` // Links added
+	Removed   int `json:"removed"`   // Folders and links of the previous import removed
+}
+
+// Write puts items into the import folder of the places database at path,
+// titled title, replacing what an earlier import left there. Links without
+// a URL are left out. The database must exist (Zen creates it on first
+// start) and Zen must be closed.
+func Write(ctx context.Context, path, title string, items []bookmarks.Bookmark) (*Result, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no bookmarks database: %w", err)
+	}
+	db, tx, err := begin(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	defer tx.Rollback()
+
+	w := &writer{ctx: ctx, tx: tx, now: time.Now().UnixMicro(), result: &Result{}}
+	if err := w.readSchema(); err != nil {
+		return nil, err
+	}
+	folderID, err := w.importFolder(title)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.insertAll(folderID, items); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.result, nil
+}
+
+// begin opens the database at path and starts a transaction that holds its
+// write lock
+func begin(ctx context.Context, path string) (*sql.DB, *sql.Tx, error) {
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?_txlock=immediate&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to lock %s (is Zen running?): %w", path, err)
+	}
+	return db, tx, nil
+}
+
+// writer holds one Write's transaction
+type writer struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	now    int64 // PRTime: microseconds since the Unix epoch
+	result *Result
+
+	placesColumns map[string]bool
+	hasOrigins    bool
+	hasTombstones bool
+	countsForeign bool // The database keeps foreign_count with its own triggers
+}
+
+func (w *writer) exec(query string, args ...interface{}) (sql.Result, error) {
+	return w.tx.ExecContext(w.ctx, query, args...)
+}
+
+func (w *writer) queryInt(query string, args ...interface{}) (int64, error) {
+	var n int64
+	err := w.tx.QueryRowContext(w.ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// readSchema checks the database is a places database and notes the optional
+// tables and columns of the Firefox version that made it
+func (w *writer) readSchema() error {
+	tables := make(map[string]bool)
+	rows, err := w.tx.QueryContext(w.ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return fmt.Errorf("failed to read the bookmarks database: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables[name] = true
+	}
+	rows.Close()
+	if !tables["moz_places"] || !tables["moz_bookmarks"] {
+		return errors.New("not a bookmarks database (no moz_places or moz_bookmarks table)")
+	}
+	w.hasOrigins = tables["moz_origins"]
+	w.hasTombstones = tables["moz_bookmarks_deleted"]
+	// Firefox keeps foreign_count with temporary triggers, so normally it's
+	// up to us; a database with permanent ones does it itself
+	triggers, err := w.queryInt("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'moz_bookmarks' AND sql LIKE '%foreign_count%'")
+	if err != nil {
+		return err
+	}
+	w.countsForeign = triggers > 0
+
+	w.placesColumns = make(map[string]bool)
+	rows, err = w.tx.QueryContext(w.ctx, "SELECT name FROM pragma_table_info('moz_places')")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		w.placesColumns[name] = true
+	}
+	return rows.Err()
+}
+
+// importFolder returns the import folder emptied, or creates it at the end
+// of Other Bookmarks
+func (w *writer) importFolder(title string) (int64, error) {
+	w.result.Folders++
+	id, err := w.queryInt("SELECT id FROM moz_bookmarks WHERE guid = ?", ImportFolderGUID)
+	if err == nil {
+		if err := w.removeChildren(id); err != nil {
+			return 0, err
+		}
+		_, err := w.exec("UPDATE moz_bookmarks SET title = ?, lastModified = ?, syncChangeCounter = syncChangeCounter + 1 WHERE id = ?", title, w.now, id)
+		return id, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	unfiled, err := w.queryInt("SELECT id FROM moz_bookmarks WHERE guid = ?", unfiledGUID)
+	if err != nil {
+		return 0, fmt.Errorf("no Other Bookmarks folder in the bookmarks database: %w", err)
+	}
+	return w.insertBookmark(unfiled, typeFolder, nil, title, ImportFolderGUID)
+}
+
+// removeChildren deletes everything under folder id, with tombstones for
+// what was synced and the place references dropped
+func (w *writer) removeChildren(id int64) error {
+	rows, err := w.tx.QueryContext(w.ctx, `
+		WITH RECURSIVE descendants(id) AS (
+			SELECT id FROM moz_bookmarks WHERE parent = ?
+			UNION ALL
+			SELECT b.id FROM moz_bookmarks b JOIN descendants d ON b.parent = d.id
+		)
+		SELECT b.id, b.fk, b.guid, b.syncStatus FROM moz_bookmarks b JOIN descendants d ON b.id = d.id`, id)
+	if err != nil {
+		return err
+	}
+	type removed struct {
+		id         int64
+		fk         sql.NullInt64
+		guid       string
+		syncStatus int
+	}
+	var items []removed
+	for rows.Next() {
+		var item removed
+		if err := rows.Scan(&item.id, &item.fk, &item.guid, &item.syncStatus); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if _, err := w.exec("DELETE FROM moz_bookmarks WHERE id = ?", item.id); err != nil {
+			return err
+		}
+		if item.fk.Valid && !w.countsForeign {
+			if _, err := w.exec("UPDATE moz_places SET foreign_count = foreign_count - 1 WHERE id = ? AND foreign_count > 0", item.fk.Int64); err != nil {
+				return err
+			}
+		}
+		if w.hasTombstones && item.syncStatus == syncStatusNormal {
+			if _, err := w.exec("INSERT OR REPLACE INTO moz_bookmarks_deleted (guid, dateRemoved) VALUES (?, ?)", item.guid, w.now); err != nil {
+				return err
+			}
+		}
+	}
+	w.result.Removed = len(items)
+	return nil
+}
+
+// insertAll adds items to the end of folder parent, depth first
+func (w *writer) insertAll(parent int64, items []bookmarks.Bookmark) error {
+	for _, item := range items {
+		if item.Folder {
+			id, err := w.insertBookmark(parent, typeFolder, nil, item.Title, "")
+			if err != nil {
+				return err
+			}
+			w.result.Folders++
+			if err := w.insertAll(id, item.Children); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.TrimSpace(item.URL) == "" {
+			continue
+		}
+		place, err := w.place(item.URL, item.Title)
+		if err != nil {
+			return err
+		}
+		if _, err := w.insertBookmark(parent, typeBookmark, place, item.Title, ""); err != nil {
+			return err
+		}
+		w.result.Bookmarks++
+	}
+	return nil
+}
+
+// insertBookmark adds a bookmark or folder at the end of parent and returns
+// its id. place is the moz_places id of a bookmark; guid is generated if
+// empty.
+func (w *writer) insertBookmark(parent int64, kind int, place interface{}, title, guid string) (int64, error) {
+	if guid == "" {
+		guid = newGUID()
+	}
+	position, err := w.queryInt("SELECT COUNT(*) FROM moz_bookmarks WHERE parent = ?", parent)
+	if err != nil {
+		return 0, err
+	}
+	res, err := w.exec(`INSERT INTO moz_bookmarks (type, fk, parent, position, title, dateAdded, lastModified, guid, syncStatus, syncChangeCounter)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`, kind, place, parent, position, title, w.now, w.now, guid, syncStatusNew)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add bookmark %q: %w", title, err)
+	}
+	if _, err := w.exec("UPDATE moz_bookmarks SET lastModified = ? WHERE id = ?", w.now, parent); err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// place returns the moz_places id of rawURL, adding the page if it's new,
+// and counts the bookmark about to reference it in foreign_count
+func (w *writer) place(rawURL, title string) (int64, error) {
+	hash := URLHash(rawURL)
+	id, err := w.queryInt("SELECT id FROM moz_places WHERE url_hash = ? AND url = ?", hash, rawURL)
+	if err == nil {
+		if !w.countsForeign {
+			_, err = w.exec("UPDATE moz_places SET foreign_count = foreign_count + 1 WHERE id = ?", id)
+		}
+		return id, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	columns := []string{"url", "title", "rev_host", "hidden", "typed", "guid", "url_hash"}
+	values := []interface{}{rawURL, title, revHost(rawURL), 0, 0, newGUID(), hash}
+	if !w.countsForeign {
+		columns = append(columns, "foreign_count")
+		values = append(values, 1)
+	}
+	if w.placesColumns["recalc_frecency"] {
+		columns = append(columns, "recalc_frecency") // frecency stays at its default until Zen computes it
+		values = append(values, 1)
+	}
+	if w.hasOrigins && w.placesColumns["origin_id"] {
+		if origin, err := w.origin(rawURL); err != nil {
+			return 0, err
+		} else if origin != 0 {
+			columns = append(columns, "origin_id")
+			values = append(values, origin)
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO moz_places (%s) VALUES (?%s)", strings.Join(columns, ", "), strings.Repeat(", ?", len(columns)-1))
+	res, err := w.exec(query, values...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add %s: %w", rawURL, err)
+	}
+	return res.LastInsertId()
+}
+
+// origin returns the moz_origins id of rawURL's prefix and host, adding it
+// if needed, or 0 for URLs without a host
+func (w *writer) origin(rawURL string) (int64, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return 0, nil
+	}
+	prefix := strings.ToLower(u.Scheme) + "://"
+	host := strings.ToLower(u.Host)
+	if _, err := w.exec("INSERT OR IGNORE INTO moz_origins (prefix, host, frecency) VALUES (?, ?, 0)", prefix, host); err != nil {
+		return 0, err
+	}
+	return w.queryInt("SELECT id FROM moz_origins WHERE prefix = ? AND host = ?", prefix, host)
+}
+
+// revHost is moz_places.rev_host: the host lowercased and reversed, with a
+// trailing dot ("." for URLs without a host)
+func revHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "."
+	}
+	host := []rune(strings.ToLower(u.Hostname()))
+	for i, j := 0, len(host)-1; i < j; i, j = i+1, j-1 {
+		host[i], host[j] = host[j], host[i]
+	}
+	return string(host) + "."
+}
+
+// newGUID returns a places GUID: 12 URL-safe base64 characters
+func newGUID() string {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,142 @@
+package places
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"arc-to-zen/bookmarks"
+)
+
+// schema is the part of Firefox's places.sqlite Write touches
+const schema = `
+CREATE TABLE moz_origins (id INTEGER PRIMARY KEY, prefix TEXT NOT NULL, host TEXT NOT NULL, frecency INTEGER NOT NULL, UNIQUE (prefix, host));
+CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0,
+	hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER,
+	guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL, origin_id INTEGER REFERENCES moz_origins(id),
+	recalc_frecency INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR,
+	keyword_id INTEGER, folder_type TEXT, dateAdded INTEGER, lastModified INTEGER, guid TEXT, syncStatus INTEGER NOT NULL DEFAULT 0,
+	syncChangeCounter INTEGER NOT NULL DEFAULT 1);
+CREATE TABLE moz_bookmarks_deleted (guid TEXT PRIMARY KEY, dateRemoved INTEGER NOT NULL DEFAULT 0);
+INSERT INTO moz_bookmarks (id, type, parent, position, title, guid) VALUES
+	(1, 2, 0, 0, '', 'root________'), (2, 2, 1, 0, 'menu', 'menu________'), (3, 2, 1, 1, 'toolbar', 'toolbar_____'),
+	(4, 2, 1, 2, 'tags', 'tags________'), (5, 2, 1, 3, 'unfiled', 'unfiled_____');
+INSERT INTO moz_bookmarks (type, parent, position, title, guid) VALUES (2, 5, 0, 'Mine', 'mine________');
+`
+
+func newTestDatabase(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	return path, db
+}
+
+func TestWrite(t *testing.T) {
+	path, db := newTestDatabase(t)
+	items := []bookmarks.Bookmark{
+		{Title: "Work", Folder: true, Children: []bookmarks.Bookmark{
+			{Title: "Docs", URL: "https://docs.example.test/"},
+			{Title: "Specs", Folder: true, Children: []bookmarks.Bookmark{{Title: "Spec", URL: "https://docs.example.test/spec"}}},
+			{Title: "No URL"},
+		}},
+		{Title: "Docs again", URL: "https://docs.example.test/"},
+	}
+	result, err := Write(context.Background(), path, "Arc", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (Result{Folders: 3, Bookmarks: 3}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The import folder goes after the user's folder, the tree keeps its order
+	var folderID, position int64
+	if err := db.QueryRow("SELECT id, position FROM moz_bookmarks WHERE guid = ? AND parent = 5", ImportFolderGUID).Scan(&folderID, &position); err != nil || position != 1 {
+		t.Fatalf("expected the import folder second in Other Bookmarks, got position %d, %v", position, err)
+	}
+	titles := func(parent int64) []string {
+		rows, err := db.Query("SELECT title FROM moz_bookmarks WHERE parent = ? ORDER BY position", parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var titles []string
+		for rows.Next() {
+			var title string
+			rows.Scan(&title)
+			titles = append(titles, title)
+		}
+		return titles
+	}
+	if got := titles(folderID); len(got) != 2 || got[0] != "Work" || got[1] != "Docs again" {
+		t.Errorf("unexpected import folder contents %v", got)
+	}
+
+	// One place per URL, counting its bookmarks, hashed and with an origin
+	var places, foreign, hash, origin int64
+	var revHost string
+	db.QueryRow("SELECT COUNT(*) FROM moz_places").Scan(&places)
+	db.QueryRow("SELECT foreign_count, url_hash, rev_host, origin_id FROM moz_places WHERE url = ?", "https://docs.example.test/").Scan(&foreign, &hash, &revHost, &origin)
+	if places != 2 || foreign != 2 || hash != URLHash("https://docs.example.test/") || revHost != "tset.elpmaxe.scod." || origin == 0 {
+		t.Errorf("unexpected places: %d places, foreign_count %d, url_hash %d, rev_host %q, origin %d", places, foreign, hash, revHost, origin)
+	}
+
+	// Importing again replaces the folder's contents, tombstoning synced items
+	db.Exec("UPDATE moz_bookmarks SET syncStatus = ? WHERE title = 'Docs again'", syncStatusNormal)
+	result, err = Write(context.Background(), path, "Arc", items[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 5 || result.Bookmarks != 1 {
+		t.Errorf("expected 5 items replaced by 1, got %+v", result)
+	}
+	var folders, tombstones int64
+	db.QueryRow("SELECT COUNT(*) FROM moz_bookmarks WHERE guid = ?", ImportFolderGUID).Scan(&folders)
+	db.QueryRow("SELECT COUNT(*) FROM moz_bookmarks_deleted").Scan(&tombstones)
+	db.QueryRow("SELECT foreign_count FROM moz_places WHERE url = ?", "https://docs.example.test/").Scan(&foreign)
+	if folders != 1 || tombstones != 1 || foreign != 1 {
+		t.Errorf("expected one import folder, one tombstone and foreign_count 1, got %d, %d, %d", folders, tombstones, foreign)
+	}
+	if got := titles(5); len(got) != 2 || got[0] != "Mine" {
+		t.Errorf("expected the user's folder left alone, got %v", got)
+	}
+}
+
+func TestWrite_NotAPlacesDatabase(t *testing.T) {
+	if _, err := Write(context.Background(), filepath.Join(t.TempDir(), FileName), "Arc", nil); err == nil {
+		t.Error("expected a missing database to be an error")
+	}
+	path := filepath.Join(t.TempDir(), "other.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("CREATE TABLE other (id INTEGER)")
+	db.Close()
+	if _, err := Write(context.Background(), path, "Arc", nil); err == nil {
+		t.Error("expected a database without moz_bookmarks to be rejected")
+	}
+}
+
+func TestURLHash(t *testing.T) {
+	// The scheme's hash is in the upper bits, so URLs of a scheme share them
+	a, b := URLHash("https://a.example.test/"), URLHash("https://b.example.test/")
+	if a>>32 != b>>32 || a == b || a>>32 == 0 {
+		t.Errorf("expected the same scheme bits and different URL bits, got %x and %x", a, b)
+	}
+	if URLHash("https://a.example.test/")>>32 == URLHash("http://a.example.test/")>>32 {
+		t.Error("expected different schemes to hash differently")
+	}
+	if got := URLHash("noscheme"); got>>32 != 0 {
+		t.Errorf("expected a URL without a scheme to have no scheme bits, got %x", got)
+	}
+}
@@ -0,0 +1,203 @@
+// Package principal picks the triggeringPrincipal stored with each restored tab.
+// Firefox checks it when session restore loads the URL: a system principal may
+// load anything, a content principal only what its origin may load, and a null
+// principal nothing privileged. Giving every tab the system principal makes
+// javascript: pins run with chrome privileges and breaks extension pages, so
+// the kind is chosen from the URL scheme.
+package principal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Kind is the type of principal a tab is restored with
+type Kind string
+
+const (
+	System  Kind = "system"  // Browser itself; what Zen stores for pinned tabs
+	Content Kind = "content" // The URL's own origin
+	Null    Kind = "null"    // Opaque origin with no privileges
+)
+
+// Serialized principal types, as keyed in Firefox's principal JSON
+const (
+	jsonContent = "1"
+	jsonNull    = "2"
+	jsonSystem  = "3"
+)
+
+// SystemBase64 is the system principal, {"3":{}}
+const SystemBase64 = "eyIzIjp7fX0="
+
+// DefaultKinds maps URL schemes to the principal their tabs are restored with.
+// Schemes not listed get Null.
+var DefaultKinds = map[string]Kind{
+	"http":          System,
+	"https":         System,
+	"ftp":           System,
+	"about":         System,
+	"file":          System, // Content principals can't load file:// at the top level
+	"data":          System, // Top-level data: loads are blocked for anything else
+	"view-source":   System,
+	"chrome":        System,
+	"resource":      System,
+	"moz-extension": Content,
+}
+
+// Policy chooses principals by URL scheme. A nil Policy uses DefaultKinds.
+type Policy struct {
+	kinds map[string]Kind   // Scheme → kind
+	raw   map[string]string // Scheme → base64 principal given verbatim
+}
+
+// NewPolicy returns DefaultKinds with overrides applied. Each override maps a
+// scheme ("file", "moz-extension") to "system", "content", "null" or a base64
+// serialized principal copied from a Firefox session.
+func NewPolicy(overrides map[string]string) (*Policy, error) {
+	p := &Policy{kinds: make(map[string]Kind), raw: make(map[string]string)}
+	for scheme, kind := range DefaultKinds {
+		p.kinds[scheme] = kind
+	}
+
+	schemes := make([]string, 0, len(overrides))
+	for scheme := range overrides {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	for _, scheme := range schemes {
+		value := strings.TrimSpace(overrides[scheme])
+		key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scheme), ":"))
+		if key == "" {
+			return nil, fmt.Errorf("principal override %q has no scheme", scheme+"="+value)
+		}
+		switch Kind(strings.ToLower(value)) {
+		case System, Content, Null:
+			p.kinds[key] = Kind(strings.ToLower(value))
+			delete(p.raw, key)
+			continue
+		}
+		if err := validateBase64(value); err != nil {
+			return nil, fmt.Errorf("principal override for %s: %w", key, err)
+		}
+		p.raw[key] = value
+	}
+	return p, nil
+}
+
+// KindFor returns the kind used for rawURL, or "" if an override gives the
+// principal verbatim
+func (p *Policy) KindFor(rawURL string) Kind {
+	scheme := schemeOf(rawURL)
+	if p == nil {
+		if kind, ok := DefaultKinds[scheme]; ok {
+			return kind
+		}
+		return Null
+	}
+	if _, ok := p.raw[scheme]; ok {
+		return ""
+	}
+	if kind, ok := p.kinds[scheme]; ok {
+		return kind
+	}
+	return Null
+}
+
+// ForURL returns the base64 triggeringPrincipal for a tab loading rawURL in
+// container userContextID (0 for none)
+func (p *Policy) ForURL(rawURL string, userContextID int) string {
+	if p != nil {
+		if raw, ok := p.raw[schemeOf(rawURL)]; ok {
+			return raw
+		}
+	}
+	switch p.KindFor(rawURL) {
+	case System:
+		return SystemBase64
+	case Content:
+		if origin := originOf(rawURL); origin != "" {
+			fields := map[string]string{"0": origin}
+			if userContextID > 0 {
+				fields["2"] = fmt.Sprintf("^userContextId=%d", userContextID)
+			}
+			return encode(jsonContent, fields)
+		}
+	}
+	return encode(jsonNull, map[string]string{"0": "moz-nullprincipal:{" + uuid.New().String() + "}"})
+}
+
+// WithUserContextID returns the base64 principal value moved to container
+// userContextID (0 for none). Only content principals carry a container;
+// other values, and values that don't decode, are returned unchanged.
+func WithUserContextID(value string, userContextID int) string {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	var parsed map[string]map[string]string
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return value
+	}
+	fields, ok := parsed[jsonContent]
+	if !ok || len(parsed) != 1 {
+		return value
+	}
+	// Field 2 is the origin attribute suffix, "^key=value&key=value"
+	var attrs []string
+	for _, attr := range strings.Split(strings.TrimPrefix(fields["2"], "^"), "&") {
+		if attr != "" && !strings.HasPrefix(attr, "userContextId=") {
+			attrs = append(attrs, attr)
+		}
+	}
+	if userContextID > 0 {
+		attrs = append([]string{fmt.Sprintf("userContextId=%d", userContextID)}, attrs...)
+	}
+	delete(fields, "2")
+	if len(attrs) > 0 {
+		fields["2"] = "^" + strings.Join(attrs, "&")
+	}
+	return encode(jsonContent, fields)
+}
+
+func encode(kind string, fields map[string]string) string {
+	data, _ := json.Marshal(map[string]map[string]string{kind: fields})
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// validateBase64 checks that value decodes to a single serialized principal
+func validateBase64(value string) error {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("expected system, content, null or base64: %w", err)
+	}
+	var principal map[string]json.RawMessage
+	if err := json.Unmarshal(data, &principal); err != nil || len(principal) != 1 {
+		return fmt.Errorf("%q is not a serialized principal", value)
+	}
+	return nil
+}
+
+// schemeOf returns the lowercased scheme of rawURL ("" if it has none)
+func schemeOf(rawURL string) string {
+	scheme, _, ok := strings.Cut(strings.TrimSpace(rawURL), ":")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// originOf returns scheme://host/ for rawURL, or "" if it has no host
+func originOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme) + "://" + u.Host + "/"
+}
@@ -0,0 +1,107 @@
+package principal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+// decode returns the principal type and fields of a base64 principal
+func decode(t *testing.T, value string) (string, map[string]string) {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("invalid base64 %q: %v", value, err)
+	}
+	var principal map[string]map[string]string
+	if err := json.Unmarshal(data, &principal); err != nil {
+		t.Fatalf("invalid principal %s: %v", data, err)
+	}
+	for kind, fields := range principal {
+		return kind, fields
+	}
+	t.Fatalf("empty principal %s", data)
+	return "", nil
+}
+
+func TestForURL_Defaults(t *testing.T) {
+	var p *Policy
+	tests := map[string]string{
+		"https://example.com/page":       jsonSystem,
+		"HTTP://example.com/":            jsonSystem,
+		"file:///Users/me/notes.html":    jsonSystem,
+		"about:blank":                    jsonSystem,
+		"moz-extension://abc-123/p.html": jsonContent,
+		"javascript:alert(1)":            jsonNull,
+		"chrome-extension://xyz/p.html":  jsonNull,
+		"arc://settings":                 jsonNull,
+		"":                               jsonNull,
+	}
+	for url, want := range tests {
+		if kind, _ := decode(t, p.ForURL(url, 0)); kind != want {
+			t.Errorf("%q: expected principal type %s, got %s", url, want, kind)
+		}
+	}
+	if got := p.ForURL("https://example.com/", 0); got != SystemBase64 {
+		t.Errorf("expected %s for https, got %s", SystemBase64, got)
+	}
+}
+
+func TestForURL_ContentPrincipalOrigin(t *testing.T) {
+	var p *Policy
+	_, fields := decode(t, p.ForURL("moz-extension://abc-123/options.html?x=1", 3))
+	if fields["0"] != "moz-extension://abc-123/" || fields["2"] != "^userContextId=3" {
+		t.Errorf("unexpected content principal %v", fields)
+	}
+	_, fields = decode(t, p.ForURL("moz-extension://abc-123/options.html", 0))
+	if _, ok := fields["2"]; ok {
+		t.Errorf("expected no origin suffix without a container, got %v", fields)
+	}
+}
+
+func TestWithUserContextID(t *testing.T) {
+	var p *Policy
+	moved := WithUserContextID(p.ForURL("moz-extension://abc-123/options.html", 3), 7)
+	if _, fields := decode(t, moved); fields["0"] != "moz-extension://abc-123/" || fields["2"] != "^userContextId=7" {
+		t.Errorf("unexpected moved principal %v", fields)
+	}
+	_, fields := decode(t, WithUserContextID(encode(jsonContent, map[string]string{"0": "https://a.test/", "2": "^userContextId=3&privateBrowsingId=1"}), 0))
+	if fields["2"] != "^privateBrowsingId=1" {
+		t.Errorf("expected other origin attributes kept, got %v", fields)
+	}
+	if got := WithUserContextID(SystemBase64, 7); got != SystemBase64 {
+		t.Errorf("expected system principal unchanged, got %s", got)
+	}
+}
+
+func TestNewPolicy_Overrides(t *testing.T) {
+	raw := base64.StdEncoding.EncodeToString([]byte(`{"1":{"0":"https://intranet.test/"}}`))
+	p, err := NewPolicy(map[string]string{"file:": "null", "HTTPS": "Content", "http": raw})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	if kind, _ := decode(t, p.ForURL("file:///tmp/a.html", 0)); kind != jsonNull {
+		t.Errorf("expected file override to null, got %s", kind)
+	}
+	if kind, fields := decode(t, p.ForURL("https://example.com/a", 0)); kind != jsonContent || fields["0"] != "https://example.com/" {
+		t.Errorf("expected https content principal, got %s %v", kind, fields)
+	}
+	if got := p.ForURL("http://example.com/", 0); got != raw {
+		t.Errorf("expected verbatim principal for http, got %s", got)
+	}
+	if p.KindFor("http://example.com/") != "" || p.KindFor("about:blank") != System {
+		t.Error("unexpected KindFor result")
+	}
+}
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	for _, overrides := range []map[string]string{
+		{"file": "root"},
+		{"file": base64.StdEncoding.EncodeToString([]byte(`[1]`))},
+		{"": "null"},
+	} {
+		if _, err := NewPolicy(overrides); err == nil {
+			t.Errorf("expected an error for %v", overrides)
+		}
+	}
+}
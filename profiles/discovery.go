@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
 // Profile represents a Zen browser profile
 type Profile struct {
-	Name    string
-	Path    string
-	Default bool
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Default bool   `json:"default"`
+	Channel string `json:"channel,omitempty"` // "twilight", "flatpak", "portable"...; "" for release
+	// HasSession is false for a profile Zen hasn't written a session to yet;
+	// the importer creates one
+	HasSession bool `json:"hasSession"`
 }
 
 // ProfilesIni represents the profiles.ini structure
@@ -38,75 +43,172 @@ type GeneralSection struct {
 	Version              int
 }
 
-// DiscoverProfiles finds all Zen browser profiles on the system
-func DiscoverProfiles() ([]Profile, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("could not determine home directory: %w", err)
+// RootEnv lists extra Zen data directories to search, separated like PATH,
+// for portable installs and other locations discovery doesn't know. Each
+// entry is a data root (with profiles.ini or Profiles/) or a profile directory.
+const RootEnv = "ARC_TO_ZEN_ZEN_ROOT"
+
+// ProfileDirEnv overrides discovery: when set, only this Zen data directory
+// (or profile directory) is searched, not the platform's usual locations
+const ProfileDirEnv = "ZEN_PROFILE_DIR"
+
+// sessionFileName is the session Zen writes once the profile has been used
+const sessionFileName = "zen-sessions.jsonlz4"
+
+// isProfileDir reports whether dir is a Zen profile. Zen writes prefs.js and
+// compatibility.ini on first start; the session comes later, so a fresh
+// profile may not have one.
+func isProfileDir(dir string) bool {
+	for _, name := range []string{prefsFileName, "compatibility.ini"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
 	}
+	return false
+}
 
-	// Zen profiles are stored at ~/Library/Application Support/zen/Profiles/
-	zenDir := filepath.Join(homeDir, "Library", "Application Support", "zen")
-	
-	// Check if Zen directory exists
-	if _, err := os.Stat(zenDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("Zen browser directory not found at: %s (is Zen installed?)", zenDir)
+// dataRoot is a directory Zen keeps profiles.ini and the profiles in
+type dataRoot struct {
+	Dir     string
+	Channel string // "" for the release channel
+}
+
+// dataRoots returns where Zen keeps profiles on goos, directories from
+// RootEnv first. Release comes before Twilight so its default profile wins.
+// appData is %APPDATA% on Windows (AppData\Roaming in homeDir if empty).
+func dataRoots(homeDir, goos, extra, appData string) []dataRoot {
+	var roots []dataRoot
+	for _, dir := range filepath.SplitList(extra) {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		if expanded, err := expandHome(dir); err == nil {
+			dir = expanded
+		}
+		roots = append(roots, dataRoot{Dir: dir, Channel: "portable"})
 	}
 
-	profilesDir := filepath.Join(zenDir, "Profiles")
-	
-	// Check if Profiles directory exists
-	if _, err := os.Stat(profilesDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("Zen profiles directory not found at: %s", profilesDir)
+	switch goos {
+	case "darwin":
+		support := filepath.Join(homeDir, "Library", "Application Support")
+		roots = append(roots,
+			dataRoot{Dir: filepath.Join(support, "zen")},
+			dataRoot{Dir: filepath.Join(support, "zen-twilight"), Channel: "twilight"},
+			dataRoot{Dir: filepath.Join(support, "Zen Twilight"), Channel: "twilight"},
+		)
+	case "windows":
+		if appData == "" {
+			appData = filepath.Join(homeDir, "AppData", "Roaming")
+		}
+		roots = append(roots,
+			dataRoot{Dir: filepath.Join(appData, "zen")},
+			dataRoot{Dir: filepath.Join(appData, "zen-twilight"), Channel: "twilight"},
+		)
+	default:
+		flatpak := filepath.Join(homeDir, ".var", "app", "app.zen_browser.zen")
+		roots = append(roots,
+			dataRoot{Dir: filepath.Join(homeDir, ".zen")},
+			dataRoot{Dir: filepath.Join(homeDir, ".zen-twilight"), Channel: "twilight"},
+			dataRoot{Dir: filepath.Join(flatpak, ".zen"), Channel: "flatpak"},
+			dataRoot{Dir: filepath.Join(flatpak, ".zen-twilight"), Channel: "flatpak twilight"},
+		)
+	}
+	return roots
+}
+
+// DiscoverProfiles finds all Zen browser profiles on the system, across the
+// release and Twilight channels and any directories listed in RootEnv, or
+// only those in ProfileDirEnv if it is set
+func DiscoverProfiles() ([]Profile, error) {
+	if dir := strings.TrimSpace(os.Getenv(ProfileDirEnv)); dir != "" {
+		if expanded, err := expandHome(dir); err == nil {
+			dir = expanded
+		}
+		profiles := discoverIn([]dataRoot{{Dir: dir}})
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("no Zen profiles found in %s (set by %s)", dir, ProfileDirEnv)
+		}
+		return profiles, nil
 	}
 
-	// Read all profile directories
-	entries, err := os.ReadDir(profilesDir)
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("could not read profiles directory: %w", err)
+		return nil, fmt.Errorf("could not determine home directory: %w", err)
 	}
 
+	roots := dataRoots(homeDir, runtime.GOOS, os.Getenv(RootEnv), os.Getenv("APPDATA"))
+	profiles := discoverIn(roots)
+	if len(profiles) == 0 {
+		var searched []string
+		for _, root := range roots {
+			searched = append(searched, root.Dir)
+		}
+		return nil, fmt.Errorf("no Zen profiles found in: %s (is Zen installed? set %s for a portable install)", strings.Join(searched, ", "), RootEnv)
+	}
+	return profiles, nil
+}
+
+// discoverIn collects the profiles in roots: those directly in a root
+// or its Profiles directory, and a root that is itself a profile. A
+// directory reached through two roots is listed once.
+func discoverIn(roots []dataRoot) []Profile {
 	var profiles []Profile
-	var defaultProfileName string
+	seen := make(map[string]bool)
+	add := func(profilePath, dirName string, root dataRoot, defaultProfileName string) {
+		if !isProfileDir(profilePath) {
+			return
+		}
+		key := profilePath
+		if resolved, err := filepath.EvalSymlinks(profilePath); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
 
-	// Try to read profiles.ini to determine default profile
-	profilesIni := filepath.Join(zenDir, "profiles.ini")
-	if data, err := os.ReadFile(profilesIni); err == nil {
-		defaultProfileName = parseDefaultProfile(string(data))
+		// Extract profile name (remove hash prefix if present)
+		name := dirName
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) == 2 {
+			name = parts[1]
+		}
+		profiles = append(profiles, Profile{
+			Name:    name,
+			Path:    profilePath,
+			Default: dirName == defaultProfileName || strings.Contains(strings.ToLower(name), "default"),
+			Channel: root.Channel,
+		})
+		if _, err := os.Stat(filepath.Join(profilePath, sessionFileName)); err == nil {
+			profiles[len(profiles)-1].HasSession = true
+		}
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, root := range roots {
+		if info, err := os.Stat(root.Dir); err != nil || !info.IsDir() {
 			continue
 		}
 
-		profilePath := filepath.Join(profilesDir, entry.Name())
-		
-		// Verify it's a valid profile by checking for key files
-		sessionFile := filepath.Join(profilePath, "zen-sessions.jsonlz4")
-		if _, err := os.Stat(sessionFile); err == nil {
-			// Extract profile name (remove hash prefix if present)
-			name := entry.Name()
-			parts := strings.SplitN(name, ".", 2)
-			if len(parts) == 2 {
-				name = parts[1]
-			}
-
-			isDefault := (entry.Name() == defaultProfileName) || strings.Contains(strings.ToLower(name), "default")
-			
-			profiles = append(profiles, Profile{
-				Name:    name,
-				Path:    profilePath,
-				Default: isDefault,
-			})
+		// profiles.ini names the default profile of this install
+		var defaultProfileName string
+		if data, err := os.ReadFile(filepath.Join(root.Dir, "profiles.ini")); err == nil {
+			defaultProfileName = parseDefaultProfile(string(data))
 		}
-	}
 
-	if len(profiles) == 0 {
-		return nil, fmt.Errorf("no valid Zen profiles found in: %s", profilesDir)
+		add(root.Dir, filepath.Base(root.Dir), root, "")
+		for _, dir := range []string{filepath.Join(root.Dir, "Profiles"), root.Dir} {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					add(filepath.Join(dir, entry.Name()), entry.Name(), root, defaultProfileName)
+				}
+			}
+		}
 	}
-
-	return profiles, nil
+	return profiles
 }
 
 // GetDefaultProfile returns the default Zen profile
@@ -191,8 +293,15 @@ func ListProfiles(profiles []Profile) string {
 		if profile.Default {
 			defaultMarker = " (default)"
 		}
-		sb.WriteString(fmt.Sprintf("  %d. %s%s\n", i+1, profile.Name, defaultMarker))
+		channel := ""
+		if profile.Channel != "" {
+			channel = fmt.Sprintf(" [%s]", profile.Channel)
+		}
+		sb.WriteString(fmt.Sprintf("  %d. %s%s%s\n", i+1, profile.Name, channel, defaultMarker))
 		sb.WriteString(fmt.Sprintf("     Path: %s\n", profile.Path))
+		if !profile.HasSession {
+			sb.WriteString("     No session yet; the import will create one\n")
+		}
 		sb.WriteString("\n")
 	}
 
@@ -0,0 +1,152 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeUsedProfile(t *testing.T, dir string) {
+	t.Helper()
+	makeProfile(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, sessionFileName), []byte("mozLz40\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDataRoots(t *testing.T) {
+	home := "/home/u"
+	roots := dataRoots(home, "linux", "/opt/zen/data"+string(filepath.ListSeparator)+" ", "")
+	if len(roots) == 0 || roots[0].Dir != "/opt/zen/data" || roots[0].Channel != "portable" {
+		t.Fatalf("expected the portable root first, got %+v", roots)
+	}
+	want := map[string]string{
+		filepath.Join(home, ".zen"):                                       "",
+		filepath.Join(home, ".zen-twilight"):                              "twilight",
+		filepath.Join(home, ".var/app/app.zen_browser.zen/.zen"):          "flatpak",
+		filepath.Join(home, ".var/app/app.zen_browser.zen/.zen-twilight"): "flatpak twilight",
+	}
+	for _, root := range roots[1:] {
+		channel, ok := want[root.Dir]
+		if !ok || channel != root.Channel {
+			t.Errorf("unexpected root %+v", root)
+		}
+		delete(want, root.Dir)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing roots: %v", want)
+	}
+
+	roots = dataRoots(home, "darwin", "", "")
+	if roots[0].Dir != filepath.Join(home, "Library", "Application Support", "zen") || roots[0].Channel != "" {
+		t.Errorf("expected the release root first on macOS, got %+v", roots[0])
+	}
+
+	roots = dataRoots(home, "windows", "", "")
+	if roots[0].Dir != filepath.Join(home, "AppData", "Roaming", "zen") {
+		t.Errorf("expected the roaming profile folder on Windows, got %+v", roots[0])
+	}
+	roots = dataRoots(home, "windows", "", "/d/Roaming")
+	if roots[0].Dir != filepath.Join("/d/Roaming", "zen") || roots[1].Channel != "twilight" {
+		t.Errorf("expected %%APPDATA%% to be used, got %+v", roots)
+	}
+}
+
+func TestDiscoverIn(t *testing.T) {
+	base := t.TempDir()
+	release := filepath.Join(base, "zen")
+	twilight := filepath.Join(base, "zen-twilight")
+	portable := filepath.Join(base, "portable", "profile")
+
+	makeUsedProfile(t, filepath.Join(release, "Profiles", "ab12.Work"))
+	makeUsedProfile(t, filepath.Join(release, "Profiles", "cd34.Personal"))
+	// Never opened with workspaces: prefs.js but no session
+	makeProfile(t, filepath.Join(release, "Profiles", "ef56.Fresh"))
+	if err := os.MkdirAll(filepath.Join(release, "Crash Reports"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(release, "profiles.ini"), []byte("[Install1]\nDefault=Profiles/cd34.Personal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Linux installs keep profiles directly in the data root
+	makeUsedProfile(t, filepath.Join(twilight, "gh78.Twilight"))
+	makeUsedProfile(t, portable)
+
+	profiles := discoverIn([]dataRoot{
+		{Dir: portable, Channel: "portable"},
+		{Dir: release},
+		{Dir: filepath.Join(base, "missing"), Channel: "twilight"},
+		{Dir: twilight, Channel: "twilight"},
+		{Dir: release, Channel: "twilight"}, // Same directory twice
+	})
+
+	got := make(map[string]Profile)
+	for _, profile := range profiles {
+		got[profile.Name] = profile
+	}
+	if len(profiles) != 5 || len(got) != 5 {
+		t.Fatalf("expected 5 profiles, got %+v", profiles)
+	}
+	if p := got["Fresh"]; p.HasSession || !got["Work"].HasSession {
+		t.Errorf("expected only Fresh without a session, got %+v", profiles)
+	}
+	if p := got["profile"]; p.Path != portable || p.Channel != "portable" {
+		t.Errorf("expected the portable profile directory itself, got %+v", p)
+	}
+	if p := got["Personal"]; !p.Default || p.Channel != "" {
+		t.Errorf("expected Personal as the release default, got %+v", p)
+	}
+	if p := got["Work"]; p.Default {
+		t.Errorf("expected Work not default, got %+v", p)
+	}
+	if p := got["Twilight"]; p.Channel != "twilight" {
+		t.Errorf("expected a twilight profile, got %+v", p)
+	}
+	if listing := ListProfiles(profiles); !strings.Contains(listing, "Twilight [twilight]") || strings.Count(listing, "No session yet") != 1 {
+		t.Errorf("expected the channel in the listing:\n%s", ListProfiles(profiles))
+	}
+}
+
+func TestDiscoverProfiles_RootEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(RootEnv, "")
+	t.Setenv(ProfileDirEnv, "")
+	if _, err := DiscoverProfiles(); err == nil || !strings.Contains(err.Error(), RootEnv) {
+		t.Errorf("expected an error mentioning %s, got %v", RootEnv, err)
+	}
+
+	root := t.TempDir()
+	makeUsedProfile(t, filepath.Join(root, "Profiles", "ab12.Portable"))
+	t.Setenv(RootEnv, root)
+	profiles, err := DiscoverProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != "Portable" || profiles[0].Channel != "portable" {
+		t.Errorf("expected the profile under %s, got %+v", RootEnv, profiles)
+	}
+}
+
+func TestDiscoverProfiles_ProfileDirEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	other := t.TempDir()
+	makeUsedProfile(t, filepath.Join(other, "Profiles", "ab12.Elsewhere"))
+	t.Setenv(RootEnv, other)
+
+	override := t.TempDir()
+	makeUsedProfile(t, filepath.Join(override, "Profiles", "cd34.Chosen"))
+	t.Setenv(ProfileDirEnv, override)
+	profiles, err := DiscoverProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != "Chosen" {
+		t.Errorf("expected only the profile under %s, got %+v", ProfileDirEnv, profiles)
+	}
+
+	t.Setenv(ProfileDirEnv, t.TempDir())
+	if _, err := DiscoverProfiles(); err == nil || !strings.Contains(err.Error(), ProfileDirEnv) {
+		t.Errorf("expected an error mentioning %s, got %v", ProfileDirEnv, err)
+	}
+}
@@ -0,0 +1,53 @@
+package profiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefsFileName is written into every Firefox and Zen profile on first start
+const prefsFileName = "prefs.js"
+
+// ResolvePath turns a profile path given on the command line into an
+// absolute one. A leading ~ is expanded, since shells leave it alone inside
+// quotes, and relative paths are resolved from the working directory. It
+// fails unless the directory looks like a Zen profile (has prefs.js).
+func ResolvePath(path string) (string, error) {
+	expanded, err := expandHome(strings.TrimSpace(path))
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(expanded)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve profile path %q: %w", path, err)
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("profile directory not found: %s", abs)
+		}
+		return "", fmt.Errorf("could not read profile directory %s: %w", abs, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("profile path is not a directory: %s", abs)
+	}
+	if _, err := os.Stat(filepath.Join(abs, prefsFileName)); err != nil {
+		return "", fmt.Errorf("%s does not look like a Zen profile (no %s); use -list to see your profiles", abs, prefsFileName)
+	}
+	return abs, nil
+}
+
+// expandHome replaces a leading "~" or "~/" with the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not expand ~ in %q: %w", path, err)
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
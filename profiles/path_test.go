@@ -0,0 +1,77 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeProfile(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, prefsFileName), []byte("// prefs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	profile := filepath.Join(home, "Library", "Application Support", "zen", "Profiles", "ab12.Default")
+	makeProfile(t, profile)
+
+	// Relative paths are resolved from the working directory
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(filepath.Dir(profile)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{
+		"~/Library/Application Support/zen/Profiles/ab12.Default",
+		"  ~/Library/Application Support/zen/Profiles/ab12.Default/ ",
+		"ab12.Default",
+		"./ab12.Default",
+		profile,
+	} {
+		got, err := ResolvePath(input)
+		if err != nil {
+			t.Errorf("ResolvePath(%q) failed: %v", input, err)
+			continue
+		}
+		// The temp dir may be reached through a symlink (macOS /var → /private/var)
+		if gotInfo, _ := os.Stat(got); !filepath.IsAbs(got) || !os.SameFile(gotInfo, mustStat(t, profile)) {
+			t.Errorf("ResolvePath(%q) = %q, want %q", input, got, profile)
+		}
+	}
+}
+
+func TestResolvePath_Errors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for input, want := range map[string]string{
+		filepath.Join(dir, "missing"): "not found",
+		file:                          "not a directory",
+		dir:                           "no prefs.js",
+	} {
+		if _, err := ResolvePath(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolvePath(%q): expected error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
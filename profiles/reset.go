@@ -15,6 +15,7 @@ func ResetProfile(profilePath string, dryRun bool) error {
 		"sessionstore.jsonlz4",
 		"sessionstore-backups",
 		"containers.json",
+		"arc-to-zen.json", // Import manifest; describes the session being removed
 	}
 
 	if dryRun {
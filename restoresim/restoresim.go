@@ -0,0 +1,268 @@
+// Package restoresim replays a Zen session through a model of Zen's session
+// restore, reporting pinned folders and tabs that Zen would drop, flatten or
+// reorder. The model covers the rules the importer depends on: tab-groups only
+// exist for folders with a tab bound to them, empty tabs without a groupId are
+// filtered out, emptyTabIds must name the folder's placeholder tabs, and nested
+// folders are placed by prevSiblingInfo.
+package restoresim
+
+import (
+	"fmt"
+	"strings"
+
+	"arc-to-zen/types"
+)
+
+// IssueKind identifies what the restore would get wrong
+type IssueKind string
+
+const (
+	IssueOrphanTab      IssueKind = "orphan-tab"      // Tab's workspace doesn't exist
+	IssueDroppedTab     IssueKind = "dropped-tab"     // Empty tab without a groupId, removed by #filterUnusedTabs
+	IssueUnboundTab     IssueKind = "unbound-tab"     // groupId names no folder, so the tab shows up loose
+	IssueDroppedFolder  IssueKind = "dropped-folder"  // No tab is bound to the folder, so no tab-group is created
+	IssueMissingGroup   IssueKind = "missing-group"   // Folder has no matching entry in groups
+	IssueReparented     IssueKind = "reparented"      // parentId is missing, dropped, in another workspace or cyclic
+	IssueEmptyTabIDs    IssueKind = "empty-tab-ids"   // emptyTabIds names a tab that isn't the folder's placeholder
+	IssueTabSibling     IssueKind = "tab-sibling"     // prevSiblingInfo references a tab ID, which Zen discards on save
+	IssueUnresolvedPrev IssueKind = "unresolved-prev" // prevSiblingInfo names no earlier sibling folder
+	IssueMisordered     IssueKind = "misordered"      // Sibling folders come back in a different order
+)
+
+// Issue is one problem found by Simulate
+type Issue struct {
+	Kind      IssueKind `json:"kind"`
+	Workspace string    `json:"workspace"` // Workspace UUID, empty if unknown
+	ID        string    `json:"id"`        // Folder ID or tab sync ID
+	Name      string    `json:"name"`      // Folder name or tab title
+	Message   string    `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %q: %s", i.Kind, i.Name, i.Message)
+}
+
+// Report is the outcome of a simulated restore
+type Report struct {
+	Folders int     // Folders restored with their tab-group
+	Tabs    int     // Tabs restored (placeholders excluded)
+	Issues  []Issue // In session order
+}
+
+// ForWorkspaces returns the issues in the given workspaces
+func (r *Report) ForWorkspaces(uuids []string) []Issue {
+	wanted := make(map[string]bool, len(uuids))
+	for _, id := range uuids {
+		wanted[id] = true
+	}
+	var issues []Issue
+	for _, issue := range r.Issues {
+		if wanted[issue.Workspace] {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Simulate restores session in memory and reports what Zen would lose
+func Simulate(session *types.ZenSession) *Report {
+	report := &Report{}
+	add := func(kind IssueKind, workspace, id, name, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{
+			Kind:      kind,
+			Workspace: workspace,
+			ID:        id,
+			Name:      name,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	workspaces := make(map[string]bool)
+	for _, space := range session.Spaces {
+		workspaces[space.UUID] = true
+	}
+	groups := make(map[string]bool)
+	for _, group := range session.Groups {
+		groups[group.ID] = true
+	}
+	folders := make(map[string]*types.ZenFolder)
+	for i := range session.Folders {
+		folders[session.Folders[i].ID] = &session.Folders[i]
+	}
+
+	// Tabs: drop unused placeholders, then bind the rest to their tab-groups
+	tabs := make(map[string]*types.ZenTab)
+	bound := make(map[string]bool) // Folder ID → has a surviving tab
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		name := tabTitle(tab)
+		if tab.ZenIsEmpty && tab.GroupID == "" {
+			add(IssueDroppedTab, tab.ZenWorkspace, tab.ZenSyncID, name, "empty tab without groupId is removed on restore")
+			continue
+		}
+		tabs[tab.ZenSyncID] = tab
+		if tab.ZenWorkspace != "" && !workspaces[tab.ZenWorkspace] {
+			add(IssueOrphanTab, tab.ZenWorkspace, tab.ZenSyncID, name, "workspace %s does not exist", tab.ZenWorkspace)
+		}
+		if tab.GroupID != "" {
+			if folders[tab.GroupID] == nil && !groups[tab.GroupID] {
+				add(IssueUnboundTab, tab.ZenWorkspace, tab.ZenSyncID, name, "groupId %s matches no folder", tab.GroupID)
+			} else {
+				bound[tab.GroupID] = true
+			}
+		}
+		if !tab.ZenIsEmpty {
+			report.Tabs++
+		}
+	}
+
+	// Folders: a tab-group exists only if some tab is bound to it
+	restored := make(map[string]bool)
+	for _, folder := range session.Folders {
+		if !bound[folder.ID] {
+			add(IssueDroppedFolder, folder.WorkspaceID, folder.ID, folder.Name, "no tab has groupId %s, so Zen creates no tab-group", folder.ID)
+			continue
+		}
+		restored[folder.ID] = true
+		report.Folders++
+		if !groups[folder.ID] {
+			add(IssueMissingGroup, folder.WorkspaceID, folder.ID, folder.Name, "no groups entry with id %s", folder.ID)
+		}
+		for _, tabID := range folder.EmptyTabIDs {
+			tab := tabs[tabID]
+			if tab == nil || !tab.ZenIsEmpty || tab.GroupID != folder.ID {
+				add(IssueEmptyTabIDs, folder.WorkspaceID, folder.ID, folder.Name, "emptyTabIds entry %s is not an empty tab in this folder", tabID)
+			}
+		}
+	}
+
+	// Parents: restored folders whose parent can't hold them end up at the root
+	parentOf := make(map[string]string)
+	for _, folder := range session.Folders {
+		if !restored[folder.ID] || folder.ParentID == "" {
+			continue
+		}
+		parent := folders[folder.ParentID]
+		switch {
+		case parent == nil:
+			add(IssueReparented, folder.WorkspaceID, folder.ID, folder.Name, "parent folder %s does not exist", folder.ParentID)
+		case !restored[parent.ID]:
+			add(IssueReparented, folder.WorkspaceID, folder.ID, folder.Name, "parent folder %q is dropped", parent.Name)
+		case parent.WorkspaceID != folder.WorkspaceID:
+			add(IssueReparented, folder.WorkspaceID, folder.ID, folder.Name, "parent folder %q is in another workspace", parent.Name)
+		default:
+			parentOf[folder.ID] = folder.ParentID
+		}
+	}
+	for _, folder := range session.Folders {
+		if inCycle(folder.ID, parentOf) {
+			add(IssueReparented, folder.WorkspaceID, folder.ID, folder.Name, "parentId chain loops back to this folder")
+			delete(parentOf, folder.ID)
+		}
+	}
+
+	// Sibling order: nested folders are inserted after prevSiblingInfo, or first
+	expected := make(map[string][]*types.ZenFolder) // Parent ID → children in session order
+	var parents []string
+	for i := range session.Folders {
+		folder := &session.Folders[i]
+		parent, ok := parentOf[folder.ID]
+		if !ok {
+			continue
+		}
+		if expected[parent] == nil {
+			parents = append(parents, parent)
+		}
+		expected[parent] = append(expected[parent], folder)
+	}
+	for _, parent := range parents {
+		children := expected[parent]
+		var order []string
+		for _, folder := range children {
+			kind, prevID := siblingRef(folder.PrevSiblingInfo)
+			pos := 0
+			switch kind {
+			case "":
+				// Start of the parent
+			case "group":
+				pos = indexOf(order, prevID) + 1
+				if pos == 0 {
+					add(IssueUnresolvedPrev, folder.WorkspaceID, folder.ID, folder.Name, "prevSiblingInfo %s is not an earlier sibling folder; placed first", prevID)
+				}
+			case "tab":
+				add(IssueTabSibling, folder.WorkspaceID, folder.ID, folder.Name, "prevSiblingInfo references tab %s, which Zen discards on save", prevID)
+			default:
+				add(IssueUnresolvedPrev, folder.WorkspaceID, folder.ID, folder.Name, "unknown prevSiblingInfo type %q; placed first", kind)
+			}
+			order = append(order[:pos], append([]string{folder.ID}, order[pos:]...)...)
+		}
+
+		want := make([]string, len(children))
+		for i, folder := range children {
+			want[i] = folder.ID
+		}
+		if strings.Join(order, ",") != strings.Join(want, ",") {
+			parentFolder := folders[parent]
+			add(IssueMisordered, parentFolder.WorkspaceID, parent, parentFolder.Name, "subfolders restore as %s instead of %s",
+				folderNames(order, folders), folderNames(want, folders))
+		}
+	}
+
+	return report
+}
+
+// siblingRef extracts the type and id of a prevSiblingInfo value
+func siblingRef(info interface{}) (kind, id string) {
+	m, ok := info.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	kind, _ = m["type"].(string)
+	id, _ = m["id"].(string)
+	if kind == "start" {
+		kind = ""
+	}
+	return kind, id
+}
+
+// inCycle reports whether following parentOf from id leads back to id
+func inCycle(id string, parentOf map[string]string) bool {
+	seen := make(map[string]bool)
+	for current, ok := parentOf[id]; ok; current, ok = parentOf[current] {
+		if current == id {
+			return true
+		}
+		if seen[current] {
+			return false
+		}
+		seen[current] = true
+	}
+	return false
+}
+
+func indexOf(ids []string, id string) int {
+	for i, candidate := range ids {
+		if candidate == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func folderNames(ids []string, folders map[string]*types.ZenFolder) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = folders[id].Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func tabTitle(tab *types.ZenTab) string {
+	if tab.ZenStaticLabel != "" {
+		return tab.ZenStaticLabel
+	}
+	if len(tab.Entries) > 0 {
+		return tab.Entries[len(tab.Entries)-1].Title
+	}
+	return tab.ZenSyncID
+}
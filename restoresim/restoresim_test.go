@@ -0,0 +1,133 @@
+package restoresim
+
+import (
+	"testing"
+
+	"arc-to-zen/types"
+)
+
+// folderSession builds a workspace "w" with folders, each bound by a placeholder tab
+func folderSession(folders ...types.ZenFolder) *types.ZenSession {
+	session := &types.ZenSession{Spaces: []types.ZenSpace{{UUID: "w", Name: "Work"}}}
+	for _, folder := range folders {
+		folder.WorkspaceID = "w"
+		placeholder := "empty-" + folder.ID
+		if folder.EmptyTabIDs == nil {
+			folder.EmptyTabIDs = []string{placeholder}
+		}
+		session.Folders = append(session.Folders, folder)
+		session.Groups = append(session.Groups, types.ZenGroup{ID: folder.ID, Name: folder.Name})
+		session.Tabs = append(session.Tabs, types.ZenTab{ZenSyncID: placeholder, ZenWorkspace: "w", ZenIsEmpty: true, GroupID: folder.ID})
+	}
+	return session
+}
+
+func group(id string) map[string]interface{} {
+	return map[string]interface{}{"type": "group", "id": id}
+}
+
+func kinds(report *Report) []IssueKind {
+	var result []IssueKind
+	for _, issue := range report.Issues {
+		result = append(result, issue.Kind)
+	}
+	return result
+}
+
+func TestSimulate_CleanNestedFolders(t *testing.T) {
+	session := folderSession(
+		types.ZenFolder{ID: "root", Name: "Root"},
+		types.ZenFolder{ID: "a", Name: "A", ParentID: "root"},
+		types.ZenFolder{ID: "b", Name: "B", ParentID: "root", PrevSiblingInfo: group("a")},
+		types.ZenFolder{ID: "c", Name: "C", ParentID: "root", PrevSiblingInfo: group("b")},
+	)
+	session.Tabs = append(session.Tabs, types.ZenTab{ZenSyncID: "t", ZenWorkspace: "w", GroupID: "b", ZenStaticLabel: "Docs"})
+
+	report := Simulate(session)
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no issues, got %v", report.Issues)
+	}
+	if report.Folders != 4 || report.Tabs != 1 {
+		t.Errorf("expected 4 folders and 1 tab, got %d and %d", report.Folders, report.Tabs)
+	}
+}
+
+func TestSimulate_ReportsBrokenStructure(t *testing.T) {
+	tests := []struct {
+		name    string
+		session func() *types.ZenSession
+		want    IssueKind
+	}{
+		{"all nested folders first", func() *types.ZenSession {
+			return folderSession(
+				types.ZenFolder{ID: "root", Name: "Root"},
+				types.ZenFolder{ID: "a", Name: "A", ParentID: "root"},
+				types.ZenFolder{ID: "b", Name: "B", ParentID: "root"},
+			)
+		}, IssueMisordered},
+		{"tab sibling", func() *types.ZenSession {
+			return folderSession(
+				types.ZenFolder{ID: "root", Name: "Root"},
+				types.ZenFolder{ID: "a", Name: "A", ParentID: "root", PrevSiblingInfo: map[string]interface{}{"type": "tab", "id": "x"}},
+			)
+		}, IssueTabSibling},
+		{"unknown sibling", func() *types.ZenSession {
+			return folderSession(
+				types.ZenFolder{ID: "root", Name: "Root"},
+				types.ZenFolder{ID: "a", Name: "A", ParentID: "root", PrevSiblingInfo: group("gone")},
+			)
+		}, IssueUnresolvedPrev},
+		{"folder without tab", func() *types.ZenSession {
+			session := folderSession(types.ZenFolder{ID: "a", Name: "A"})
+			session.Tabs = nil
+			return session
+		}, IssueDroppedFolder},
+		{"missing parent", func() *types.ZenSession {
+			return folderSession(types.ZenFolder{ID: "a", Name: "A", ParentID: "gone"})
+		}, IssueReparented},
+		{"parent cycle", func() *types.ZenSession {
+			return folderSession(
+				types.ZenFolder{ID: "a", Name: "A", ParentID: "b"},
+				types.ZenFolder{ID: "b", Name: "B", ParentID: "a"},
+			)
+		}, IssueReparented},
+		{"stale emptyTabIds", func() *types.ZenSession {
+			return folderSession(types.ZenFolder{ID: "a", Name: "A", EmptyTabIDs: []string{"gone"}})
+		}, IssueEmptyTabIDs},
+		{"missing group entry", func() *types.ZenSession {
+			session := folderSession(types.ZenFolder{ID: "a", Name: "A"})
+			session.Groups = nil
+			return session
+		}, IssueMissingGroup},
+		{"loose placeholder", func() *types.ZenSession {
+			session := folderSession()
+			session.Tabs = append(session.Tabs, types.ZenTab{ZenSyncID: "e", ZenWorkspace: "w", ZenIsEmpty: true})
+			return session
+		}, IssueDroppedTab},
+		{"unknown group", func() *types.ZenSession {
+			session := folderSession()
+			session.Tabs = append(session.Tabs, types.ZenTab{ZenSyncID: "t", ZenWorkspace: "w", GroupID: "gone"})
+			return session
+		}, IssueUnboundTab},
+		{"unknown workspace", func() *types.ZenSession {
+			session := folderSession()
+			session.Tabs = append(session.Tabs, types.ZenTab{ZenSyncID: "t", ZenWorkspace: "gone"})
+			return session
+		}, IssueOrphanTab},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Simulate(tt.session())
+			if got := kinds(report); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("expected [%s], got %v (%v)", tt.want, got, report.Issues)
+			}
+		})
+	}
+}
+
+func TestReport_ForWorkspaces(t *testing.T) {
+	report := &Report{Issues: []Issue{{Workspace: "a"}, {Workspace: "b"}, {Workspace: "a"}}}
+	if got := report.ForWorkspaces([]string{"a"}); len(got) != 2 {
+		t.Errorf("expected 2 issues, got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package rewrite applies declarative URL rewrite rules to imported tabs, e.g.
+// to unwrap corporate SSO redirects or replace share links with the pages
+// they point to. Rules are regular expressions with a replacement, applied
+// in order, each to the result of the ones before.
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"arc-to-zen/paths"
+)
+
+// rulesFile is the optional per-user rules file in the config dir
+const rulesFile = "rewrites.json"
+
+// Rule rewrites the URLs its Match expression matches to Replace, in which
+// $1 or ${name} stand for the groups of the match (see regexp.Expand)
+type Rule struct {
+	Name     string `json:"name,omitempty"` // Shown in previews; defaults to Match
+	Match    string `json:"match"`
+	Replace  string `json:"replace"`
+	Unescape bool   `json:"unescape,omitempty"` // Percent-decode the result, for destinations passed as a query parameter
+}
+
+func (r Rule) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Match
+}
+
+// file is the layout of a rules file
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+// DefaultPath returns where Load looks by default
+func DefaultPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, rulesFile), nil
+}
+
+// Load reads the rules in the JSON file at path ({"rules": [...]}). A missing
+// file yields no rules. The rules aren't compiled; New reports bad ones.
+func Load(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rewrite rules: %w", err)
+	}
+	var rules file
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rewrite rules %s: %w", path, err)
+	}
+	return rules.Rules, nil
+}
+
+type compiled struct {
+	rule Rule
+	re   *regexp.Regexp
+}
+
+// Rewriter applies compiled rules. A nil Rewriter leaves every URL alone.
+type Rewriter struct {
+	rules []compiled
+}
+
+// New compiles rules, or returns nil if there are none
+func New(rules []Rule) (*Rewriter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	r := &Rewriter{}
+	for i, rule := range rules {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("rule %d (%s): empty match", i+1, rule.label())
+		}
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.label(), err)
+		}
+		r.rules = append(r.rules, compiled{rule: rule, re: re})
+	}
+	return r, nil
+}
+
+// Rewrite returns rawURL with every matching rule applied, and the names of
+// the rules that changed it (none if it was left alone)
+func (r *Rewriter) Rewrite(rawURL string) (string, []string) {
+	if r == nil {
+		return rawURL, nil
+	}
+	var applied []string
+	for _, c := range r.rules {
+		if !c.re.MatchString(rawURL) {
+			continue
+		}
+		rewritten := c.re.ReplaceAllString(rawURL, c.rule.Replace)
+		if c.rule.Unescape {
+			if unescaped, err := url.QueryUnescape(rewritten); err == nil {
+				rewritten = unescaped
+			}
+		}
+		if rewritten != rawURL {
+			rawURL = rewritten
+			applied = append(applied, c.rule.label())
+		}
+	}
+	return rawURL, applied
+}
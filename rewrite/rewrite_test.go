@@ -0,0 +1,61 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	r, err := New([]Rule{
+		{Name: "sso", Match: `^https://sso\.corp\.test/login\?next=(.+)$`, Replace: "$1", Unescape: true},
+		{Match: `^http://(intranet\.corp\.test)/`, Replace: "https://${1}/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+		rules    []string
+	}{
+		{"https://sso.corp.test/login?next=https%3A%2F%2Fwiki.corp.test%2Fa%3Fb%3Dc", "https://wiki.corp.test/a?b=c", []string{"sso"}},
+		{"https://sso.corp.test/login?next=http%3A%2F%2Fintranet.corp.test%2Fhome", "https://intranet.corp.test/home", []string{"sso", `^http://(intranet\.corp\.test)/`}},
+		{"https://example.test/", "https://example.test/", nil},
+	}
+	for _, tt := range tests {
+		got, rules := r.Rewrite(tt.in)
+		if got != tt.want || strings.Join(rules, ",") != strings.Join(tt.rules, ",") {
+			t.Errorf("Rewrite(%q) = %q %v, want %q %v", tt.in, got, rules, tt.want, tt.rules)
+		}
+	}
+
+	var none *Rewriter
+	if got, rules := none.Rewrite("https://example.test/"); got != "https://example.test/" || rules != nil {
+		t.Errorf("expected a nil Rewriter to leave URLs alone, got %q %v", got, rules)
+	}
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	if _, err := New([]Rule{{Match: "("}}); err == nil {
+		t.Error("expected a bad expression to be rejected")
+	}
+	if _, err := New([]Rule{{Name: "blank", Replace: "x"}}); err == nil || !strings.Contains(err.Error(), "blank") {
+		t.Errorf("expected an empty match to be rejected by name, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if rules, err := Load(filepath.Join(dir, "missing.json")); err != nil || rules != nil {
+		t.Errorf("expected no rules from a missing file, got %v %v", rules, err)
+	}
+	path := filepath.Join(dir, "rewrites.json")
+	if err := os.WriteFile(path, []byte(`{"rules": [{"name": "share", "match": "^https://arc\\.net/", "replace": "https://example.test/"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Load(path)
+	if err != nil || len(rules) != 1 || rules[0].Name != "share" {
+		t.Errorf("unexpected rules %+v, %v", rules, err)
+	}
+}
@@ -0,0 +1,96 @@
+package rewrite
+
+import (
+	_ "embed"
+	"net/url"
+	"strings"
+)
+
+// TrackingRule is how stripped tracking parameters are labeled among the
+// rules that changed a URL
+const TrackingRule = "tracking parameters"
+
+// trackingRules is the built-in list of tracking parameters, kept in
+// tracking.txt
+//
+//go:embed tracking.txt
+var trackingRules string
+
+// trackingParam is one line of tracking.txt
+type trackingParam struct {
+	name   string // Lowercased
+	prefix bool
+	host   string // Empty for every site
+}
+
+var trackingParams = parseTracking(trackingRules)
+
+func parseTracking(rules string) []trackingParam {
+	var params []trackingParam
+	for _, line := range strings.Split(rules, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var param trackingParam
+		line, param.host, _ = strings.Cut(line, "@")
+		param.name = strings.ToLower(line)
+		if strings.HasSuffix(param.name, "*") {
+			param.name = strings.TrimSuffix(param.name, "*")
+			param.prefix = true
+		}
+		param.host = strings.ToLower(param.host)
+		params = append(params, param)
+	}
+	return params
+}
+
+func (p trackingParam) matches(name, host string) bool {
+	if p.host != "" && host != p.host && !strings.HasSuffix(host, "."+p.host) {
+		return false
+	}
+	if p.prefix {
+		return strings.HasPrefix(name, p.name)
+	}
+	return name == p.name
+}
+
+// isTracking reports whether the query parameter name is a tracking
+// parameter on host
+func isTracking(name, host string) bool {
+	name = strings.ToLower(name)
+	for _, param := range trackingParams {
+		if param.matches(name, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripTracking returns rawURL without its tracking parameters (see
+// tracking.txt), and whether any were removed. The other parameters keep
+// their order and encoding.
+func StripTracking(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" || u.Opaque != "" {
+		return rawURL, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	pairs := strings.Split(u.RawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !isTracking(name, host) {
+			kept = append(kept, pair)
+		}
+	}
+	if len(kept) == len(pairs) {
+		return rawURL, false
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String(), true
+}
@@ -0,0 +1,33 @@
+package rewrite
+
+import "testing"
+
+func TestStripTracking(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://example.test/a?utm_source=news&id=7&UTM_Medium=mail#top", "https://example.test/a?id=7#top"},
+		{"https://example.test/?fbclid=abc", "https://example.test/"},
+		{"https://example.test/?q=a%20b&gclid=x&sort=new", "https://example.test/?q=a%20b&sort=new"},
+		{"https://www.youtube.com/watch?v=abc&si=xyz", "https://www.youtube.com/watch?v=abc"},
+		{"https://m.youtube.com/watch?v=abc&si=xyz", "https://m.youtube.com/watch?v=abc"},
+		{"https://example.test/?si=kept", "https://example.test/?si=kept"},
+		{"https://example.test/?utm", "https://example.test/?utm"},
+		{"https://example.test/plain", "https://example.test/plain"},
+		{"mailto:someone@example.test?utm_source=x", "mailto:someone@example.test?utm_source=x"},
+	}
+	for _, tt := range tests {
+		got, stripped := StripTracking(tt.in)
+		if got != tt.want || stripped != (tt.in != tt.want) {
+			t.Errorf("StripTracking(%q) = %q, %v, want %q", tt.in, got, stripped, tt.want)
+		}
+	}
+}
+
+func TestParseTracking(t *testing.T) {
+	params := parseTracking("# comment\n\nUTM_*\nsi@YouTube.com\n")
+	want := []trackingParam{{name: "utm_", prefix: true}, {name: "si", host: "youtube.com"}}
+	if len(params) != len(want) || params[0] != want[0] || params[1] != want[1] {
+		t.Errorf("parseTracking = %+v, want %+v", params, want)
+	}
+}
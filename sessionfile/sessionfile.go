@@ -0,0 +1,70 @@
+// Package sessionfile reads and writes a Zen profile's zen-sessions.jsonlz4,
+// and writes profile files atomically, alone or together, for the importer
+// and the commands that rewrite the session in place (compact, untag,
+// upgrade-session).
+package sessionfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/types"
+)
+
+// FileName is the session file in a Zen profile
+const FileName = "zen-sessions.jsonlz4"
+
+// Path returns the session file of the Zen profile at profilePath
+func Path(profilePath string) string {
+	return filepath.Join(profilePath, FileName)
+}
+
+// Read reads and decodes the session of the Zen profile at profilePath. size
+// is the compressed size of the file.
+func Read(profilePath string) (session *types.ZenSession, size int64, err error) {
+	sessionPath := Path(profilePath)
+	compressed, err := os.ReadFile(sessionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, fmt.Errorf("zen-sessions.jsonlz4 not found at: %s", sessionPath)
+		}
+		return nil, 0, fmt.Errorf("failed to read session file: %w", err)
+	}
+	session, err = Decode(compressed)
+	if err != nil {
+		return nil, 0, err
+	}
+	return session, int64(len(compressed)), nil
+}
+
+// Decode decompresses and parses session file contents
+func Decode(compressed []byte) (*types.ZenSession, error) {
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress session: %w", err)
+	}
+	var session types.ZenSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	return &session, nil
+}
+
+// Encode returns the compressed session file contents
+func Encode(session *types.ZenSession) ([]byte, error) {
+	// Marshal to JSON (no indentation for compression). Called directly so
+	// json.Marshal doesn't HTML-escape the fields written back as read.
+	jsonData, err := session.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	compressedData, err := mozlz4.Compress(jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress session: %w", err)
+	}
+	return compressedData, nil
+}
@@ -0,0 +1,129 @@
+package sessionfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// rename is swapped out in tests to simulate a failed commit
+var rename = os.Rename
+
+// File is a file that is written to a temp path first and moved into place later
+type File struct {
+	Path string
+	Data []byte
+
+	tmpPath string // staged new contents
+	oldPath string // original contents, moved aside during commit
+	moved   bool   // original has been moved to oldPath
+	placed  bool   // new contents are at path
+}
+
+// WriteTogether writes every file or none of them. All contents are staged
+// in temp files next to their targets, then swapped in; if any swap fails the
+// files already replaced are restored.
+func WriteTogether(files []File) error {
+	for i := range files {
+		if err := files[i].stage(); err != nil {
+			removeStaged(files)
+			return err
+		}
+	}
+
+	for i := range files {
+		if err := files[i].commit(); err != nil {
+			if rbErr := rollback(files[:i+1]); rbErr != nil {
+				err = fmt.Errorf("%w; rollback also failed: %v", err, rbErr)
+			}
+			removeStaged(files)
+			return err
+		}
+	}
+
+	// Everything is in place; the originals are no longer needed
+	for _, f := range files {
+		if f.moved {
+			os.Remove(f.oldPath)
+		}
+	}
+	return nil
+}
+
+// WriteAtomic writes data to path via a temp file and a rename, so an
+// interrupted write leaves the old contents in place
+func WriteAtomic(path string, data []byte) error {
+	return WriteTogether([]File{{Path: path, Data: data}})
+}
+
+// stage writes the new contents to a temp file in the target's directory, so the
+// final rename never crosses filesystems
+func (f *File) stage() error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), "."+filepath.Base(f.Path)+".arc-to-zen-*")
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", filepath.Base(f.Path), err)
+	}
+	f.tmpPath = tmp.Name()
+
+	_, err = tmp.Write(f.Data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.tmpPath, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", filepath.Base(f.Path), err)
+	}
+	return nil
+}
+
+// commit moves the original aside and the staged contents into place
+func (f *File) commit() error {
+	if _, err := os.Stat(f.Path); err == nil {
+		f.oldPath = f.tmpPath + ".old"
+		if err := rename(f.Path, f.oldPath); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filepath.Base(f.Path), err)
+		}
+		f.moved = true
+	}
+
+	if err := rename(f.tmpPath, f.Path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(f.Path), err)
+	}
+	f.placed = true
+	return nil
+}
+
+// rollback restores the originals of files that were committed
+func rollback(files []File) error {
+	var errs []error
+	for i := len(files) - 1; i >= 0; i-- {
+		f := &files[i]
+		if f.placed && !f.moved {
+			// File didn't exist before
+			if err := os.Remove(f.Path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if f.moved {
+			if err := rename(f.oldPath, f.Path); err != nil {
+				errs = append(errs, fmt.Errorf("%s (original kept at %s): %w", f.Path, f.oldPath, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeStaged deletes temp files that were never moved into place
+func removeStaged(files []File) {
+	for _, f := range files {
+		if f.tmpPath != "" && !f.placed {
+			os.Remove(f.tmpPath)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package sessionfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTogether(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "containers.json")
+	b := filepath.Join(dir, "zen-sessions.jsonlz4")
+	if err := os.WriteFile(a, []byte("old a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WriteTogether([]File{{Path: a, Data: []byte("new a")}, {Path: b, Data: []byte("new b")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, want := range map[string]string{a: "new a", b: "new b"} {
+		if got, _ := os.ReadFile(path); string(got) != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(path), want, got)
+		}
+	}
+	assertOnlyFiles(t, dir, 2)
+}
+
+func TestWriteTogether_RollsBackOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "containers.json")
+	b := filepath.Join(dir, "zen-sessions.jsonlz4")
+	for path, data := range map[string]string{a: "old a", b: "old b"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fail when the staged session is moved into place, after containers.json was replaced
+	defer func() { rename = os.Rename }()
+	failed := false
+	rename = func(from, to string) error {
+		if to == b && !failed {
+			failed = true
+			return errors.New("disk on fire")
+		}
+		return os.Rename(from, to)
+	}
+
+	err := WriteTogether([]File{{Path: a, Data: []byte("new a")}, {Path: b, Data: []byte("new b")}})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for path, want := range map[string]string{a: "old a", b: "old b"} {
+		if got, _ := os.ReadFile(path); string(got) != want {
+			t.Errorf("%s: expected original %q, got %q", filepath.Base(path), want, got)
+		}
+	}
+	assertOnlyFiles(t, dir, 2)
+}
+
+// assertOnlyFiles fails if staging left temp files behind
+func assertOnlyFiles(t *testing.T, dir string, want int) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != want {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected %d files, got %v", want, names)
+	}
+}
@@ -0,0 +1,56 @@
+// Package tag removes the label prefix the importer's -tag-prefix option puts
+// on imported tabs, so migrated pins can be told apart from native Zen pins
+// for a while and made to look native again once the transition is over.
+package tag
+
+import (
+	"strings"
+
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+)
+
+// DefaultPrefix is the prefix suggested for -tag-prefix and removed by
+// default by the untag command
+const DefaultPrefix = "[arc] "
+
+// Strip removes prefix from the static labels of session's tabs and returns
+// how many tabs had it
+func Strip(session *types.ZenSession, prefix string) int {
+	if prefix == "" {
+		return 0
+	}
+	tabs := 0
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		if strings.HasPrefix(tab.ZenStaticLabel, prefix) {
+			tab.ZenStaticLabel = strings.TrimPrefix(tab.ZenStaticLabel, prefix)
+			tabs++
+		}
+	}
+	return tabs
+}
+
+// Profile removes prefix from the tab labels in the session of the Zen
+// profile at profilePath and returns how many tabs had it. The caller backs
+// the session up first. In dry-run nothing is written.
+func Profile(profilePath, prefix string, dryRun bool) (int, error) {
+	session, _, err := sessionfile.Read(profilePath)
+	if err != nil {
+		return 0, err
+	}
+
+	tabs := Strip(session, prefix)
+	if dryRun || tabs == 0 {
+		return tabs, nil
+	}
+
+	untagged, err := sessionfile.Encode(session)
+	if err != nil {
+		return 0, err
+	}
+	if err := sessionfile.WriteAtomic(sessionfile.Path(profilePath), untagged); err != nil {
+		return 0, err
+	}
+	return tabs, nil
+}
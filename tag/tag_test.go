@@ -0,0 +1,66 @@
+package tag
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/sessionfile"
+)
+
+func TestProfile(t *testing.T) {
+	raw := `{"spaces": [], "tabs": [
+		{"entries": [{"url": "https://a.example/"}], "zenStaticLabel": "[arc] Docs", "futureField": {"kept": 1}},
+		{"entries": [{"url": "https://b.example/"}], "zenStaticLabel": "Native pin"},
+		{"entries": [], "zenIsEmpty": true}
+	], "folders": [], "groups": [], "splitViewData": []}`
+
+	dir := t.TempDir()
+	original, err := mozlz4.Compress([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionPath := sessionfile.Path(dir)
+	if err := os.WriteFile(sessionPath, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry run reports without writing
+	if tabs, err := Profile(dir, DefaultPrefix, true); err != nil || tabs != 1 {
+		t.Fatalf("dry run: got %d tabs, %v", tabs, err)
+	}
+	if current, _ := os.ReadFile(sessionPath); !bytes.Equal(current, original) {
+		t.Error("dry run changed the session file")
+	}
+
+	if tabs, err := Profile(dir, DefaultPrefix, false); err != nil || tabs != 1 {
+		t.Fatalf("untag: got %d tabs, %v", tabs, err)
+	}
+	compressed, _ := os.ReadFile(sessionPath)
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session struct {
+		Tabs []map[string]interface{} `json:"tabs"`
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	if got := session.Tabs[0]["zenStaticLabel"]; got != "Docs" {
+		t.Errorf("expected the prefix removed, got %v", got)
+	}
+	if got := session.Tabs[1]["zenStaticLabel"]; got != "Native pin" {
+		t.Errorf("expected the native pin untouched, got %v", got)
+	}
+	if session.Tabs[0]["futureField"] == nil {
+		t.Error("expected unknown fields to survive")
+	}
+
+	// Running it again finds nothing left
+	if tabs, err := Profile(dir, DefaultPrefix, false); err != nil || tabs != 0 {
+		t.Errorf("second run: got %d tabs, %v", tabs, err)
+	}
+}
@@ -1,8 +1,29 @@
 package types
 
+import "encoding/json"
+
 // ArcData represents the top-level Arc browser data structure
 type ArcData struct {
 	Sidebar *ArcSidebar `json:"sidebar"`
+
+	// ProfileNames maps profile directory names (e.g. "Profile 1") to their display
+	// names. Not part of StorableSidebar.json; loaded from Arc's Local State file.
+	ProfileNames map[string]string `json:"-"`
+}
+
+// ArcLocalState represents the parts of Arc's Chromium "Local State" file we use
+type ArcLocalState struct {
+	Profile *ArcLocalStateProfile `json:"profile"`
+}
+
+// ArcLocalStateProfile contains per-profile metadata keyed by directory name
+type ArcLocalStateProfile struct {
+	InfoCache map[string]ArcProfileInfo `json:"info_cache"`
+}
+
+// ArcProfileInfo contains the user-visible details of an Arc profile
+type ArcProfileInfo struct {
+	Name string `json:"name"`
 }
 
 // ArcSidebar contains Arc's sidebar configuration
@@ -10,10 +31,11 @@ type ArcSidebar struct {
 	Containers []*ArcContainer `json:"containers"`
 }
 
-// ArcContainer represents a container in Arc (spaces + items)
+// ArcContainer represents a container in Arc (spaces + items). Entries are
+// kept as raw JSON and parsed one at a time by the importer.
 type ArcContainer struct {
-	Spaces []interface{} `json:"spaces"` // Can be objects or strings
-	Items  []interface{} `json:"items"`  // Can be objects or strings
+	Spaces []json.RawMessage `json:"spaces"` // Can be objects or strings
+	Items  []json.RawMessage `json:"items"`  // Can be objects or strings
 }
 
 // ArcSpace represents an Arc workspace/space
@@ -25,6 +47,8 @@ type ArcSpace struct {
 	ContainerIDs []interface{}  `json:"containerIDs"` // Can be strings or objects
 	CustomInfo   *ArcCustomInfo `json:"customInfo"`
 	Profile      *ArcProfile    `json:"profile"`
+
+	AutoArchive *ArcAutoArchive `json:"-"` // Read by UnmarshalJSON in arc_archive.go; nil if not set
 }
 
 // ArcProfile represents an Arc browser profile
@@ -46,12 +70,14 @@ type ArcProfileData struct {
 
 // ArcCustomInfo contains custom space configuration
 type ArcCustomInfo struct {
-	IconType *ArcIconType `json:"iconType"`
+	IconType    *ArcIconType    `json:"iconType"`
+	WindowTheme json.RawMessage `json:"windowTheme,omitempty"` // Read by ArcSpace.ThemeColors
 }
 
 // ArcIconType contains icon information
 type ArcIconType struct {
-	Icon string `json:"icon"`
+	Icon  string `json:"icon"`
+	Emoji string `json:"emoji_v2"` // Set instead of Icon for spaces with an emoji icon
 }
 
 // ArcItem represents a tab or folder in Arc
@@ -61,18 +87,25 @@ type ArcItem struct {
 	ParentID    string      `json:"parentID"`
 	ChildrenIds []string    `json:"childrenIds"`
 	Data        *ArcItemData `json:"data"`
+
+	CustomIcon *ArcCustomIcon `json:"-"` // Read by UnmarshalJSON in arc_icon.go; nil if not set
+	CreatedAt  float64        `json:"-"` // Arc time (seconds since 2001), read by UnmarshalJSON; 0 if unknown
 }
 
-// ArcItemData contains tab or container data
+// ArcItemData contains tab or container data. Some Arc versions keep the tab
+// under "list" instead of "tab"; see UnmarshalJSON in arc_item.go.
 type ArcItemData struct {
 	Tab           *ArcTab           `json:"tab"`
 	ItemContainer *ArcItemContainer `json:"itemContainer"`
+	List          json.RawMessage   `json:"list"` // Set on folders, even empty ones
 }
 
-// ArcTab represents a browser tab
+// ArcTab represents a browser tab. Key casing and nesting vary between Arc
+// versions; see UnmarshalJSON in arc_item.go.
 type ArcTab struct {
-	SavedTitle string `json:"savedTitle"`
-	SavedURL   string `json:"savedURL"`
+	SavedTitle   string  `json:"savedTitle"`
+	SavedURL     string  `json:"savedURL"`
+	LastActiveAt float64 `json:"-"` // Arc time of the last visit (timeLastActiveAt); 0 if unknown
 }
 
 // ArcItemContainer represents Arc internal containers (to be skipped)
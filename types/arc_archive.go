@@ -0,0 +1,115 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Arc archives unpinned tabs a space hasn't used for a while. Which key holds
+// the setting, and whether it is seconds, a named interval or a Swift enum
+// object ({"twelveHours": {}}), has changed between versions, so it is found
+// by name: any key containing "archive" on the space or its customInfo.
+
+// arcDefaultArchiveAfter is Arc's interval when a space only says archiving is on
+const arcDefaultArchiveAfter = 12 * time.Hour
+
+// arcArchiveIntervals are Arc's named archive intervals, lowercased
+var arcArchiveIntervals = map[string]time.Duration{
+	"twelvehours":     12 * time.Hour,
+	"oneday":          24 * time.Hour,
+	"twentyfourhours": 24 * time.Hour,
+	"sevendays":       7 * 24 * time.Hour,
+	"oneweek":         7 * 24 * time.Hour,
+	"thirtydays":      30 * 24 * time.Hour,
+	"onemonth":        30 * 24 * time.Hour,
+}
+
+// ArcAutoArchive is when Arc archives a space's unpinned tabs
+type ArcAutoArchive struct {
+	After time.Duration // Idle time before a tab is archived (0 if Never)
+	Never bool          // Auto-archive is off for the space
+	Raw   string        // The setting as stored, for reports
+}
+
+// UnmarshalJSON reads a space and its auto-archive setting, if it has one
+func (s *ArcSpace) UnmarshalJSON(data []byte) error {
+	type plain ArcSpace
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	s.AutoArchive = findAutoArchive(fields)
+	if s.AutoArchive == nil {
+		var customInfo map[string]json.RawMessage
+		if json.Unmarshal(fields["customInfo"], &customInfo) == nil {
+			s.AutoArchive = findAutoArchive(customInfo)
+		}
+	}
+	return nil
+}
+
+// findAutoArchive reads the first archive key of fields, in sorted key order
+func findAutoArchive(fields map[string]json.RawMessage) *ArcAutoArchive {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if strings.Contains(strings.ToLower(key), "archive") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if setting := parseAutoArchive(fields[key]); setting != nil {
+			return setting
+		}
+	}
+	return nil
+}
+
+// parseAutoArchive reads seconds, true/false, a named interval, a duration
+// ("12h"), "never", or an enum object keyed by one of those names
+func parseAutoArchive(raw json.RawMessage) *ArcAutoArchive {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+	switch v := value.(type) {
+	case float64:
+		if v <= 0 {
+			return &ArcAutoArchive{Never: true, Raw: string(raw)}
+		}
+		return &ArcAutoArchive{After: time.Duration(v * float64(time.Second)), Raw: string(raw)}
+	case bool:
+		if !v {
+			return &ArcAutoArchive{Never: true, Raw: "false"}
+		}
+		return &ArcAutoArchive{After: arcDefaultArchiveAfter, Raw: "true"}
+	case string:
+		return parseArchiveName(v)
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for name := range v {
+				return parseArchiveName(name)
+			}
+		}
+	}
+	return nil
+}
+
+func parseArchiveName(name string) *ArcAutoArchive {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "never" || key == "off" || key == "disabled" {
+		return &ArcAutoArchive{Never: true, Raw: name}
+	}
+	if after, ok := arcArchiveIntervals[key]; ok {
+		return &ArcAutoArchive{After: after, Raw: name}
+	}
+	if after, err := time.ParseDuration(key); err == nil && after > 0 {
+		return &ArcAutoArchive{After: after, Raw: name}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestArcSpace_AutoArchive(t *testing.T) {
+	tests := []struct {
+		json  string
+		after time.Duration
+		never bool
+		found bool
+	}{
+		{`{"id": "s", "autoArchiveThreshold": 43200}`, 12 * time.Hour, false, true},
+		{`{"id": "s", "customInfo": {"archiveInterval": {"sevenDays": {}}}}`, 7 * 24 * time.Hour, false, true},
+		{`{"id": "s", "archiveAfter": "thirtyDays"}`, 30 * 24 * time.Hour, false, true},
+		{`{"id": "s", "archiveAfter": "36h"}`, 36 * time.Hour, false, true},
+		{`{"id": "s", "isAutoArchiveEnabled": false}`, 0, true, true},
+		{`{"id": "s", "autoArchive": "never"}`, 0, true, true},
+		{`{"id": "s", "autoArchive": "sometimes"}`, 0, false, false},
+		{`{"id": "s", "title": "Work"}`, 0, false, false},
+	}
+	for _, tt := range tests {
+		var space ArcSpace
+		if err := json.Unmarshal([]byte(tt.json), &space); err != nil {
+			t.Fatalf("%s: %v", tt.json, err)
+		}
+		if space.ID != "s" {
+			t.Errorf("%s: space fields not decoded", tt.json)
+		}
+		got := space.AutoArchive
+		if (got != nil) != tt.found {
+			t.Errorf("%s: expected found=%v, got %+v", tt.json, tt.found, got)
+			continue
+		}
+		if got != nil && (got.After != tt.after || got.Never != tt.never) {
+			t.Errorf("%s: got after %v never %v, want %v %v", tt.json, got.After, got.Never, tt.after, tt.never)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Arc lets pinned tabs have a custom icon: an image, kept as a data: URL, or an
+// emoji. Where it is stored differs between versions (on the item, its
+// customInfo, or the tab data), so it is found by key name: a data:image URL
+// under a key containing "icon" or "image", or a string under a key containing
+// "emoji".
+
+// maxCustomIconDepth bounds the search for custom icon fields
+const maxCustomIconDepth = 4
+
+// ArcCustomIcon is the icon a user picked for a tab in Arc
+type ArcCustomIcon struct {
+	DataURL string // data:image/... URL, if the icon is an image
+	Emoji   string // The emoji, if the icon is one (and DataURL is empty)
+}
+
+// UnmarshalJSON reads an item and its custom tab icon, if it has one
+func (i *ArcItem) UnmarshalJSON(data []byte) error {
+	type plain ArcItem
+	if err := json.Unmarshal(data, (*plain)(i)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	i.CreatedAt = arcTime(fields, "createdAt")
+	icon := &ArcCustomIcon{}
+	findCustomIcon(fields, icon, maxCustomIconDepth)
+	if icon.DataURL != "" {
+		icon.Emoji = ""
+	}
+	if icon.DataURL != "" || icon.Emoji != "" {
+		i.CustomIcon = icon
+	}
+	return nil
+}
+
+// findCustomIcon fills in the first data URL and emoji found in fields, in
+// sorted key order, looking into nested objects up to depth levels
+func findCustomIcon(fields map[string]json.RawMessage, icon *ArcCustomIcon, depth int) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lower := strings.ToLower(key)
+		var value string
+		if json.Unmarshal(fields[key], &value) == nil {
+			value = strings.TrimSpace(value)
+			switch {
+			case icon.DataURL == "" && (strings.Contains(lower, "icon") || strings.Contains(lower, "image")) &&
+				strings.HasPrefix(strings.ToLower(value), "data:image/"):
+				icon.DataURL = value
+			case icon.Emoji == "" && strings.Contains(lower, "emoji") && value != "":
+				icon.Emoji = value
+			}
+			continue
+		}
+		if depth == 0 {
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(fields[key], &nested) == nil {
+			findCustomIcon(nested, icon, depth-1)
+		}
+	}
+}
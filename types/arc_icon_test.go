@@ -0,0 +1,37 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestArcItem_CustomIcon(t *testing.T) {
+	const png = "data:image/png;base64,iVBORw0KGgo="
+	tests := map[string]struct {
+		raw  string
+		want *ArcCustomIcon
+	}{
+		"none":          {`{"id": "t1", "data": {"tab": {"savedURL": "https://a.example/"}}}`, nil},
+		"customInfo":    {`{"id": "t1", "customInfo": {"iconType": {"emoji_v2": "🔥"}}}`, &ArcCustomIcon{Emoji: "🔥"}},
+		"tab image":     {`{"id": "t1", "data": {"tab": {"customIconData": "` + png + `"}}}`, &ArcCustomIcon{DataURL: png}},
+		"image wins":    {`{"id": "t1", "emoji": "🔥", "iconImage": "` + png + `"}`, &ArcCustomIcon{DataURL: png}},
+		"not an image":  {`{"id": "t1", "icon": "data:text/html,hi"}`, nil},
+		"url elsewhere": {`{"id": "t1", "data": {"tab": {"savedURL": "` + png + `"}}}`, nil},
+	}
+	for name, tt := range tests {
+		var item ArcItem
+		if err := json.Unmarshal([]byte(tt.raw), &item); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if item.ID != "t1" {
+			t.Errorf("%s: item fields not read: %+v", name, item)
+		}
+		switch {
+		case tt.want == nil && item.CustomIcon != nil:
+			t.Errorf("%s: expected no custom icon, got %+v", name, item.CustomIcon)
+		case tt.want != nil && (item.CustomIcon == nil || *item.CustomIcon != *tt.want):
+			t.Errorf("%s: expected %+v, got %+v", name, tt.want, item.CustomIcon)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Arc has stored tab data under different keys over time. These are tried in
+// order, matched case-insensitively.
+var (
+	arcTabURLKeys   = []string{"savedURL", "url", "urlString"}
+	arcTabTitleKeys = []string{"savedTitle", "title"}
+)
+
+// maxTabDataDepth bounds the search for tab fields buried in nested objects
+// such as "savedMuteStatus"
+const maxTabDataDepth = 3
+
+// UnmarshalJSON reads a tab whose URL and title may use any known key casing,
+// or sit in a nested object
+func (t *ArcTab) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	t.SavedURL = findTabString(fields, arcTabURLKeys, maxTabDataDepth)
+	t.SavedTitle = findTabString(fields, arcTabTitleKeys, maxTabDataDepth)
+	t.LastActiveAt = arcTime(fields, "timeLastActiveAt")
+	return nil
+}
+
+// arcTime reads an Arc timestamp (seconds since 2001-01-01) under key, or 0
+// if it is missing or not a number. Only its order matters to the importer.
+func arcTime(fields map[string]json.RawMessage, key string) float64 {
+	raw, ok := lookupKey(fields, key)
+	if !ok {
+		return 0
+	}
+	var t float64
+	if json.Unmarshal(raw, &t) != nil {
+		return 0
+	}
+	return t
+}
+
+// UnmarshalJSON reads item data, falling back to a tab stored under "list"
+func (d *ArcItemData) UnmarshalJSON(data []byte) error {
+	type plain ArcItemData
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+	if d.Tab != nil {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	list, ok := lookupKey(fields, "list")
+	if !ok {
+		return nil
+	}
+	var listFields map[string]json.RawMessage
+	if json.Unmarshal(list, &listFields) != nil {
+		return nil // Not an object, so not a tab
+	}
+	if nested, ok := lookupKey(listFields, "tab"); ok {
+		list = nested
+	}
+	var tab ArcTab
+	if err := json.Unmarshal(list, &tab); err != nil {
+		return nil
+	}
+	if tab.SavedURL != "" || tab.SavedTitle != "" {
+		d.Tab = &tab
+	}
+	return nil
+}
+
+// findTabString returns the first string value under one of keys, looking into
+// nested objects (up to depth levels) when the object itself has none
+func findTabString(fields map[string]json.RawMessage, keys []string, depth int) string {
+	for _, key := range keys {
+		if raw, ok := lookupKey(fields, key); ok {
+			var value string
+			if json.Unmarshal(raw, &value) == nil && value != "" {
+				return value
+			}
+		}
+	}
+	if depth == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Deterministic if several nested objects qualify
+	for _, name := range names {
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(fields[name], &nested) != nil {
+			continue
+		}
+		if value := findTabString(nested, keys, depth-1); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// lookupKey finds key in fields, preferring an exact match over other casings
+func lookupKey(fields map[string]json.RawMessage, key string) (json.RawMessage, bool) {
+	if raw, ok := fields[key]; ok {
+		return raw, true
+	}
+	for name, raw := range fields {
+		if strings.EqualFold(name, key) {
+			return raw, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestArcItemData_TabVariants(t *testing.T) {
+	tests := map[string]string{
+		"standard":        `{"tab": {"savedTitle": "Docs", "savedURL": "https://docs.example/"}}`,
+		"lowercase url":   `{"tab": {"savedTitle": "Docs", "savedUrl": "https://docs.example/"}}`,
+		"plain keys":      `{"tab": {"title": "Docs", "url": "https://docs.example/"}}`,
+		"buried":          `{"tab": {"savedMuteStatus": {"savedTitle": "Docs", "savedURL": "https://docs.example/"}}}`,
+		"list":            `{"list": {"savedTitle": "Docs", "savedURL": "https://docs.example/"}}`,
+		"list with tab":   `{"list": {"tab": {"savedTitle": "Docs", "savedURL": "https://docs.example/"}}}`,
+		"top level wins":  `{"tab": {"savedTitle": "Docs", "savedURL": "https://docs.example/", "x": {"savedURL": "https://other.example/"}}}`,
+		"tab before list": `{"tab": {"savedTitle": "Docs", "savedURL": "https://docs.example/"}, "list": {"savedURL": "https://other.example/"}}`,
+	}
+	for name, raw := range tests {
+		var data ArcItemData
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if data.Tab == nil || data.Tab.SavedURL != "https://docs.example/" || data.Tab.SavedTitle != "Docs" {
+			t.Errorf("%s: unexpected tab %+v", name, data.Tab)
+		}
+	}
+}
+
+func TestArcItemData_NoTab(t *testing.T) {
+	for _, raw := range []string{
+		`{}`,
+		`{"list": {}}`,
+		`{"list": "pinned"}`,
+		`{"itemContainer": {"containerType": {"spaceItems": {}}}}`,
+	} {
+		var data ArcItemData
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			t.Errorf("%s: %v", raw, err)
+			continue
+		}
+		if data.Tab != nil {
+			t.Errorf("%s: expected no tab, got %+v", raw, data.Tab)
+		}
+	}
+}
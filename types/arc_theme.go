@@ -0,0 +1,128 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// Arc stores a space's color as its window theme: nested objects holding
+// {"red", "green", "blue", "alpha"} components (0–1, extended sRGB), in a
+// primaryColorPalette and in the gradient's base colors. The layout varies
+// between Arc versions, so colors are found by shape rather than by path.
+
+// maxThemeColors is how many distinct colors ThemeColors returns at most
+const maxThemeColors = 3
+
+// ArcColor is one RGB color from an Arc theme, components 0–1
+type ArcColor struct {
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+	Alpha float64 `json:"alpha"`
+}
+
+// RGB returns the color as 0–255 components
+func (c ArcColor) RGB() (r, g, b int) {
+	return to8bit(c.Red), to8bit(c.Green), to8bit(c.Blue)
+}
+
+func to8bit(v float64) int {
+	return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// ThemeColors returns the distinct colors of the space's Arc theme, primary
+// first, or nil if it has none
+func (s *ArcSpace) ThemeColors() []ArcColor {
+	if s.CustomInfo == nil || len(s.CustomInfo.WindowTheme) == 0 {
+		return nil
+	}
+	var theme interface{}
+	if err := json.Unmarshal(s.CustomInfo.WindowTheme, &theme); err != nil {
+		return nil
+	}
+
+	var colors []ArcColor
+	add := func(c ArcColor) {
+		if len(colors) >= maxThemeColors || c.Alpha == 0 {
+			return
+		}
+		r, g, b := c.RGB()
+		for _, existing := range colors {
+			er, eg, eb := existing.RGB()
+			if abs(r-er)+abs(g-eg)+abs(b-eb) < 8 {
+				return // Same color at a different shade step
+			}
+		}
+		colors = append(colors, c)
+	}
+
+	// The palette's mid tone is the color Arc shows for the space; its other
+	// tones are shades of it, not gradient colors
+	if root, ok := theme.(map[string]interface{}); ok {
+		if palette, ok := root["primaryColorPalette"].(map[string]interface{}); ok {
+			if c, ok := asColor(palette["midTone"]); ok {
+				add(c)
+			}
+		}
+		rest := make(map[string]interface{}, len(root))
+		for key, value := range root {
+			if key != "primaryColorPalette" {
+				rest[key] = value
+			}
+		}
+		theme = rest
+	}
+	walkColors(theme, add)
+	return colors
+}
+
+// walkColors calls add for every color object in v, in array order and
+// sorted key order
+func walkColors(v interface{}, add func(ArcColor)) {
+	if c, ok := asColor(v); ok {
+		add(c)
+		return
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkColors(v[key], add)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkColors(item, add)
+		}
+	}
+}
+
+// asColor reads v as a color if it has numeric red, green and blue fields
+func asColor(v interface{}) (ArcColor, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ArcColor{}, false
+	}
+	red, okR := m["red"].(float64)
+	green, okG := m["green"].(float64)
+	blue, okB := m["blue"].(float64)
+	if !okR || !okG || !okB {
+		return ArcColor{}, false
+	}
+	alpha, ok := m["alpha"].(float64)
+	if !ok {
+		alpha = 1
+	}
+	return ArcColor{Red: red, Green: green, Blue: blue, Alpha: alpha}, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
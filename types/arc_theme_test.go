@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// arcThemeSpace has a blue/purple gradient theme with a blue palette
+const arcThemeSpace = `{
+	"id": "s1", "title": "Work",
+	"customInfo": {"windowTheme": {
+		"background": {"single": {"_0": {"style": {"color": {"_0": {"blendedGradient": {"_0": {
+			"baseColors": [
+				{"red": 0.2, "green": 0.4, "blue": 0.9, "alpha": 1},
+				{"red": 0.6, "green": 0.3, "blue": 0.85, "alpha": 1},
+				{"red": 0.6, "green": 0.3, "blue": 0.85, "alpha": 1}
+			]
+		}}}}}}}},
+		"primaryColorPalette": {
+			"midTone": {"red": 0.21, "green": 0.41, "blue": 0.9, "alpha": 1},
+			"shadedTone": {"red": 0.1, "green": 0.2, "blue": 0.5, "alpha": 1},
+			"tintedTone": {"red": 0.8, "green": 0.85, "blue": 1, "alpha": 1}
+		}
+	}}
+}`
+
+func TestThemeColors_PaletteFirstThenGradient(t *testing.T) {
+	var space ArcSpace
+	if err := json.Unmarshal([]byte(arcThemeSpace), &space); err != nil {
+		t.Fatal(err)
+	}
+	colors := space.ThemeColors()
+	if len(colors) != 2 {
+		t.Fatalf("expected the mid tone and one more gradient color, got %+v", colors)
+	}
+	if r, g, b := colors[0].RGB(); r != 54 || g != 105 || b != 230 {
+		t.Errorf("expected the mid tone first, got %d,%d,%d", r, g, b)
+	}
+	if r, g, b := colors[1].RGB(); r != 153 || g != 77 || b != 217 {
+		t.Errorf("expected the purple gradient color second, got %d,%d,%d", r, g, b)
+	}
+}
+
+func TestThemeColors_None(t *testing.T) {
+	for _, raw := range []string{
+		`{"id": "s1"}`,
+		`{"id": "s1", "customInfo": {"iconType": {"icon": "star"}}}`,
+		`{"id": "s1", "customInfo": {"windowTheme": {"semanticColorPalette": {}}}}`,
+		`{"id": "s1", "customInfo": {"windowTheme": {"c": {"red": 1, "green": 0, "blue": 0, "alpha": 0}}}}`,
+	} {
+		var space ArcSpace
+		if err := json.Unmarshal([]byte(raw), &space); err != nil {
+			t.Fatal(err)
+		}
+		if colors := space.ThemeColors(); colors != nil {
+			t.Errorf("%s: expected no colors, got %+v", raw, colors)
+		}
+	}
+}
+
+func TestArcColorRGB_Clamps(t *testing.T) {
+	if r, g, b := (ArcColor{Red: 1.08, Green: -0.02, Blue: 0.5}).RGB(); r != 255 || g != 0 || b != 128 {
+		t.Errorf("expected extended sRGB to be clamped, got %d,%d,%d", r, g, b)
+	}
+}
@@ -0,0 +1,214 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Zen adds session fields between versions. The Zen types keep any field they
+// don't model in Extra and write it back unchanged, so rewriting a session
+// never drops data written by a newer Zen.
+
+// knownFieldsCache maps a struct type to the JSON names of its fields
+var knownFieldsCache sync.Map
+
+// knownFields returns the JSON field names of struct type t
+func knownFields(t reflect.Type) map[string]bool {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		known[name] = true
+	}
+	knownFieldsCache.Store(t, known)
+	return known
+}
+
+// unmarshalWithExtra decodes data into v (a pointer to a struct without custom
+// unmarshaling) and returns the fields v has no place for, and all fields
+func unmarshalWithExtra(data []byte, v interface{}) (extra, raw map[string]json.RawMessage, err error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	known := knownFields(reflect.TypeOf(v).Elem())
+	for name, value := range raw {
+		if known[name] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[name] = value
+	}
+	return extra, raw, nil
+}
+
+// marshalWithExtra encodes v and adds the extra fields to the object. For a
+// value read from a session (raw is the object it came from), modeled fields
+// that still hold what was read are written back byte for byte, and scalar
+// ones the object didn't have are left out while they are empty, so a
+// round-trip doesn't add fields or re-encode the heavy ones (entries with
+// scroll, formdata, docshell IDs). Arrays the importer adds, such as an empty
+// splitViewData, are kept.
+func marshalWithExtra(v interface{}, extra, raw map[string]json.RawMessage) ([]byte, error) {
+	data, err := marshalNoEscape(v)
+	if err != nil || (len(extra) == 0 && raw == nil) {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		for name, value := range fields {
+			original, had := raw[name]
+			switch {
+			case had && sameJSON(original, value):
+				fields[name] = original
+			case !had && isEmptyScalar(value):
+				delete(fields, name)
+			}
+		}
+	}
+	for name, value := range extra {
+		if _, exists := fields[name]; !exists {
+			fields[name] = value
+		}
+	}
+	return marshalNoEscape(fields)
+}
+
+// marshalNoEscape is json.Marshal without escaping <, > and &, which Firefox
+// doesn't do either
+func marshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// sameJSON reports whether a and b encode the same value
+func sameJSON(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, a) == nil && bytes.Equal(compacted.Bytes(), b) {
+		return true
+	}
+	var va, vb interface{}
+	if decodeNumbers(a, &va) != nil || decodeNumbers(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func decodeNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// isEmptyScalar reports whether value is null, false, 0 or ""
+func isEmptyScalar(value json.RawMessage) bool {
+	switch string(bytes.TrimSpace(value)) {
+	case "null", "false", "0", `""`:
+		return true
+	}
+	return false
+}
+
+func (s *ZenSession) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenSession
+	s.Extra, s.raw, err = unmarshalWithExtra(data, (*plain)(s))
+	return err
+}
+
+func (s ZenSession) MarshalJSON() ([]byte, error) {
+	type plain ZenSession
+	return marshalWithExtra(plain(s), s.Extra, s.raw)
+}
+
+func (s *ZenSpace) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenSpace
+	s.Extra, s.raw, err = unmarshalWithExtra(data, (*plain)(s))
+	return err
+}
+
+func (s ZenSpace) MarshalJSON() ([]byte, error) {
+	type plain ZenSpace
+	return marshalWithExtra(plain(s), s.Extra, s.raw)
+}
+
+func (t *ZenTheme) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenTheme
+	t.Extra, t.raw, err = unmarshalWithExtra(data, (*plain)(t))
+	return err
+}
+
+func (t ZenTheme) MarshalJSON() ([]byte, error) {
+	type plain ZenTheme
+	return marshalWithExtra(plain(t), t.Extra, t.raw)
+}
+
+func (t *ZenTab) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenTab
+	t.Extra, t.raw, err = unmarshalWithExtra(data, (*plain)(t))
+	return err
+}
+
+func (t ZenTab) MarshalJSON() ([]byte, error) {
+	type plain ZenTab
+	return marshalWithExtra(plain(t), t.Extra, t.raw)
+}
+
+func (e *ZenTabEntry) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenTabEntry
+	e.Extra, e.raw, err = unmarshalWithExtra(data, (*plain)(e))
+	return err
+}
+
+func (e ZenTabEntry) MarshalJSON() ([]byte, error) {
+	type plain ZenTabEntry
+	return marshalWithExtra(plain(e), e.Extra, e.raw)
+}
+
+func (f *ZenFolder) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenFolder
+	f.Extra, f.raw, err = unmarshalWithExtra(data, (*plain)(f))
+	return err
+}
+
+func (f ZenFolder) MarshalJSON() ([]byte, error) {
+	type plain ZenFolder
+	return marshalWithExtra(plain(f), f.Extra, f.raw)
+}
+
+func (g *ZenGroup) UnmarshalJSON(data []byte) (err error) {
+	type plain ZenGroup
+	g.Extra, g.raw, err = unmarshalWithExtra(data, (*plain)(g))
+	return err
+}
+
+func (g ZenGroup) MarshalJSON() ([]byte, error) {
+	type plain ZenGroup
+	return marshalWithExtra(plain(g), g.Extra, g.raw)
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sessionTab is a tab as Firefox writes it for a page with history, scroll
+// position, form data and subframes. It lacks several fields ZenTab models.
+const sessionTab = `{
+	"entries": [{
+		"url": "https://example.com/search?q=a&b=<c>",
+		"title": "Search",
+		"cacheKey": 0,
+		"ID": 9007199254740993,
+		"docshellUUID": "{5b1c7f2e-8a0c-4b8e-9f57-0d3f0a1e2b3c}",
+		"resultPrincipalURI": null,
+		"hasUserInteraction": true,
+		"triggeringPrincipal_base64": "eyIzIjp7fX0=",
+		"docIdentifier": 18,
+		"persist": true,
+		"scrollRestorationIsManual": false,
+		"children": [{"url": "about:blank", "ID": 4294967298, "docshellUUID": "{00000000-0000-0000-0000-000000000001}"}]
+	}, {
+		"url": "about:blank",
+		"title": "Blank"
+	}],
+	"lastAccessed": 1718000000000,
+	"hidden": false,
+	"attributes": {},
+	"index": 1,
+	"userContextId": 0,
+	"requestedIndex": 0,
+	"scroll": {"scroll": "0,1480", "children": [null, {"scroll": "0,24"}]},
+	"formdata": {"url": "https://example.com/search", "id": {"q": "<b>a & b</b>"}, "xpath": {}},
+	"storage": {"https://example.com": {"key": "value"}},
+	"image": "https://example.com/favicon.ico",
+	"pinned": true,
+	"zenWorkspace": "{ws}",
+	"zenSyncId": "1718000000000-42"
+}`
+
+func decodeGeneric(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	return v
+}
+
+func TestZenTab_RoundTripKeepsHeavyFields(t *testing.T) {
+	var tab ZenTab
+	if err := json.Unmarshal([]byte(sessionTab), &tab); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := tab.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := decodeGeneric(t, encoded), decodeGeneric(t, []byte(sessionTab)); !reflect.DeepEqual(got, want) {
+		t.Errorf("round-trip changed the tab:\ngot  %s", encoded)
+	}
+	for _, verbatim := range []string{
+		`"ID":9007199254740993`, // Beyond float64 precision
+		`"scroll":{"scroll":"0,1480","children":[null,{"scroll":"0,24"}]}`,
+		`"id":{"q":"<b>a & b</b>"}`,
+	} {
+		if !strings.Contains(string(encoded), verbatim) {
+			t.Errorf("expected %s written back as read, got %s", verbatim, encoded)
+		}
+	}
+	for _, added := range []string{"zenEssential", "zenPinnedIcon", "userTypedValue", "searchMode"} {
+		if strings.Contains(string(encoded), `"`+added+`"`) {
+			t.Errorf("round-trip added %s, which the tab didn't have", added)
+		}
+	}
+}
+
+func TestZenTab_ChangedFieldsAreWritten(t *testing.T) {
+	var tab ZenTab
+	if err := json.Unmarshal([]byte(sessionTab), &tab); err != nil {
+		t.Fatal(err)
+	}
+	tab.Pinned = false
+	tab.ZenEssential = true
+	tab.Entries[1].Title = "Changed"
+	encoded, err := json.Marshal(tab)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := decodeGeneric(t, encoded).(map[string]interface{})
+	if got["pinned"] != false || got["zenEssential"] != true {
+		t.Errorf("expected the changed fields, got pinned=%v zenEssential=%v", got["pinned"], got["zenEssential"])
+	}
+	entries := got["entries"].([]interface{})
+	if title := entries[1].(map[string]interface{})["title"]; title != "Changed" {
+		t.Errorf("expected the changed entry title, got %v", title)
+	}
+	if _, ok := got["formdata"]; !ok {
+		t.Error("changing a tab dropped its formdata")
+	}
+}
+
+func TestZenTab_NewTabWritesAllFields(t *testing.T) {
+	encoded, err := json.Marshal(ZenTab{Entries: []ZenTabEntry{{URL: "https://example.com/"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"zenEssential":false`, `"triggeringPrincipal_base64":""`, `"hidden":false`} {
+		if !strings.Contains(string(encoded), field) {
+			t.Errorf("expected %s in a tab built by the importer, got %s", field, encoded)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "encoding/json"
+
 // ZenSession represents the Zen browser session structure
 type ZenSession struct {
 	Spaces        []ZenSpace      `json:"spaces"`
@@ -8,6 +10,9 @@ type ZenSession struct {
 	Groups        []ZenGroup      `json:"groups"`
 	SplitViewData []interface{}   `json:"splitViewData"`
 	LastCollected int64           `json:"lastCollected"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenSpace represents a Zen workspace
@@ -19,6 +24,9 @@ type ZenSpace struct {
 	Position               int       `json:"position"`
 	Theme                  ZenTheme  `json:"theme"`
 	HasCollapsedPinnedTabs bool      `json:"hasCollapsedPinnedTabs"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenTheme represents workspace theme configuration
@@ -28,6 +36,9 @@ type ZenTheme struct {
 	Opacity        float64       `json:"opacity"`
 	Rotation       interface{}   `json:"rotation"`
 	Texture        interface{}   `json:"texture"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenTab represents a browser tab
@@ -55,6 +66,9 @@ type ZenTab struct {
 	UserTypedClear          int           `json:"userTypedClear"`
 	Image                   interface{}   `json:"image"`
 	GroupID                 string        `json:"groupId,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenTabEntry represents a tab's history entry
@@ -62,6 +76,9 @@ type ZenTabEntry struct {
 	URL                      string `json:"url"`
 	Title                    string `json:"title"`
 	TriggeringPrincipalBase64 string `json:"triggeringPrincipal_base64"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenFolder represents a pinned folder
@@ -77,6 +94,9 @@ type ZenFolder struct {
 	EmptyTabIDs        []string    `json:"emptyTabIds"`
 	UserIcon           string      `json:"userIcon"`
 	WorkspaceID        string      `json:"workspaceId"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ZenGroup represents a tab group (Firefox requirement)
@@ -88,6 +108,9 @@ type ZenGroup struct {
 	Pinned    bool        `json:"pinned"`
 	Essential bool        `json:"essential"`
 	SplitView bool        `json:"splitView"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields this version doesn't model, written back as-is
+	raw   map[string]json.RawMessage // Object this was read from (see extra.go)
 }
 
 // ContainersData represents the containers.json structure
@@ -0,0 +1,390 @@
+// Package upgrade brings a Zen session written by an older version of
+// arc-to-zen up to the structure the importer writes now. Earlier versions
+// created folders without an empty anchor tab (so Zen dropped them on
+// restore), chained nested folders by prevSiblingInfo references to tabs
+// (which Zen discards on save) and created a new container for the same
+// profile on every run. Session and Containers repair these artifacts in
+// place and report what they changed; ContainerIDs also repairs containers
+// that share a userContextId, whoever wrote them.
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/principal"
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+
+	"github.com/google/uuid"
+)
+
+const containersFileName = "containers.json"
+
+// Kind identifies an artifact of an older version
+type Kind string
+
+const (
+	MissingAnchor      Kind = "missing-anchor"      // Folder has no empty anchor tab bound to it
+	StaleEmptyTabIDs   Kind = "empty-tab-ids"       // emptyTabIds doesn't list exactly the folder's anchor tabs
+	MissingGroup       Kind = "missing-group"       // Folder has no matching entry in groups
+	OldSiblingFormat   Kind = "old-prev-sibling"    // prevSiblingInfo references a tab or isn't a {type, id} object
+	DuplicateContainer Kind = "duplicate-container" // Container with the same name as an earlier one
+	DuplicateID        Kind = "duplicate-id"        // Container with the userContextId of an earlier or internal one
+)
+
+// Kinds lists every artifact Session, ContainerIDs and Containers repair
+var Kinds = []Kind{MissingAnchor, StaleEmptyTabIDs, MissingGroup, OldSiblingFormat, DuplicateContainer, DuplicateID}
+
+// Finding is one artifact, already repaired in the session passed in
+type Finding struct {
+	Kind    Kind
+	Name    string // Folder or container name
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %q: %s", f.Kind, f.Name, f.Message)
+}
+
+// Result describes what upgrading a profile found and changed
+type Result struct {
+	Findings []Finding
+}
+
+// Session repairs the folder structure of session in place and returns what
+// it changed. Folders the current importer would have written identically
+// are left alone.
+func Session(session *types.ZenSession) []Finding {
+	var findings []Finding
+	add := func(kind Kind, name, format string, args ...interface{}) {
+		findings = append(findings, Finding{Kind: kind, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	containerOf := make(map[string]int) // Workspace UUID → container
+	for _, space := range session.Spaces {
+		containerOf[space.UUID] = space.ContainerTabID
+	}
+	groups := make(map[string]bool)
+	for _, group := range session.Groups {
+		groups[group.ID] = true
+	}
+	folders := make(map[string]bool)
+	for _, folder := range session.Folders {
+		folders[folder.ID] = true
+	}
+	anchors := make(map[string][]string) // Folder ID → empty tabs bound to it
+	for _, tab := range session.Tabs {
+		if tab.ZenIsEmpty && tab.GroupID != "" {
+			anchors[tab.GroupID] = append(anchors[tab.GroupID], tab.ZenSyncID)
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	lastByParent := make(map[string]string) // Parent folder ID → last nested folder seen
+	for i := range session.Folders {
+		folder := &session.Folders[i]
+
+		if len(anchors[folder.ID]) == 0 {
+			anchorID := newAnchorTab(session, folder, containerOf[folder.WorkspaceID], now)
+			anchors[folder.ID] = []string{anchorID}
+			add(MissingAnchor, folder.Name, "added empty anchor tab %s so Zen restores the folder", anchorID)
+		}
+		if !sameIDs(folder.EmptyTabIDs, anchors[folder.ID]) {
+			add(StaleEmptyTabIDs, folder.Name, "emptyTabIds %v replaced with the folder's anchor tabs %v", folder.EmptyTabIDs, anchors[folder.ID])
+			folder.EmptyTabIDs = append([]string(nil), anchors[folder.ID]...)
+		}
+		if !groups[folder.ID] {
+			session.Groups = append(session.Groups, types.ZenGroup{
+				ID:        folder.ID,
+				Name:      folder.Name,
+				Collapsed: folder.Collapsed,
+				Pinned:    folder.Pinned,
+			})
+			groups[folder.ID] = true
+			add(MissingGroup, folder.Name, "added groups entry %s", folder.ID)
+		}
+
+		// Nested folders follow their previous sibling folder; the first, and
+		// root folders, have none
+		var want interface{}
+		if prev, ok := lastByParent[folder.ParentID]; ok && folder.ParentID != "" && folders[folder.ParentID] {
+			want = map[string]interface{}{"type": "group", "id": prev}
+		}
+		lastByParent[folder.ParentID] = folder.ID
+		if isOldSiblingInfo(folder.PrevSiblingInfo) {
+			add(OldSiblingFormat, folder.Name, "prevSiblingInfo %s replaced with %s", describeSibling(folder.PrevSiblingInfo), describeSibling(want))
+			folder.PrevSiblingInfo = want
+		}
+	}
+	return findings
+}
+
+// newAnchorTab appends the empty, pinned placeholder tab the importer binds
+// to every folder and returns its ID
+func newAnchorTab(session *types.ZenSession, folder *types.ZenFolder, containerID int, now int64) string {
+	anchorID := fmt.Sprintf("{%s}", uuid.New().String())
+	session.Tabs = append(session.Tabs, types.ZenTab{
+		Entries: []types.ZenTabEntry{{
+			URL:                       "about:blank",
+			TriggeringPrincipalBase64: principal.SystemBase64,
+		}},
+		LastAccessed:            now,
+		Pinned:                  true,
+		ZenWorkspace:            folder.WorkspaceID,
+		ZenSyncID:               anchorID,
+		ZenDefaultUserContextID: containerID,
+		ZenIsEmpty:              true,
+		UserContextID:           containerID,
+		Attributes:              map[string]interface{}{},
+		Index:                   len(session.Tabs),
+		GroupID:                 folder.ID,
+	})
+	return anchorID
+}
+
+// isOldSiblingInfo reports whether a prevSiblingInfo value is in a format the
+// importer no longer writes: a tab reference, or anything but a {type, id}
+// object. Group and start references, including ones Zen wrote, are current.
+func isOldSiblingInfo(info interface{}) bool {
+	if info == nil {
+		return false
+	}
+	m, ok := info.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	kind, _ := m["type"].(string)
+	return kind != "group" && kind != "start"
+}
+
+func describeSibling(info interface{}) string {
+	if info == nil {
+		return "null"
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Sprint(info)
+	}
+	return string(data)
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Containers merges public containers that share a name into the first one
+// with a valid userContextId, moving session's tabs and spaces over to it, and
+// returns what it changed. Built-in containers (l10nId, no name) are kept.
+func Containers(data *types.ContainersData, session *types.ZenSession) []Finding {
+	keep := make(map[string]int) // Name → userContextId kept
+	for _, container := range data.Identities {
+		if _, seen := keep[container.Name]; !seen && container.Public && container.Name != "" && container.HasValidUserContextID() {
+			keep[container.Name] = container.GetUserContextID()
+		}
+	}
+
+	var findings []Finding
+	remap := make(map[int]int)
+	identities := data.Identities[:0]
+	for _, container := range data.Identities {
+		target, named := keep[container.Name]
+		if !container.Public || container.Name == "" || !named || (container.HasValidUserContextID() && container.GetUserContextID() == target) {
+			identities = append(identities, container)
+			continue
+		}
+		if container.HasValidUserContextID() {
+			remap[container.GetUserContextID()] = target
+			findings = append(findings, Finding{Kind: DuplicateContainer, Name: container.Name,
+				Message: fmt.Sprintf("container %d merged into %d", container.GetUserContextID(), target)})
+		} else {
+			findings = append(findings, Finding{Kind: DuplicateContainer, Name: container.Name,
+				Message: fmt.Sprintf("copy without a valid userContextId removed; %d kept", target)})
+		}
+	}
+	data.Identities = identities
+
+	if len(remap) > 0 {
+		remapSession(session, remap)
+	}
+	return findings
+}
+
+// ContainerIDs gives public containers that share a userContextId with an
+// earlier container an unused one, and removes those that are exact copies of
+// an earlier public container. Tabs keep the earlier container, which is the
+// one Zen finds for the ID, except that tabs of a container that took the ID
+// of one of Firefox's internal containers move with it to its new ID. Run it
+// before Containers, which then merges the copies that only share a name.
+func ContainerIDs(data *types.ContainersData, session *types.ZenSession) []Finding {
+	used := make(map[int]bool)
+	internal := make(map[int]bool)
+	nextID := 1
+	if data.LastUserContextID != nil {
+		nextID = *data.LastUserContextID + 1
+	}
+	for _, container := range data.Identities {
+		if container.HasValidUserContextID() {
+			used[container.GetUserContextID()] = true
+			if !container.Public {
+				internal[container.GetUserContextID()] = true
+			}
+		}
+	}
+	for _, container := range data.Identities {
+		if id := container.GetUserContextID(); container.Public && !internal[id] && id >= nextID {
+			nextID = id + 1
+		}
+	}
+
+	var findings []Finding
+	remap := make(map[int]int)
+	owner := make(map[int]types.ContainerIdentity) // userContextId → first public container with it
+	identities := data.Identities[:0]
+	for _, container := range data.Identities {
+		if !container.Public || !container.HasValidUserContextID() {
+			identities = append(identities, container)
+			continue
+		}
+		id := container.GetUserContextID()
+		first, taken := owner[id]
+		if !taken && !internal[id] {
+			owner[id] = container
+			identities = append(identities, container)
+			continue
+		}
+		if taken && first.Name == container.Name && first.L10nID == container.L10nID {
+			findings = append(findings, Finding{Kind: DuplicateID, Name: containerName(container),
+				Message: fmt.Sprintf("copy of container %d removed", id)})
+			continue
+		}
+
+		for used[nextID] {
+			nextID++
+		}
+		newID := nextID
+		used[newID] = true
+		container.UserContextID = &newID
+		data.LastUserContextID = &newID
+		if taken {
+			findings = append(findings, Finding{Kind: DuplicateID, Name: containerName(container),
+				Message: fmt.Sprintf("shared userContextId %d with %q; now %d", id, containerName(first), newID)})
+		} else {
+			owner[id] = container
+			remap[id] = newID
+			findings = append(findings, Finding{Kind: DuplicateID, Name: containerName(container),
+				Message: fmt.Sprintf("had the userContextId of an internal container; moved from %d to %d", id, newID)})
+		}
+		identities = append(identities, container)
+	}
+	data.Identities = identities
+
+	if len(remap) > 0 {
+		remapSession(session, remap)
+	}
+	return findings
+}
+
+func containerName(container types.ContainerIdentity) string {
+	if container.Name != "" {
+		return container.Name
+	}
+	return container.L10nID
+}
+
+// remapSession moves tabs and spaces from the containers in remap (old →
+// new userContextId) to their replacements
+func remapSession(session *types.ZenSession, remap map[int]int) {
+	for i := range session.Spaces {
+		if to, ok := remap[session.Spaces[i].ContainerTabID]; ok {
+			session.Spaces[i].ContainerTabID = to
+		}
+	}
+	for i := range session.Tabs {
+		tab := &session.Tabs[i]
+		if to, ok := remap[tab.UserContextID]; ok {
+			tab.UserContextID = to
+			for j := range tab.Entries {
+				tab.Entries[j].TriggeringPrincipalBase64 = principal.WithUserContextID(tab.Entries[j].TriggeringPrincipalBase64, to)
+			}
+		}
+		if from, ok := contextID(tab.ZenDefaultUserContextID); ok {
+			if to, ok := remap[from]; ok {
+				tab.ZenDefaultUserContextID = to
+			}
+		}
+	}
+}
+
+// contextID reads zenDefaultUserContextId, which Zen writes as a number or a string
+func contextID(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	case string:
+		id, err := strconv.Atoi(v)
+		return id, err == nil
+	}
+	return 0, false
+}
+
+// Profile upgrades the session and containers.json of the Zen profile at
+// profilePath. The caller backs both up first; they are written together so
+// a failure leaves neither half-upgraded. In dry-run nothing is written; the
+// findings are the same.
+func Profile(profilePath string, dryRun bool) (*Result, error) {
+	session, _, err := sessionfile.Read(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Findings: Session(session)}
+
+	// containers.json is optional: Zen only writes it once a container is edited
+	var containersData *types.ContainersData
+	var containerFindings []Finding
+	if _, err := os.Stat(filepath.Join(profilePath, containersFileName)); err == nil {
+		containersData, err = containers.Load(profilePath)
+		if err != nil {
+			return nil, err
+		}
+		containerFindings = append(ContainerIDs(containersData, session), Containers(containersData, session)...)
+		result.Findings = append(result.Findings, containerFindings...)
+	}
+
+	if dryRun || len(result.Findings) == 0 {
+		return result, nil
+	}
+
+	upgraded, err := sessionfile.Encode(session)
+	if err != nil {
+		return nil, err
+	}
+	files := []sessionfile.File{{Path: sessionfile.Path(profilePath), Data: upgraded}}
+	if len(containerFindings) > 0 {
+		containersJSON, err := containers.Encode(containersData)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sessionfile.File{Path: filepath.Join(profilePath, containersFileName), Data: containersJSON})
+	}
+	if err := sessionfile.WriteTogether(files); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,220 @@
+package upgrade
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arc-to-zen/containers"
+	"arc-to-zen/mozlz4"
+	"arc-to-zen/principal"
+	"arc-to-zen/restoresim"
+	"arc-to-zen/sessionfile"
+	"arc-to-zen/types"
+)
+
+// oldSession is what earlier versions wrote: no anchor tab or group for
+// "Root", an emptyTabIds naming a regular tab, a nested folder placed after a
+// tab, and tabs in a second copy of the "Work" container
+const oldSession = `{"spaces": [{"uuid": "w", "name": "Work", "containerTabId": 7}],
+	"tabs": [
+		{"entries": [{"url": "https://a.test/", "triggeringPrincipal_base64": "eyIzIjp7fX0="}], "zenSyncId": "t1",
+		 "zenWorkspace": "w", "pinned": true, "groupId": "b", "userContextId": 7, "zenDefaultUserContextId": "7"},
+		{"entries": [{"url": "moz-extension://ext/page.html", "triggeringPrincipal_base64": "PRINCIPAL"}], "zenSyncId": "t2",
+		 "zenWorkspace": "w", "pinned": true, "groupId": "a", "userContextId": 7, "zenDefaultUserContextId": 7},
+		{"entries": [{"url": "about:blank"}], "zenSyncId": "anchor-a", "zenWorkspace": "w", "zenIsEmpty": true, "groupId": "a"},
+		{"entries": [{"url": "about:blank"}], "zenSyncId": "anchor-b", "zenWorkspace": "w", "zenIsEmpty": true, "groupId": "b"}
+	],
+	"folders": [
+		{"id": "root", "name": "Root", "workspaceId": "w", "pinned": true, "emptyTabIds": []},
+		{"id": "a", "name": "A", "parentId": "root", "workspaceId": "w", "pinned": true, "emptyTabIds": ["t2"]},
+		{"id": "b", "name": "B", "parentId": "root", "workspaceId": "w", "pinned": true,
+		 "prevSiblingInfo": {"type": "tab", "id": "t2"}, "emptyTabIds": ["anchor-b"]}
+	],
+	"groups": [{"id": "a", "name": "A"}, {"id": "b", "name": "B"}],
+	"splitViewData": []}`
+
+const oldContainers = `{"version": 5, "lastUserContextId": 8, "identities": [
+	{"l10nId": "user-context-personal", "icon": "fingerprint", "color": "blue", "public": true, "userContextId": 1},
+	{"name": "Work", "icon": "briefcase", "color": "orange", "public": true, "userContextId": 5},
+	{"name": "Work", "icon": "briefcase", "color": "orange", "public": true, "userContextId": 7},
+	{"name": "Work", "icon": "briefcase", "color": "orange", "public": true, "userContextId": null},
+	{"name": "Personal", "icon": "circle", "color": "red", "public": true, "userContextId": 8}
+]}`
+
+func writeProfile(t *testing.T) (dir string, session []byte) {
+	t.Helper()
+	dir = t.TempDir()
+	var p *principal.Policy
+	raw := bytes.Replace([]byte(oldSession), []byte("PRINCIPAL"), []byte(p.ForURL("moz-extension://ext/page.html", 7)), 1)
+	compressed, err := mozlz4.Compress(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sessionfile.Path(dir), compressed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, containersFileName), []byte(oldContainers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, compressed
+}
+
+func readSession(t *testing.T, dir string) *types.ZenSession {
+	t.Helper()
+	compressed, err := os.ReadFile(sessionfile.Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mozlz4.Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session types.ZenSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	return &session
+}
+
+func countKinds(findings []Finding) map[Kind]int {
+	counts := make(map[Kind]int)
+	for _, finding := range findings {
+		counts[finding.Kind]++
+	}
+	return counts
+}
+
+func TestProfile(t *testing.T) {
+	dir, original := writeProfile(t)
+
+	// Dry run reports without writing
+	result, err := Profile(dir, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	want := map[Kind]int{MissingAnchor: 1, StaleEmptyTabIDs: 2, MissingGroup: 1, OldSiblingFormat: 1, DuplicateContainer: 2}
+	got := countKinds(result.Findings)
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("expected %d %s findings, got %d: %v", n, kind, got[kind], result.Findings)
+		}
+	}
+	if current, _ := os.ReadFile(sessionfile.Path(dir)); !bytes.Equal(current, original) {
+		t.Error("dry run changed the session file")
+	}
+	if current, _ := os.ReadFile(filepath.Join(dir, containersFileName)); string(current) != oldContainers {
+		t.Error("dry run changed containers.json")
+	}
+
+	if _, err := Profile(dir, false); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+	// Both files are swapped in together, leaving no staged copies behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected only the session and containers.json in the profile, got %v", entries)
+	}
+	session := readSession(t, dir)
+	if report := restoresim.Simulate(session); len(report.Issues) > 0 || report.Folders != 3 {
+		t.Errorf("expected the upgraded session to restore cleanly, got %d folders: %v", report.Folders, report.Issues)
+	}
+	if prev, _ := session.Folders[2].PrevSiblingInfo.(map[string]interface{}); prev["type"] != "group" || prev["id"] != "a" {
+		t.Errorf("expected B placed after folder A, got %v", session.Folders[2].PrevSiblingInfo)
+	}
+
+	// Tabs and the space move to the container that is kept
+	if session.Spaces[0].ContainerTabID != 5 {
+		t.Errorf("expected the space moved to container 5, got %d", session.Spaces[0].ContainerTabID)
+	}
+	for _, tab := range session.Tabs[:2] {
+		if tab.UserContextID != 5 || tab.ZenDefaultUserContextID != float64(5) {
+			t.Errorf("expected tab %s moved to container 5, got %d/%v", tab.ZenSyncID, tab.UserContextID, tab.ZenDefaultUserContextID)
+		}
+	}
+	var p *principal.Policy
+	if got := session.Tabs[1].Entries[0].TriggeringPrincipalBase64; got != p.ForURL("moz-extension://ext/page.html", 5) {
+		t.Errorf("expected the content principal moved to container 5, got %s", got)
+	}
+	data, err := containers.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Identities) != 3 {
+		t.Errorf("expected the Work copies removed, got %+v", data.Identities)
+	}
+	if err := containers.Validate(data); err != nil {
+		t.Errorf("expected valid containers, got %v", err)
+	}
+
+	// A second run finds nothing to do
+	if result, err := Profile(dir, false); err != nil || len(result.Findings) != 0 {
+		t.Errorf("expected an upgraded profile, got %v, %v", result.Findings, err)
+	}
+}
+
+func TestSession_CurrentStructureUnchanged(t *testing.T) {
+	session := &types.ZenSession{
+		Spaces: []types.ZenSpace{{UUID: "w"}},
+		Tabs: []types.ZenTab{
+			{ZenSyncID: "e1", ZenWorkspace: "w", ZenIsEmpty: true, GroupID: "f1"},
+			{ZenSyncID: "e2", ZenWorkspace: "w", ZenIsEmpty: true, GroupID: "f2"},
+			{ZenSyncID: "e3", ZenWorkspace: "w", ZenIsEmpty: true, GroupID: "f3"},
+		},
+		Folders: []types.ZenFolder{
+			{ID: "f1", WorkspaceID: "w", EmptyTabIDs: []string{"e1"}},
+			{ID: "f2", WorkspaceID: "w", ParentID: "f1", EmptyTabIDs: []string{"e2"}},
+			// Zen rewrites the first sibling as a start reference
+			{ID: "f3", WorkspaceID: "w", ParentID: "f1", EmptyTabIDs: []string{"e3"}, PrevSiblingInfo: map[string]interface{}{"type": "start"}},
+		},
+		Groups: []types.ZenGroup{{ID: "f1"}, {ID: "f2"}, {ID: "f3"}},
+	}
+	if findings := Session(session); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+func TestContainerIDs(t *testing.T) {
+	id := func(v int) *int { return &v }
+	last := 9
+	data := &types.ContainersData{
+		Version:           5,
+		LastUserContextID: &last,
+		Identities: []types.ContainerIdentity{
+			{UserContextID: id(4), Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+			{UserContextID: id(4), Name: "Work", Icon: "briefcase", Color: "orange", Public: true},
+			{UserContextID: id(4), Name: "Banking", Icon: "dollar", Color: "green", Public: true},
+			{UserContextID: id(4294967295), Name: "userContextIdInternal.thumbnail"},
+			{UserContextID: id(4294967295), Name: "Travel", Icon: "vacation", Color: "blue", Public: true},
+		},
+	}
+	session := &types.ZenSession{
+		Spaces: []types.ZenSpace{{UUID: "w", ContainerTabID: 4294967295}},
+		Tabs:   []types.ZenTab{{ZenSyncID: "t1", UserContextID: 4}, {ZenSyncID: "t2", UserContextID: 4294967295}},
+	}
+
+	findings := ContainerIDs(data, session)
+	if counts := countKinds(findings); counts[DuplicateID] != 3 || len(findings) != 3 {
+		t.Errorf("expected 3 duplicate ID findings, got %v", findings)
+	}
+	var ids []int
+	for _, container := range data.Identities {
+		ids = append(ids, container.GetUserContextID())
+	}
+	if want := []int{4, 10, 4294967295, 11}; len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] || ids[3] != want[3] {
+		t.Errorf("expected IDs %v, got %v", want, ids)
+	}
+	if *data.LastUserContextID != 11 {
+		t.Errorf("expected lastUserContextId 11, got %d", *data.LastUserContextID)
+	}
+	if err := containers.Validate(data); err != nil || len(containers.Duplicates(data)) != 0 {
+		t.Errorf("expected no duplicates left, got %v %v", err, containers.Duplicates(data))
+	}
+
+	// Only the tabs of the container that took an internal ID move
+	if session.Tabs[0].UserContextID != 4 || session.Tabs[1].UserContextID != 11 || session.Spaces[0].ContainerTabID != 11 {
+		t.Errorf("unexpected containers after repair: tabs %d, %d, space %d",
+			session.Tabs[0].UserContextID, session.Tabs[1].UserContextID, session.Spaces[0].ContainerTabID)
+	}
+}